@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// client is a minimal HTTP client for the /api/v1 endpoints lectorctl needs.
+type client struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newClient(baseURL, token string) *client {
+	return &client{
+		baseURL: strings.TrimRight(baseURL, "/") + "/api/v1",
+		token:   token,
+		// Uploads of large PDFs include server-side extraction.
+		http: &http.Client{Timeout: 5 * time.Minute},
+	}
+}
+
+// apiError is a non-2xx response from the server.
+type apiError struct {
+	Status  int
+	Message string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("server returned %d: %s", e.Status, e.Message)
+}
+
+func (c *client) login(email, password string) (*domain.AuthSession, error) {
+	body, err := json.Marshal(map[string]string{"email": email, "password": password})
+	if err != nil {
+		return nil, err
+	}
+	var session domain.AuthSession
+	if err := c.do(http.MethodPost, "/auth/login", "application/json", bytes.NewReader(body), &session); err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (c *client) library() ([]domain.DocumentWithPosition, error) {
+	var library domain.LibraryResponse
+	if err := c.do(http.MethodGet, "/documents/library", "", nil, &library); err != nil {
+		return nil, err
+	}
+	return library.Documents, nil
+}
+
+func (c *client) search(query string) ([]*domain.Document, error) {
+	var docs []*domain.Document
+	if err := c.do(http.MethodGet, "/documents/search?q="+url.QueryEscape(query), "", nil, &docs); err != nil {
+		return nil, err
+	}
+	return docs, nil
+}
+
+func (c *client) highlights(documentID string) ([]*domain.Highlight, error) {
+	path := "/highlights"
+	if documentID != "" {
+		path += "?document_id=" + url.QueryEscape(documentID)
+	}
+	var highlights []*domain.Highlight
+	if err := c.do(http.MethodGet, path, "", nil, &highlights); err != nil {
+		return nil, err
+	}
+	return highlights, nil
+}
+
+func (c *client) upload(path string) (*domain.Document, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Stream the multipart body instead of buffering the whole file.
+	pr, pw := io.Pipe()
+	form := multipart.NewWriter(pw)
+	go func() {
+		part, err := form.CreateFormFile("file", filepath.Base(path))
+		if err == nil {
+			_, err = io.Copy(part, file)
+		}
+		if err == nil {
+			err = form.Close()
+		}
+		pw.CloseWithError(err)
+	}()
+
+	var doc domain.Document
+	if err := c.do(http.MethodPost, "/documents", form.FormDataContentType(), pr, &doc); err != nil {
+		return nil, err
+	}
+	return &doc, nil
+}
+
+func (c *client) do(method, path, contentType string, body io.Reader, out interface{}) error {
+	req, err := http.NewRequest(method, c.baseURL+path, body)
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var payload struct {
+			Error string `json:"error"`
+		}
+		raw, _ := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+		if json.Unmarshal(raw, &payload) != nil || payload.Error == "" {
+			payload.Error = strings.TrimSpace(string(raw))
+		}
+		return &apiError{Status: resp.StatusCode, Message: payload.Error}
+	}
+
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+// Command lectorctl scripts a Lector library over the HTTP API.
+//
+//	lectorctl [-server URL] [-token TOKEN] <command> [flags] [args]
+//
+// Commands:
+//
+//	login -email E -password P    print an access token (self-hosted servers)
+//	list [-json]                  list the library with reading progress
+//	search [-json] QUERY          search titles and authors
+//	upload FILE...                upload documents
+//	highlights [-document ID] [-format markdown|json]
+//	                              export highlights
+//
+// The server URL and access token default to $LECTOR_URL and $LECTOR_TOKEN.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const defaultServerURL = "http://localhost:8080"
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "lectorctl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+	global := flag.NewFlagSet("lectorctl", flag.ContinueOnError)
+	serverURL := global.String("server", envOrDefault("LECTOR_URL", defaultServerURL), "server base URL")
+	token := global.String("token", os.Getenv("LECTOR_TOKEN"), "access token")
+	global.Usage = func() {
+		fmt.Fprintln(global.Output(), "usage: lectorctl [-server URL] [-token TOKEN] login|list|search|upload|highlights [flags] [args]")
+		global.PrintDefaults()
+	}
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return errors.New("missing command")
+	}
+
+	c := newClient(*serverURL, *token)
+	command, rest := global.Arg(0), global.Args()[1:]
+	if command != "login" && *token == "" {
+		return errors.New("an access token is required: pass -token or set LECTOR_TOKEN")
+	}
+
+	switch command {
+	case "login":
+		return runLogin(c, rest, stdout)
+	case "list":
+		return runList(c, rest, stdout)
+	case "search":
+		return runSearch(c, rest, stdout)
+	case "upload":
+		return runUpload(c, rest, stdout)
+	case "highlights":
+		return runHighlights(c, rest, stdout)
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+func runLogin(c *client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	email := fs.String("email", "", "account email")
+	password := fs.String("password", os.Getenv("LECTOR_PASSWORD"), "account password (default $LECTOR_PASSWORD)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *email == "" || *password == "" {
+		return errors.New("login needs -email and -password")
+	}
+
+	session, err := c.login(*email, *password)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintln(stdout, session.AccessToken)
+	return err
+}
+
+func runList(c *client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	entries, err := c.library()
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(stdout, entries)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tAUTHOR\tTAG\tPROGRESS")
+	for _, entry := range entries {
+		progress := "-"
+		if entry.ReadingPosition != nil {
+			progress = fmt.Sprintf("%.0f%%", entry.ReadingPosition.Progress*100)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", entry.DocumentData.ID, entry.DocumentData.Title,
+			deref(entry.DocumentData.Author), deref(entry.DocumentData.Tag), progress)
+	}
+	return tw.Flush()
+}
+
+func runSearch(c *client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	asJSON := fs.Bool("json", false, "print JSON")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	query := strings.Join(fs.Args(), " ")
+	if query == "" {
+		return errors.New("search needs a query")
+	}
+
+	docs, err := c.search(query)
+	if err != nil {
+		return err
+	}
+	if *asJSON {
+		return writeJSON(stdout, docs)
+	}
+
+	tw := tabwriter.NewWriter(stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTITLE\tAUTHOR")
+	for _, doc := range docs {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", doc.ID, doc.Title, deref(doc.Author))
+	}
+	return tw.Flush()
+}
+
+func runUpload(c *client, args []string, stdout io.Writer) error {
+	if len(args) == 0 {
+		return errors.New("upload needs at least one file")
+	}
+
+	var failed int
+	for _, path := range args {
+		doc, err := c.upload(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", path, err)
+			failed++
+			continue
+		}
+		fmt.Fprintf(stdout, "%s\t%s\n", doc.ID, doc.Title)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d uploads failed", failed, len(args))
+	}
+	return nil
+}
+
+func runHighlights(c *client, args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("highlights", flag.ContinueOnError)
+	documentID := fs.String("document", "", "only export highlights of this document")
+	format := fs.String("format", "markdown", "output format: markdown or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	highlights, err := c.highlights(*documentID)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "json":
+		return writeJSON(stdout, highlights)
+	case "markdown":
+		entries, err := c.library()
+		if err != nil {
+			return err
+		}
+		titles := make(map[string]string, len(entries))
+		for _, entry := range entries {
+			titles[entry.DocumentData.ID] = entry.DocumentData.Title
+		}
+		return writeHighlightsMarkdown(stdout, highlights, titles)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+// writeHighlightsMarkdown groups highlights by document, oldest first.
+func writeHighlightsMarkdown(w io.Writer, highlights []*domain.Highlight, titles map[string]string) error {
+	byDocument := make(map[string][]*domain.Highlight)
+	var order []string
+	for _, h := range highlights {
+		if _, ok := byDocument[h.DocumentID]; !ok {
+			order = append(order, h.DocumentID)
+		}
+		byDocument[h.DocumentID] = append(byDocument[h.DocumentID], h)
+	}
+	sort.SliceStable(order, func(i, j int) bool { return titles[order[i]] < titles[order[j]] })
+
+	for i, documentID := range order {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		title := titles[documentID]
+		if title == "" {
+			title = documentID
+		}
+		fmt.Fprintf(w, "## %s\n\n", title)
+
+		group := byDocument[documentID]
+		sort.SliceStable(group, func(i, j int) bool { return group[i].CreatedAt.Before(group[j].CreatedAt) })
+		for _, h := range group {
+			quote := strings.ReplaceAll(strings.TrimSpace(h.Quote), "\n", "\n> ")
+			fmt.Fprintf(w, "> %s\n", quote)
+			if h.PageNumber != nil {
+				fmt.Fprintf(w, ">\n> — page %d\n", *h.PageNumber)
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func envOrDefault(key, fallback string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return fallback
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
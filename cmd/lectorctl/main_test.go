@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/documents/library", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer t0ken" {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"error":"Unauthorized"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"documents":[
+			{"document":{"id":"d1","title":"Pride and Prejudice","author":"Jane Austen"},"reading_position":{"progress":0.4}},
+			{"document":{"id":"d2","title":"Alice"}}
+		]}`))
+	})
+	mux.HandleFunc("/api/v1/highlights", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`[
+			{"id":"h2","document_id":"d1","quote":"second","created_at":"2026-01-02T00:00:00Z"},
+			{"id":"h1","document_id":"d1","quote":"first","page_number":3,"created_at":"2026-01-01T00:00:00Z"}
+		]`))
+	})
+	mux.HandleFunc("/api/v1/documents", func(w http.ResponseWriter, r *http.Request) {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"error":"No file provided"}`))
+			return
+		}
+		content, _ := io.ReadAll(file)
+		if string(content) != "hello" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"id":"d3","title":"` + header.Filename + `"}`))
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestRun_List(t *testing.T) {
+	server := newTestServer(t)
+
+	var out bytes.Buffer
+	if err := run([]string{"-server", server.URL, "-token", "t0ken", "list"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.Contains(out.String(), "Pride and Prejudice") || !strings.Contains(out.String(), "40%") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+
+	err := run([]string{"-server", server.URL, "-token", "wrong", "list"}, &out)
+	if err == nil || !strings.Contains(err.Error(), "401: Unauthorized") {
+		t.Fatalf("expected the server error to be reported, got %v", err)
+	}
+}
+
+func TestRun_RequiresToken(t *testing.T) {
+	t.Setenv("LECTOR_TOKEN", "")
+	if err := run([]string{"list"}, io.Discard); err == nil {
+		t.Fatalf("expected an error without a token")
+	}
+}
+
+func TestRun_HighlightsMarkdown(t *testing.T) {
+	server := newTestServer(t)
+
+	var out bytes.Buffer
+	if err := run([]string{"-server", server.URL, "-token", "t0ken", "highlights"}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "## Pride and Prejudice\n\n> first\n>\n> — page 3\n\n> second\n\n"
+	if out.String() != want {
+		t.Fatalf("unexpected markdown:\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+func TestRun_Upload(t *testing.T) {
+	server := newTestServer(t)
+	path := filepath.Join(t.TempDir(), "notes.txt")
+	if err := os.WriteFile(path, []byte("hello"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := run([]string{"-server", server.URL, "-token", "t0ken", "upload", path}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.String() != "d3\tnotes.txt\n" {
+		t.Fatalf("unexpected output: %q", out.String())
+	}
+}
@@ -0,0 +1,254 @@
+// Command seed creates a demo account with a small sample library (documents,
+// highlights, reading positions and preferences) in the environment configured by
+// .env, for onboarding demos and frontend development.
+//
+//	go run ./cmd/seed -email demo@lector.local -password <password>
+//
+// There is no default password, and it refuses to run when APP_ENV is production.
+// It is safe to run repeatedly: an already seeded account is left alone unless -reset
+// is given, which deletes its documents first.
+package main
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/joho/godotenv"
+	"github.com/supabase-community/gotrue-go/types"
+	"github.com/supabase-community/supabase-go"
+)
+
+//go:embed samples/*.txt
+var samples embed.FS
+
+// sample describes one seeded document and what the demo user has done with it.
+type sample struct {
+	file       string
+	title      string
+	author     string
+	tag        string
+	favorite   bool
+	progress   float32
+	page       int
+	highlights []string
+}
+
+var library = []sample{
+	{
+		file:     "welcome-to-lector.txt",
+		title:    "Welcome to Lector",
+		author:   "Lector",
+		tag:      "guides",
+		favorite: true,
+		progress: 1,
+		page:     1,
+		highlights: []string{
+			"Select any passage to save it as a highlight.",
+		},
+	},
+	{
+		file:     "pride-and-prejudice.txt",
+		title:    "Pride and Prejudice",
+		author:   "Jane Austen",
+		tag:      "classics",
+		favorite: true,
+		progress: 0.4,
+		page:     1,
+		highlights: []string{
+			"It is a truth universally acknowledged, that a single man in possession of a good fortune, must be in want of a wife.",
+			"\"My dear Mr. Bennet,\" said his lady to him one day, \"have you heard that Netherfield Park is let at last?\"",
+		},
+	},
+	{
+		file:     "alice-in-wonderland.txt",
+		title:    "Alice's Adventures in Wonderland",
+		author:   "Lewis Carroll",
+		tag:      "classics",
+		progress: 0.15,
+		page:     1,
+		highlights: []string{
+			"\"and what is the use of a book,\" thought Alice \"without pictures or conversations?\"",
+		},
+	},
+}
+
+func main() {
+	email := flag.String("email", "demo@lector.local", "email of the demo account")
+	password := flag.String("password", "", "password of the demo account (required)")
+	reset := flag.Bool("reset", false, "delete the demo account's documents before seeding")
+	flag.Parse()
+
+	if err := godotenv.Load(); err != nil {
+		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
+	}
+
+	if err := checkSeed(config.NewConfig().GetEnvironment(), *password); err != nil {
+		log.Fatal(err)
+	}
+
+	container := config.NewContainer()
+	defer container.Close()
+
+	if err := run(context.Background(), container, *email, *password, *reset); err != nil {
+		container.Logger.Error("Seeding failed", err)
+		container.Close()
+		os.Exit(1)
+	}
+}
+
+// checkSeed refuses to create a demo account in production, or one whose password
+// anyone who has read this file would know.
+func checkSeed(environment, password string) error {
+	if environment == "production" {
+		return errors.New("refusing to seed a production environment")
+	}
+	if password == "" {
+		return errors.New("-password is required")
+	}
+	return nil
+}
+
+func run(ctx context.Context, c *config.Container, email, password string, reset bool) error {
+	principal, err := demoPrincipal(ctx, c, email, password)
+	if err != nil {
+		return err
+	}
+
+	existing, err := c.DocumentService.GetDocumentsByUserID(ctx, principal)
+	if err != nil {
+		return fmt.Errorf("failed to list documents: %w", err)
+	}
+	if len(existing) > 0 {
+		if !reset {
+			c.Logger.Info("Demo account already has documents; use -reset to seed again", "email", email, "documents", len(existing))
+			return nil
+		}
+		for _, doc := range existing {
+			if err := c.DocumentService.DeleteDocument(ctx, principal, doc.ID); err != nil {
+				return fmt.Errorf("failed to delete document %s: %w", doc.ID, err)
+			}
+		}
+	}
+
+	tags, err := c.DocumentService.GetDocumentTags(ctx, principal)
+	if err != nil {
+		return fmt.Errorf("failed to list tags: %w", err)
+	}
+	known := make(map[string]bool, len(tags))
+	for _, tag := range tags {
+		known[tag] = true
+	}
+
+	for _, s := range library {
+		if !known[s.tag] {
+			if err := c.DocumentService.CreateTag(ctx, principal, s.tag); err != nil && !errors.Is(err, domain.ErrTagAlreadyExists) {
+				return fmt.Errorf("failed to create tag %s: %w", s.tag, err)
+			}
+			known[s.tag] = true
+		}
+		if err := seedDocument(ctx, c, principal, s); err != nil {
+			return fmt.Errorf("failed to seed %s: %w", s.file, err)
+		}
+	}
+
+	prefs, err := c.UserPreferencesService.GetPreferences(ctx, principal)
+	if err != nil {
+		return fmt.Errorf("failed to load preferences: %w", err)
+	}
+	prefs.Theme = "sepia"
+	prefs.FontSize = 18
+	if err := c.UserPreferencesService.UpdatePreferences(ctx, principal, prefs); err != nil {
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	c.Logger.Info("Demo account seeded", "email", email, "user_id", principal.UserID, "documents", len(library))
+	return nil
+}
+
+func seedDocument(ctx context.Context, c *config.Container, principal domain.Principal, s sample) error {
+	content, err := samples.ReadFile("samples/" + s.file)
+	if err != nil {
+		return err
+	}
+
+	doc, err := c.DocumentService.Upload(ctx, principal, bytes.NewReader(content), s.file)
+	if err != nil {
+		return fmt.Errorf("upload failed: %w", err)
+	}
+	if _, err := c.DocumentService.UpdateDocumentDetails(ctx, principal, doc.ID, &s.title, &s.author, &s.tag); err != nil {
+		return fmt.Errorf("failed to update details: %w", err)
+	}
+	if s.favorite {
+		if err := c.DocumentService.SetFavorite(ctx, principal, doc.ID, true); err != nil {
+			return fmt.Errorf("failed to favorite: %w", err)
+		}
+	}
+
+	position := &domain.ReadingPosition{Progress: s.progress, PageNumber: s.page}
+	if err := c.UserPreferencesService.UpdateReadingPosition(ctx, principal, doc.ID, position); err != nil {
+		return fmt.Errorf("failed to save reading position: %w", err)
+	}
+
+	for _, quote := range s.highlights {
+		page := s.page
+		highlight := &domain.Highlight{DocumentID: doc.ID, Quote: quote, PageNumber: &page}
+		if _, err := c.HighlightService.CreateHighlight(ctx, principal, highlight); err != nil {
+			return fmt.Errorf("failed to create highlight: %w", err)
+		}
+	}
+
+	c.Logger.Info("Seeded document", "id", doc.ID, "title", s.title)
+	return nil
+}
+
+// demoPrincipal signs in as the demo account, creating it first if needed. Self-hosted
+// servers use the local auth backend; Supabase needs SUPABASE_SERVICE_ROLE_KEY to
+// create a confirmed user.
+func demoPrincipal(ctx context.Context, c *config.Container, email, password string) (domain.Principal, error) {
+	if c.LocalAuthService != nil {
+		session, err := c.LocalAuthService.Register(ctx, email, password, "Demo Reader")
+		if errors.Is(err, domain.ErrEmailTaken) {
+			session, err = c.LocalAuthService.Login(ctx, email, password)
+		}
+		if err != nil {
+			return domain.Principal{}, fmt.Errorf("failed to sign in as %s: %w", email, err)
+		}
+		return domain.NewPrincipal(session.User, session.AccessToken), nil
+	}
+
+	serviceRoleKey := os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+	if serviceRoleKey == "" {
+		return domain.Principal{}, fmt.Errorf("SUPABASE_SERVICE_ROLE_KEY is required to create the demo user")
+	}
+	admin, err := supabase.NewClient(c.Config.GetSupabaseURL(), serviceRoleKey, &supabase.ClientOptions{})
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("failed to create service role client: %w", err)
+	}
+
+	// Creating fails when the account exists; signing in below tells the two apart.
+	_, createErr := admin.Auth.WithToken(serviceRoleKey).AdminCreateUser(types.AdminCreateUserRequest{
+		Email:        email,
+		Password:     &password,
+		EmailConfirm: true,
+		UserMetadata: map[string]interface{}{"name": "Demo Reader"},
+	})
+
+	session, err := c.SupabaseClient.DB().Auth.SignInWithEmailPassword(email, password)
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("failed to sign in as %s: %w (create: %v)", email, err, createErr)
+	}
+	user, err := c.AuthService.ValidateToken(ctx, session.AccessToken)
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("failed to validate demo session: %w", err)
+	}
+	return domain.NewPrincipal(user, session.AccessToken), nil
+}
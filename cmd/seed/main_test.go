@@ -0,0 +1,20 @@
+package main
+
+import "testing"
+
+func TestCheckSeed(t *testing.T) {
+	tests := []struct {
+		environment, password string
+		ok                    bool
+	}{
+		{"development", "s3cret-demo", true},
+		{"staging", "s3cret-demo", true},
+		{"development", "", false},
+		{"production", "s3cret-demo", false},
+	}
+	for _, tt := range tests {
+		if err := checkSeed(tt.environment, tt.password); (err == nil) != tt.ok {
+			t.Errorf("checkSeed(%q, %q) = %v, want ok %v", tt.environment, tt.password, err, tt.ok)
+		}
+	}
+}
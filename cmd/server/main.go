@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -9,23 +13,45 @@ import (
 	"time"
 
 	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/graphqlserver"
+	"pdf-text-reader/internal/grpcserver"
 	"pdf-text-reader/internal/handler"
+	"pdf-text-reader/internal/infra/blobstore"
 
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 )
 
 func main() {
+	migrate := flag.Bool("migrate", false, "apply pending database migrations (needs DATABASE_URL) before serving")
+	flag.Parse()
+
 	// Load environment variables from .env file
 	if err := godotenv.Load(); err != nil {
 		log.Printf("Warning: .env file not found or could not be loaded: %v", err)
 	}
 	// Wiring
 	container := config.NewContainer()
+	defer container.Close()
+
+	if *migrate {
+		if container.Migrator == nil {
+			container.Logger.Error("Cannot migrate", errors.New("DATABASE_URL is not set"))
+			os.Exit(1)
+		}
+		applied, err := container.Migrator.Migrate(context.Background())
+		if err != nil {
+			container.Logger.Error("Database migration failed", err)
+			os.Exit(1)
+		}
+		container.Logger.Info("Database is up to date", "applied", len(applied))
+	}
 
 	// Handlers
 	documentHandler := handler.NewDocumentHandler(
 		container.DocumentService,
 		container.UserPreferencesService,
+		container.SavedSearchService,
 		container.Logger,
 	)
 
@@ -33,7 +59,11 @@ func main() {
 		container,
 	)
 
-	adminHandler := handler.NewAdminHandler()
+	if container.Config.GetMaintenanceMode() {
+		container.Maintenance.Set(true, "")
+		container.Logger.Warn("Starting in maintenance mode: writes are refused")
+	}
+	adminHandler := handler.NewAdminHandler(container.Migrator, container.PDFMetrics, container.BlobStore, container.Maintenance, container.AbuseMonitor)
 
 	preferenceHandler := handler.NewPreferenceHandler(
 		container,
@@ -45,21 +75,69 @@ func main() {
 		container.Logger,
 	)
 
+	libraryHandler := handler.NewLibraryHandler(
+		container,
+		container.Logger,
+	)
+
+	notificationHandler := handler.NewNotificationHandler(
+		container,
+		container.Logger,
+	)
+
+	importHandler := handler.NewImportHandler(
+		container,
+		container.Logger,
+	)
+
+	viewerHandler := handler.NewViewerHandler(
+		container,
+		container.Logger,
+	)
+
 	authMiddleware := handler.NewAuthMiddleware(
 		container.AuthService,
 		container.Logger,
 	)
 
-	// Router
-	router := handler.NewRouter(
-		authHandler,
+	modules := []handler.RouteModule{
 		adminHandler,
+		authHandler,
 		documentHandler,
+		libraryHandler,
 		preferenceHandler,
 		highlightHandler,
+		notificationHandler,
+		importHandler,
+		viewerHandler,
+	}
+	if container.Config.GetGraphQLEnabled() {
+		modules = append(modules, handler.GraphQLRoutes(graphqlserver.NewHandler(
+			container.DocumentService,
+			container.UserPreferencesService,
+			container.HighlightService,
+			container.Logger,
+		)))
+	}
+
+	// Router
+	router := handler.NewRouter(
 		authMiddleware.Middleware,
+		container.Config.GetCORSAllowedOrigins(),
+		handler.Guards{Maintenance: container.Maintenance, Abuse: container.AbuseMonitor},
+		modules...,
 	)
 
+	// The local storage backend serves its own signed download links.
+	if files, ok := container.BlobStore.(http.Handler); ok {
+		mux := http.NewServeMux()
+		mux.Handle(blobstore.LocalFilesPrefix, files)
+		mux.Handle("/", router)
+		router = mux
+	}
+
+	router = handler.ClientAddress(container.Config.GetTrustedProxies())(router)
+
 	// start server
 	server := &http.Server{
 		Addr:              ":" + container.Config.GetServerPort(),
@@ -78,13 +156,53 @@ func main() {
 			os.Exit(1)
 		}
 	}()
+
+	// The gRPC API listens on its own port when GRPC_PORT is set.
+	var grpcServer *grpc.Server
+	if port := container.Config.GetGRPCPort(); port != "" {
+		listener, err := net.Listen("tcp", ":"+port)
+		if err != nil {
+			container.Logger.Error("gRPC server failed to start", err)
+			os.Exit(1)
+		}
+		grpcServer = grpcserver.NewServer(
+			container.DocumentService,
+			container.UserPreferencesService,
+			container.AuthService,
+			container.Maintenance,
+			container.Logger,
+		)
+		go func() {
+			container.Logger.Info("gRPC server listening", "address", listener.Addr().String())
+			if err := grpcServer.Serve(listener); err != nil {
+				container.Logger.Error("gRPC server failed", err)
+				os.Exit(1)
+			}
+		}()
+	}
+
+	// Background jobs stop when the server shuts down.
+	jobs, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	if container.DigestService != nil {
+		container.Logger.Info("Weekly reading digest enabled")
+		go container.DigestService.Run(jobs)
+	}
+	if container.RecoveryService != nil {
+		go container.RecoveryService.Run(jobs)
+	}
+
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
 	container.Logger.Info("Shutting down server...")
+	stopJobs()
 	_ = server.Close()
+	if grpcServer != nil {
+		grpcServer.GracefulStop()
+	}
 
 	container.Logger.Info("Server exited")
 }
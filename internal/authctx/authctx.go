@@ -0,0 +1,44 @@
+// Package authctx carries the authenticated caller through a request context. The
+// HTTP and gRPC auth middlewares store it; handlers and resolvers read it back. Keys
+// are unexported struct types, so no other package can collide with or forge them.
+package authctx
+
+import (
+	"context"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type (
+	userKey      struct{}
+	principalKey struct{}
+)
+
+// WithUser returns a copy of ctx carrying the validated Supabase user.
+func WithUser(ctx context.Context, user *domain.SupabaseUser) context.Context {
+	return context.WithValue(ctx, userKey{}, user)
+}
+
+// User returns the Supabase user stored by the auth middleware.
+func User(ctx context.Context) (*domain.SupabaseUser, bool) {
+	user, ok := ctx.Value(userKey{}).(*domain.SupabaseUser)
+	return user, ok && user != nil
+}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p domain.Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// Principal returns the principal stored by the auth middleware. A principal
+// without a user ID or token is treated as missing.
+func Principal(ctx context.Context) (domain.Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(domain.Principal)
+	return p, ok && p.UserID != "" && p.Token != ""
+}
+
+// WithCaller stores both the user and the principal built from it and token, as
+// the auth middlewares do once a token is validated.
+func WithCaller(ctx context.Context, user *domain.SupabaseUser, token string) context.Context {
+	return WithPrincipal(WithUser(ctx, user), domain.NewPrincipal(user, token))
+}
@@ -0,0 +1,48 @@
+package authctx
+
+import (
+	"context"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestPrincipal(t *testing.T) {
+	user := &domain.SupabaseUser{ID: "user-1", Email: "test@example.com"}
+	ctx := WithCaller(context.Background(), user, "token")
+
+	p, ok := Principal(ctx)
+	if !ok {
+		t.Fatalf("expected principal in context")
+	}
+	if p.UserID != "user-1" || p.Email != "test@example.com" || p.Token != "token" {
+		t.Fatalf("unexpected principal: %+v", p)
+	}
+	if got, ok := User(ctx); !ok || got != user {
+		t.Fatalf("expected user in context, got %+v", got)
+	}
+}
+
+func TestPrincipal_Missing(t *testing.T) {
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{name: "no principal", ctx: context.Background()},
+		{name: "no token", ctx: WithPrincipal(context.Background(), domain.Principal{UserID: "user-1"})},
+		{name: "no user", ctx: WithPrincipal(context.Background(), domain.Principal{Token: "token"})},
+		// A string key of the same name must not be mistaken for the typed one.
+		{name: "string key", ctx: context.WithValue(context.Background(), "principal", domain.Principal{UserID: "user-1", Token: "token"})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, ok := Principal(tt.ctx); ok {
+				t.Fatalf("expected no principal")
+			}
+		})
+	}
+	if _, ok := User(context.WithValue(context.Background(), "user", &domain.SupabaseUser{ID: "user-1"})); ok {
+		t.Fatalf("expected no user under a string key")
+	}
+}
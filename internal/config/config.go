@@ -2,7 +2,10 @@ package config
 
 import (
 	"os"
+	"runtime"
 	"strconv"
+	"strings"
+	"time"
 
 	"pdf-text-reader/internal/domain"
 )
@@ -10,24 +13,229 @@ import (
 // AppConfig implements the domain.Config interface
 type AppConfig struct {
 	ServerPort  string
+	GRPCPort    string // Empty disables the gRPC API
 	MaxFileSize int64
 	LogLevel    string
 	SupabaseURL string
 	SupabaseKey string
 	JWTSecret   string
+
+	// Per-extension single-upload caps (MAX_FILE_SIZE_BY_FORMAT="pdf=104857600,epub=52428800").
+	FormatMaxFileSizes map[string]int64
+
+	// PDF extraction guardrails (PDF_MAX_PAGES, PDF_MAX_CHARS, PDF_PROCESS_TIMEOUT,
+	// PDF_MAX_CONCURRENT); 0 disables a limit. PDF_PAGE_WORKERS sets how many pages
+	// of one document are extracted in parallel.
+	PDFLimits domain.PDFLimits
+
+	// Optional direct Postgres access: REPOSITORY_BACKEND=pgx serves the repositories
+	// over a pgx pool instead of PostgREST.
+	DatabaseURL       string
+	RepositoryBackend string
+
+	// Upload malware scanning: UPLOAD_SCANNER is "", "clamav" or "http".
+	ScannerBackend string
+	ClamAVAddress  string
+	ScannerURL     string
+	ScannerAPIKey  string
+
+	// Blob storage: STORAGE_BACKEND is "supabase" (default), "s3" or "local".
+	StorageBackend  string
+	LocalStorageDir string
+	PublicURL       string
+	S3              domain.S3Config
+
+	// SelfHosted runs without Supabase: pgx repositories, local JWT auth and local
+	// storage by default (SELF_HOSTED=true).
+	SelfHosted bool
+
+	// Outgoing e-mail: EMAIL_BACKEND is "smtp" or "sendgrid".
+	Email domain.EmailConfig
+
+	// Weekly reading digest job (WEEKLY_DIGEST_ENABLED=true); needs e-mail and the
+	// pgx repository backend.
+	Digest domain.DigestConfig
+
+	// Re-extraction of documents whose background processing was lost to a restart
+	// (PROCESSING_RECOVERY_ENABLED, on by default); needs the pgx repository backend
+	// and a storage backend the server holds credentials for (s3 or local).
+	ProcessingRecovery domain.ProcessingRecoveryConfig
+
+	// Push notifications through FCM (android, web) and APNs (ios); both optional.
+	Push domain.PushConfig
+
+	// Cloud drive import; a provider is enabled when its OAuth client is configured.
+	CloudImport domain.CloudImportConfig
+
+	// External book catalogs used to enrich document metadata.
+	BookCatalog domain.BookCatalogConfig
+
+	// Project Gutenberg import (GUTENBERG_CATALOG_URL, GUTENBERG_MIRRORS).
+	Gutenberg domain.GutenbergConfig
+
+	// Rasterization of PDF pages into tiles for the fixed-layout viewer
+	// (PAGE_RENDER_ENABLED, PAGE_RENDER_SCALES, PAGE_RENDER_TILE_SIZE, PAGE_RENDER_MAX_PAGES).
+	PageRender domain.PageRenderConfig
+
+	// GraphQLEnabled serves the read-only /graphql library endpoint (GRAPHQL_ENABLED=true).
+	GraphQLEnabled bool
+
+	// StarterContentEnabled adds a sample book with highlights to each account that
+	// signs up (STARTER_CONTENT_ENABLED=true).
+	StarterContentEnabled bool
+
+	// MaintenanceMode starts the API refusing writes (MAINTENANCE_MODE=true); admins
+	// switch it at runtime through /api/v1/admin/maintenance.
+	MaintenanceMode bool
+
+	// TrustedProxies is how many reverse proxies in front of the API append to
+	// X-Forwarded-For (TRUSTED_PROXIES). With 0 the header is ignored and the client
+	// address is the connection's.
+	TrustedProxies int
+
+	// Environment selects per-environment defaults: "development" (default), "staging" or "production".
+	Environment        string
+	CORSAllowedOrigins []string
+}
+
+// defaultJWTSecret is the placeholder JWT_SECRET used when none is configured.
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
+// defaultCORSOrigins lists the frontends allowed per environment when
+// CORS_ALLOWED_ORIGINS is not set.
+var defaultCORSOrigins = map[string][]string{
+	"development": {
+		"http://localhost:5173",
+		"http://localhost:4173",
+		"http://localhost:3000",
+		"https://lector.thefndrs.com",
+	},
+	"staging": {
+		"https://lector.thefndrs.com",
+	},
+	"production": {
+		"https://lector.thefndrs.com",
+	},
 }
 
 // NewConfig creates a new configuration instance with default values
 func NewConfig() domain.Config {
+	env := strings.ToLower(getEnvOrDefault("APP_ENV", "development"))
+	origins, ok := defaultCORSOrigins[env]
+	if !ok {
+		// rs/cors treats an empty list as "allow all"; never fall through to that.
+		origins = defaultCORSOrigins["production"]
+	}
+
+	port := getEnvOrDefault("PORT", getEnvOrDefault("SERVER_PORT", "8080"))
+
+	// Self-hosted mode has no Supabase to fall back on, so its backends default to
+	// the ones that run next to the server.
+	selfHosted := getEnvOrDefault("SELF_HOSTED", "false") == "true"
+	repositoryBackend, storageBackend := "postgrest", "supabase"
+	if selfHosted {
+		repositoryBackend, storageBackend = "pgx", "local"
+	}
+
 	return &AppConfig{
 		// Cloud Run (and many PaaS) provide the listening port via PORT.
 		// Keep SERVER_PORT for local/dev compatibility.
-		ServerPort:  getEnvOrDefault("PORT", getEnvOrDefault("SERVER_PORT", "8080")),
-		MaxFileSize: getEnvInt64OrDefault("MAX_FILE_SIZE", 50*1024*1024), // 50MB default
+		ServerPort:  port,
+		GRPCPort:    getEnvOrDefault("GRPC_PORT", ""),
+		MaxFileSize: getEnvInt64OrDefault("MAX_FILE_SIZE", 200*1024*1024), // 200MB default (largest plan entitlement)
 		LogLevel:    getEnvOrDefault("LOG_LEVEL", "info"),
 		SupabaseURL: getEnvOrDefault("SUPABASE_URL", ""),
 		SupabaseKey: getEnvOrDefault("SUPABASE_ANON_KEY", ""),
-		JWTSecret:   getEnvOrDefault("JWT_SECRET", "your-secret-key-change-in-production"),
+		JWTSecret:   getEnvOrDefault("JWT_SECRET", defaultJWTSecret),
+
+		FormatMaxFileSizes: getEnvSizeMap("MAX_FILE_SIZE_BY_FORMAT"),
+
+		PDFLimits: domain.PDFLimits{
+			MaxPages:      int(getEnvInt64OrDefault("PDF_MAX_PAGES", 5000)),
+			MaxChars:      int(getEnvInt64OrDefault("PDF_MAX_CHARS", 20_000_000)),
+			Timeout:       getEnvDurationOrDefault("PDF_PROCESS_TIMEOUT", 5*time.Minute),
+			MaxConcurrent: int(getEnvInt64OrDefault("PDF_MAX_CONCURRENT", int64(runtime.NumCPU()))),
+			PageWorkers:   int(getEnvInt64OrDefault("PDF_PAGE_WORKERS", int64(min(runtime.NumCPU(), 4)))),
+		},
+
+		DatabaseURL:       getEnvOrDefault("DATABASE_URL", ""),
+		RepositoryBackend: getEnvOrDefault("REPOSITORY_BACKEND", repositoryBackend),
+
+		ScannerBackend: strings.ToLower(getEnvOrDefault("UPLOAD_SCANNER", "")),
+		ClamAVAddress:  getEnvOrDefault("CLAMAV_ADDRESS", ""),
+		ScannerURL:     getEnvOrDefault("SCANNER_URL", ""),
+		ScannerAPIKey:  getEnvOrDefault("SCANNER_API_KEY", ""),
+
+		StorageBackend:  strings.ToLower(getEnvOrDefault("STORAGE_BACKEND", storageBackend)),
+		LocalStorageDir: getEnvOrDefault("LOCAL_STORAGE_DIR", "data/storage"),
+		PublicURL:       getEnvOrDefault("PUBLIC_URL", "http://localhost:"+port),
+		S3: domain.S3Config{
+			Endpoint:        getEnvOrDefault("S3_ENDPOINT", ""),
+			Region:          getEnvOrDefault("S3_REGION", "us-east-1"),
+			Bucket:          getEnvOrDefault("S3_BUCKET", ""),
+			AccessKeyID:     getEnvOrDefault("S3_ACCESS_KEY_ID", ""),
+			SecretAccessKey: getEnvOrDefault("S3_SECRET_ACCESS_KEY", ""),
+			PathStyle:       getEnvOrDefault("S3_PATH_STYLE", "true") == "true",
+		},
+
+		SelfHosted: selfHosted,
+
+		Email: domain.EmailConfig{
+			Backend:        strings.ToLower(getEnvOrDefault("EMAIL_BACKEND", "")),
+			From:           getEnvOrDefault("EMAIL_FROM", ""),
+			SMTPAddress:    getEnvOrDefault("SMTP_ADDRESS", ""),
+			SMTPUsername:   getEnvOrDefault("SMTP_USERNAME", ""),
+			SMTPPassword:   getEnvOrDefault("SMTP_PASSWORD", ""),
+			SendGridAPIKey: getEnvOrDefault("SENDGRID_API_KEY", ""),
+		},
+		Digest: domain.DigestConfig{
+			Enabled:       getEnvOrDefault("WEEKLY_DIGEST_ENABLED", "false") == "true",
+			CheckInterval: getEnvDurationOrDefault("WEEKLY_DIGEST_CHECK_INTERVAL", time.Hour),
+		},
+		ProcessingRecovery: domain.ProcessingRecoveryConfig{
+			Enabled:       getEnvOrDefault("PROCESSING_RECOVERY_ENABLED", "true") == "true",
+			CheckInterval: getEnvDurationOrDefault("PROCESSING_RECOVERY_INTERVAL", time.Hour),
+			GracePeriod:   getEnvDurationOrDefault("PROCESSING_RECOVERY_GRACE_PERIOD", 30*time.Minute),
+		},
+		Push: domain.PushConfig{
+			FCMCredentialsFile: getEnvOrDefault("FCM_CREDENTIALS_FILE", ""),
+			APNsKeyFile:        getEnvOrDefault("APNS_KEY_FILE", ""),
+			APNsKeyID:          getEnvOrDefault("APNS_KEY_ID", ""),
+			APNsTeamID:         getEnvOrDefault("APNS_TEAM_ID", ""),
+			APNsTopic:          getEnvOrDefault("APNS_TOPIC", ""),
+			APNsSandbox:        getEnvOrDefault("APNS_SANDBOX", "false") == "true",
+		},
+		CloudImport: domain.CloudImportConfig{
+			RedirectURL:         getEnvOrDefault("CLOUD_IMPORT_REDIRECT_URL", ""),
+			GoogleClientID:      getEnvOrDefault("GOOGLE_DRIVE_CLIENT_ID", ""),
+			GoogleClientSecret:  getEnvOrDefault("GOOGLE_DRIVE_CLIENT_SECRET", ""),
+			DropboxClientID:     getEnvOrDefault("DROPBOX_CLIENT_ID", ""),
+			DropboxClientSecret: getEnvOrDefault("DROPBOX_CLIENT_SECRET", ""),
+		},
+		BookCatalog: domain.BookCatalogConfig{
+			GoogleBooksAPIKey: getEnvOrDefault("GOOGLE_BOOKS_API_KEY", ""),
+		},
+		Gutenberg: domain.GutenbergConfig{
+			CatalogURL: getEnvOrDefault("GUTENBERG_CATALOG_URL", "https://gutendex.com"),
+			Mirrors:    getEnvListOrDefault("GUTENBERG_MIRRORS", []string{"https://www.gutenberg.org", "https://aleph.gutenberg.org"}),
+		},
+
+		PageRender: domain.PageRenderConfig{
+			Enabled:  getEnvOrDefault("PAGE_RENDER_ENABLED", "false") == "true",
+			Scales:   getEnvFloatListOrDefault("PAGE_RENDER_SCALES", []float64{1, 2, 3}),
+			TileSize: int(getEnvInt64OrDefault("PAGE_RENDER_TILE_SIZE", 512)),
+			MaxPages: int(getEnvInt64OrDefault("PAGE_RENDER_MAX_PAGES", 1000)),
+		},
+
+		GraphQLEnabled:  getEnvOrDefault("GRAPHQL_ENABLED", "false") == "true",
+		MaintenanceMode: getEnvOrDefault("MAINTENANCE_MODE", "false") == "true",
+
+		StarterContentEnabled: getEnvOrDefault("STARTER_CONTENT_ENABLED", "false") == "true",
+
+		TrustedProxies: int(getEnvInt64OrDefault("TRUSTED_PROXIES", 0)),
+
+		Environment:        env,
+		CORSAllowedOrigins: getEnvListOrDefault("CORS_ALLOWED_ORIGINS", origins),
 	}
 }
 
@@ -36,11 +244,49 @@ func (c *AppConfig) GetServerPort() string {
 	return c.ServerPort
 }
 
+// GetGRPCPort returns the gRPC port, or "" when the gRPC API is disabled
+func (c *AppConfig) GetGRPCPort() string {
+	return c.GRPCPort
+}
+
+// GetGraphQLEnabled reports whether the /graphql endpoint is served
+func (c *AppConfig) GetGraphQLEnabled() bool {
+	return c.GraphQLEnabled
+}
+
+// GetStarterContentEnabled reports whether new accounts get the starter library
+func (c *AppConfig) GetStarterContentEnabled() bool {
+	return c.StarterContentEnabled
+}
+
+// GetMaintenanceMode reports whether the API starts in maintenance mode
+func (c *AppConfig) GetMaintenanceMode() bool {
+	return c.MaintenanceMode
+}
+
+// GetTrustedProxies returns how many reverse proxies append to X-Forwarded-For
+func (c *AppConfig) GetTrustedProxies() int {
+	return c.TrustedProxies
+}
+
 // GetMaxFileSize returns the maximum allowed file size
 func (c *AppConfig) GetMaxFileSize() int64 {
 	return c.MaxFileSize
 }
 
+// GetUploadLimits returns the configured upload caps; plan entitlements are applied on top
+func (c *AppConfig) GetUploadLimits() domain.UploadLimits {
+	return domain.UploadLimits{
+		MaxFileSize: c.MaxFileSize,
+		PerFormat:   c.FormatMaxFileSizes,
+	}
+}
+
+// GetPDFLimits returns the per-job PDF extraction limits
+func (c *AppConfig) GetPDFLimits() domain.PDFLimits {
+	return c.PDFLimits
+}
+
 // GetLogLevel returns the logging level
 func (c *AppConfig) GetLogLevel() string {
 	return c.LogLevel
@@ -61,6 +307,112 @@ func (c *AppConfig) GetJWTSecret() string {
 	return c.JWTSecret
 }
 
+// GetDatabaseURL returns the direct Postgres connection string (empty when unused)
+func (c *AppConfig) GetDatabaseURL() string {
+	return c.DatabaseURL
+}
+
+// GetRepositoryBackend returns the repository backend: "postgrest" (default) or "pgx"
+func (c *AppConfig) GetRepositoryBackend() string {
+	return c.RepositoryBackend
+}
+
+// GetScannerBackend returns the upload scanner backend (empty when scanning is disabled)
+func (c *AppConfig) GetScannerBackend() string {
+	return c.ScannerBackend
+}
+
+// GetClamAVAddress returns the clamd address, e.g. "tcp://localhost:3310"
+func (c *AppConfig) GetClamAVAddress() string {
+	return c.ClamAVAddress
+}
+
+// GetScannerURL returns the endpoint of the HTTP scanning API
+func (c *AppConfig) GetScannerURL() string {
+	return c.ScannerURL
+}
+
+// GetScannerAPIKey returns the bearer token for the HTTP scanning API
+func (c *AppConfig) GetScannerAPIKey() string {
+	return c.ScannerAPIKey
+}
+
+// GetStorageBackend returns the blob store backend: "supabase", "s3" or "local"
+func (c *AppConfig) GetStorageBackend() string {
+	return c.StorageBackend
+}
+
+// GetLocalStorageDir returns the directory used by the local storage backend
+func (c *AppConfig) GetLocalStorageDir() string {
+	return c.LocalStorageDir
+}
+
+// GetPublicURL returns the externally reachable base URL of this server
+func (c *AppConfig) GetPublicURL() string {
+	return c.PublicURL
+}
+
+// GetS3Config returns the settings of the s3 storage backend
+func (c *AppConfig) GetS3Config() domain.S3Config {
+	return c.S3
+}
+
+// GetSelfHosted reports whether the server runs without Supabase
+func (c *AppConfig) GetSelfHosted() bool {
+	return c.SelfHosted
+}
+
+// GetEmailConfig returns the outgoing e-mail settings
+func (c *AppConfig) GetEmailConfig() domain.EmailConfig {
+	return c.Email
+}
+
+// GetProcessingRecoveryConfig returns the lost-extraction recovery settings
+func (c *AppConfig) GetProcessingRecoveryConfig() domain.ProcessingRecoveryConfig {
+	return c.ProcessingRecovery
+}
+
+// GetDigestConfig returns the weekly reading digest settings
+func (c *AppConfig) GetDigestConfig() domain.DigestConfig {
+	return c.Digest
+}
+
+// GetPushConfig returns the push notification provider settings
+func (c *AppConfig) GetPushConfig() domain.PushConfig {
+	return c.Push
+}
+
+// GetCloudImportConfig returns the cloud drive import settings
+func (c *AppConfig) GetCloudImportConfig() domain.CloudImportConfig {
+	return c.CloudImport
+}
+
+// GetBookCatalogConfig returns the external book catalog settings
+func (c *AppConfig) GetBookCatalogConfig() domain.BookCatalogConfig {
+	return c.BookCatalog
+}
+
+// GetGutenbergConfig returns the Project Gutenberg catalog settings
+func (c *AppConfig) GetGutenbergConfig() domain.GutenbergConfig {
+	return c.Gutenberg
+}
+
+// GetPageRenderConfig returns the page rendering settings
+func (c *AppConfig) GetPageRenderConfig() domain.PageRenderConfig {
+	return c.PageRender
+}
+
+// GetEnvironment returns the deployment environment name
+func (c *AppConfig) GetEnvironment() string {
+	return c.Environment
+}
+
+// GetCORSAllowedOrigins returns the allowed CORS origins. Entries may contain a single
+// "*" wildcard, e.g. "https://*.vercel.app" for preview deployments.
+func (c *AppConfig) GetCORSAllowedOrigins() []string {
+	return c.CORSAllowedOrigins
+}
+
 // Helper functions for environment variable handling
 func getEnvOrDefault(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
@@ -77,3 +429,70 @@ func getEnvInt64OrDefault(key string, defaultValue int64) int64 {
 	}
 	return defaultValue
 }
+
+// getEnvDurationOrDefault parses a time.ParseDuration value such as "90s" or "5m".
+func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
+	if value := os.Getenv(key); value != "" {
+		if d, err := time.ParseDuration(value); err == nil && d >= 0 {
+			return d
+		}
+	}
+	return defaultValue
+}
+
+// getEnvListOrDefault parses a comma-separated list, dropping blanks and trailing slashes.
+func getEnvListOrDefault(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimRight(strings.TrimSpace(item), "/")
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return defaultValue
+	}
+	return items
+}
+
+// getEnvFloatListOrDefault parses a comma-separated list of positive numbers. Any
+// malformed entry falls back to the default.
+func getEnvFloatListOrDefault(key string, defaultValue []float64) []float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []float64
+	for _, item := range strings.Split(value, ",") {
+		f, err := strconv.ParseFloat(strings.TrimSpace(item), 64)
+		if err != nil || f <= 0 {
+			return defaultValue
+		}
+		items = append(items, f)
+	}
+	return items
+}
+
+// getEnvSizeMap parses "key=bytes" pairs separated by commas. Malformed pairs are skipped.
+func getEnvSizeMap(key string) map[string]int64 {
+	sizes := make(map[string]int64)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok {
+			continue
+		}
+		name = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "."))
+		size, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if name == "" || err != nil || size <= 0 {
+			continue
+		}
+		sizes[name] = size
+	}
+	return sizes
+}
@@ -1,23 +1,38 @@
 package config
 
-import "testing"
+import (
+	"strings"
+	"testing"
+	"time"
+)
 
-const defaultMaxFileSize int64 = 50 * 1024 * 1024
+const defaultMaxFileSize int64 = 200 * 1024 * 1024
 
 func TestNewConfig_Defaults(t *testing.T) {
 	t.Setenv("PORT", "")
 	t.Setenv("SERVER_PORT", "")
+	t.Setenv("GRPC_PORT", "")
 	t.Setenv("MAX_FILE_SIZE", "")
 	t.Setenv("LOG_LEVEL", "")
 	t.Setenv("SUPABASE_URL", "")
 	t.Setenv("SUPABASE_ANON_KEY", "")
 	t.Setenv("JWT_SECRET", "")
+	t.Setenv("DATABASE_URL", "")
+	t.Setenv("REPOSITORY_BACKEND", "")
+	t.Setenv("WEEKLY_DIGEST_ENABLED", "")
+	t.Setenv("WEEKLY_DIGEST_CHECK_INTERVAL", "")
+	t.Setenv("PROCESSING_RECOVERY_ENABLED", "")
+	t.Setenv("PROCESSING_RECOVERY_INTERVAL", "")
+	t.Setenv("PROCESSING_RECOVERY_GRACE_PERIOD", "")
 
 	cfg := NewConfig()
 
 	if cfg.GetServerPort() != "8080" {
 		t.Fatalf("expected default server port 8080, got %s", cfg.GetServerPort())
 	}
+	if cfg.GetGRPCPort() != "" {
+		t.Fatalf("expected the gRPC API to be disabled by default, got port %s", cfg.GetGRPCPort())
+	}
 	if cfg.GetMaxFileSize() != defaultMaxFileSize {
 		t.Fatalf("expected default max file size %d, got %d", defaultMaxFileSize, cfg.GetMaxFileSize())
 	}
@@ -33,6 +48,18 @@ func TestNewConfig_Defaults(t *testing.T) {
 	if cfg.GetJWTSecret() != "your-secret-key-change-in-production" {
 		t.Fatalf("expected default jwt secret, got %s", cfg.GetJWTSecret())
 	}
+	if cfg.GetDatabaseURL() != "" {
+		t.Fatalf("expected default database url empty, got %s", cfg.GetDatabaseURL())
+	}
+	if cfg.GetRepositoryBackend() != "postgrest" {
+		t.Fatalf("expected default repository backend postgrest, got %s", cfg.GetRepositoryBackend())
+	}
+	if digest := cfg.GetDigestConfig(); digest.Enabled || digest.CheckInterval != time.Hour {
+		t.Fatalf("expected the weekly digest off with an hourly check, got %+v", digest)
+	}
+	if recovery := cfg.GetProcessingRecoveryConfig(); !recovery.Enabled || recovery.CheckInterval != time.Hour || recovery.GracePeriod != 30*time.Minute {
+		t.Fatalf("expected processing recovery on, hourly, after 30 minutes, got %+v", recovery)
+	}
 }
 
 func TestNewConfig_Overrides(t *testing.T) {
@@ -43,6 +70,8 @@ func TestNewConfig_Overrides(t *testing.T) {
 	t.Setenv("SUPABASE_URL", "http://localhost:54321")
 	t.Setenv("SUPABASE_ANON_KEY", "test-key")
 	t.Setenv("JWT_SECRET", "secret")
+	t.Setenv("DATABASE_URL", "postgres://localhost:5432/postgres")
+	t.Setenv("REPOSITORY_BACKEND", "pgx")
 
 	cfg := NewConfig()
 
@@ -64,12 +93,21 @@ func TestNewConfig_Overrides(t *testing.T) {
 	if cfg.GetJWTSecret() != "secret" {
 		t.Fatalf("expected jwt secret secret, got %s", cfg.GetJWTSecret())
 	}
+	if cfg.GetDatabaseURL() != "postgres://localhost:5432/postgres" {
+		t.Fatalf("expected database url postgres://localhost:5432/postgres, got %s", cfg.GetDatabaseURL())
+	}
+	if cfg.GetRepositoryBackend() != "pgx" {
+		t.Fatalf("expected repository backend pgx, got %s", cfg.GetRepositoryBackend())
+	}
 }
 
 func TestNewConfig_Fallbacks(t *testing.T) {
 	t.Setenv("PORT", "")
 	t.Setenv("SERVER_PORT", "9091")
 	t.Setenv("MAX_FILE_SIZE", "not-a-number")
+	t.Setenv("PDF_MAX_PAGES", "")
+	t.Setenv("PDF_PROCESS_TIMEOUT", "soon")
+	t.Setenv("PAGE_RENDER_SCALES", "1,big")
 
 	cfg := NewConfig()
 
@@ -79,4 +117,139 @@ func TestNewConfig_Fallbacks(t *testing.T) {
 	if cfg.GetMaxFileSize() != defaultMaxFileSize {
 		t.Fatalf("expected default max file size %d, got %d", defaultMaxFileSize, cfg.GetMaxFileSize())
 	}
+	if limits := cfg.GetPDFLimits(); limits.MaxPages != 5000 || limits.Timeout != 5*time.Minute || limits.MaxConcurrent <= 0 {
+		t.Fatalf("expected default PDF limits, got %+v", limits)
+	}
+	if render := cfg.GetPageRenderConfig(); render.Enabled || len(render.Scales) != 3 || render.TileSize != 512 {
+		t.Fatalf("expected default page render settings, got %+v", render)
+	}
+}
+
+func TestNewConfig_Storage(t *testing.T) {
+	t.Setenv("PORT", "9092")
+	t.Setenv("STORAGE_BACKEND", "")
+	t.Setenv("PUBLIC_URL", "")
+	t.Setenv("S3_REGION", "")
+	t.Setenv("S3_PATH_STYLE", "")
+
+	cfg := NewConfig()
+	if cfg.GetStorageBackend() != "supabase" {
+		t.Fatalf("expected default storage backend supabase, got %s", cfg.GetStorageBackend())
+	}
+	if cfg.GetPublicURL() != "http://localhost:9092" {
+		t.Fatalf("expected public url on the server port, got %s", cfg.GetPublicURL())
+	}
+	if s3 := cfg.GetS3Config(); s3.Region != "us-east-1" || !s3.PathStyle {
+		t.Fatalf("unexpected s3 defaults: %+v", s3)
+	}
+
+	t.Setenv("STORAGE_BACKEND", "S3")
+	t.Setenv("S3_BUCKET", "books")
+	t.Setenv("S3_PATH_STYLE", "false")
+
+	cfg = NewConfig()
+	if cfg.GetStorageBackend() != "s3" {
+		t.Fatalf("expected storage backend s3, got %s", cfg.GetStorageBackend())
+	}
+	if s3 := cfg.GetS3Config(); s3.Bucket != "books" || s3.PathStyle {
+		t.Fatalf("unexpected s3 config: %+v", s3)
+	}
+}
+
+func TestNewConfig_CORSAllowedOrigins(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     string
+		origins string
+		want    []string
+	}{
+		{
+			name: "development defaults",
+			env:  "",
+			want: defaultCORSOrigins["development"],
+		},
+		{
+			name: "production defaults",
+			env:  "production",
+			want: []string{"https://lector.thefndrs.com"},
+		},
+		{
+			name: "unknown environment uses production defaults",
+			env:  "qa",
+			want: defaultCORSOrigins["production"],
+		},
+		{
+			name:    "explicit list",
+			env:     "production",
+			origins: " https://lector.thefndrs.com/ , https://*.vercel.app,,",
+			want:    []string{"https://lector.thefndrs.com", "https://*.vercel.app"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("APP_ENV", tt.env)
+			t.Setenv("CORS_ALLOWED_ORIGINS", tt.origins)
+
+			got := NewConfig().GetCORSAllowedOrigins()
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Fatalf("expected origins %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewConfig_UploadLimits(t *testing.T) {
+	t.Setenv("MAX_FILE_SIZE", "1000")
+	t.Setenv("MAX_FILE_SIZE_BY_FORMAT", "pdf=500, .EPUB=200,txt=bad,cbz")
+
+	limits := NewConfig().GetUploadLimits()
+
+	if limits.MaxFileSize != 1000 {
+		t.Fatalf("expected max file size 1000, got %d", limits.MaxFileSize)
+	}
+	if limits.PerFormat["pdf"] != 500 || limits.PerFormat["epub"] != 200 {
+		t.Fatalf("unexpected per-format limits: %v", limits.PerFormat)
+	}
+	if _, ok := limits.PerFormat["txt"]; ok {
+		t.Fatalf("expected malformed txt limit to be skipped")
+	}
+}
+
+func TestNewConfig_SelfHosted(t *testing.T) {
+	t.Setenv("SELF_HOSTED", "true")
+	t.Setenv("REPOSITORY_BACKEND", "")
+	t.Setenv("STORAGE_BACKEND", "")
+	t.Setenv("DATABASE_URL", "postgres://lector@localhost/lector")
+	t.Setenv("JWT_SECRET", "")
+
+	cfg := NewConfig()
+	if !cfg.GetSelfHosted() {
+		t.Fatalf("expected self-hosted mode")
+	}
+	if cfg.GetRepositoryBackend() != "pgx" {
+		t.Fatalf("expected repository backend pgx, got %s", cfg.GetRepositoryBackend())
+	}
+	if cfg.GetStorageBackend() != "local" {
+		t.Fatalf("expected storage backend local, got %s", cfg.GetStorageBackend())
+	}
+	if err := validateSelfHosted(cfg); err == nil {
+		t.Fatalf("expected the default JWT secret to be rejected")
+	}
+
+	t.Setenv("JWT_SECRET", strings.Repeat("s", 32))
+	if err := validateSelfHosted(NewConfig()); err != nil {
+		t.Fatalf("expected valid self-hosted config, got %v", err)
+	}
+
+	t.Setenv("STORAGE_BACKEND", "supabase")
+	if err := validateSelfHosted(NewConfig()); err == nil {
+		t.Fatalf("expected supabase storage to be rejected")
+	}
+
+	t.Setenv("STORAGE_BACKEND", "")
+	t.Setenv("DATABASE_URL", "")
+	if err := validateSelfHosted(NewConfig()); err == nil {
+		t.Fatalf("expected missing DATABASE_URL to be rejected")
+	}
 }
@@ -1,23 +1,65 @@
 package config
 
 import (
+	"context"
+	"fmt"
+
 	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/blobstore"
+	"pdf-text-reader/internal/infra/bookcatalog"
+	"pdf-text-reader/internal/infra/clouddrive"
+	"pdf-text-reader/internal/infra/email"
+	"pdf-text-reader/internal/infra/gutenberg"
+	"pdf-text-reader/internal/infra/postgres"
+	"pdf-text-reader/internal/infra/push"
+	"pdf-text-reader/internal/infra/scanner"
 	"pdf-text-reader/internal/infra/supabase"
+	"pdf-text-reader/internal/infra/webpage"
 	"pdf-text-reader/internal/repository"
 	"pdf-text-reader/internal/service"
 	"pdf-text-reader/pkg/logger"
+
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 // Container holds all application dependencies
 type Container struct {
 	Config                 domain.Config
 	Logger                 domain.Logger
-	SupabaseClient         domain.SupabaseClient
+	SupabaseClient         domain.SupabaseClient // Nil when self-hosted
 	DocumentService        domain.DocumentService
 	AuthService            domain.AuthService
-	StorageService         domain.StorageService
+	LocalAuthService       domain.LocalAuthService // Set only when self-hosted
+	BlobStore              domain.BlobStore
 	UserPreferencesService domain.UserPreferencesService
+	FontService            domain.FontService
+	ThemeService           domain.ThemeService
+	SavedSearchService     domain.SavedSearchService
 	HighlightService       domain.HighlightService
+	HighlightImportService domain.HighlightImportService
+	RecommendationService  domain.RecommendationService
+	CatalogService         domain.CatalogService
+	EnrichmentService      domain.EnrichmentService
+	DuplicateService       domain.DuplicateService
+	LocatorService         domain.LocatorService
+	ShareCardService       domain.ShareCardService
+	PageRenderService      domain.PageRenderService
+	AuthorizationService   domain.AuthorizationService
+	Migrator               domain.SchemaMigrator // Nil without DATABASE_URL
+	PDFMetrics             domain.PDFProcessingMetrics
+	OnboardingService      domain.OnboardingService         // Nil unless starter content is enabled
+	DigestService          domain.DigestService             // Nil unless the weekly digest is enabled
+	RecoveryService        domain.ProcessingRecoveryService // Nil unless processing recovery can run
+	NotificationService    domain.NotificationService
+	EventBroker            domain.EventBroker
+	AbuseMonitor           domain.AbuseMonitor
+	Maintenance            domain.MaintenanceMode
+	CloudImportService     domain.CloudImportService
+	CalibreImportService   domain.CalibreImportService
+	GutenbergService       domain.GutenbergService
+	ReadLaterImportService domain.ReadLaterImportService
+
+	closers []func()
 }
 
 // NewContainer creates a new dependency injection container
@@ -25,57 +67,178 @@ func NewContainer() *Container {
 	cfg := NewConfig()
 	log := logger.NewLogger(cfg.GetLogLevel())
 
-	// Supabase client
-	supabaseClient := supabase.NewSupabaseClient(cfg, log)
-	if err := supabaseClient.Initialize(); err != nil {
-		log.Error("Failed to initialize Supabase client", err)
+	if err := validateSelfHosted(cfg); err != nil {
+		log.Error("Invalid self-hosted configuration", err)
 		panic(err)
 	}
 
+	var closers []func()
+
+	// Direct Postgres access: every repository goes through pgx instead of PostgREST.
+	var pool *pgxpool.Pool
+	if cfg.GetRepositoryBackend() == "pgx" {
+		var err error
+		pool, err = postgres.NewPool(context.Background(), cfg.GetDatabaseURL())
+		if err != nil {
+			log.Error("Failed to initialize Postgres pool", err)
+			panic(err)
+		}
+		closers = append(closers, pool.Close)
+		log.Info("Using pgx repository backend")
+
+	}
+
+	// Schema migrations; the PostgREST backend connects only while migrating.
+	var migrator domain.SchemaMigrator
+	if cfg.GetDatabaseURL() != "" {
+		migrator = postgres.NewMigrator(pool, cfg.GetDatabaseURL(), log)
+	}
+
+	// Self-hosted databases are always kept on the latest schema.
+	if cfg.GetSelfHosted() {
+		if err := postgres.EnsureSelfHosted(context.Background(), pool); err != nil {
+			log.Error("Failed to prepare self-hosted database", err)
+			panic(err)
+		}
+		if _, err := migrator.Migrate(context.Background()); err != nil {
+			log.Error("Failed to migrate self-hosted database", err)
+			panic(err)
+		}
+	}
+
+	// Supabase client (not used when self-hosted)
+	var supabaseClient domain.SupabaseClient
+	if !cfg.GetSelfHosted() {
+		supabaseClient = supabase.NewSupabaseClient(cfg, log)
+		if err := supabaseClient.Initialize(); err != nil {
+			log.Error("Failed to initialize Supabase client", err)
+			panic(err)
+		}
+	}
+
 	// Repositories
-	documentRepo := repository.NewDocumentRepository(
-		supabaseClient,
-		log,
-	)
+	repos := newRepositories(pool, supabaseClient, log)
 
-	preferenceRepo := repository.NewUserPreferencesRepository(
-		supabaseClient,
-		log,
-	)
+	// Services
+
+	authorizationService := service.NewAuthorizationService(log)
 
-	highlightRepo := repository.NewHighlightRepository(
-		supabaseClient,
+	uploadScanner, err := scanner.New(cfg, log)
+	if err != nil {
+		log.Error("Failed to initialize upload scanner", err)
+		panic(err)
+	}
+
+	blobStore, err := blobstore.New(cfg, log)
+	if err != nil {
+		log.Error("Failed to initialize blob store", err)
+		panic(err)
+	}
+
+	pushSender, err := push.New(cfg.GetPushConfig())
+	if err != nil {
+		log.Error("Failed to initialize push notifications", err)
+		panic(err)
+	}
+	if pushSender == nil {
+		log.Warn("No push provider configured; device notifications are disabled")
+	}
+	notificationService := service.NewNotificationService(repos.devices, pushSender, log)
+	eventBroker := service.NewEventBroker(log)
+	abuseMonitor := service.NewAbuseMonitor(service.DefaultAbuseLimits(), log)
+
+	documentService := service.NewDocumentService(
+		repos.documents,
+		repos.preferences,
+		repos.versions,
+		blobStore,
+		authorizationService,
+		cfg.GetUploadLimits(),
+		cfg.GetPDFLimits(),
+		uploadScanner,
+		service.EventPublishers{eventBroker, notificationService},
 		log,
 	)
 
-	// Services
+	cloudDrives, err := clouddrive.New(cfg.GetCloudImportConfig())
+	if err != nil {
+		log.Error("Failed to initialize cloud drive import", err)
+		panic(err)
+	}
+	importJobs := service.NewImportJobTracker()
+	cloudImportService := service.NewCloudImportService(cloudDrives, repos.cloudConnections, documentService, importJobs, log)
+	calibreImportService := service.NewCalibreImportService(documentService, repos.documents, importJobs, log)
+	gutenbergService := service.NewGutenbergService(gutenberg.New(cfg.GetGutenbergConfig()), documentService, log)
+	readLaterImportService := service.NewReadLaterImportService(documentService, repos.documents, webpage.New(), importJobs, log)
 
-	storageService := service.NewStorageService(
-		cfg.GetSupabaseURL(),
-		cfg.GetSupabaseKey(),
-	)
+	// Self-hosted servers issue their own tokens instead of relying on Supabase Auth.
+	var authService domain.AuthService
+	var localAuthService domain.LocalAuthService
+	if cfg.GetSelfHosted() {
+		localAuthService = service.NewLocalAuthService(
+			repository.NewPgUserRepository(pool, log),
+			repos.preferences,
+			cfg.GetJWTSecret(),
+			log,
+		)
+		authService = localAuthService
+		log.Info("Running self-hosted with local authentication")
+	} else {
+		authService = service.NewAuthService(
+			supabaseClient,
+			log,
+		)
+	}
 
-	documentService := service.NewDocumentService(
-		documentRepo,
-		preferenceRepo,
-		storageService,
+	userPreferencesService := service.NewUserPreferencesService(
+		repos.preferences,
+		documentService,
 		log,
 	)
 
-	authService := service.NewAuthService(
-		supabaseClient,
+	fontService := service.NewFontService(
+		repos.fonts,
+		repos.preferences,
+		blobStore,
 		log,
 	)
 
-	userPreferencesService := service.NewUserPreferencesService(
-		preferenceRepo,
+	themeService := service.NewThemeService(
+		repos.themes,
+		repos.preferences,
 		log,
 	)
 
+	savedSearchService := service.NewSavedSearchService(repos.savedSearches, documentService, log)
+
 	highlightService := service.NewHighlightService(
-		highlightRepo,
+		repos.highlights,
+		repos.documents,
+		repos.preferences,
+		authorizationService,
+		log,
+	)
+	highlightImportService := service.NewHighlightImportService(repos.highlights, repos.documents, repos.preferences, authorizationService, log)
+
+	var onboardingService domain.OnboardingService
+	if cfg.GetStarterContentEnabled() {
+		onboardingService = service.NewOnboardingService(repos.onboarding, documentService, highlightService, log)
+	}
+
+	recommendationService := service.NewRecommendationService(
+		repos.documents,
+		repos.preferences,
 		log,
 	)
+	catalogService := service.NewCatalogService(repos.documents, log)
+	duplicateService := service.NewDuplicateService(documentService, repos.highlights, repos.preferences, log)
+	locatorService := service.NewLocatorService(documentService, repos.highlights, repos.preferences, log)
+	shareCardService := service.NewShareCardService(documentService, repos.highlights, log)
+	pageRenderService := service.NewPageRenderService(documentService, cfg.GetPageRenderConfig(), log)
+	enrichmentService := service.NewEnrichmentService(documentService, bookcatalog.New(cfg.GetBookCatalogConfig()), log)
+
+	digestService := newDigestService(cfg, pool, log)
+	recoveryService := newRecoveryService(cfg, pool, documentService, log)
 
 	return &Container{
 		Config:                 cfg,
@@ -83,8 +246,158 @@ func NewContainer() *Container {
 		SupabaseClient:         supabaseClient,
 		DocumentService:        documentService,
 		AuthService:            authService,
-		StorageService:         storageService,
+		LocalAuthService:       localAuthService,
+		BlobStore:              blobStore,
 		UserPreferencesService: userPreferencesService,
+		FontService:            fontService,
+		ThemeService:           themeService,
+		SavedSearchService:     savedSearchService,
 		HighlightService:       highlightService,
+		HighlightImportService: highlightImportService,
+		RecommendationService:  recommendationService,
+		CatalogService:         catalogService,
+		EnrichmentService:      enrichmentService,
+		DuplicateService:       duplicateService,
+		LocatorService:         locatorService,
+		ShareCardService:       shareCardService,
+		PageRenderService:      pageRenderService,
+		AuthorizationService:   authorizationService,
+		Migrator:               migrator,
+		PDFMetrics:             documentService,
+		OnboardingService:      onboardingService,
+		DigestService:          digestService,
+		RecoveryService:        recoveryService,
+		NotificationService:    notificationService,
+		EventBroker:            eventBroker,
+		AbuseMonitor:           abuseMonitor,
+		Maintenance:            service.NewMaintenanceMode(),
+		CloudImportService:     cloudImportService,
+		CalibreImportService:   calibreImportService,
+		GutenbergService:       gutenbergService,
+		ReadLaterImportService: readLaterImportService,
+		closers:                closers,
+	}
+}
+
+// repositories are the data access implementations shared by the services.
+type repositories struct {
+	documents        domain.DocumentRepository
+	preferences      domain.UserPreferencesRepository
+	versions         domain.DocumentVersionRepository
+	fonts            domain.FontRepository
+	themes           domain.ThemeRepository
+	savedSearches    domain.SavedSearchRepository
+	highlights       domain.HighlightRepository
+	onboarding       domain.OnboardingRepository
+	devices          domain.DeviceRepository
+	cloudConnections domain.CloudConnectionRepository
+}
+
+// newRepositories serves every repository over pgx when a pool is configured and
+// over PostgREST otherwise.
+func newRepositories(pool *pgxpool.Pool, supabaseClient domain.SupabaseClient, log domain.Logger) repositories {
+	if pool != nil {
+		return repositories{
+			documents:        repository.NewPgDocumentRepository(pool, log),
+			preferences:      repository.NewPgUserPreferencesRepository(pool, log),
+			versions:         repository.NewPgDocumentVersionRepository(pool, log),
+			fonts:            repository.NewPgFontRepository(pool, log),
+			themes:           repository.NewPgThemeRepository(pool, log),
+			savedSearches:    repository.NewPgSavedSearchRepository(pool, log),
+			highlights:       repository.NewPgHighlightRepository(pool, log),
+			onboarding:       repository.NewPgOnboardingRepository(pool, log),
+			devices:          repository.NewPgDeviceRepository(pool, log),
+			cloudConnections: repository.NewPgCloudConnectionRepository(pool, log),
+		}
+	}
+	return repositories{
+		documents:        repository.NewDocumentRepository(supabaseClient, log),
+		preferences:      repository.NewUserPreferencesRepository(supabaseClient, log),
+		versions:         repository.NewDocumentVersionRepository(supabaseClient, log),
+		fonts:            repository.NewFontRepository(supabaseClient, log),
+		themes:           repository.NewThemeRepository(supabaseClient, log),
+		savedSearches:    repository.NewSavedSearchRepository(supabaseClient, log),
+		highlights:       repository.NewHighlightRepository(supabaseClient, log),
+		onboarding:       repository.NewOnboardingRepository(supabaseClient, log),
+		devices:          repository.NewDeviceRepository(supabaseClient, log),
+		cloudConnections: repository.NewCloudConnectionRepository(supabaseClient, log),
+	}
+}
+
+// newDigestService builds the weekly digest job, or returns nil when it is disabled.
+// The digest reads every opted-in user's activity, which only the pgx backend can do
+// with the server's own connection.
+func newDigestService(cfg domain.Config, pool *pgxpool.Pool, log domain.Logger) domain.DigestService {
+	if !cfg.GetDigestConfig().Enabled {
+		return nil
+	}
+	if pool == nil {
+		err := fmt.Errorf("the weekly digest requires REPOSITORY_BACKEND=pgx")
+		log.Error("Failed to initialize weekly digest", err)
+		panic(err)
+	}
+	sender, err := email.New(cfg.GetEmailConfig())
+	if err != nil {
+		log.Error("Failed to initialize e-mail sender", err)
+		panic(err)
+	}
+	return service.NewDigestService(
+		repository.NewPgDigestRepository(pool, log),
+		sender,
+		cfg.GetDigestConfig(),
+		log,
+	)
+}
+
+// newRecoveryService builds the lost-extraction recovery job, or returns nil when it
+// is disabled or cannot run. Recovery reprocesses other users' documents, so it needs
+// the pgx backend to find them and a storage backend that does not depend on the
+// owner's access token.
+func newRecoveryService(cfg domain.Config, pool *pgxpool.Pool, documents *service.DocumentService, log domain.Logger) domain.ProcessingRecoveryService {
+	recovery := cfg.GetProcessingRecoveryConfig()
+	if !recovery.Enabled {
+		return nil
+	}
+	switch backend := cfg.GetStorageBackend(); {
+	case pool == nil:
+		log.Info("Processing recovery disabled: it requires REPOSITORY_BACKEND=pgx")
+		return nil
+	case backend != "s3" && backend != "local":
+		log.Info("Processing recovery disabled: it requires STORAGE_BACKEND=s3 or local", "storage_backend", backend)
+		return nil
+	}
+	return service.NewProcessingRecoveryService(
+		repository.NewPgProcessingRecoveryRepository(pool, log),
+		documents,
+		recovery,
+		log,
+	)
+}
+
+// validateSelfHosted rejects self-hosted settings that would still need Supabase or
+// would sign tokens with the well-known default secret.
+func validateSelfHosted(cfg domain.Config) error {
+	if !cfg.GetSelfHosted() {
+		return nil
+	}
+	if cfg.GetRepositoryBackend() != "pgx" {
+		return fmt.Errorf("self-hosted mode requires REPOSITORY_BACKEND=pgx, got %q", cfg.GetRepositoryBackend())
+	}
+	if cfg.GetDatabaseURL() == "" {
+		return fmt.Errorf("self-hosted mode requires DATABASE_URL")
+	}
+	if backend := cfg.GetStorageBackend(); backend == "" || backend == "supabase" {
+		return fmt.Errorf("self-hosted mode cannot use the supabase storage backend")
+	}
+	if secret := cfg.GetJWTSecret(); secret == defaultJWTSecret || len(secret) < 32 {
+		return fmt.Errorf("self-hosted mode requires a JWT_SECRET of at least 32 characters")
+	}
+	return nil
+}
+
+// Close releases resources held by the container (e.g. database pools)
+func (c *Container) Close() {
+	for _, closeFn := range c.closers {
+		closeFn()
 	}
 }
@@ -0,0 +1,42 @@
+package domain
+
+import "time"
+
+// AbuseSignal is a kind of request counted to detect scripted abuse.
+type AbuseSignal string
+
+const (
+	AbuseSignalUpload      AbuseSignal = "upload"       // uploads and imports
+	AbuseSignalAuthFailure AbuseSignal = "auth_failure" // requests rejected with 401
+)
+
+// AbuseLimit is how many events of a signal a user or IP may cause within Window.
+// Going over it throttles them for Cooldown.
+type AbuseLimit struct {
+	Max      int
+	Window   time.Duration
+	Cooldown time.Duration
+}
+
+// Throttle is a temporary block placed on a user or IP that went over a limit.
+type Throttle struct {
+	Key    string      `json:"key"` // "user:<id>" or "ip:<address>"
+	Signal AbuseSignal `json:"signal"`
+	Count  int         `json:"count"` // Events within the window when the throttle started
+	Since  time.Time   `json:"since"`
+	Until  time.Time   `json:"until"`
+}
+
+// AbuseMonitor counts requests per user and IP and throttles those that go over
+// their limits.
+type AbuseMonitor interface {
+	// Record counts an event of signal for key and returns how long key is now
+	// throttled for it, or zero.
+	Record(signal AbuseSignal, key string) time.Duration
+	// Throttled returns how long key is still throttled for signal, or zero.
+	Throttled(signal AbuseSignal, key string) time.Duration
+	// Throttles lists the active throttles, newest first.
+	Throttles() []Throttle
+	// Release lifts every throttle on key and reports whether there was one.
+	Release(key string) bool
+}
@@ -1,6 +1,38 @@
 package domain
 
+import "context"
+
 type AuthService interface {
-	ValidateToken(token string) (*SupabaseUser, error)
-	IsAccountDisabled(userID string, token string) (bool, error)
+	ValidateToken(ctx context.Context, token string) (*SupabaseUser, error)
+	IsAccountDisabled(ctx context.Context, userID string, token string) (bool, error)
+}
+
+// MinPasswordLength is the shortest password the local auth backend accepts.
+const MinPasswordLength = 8
+
+// AuthSession is returned by the local auth backend on sign-up and sign-in. Its JSON
+// shape follows the Supabase session so clients can handle both backends alike.
+type AuthSession struct {
+	AccessToken string        `json:"access_token"`
+	TokenType   string        `json:"token_type"`
+	ExpiresIn   int64         `json:"expires_in"` // Seconds
+	ExpiresAt   int64         `json:"expires_at"` // Unix seconds
+	User        *SupabaseUser `json:"user"`
+}
+
+// LocalAuthService issues and validates the server's own JWTs when running
+// self-hosted, without Supabase Auth.
+type LocalAuthService interface {
+	AuthService
+	Register(ctx context.Context, email, password, name string) (*AuthSession, error)
+	Login(ctx context.Context, email, password string) (*AuthSession, error)
+}
+
+// UserRepository stores the accounts of the local auth backend. Lookups run before
+// the caller is authenticated, so they take no principal.
+type UserRepository interface {
+	Create(ctx context.Context, user *User) error
+	// GetByEmail and GetByID return ErrUserNotFound when there is no such account.
+	GetByEmail(ctx context.Context, email string) (*User, error)
+	GetByID(ctx context.Context, id string) (*User, error)
 }
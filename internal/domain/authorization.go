@@ -0,0 +1,49 @@
+package domain
+
+import "context"
+
+// Role is the relationship between a principal and a resource.
+type Role string
+
+const (
+	RoleNone         Role = ""
+	RoleOwner        Role = "owner"
+	RoleCollaborator Role = "collaborator"
+	RoleAdmin        Role = "admin"
+)
+
+// Permission is an action a principal can take on a resource.
+type Permission string
+
+const (
+	PermissionRead     Permission = "read"
+	PermissionAnnotate Permission = "annotate" // highlights, favorites, reading progress
+	PermissionWrite    Permission = "write"    // title, author, tags
+	PermissionDelete   Permission = "delete"
+)
+
+// rolePermissions lists what each role is allowed to do on a document.
+var rolePermissions = map[Role][]Permission{
+	RoleOwner:        {PermissionRead, PermissionAnnotate, PermissionWrite, PermissionDelete},
+	RoleCollaborator: {PermissionRead, PermissionAnnotate},
+	RoleAdmin:        {PermissionRead, PermissionDelete},
+}
+
+// Can reports whether the role grants perm.
+func (r Role) Can(perm Permission) bool {
+	for _, p := range rolePermissions[r] {
+		if p == perm {
+			return true
+		}
+	}
+	return false
+}
+
+// AuthorizationService decides what a principal may do with a resource. Handlers and
+// services go through it instead of comparing user IDs themselves.
+type AuthorizationService interface {
+	// DocumentRole returns the principal's role on the document.
+	DocumentRole(ctx context.Context, principal Principal, document *Document) Role
+	// AuthorizeDocument returns ErrAccessDenied unless the principal's role grants perm.
+	AuthorizeDocument(ctx context.Context, principal Principal, document *Document, perm Permission) error
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+// AuthorSeparator joins the names of a document with several authors.
+const AuthorSeparator = " & "
+
+// Author groups the documents written by one person. Authors are derived from the
+// documents' author field: spelling variants that only differ in case, spacing or
+// punctuation share an ID.
+type Author struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	DocumentCount int             `json:"document_count"`
+	Documents     []*DocumentData `json:"documents,omitempty"`
+}
+
+// Series groups the documents of a book series, derived from metadata.series.
+type Series struct {
+	ID            string          `json:"id"`
+	Name          string          `json:"name"`
+	DocumentCount int             `json:"document_count"`
+	Documents     []*DocumentData `json:"documents,omitempty"` // in series order
+}
+
+// SplitAuthors returns the names in a document's author field, which holds several
+// authors separated by " & " or ";".
+func SplitAuthors(author string) []string {
+	var names []string
+	for _, part := range strings.Split(strings.ReplaceAll(author, ";", AuthorSeparator), AuthorSeparator) {
+		if part = strings.TrimSpace(part); part != "" {
+			names = append(names, part)
+		}
+	}
+	return names
+}
+
+// CatalogKey is the ID of the author or series with the given name: its letters and
+// digits lower-cased, words joined by "-".
+func CatalogKey(name string) string {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(words, "-")
+}
+
+// CatalogService browses the library by author and series. Renaming and merging
+// rewrite the documents, so the grouping follows.
+type CatalogService interface {
+	// ListAuthors returns every author without their documents, by name.
+	ListAuthors(ctx context.Context, principal Principal) ([]*Author, error)
+	// GetAuthor returns the author with their documents, or ErrAuthorNotFound.
+	GetAuthor(ctx context.Context, principal Principal, id string) (*Author, error)
+	RenameAuthor(ctx context.Context, principal Principal, id, name string) (*Author, error)
+	// MergeAuthors renames the given authors to the name of the author they merge into.
+	MergeAuthors(ctx context.Context, principal Principal, ids []string, intoID string) (*Author, error)
+
+	ListSeries(ctx context.Context, principal Principal) ([]*Series, error)
+	// GetSeries returns the series with its documents, or ErrSeriesNotFound.
+	GetSeries(ctx context.Context, principal Principal, id string) (*Series, error)
+	RenameSeries(ctx context.Context, principal Principal, id, name string) (*Series, error)
+	MergeSeries(ctx context.Context, principal Principal, ids []string, intoID string) (*Series, error)
+}
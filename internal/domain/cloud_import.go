@@ -0,0 +1,169 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Cloud drives documents can be imported from.
+const (
+	CloudProviderGoogleDrive = "google-drive"
+	CloudProviderDropbox     = "dropbox"
+)
+
+// MaxImportFiles bounds how many files one import job transfers.
+const MaxImportFiles = 50
+
+// CloudFile is a PDF or EPUB in the user's cloud drive.
+type CloudFile struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModifiedAt time.Time `json:"modified_at"`
+}
+
+// CloudToken is the OAuth token a user granted for their cloud drive.
+type CloudToken struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time // zero when the access token does not expire
+}
+
+// Expired reports whether the access token is expired or about to be.
+func (t *CloudToken) Expired() bool {
+	return !t.Expiry.IsZero() && time.Now().Add(time.Minute).After(t.Expiry)
+}
+
+// CloudDrive is an OAuth connector to one cloud drive provider.
+type CloudDrive interface {
+	// AuthURL returns the consent page the user is sent to; the provider redirects
+	// back to the configured redirect URL with a code and the given state.
+	AuthURL(state string) string
+	// Exchange trades the code from the redirect for a token.
+	Exchange(ctx context.Context, code string) (*CloudToken, error)
+	// Refresh returns a new access token for an expired one.
+	Refresh(ctx context.Context, token *CloudToken) (*CloudToken, error)
+	// ListFiles returns the PDFs and EPUBs whose name contains query, newest first.
+	ListFiles(ctx context.Context, token *CloudToken, query string) ([]CloudFile, error)
+	// Download opens a file's content; the caller closes it.
+	Download(ctx context.Context, token *CloudToken, fileID string) (*CloudFile, io.ReadCloser, error)
+}
+
+// CloudImportConfig configures the cloud drive connectors. A provider is enabled
+// when its client ID and secret are set.
+type CloudImportConfig struct {
+	// RedirectURL is the web app page the providers send the user back to; it must
+	// be registered with every provider.
+	RedirectURL         string
+	GoogleClientID      string
+	GoogleClientSecret  string
+	DropboxClientID     string
+	DropboxClientSecret string
+}
+
+// CloudConnection is a user's authorised link to a cloud drive.
+type CloudConnection struct {
+	UserID    string
+	Provider  string
+	Token     CloudToken
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CloudConnectionRepository interface {
+	// Get returns ErrCloudNotConnected when the user has not connected the provider.
+	Get(ctx context.Context, principal Principal, provider string) (*CloudConnection, error)
+	Upsert(ctx context.Context, principal Principal, connection *CloudConnection) error
+	Delete(ctx context.Context, principal Principal, provider string) error
+}
+
+// Import job and file statuses.
+const (
+	ImportStatusPending = "pending"
+	ImportStatusRunning = "running"
+	ImportStatusDone    = "done"
+	ImportStatusFailed  = "failed"
+)
+
+// ImportFile is the transfer of one file within an import job.
+type ImportFile struct {
+	FileID     string `json:"file_id"`
+	Name       string `json:"name,omitempty"`
+	Status     string `json:"status"`
+	DocumentID string `json:"document_id,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// ImportJob transfers the selected files of a cloud drive into the library in the
+// background. Status is done once every file was tried, even when some failed.
+type ImportJob struct {
+	ID        string       `json:"id"`
+	UserID    string       `json:"user_id"`
+	Provider  string       `json:"provider"`
+	Status    string       `json:"status"`
+	Files     []ImportFile `json:"files"`
+	Completed int          `json:"completed"`
+	Failed    int          `json:"failed"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+type CloudImportService interface {
+	// Providers lists the configured providers.
+	Providers() []string
+	AuthURL(provider, state string) (string, error)
+	Connect(ctx context.Context, principal Principal, provider, code string) error
+	Disconnect(ctx context.Context, principal Principal, provider string) error
+	ListFiles(ctx context.Context, principal Principal, provider, query string) ([]CloudFile, error)
+	// StartImport queues the files for transfer and returns the job right away.
+	StartImport(ctx context.Context, principal Principal, provider string, fileIDs []string) (*ImportJob, error)
+	// GetImportJob returns ErrImportJobNotFound for jobs of other users.
+	GetImportJob(ctx context.Context, principal Principal, jobID string) (*ImportJob, error)
+}
+
+// ImportedBook is the library metadata of a book imported from another reading app.
+type ImportedBook struct {
+	Title       string   `json:"title"`
+	Authors     []string `json:"authors,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Series      string   `json:"series,omitempty"`
+	SeriesIndex float64  `json:"series_index,omitempty"`
+	ISBN        string   `json:"isbn,omitempty"`
+	// SourceURL is the original address of a web article.
+	SourceURL string `json:"source_url,omitempty"`
+	// Source names the app the book came from; it is stored as metadata.source.
+	Source string `json:"-"`
+	// Cover is the cover image chosen in the source app, if any.
+	Cover     []byte `json:"-"`
+	CoverType string `json:"-"`
+}
+
+// MaxCalibreArchiveSize bounds the zipped Calibre library accepted by an import.
+const MaxCalibreArchiveSize int64 = 2 << 30 // 2GB
+
+// CalibreBook is one book found in a Calibre library export.
+type CalibreBook struct {
+	ImportedBook
+	Path     string `json:"path"`             // book directory within the export
+	Format   string `json:"format,omitempty"` // format that will be imported
+	HasCover bool   `json:"has_cover"`
+	// Skipped explains why the book cannot be imported; such books are left out.
+	Skipped string `json:"skipped,omitempty"`
+}
+
+// CalibrePreview is the dry run of a Calibre import.
+type CalibrePreview struct {
+	Books []CalibreBook `json:"books"`
+	// NewTags are the Calibre tags that will be added to the user's tags.
+	NewTags []string `json:"new_tags"`
+}
+
+type CalibreImportService interface {
+	// Preview lists what importing the Calibre library export (a zip of the library
+	// folder) would add, without changing anything.
+	Preview(ctx context.Context, principal Principal, archive io.Reader) (*CalibrePreview, error)
+	// Import queues every importable book of the export and returns the job right away.
+	Import(ctx context.Context, principal Principal, archive io.Reader) (*ImportJob, error)
+}
@@ -0,0 +1,53 @@
+package domain
+
+// Change block types, describing how a run of paragraphs differs between the left
+// (base) and right documents.
+const (
+	ChangeEqual   = "equal"
+	ChangeInsert  = "insert"
+	ChangeDelete  = "delete"
+	ChangeReplace = "replace"
+)
+
+// Page comparison statuses.
+const (
+	PageUnchanged = "unchanged"
+	PageChanged   = "changed"
+	PageAdded     = "added"   // Page only exists in the right document
+	PageRemoved   = "removed" // Page only exists in the left document
+)
+
+// ChangeBlock is a run of consecutive paragraphs with the same change type. Left and
+// Right hold the paragraph text on each side; positions locate the first paragraph
+// of the run within its page.
+type ChangeBlock struct {
+	Type          string   `json:"type"`
+	Left          []string `json:"left,omitempty"`
+	Right         []string `json:"right,omitempty"`
+	LeftPosition  int      `json:"left_position"`
+	RightPosition int      `json:"right_position"`
+}
+
+// PageComparison is the diff of one page number across both documents. Unchanged
+// pages carry no change blocks.
+type PageComparison struct {
+	Page    int           `json:"page"` // 1-indexed, shared by both documents
+	Status  string        `json:"status"`
+	Changes []ChangeBlock `json:"changes,omitempty"`
+}
+
+// ComparisonSummary counts differences across the whole comparison.
+type ComparisonSummary struct {
+	PagesChanged       int `json:"pages_changed"`
+	ParagraphsInserted int `json:"paragraphs_inserted"`
+	ParagraphsDeleted  int `json:"paragraphs_deleted"`
+	ParagraphsReplaced int `json:"paragraphs_replaced"` // Left-side paragraphs in replace blocks
+}
+
+// DocumentComparison is a page-aligned text diff between two documents.
+type DocumentComparison struct {
+	LeftDocumentID  string            `json:"left_document_id"`
+	RightDocumentID string            `json:"right_document_id"`
+	Summary         ComparisonSummary `json:"summary"`
+	Pages           []PageComparison  `json:"pages"`
+}
@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// DigestPeriod is how often a user who opted in receives the reading digest.
+const DigestPeriod = 7 * 24 * time.Hour
+
+// EmailMessage is one outgoing e-mail with a plain-text and an optional HTML body.
+type EmailMessage struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailSender delivers e-mails through a mail provider.
+type EmailSender interface {
+	Send(ctx context.Context, msg *EmailMessage) error
+}
+
+// EmailConfig configures outgoing e-mail. Backend is "smtp" or "sendgrid".
+type EmailConfig struct {
+	Backend        string
+	From           string // e.g. "Lector <digest@example.com>"
+	SMTPAddress    string // host:port
+	SMTPUsername   string
+	SMTPPassword   string
+	SendGridAPIKey string
+}
+
+// DigestConfig configures the weekly reading digest job.
+type DigestConfig struct {
+	Enabled bool
+	// CheckInterval is how often the job looks for users whose digest is due.
+	CheckInterval time.Duration
+}
+
+// DigestRecipient is a user who opted in to the weekly digest.
+type DigestRecipient struct {
+	UserID string
+	Email  string
+}
+
+// DigestBook is a document the user read during the digest period.
+type DigestBook struct {
+	DocumentID string
+	Title      string
+	Progress   float32
+	PageNumber int
+	ReadAt     time.Time // when the reading position was last saved
+}
+
+// ReadingDigest summarises a user's reading over one digest period.
+type ReadingDigest struct {
+	Since      time.Time
+	Until      time.Time
+	Books      []DigestBook // read during the period, most recent first
+	Highlights int          // highlights made during the period
+	// Current is the unfinished book read most recently, even before the period; nil
+	// when every book is finished.
+	Current *DigestBook
+}
+
+// DigestRepository reads reading activity across users for the digest job. It runs
+// with the server's own database connection, not on behalf of a user.
+type DigestRepository interface {
+	// ListDigestRecipients returns the opted-in users who have not been sent a digest
+	// since sentBefore.
+	ListDigestRecipients(ctx context.Context, sentBefore time.Time) ([]DigestRecipient, error)
+	// GetReadingDigest returns the user's reading activity since the given time.
+	GetReadingDigest(ctx context.Context, userID string, since time.Time) (*ReadingDigest, error)
+	// MarkDigestSent records that the user's digest was sent at the given time.
+	MarkDigestSent(ctx context.Context, userID string, at time.Time) error
+}
+
+// DigestService sends the weekly reading digest.
+type DigestService interface {
+	// SendDueDigests e-mails every opted-in user whose digest is due and returns how
+	// many were sent.
+	SendDueDigests(ctx context.Context) (int, error)
+	// Run calls SendDueDigests on every check interval until ctx is cancelled.
+	Run(ctx context.Context)
+}
@@ -4,6 +4,10 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,10 +18,63 @@ type ReadingPosition struct {
 
 	Progress   float32 `json:"progress"`
 	PageNumber int     `json:"page_number"`
+	// CharOffset anchors the position in the document's extracted text, counted in
+	// characters from the start. Unlike page_number it does not depend on how a
+	// client paginates, so progress is recomputed from it when set.
+	CharOffset *int `json:"char_offset,omitempty"`
+	// Locator is the structural position in an EPUB; when set it takes precedence
+	// over char_offset.
+	Locator *EPUBLocator `json:"locator,omitempty"`
 
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+// TextAnchor is one place in a document's text in every unit clients use: the
+// character offset, the fraction of the text before it and its source page.
+type TextAnchor struct {
+	CharOffset int          `json:"char_offset"`
+	CharCount  int          `json:"char_count"`
+	Progress   float32      `json:"progress"`
+	PageNumber int          `json:"page_number"`
+	Locator    *EPUBLocator `json:"locator,omitempty"` // EPUBs extracted with structure only
+}
+
+// AnchorQuery is a place to resolve, given in exactly one unit.
+type AnchorQuery struct {
+	CharOffset *int
+	Progress   *float32
+	Locator    *EPUBLocator
+}
+
+// TextAnchorResolver converts a character offset, a progress or an EPUB locator into
+// a TextAnchor against the document's current text.
+type TextAnchorResolver interface {
+	ResolveAnchor(ctx context.Context, principal Principal, documentID string, query AnchorQuery) (*TextAnchor, error)
+}
+
+// DocumentMatch is one occurrence of a find-in-document query, placed like a
+// TextAnchor. The snippet is the text around it, with the match at
+// [SnippetStart, SnippetEnd) counted in characters.
+type DocumentMatch struct {
+	CharOffset   int          `json:"char_offset"`
+	Length       int          `json:"length"`
+	Progress     float32      `json:"progress"`
+	PageNumber   int          `json:"page_number"`
+	Locator      *EPUBLocator `json:"locator,omitempty"`
+	Snippet      string       `json:"snippet"`
+	SnippetStart int          `json:"snippet_start"`
+	SnippetEnd   int          `json:"snippet_end"`
+}
+
+// DocumentFinder searches one document's text, ignoring case and line breaks.
+type DocumentFinder interface {
+	// FindInDocument returns every match of query in reading order.
+	FindInDocument(ctx context.Context, principal Principal, documentID, query string) ([]*DocumentMatch, error)
+}
+
+// FinishedProgress is the reading progress from which a document counts as read.
+const FinishedProgress = 0.95
+
 // Validate checks if the reading position has all required fields and valid values.
 // Returns an error if validation fails, nil otherwise.
 func (r *ReadingPosition) Validate() error {
@@ -33,6 +90,12 @@ func (r *ReadingPosition) Validate() error {
 	if r.PageNumber < 0 {
 		return &ValidationError{Field: "page_number", Message: "page number cannot be negative"}
 	}
+	if r.CharOffset != nil && *r.CharOffset < 0 {
+		return &ValidationError{Field: "char_offset", Message: "character offset cannot be negative"}
+	}
+	if r.Locator != nil {
+		return r.Locator.Validate()
+	}
 	return nil
 }
 
@@ -44,8 +107,85 @@ type DocumentMetadata struct {
 	WordCount      int    `json:"word_count,omitempty"`
 	FileSize       int64  `json:"file_size,omitempty"`
 	Format         string `json:"format,omitempty"`
+	SHA256         string `json:"sha256,omitempty"` // Hex digest of the uploaded file
 	Source         string `json:"source,omitempty"`
 	HasPassword    bool   `json:"has_password,omitempty"`
+	CoverPath      string `json:"cover_path,omitempty"` // Storage path of the cover image (EPUB, CBZ)
+
+	// ContentVersion increases each time the extracted text is replaced, so
+	// positions anchored in older text know to relocate.
+	ContentVersion int `json:"content_version,omitempty"`
+
+	// ProcessingError is why the last extraction failed; ProcessingAttempts counts
+	// the failed ones, which recovery stops retrying at MaxProcessingAttempts.
+	ProcessingError    string `json:"processing_error,omitempty"`
+	ProcessingAttempts int    `json:"processing_attempts,omitempty"`
+
+	// Library metadata carried over from an import (e.g. Calibre, Pocket).
+	Series      string  `json:"series,omitempty"`
+	SeriesIndex float64 `json:"series_index,omitempty"`
+	ISBN        string  `json:"isbn,omitempty"`
+	SourceURL   string  `json:"source_url,omitempty"` // Original address of an imported web article
+	// SourceTags are all the tags the document had in the app it came from; the
+	// first is assigned as its tag.
+	SourceTags []string `json:"source_tags,omitempty"`
+
+	// PublicationYear and Enrichment are filled in from an external book catalog.
+	PublicationYear int                 `json:"publication_year,omitempty"`
+	Enrichment      *MetadataEnrichment `json:"enrichment,omitempty"`
+
+	// Outline is the table of contents extracted from the PDF outline or EPUB nav/NCX.
+	Outline []OutlineEntry `json:"outline,omitempty"`
+
+	// References are the parsed bibliography entries of a scientific paper.
+	References []Reference `json:"references,omitempty"`
+
+	// Quarantine is set while an upload is held for malware review.
+	Quarantine *Quarantine `json:"quarantine,omitempty"`
+}
+
+// OutlineEntry is one table-of-contents entry pointing at a block in the document.
+type OutlineEntry struct {
+	Title    string `json:"title"`
+	Depth    int    `json:"depth"`    // 0 for top-level entries
+	Page     int    `json:"page"`     // Target page (1-indexed)
+	Position int    `json:"position"` // Target block position within the page
+}
+
+// Reference is one entry of a paper's references section. Fields other than Raw are
+// best-effort parses of the printed citation.
+type Reference struct {
+	Number   int      `json:"number"` // 1-indexed order; numeric in-text markers use it
+	Raw      string   `json:"raw"`    // Entry text as printed, without its marker
+	Authors  []string `json:"authors,omitempty"`
+	Title    string   `json:"title,omitempty"`
+	Venue    string   `json:"venue,omitempty"`
+	Year     int      `json:"year,omitempty"`
+	DOI      string   `json:"doi,omitempty"`
+	ArXivID  string   `json:"arxiv_id,omitempty"`
+	URL      string   `json:"url,omitempty"`
+	Page     int      `json:"page"`     // Block holding the entry (1-indexed page)
+	Position int      `json:"position"` // Block position within the page
+}
+
+// ComicPage is one entry of a comic's page-image manifest, which is stored as the
+// document content in place of text blocks.
+type ComicPage struct {
+	Page        int    `json:"page"` // 1-indexed, matching reading positions
+	Path        string `json:"path"` // Storage path of the page image
+	ContentType string `json:"content_type"`
+	Width       int    `json:"width,omitempty"`
+	Height      int    `json:"height,omitempty"`
+}
+
+// PageImage is a short-lived signed URL for reading one comic page.
+type PageImage struct {
+	Page        int       `json:"page"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width,omitempty"`
+	Height      int       `json:"height,omitempty"`
+	ExpiresAt   time.Time `json:"expires_at"`
 }
 
 // Validate checks if the metadata has valid values.
@@ -77,12 +217,20 @@ type Document struct {
 	Tag      *string          `json:"tag,omitempty"` // Single tag (document can only have one tag)
 
 	IsFavorite bool `json:"is_favorite"`
+	// IsArchived hides the document from the default library listing and the
+	// continue-reading shelf without deleting anything.
+	IsArchived bool `json:"is_archived"`
 
 	// Optional reading position (when requested by endpoints like documents/user/{id}).
 	ReadingPosition *ReadingPosition `json:"reading_position,omitempty"`
+	// Snippets show why a library search matched the document (search results only).
+	Snippets []SearchSnippet `json:"snippets,omitempty"`
 
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	// LastOpenedAt is when the document was last opened in a reader. Edits change
+	// UpdatedAt but not this, so it orders "recently opened" shelves.
+	LastOpenedAt *time.Time `json:"last_opened_at,omitempty"`
 }
 
 // Validate checks if the document has all required fields and valid values.
@@ -103,14 +251,172 @@ func (d *Document) Validate() error {
 	return nil
 }
 
+// IsQuarantined reports whether the document is held for malware review.
+func (d *Document) IsQuarantined() bool {
+	return d.Metadata.Quarantine != nil
+}
+
 // DocumentData is the data transfer representation used by services and handlers.
 // Alias to Document so they are interchangeable.
 type DocumentData = Document
 
+// Document statuses reported by the library overview. Uploads are processed before
+// the document is stored, so a stored document is either ready or held for review.
+const (
+	DocumentStatusReady       = "ready"
+	DocumentStatusQuarantined = "quarantined"
+)
+
+// Status returns the document's processing status.
+func (d *Document) Status() string {
+	if d.IsQuarantined() {
+		return DocumentStatusQuarantined
+	}
+	return DocumentStatusReady
+}
+
+// ArchiveFilter selects documents by their archived state in library listings.
+type ArchiveFilter string
+
+const (
+	ArchiveFilterActive   ArchiveFilter = "false" // the default
+	ArchiveFilterArchived ArchiveFilter = "true"
+	ArchiveFilterAll      ArchiveFilter = "all"
+)
+
+// ParseArchiveFilter parses the archived query parameter; empty means active only.
+func ParseArchiveFilter(value string) (ArchiveFilter, error) {
+	switch filter := ArchiveFilter(value); filter {
+	case "":
+		return ArchiveFilterActive, nil
+	case ArchiveFilterActive, ArchiveFilterArchived, ArchiveFilterAll:
+		return filter, nil
+	}
+	return "", &ValidationError{Field: "archived", Message: "must be true, false or all"}
+}
+
+// Apply returns the documents the filter selects, in their original order.
+func (f ArchiveFilter) Apply(docs []*Document) []*Document {
+	if f == ArchiveFilterAll {
+		return docs
+	}
+	selected := make([]*Document, 0, len(docs))
+	for _, doc := range docs {
+		if doc != nil && doc.IsArchived == (f == ArchiveFilterArchived) {
+			selected = append(selected, doc)
+		}
+	}
+	return selected
+}
+
+// LibrarySort orders documents in library listings.
+type LibrarySort string
+
+const (
+	LibrarySortNone       LibrarySort = ""            // the repository's order, the default
+	LibrarySortLastOpened LibrarySort = "last_opened" // most recently opened first; never opened last
+	LibrarySortUpdated    LibrarySort = "updated"     // most recently edited first
+	LibrarySortCreated    LibrarySort = "created"     // most recently added first
+	LibrarySortTitle      LibrarySort = "title"       // A to Z, ignoring case
+)
+
+// LibraryQuery selects and orders the documents of a library listing, from the
+// archived, sort, opened_since and collection query parameters.
+type LibraryQuery struct {
+	Archived ArchiveFilter
+	Sort     LibrarySort
+	// OpenedSince keeps only documents opened at or after the time.
+	OpenedSince *time.Time
+	// Collection is the ID of a saved search whose matches are listed instead of
+	// the whole library. Apply leaves it to the SavedSearchService.
+	Collection string
+}
+
+// ParseLibraryQuery parses a listing's query parameters.
+func ParseLibraryQuery(values url.Values) (LibraryQuery, error) {
+	archived, err := ParseArchiveFilter(values.Get("archived"))
+	if err != nil {
+		return LibraryQuery{}, err
+	}
+	query := LibraryQuery{Archived: archived}
+
+	switch sort := LibrarySort(values.Get("sort")); sort {
+	case LibrarySortNone, LibrarySortLastOpened, LibrarySortUpdated, LibrarySortCreated, LibrarySortTitle:
+		query.Sort = sort
+	default:
+		return LibraryQuery{}, &ValidationError{Field: "sort", Message: "must be last_opened, updated, created or title"}
+	}
+
+	if value := values.Get("opened_since"); value != "" {
+		since, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return LibraryQuery{}, &ValidationError{Field: "opened_since", Message: "must be an RFC 3339 timestamp"}
+		}
+		query.OpenedSince = &since
+	}
+
+	if query.Collection, err = ParseSmartCollection(values.Get("collection")); err != nil {
+		return LibraryQuery{}, err
+	}
+	return query, nil
+}
+
+// Apply returns the documents the query selects, in its order.
+func (q LibraryQuery) Apply(docs []*Document) []*Document {
+	docs = q.Archived.Apply(docs)
+	if q.OpenedSince != nil {
+		opened := make([]*Document, 0, len(docs))
+		for _, doc := range docs {
+			if doc.LastOpenedAt != nil && !doc.LastOpenedAt.Before(*q.OpenedSince) {
+				opened = append(opened, doc)
+			}
+		}
+		docs = opened
+	}
+
+	var compare func(a, b *Document) int
+	switch q.Sort {
+	case LibrarySortLastOpened:
+		compare = func(a, b *Document) int {
+			if a.LastOpenedAt == nil || b.LastOpenedAt == nil {
+				return boolCompare(a.LastOpenedAt == nil, b.LastOpenedAt == nil)
+			}
+			return b.LastOpenedAt.Compare(*a.LastOpenedAt)
+		}
+	case LibrarySortUpdated:
+		compare = func(a, b *Document) int { return b.UpdatedAt.Compare(a.UpdatedAt) }
+	case LibrarySortCreated:
+		compare = func(a, b *Document) int { return b.CreatedAt.Compare(a.CreatedAt) }
+	case LibrarySortTitle:
+		compare = func(a, b *Document) int { return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)) }
+	default:
+		return docs
+	}
+	// Filtering above may have returned the caller's slice; sort a copy.
+	docs = slices.Clone(docs)
+	slices.SortStableFunc(docs, compare)
+	return docs
+}
+
+// boolCompare orders false before true.
+func boolCompare(a, b bool) int {
+	switch {
+	case a == b:
+		return 0
+	case a:
+		return 1
+	}
+	return -1
+}
+
 // DocumentWithPosition represents a document together with the user's current reading state.
 type DocumentWithPosition struct {
 	DocumentData    *DocumentData    `json:"document"`
 	ReadingPosition *ReadingPosition `json:"reading_position,omitempty"`
+
+	// Only set by the library overview.
+	HighlightCount *int   `json:"highlight_count,omitempty"`
+	Status         string `json:"status,omitempty"`
 }
 
 // LibraryResponse is the payload returned by the library endpoint.
@@ -118,45 +424,119 @@ type LibraryResponse struct {
 	Documents []DocumentWithPosition `json:"documents"`
 }
 
+// DocumentPatch lists the document columns an edit changes; nil fields are left
+// as they are. An empty Tag removes the document's tag.
+type DocumentPatch struct {
+	Title     *string
+	Author    *string
+	Tag       *string
+	UpdatedAt time.Time
+}
+
 // DocumentRepository defines persistence operations for documents.
+// Operations run with the principal's token so row level security applies.
 type DocumentRepository interface {
-	Create(document *Document, token string) error
-	GetByID(id string, token string) (*Document, error)
-	GetByUserID(userID string, token string) ([]*Document, error)
-	Update(document *Document, token string) error
-	Delete(id string, token string) error
-	Search(userID, query string, token string) ([]*Document, error)
-	GetTagsByUserID(userID string, token string) ([]string, error)
-	CreateTag(userID string, tagName string, token string) error
-	DeleteTag(userID string, tagName string, token string) error
+	Create(ctx context.Context, principal Principal, document *Document) error
+	GetByID(ctx context.Context, principal Principal, id string) (*Document, error)
+	GetByUserID(ctx context.Context, principal Principal) ([]*Document, error)
+	Update(ctx context.Context, principal Principal, document *Document) error
+	// Patch writes only the patched columns and updated_at, so edits made from another
+	// device to the rest of the row are not overwritten.
+	Patch(ctx context.Context, principal Principal, documentID string, patch DocumentPatch) error
+	Delete(ctx context.Context, principal Principal, id string) error
+	Search(ctx context.Context, principal Principal, query *DocumentQuery) ([]*Document, error)
+	GetTagsByUserID(ctx context.Context, principal Principal) ([]string, error)
+	CreateTag(ctx context.Context, principal Principal, tagName string) error
+	DeleteTag(ctx context.Context, principal Principal, tagName string) error
 
 	// Favorites
-	SetFavorite(userID string, documentID string, isFavorite bool, token string) error
+	SetFavorite(ctx context.Context, principal Principal, documentID string, isFavorite bool) error
+	// SetArchived archives or unarchives a document, leaving its data untouched.
+	SetArchived(ctx context.Context, principal Principal, documentID string, archived bool) error
+	// SetLastOpened records a reader opening the document, leaving updated_at alone.
+	SetLastOpened(ctx context.Context, principal Principal, documentID string, at time.Time) error
 }
 
 // DocumentService defines the use-case operations for documents.
 type DocumentService interface {
-	GetDocumentsByUserID(userID string, token string) ([]*DocumentData, error)
-	GetDocument(documentID string, token string) (*DocumentData, error)
-	DeleteDocument(documentID string, token string) error
-	SearchDocuments(userID, query string, token string) ([]*DocumentData, error)
-	SetFavorite(userID string, documentID string, isFavorite bool, token string) error
+	GetDocumentsByUserID(ctx context.Context, principal Principal) ([]*DocumentData, error)
+	GetDocument(ctx context.Context, principal Principal, documentID string) (*DocumentData, error)
+	// OpenDocument returns the document like GetDocument and records it as opened.
+	OpenDocument(ctx context.Context, principal Principal, documentID string) (*DocumentData, error)
+	DeleteDocument(ctx context.Context, principal Principal, documentID string) error
+	SearchDocuments(ctx context.Context, principal Principal, query string) ([]*DocumentData, error)
+	SetFavorite(ctx context.Context, principal Principal, documentID string, isFavorite bool) error
+	SetArchived(ctx context.Context, principal Principal, documentID string, archived bool) error
 	UpdateDocumentDetails(
-		userID string,
+		ctx context.Context,
+		principal Principal,
 		documentID string,
 		title *string,
 		author *string,
 		tag *string,
-		token string,
 	) (*DocumentData, error)
-	GetDocumentTags(userID string, token string) ([]string, error)
-	CreateTag(userID string, tagName string, token string) error
-	DeleteTag(userID string, tagName string, token string) error
+	// ListVersions returns the document's saved versions, newest first.
+	ListVersions(ctx context.Context, principal Principal, documentID string) ([]*DocumentVersion, error)
+	// RestoreVersion overwrites the document with a saved version, snapshotting it first.
+	RestoreVersion(ctx context.Context, principal Principal, documentID string, version int) (*DocumentData, error)
+	GetOutline(ctx context.Context, principal Principal, documentID string) ([]OutlineEntry, error)
+	// CompareDocuments diffs the extracted text of two documents page by page.
+	CompareDocuments(ctx context.Context, principal Principal, leftID, rightID string) (*DocumentComparison, error)
+	GetReferences(ctx context.Context, principal Principal, documentID string) ([]Reference, error)
+	TextAnchorResolver
+	DocumentFinder
+	DocumentExporter
+	DocumentVerifier
+	DocumentFileOpener
+	UploadProgressTracker
+	// GetPageImage returns a signed URL for a comic page (1-indexed).
+	GetPageImage(ctx context.Context, principal Principal, documentID string, page int) (*PageImage, error)
+	GetDocumentTags(ctx context.Context, principal Principal) ([]string, error)
+	CreateTag(ctx context.Context, principal Principal, tagName string) error
+	DeleteTag(ctx context.Context, principal Principal, tagName string) error
 	Upload(
 		ctx context.Context,
-		userID string,
+		principal Principal,
 		file io.Reader,
-		token string,
 		originalName string,
 	) (*DocumentData, error)
+	// UploadLimit resolves the single-file cap for the principal's plan and the given
+	// format (lower-case extension; empty for the plan-wide cap).
+	UploadLimit(ctx context.Context, principal Principal, format string) UploadLimit
+}
+
+// StripUnsafeUnicodeEscapes removes escapes for control characters (\u0000-\u001F, \b, \f) and
+// surrogates (\uD800-\uDFFF) from a JSON document. Unlike a plain regex it skips over escaped
+// backslashes, so literal text such as `\\u0001` is never turned into a real escape.
+func StripUnsafeUnicodeEscapes(jsonStr string) string {
+	var result strings.Builder
+	result.Grow(len(jsonStr))
+
+	for i := 0; i < len(jsonStr); i++ {
+		c := jsonStr[i]
+		if c != '\\' || i+1 >= len(jsonStr) {
+			result.WriteByte(c)
+			continue
+		}
+
+		if jsonStr[i+1] == 'b' || jsonStr[i+1] == 'f' {
+			i++
+			continue
+		}
+		if jsonStr[i+1] == 'u' && i+6 <= len(jsonStr) {
+			if code, err := strconv.ParseUint(jsonStr[i+2:i+6], 16, 32); err == nil {
+				if code < 0x20 || (code >= 0xD800 && code <= 0xDFFF) {
+					i += 5
+					continue
+				}
+			}
+		}
+
+		// Copy the escape pair as-is so the next iteration starts after it
+		result.WriteByte(c)
+		result.WriteByte(jsonStr[i+1])
+		i++
+	}
+
+	return result.String()
 }
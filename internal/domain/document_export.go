@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+)
+
+// Formats a document can be exported to.
+const (
+	ExportFormatEPUB = "epub"
+	ExportFormatPDF  = "pdf"
+	ExportFormatTXT  = "txt"
+)
+
+// DocumentExport is a file regenerated from a document's extracted text.
+type DocumentExport struct {
+	Filename    string
+	ContentType string
+	Data        []byte
+}
+
+// ValidateExportFormat rejects formats that cannot be exported to.
+func ValidateExportFormat(format string) error {
+	switch format {
+	case ExportFormatEPUB, ExportFormatPDF, ExportFormatTXT:
+		return nil
+	}
+	return ValidationErrors{{Field: "format", Message: fmt.Sprintf("format must be %s, %s or %s", ExportFormatEPUB, ExportFormatPDF, ExportFormatTXT)}}
+}
+
+// DocumentExporter rebuilds a clean file from a document's stored text blocks,
+// for documents (plain text, Markdown, web pages) whose original file is gone
+// or not worth keeping.
+type DocumentExporter interface {
+	ExportDocument(ctx context.Context, principal Principal, documentID string, format string) (*DocumentExport, error)
+	// PlainText returns the raw extracted text, pages separated by form feeds.
+	PlainText(ctx context.Context, principal Principal, documentID string) (*DocumentExport, error)
+}
@@ -0,0 +1,33 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// DocumentFile is the stored original of a document, opened for streaming. Content
+// seeks lazily, so serving a byte range does not hold the whole file.
+type DocumentFile struct {
+	Filename    string
+	ContentType string
+	Size        int64
+	ModTime     time.Time
+	// ETag identifies this version of the file, from its SHA-256; empty when unknown.
+	ETag    string
+	Content io.ReadSeekCloser
+}
+
+// RangeLimiter is implemented by DocumentFile contents that download lazily. A
+// response that only serves the bytes before end says so, and downloads stop there
+// instead of running on to the end of the file.
+type RangeLimiter interface {
+	LimitRange(end int64)
+}
+
+// DocumentFileOpener streams original files through the server instead of handing
+// out storage URLs, so every access is authorized and can be logged.
+type DocumentFileOpener interface {
+	// OpenFile opens the document's original; the caller closes Content.
+	OpenFile(ctx context.Context, principal Principal, documentID string) (*DocumentFile, error)
+}
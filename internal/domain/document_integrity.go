@@ -0,0 +1,43 @@
+package domain
+
+import "context"
+
+// Problems document verification reports.
+const (
+	IntegrityFileMissing       = "file_missing"        // the original upload is gone from storage
+	IntegrityContentMissing    = "content_missing"     // extraction never finished or failed
+	IntegrityContentUnreadable = "content_unreadable"  // stored content is not valid block JSON
+	IntegrityPageCountMismatch = "page_count_mismatch" // content and metadata disagree on pages
+	IntegrityAssetMissing      = "asset_missing"       // an image, cover or comic page is gone
+)
+
+// IntegrityIssue is one inconsistency found in a stored document.
+type IntegrityIssue struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Path    string `json:"path,omitempty"` // storage path, for missing files
+}
+
+// DocumentIntegrity compares a document's stored content with its metadata and the
+// files it refers to.
+type DocumentIntegrity struct {
+	DocumentID   string           `json:"document_id"`
+	Format       string           `json:"format"`
+	OK           bool             `json:"ok"`
+	FileExists   bool             `json:"file_exists"`
+	BlockCount   int              `json:"block_count"`   // text blocks, or comic pages
+	PageCount    int              `json:"page_count"`    // from the metadata
+	ContentPages int              `json:"content_pages"` // highest page the content reaches
+	Issues       []IntegrityIssue `json:"issues"`
+	// Repairable is set when reprocessing the stored original should fix the issues.
+	Repairable bool `json:"repairable"`
+}
+
+// DocumentVerifier checks documents for inconsistencies and repairs them by
+// extracting them again from the original file.
+type DocumentVerifier interface {
+	VerifyDocument(ctx context.Context, principal Principal, documentID string) (*DocumentIntegrity, error)
+	// ReprocessDocument re-extracts the content from the stored original, keeping the
+	// title, author and library metadata the user set.
+	ReprocessDocument(ctx context.Context, principal Principal, documentID string) (*DocumentData, error)
+}
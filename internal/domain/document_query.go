@@ -0,0 +1,504 @@
+package domain
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Fields a library search term can be scoped to with field:value. A term without a
+// field matches the title, the author or the start of the text.
+const (
+	SearchFieldTitle  = "title"
+	SearchFieldAuthor = "author"
+	SearchFieldTag    = "tag"
+)
+
+// SearchContentPrefix is how much of a document's stored content, in characters,
+// library search looks at, the same count Postgres's left() takes. Searching whole books belongs to in-document find.
+const SearchContentPrefix = 1000
+
+// Limits on a library search query.
+const (
+	MaxSearchQueryTerms = 32
+	MaxSearchRegexLen   = 200
+)
+
+// Operators of a DocumentQuery node.
+const (
+	SearchTerm = "term"
+	SearchAnd  = "and"
+	SearchOr   = "or"
+	SearchNot  = "not"
+)
+
+// DocumentQuery is a parsed library search. The syntax is that of web search with
+// fields and patterns:
+//
+//	whale "white whale"        both, anywhere (AND is implied)
+//	melville OR hawthorne      either
+//	-draft  NOT draft          without
+//	(a OR b) c                 grouping
+//	author:melville tag:"to read" title:/^moby/
+//	/colou?r/                  a case-insensitive regular expression
+//
+// Words and phrases match as case-insensitive substrings.
+type DocumentQuery struct {
+	Op       string
+	Children []*DocumentQuery // and, or: two or more; not: one
+
+	// Terms only.
+	Field string // "" or one of the SearchField constants
+	Text  string // the word, phrase or pattern
+	Regex *regexp.Regexp
+}
+
+// ParseDocumentQuery parses a library search query. Syntax errors are returned as
+// ValidationErrors on the q parameter.
+func ParseDocumentQuery(s string) (*DocumentQuery, error) {
+	tokens, err := tokenizeDocumentQuery(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(tokens) == 0 {
+		return nil, searchQueryError("a search query is required")
+	}
+	terms := 0
+	for _, t := range tokens {
+		if t.term != nil {
+			terms++
+		}
+	}
+	if terms == 0 {
+		return nil, searchQueryError("the query has no search terms")
+	}
+	if terms > MaxSearchQueryTerms {
+		return nil, searchQueryError("the query has too many terms")
+	}
+
+	p := &documentQueryParser{tokens: tokens}
+	query, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.tokens) {
+		return nil, searchQueryError("unmatched )")
+	}
+	return query, nil
+}
+
+// Uses reports whether any term of the query is scoped to field.
+func (q *DocumentQuery) Uses(field string) bool {
+	if q.Op == SearchTerm {
+		return q.Field == field
+	}
+	for _, child := range q.Children {
+		if child.Uses(field) {
+			return true
+		}
+	}
+	return false
+}
+
+// Match evaluates the query against a document, for stores that cannot run it.
+func (q *DocumentQuery) Match(doc *Document) bool {
+	switch q.Op {
+	case SearchAnd:
+		for _, child := range q.Children {
+			if !child.Match(doc) {
+				return false
+			}
+		}
+		return true
+	case SearchOr:
+		for _, child := range q.Children {
+			if child.Match(doc) {
+				return true
+			}
+		}
+		return false
+	case SearchNot:
+		return !q.Children[0].Match(doc)
+	}
+
+	var author, tag string
+	if doc.Author != nil {
+		author = *doc.Author
+	}
+	if doc.Tag != nil {
+		tag = *doc.Tag
+	}
+	switch q.Field {
+	case SearchFieldTitle:
+		return q.matchText(doc.Title)
+	case SearchFieldAuthor:
+		return q.matchText(author)
+	case SearchFieldTag:
+		// Tags are names, so tag:x is the tag x rather than any tag containing it.
+		if q.Regex == nil {
+			return strings.EqualFold(tag, q.Text)
+		}
+		return q.Regex.MatchString(tag)
+	}
+	content := string(doc.Content)
+	if len(content) > SearchContentPrefix {
+		n := 0
+		for i := range content {
+			if n == SearchContentPrefix {
+				content = content[:i]
+				break
+			}
+			n++
+		}
+	}
+	return q.matchText(doc.Title) || q.matchText(author) || q.matchText(content)
+}
+
+func (q *DocumentQuery) matchText(s string) bool {
+	if q.Regex != nil {
+		return q.Regex.MatchString(s)
+	}
+	return strings.Contains(strings.ToLower(s), strings.ToLower(q.Text))
+}
+
+func searchQueryError(message string) error {
+	return ValidationErrors{{Field: "q", Message: message}}
+}
+
+// documentQueryToken is an operator, a parenthesis or a term.
+type documentQueryToken struct {
+	op   string // "(", ")", or, and, not
+	term *DocumentQuery
+}
+
+func tokenizeDocumentQuery(s string) ([]documentQueryToken, error) {
+	var tokens []documentQueryToken
+	runes := []rune(s)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+		switch {
+		case unicode.IsSpace(r):
+			i++
+			continue
+		case r == '(' || r == ')':
+			tokens = append(tokens, documentQueryToken{op: string(r)})
+			i++
+			continue
+		case r == '-' && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]):
+			tokens = append(tokens, documentQueryToken{op: SearchNot})
+			i++
+			continue
+		}
+
+		// A field prefix applies to the value right after it.
+		field := ""
+		if j := indexRune(runes[i:], ':'); j > 0 {
+			switch name := strings.ToLower(string(runes[i : i+j])); name {
+			case SearchFieldTitle, SearchFieldAuthor, SearchFieldTag:
+				if i+j+1 < len(runes) && !unicode.IsSpace(runes[i+j+1]) {
+					field = name
+					i += j + 1
+				}
+			}
+		}
+
+		term := &DocumentQuery{Op: SearchTerm, Field: field}
+		switch runes[i] {
+		case '"':
+			end := slices.Index(runes[i+1:], '"')
+			if end < 0 {
+				return nil, searchQueryError("unterminated quoted phrase")
+			}
+			term.Text = strings.Join(strings.Fields(string(runes[i+1:i+1+end])), " ")
+			i += end + 2
+			if term.Text == "" {
+				continue
+			}
+		case '/':
+			pattern, n, ok := readSearchPattern(runes[i+1:])
+			if !ok {
+				return nil, searchQueryError("unterminated /regular expression/")
+			}
+			if len(pattern) > MaxSearchRegexLen {
+				return nil, searchQueryError("regular expression is too long")
+			}
+			re, err := regexp.Compile("(?i)" + pattern)
+			if err != nil {
+				return nil, searchQueryError("invalid regular expression: " + err.Error())
+			}
+			term.Text, term.Regex = pattern, re
+			i += n + 2
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && runes[i] != '(' && runes[i] != ')' {
+				i++
+			}
+			word := string(runes[start:i])
+			if field == "" {
+				switch word {
+				case "OR", "AND", "NOT":
+					tokens = append(tokens, documentQueryToken{op: strings.ToLower(word)})
+					continue
+				}
+			}
+			term.Text = word
+		}
+		tokens = append(tokens, documentQueryToken{term: term})
+	}
+	return tokens, nil
+}
+
+// readSearchPattern reads a pattern up to its closing slash; \/ is a literal slash.
+// It returns the pattern and how many runes it spans.
+func readSearchPattern(runes []rune) (string, int, bool) {
+	var b strings.Builder
+	for i := 0; i < len(runes); i++ {
+		switch {
+		case runes[i] == '\\' && i+1 < len(runes) && runes[i+1] == '/':
+			b.WriteRune('/')
+			i++
+		case runes[i] == '/':
+			return b.String(), i, b.Len() > 0
+		default:
+			b.WriteRune(runes[i])
+		}
+	}
+	return "", 0, false
+}
+
+// indexRune finds r in the word at the start of runes.
+func indexRune(runes []rune, r rune) int {
+	for i, c := range runes {
+		if c == r {
+			return i
+		}
+		if unicode.IsSpace(c) {
+			return -1
+		}
+	}
+	return -1
+}
+
+// documentQueryParser parses tokens by precedence: NOT binds tightest, then AND
+// (explicit or implied), then OR.
+type documentQueryParser struct {
+	tokens []documentQueryToken
+	pos    int
+}
+
+func (p *documentQueryParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	if p.tokens[p.pos].term != nil {
+		return SearchTerm
+	}
+	return p.tokens[p.pos].op
+}
+
+func (p *documentQueryParser) or() (*DocumentQuery, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+	node := &DocumentQuery{Op: SearchOr, Children: []*DocumentQuery{left}}
+	for p.peek() == SearchOr {
+		p.pos++
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, right)
+	}
+	if len(node.Children) == 1 {
+		return left, nil
+	}
+	return node, nil
+}
+
+func (p *documentQueryParser) and() (*DocumentQuery, error) {
+	node := &DocumentQuery{Op: SearchAnd}
+	for {
+		switch p.peek() {
+		case "", ")", SearchOr:
+			switch len(node.Children) {
+			case 0:
+				return nil, searchQueryError("an operator is missing its search term")
+			case 1:
+				return node.Children[0], nil
+			}
+			return node, nil
+		case SearchAnd:
+			p.pos++
+			continue
+		}
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		node.Children = append(node.Children, operand)
+	}
+}
+
+func (p *documentQueryParser) not() (*DocumentQuery, error) {
+	switch p.peek() {
+	case SearchNot:
+		p.pos++
+		operand, err := p.not()
+		if err != nil {
+			return nil, err
+		}
+		return &DocumentQuery{Op: SearchNot, Children: []*DocumentQuery{operand}}, nil
+	case "(":
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, searchQueryError("unmatched (")
+		}
+		p.pos++
+		return inner, nil
+	case SearchTerm:
+		term := p.tokens[p.pos].term
+		p.pos++
+		return term, nil
+	}
+	return nil, searchQueryError("an operator is missing its search term")
+}
+
+// Snippet sizes of library search results.
+const (
+	SearchSnippetContext     = 60 // characters of content on each side of a match
+	MaxSearchContentSnippets = 2
+)
+
+// SearchSnippet shows where a library search matched a document: the text of a
+// field, or a window of its content, with the matches marked.
+type SearchSnippet struct {
+	Field string       `json:"field"` // title, author, tag or content
+	Text  string       `json:"text"`
+	Marks []SearchMark `json:"marks"`
+}
+
+// SearchMark is a match at [Start, End) of a snippet's text, counted in characters,
+// for clients to wrap in <mark>.
+type SearchMark struct {
+	Start int `json:"start"`
+	End   int `json:"end"`
+}
+
+// Snippets marks the terms of the query in the document's title, author and tag and
+// in windows of text, its extracted text in reading order. Negated terms are not
+// marked.
+func (q *DocumentQuery) Snippets(doc *Document, text string) []SearchSnippet {
+	var snippets []SearchSnippet
+	field := func(name, value string) {
+		if value == "" {
+			return
+		}
+		if marks := q.marks(name, []rune(value)); len(marks) > 0 {
+			snippets = append(snippets, SearchSnippet{Field: name, Text: value, Marks: marks})
+		}
+	}
+	field(SearchFieldTitle, doc.Title)
+	if doc.Author != nil {
+		field(SearchFieldAuthor, *doc.Author)
+	}
+	if doc.Tag != nil {
+		field(SearchFieldTag, *doc.Tag)
+	}
+
+	runes := []rune(text)
+	marks := q.marks("", runes)
+	for i, n := 0, 0; i < len(marks) && n < MaxSearchContentSnippets; n++ {
+		from := max(0, marks[i].Start-SearchSnippetContext)
+		to := min(len(runes), marks[i].End+SearchSnippetContext)
+		snippet := SearchSnippet{Field: "content", Text: string(runes[from:to])}
+		for ; i < len(marks) && marks[i].End <= to; i++ {
+			snippet.Marks = append(snippet.Marks, SearchMark{Start: marks[i].Start - from, End: marks[i].End - from})
+		}
+		snippets = append(snippets, snippet)
+	}
+	return snippets
+}
+
+// marks finds the query's positive terms that apply to a field ("" for content) in
+// text, in order and without overlaps.
+func (q *DocumentQuery) marks(field string, text []rune) []SearchMark {
+	var marks []SearchMark
+	for _, term := range q.positiveTerms(nil) {
+		switch {
+		case field == SearchFieldTag:
+			// Like Match, a tag term matches the whole tag.
+			if term.Field == SearchFieldTag && (term.Regex != nil || strings.EqualFold(string(text), term.Text)) {
+				marks = append(marks, term.find(text)...)
+			}
+		case term.Field == "" || term.Field == field:
+			marks = append(marks, term.find(text)...)
+		}
+	}
+	slices.SortFunc(marks, func(a, b SearchMark) int { return a.Start - b.Start })
+	merged := marks[:0]
+	for _, m := range marks {
+		if n := len(merged); n > 0 && m.Start < merged[n-1].End {
+			merged[n-1].End = max(merged[n-1].End, m.End)
+			continue
+		}
+		merged = append(merged, m)
+	}
+	return merged
+}
+
+func (q *DocumentQuery) positiveTerms(terms []*DocumentQuery) []*DocumentQuery {
+	switch q.Op {
+	case SearchTerm:
+		return append(terms, q)
+	case SearchNot:
+		return terms
+	}
+	for _, child := range q.Children {
+		terms = child.positiveTerms(terms)
+	}
+	return terms
+}
+
+// find returns where a term occurs in text.
+func (q *DocumentQuery) find(text []rune) []SearchMark {
+	var marks []SearchMark
+	if q.Regex != nil {
+		s := string(text)
+		for _, loc := range q.Regex.FindAllStringIndex(s, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			start := utf8.RuneCountInString(s[:loc[0]])
+			marks = append(marks, SearchMark{Start: start, End: start + utf8.RuneCountInString(s[loc[0]:loc[1]])})
+		}
+		return marks
+	}
+	needle := []rune(q.Text)
+	for i, r := range needle {
+		needle[i] = unicode.ToLower(r)
+	}
+	if len(needle) == 0 {
+		return nil
+	}
+	for i := 0; i+len(needle) <= len(text); i++ {
+		match := true
+		for j, r := range needle {
+			if unicode.ToLower(text[i+j]) != r {
+				match = false
+				break
+			}
+		}
+		if match {
+			marks = append(marks, SearchMark{Start: i, End: i + len(needle)})
+			i += len(needle) - 1
+		}
+	}
+	return marks
+}
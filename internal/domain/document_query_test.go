@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseDocumentQuery_Match(t *testing.T) {
+	author, tag := "Herman Melville", "Classics"
+	moby := &Document{Title: "Moby-Dick; or, The Whale", Author: &author, Tag: &tag,
+		Content: []byte(`[{"type":"paragraph","content":"Call me Ishmael."}]`)}
+	other := "Nathaniel Hawthorne"
+	letter := &Document{Title: "The Scarlet Letter", Author: &other}
+
+	tests := []struct {
+		query       string
+		moby, other bool
+	}{
+		{"whale", true, false},
+		{"WHALE ishmael", true, false},
+		{`"call me ishmael"`, true, false},
+		{`"me call"`, false, false},
+		{"melville OR hawthorne", true, true},
+		{"the -whale", false, true},
+		{"the NOT whale", false, true},
+		{"the AND letter", false, true},
+		{"(whale OR letter) author:nathaniel", false, true},
+		{"author:melville", true, false},
+		{"title:ishmael", false, false},
+		{`tag:classics`, true, false},
+		{`tag:class`, false, false},
+		{`tag:/^class/`, true, false},
+		{`title:/^moby-d/`, true, false},
+		{`/colou?r|scar+let/`, false, true},
+		{`title:/a\/b/`, false, false},
+		{"unknown:whale", false, false},
+	}
+	for _, tt := range tests {
+		q, err := ParseDocumentQuery(tt.query)
+		if err != nil {
+			t.Errorf("%s: %v", tt.query, err)
+			continue
+		}
+		if got := q.Match(moby); got != tt.moby {
+			t.Errorf("%s: Moby-Dick matched = %v, want %v", tt.query, got, tt.moby)
+		}
+		if got := q.Match(letter); got != tt.other {
+			t.Errorf("%s: The Scarlet Letter matched = %v, want %v", tt.query, got, tt.other)
+		}
+	}
+}
+
+func TestDocumentQuery_MatchContentPrefix(t *testing.T) {
+	// The prefix counts characters, as Postgres's left() does, not bytes.
+	doc := &Document{Title: "Accents", Content: []byte(strings.Repeat("é", SearchContentPrefix-1) + "whale")}
+	for query, want := range map[string]bool{"éw": true, "wh": false} {
+		q, err := ParseDocumentQuery(query)
+		if err != nil {
+			t.Fatalf("%s: %v", query, err)
+		}
+		if got := q.Match(doc); got != want {
+			t.Errorf("%s: matched = %v, want %v", query, got, want)
+		}
+	}
+}
+
+func TestParseDocumentQuery_Invalid(t *testing.T) {
+	for _, query := range []string{
+		"",
+		"   ",
+		`""`,
+		`"unterminated`,
+		"/unterminated",
+		"/(/",
+		"whale OR",
+		"NOT",
+		"(whale",
+		"whale)",
+		"a b c d e f g h i j k l m n o p q r s t u v w x y z aa bb cc dd ee ff gg",
+	} {
+		_, err := ParseDocumentQuery(query)
+		var validationErrs ValidationErrors
+		if !errors.As(err, &validationErrs) || validationErrs[0].Field != "q" {
+			t.Errorf("%q: got %v, want a validation error on q", query, err)
+		}
+	}
+}
+
+func TestDocumentQuery_Uses(t *testing.T) {
+	q, err := ParseDocumentQuery("whale OR -tag:classics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !q.Uses(SearchFieldTag) || q.Uses(SearchFieldAuthor) {
+		t.Errorf("unexpected fields used by %+v", q)
+	}
+}
+
+func TestDocumentQuery_Snippets(t *testing.T) {
+	author, tag := "Herman Melville", "Classics"
+	doc := &Document{Title: "Moby-Dick; or, The Whale", Author: &author, Tag: &tag}
+	text := "Call me Ishmael. " + strings.Repeat("x", 200) + " the whale, the WHALE. " + strings.Repeat("y", 200) + " a whale"
+
+	q, err := ParseDocumentQuery(`whale author:/mel+/ tag:classics -ishmael`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snippets := q.Snippets(doc, text)
+	if len(snippets) != 5 {
+		t.Fatalf("got %d snippets, want title, author, tag and two of content: %+v", len(snippets), snippets)
+	}
+	for _, s := range snippets {
+		for _, m := range s.Marks {
+			marked := strings.ToLower(string([]rune(s.Text)[m.Start:m.End]))
+			if marked != "whale" && marked != "mel" && marked != "classics" {
+				t.Errorf("%s snippet %q marks %q", s.Field, s.Text, marked)
+			}
+		}
+	}
+	if s := snippets[3]; s.Field != "content" || len(s.Marks) != 2 || strings.Contains(s.Text, "Ishmael") {
+		t.Errorf("first content snippet: %+v", s)
+	}
+	if len([]rune(snippets[3].Text)) > 2*SearchSnippetContext+len("whale, the WHALE") {
+		t.Errorf("content snippet is too long: %q", snippets[3].Text)
+	}
+
+	// A tag term marks only a tag it matches in full.
+	q, _ = ParseDocumentQuery("tag:class")
+	if snippets := q.Snippets(doc, text); len(snippets) != 0 {
+		t.Errorf("tag:class: got %+v", snippets)
+	}
+}
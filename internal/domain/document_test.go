@@ -2,6 +2,8 @@ package domain
 
 import (
 	"encoding/json"
+	"net/url"
+	"slices"
 	"testing"
 	"time"
 )
@@ -600,3 +602,99 @@ func TestDocument_ContentJSONValidity(t *testing.T) {
 		})
 	}
 }
+
+func TestArchiveFilter(t *testing.T) {
+	docs := []*Document{{ID: "active"}, {ID: "archived", IsArchived: true}}
+
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", []string{"active"}},
+		{"false", []string{"active"}},
+		{"true", []string{"archived"}},
+		{"all", []string{"active", "archived"}},
+	}
+	for _, tt := range tests {
+		filter, err := ParseArchiveFilter(tt.value)
+		if err != nil {
+			t.Fatalf("ParseArchiveFilter(%q): %v", tt.value, err)
+		}
+		var got []string
+		for _, doc := range filter.Apply(docs) {
+			got = append(got, doc.ID)
+		}
+		if len(got) != len(tt.want) || (len(got) > 0 && got[0] != tt.want[0]) || (len(got) > 1 && got[1] != tt.want[1]) {
+			t.Errorf("archived=%q: expected %v, got %v", tt.value, tt.want, got)
+		}
+	}
+
+	if _, err := ParseArchiveFilter("yes"); err == nil {
+		t.Error("expected an error for an unknown value")
+	}
+}
+
+func TestLibraryQuery(t *testing.T) {
+	day := func(d int) *time.Time {
+		at := time.Date(2026, 3, d, 12, 0, 0, 0, time.UTC)
+		return &at
+	}
+	docs := []*Document{
+		{ID: "never", Title: "beta", UpdatedAt: *day(9), CreatedAt: *day(1)},
+		{ID: "old", Title: "Alpha", UpdatedAt: *day(2), CreatedAt: *day(2), LastOpenedAt: day(3)},
+		{ID: "recent", Title: "gamma", UpdatedAt: *day(1), CreatedAt: *day(3), LastOpenedAt: day(8)},
+		{ID: "archived", Title: "delta", IsArchived: true, LastOpenedAt: day(9)},
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"never", "old", "recent"}},
+		{"sort=last_opened", []string{"recent", "old", "never"}},
+		{"sort=updated", []string{"never", "old", "recent"}},
+		{"sort=created", []string{"recent", "old", "never"}},
+		{"sort=title", []string{"old", "never", "recent"}},
+		{"sort=last_opened&opened_since=2026-03-05T00:00:00Z", []string{"recent"}},
+		{"sort=last_opened&archived=all", []string{"archived", "recent", "old", "never"}},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		query, err := ParseLibraryQuery(values)
+		if err != nil {
+			t.Fatalf("ParseLibraryQuery(%q): %v", tt.query, err)
+		}
+		var got []string
+		for _, doc := range query.Apply(docs) {
+			got = append(got, doc.ID)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("%q: expected %v, got %v", tt.query, tt.want, got)
+		}
+	}
+	if docs[0].ID != "never" {
+		t.Error("sorting reordered the caller's slice")
+	}
+
+	for _, invalid := range []string{"sort=oldest", "opened_since=yesterday", "archived=yes"} {
+		values, _ := url.ParseQuery(invalid)
+		if _, err := ParseLibraryQuery(values); err == nil {
+			t.Errorf("%q: expected an error", invalid)
+		}
+	}
+}
+
+func TestStripUnsafeUnicodeEscapes(t *testing.T) {
+	tests := map[string]string{
+		`"a\u0000b\u001fc\u0009"`:      `"abc"`,
+		`"lone \ud83d\udcda pair"`:     `"lone  pair"`,
+		`"page\fbreak\bx"`:             `"pagebreakx"`,
+		`"literal \\u0001"`:            `"literal \\u0001"`,
+		`"kept \u00e9 and \n escapes"`: `"kept \u00e9 and \n escapes"`,
+	}
+	for in, want := range tests {
+		if got := StripUnsafeUnicodeEscapes(in); got != want {
+			t.Errorf("StripUnsafeUnicodeEscapes(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "context"
+
+// Reasons two documents are considered duplicates.
+const (
+	DuplicateSameFile      = "same_file"       // identical file hash
+	DuplicateSimilarTitle  = "similar_title"   // titles match after normalization or nearly so
+	DuplicateSameAuthor    = "same_author"     // authors match
+	DuplicateSamePageCount = "same_page_count" // page counts match
+)
+
+// DuplicateCluster is a group of documents that are likely copies of one another.
+type DuplicateCluster struct {
+	Documents []*DocumentData `json:"documents"`
+	Reasons   []string        `json:"reasons"`
+	// Confidence is 1 for identical files and lower for metadata matches.
+	Confidence float64 `json:"confidence"`
+	// CanonicalID is the suggested copy to keep: the one with the most highlights,
+	// then the oldest.
+	CanonicalID string `json:"canonical_id"`
+}
+
+// DuplicateMerge reports what a merge moved onto the canonical copy.
+type DuplicateMerge struct {
+	Document        *DocumentData `json:"document"`
+	MergedIDs       []string      `json:"merged_ids"`
+	HighlightsMoved int           `json:"highlights_moved"`
+	PositionAdopted bool          `json:"position_adopted"` // a duplicate's more recent position was kept
+}
+
+type DuplicateService interface {
+	// FindDuplicates clusters likely duplicates in the user's library, most confident first.
+	FindDuplicates(ctx context.Context, principal Principal) ([]*DuplicateCluster, error)
+	// MergeDuplicates moves the duplicates' highlights onto the canonical document,
+	// keeps the most recent reading position, favorite flag and tag, then deletes the
+	// duplicates.
+	MergeDuplicates(ctx context.Context, principal Principal, canonicalID string, duplicateIDs []string) (*DuplicateMerge, error)
+}
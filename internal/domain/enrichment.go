@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// BookQuery identifies a book to look up in an external catalog. ISBN is preferred;
+// catalogs fall back to searching by title and author without one.
+type BookQuery struct {
+	ISBN   string
+	Title  string
+	Author string
+}
+
+// BookInfo is what an external catalog knows about a book.
+type BookInfo struct {
+	Source          string // catalog name, e.g. "openlibrary"
+	SourceID        string // the book's ID in that catalog
+	Title           string
+	Authors         []string
+	Description     string
+	PublicationYear int
+	ISBN            string
+	CoverURL        string
+}
+
+// BookCatalog is an external book catalog such as OpenLibrary or Google Books.
+type BookCatalog interface {
+	Name() string
+	// Lookup returns the best match for the query, or ErrBookNotFound.
+	Lookup(ctx context.Context, query BookQuery) (*BookInfo, error)
+	// FetchCover downloads the cover image at info.CoverURL and returns its content type.
+	FetchCover(ctx context.Context, info *BookInfo) ([]byte, string, error)
+}
+
+// BookCatalogConfig configures the external catalogs. OpenLibrary needs no key;
+// Google Books works without one at a low shared quota.
+type BookCatalogConfig struct {
+	GoogleBooksAPIKey string
+}
+
+// MetadataEnrichment records which catalog filled in a document's details.
+type MetadataEnrichment struct {
+	Source     string    `json:"source"`
+	SourceID   string    `json:"source_id,omitempty"`
+	Fields     []string  `json:"fields"` // the fields that were filled in
+	EnrichedAt time.Time `json:"enriched_at"`
+}
+
+type EnrichmentService interface {
+	// EnrichDocument fills in the document's missing author, description, publication
+	// year, ISBN and cover from the first catalog that knows the book. Details the
+	// user already has are kept. Returns ErrBookNotFound when no catalog matches.
+	EnrichDocument(ctx context.Context, principal Principal, documentID string) (*DocumentData, error)
+}
@@ -1,24 +1,67 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"strings"
+	"time"
+)
 
 // Domain errors
 var (
 	ErrDocumentNotFound        = errors.New("document not found")
 	ErrAccessDenied            = errors.New("access denied")
+	ErrAccountDisabled         = errors.New("account disabled")
 	ErrReadingPositionNotFound = errors.New("reading position not found")
 	ErrUserNotFound            = errors.New("user not found")
 	ErrInvalidToken            = errors.New("invalid token")
+	ErrInvalidCredentials      = errors.New("invalid email or password")
+	ErrEmailTaken              = errors.New("an account with this email already exists")
 	ErrStorageLimitExceeded    = errors.New("storage limit exceeded")
+	ErrFileTooLarge            = errors.New("file too large")
 	ErrInvalidFile             = errors.New("invalid file")
+	ErrUnsupportedFileType     = errors.New("unsupported file type")
+	ErrDocumentQuarantined     = errors.New("document is quarantined")
+	ErrPageNotFound            = errors.New("page not found")
+	ErrDocumentNotComparable   = errors.New("document has no comparable text")
+	ErrDocumentHasNoText       = errors.New("document has no extracted text")
+	ErrLocatorNotFound         = errors.New("location not found in the document")
+	ErrHighlightNotFound       = errors.New("highlight not found")
+	ErrStaleUpdate             = errors.New("resource was modified since it was read")
+	ErrVersionNotFound         = errors.New("document version not found")
+	ErrFontNotFound            = errors.New("font not found")
+	ErrPlanRequired            = errors.New("feature requires a Pro plan")
+	ErrFontLimitReached        = errors.New("font limit reached")
+	ErrThemeNotFound           = errors.New("theme not found")
+	ErrThemeExists             = errors.New("a theme with this name already exists")
+	ErrThemeLimitReached       = errors.New("theme limit reached")
+	ErrSavedSearchNotFound     = errors.New("saved search not found")
+	ErrSavedSearchExists       = errors.New("a saved search with this name already exists")
+	ErrSavedSearchLimitReached = errors.New("saved search limit reached")
 	ErrTagNotFound             = errors.New("tag not found")
 	ErrTagAlreadyExists        = errors.New("tag already exists")
+	ErrDeviceUnregistered      = errors.New("device token is no longer registered")
+	ErrCloudProviderNotFound   = errors.New("cloud provider not configured")
+	ErrCloudNotConnected       = errors.New("cloud drive not connected")
+	ErrImportJobNotFound       = errors.New("import job not found")
+	ErrAuthorNotFound          = errors.New("author not found")
+	ErrSeriesNotFound          = errors.New("series not found")
+	ErrBookNotFound            = errors.New("book not found in external catalogs")
+	ErrCatalogUnavailable      = errors.New("external book catalog unavailable")
+	ErrBlobNotFound            = errors.New("file not found in storage")
+	ErrNotReprocessable        = errors.New("document format has no content to re-extract")
+	ErrUploadNotFound          = errors.New("upload not found")
+	ErrUploadInProgress        = errors.New("an upload with this ID is already in progress")
+	ErrTooManyUploads          = errors.New("too many uploads in progress")
+	ErrUpstreamUnavailable     = errors.New("service temporarily unavailable")
+	ErrPageRenderDisabled      = errors.New("page rendering is not enabled")
+	ErrNotRenderable           = errors.New("document cannot be rendered to page images")
+	ErrRenderNotFound          = errors.New("document pages have not been rendered")
 )
 
 // ValidationError represents a validation error with field and message information.
 type ValidationError struct {
-	Field   string
-	Message string
+	Field   string `json:"field"`
+	Message string `json:"message"`
 }
 
 func (e *ValidationError) Error() string {
@@ -27,3 +70,69 @@ func (e *ValidationError) Error() string {
 	}
 	return e.Message
 }
+
+// ValidationErrors reports every invalid field of a request at once.
+type ValidationErrors []*ValidationError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, 0, len(e))
+	for _, err := range e {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+// PDF validation failure codes. They are returned to clients so the UI can explain
+// what is wrong with a file instead of showing a generic error.
+const (
+	PDFErrorCorrupt            = "pdf_corrupt"
+	PDFErrorUnsupportedVersion = "pdf_unsupported_version"
+	PDFErrorNoPages            = "pdf_no_pages"
+	PDFErrorEncrypted          = "pdf_encrypted"
+	PDFErrorTooLarge           = "pdf_too_large"   // over the page or extracted text limit
+	PDFErrorTooComplex         = "pdf_too_complex" // extraction ran past the time limit
+)
+
+// PDFValidationError describes why an uploaded PDF cannot be read.
+type PDFValidationError struct {
+	Code    string
+	Message string
+	Err     error
+}
+
+func (e *PDFValidationError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *PDFValidationError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every PDF validation failure match ErrInvalidFile.
+func (e *PDFValidationError) Is(target error) bool {
+	return target == ErrInvalidFile
+}
+
+// UpstreamError wraps a failure of a backing service (Supabase) that is throttling
+// requests or cannot be reached. RetryAfter is how long clients should wait before
+// trying again.
+type UpstreamError struct {
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *UpstreamError) Error() string {
+	return ErrUpstreamUnavailable.Error() + ": " + e.Err.Error()
+}
+
+func (e *UpstreamError) Unwrap() error {
+	return e.Err
+}
+
+// Is makes every upstream failure match ErrUpstreamUnavailable.
+func (e *UpstreamError) Is(target error) bool {
+	return target == ErrUpstreamUnavailable
+}
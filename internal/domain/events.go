@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Event types published about a user's library.
+const (
+	// EventDocumentProcessed is published when background processing of an upload
+	// finishes and the document can be read.
+	EventDocumentProcessed = "document.processed"
+	// EventIngestionDone is published when an upload has been stored and its document
+	// created; large PDFs are still being processed at that point.
+	EventIngestionDone = "ingestion.done"
+	// EventQuotaWarning is published when an upload takes the user past
+	// QuotaWarningRatio of their storage limit.
+	EventQuotaWarning = "quota.warning"
+)
+
+// QuotaWarningRatio is the share of the storage limit at which users are warned.
+const QuotaWarningRatio = 0.9
+
+// DocumentEvent is the data of document events.
+type DocumentEvent struct {
+	DocumentID string `json:"document_id"`
+	Title      string `json:"title"`
+}
+
+// QuotaWarning is the data of EventQuotaWarning.
+type QuotaWarning struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	LimitBytes int64 `json:"limit_bytes"`
+}
+
+// Event is something that happened to a user's data, delivered to their devices and
+// open event streams.
+type Event struct {
+	Type string    `json:"type"`
+	Data any       `json:"data,omitempty"`
+	At   time.Time `json:"at"`
+}
+
+// EventPublisher delivers events to the user they concern. Publishing never fails
+// the caller; publishers log their own delivery errors.
+type EventPublisher interface {
+	Publish(ctx context.Context, principal Principal, event Event)
+}
+
+// EventBroker hands published events to the user's open event streams.
+type EventBroker interface {
+	EventPublisher
+	// Subscribe returns the user's events until ctx is done, when the channel is
+	// closed. Events are dropped for a subscriber that does not keep up.
+	Subscribe(ctx context.Context, principal Principal) <-chan Event
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"strings"
+	"time"
+)
+
+// Custom font formats accepted for upload.
+const (
+	FontFormatWOFF2 = "woff2"
+	FontFormatTTF   = "ttf"
+)
+
+// Custom font limits per user.
+const (
+	MaxFontBytes    = 5 * 1024 * 1024
+	MaxFontsPerUser = 20
+)
+
+// CustomFontPrefix marks a font_family preference that refers to an uploaded font,
+// as in "custom:<font id>".
+const CustomFontPrefix = "custom:"
+
+// Font is a font file uploaded by a Pro user for use in the reader.
+type Font struct {
+	ID        string    `json:"id"`
+	UserID    string    `json:"user_id"`
+	Name      string    `json:"name"`
+	Format    string    `json:"format"`
+	SizeBytes int64     `json:"size_bytes"`
+	Path      string    `json:"-"`             // Storage path of the font file
+	URL       string    `json:"url,omitempty"` // Short-lived signed download URL
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FontFamily is the font_family preference value selecting this font.
+func (f *Font) FontFamily() string {
+	return CustomFontPrefix + f.ID
+}
+
+// CustomFontID returns the font ID referenced by a font_family preference, if any.
+func CustomFontID(fontFamily string) (string, bool) {
+	id, ok := strings.CutPrefix(fontFamily, CustomFontPrefix)
+	return id, ok && id != ""
+}
+
+// FontRepository defines persistence operations for uploaded fonts.
+type FontRepository interface {
+	Create(ctx context.Context, principal Principal, font *Font) (*Font, error)
+	ListByUser(ctx context.Context, principal Principal) ([]*Font, error)
+	Get(ctx context.Context, principal Principal, fontID string) (*Font, error)
+	Delete(ctx context.Context, principal Principal, fontID string) error
+}
+
+// FontService defines the use-case operations for uploaded fonts.
+type FontService interface {
+	// UploadFont stores a woff2 or ttf file; only Pro plans may upload fonts.
+	UploadFont(ctx context.Context, principal Principal, file io.Reader, filename string) (*Font, error)
+	// GetFont returns one of the user's fonts, or ErrFontNotFound.
+	GetFont(ctx context.Context, principal Principal, fontID string) (*Font, error)
+	// ListFonts returns the user's fonts with signed download URLs.
+	ListFonts(ctx context.Context, principal Principal) ([]*Font, error)
+	// DeleteFont removes a font; preferences using it fall back to the default font.
+	DeleteFont(ctx context.Context, principal Principal, fontID string) error
+}
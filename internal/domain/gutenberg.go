@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// GutenbergSource is the metadata.source of documents imported from Project Gutenberg.
+const GutenbergSource = "gutenberg"
+
+// GutenbergBook is a Project Gutenberg ebook as listed in its catalog.
+type GutenbergBook struct {
+	ID            int      `json:"id"`
+	Title         string   `json:"title"`
+	Authors       []string `json:"authors"`
+	Subjects      []string `json:"subjects,omitempty"`
+	Languages     []string `json:"languages,omitempty"`
+	DownloadCount int      `json:"download_count"`
+	CoverURL      string   `json:"cover_url,omitempty"`
+	// HasEPUB is false for audio books and scans, which cannot be imported.
+	HasEPUB bool `json:"has_epub"`
+}
+
+// GutenbergSearchResult is one page of catalog matches, most downloaded first.
+type GutenbergSearchResult struct {
+	Books    []GutenbergBook `json:"books"`
+	Total    int             `json:"total"`
+	Page     int             `json:"page"`
+	NextPage int             `json:"next_page,omitempty"` // 0 on the last page
+}
+
+// GutenbergCatalog searches Project Gutenberg and downloads its EPUBs.
+type GutenbergCatalog interface {
+	// Search returns a page (from 1) of books whose title or author matches query.
+	Search(ctx context.Context, query string, page int) (*GutenbergSearchResult, error)
+	// Book returns one book, or ErrBookNotFound.
+	Book(ctx context.Context, id int) (*GutenbergBook, error)
+	// DownloadEPUB opens the book's EPUB from the first mirror that serves it; the
+	// caller closes it.
+	DownloadEPUB(ctx context.Context, id int) (io.ReadCloser, error)
+}
+
+// GutenbergConfig configures the Project Gutenberg catalog. The catalog is searched
+// through a Gutendex instance and books are downloaded from the mirrors in order.
+type GutenbergConfig struct {
+	CatalogURL string
+	Mirrors    []string
+}
+
+type GutenbergService interface {
+	SearchCatalog(ctx context.Context, query string, page int) (*GutenbergSearchResult, error)
+	// ImportBook adds the book to the user's library as an EPUB with the catalog's
+	// title and authors. Returns ErrBookNotFound for unknown books and books without
+	// an EPUB.
+	ImportBook(ctx context.Context, principal Principal, id int) (*DocumentData, error)
+}
@@ -1,29 +1,109 @@
 package domain
 
-import "time"
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
 
 // Highlight represents a user's saved excerpt from a document.
 type Highlight struct {
-	ID         string  `json:"id"`
-	UserID     string  `json:"user_id"`
-	DocumentID string  `json:"document_id"`
-	Quote      string  `json:"quote"`
-	PageNumber *int    `json:"page_number,omitempty"`
+	ID         string   `json:"id"`
+	UserID     string   `json:"user_id"`
+	DocumentID string   `json:"document_id"`
+	Quote      string   `json:"quote"`
+	Note       string   `json:"note,omitempty"` // the reader's own comment
+	PageNumber *int     `json:"page_number,omitempty"`
 	Progress   *float32 `json:"progress,omitempty"`
-	CreatedAt  time.Time `json:"created_at"`
+	// Locator places an EPUB highlight structurally; page_number and progress are
+	// derived from it.
+	Locator *EPUBLocator `json:"locator,omitempty"`
+	// The text around the quote, used to tell its occurrences apart and to find it
+	// again after the document's text changes.
+	ContextBefore string `json:"context_before,omitempty"`
+	ContextAfter  string `json:"context_after,omitempty"`
+	// ContentVersion is the document content version the highlight was placed in.
+	// Highlights of an older version are re-anchored when listed.
+	ContentVersion int `json:"content_version"`
+	// Orphaned is set when the quote could not be found in the current text; the
+	// page and progress are then those of the older text.
+	Orphaned bool `json:"orphaned,omitempty"`
+	// Daily review schedule: the interval grows with each review, and the highlight
+	// is due again once it has passed.
+	IsFavorite     bool       `json:"is_favorite,omitempty"`
+	ReviewCount    int        `json:"review_count,omitempty"`
+	ReviewInterval int        `json:"review_interval_days,omitempty"`
+	ReviewDueAt    *time.Time `json:"review_due_at,omitempty"`
+	ReviewedAt     *time.Time `json:"reviewed_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
 }
 
 // HighlightRepository defines persistence operations for highlights.
 type HighlightRepository interface {
-	Create(highlight *Highlight, token string) (*Highlight, error)
-	ListByUser(userID string, documentID *string, token string) ([]*Highlight, error)
-	Delete(userID string, highlightID string, token string) error
+	Create(ctx context.Context, principal Principal, highlight *Highlight) (*Highlight, error)
+	ListByUser(ctx context.Context, principal Principal, documentID *string) ([]*Highlight, error)
+	// CountByDocuments returns the number of highlights of each given document in one
+	// round trip. Documents without highlights are absent.
+	CountByDocuments(ctx context.Context, principal Principal, documentIDs []string) (map[string]int, error)
+	Delete(ctx context.Context, principal Principal, highlightID string) error
+	// MoveToDocument reassigns the highlights of the given documents to another
+	// document and returns how many were moved.
+	MoveToDocument(ctx context.Context, principal Principal, fromDocumentIDs []string, toDocumentID string) (int, error)
+	// UpdateAnchor stores where a highlight is: its page, progress, locator, context,
+	// content version and whether it is orphaned.
+	UpdateAnchor(ctx context.Context, principal Principal, highlight *Highlight) error
+	// Search returns the highlights matching a full-text search, best matches first.
+	Search(ctx context.Context, principal Principal, search HighlightSearch) ([]*Highlight, error)
+	Get(ctx context.Context, principal Principal, highlightID string) (*Highlight, error)
+	// UpdateReview stores a highlight's favorite flag and review schedule.
+	UpdateReview(ctx context.Context, principal Principal, highlight *Highlight) error
 }
 
 // HighlightService defines the use-case operations for highlights.
 type HighlightService interface {
-	CreateHighlight(userID string, highlight *Highlight, token string) (*Highlight, error)
-	ListHighlights(userID string, documentID *string, token string) ([]*Highlight, error)
-	DeleteHighlight(userID string, highlightID string, token string) error
+	CreateHighlight(ctx context.Context, principal Principal, highlight *Highlight) (*Highlight, error)
+	ListHighlights(ctx context.Context, principal Principal, documentID *string) ([]*Highlight, error)
+	CountHighlights(ctx context.Context, principal Principal, documentIDs []string) (map[string]int, error)
+	DeleteHighlight(ctx context.Context, principal Principal, highlightID string) error
+	SearchHighlights(ctx context.Context, principal Principal, search HighlightSearch) ([]*Highlight, error)
+	// GetReview returns today's highlights to review, at most size of them.
+	GetReview(ctx context.Context, principal Principal, size int) (*HighlightReview, error)
+	// ReviewHighlight records a review with a rating and schedules the next one.
+	ReviewHighlight(ctx context.Context, principal Principal, highlightID, rating string) (*Highlight, error)
+	SetFavorite(ctx context.Context, principal Principal, highlightID string, isFavorite bool) (*Highlight, error)
 }
 
+// Results returned by a highlight search by default and at most.
+const (
+	DefaultHighlightSearchLimit = 50
+	MaxHighlightSearchLimit     = 200
+)
+
+// HighlightSearch is a full-text search over the quotes and notes of a user's
+// highlights. Query uses web search syntax: words, "quoted phrases", OR and -word.
+type HighlightSearch struct {
+	Query      string
+	DocumentID string // only highlights of this document
+	Tag        string // only highlights of documents with this tag
+	Limit      int
+}
+
+// Validate checks the query and fills in the default limit.
+func (s *HighlightSearch) Validate() error {
+	var errs ValidationErrors
+	s.Query = strings.TrimSpace(s.Query)
+	if s.Query == "" {
+		errs = append(errs, &ValidationError{Field: "q", Message: "a search query is required"})
+	}
+	switch {
+	case s.Limit == 0:
+		s.Limit = DefaultHighlightSearchLimit
+	case s.Limit < 0 || s.Limit > MaxHighlightSearchLimit:
+		errs = append(errs, &ValidationError{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", MaxHighlightSearchLimit)})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
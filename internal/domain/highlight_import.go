@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"io"
+)
+
+// Highlight exports that can be imported.
+const (
+	HighlightExportKindle = "kindle"
+	HighlightExportKobo   = "kobo"
+)
+
+// MaxHighlightExportSize bounds the export file accepted by a highlights import.
+const MaxHighlightExportSize int64 = 20 << 20 // 20MB
+
+// HighlightImportResult reports what a highlights import did, book by book.
+type HighlightImportResult struct {
+	Format   string `json:"format"`
+	Imported int    `json:"imported"`
+	// Duplicates were already in the library, from an earlier import or otherwise.
+	Duplicates int                    `json:"duplicates"`
+	Books      []*HighlightImportBook `json:"books"`
+}
+
+// HighlightImportBook is one book of the export and the library document its
+// highlights went to. Books without a matching document are listed so the reader
+// can upload them and import again.
+type HighlightImportBook struct {
+	Title         string `json:"title"`
+	Author        string `json:"author,omitempty"`
+	DocumentID    string `json:"document_id,omitempty"`
+	DocumentTitle string `json:"document_title,omitempty"`
+	Highlights    int    `json:"highlights"`
+	Imported      int    `json:"imported"`
+	// Estimated highlights were not found in the document's text; they are placed by
+	// the page or location the reading app recorded.
+	Estimated int `json:"estimated"`
+}
+
+type HighlightImportService interface {
+	// ImportHighlights reads a Kindle "My Clippings.txt" or a Kobo annotation export,
+	// matches its books to library documents by title and author, and creates their
+	// highlights. Highlights already in the library are skipped, so the same export
+	// can be imported again as it grows.
+	ImportHighlights(ctx context.Context, principal Principal, export io.Reader) (*HighlightImportResult, error)
+}
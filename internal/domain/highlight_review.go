@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"time"
+)
+
+// Highlights reviewed per day by default and at most.
+const (
+	DefaultHighlightReviewSize = 5
+	MaxHighlightReviewSize     = 50
+)
+
+// How well a reader remembered a reviewed highlight. It decides how long until the
+// highlight comes back.
+const (
+	ReviewAgain = "again"
+	ReviewGood  = "good"
+	ReviewEasy  = "easy"
+)
+
+// Review intervals: the first after a good or easy review, and the longest. Favorites
+// keep coming back at least every MaxFavoriteReviewInterval.
+const (
+	FirstReviewInterval       = 3 * 24 * time.Hour
+	FirstEasyReviewInterval   = 7 * 24 * time.Hour
+	MaxReviewInterval         = 365 * 24 * time.Hour
+	MaxFavoriteReviewInterval = 60 * 24 * time.Hour
+)
+
+// HighlightReview is the day's selection of highlights to review: those due first,
+// then never-reviewed ones in an order that changes daily. Highlights reviewed today
+// count towards the day's size.
+type HighlightReview struct {
+	Date       string       `json:"date"` // YYYY-MM-DD (UTC)
+	Highlights []*Highlight `json:"highlights"`
+	Reviewed   int          `json:"reviewed"`  // highlights already reviewed today
+	Remaining  int          `json:"remaining"` // due or new highlights not selected
+}
+
+// ValidateReviewRating checks a rating, where empty means good.
+func ValidateReviewRating(rating string) error {
+	if rating == "" || slices.Contains([]string{ReviewAgain, ReviewGood, ReviewEasy}, rating) {
+		return nil
+	}
+	return ValidationErrors{{Field: "rating", Message: fmt.Sprintf("rating must be %s, %s or %s", ReviewAgain, ReviewGood, ReviewEasy)}}
+}
@@ -11,9 +11,37 @@ type Logger interface {
 // Config defines the interface for configuration management
 type Config interface {
 	GetServerPort() string
+	GetGRPCPort() string
+	GetGraphQLEnabled() bool
+	GetMaintenanceMode() bool
+	GetStarterContentEnabled() bool
+	GetTrustedProxies() int
 	GetMaxFileSize() int64
+	GetUploadLimits() UploadLimits
+	GetPDFLimits() PDFLimits
 	GetLogLevel() string
 	GetSupabaseURL() string
 	GetSupabaseKey() string
 	GetJWTSecret() string
+	GetDatabaseURL() string
+	GetRepositoryBackend() string
+	GetScannerBackend() string
+	GetClamAVAddress() string
+	GetScannerURL() string
+	GetScannerAPIKey() string
+	GetStorageBackend() string
+	GetLocalStorageDir() string
+	GetPublicURL() string
+	GetS3Config() S3Config
+	GetSelfHosted() bool
+	GetEmailConfig() EmailConfig
+	GetDigestConfig() DigestConfig
+	GetProcessingRecoveryConfig() ProcessingRecoveryConfig
+	GetPushConfig() PushConfig
+	GetCloudImportConfig() CloudImportConfig
+	GetBookCatalogConfig() BookCatalogConfig
+	GetGutenbergConfig() GutenbergConfig
+	GetPageRenderConfig() PageRenderConfig
+	GetEnvironment() string
+	GetCORSAllowedOrigins() []string
 }
@@ -0,0 +1,53 @@
+package domain
+
+import (
+	"context"
+	"regexp"
+)
+
+// EPUBLocator is a structural position in an EPUB, in the spirit of an EPUB CFI:
+// the spine document, the steps to an element inside it and a character offset into
+// that element's text. Unlike page numbers and block positions it does not depend
+// on how the server splits a book into blocks, so it survives re-extraction.
+type EPUBLocator struct {
+	Spine  string `json:"spine"`  // archive path of the spine document
+	Path   string `json:"path"`   // element steps below the root element, e.g. "/4/2/6"
+	Offset int    `json:"offset"` // characters into the element's text
+}
+
+var locatorPathPattern = regexp.MustCompile(`^(/[0-9]+)*$`)
+
+// Validate checks the locator is well formed; whether it exists in a book is only
+// known against the book's content.
+func (l *EPUBLocator) Validate() error {
+	var errs ValidationErrors
+	if l.Spine == "" {
+		errs = append(errs, &ValidationError{Field: "locator.spine", Message: "spine document is required"})
+	}
+	if !locatorPathPattern.MatchString(l.Path) {
+		errs = append(errs, &ValidationError{Field: "locator.path", Message: "must be element steps such as /4/2"})
+	}
+	if l.Offset < 0 {
+		errs = append(errs, &ValidationError{Field: "locator.offset", Message: "offset cannot be negative"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// LocatorMigration reports a backfill of locators onto records that only had a page
+// number, progress or character offset.
+type LocatorMigration struct {
+	Documents  int `json:"documents"`  // EPUBs examined
+	Positions  int `json:"positions"`  // reading positions given a locator
+	Highlights int `json:"highlights"` // highlights given a locator
+	// Skipped counts EPUBs extracted before locators existed; they need reprocessing.
+	Skipped int `json:"skipped"`
+}
+
+type LocatorService interface {
+	// MigrateLocators gives the reading positions and highlights of the user's EPUBs
+	// that lack one a locator. Highlights are placed by their quote when it is found.
+	MigrateLocators(ctx context.Context, principal Principal) (*LocatorMigration, error)
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaintenanceStatus is the state of maintenance mode as reported to admins.
+type MaintenanceStatus struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+// MaintenanceMode is the switch behind maintenance mode. While it is on, the HTTP
+// and gRPC APIs refuse writes so the documents schema can be migrated, and reads
+// keep working.
+type MaintenanceMode interface {
+	// Status returns the current state.
+	Status() MaintenanceStatus
+	// Set switches maintenance mode on or off; an empty message uses a default one.
+	Set(enabled bool, message string) MaintenanceStatus
+}
+
+type readOnlyContextKey struct{}
+
+// ContextWithReadOnly returns a copy of ctx for a read served during maintenance.
+// Services skip the bookkeeping writes they would otherwise make on a read, such
+// as recording when a document was opened.
+func ContextWithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyContextKey{}, true)
+}
+
+// ReadOnlyFromContext reports whether ctx was marked by ContextWithReadOnly.
+func ReadOnlyFromContext(ctx context.Context) bool {
+	readOnly, _ := ctx.Value(readOnlyContextKey{}).(bool)
+	return readOnly
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MigrationStatus describes one embedded schema migration. AppliedAt is nil while the
+// migration is pending.
+type MigrationStatus struct {
+	Version   int        `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// SchemaMigrator applies the versioned SQL migrations shipped with the server.
+type SchemaMigrator interface {
+	// Migrate applies every pending migration in order and returns the ones it applied.
+	Migrate(ctx context.Context) ([]MigrationStatus, error)
+	// Status lists all known migrations, applied or not.
+	Status(ctx context.Context) ([]MigrationStatus, error)
+}
@@ -0,0 +1,84 @@
+package domain
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Platforms a push token can belong to.
+const (
+	DevicePlatformIOS     = "ios"
+	DevicePlatformAndroid = "android"
+	DevicePlatformWeb     = "web"
+)
+
+// maxDeviceTokenLength bounds registered push tokens; FCM tokens are about 160
+// characters and APNs tokens 64.
+const maxDeviceTokenLength = 4096
+
+// Device is a push token registered by one of the user's apps.
+type Device struct {
+	UserID    string    `json:"user_id"`
+	Token     string    `json:"token"`
+	Platform  string    `json:"platform"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Validate checks the token and platform of a device being registered.
+func (d *Device) Validate() error {
+	var errs ValidationErrors
+	if token := strings.TrimSpace(d.Token); token == "" || len(token) > maxDeviceTokenLength {
+		errs = append(errs, &ValidationError{Field: "token", Message: "token is required"})
+	}
+	if !oneOf(d.Platform, DevicePlatformIOS, DevicePlatformAndroid, DevicePlatformWeb) {
+		errs = append(errs, &ValidationError{Field: "platform", Message: "platform must be ios, android or web"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+type DeviceRepository interface {
+	// Upsert registers the device, or refreshes it when the token is already known.
+	Upsert(ctx context.Context, principal Principal, device *Device) error
+	ListByUser(ctx context.Context, principal Principal) ([]*Device, error)
+	Delete(ctx context.Context, principal Principal, token string) error
+}
+
+// PushNotification is a message shown on the user's devices. Data is passed to the
+// app so it can open the right screen.
+type PushNotification struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// PushSender delivers a notification to one device. It returns ErrDeviceUnregistered
+// when the provider reports the token as no longer valid.
+type PushSender interface {
+	Send(ctx context.Context, device *Device, notification *PushNotification) error
+}
+
+// PushConfig configures the push providers. FCM serves Android and web devices,
+// APNs serves iOS; an unconfigured provider leaves its platforms without pushes.
+type PushConfig struct {
+	FCMCredentialsFile string // Google service account JSON with FCM access
+	APNsKeyFile        string // .p8 signing key
+	APNsKeyID          string
+	APNsTeamID         string
+	APNsTopic          string // the iOS app's bundle ID
+	APNsSandbox        bool
+}
+
+type NotificationService interface {
+	EventPublisher
+	RegisterDevice(ctx context.Context, principal Principal, device *Device) (*Device, error)
+	UnregisterDevice(ctx context.Context, principal Principal, token string) error
+	ListDevices(ctx context.Context, principal Principal) ([]*Device, error)
+	// Notify pushes the notification to every device of the user and returns how
+	// many accepted it. Tokens the provider rejects as unregistered are removed.
+	Notify(ctx context.Context, principal Principal, notification *PushNotification) (int, error)
+}
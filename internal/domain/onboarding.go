@@ -0,0 +1,24 @@
+package domain
+
+import "context"
+
+// StarterContentSource is the metadata.source of documents added by onboarding.
+const StarterContentSource = "starter"
+
+// OnboardingRepository tracks the accounts waiting for onboarding. Signing up
+// queues an account; accounts created before onboarding existed are never queued.
+type OnboardingRepository interface {
+	// ClaimOnboarding marks the account's queued onboarding done and reports whether
+	// this call did so, so concurrent calls onboard an account once. Accounts that
+	// are not queued report false.
+	ClaimOnboarding(ctx context.Context, principal Principal) (bool, error)
+}
+
+// OnboardingService prepares new accounts right after sign-up
+// (STARTER_CONTENT_ENABLED=true).
+type OnboardingService interface {
+	// OnboardUser adds the starter content to a newly signed-up account, if its
+	// library is empty. Calls for accounts already onboarded, or that existed
+	// before onboarding, do nothing.
+	OnboardUser(ctx context.Context, principal Principal) error
+}
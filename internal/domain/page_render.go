@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Page render statuses.
+const (
+	PageRenderRendering = "rendering"
+	PageRenderReady     = "ready"
+	PageRenderFailed    = "failed"
+)
+
+// PageRenderConfig configures the rasterization of PDF pages into image tiles for
+// the fixed-layout viewer (PAGE_RENDER_ENABLED=true).
+type PageRenderConfig struct {
+	Enabled bool
+	// Scales are the zoom levels, as multiples of 72 dpi, e.g. 1, 2 and 3.
+	Scales []float64
+	// TileSize is the width and height of a tile in pixels.
+	TileSize int
+	// MaxPages refuses to render longer documents; 0 disables the limit.
+	MaxPages int
+}
+
+// PageRenderLevel is one zoom level of a rendered page, cut into Columns x Rows
+// tiles. Tiles on the right and bottom edges may be smaller than the tile size.
+type PageRenderLevel struct {
+	Scale   float64 `json:"scale"`
+	Width   int     `json:"width"`
+	Height  int     `json:"height"`
+	Columns int     `json:"columns"`
+	Rows    int     `json:"rows"`
+}
+
+// RenderedPage is a page in points (1/72 inch) and its zoom levels, in the order
+// of the manifest's scales.
+type RenderedPage struct {
+	Page   int               `json:"page"`
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	Levels []PageRenderLevel `json:"levels"`
+}
+
+// PageRenderManifest describes the tiles rendered for a document. While Status is
+// rendering, Pages lists the pages finished so far.
+type PageRenderManifest struct {
+	DocumentID string         `json:"document_id"`
+	Status     string         `json:"status"`
+	Error      string         `json:"error,omitempty"`
+	PageCount  int            `json:"page_count"`
+	TileSize   int            `json:"tile_size"`
+	Scales     []float64      `json:"scales"`
+	Pages      []RenderedPage `json:"pages"`
+	// TileURL is the template for tile URLs, filled in by the API.
+	TileURL string `json:"tile_url,omitempty"`
+	// SourceSHA256 is the digest of the original the tiles were rendered from.
+	SourceSHA256 string    `json:"source_sha256,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// PageRenderService renders PDF pages to tiled images stored next to the document.
+type PageRenderService interface {
+	// RenderPages starts rendering the document in the background and returns the
+	// manifest as it stands. Pages already rendered from the same file are kept.
+	RenderPages(ctx context.Context, principal Principal, documentID string) (*PageRenderManifest, error)
+	// GetRenderManifest returns ErrRenderNotFound until rendering has started.
+	GetRenderManifest(ctx context.Context, principal Principal, documentID string) (*PageRenderManifest, error)
+	// OpenTile opens one PNG tile of a page (1-indexed) at a zoom level (0-indexed).
+	OpenTile(ctx context.Context, principal Principal, documentID string, page, level, column, row int) (io.ReadCloser, error)
+	// GetPageLayout returns the words of a page (1-indexed) and where they are.
+	GetPageLayout(ctx context.Context, principal Principal, documentID string, page int) (*PageLayout, error)
+}
+
+// PageLayout is the text of a PDF page with the position of each word, for
+// selecting text on rendered page images. Coordinates are in points from the
+// top-left corner of the page.
+type PageLayout struct {
+	DocumentID string `json:"document_id"`
+	Page       int    `json:"page"`
+	Width      int    `json:"width"`
+	Height     int    `json:"height"`
+	// Text is the page's text, one line per line of the page. Word offsets count
+	// characters into it, so a selection maps to a quote and its context.
+	Text  string       `json:"text"`
+	Words []LayoutWord `json:"words"`
+	// SourceSHA256 is the digest of the original the layout was read from.
+	SourceSHA256 string `json:"source_sha256,omitempty"`
+}
+
+// LayoutWord is one word of a PageLayout and its bounding box.
+type LayoutWord struct {
+	Text   string  `json:"text"`
+	Offset int     `json:"offset"`
+	Line   int     `json:"line"`
+	X      float64 `json:"x"`
+	Y      float64 `json:"y"`
+	Width  float64 `json:"width"`
+	Height float64 `json:"height"`
+}
@@ -0,0 +1,53 @@
+package domain
+
+import "time"
+
+// Position history is sampled: a saved reading position is kept as a sample when the
+// last one is older than PositionSampleInterval or the reader moved by at least
+// PositionSampleJump of the document since it.
+const (
+	PositionSampleInterval = 15 * time.Minute
+	PositionSampleJump     = 0.05
+)
+
+// Days of history returned by default and at most.
+const (
+	DefaultPositionHistoryDays = 30
+	MaxPositionHistoryDays     = 365
+)
+
+// PositionSample is a reading position as it was at one moment.
+type PositionSample struct {
+	DocumentID string    `json:"document_id"`
+	Progress   float32   `json:"progress"`
+	PageNumber int       `json:"page_number"`
+	CharOffset *int      `json:"char_offset,omitempty"`
+	RecordedAt time.Time `json:"recorded_at"`
+}
+
+// PositionDay summarizes a day of reading a document (UTC).
+type PositionDay struct {
+	Date      string  `json:"date"`     // YYYY-MM-DD
+	Progress  float32 `json:"progress"` // at the end of the day
+	PagesRead int     `json:"pages_read"`
+}
+
+// PositionHistory is a document's reading timeline, oldest sample first.
+type PositionHistory struct {
+	DocumentID string            `json:"document_id"`
+	Samples    []*PositionSample `json:"samples"`
+	Days       []PositionDay     `json:"days"`
+}
+
+// ShouldSample reports whether position is worth keeping in the history after the
+// latest sample, which is nil when there is none yet.
+func ShouldSample(latest *PositionSample, position *ReadingPosition) bool {
+	if latest == nil {
+		return true
+	}
+	if position.UpdatedAt.Sub(latest.RecordedAt) >= PositionSampleInterval {
+		return true
+	}
+	jump := position.Progress - latest.Progress
+	return jump >= PositionSampleJump || jump <= -PositionSampleJump
+}
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Updates carrying a precondition only apply when the stored row still has the
+// updated_at the client last read; otherwise repositories return ErrStaleUpdate.
+
+type preconditionContextKey struct{}
+
+// ContextWithUnmodifiedSince returns a copy of ctx requiring the row being updated to
+// still have updatedAt as its last modification time.
+func ContextWithUnmodifiedSince(ctx context.Context, updatedAt time.Time) context.Context {
+	return context.WithValue(ctx, preconditionContextKey{}, PreconditionTime(updatedAt))
+}
+
+// UnmodifiedSinceFromContext returns the precondition stored on ctx, if any.
+func UnmodifiedSinceFromContext(ctx context.Context) (time.Time, bool) {
+	t, ok := ctx.Value(preconditionContextKey{}).(time.Time)
+	return t, ok
+}
+
+// PreconditionTime normalizes a timestamp to the microsecond precision Postgres
+// stores, so values read back from the database compare equal.
+func PreconditionTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
@@ -0,0 +1,207 @@
+package domain
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Built-in reader themes. Users may also save custom themes, referenced by name.
+const (
+	ThemeLight        = "light"
+	ThemeDark         = "dark"
+	ThemeSepia        = "sepia"
+	ThemeHighContrast = "high-contrast"
+	ThemeOLEDBlack    = "oled-black"
+)
+
+// Font size bounds in points.
+const (
+	MinFontSize = 8
+	MaxFontSize = 72
+)
+
+// maxFontFamilyLength bounds free-form font family names.
+const maxFontFamilyLength = 100
+
+// DefaultHighlightColor is the color new highlights are drawn with.
+const DefaultHighlightColor = "#FFEB3B"
+
+// hexColorPattern matches #RGB and #RRGGBB colors.
+var hexColorPattern = regexp.MustCompile(`^#(?:[0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Margin sizes around the reading area.
+const (
+	MarginNarrow = "narrow"
+	MarginNormal = "normal"
+	MarginWide   = "wide"
+)
+
+// Text alignments for reflowable content.
+const (
+	TextAlignLeft    = "left"
+	TextAlignJustify = "justify"
+)
+
+// Reading modes: paginated turns pages, scroll reads the document as one column.
+const (
+	ReadingModePaginated = "paginated"
+	ReadingModeScroll    = "scroll"
+)
+
+// Page-turn animations used in paginated mode.
+const (
+	PageTurnNone  = "none"
+	PageTurnSlide = "slide"
+	PageTurnCurl  = "curl"
+)
+
+// Brightness bounds; 0 would leave the page unreadable.
+const (
+	MinBrightness = 0.1
+	MaxBrightness = 1.0
+)
+
+// DefaultUserPreferences returns the preferences of a user who has not saved any.
+func DefaultUserPreferences(userID string) *UserPreferences {
+	return &UserPreferences{
+		UserID:            userID,
+		FontSize:          16,
+		FontFamily:        "system-ui",
+		Theme:             ThemeLight,
+		HighlightColor:    DefaultHighlightColor,
+		MarginSize:        MarginNormal,
+		TextAlign:         TextAlignLeft,
+		ReadingMode:       ReadingModePaginated,
+		PageTurnAnimation: PageTurnSlide,
+		Brightness:        MaxBrightness,
+		SubscriptionPlan:  "free",
+		StorageLimitBytes: StorageLimitBytesForPlan("free"),
+		Tags:              []string{},
+	}
+}
+
+// PreferencesUpdate is a partial update of UserPreferences. Nil fields are left as
+// they are.
+type PreferencesUpdate struct {
+	FontSize          *int      `json:"font_size"`
+	FontFamily        *string   `json:"font_family"`
+	Theme             *string   `json:"theme"`
+	HighlightColor    *string   `json:"highlight_color"`
+	MarginSize        *string   `json:"margin_size"`
+	TextAlign         *string   `json:"text_align"`
+	Hyphenation       *bool     `json:"hyphenation"`
+	ReadingMode       *string   `json:"reading_mode"`
+	PageTurnAnimation *string   `json:"page_turn_animation"`
+	Brightness        *float64  `json:"brightness"`
+	SubscriptionPlan  *string   `json:"subscription_plan"`
+	Tags              *[]string `json:"tags"`
+	WeeklyDigest      *bool     `json:"weekly_digest"`
+}
+
+// Validate checks every field being updated and returns ValidationErrors listing
+// all invalid fields, or nil.
+func (u *PreferencesUpdate) Validate() error {
+	var errs ValidationErrors
+	invalid := func(field, message string) {
+		errs = append(errs, &ValidationError{Field: field, Message: message})
+	}
+
+	if u.FontSize != nil && (*u.FontSize < MinFontSize || *u.FontSize > MaxFontSize) {
+		invalid("font_size", fmt.Sprintf("font size must be between %d and %d", MinFontSize, MaxFontSize))
+	}
+	if u.FontFamily != nil && (strings.TrimSpace(*u.FontFamily) == "" || len(*u.FontFamily) > maxFontFamilyLength) {
+		invalid("font_family", fmt.Sprintf("font family must be 1 to %d characters", maxFontFamilyLength))
+	}
+	if u.Theme != nil && !IsThemePreset(*u.Theme) && !validThemeName(*u.Theme) {
+		invalid("theme", fmt.Sprintf("theme must be a preset or a custom theme name of 1 to %d characters", maxThemeNameLength))
+	}
+	if u.HighlightColor != nil && !hexColorPattern.MatchString(*u.HighlightColor) {
+		invalid("highlight_color", "highlight color must be a hex color like #FFEB3B")
+	}
+	if u.MarginSize != nil && !oneOf(*u.MarginSize, MarginNarrow, MarginNormal, MarginWide) {
+		invalid("margin_size", "margin size must be narrow, normal or wide")
+	}
+	if u.TextAlign != nil && !oneOf(*u.TextAlign, TextAlignLeft, TextAlignJustify) {
+		invalid("text_align", "text alignment must be left or justify")
+	}
+	if u.ReadingMode != nil && !oneOf(*u.ReadingMode, ReadingModePaginated, ReadingModeScroll) {
+		invalid("reading_mode", "reading mode must be paginated or scroll")
+	}
+	if u.PageTurnAnimation != nil && !oneOf(*u.PageTurnAnimation, PageTurnNone, PageTurnSlide, PageTurnCurl) {
+		invalid("page_turn_animation", "page turn animation must be none, slide or curl")
+	}
+	if u.Brightness != nil && (*u.Brightness < MinBrightness || *u.Brightness > MaxBrightness) {
+		invalid("brightness", "brightness must be between 0.1 and 1")
+	}
+	if u.SubscriptionPlan != nil && *u.SubscriptionPlan == "" {
+		invalid("subscription_plan", "subscription plan cannot be empty")
+	}
+	if u.Tags != nil {
+		for _, tag := range *u.Tags {
+			if strings.TrimSpace(tag) == "" {
+				invalid("tags", "tags cannot be empty")
+				break
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ApplyTo copies the set fields onto prefs. Changing the plan also resets the
+// storage limit to the plan's quota.
+func (u *PreferencesUpdate) ApplyTo(prefs *UserPreferences) {
+	if u.FontSize != nil {
+		prefs.FontSize = *u.FontSize
+	}
+	if u.FontFamily != nil {
+		prefs.FontFamily = *u.FontFamily
+	}
+	if u.Theme != nil {
+		prefs.Theme = *u.Theme
+	}
+	if u.HighlightColor != nil {
+		prefs.HighlightColor = *u.HighlightColor
+	}
+	if u.MarginSize != nil {
+		prefs.MarginSize = *u.MarginSize
+	}
+	if u.TextAlign != nil {
+		prefs.TextAlign = *u.TextAlign
+	}
+	if u.Hyphenation != nil {
+		prefs.Hyphenation = *u.Hyphenation
+	}
+	if u.ReadingMode != nil {
+		prefs.ReadingMode = *u.ReadingMode
+	}
+	if u.PageTurnAnimation != nil {
+		prefs.PageTurnAnimation = *u.PageTurnAnimation
+	}
+	if u.Brightness != nil {
+		prefs.Brightness = *u.Brightness
+	}
+	if u.SubscriptionPlan != nil {
+		prefs.SubscriptionPlan = *u.SubscriptionPlan
+		prefs.StorageLimitBytes = StorageLimitBytesForPlan(*u.SubscriptionPlan)
+	}
+	if u.Tags != nil {
+		prefs.Tags = *u.Tags
+	}
+	if u.WeeklyDigest != nil {
+		prefs.WeeklyDigest = *u.WeeklyDigest
+	}
+}
+
+func oneOf(value string, allowed ...string) bool {
+	for _, a := range allowed {
+		if value == a {
+			return true
+		}
+	}
+	return false
+}
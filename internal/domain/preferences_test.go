@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPreferencesUpdate_Validate(t *testing.T) {
+	str := func(s string) *string { return &s }
+	num := func(f float64) *float64 { return &f }
+	size := func(n int) *int { return &n }
+
+	tests := []struct {
+		name       string
+		update     PreferencesUpdate
+		wantFields []string // Fields reported invalid, in order
+	}{
+		{"empty update", PreferencesUpdate{}, nil},
+		{"valid fields", PreferencesUpdate{FontSize: size(18), Theme: str(ThemeSepia), HighlightColor: str("#a0f"), MarginSize: str(MarginWide), TextAlign: str(TextAlignJustify), ReadingMode: str(ReadingModeScroll), PageTurnAnimation: str(PageTurnCurl), Brightness: num(0.5)}, nil},
+		{"font size too small", PreferencesUpdate{FontSize: size(7)}, []string{"font_size"}},
+		{"font size too large", PreferencesUpdate{FontSize: size(73)}, []string{"font_size"}},
+		{"blank font family", PreferencesUpdate{FontFamily: str("  ")}, []string{"font_family"}},
+		{"preset theme", PreferencesUpdate{Theme: str(ThemeOLEDBlack)}, nil},
+		{"custom theme name", PreferencesUpdate{Theme: str("Night Owl")}, nil},
+		{"empty theme", PreferencesUpdate{Theme: str("")}, []string{"theme"}},
+		{"theme name too long", PreferencesUpdate{Theme: str(strings.Repeat("x", 51))}, []string{"theme"}},
+		{"color without hash", PreferencesUpdate{HighlightColor: str("FFEB3B")}, []string{"highlight_color"}},
+		{"color with bad digits", PreferencesUpdate{HighlightColor: str("#GGGGGG")}, []string{"highlight_color"}},
+		{"unknown margin", PreferencesUpdate{MarginSize: str("huge")}, []string{"margin_size"}},
+		{"unknown alignment", PreferencesUpdate{TextAlign: str("center")}, []string{"text_align"}},
+		{"unknown reading mode", PreferencesUpdate{ReadingMode: str("vertical")}, []string{"reading_mode"}},
+		{"unknown animation", PreferencesUpdate{PageTurnAnimation: str("fade")}, []string{"page_turn_animation"}},
+		{"brightness too low", PreferencesUpdate{Brightness: num(0)}, []string{"brightness"}},
+		{"brightness too high", PreferencesUpdate{Brightness: num(1.5)}, []string{"brightness"}},
+		{"empty tag", PreferencesUpdate{Tags: &[]string{"ok", ""}}, []string{"tags"}},
+		{"several invalid fields", PreferencesUpdate{FontSize: size(100), Theme: str(" neon"), Brightness: num(3)}, []string{"font_size", "theme", "brightness"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.update.Validate()
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.wantFields) {
+				t.Fatalf("Validate() error = %v, want errors on %v", err, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Fatalf("error %d on %s, want %s", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestPreferencesUpdate_ApplyTo(t *testing.T) {
+	prefs := DefaultUserPreferences("user-1")
+	hyphenation, mode, plan := true, ReadingModeScroll, "pro_monthly"
+	tags := []string{"fiction"}
+
+	update := PreferencesUpdate{Hyphenation: &hyphenation, ReadingMode: &mode, SubscriptionPlan: &plan, Tags: &tags}
+	update.ApplyTo(prefs)
+
+	if !prefs.Hyphenation || prefs.ReadingMode != ReadingModeScroll {
+		t.Fatalf("expected set fields applied, got %+v", prefs)
+	}
+	if prefs.FontSize != 16 || prefs.MarginSize != MarginNormal || prefs.Brightness != MaxBrightness {
+		t.Fatalf("fields left out of the update should not change, got %+v", prefs)
+	}
+	if prefs.StorageLimitBytes != StorageLimitBytesForPlan(plan) {
+		t.Fatalf("expected storage limit for %s, got %d", plan, prefs.StorageLimitBytes)
+	}
+	if len(prefs.Tags) != 1 || prefs.Tags[0] != "fiction" {
+		t.Fatalf("expected tags replaced, got %v", prefs.Tags)
+	}
+}
@@ -0,0 +1,25 @@
+package domain
+
+// Principal is the authenticated caller of a request. It keeps the user's identity and
+// the access token issued to that user together, so repositories always act with the
+// token of the user they are acting for.
+type Principal struct {
+	UserID string
+	Email  string
+	Token  string
+
+	// Admin is set for users whose app_metadata.role is "admin". app_metadata can only
+	// be changed with the service role key, so users cannot grant it to themselves.
+	Admin bool
+}
+
+// NewPrincipal builds the principal for a validated Supabase user and its access token.
+func NewPrincipal(user *SupabaseUser, token string) Principal {
+	role, _ := user.AppMetadata["role"].(string)
+	return Principal{
+		UserID: user.ID,
+		Email:  user.Email,
+		Token:  token,
+		Admin:  role == string(RoleAdmin),
+	}
+}
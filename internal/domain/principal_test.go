@@ -0,0 +1,15 @@
+package domain
+
+import "testing"
+
+func TestNewPrincipal_Admin(t *testing.T) {
+	admin := NewPrincipal(&SupabaseUser{ID: "user-1", AppMetadata: map[string]interface{}{"role": "admin"}}, "token")
+	if !admin.Admin {
+		t.Fatalf("expected admin principal")
+	}
+
+	regular := NewPrincipal(&SupabaseUser{ID: "user-2", AppMetadata: map[string]interface{}{"role": "member"}}, "token")
+	if regular.Admin {
+		t.Fatalf("expected non-admin principal")
+	}
+}
@@ -0,0 +1,47 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// MaxProcessingAttempts is how many times recovery re-extracts a document before
+// leaving it for the user to reprocess by hand.
+const MaxProcessingAttempts = 3
+
+// ProcessingRecoveryConfig configures the job that finishes extractions lost to a
+// restart.
+type ProcessingRecoveryConfig struct {
+	Enabled bool
+	// CheckInterval is how often the job runs after the run at startup.
+	CheckInterval time.Duration
+	// GracePeriod is how old an unprocessed document must be before it counts as
+	// lost rather than still being extracted.
+	GracePeriod time.Duration
+}
+
+// UnprocessedDocument is a document whose extraction started but never stored any
+// content.
+type UnprocessedDocument struct {
+	DocumentID string
+	UserID     string
+}
+
+// ProcessingRecoveryRepository finds unprocessed documents across all users.
+type ProcessingRecoveryRepository interface {
+	// ListUnprocessed returns PDFs created before createdBefore that have no content
+	// and no page count and were tried fewer than MaxProcessingAttempts times,
+	// oldest first.
+	ListUnprocessed(ctx context.Context, createdBefore time.Time, limit int) ([]UnprocessedDocument, error)
+}
+
+// ProcessingRecoveryService re-extracts documents whose background processing was
+// lost, e.g. to a deploy.
+type ProcessingRecoveryService interface {
+	// RecoverDocuments reprocesses the lost documents found and returns how many
+	// now have content.
+	RecoverDocuments(ctx context.Context) (int, error)
+	// Run calls RecoverDocuments at once and then on every check interval until ctx
+	// is cancelled.
+	Run(ctx context.Context)
+}
@@ -0,0 +1,50 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Read-later services whose exports can be imported.
+const (
+	ReadLaterPocket     = "pocket"
+	ReadLaterInstapaper = "instapaper"
+)
+
+const (
+	// MaxReadLaterExportSize bounds the export file accepted by a read-later import.
+	MaxReadLaterExportSize int64 = 50 << 20 // 50MB
+	// MaxReadLaterArticles bounds how many articles one import fetches.
+	MaxReadLaterArticles = 5000
+)
+
+// SavedArticle is one article listed in a read-later export.
+type SavedArticle struct {
+	URL      string
+	Title    string
+	Tags     []string
+	AddedAt  time.Time
+	Archived bool // read, or moved to the archive, in the source service
+}
+
+// WebPage is a fetched HTML page.
+type WebPage struct {
+	URL  string // after redirects
+	HTML []byte
+}
+
+// WebPageFetcher downloads public web pages for import.
+type WebPageFetcher interface {
+	// Fetch returns the page's HTML, refusing non-HTML responses and addresses
+	// outside the public internet.
+	Fetch(ctx context.Context, url string) (*WebPage, error)
+}
+
+type ReadLaterImportService interface {
+	// Import reads the export of a read-later service (ReadLaterPocket or
+	// ReadLaterInstapaper) and fetches its articles into the library in the
+	// background, returning the job right away. Articles already in the library are
+	// skipped.
+	Import(ctx context.Context, principal Principal, service string, export io.Reader) (*ImportJob, error)
+}
@@ -0,0 +1,17 @@
+package domain
+
+import "context"
+
+// Recommendation suggests a document from the user's library to read next.
+type Recommendation struct {
+	Document *DocumentData `json:"document"`
+	Score    float64       `json:"score"`
+	Reasons  []string      `json:"reasons"`
+}
+
+// RecommendationService ranks the user's unfinished documents by what they have
+// been reading.
+type RecommendationService interface {
+	// Recommend returns up to limit unfinished documents, best first.
+	Recommend(ctx context.Context, principal Principal, limit int) ([]*Recommendation, error)
+}
@@ -0,0 +1,135 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxSavedSearchNameLength bounds saved search names.
+const maxSavedSearchNameLength = 100
+
+// MaxSavedSearchesPerUser caps how many searches a user can save.
+const MaxSavedSearchesPerUser = 100
+
+// SmartCollectionPrefix introduces a saved search in a listing's collection
+// parameter: ?collection=smart:{id}.
+const SmartCollectionPrefix = "smart:"
+
+// SavedSearch is a search query and listing filters saved under a name. Listed as a
+// smart collection, it is evaluated again on every request, so documents join and
+// leave it as they change.
+type SavedSearch struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id,omitempty"`
+	Name   string `json:"name"`
+	// Query is in the syntax of ParseDocumentQuery. Empty matches every document,
+	// leaving the selection to the filters.
+	Query     string             `json:"query"`
+	Filters   SavedSearchFilters `json:"filters"`
+	CreatedAt time.Time          `json:"created_at"`
+	UpdatedAt time.Time          `json:"updated_at"`
+}
+
+// SavedSearchFilters are the listing parameters of ParseLibraryQuery saved with a
+// search. Empty fields keep the listing's defaults.
+type SavedSearchFilters struct {
+	Archived    string     `json:"archived,omitempty"`
+	Sort        string     `json:"sort,omitempty"`
+	OpenedSince *time.Time `json:"opened_since,omitempty"`
+}
+
+// Values returns the filters as listing query parameters.
+func (f SavedSearchFilters) Values() url.Values {
+	values := url.Values{}
+	if f.Archived != "" {
+		values.Set("archived", f.Archived)
+	}
+	if f.Sort != "" {
+		values.Set("sort", f.Sort)
+	}
+	if f.OpenedSince != nil {
+		values.Set("opened_since", f.OpenedSince.UTC().Format(time.RFC3339))
+	}
+	return values
+}
+
+// LibraryQuery returns the search's filters with the parameters of a listing request
+// laid over them, so a request can still sort or filter the collection its own way.
+func (s *SavedSearch) LibraryQuery(params url.Values) (LibraryQuery, error) {
+	values := s.Filters.Values()
+	for _, key := range []string{"archived", "sort", "opened_since"} {
+		if value := params.Get(key); value != "" {
+			values.Set(key, value)
+		}
+	}
+	return ParseLibraryQuery(values)
+}
+
+// ParseSmartCollection returns the saved search ID of a collection parameter, or
+// "" when the parameter is empty.
+func ParseSmartCollection(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	id, ok := strings.CutPrefix(value, SmartCollectionPrefix)
+	if !ok || id == "" {
+		return "", &ValidationError{Field: "collection", Message: "must be smart:{id}"}
+	}
+	return id, nil
+}
+
+// Validate checks a saved search and returns ValidationErrors listing all invalid
+// fields, or nil.
+func (s *SavedSearch) Validate() error {
+	var errs ValidationErrors
+	invalid := func(field, message string) {
+		errs = append(errs, &ValidationError{Field: field, Message: message})
+	}
+
+	if strings.TrimSpace(s.Name) != s.Name || s.Name == "" || len(s.Name) > maxSavedSearchNameLength {
+		invalid("name", fmt.Sprintf("name must be 1 to %d characters without surrounding spaces", maxSavedSearchNameLength))
+	}
+	if strings.TrimSpace(s.Query) != "" {
+		var queryErrs ValidationErrors
+		if _, err := ParseDocumentQuery(s.Query); errors.As(err, &queryErrs) {
+			for _, queryErr := range queryErrs {
+				invalid("query", queryErr.Message)
+			}
+		}
+	}
+	var filterErr *ValidationError
+	if _, err := ParseLibraryQuery(s.Filters.Values()); errors.As(err, &filterErr) {
+		invalid("filters."+filterErr.Field, filterErr.Message)
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// SavedSearchRepository defines persistence operations for saved searches.
+type SavedSearchRepository interface {
+	Create(ctx context.Context, principal Principal, search *SavedSearch) (*SavedSearch, error)
+	Update(ctx context.Context, principal Principal, search *SavedSearch) (*SavedSearch, error)
+	ListByUser(ctx context.Context, principal Principal) ([]*SavedSearch, error)
+	Get(ctx context.Context, principal Principal, searchID string) (*SavedSearch, error)
+	Delete(ctx context.Context, principal Principal, searchID string) error
+}
+
+// SavedSearchService defines the use-case operations for saved searches and the
+// smart collections they define.
+type SavedSearchService interface {
+	ListSavedSearches(ctx context.Context, principal Principal) ([]*SavedSearch, error)
+	GetSavedSearch(ctx context.Context, principal Principal, searchID string) (*SavedSearch, error)
+	CreateSavedSearch(ctx context.Context, principal Principal, search *SavedSearch) (*SavedSearch, error)
+	UpdateSavedSearch(ctx context.Context, principal Principal, searchID string, search *SavedSearch) (*SavedSearch, error)
+	DeleteSavedSearch(ctx context.Context, principal Principal, searchID string) error
+	// CollectionDocuments runs a saved search as a library listing: the matching
+	// documents, filtered and ordered by its filters overlaid with params.
+	CollectionDocuments(ctx context.Context, principal Principal, searchID string, params url.Values) ([]*Document, error)
+}
@@ -0,0 +1,70 @@
+package domain
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestSavedSearch_Validate(t *testing.T) {
+	tests := []struct {
+		name       string
+		search     SavedSearch
+		wantFields []string
+	}{
+		{"valid", SavedSearch{Name: "Unread Tolkien", Query: `author:tolkien -"the hobbit"`}, nil},
+		{"filters only", SavedSearch{Name: "Archive", Filters: SavedSearchFilters{Archived: "true", Sort: "title"}}, nil},
+		{"empty name", SavedSearch{Query: "go"}, []string{"name"}},
+		{"long name", SavedSearch{Name: strings.Repeat("x", 101)}, []string{"name"}},
+		{"bad query", SavedSearch{Name: "Broken", Query: "(go"}, []string{"query"}},
+		{"bad filter", SavedSearch{Name: "Sorted", Filters: SavedSearchFilters{Sort: "size"}}, []string{"filters.sort"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.search.Validate()
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.wantFields) {
+				t.Fatalf("Validate() error = %v, want errors on %v", err, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Fatalf("Validate() error %d on %s, want %s", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestSavedSearch_LibraryQuery(t *testing.T) {
+	search := &SavedSearch{Filters: SavedSearchFilters{Archived: "all", Sort: "title"}}
+
+	query, err := search.LibraryQuery(url.Values{})
+	if err != nil || query.Archived != ArchiveFilterAll || query.Sort != LibrarySortTitle {
+		t.Fatalf("expected the saved filters, got %+v, %v", query, err)
+	}
+
+	// Parameters on the request win over the saved ones.
+	query, err = search.LibraryQuery(url.Values{"sort": {"created"}})
+	if err != nil || query.Archived != ArchiveFilterAll || query.Sort != LibrarySortCreated {
+		t.Fatalf("expected the request's sort over the saved one, got %+v, %v", query, err)
+	}
+}
+
+func TestParseLibraryQuery_Collection(t *testing.T) {
+	query, err := ParseLibraryQuery(url.Values{"collection": {"smart:search-1"}})
+	if err != nil || query.Collection != "search-1" {
+		t.Fatalf("expected collection search-1, got %+v, %v", query, err)
+	}
+	for _, value := range []string{"smart:", "shelf:1", "search-1"} {
+		if _, err := ParseLibraryQuery(url.Values{"collection": {value}}); err == nil {
+			t.Fatalf("expected collection %q to be rejected", value)
+		}
+	}
+}
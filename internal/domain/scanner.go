@@ -0,0 +1,35 @@
+package domain
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// UploadScanner inspects uploaded content for malware before it is persisted. The
+// content is streamed so large uploads need not be held in memory.
+type UploadScanner interface {
+	Scan(ctx context.Context, filename string, content io.Reader) (*ScanResult, error)
+}
+
+// ScanResult is the verdict of an UploadScanner.
+type ScanResult struct {
+	Clean     bool
+	Signature string // matched signature when not clean
+	Engine    string
+}
+
+// Quarantine reasons.
+const (
+	QuarantineMalwareDetected = "malware_detected"
+	QuarantineScanFailed      = "scan_failed"
+)
+
+// Quarantine marks an upload held for admin review. The file is stored but not
+// processed or served until an admin releases it.
+type Quarantine struct {
+	Reason        string    `json:"reason"`
+	Signature     string    `json:"signature,omitempty"`
+	Engine        string    `json:"engine,omitempty"`
+	QuarantinedAt time.Time `json:"quarantined_at"`
+}
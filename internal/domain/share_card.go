@@ -0,0 +1,23 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// ShareCard is a highlight rendered as an image for sharing. The URL needs no
+// authentication and is valid until ExpiresAt; creating the card again returns a
+// fresh URL for the same image.
+type ShareCard struct {
+	HighlightID string    `json:"highlight_id"`
+	URL         string    `json:"url"`
+	ContentType string    `json:"content_type"`
+	Width       int       `json:"width"`
+	Height      int       `json:"height"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// ShareCardService renders highlights into shareable images.
+type ShareCardService interface {
+	CreateShareCard(ctx context.Context, principal Principal, highlightID string) (*ShareCard, error)
+}
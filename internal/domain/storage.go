@@ -3,8 +3,39 @@ package domain
 import (
 	"context"
 	"io"
+	"time"
 )
 
-type StorageService interface {
-	Upload(ctx context.Context, path string, file io.Reader, token string) error
+// BlobStore stores uploaded files and their derived assets. Paths are relative to
+// the store root, e.g. "<user id>/<document id>.pdf". token is the caller's access
+// token; backends that enforce per-user policies (Supabase) use it, others ignore it.
+type BlobStore interface {
+	Upload(ctx context.Context, path string, file io.Reader, contentType string, token string) error
+	// SignedURL returns a URL granting read access to path until it expires.
+	SignedURL(ctx context.Context, path string, expiresIn time.Duration, token string) (string, error)
+	Delete(ctx context.Context, path string, token string) error
+	Exists(ctx context.Context, path string, token string) (bool, error)
+	// Stat returns the size of the file at path without reading it; a missing file
+	// returns ErrBlobNotFound.
+	Stat(ctx context.Context, path string, token string) (int64, error)
+	// Download opens the file at path; a missing file returns ErrBlobNotFound.
+	Download(ctx context.Context, path string, token string) (io.ReadCloser, error)
+}
+
+// RangeDownloader is implemented by blob stores that can read part of a file, so
+// seeking in a large original does not download the bytes before the offset.
+type RangeDownloader interface {
+	// DownloadRange opens length bytes of the file at path, starting at offset.
+	DownloadRange(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error)
+}
+
+// S3Config configures an S3-compatible blob store (AWS S3, MinIO, R2, B2, ...).
+type S3Config struct {
+	Endpoint        string // e.g. "https://s3.eu-west-1.amazonaws.com" or "http://minio:9000"
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	// PathStyle addresses objects as endpoint/bucket/key instead of bucket.endpoint/key.
+	PathStyle bool
 }
@@ -13,3 +13,23 @@ func StorageLimitBytesForPlan(plan string) int64 {
 	}
 }
 
+
+// MaxUploadBytesForPlan returns the largest single file a plan may upload.
+func MaxUploadBytesForPlan(plan string) int64 {
+	switch plan {
+	case "pro_monthly", "pro_yearly", "founder_lifetime":
+		return 200 * 1024 * 1024
+	default:
+		return 15 * 1024 * 1024
+	}
+}
+
+// IsProPlan reports whether plan is one of the paid plans.
+func IsProPlan(plan string) bool {
+	switch plan {
+	case "pro_monthly", "pro_yearly", "founder_lifetime":
+		return true
+	default:
+		return false
+	}
+}
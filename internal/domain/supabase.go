@@ -1,11 +1,42 @@
 package domain
 
-import "github.com/supabase-community/supabase-go"
+import (
+	"context"
+	"errors"
+	"time"
+
+	"pdf-text-reader/pkg/resilience"
+
+	"github.com/supabase-community/supabase-go"
+)
 
 type SupabaseClient interface {
 	Initialize() error
-	ValidateToken(token string) (*SupabaseUser, error)
+	ValidateToken(ctx context.Context, token string) (*SupabaseUser, error)
 
 	DB() *supabase.Client
 	GetClientWithToken(token string) (*supabase.Client, error)
+
+	// Guard wraps calls to Supabase with retry/backoff and a circuit breaker.
+	Guard() *resilience.Executor
+}
+
+// UpstreamRetryAfter is the wait suggested to clients when Supabase throttles or fails
+// a call and no better estimate is known.
+const UpstreamRetryAfter = 5 * time.Second
+
+// UpstreamFailure marks failures of a guarded Supabase call that mean Supabase is
+// throttling us or cannot serve the call right now, so handlers can answer 503 with a
+// Retry-After instead of a generic 500, or a 401 for a token that could not be
+// checked. While the breaker is open the hint is the time until its trial call.
+func UpstreamFailure(guard *resilience.Executor, err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, resilience.ErrCircuitOpen):
+		return &UpstreamError{RetryAfter: max(guard.RetryAfter(), time.Second), Err: err}
+	case guard.Transient(err):
+		return &UpstreamError{RetryAfter: UpstreamRetryAfter, Err: err}
+	}
+	return err
 }
@@ -0,0 +1,133 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// maxThemeNameLength bounds custom theme names.
+const maxThemeNameLength = 50
+
+// MaxThemesPerUser caps how many custom themes a user can save.
+const MaxThemesPerUser = 50
+
+// Line height bounds, as a multiple of the font size.
+const (
+	MinLineHeight = 1.0
+	MaxLineHeight = 3.0
+)
+
+// Theme is a set of reader colors and typography. Presets are built in; custom
+// themes are saved per user. UserPreferences.Theme refers to either by name.
+type Theme struct {
+	ID              string    `json:"id,omitempty"`
+	UserID          string    `json:"user_id,omitempty"`
+	Name            string    `json:"name"`
+	Preset          bool      `json:"preset"`
+	BackgroundColor string    `json:"background_color"`
+	TextColor       string    `json:"text_color"`
+	AccentColor     string    `json:"accent_color"`
+	LineHeight      float64   `json:"line_height"`
+	FontFamily      string    `json:"font_family,omitempty"` // Empty keeps the preference font
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+var themePresets = []Theme{
+	{Name: ThemeLight, BackgroundColor: "#FFFFFF", TextColor: "#1A1A1A", AccentColor: "#1E88E5", LineHeight: 1.5},
+	{Name: ThemeDark, BackgroundColor: "#121212", TextColor: "#E0E0E0", AccentColor: "#90CAF9", LineHeight: 1.5},
+	{Name: ThemeSepia, BackgroundColor: "#F4ECD8", TextColor: "#5B4636", AccentColor: "#A0522D", LineHeight: 1.6},
+	{Name: ThemeHighContrast, BackgroundColor: "#FFFFFF", TextColor: "#000000", AccentColor: "#0000EE", LineHeight: 1.6},
+	{Name: ThemeOLEDBlack, BackgroundColor: "#000000", TextColor: "#B0B0B0", AccentColor: "#BB86FC", LineHeight: 1.5},
+}
+
+// ThemePresets returns the built-in themes.
+func ThemePresets() []*Theme {
+	out := make([]*Theme, len(themePresets))
+	for i := range themePresets {
+		preset := themePresets[i]
+		preset.Preset = true
+		out[i] = &preset
+	}
+	return out
+}
+
+// ThemePreset returns the built-in theme with the given name.
+func ThemePreset(name string) (*Theme, bool) {
+	for _, preset := range ThemePresets() {
+		if preset.Name == name {
+			return preset, true
+		}
+	}
+	return nil, false
+}
+
+// IsThemePreset reports whether name is a built-in theme.
+func IsThemePreset(name string) bool {
+	_, ok := ThemePreset(name)
+	return ok
+}
+
+func validThemeName(name string) bool {
+	return strings.TrimSpace(name) == name && name != "" && len(name) <= maxThemeNameLength
+}
+
+// Validate checks a custom theme and returns ValidationErrors listing all invalid
+// fields, or nil.
+func (t *Theme) Validate() error {
+	var errs ValidationErrors
+	invalid := func(field, message string) {
+		errs = append(errs, &ValidationError{Field: field, Message: message})
+	}
+
+	switch {
+	case !validThemeName(t.Name):
+		invalid("name", fmt.Sprintf("name must be 1 to %d characters without surrounding spaces", maxThemeNameLength))
+	case IsThemePreset(t.Name):
+		invalid("name", "name is reserved for a built-in theme")
+	}
+	for _, color := range []struct{ field, value string }{
+		{"background_color", t.BackgroundColor},
+		{"text_color", t.TextColor},
+		{"accent_color", t.AccentColor},
+	} {
+		if !hexColorPattern.MatchString(color.value) {
+			invalid(color.field, "must be a hex color like #FFFFFF")
+		}
+	}
+	if t.LineHeight < MinLineHeight || t.LineHeight > MaxLineHeight {
+		invalid("line_height", fmt.Sprintf("line height must be between %g and %g", MinLineHeight, MaxLineHeight))
+	}
+	if len(t.FontFamily) > maxFontFamilyLength {
+		invalid("font_family", fmt.Sprintf("font family must be at most %d characters", maxFontFamilyLength))
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ThemeRepository defines persistence operations for custom themes.
+type ThemeRepository interface {
+	Create(ctx context.Context, principal Principal, theme *Theme) (*Theme, error)
+	Update(ctx context.Context, principal Principal, theme *Theme) (*Theme, error)
+	ListByUser(ctx context.Context, principal Principal) ([]*Theme, error)
+	Get(ctx context.Context, principal Principal, themeID string) (*Theme, error)
+	Delete(ctx context.Context, principal Principal, themeID string) error
+}
+
+// ThemeService defines the use-case operations for reader themes.
+type ThemeService interface {
+	// ListThemes returns the presets followed by the user's custom themes.
+	ListThemes(ctx context.Context, principal Principal) ([]*Theme, error)
+	// GetTheme resolves a theme name as stored in UserPreferences.Theme.
+	GetTheme(ctx context.Context, principal Principal, name string) (*Theme, error)
+	CreateTheme(ctx context.Context, principal Principal, theme *Theme) (*Theme, error)
+	// UpdateTheme replaces a custom theme; preferences follow a rename.
+	UpdateTheme(ctx context.Context, principal Principal, themeID string, theme *Theme) (*Theme, error)
+	// DeleteTheme removes a custom theme; preferences using it fall back to light.
+	DeleteTheme(ctx context.Context, principal Principal, themeID string) error
+}
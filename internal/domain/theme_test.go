@@ -0,0 +1,68 @@
+package domain
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestThemePresets(t *testing.T) {
+	for _, name := range []string{ThemeLight, ThemeDark, ThemeSepia, ThemeHighContrast, ThemeOLEDBlack} {
+		preset, ok := ThemePreset(name)
+		if !ok {
+			t.Fatalf("expected preset %s", name)
+		}
+		if !preset.Preset {
+			t.Fatalf("expected %s to be marked as a preset", name)
+		}
+	}
+
+	// Callers get copies, so editing one cannot change the built-ins.
+	ThemePresets()[0].BackgroundColor = "#123456"
+	if preset, _ := ThemePreset(ThemeLight); preset.BackgroundColor == "#123456" {
+		t.Fatalf("expected presets to be immutable")
+	}
+}
+
+func TestTheme_Validate(t *testing.T) {
+	valid := func() Theme {
+		return Theme{Name: "Night Owl", BackgroundColor: "#011627", TextColor: "#d6deeb", AccentColor: "#82AAFF", LineHeight: 1.6}
+	}
+
+	tests := []struct {
+		name       string
+		modify     func(*Theme)
+		wantFields []string
+	}{
+		{"valid", func(*Theme) {}, nil},
+		{"custom font", func(th *Theme) { th.FontFamily = "custom:font-1" }, nil},
+		{"empty name", func(th *Theme) { th.Name = "" }, []string{"name"}},
+		{"padded name", func(th *Theme) { th.Name = " Night" }, []string{"name"}},
+		{"preset name", func(th *Theme) { th.Name = ThemeSepia }, []string{"name"}},
+		{"bad colors", func(th *Theme) { th.BackgroundColor = "navy"; th.AccentColor = "" }, []string{"background_color", "accent_color"}},
+		{"line height too small", func(th *Theme) { th.LineHeight = 0.5 }, []string{"line_height"}},
+		{"font family too long", func(th *Theme) { th.FontFamily = strings.Repeat("x", 101) }, []string{"font_family"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			theme := valid()
+			tt.modify(&theme)
+			err := theme.Validate()
+			if tt.wantFields == nil {
+				if err != nil {
+					t.Fatalf("Validate() unexpected error: %v", err)
+				}
+				return
+			}
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != len(tt.wantFields) {
+				t.Fatalf("Validate() error = %v, want errors on %v", err, tt.wantFields)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Fatalf("error %d on %q, want %q", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// UploadLimits holds the server-side upload caps from configuration. Plan entitlements
+// are applied on top, so the effective limit is the smallest of the three.
+type UploadLimits struct {
+	MaxFileSize int64            // server-wide ceiling; 0 means no ceiling
+	PerFormat   map[string]int64 // optional caps keyed by lower-case extension, e.g. "pdf"
+}
+
+// PDFLimits bounds the work a single PDF extraction job may do, so one huge or
+// malicious file cannot exhaust the server. Zero fields are unlimited.
+type PDFLimits struct {
+	MaxPages      int           // pages a PDF may have
+	MaxChars      int           // characters of text extracted from one PDF
+	Timeout       time.Duration // wall-clock time of one extraction
+	MaxConcurrent int           // extraction jobs running at once; others wait
+	PageWorkers   int           // pages of one job extracted in parallel; 0 or 1 is sequential
+}
+
+// PDFProcessingStats is a point-in-time snapshot of PDF extraction metrics.
+type PDFProcessingStats struct {
+	Processed     int64 `json:"processed"`
+	Failed        int64 `json:"failed"`
+	RejectedLarge int64 `json:"rejected_too_large"`
+	TimedOut      int64 `json:"timed_out"`
+	InFlight      int64 `json:"in_flight"`
+	Waiting       int64 `json:"waiting"`
+	MaxConcurrent int   `json:"max_concurrent"`
+}
+
+// PDFProcessingMetrics exposes extraction metrics to the admin API.
+type PDFProcessingMetrics interface {
+	PDFProcessingStats() PDFProcessingStats
+}
+
+// UploadLimit is the effective single-file cap for a user's plan and a file format.
+type UploadLimit struct {
+	MaxBytes int64  `json:"limit_bytes"`
+	Plan     string `json:"plan"`
+	Format   string `json:"format,omitempty"`
+}
+
+// Resolve returns the effective single-file cap. An empty format skips the per-format cap.
+func (l UploadLimits) Resolve(plan, format string) UploadLimit {
+	limit := MaxUploadBytesForPlan(plan)
+	if l.MaxFileSize > 0 && l.MaxFileSize < limit {
+		limit = l.MaxFileSize
+	}
+	if formatLimit, ok := l.PerFormat[format]; ok && formatLimit > 0 && formatLimit < limit {
+		limit = formatLimit
+	}
+	return UploadLimit{MaxBytes: limit, Plan: plan, Format: format}
+}
+
+// FileType is a supported upload format detected from the file's content.
+type FileType struct {
+	Format      string // metadata.Format value, e.g. "pdf"
+	Extension   string // storage object extension, e.g. ".pdf"
+	ContentType string
+}
+
+// UploadFormat returns the lower-case extension of filename without the dot, e.g. "pdf".
+func UploadFormat(filename string) string {
+	return strings.TrimPrefix(strings.ToLower(filepath.Ext(filename)), ".")
+}
@@ -0,0 +1,67 @@
+package domain
+
+import (
+	"context"
+	"time"
+)
+
+// Upload session statuses. A direct upload is receiving while its body arrives,
+// processing while the file is validated, extracted and stored, and then done or
+// failed.
+const (
+	UploadStatusReceiving  = "receiving"
+	UploadStatusProcessing = "processing"
+	UploadStatusDone       = "done"
+	UploadStatusFailed     = "failed"
+)
+
+// MaxUploadIDLength bounds the client-chosen ID of an upload session.
+const MaxUploadIDLength = 64
+
+// UploadProgress is how far one direct upload has come. Byte counts are of the
+// request body, multipart framing included, so they match what the client sends.
+type UploadProgress struct {
+	ID            string `json:"id"`
+	UserID        string `json:"-"`
+	Status        string `json:"status"`
+	BytesReceived int64  `json:"bytes_received"`
+	// TotalBytes is the request's Content-Length; 0 when the client did not send one.
+	TotalBytes int64 `json:"total_bytes"`
+	// Percent of TotalBytes received, 0 while the total is unknown.
+	Percent    float64   `json:"percent"`
+	DocumentID string    `json:"document_id,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// ValidateUploadID checks an upload session ID chosen by the client: up to
+// MaxUploadIDLength letters, digits, '-' or '_', such as a UUID.
+func ValidateUploadID(id string) error {
+	if id == "" || len(id) > MaxUploadIDLength {
+		return ValidationErrors{{Field: "upload_id", Message: "upload_id must be 1 to 64 characters long"}}
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return ValidationErrors{{Field: "upload_id", Message: "upload_id may only contain letters, digits, '-' and '_'"}}
+		}
+	}
+	return nil
+}
+
+// UploadProgressTracker follows direct uploads that name a session, so a client can
+// poll the progress of a large file from another request.
+type UploadProgressTracker interface {
+	// StartUpload opens a session. An ID that is still receiving or processing
+	// returns ErrUploadInProgress; a finished one is replaced. A user with too many
+	// uploads running at once gets ErrTooManyUploads.
+	StartUpload(principal Principal, uploadID string, totalBytes int64) error
+	// UploadReceived adds n bytes to the count of the session.
+	UploadReceived(principal Principal, uploadID string, n int64)
+	// UploadProcessing marks the body as fully received.
+	UploadProcessing(principal Principal, uploadID string)
+	// FinishUpload records the uploaded document, or the error when err is set.
+	FinishUpload(principal Principal, uploadID, documentID string, err error)
+	// GetUploadProgress returns ErrUploadNotFound for sessions of other users.
+	GetUploadProgress(ctx context.Context, principal Principal, uploadID string) (*UploadProgress, error)
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "testing"
+
+func TestUploadLimits_Resolve(t *testing.T) {
+	limits := UploadLimits{
+		MaxFileSize: 100 * 1024 * 1024,
+		PerFormat:   map[string]int64{"epub": 5 * 1024 * 1024},
+	}
+
+	tests := []struct {
+		name   string
+		plan   string
+		format string
+		want   int64
+	}{
+		{"free plan", "free", "pdf", 15 * 1024 * 1024},
+		{"pro capped by server ceiling", "pro_monthly", "pdf", 100 * 1024 * 1024},
+		{"format cap", "pro_yearly", "epub", 5 * 1024 * 1024},
+		{"plan-wide", "pro_yearly", "", 100 * 1024 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := limits.Resolve(tt.plan, tt.format)
+			if got.MaxBytes != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, got.MaxBytes)
+			}
+			if got.Plan != tt.plan || got.Format != tt.format {
+				t.Fatalf("unexpected limit: %+v", got)
+			}
+		})
+	}
+}
+
+func TestUploadLimits_ResolveNoCeiling(t *testing.T) {
+	if got := (UploadLimits{}).Resolve("founder_lifetime", "pdf"); got.MaxBytes != 200*1024*1024 {
+		t.Fatalf("expected plan entitlement, got %d", got.MaxBytes)
+	}
+}
+
+func TestUploadFormat(t *testing.T) {
+	for name, want := range map[string]string{"Book.PDF": "pdf", "a.b.epub": "epub", "noext": ""} {
+		if got := UploadFormat(name); got != want {
+			t.Fatalf("UploadFormat(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
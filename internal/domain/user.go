@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
@@ -19,6 +20,7 @@ type SupabaseUser struct {
 	ID           string
 	Email        string
 	UserMetadata map[string]interface{}
+	AppMetadata  map[string]interface{}
 	CreatedAt    string
 	UpdatedAt    string
 }
@@ -29,25 +31,116 @@ type UserPreferences struct {
 	FontSize          int       `json:"font_size"`
 	FontFamily        string    `json:"font_family"`
 	Theme             string    `json:"theme"`
+	HighlightColor    string    `json:"highlight_color"`
+	MarginSize        string    `json:"margin_size"`
+	TextAlign         string    `json:"text_align"`
+	Hyphenation       bool      `json:"hyphenation"`
+	ReadingMode       string    `json:"reading_mode"`
+	PageTurnAnimation string    `json:"page_turn_animation"`
+	Brightness        float64   `json:"brightness"` // Screen brightness applied by the reader, 0.1-1
 	SubscriptionPlan  string    `json:"subscription_plan"`
 	StorageLimitBytes int64     `json:"storage_limit_bytes"`
 	AccountDisabled   bool      `json:"account_disabled"`
 	Tags              []string  `json:"tags"`
+	WeeklyDigest      bool      `json:"weekly_digest"` // Opted in to the weekly reading digest e-mail
 	UpdatedAt         time.Time `json:"updated_at"`
+
+	// DocumentOverride is set when the preferences were resolved for a document that
+	// has its own overrides; the fields above already include them.
+	DocumentOverride *DocumentPreferences `json:"document_override,omitempty"`
+}
+
+// DocumentPreferences overrides the user's reading preferences for one document.
+// Nil fields fall back to the global preference.
+type DocumentPreferences struct {
+	UserID     string    `json:"user_id"`
+	DocumentID string    `json:"document_id"`
+	FontSize   *int      `json:"font_size,omitempty"`
+	FontFamily *string   `json:"font_family,omitempty"`
+	Theme      *string   `json:"theme,omitempty"`
+	MarginSize *string   `json:"margin_size,omitempty"`
+	TextAlign  *string   `json:"text_align,omitempty"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// IsEmpty reports whether the override changes no preference.
+func (o *DocumentPreferences) IsEmpty() bool {
+	return o.FontSize == nil && o.FontFamily == nil && o.Theme == nil && o.MarginSize == nil && o.TextAlign == nil
+}
+
+// Validate checks the overridden values with the same rules as preference updates.
+func (o *DocumentPreferences) Validate() error {
+	update := PreferencesUpdate{
+		FontSize:   o.FontSize,
+		FontFamily: o.FontFamily,
+		Theme:      o.Theme,
+		MarginSize: o.MarginSize,
+		TextAlign:  o.TextAlign,
+	}
+	return update.Validate()
+}
+
+// WithOverride returns a copy of p with the override's fields applied. A nil override
+// returns an unchanged copy.
+func (p *UserPreferences) WithOverride(o *DocumentPreferences) *UserPreferences {
+	merged := *p
+	if o == nil {
+		return &merged
+	}
+	if o.FontSize != nil {
+		merged.FontSize = *o.FontSize
+	}
+	if o.FontFamily != nil {
+		merged.FontFamily = *o.FontFamily
+	}
+	if o.Theme != nil {
+		merged.Theme = *o.Theme
+	}
+	if o.MarginSize != nil {
+		merged.MarginSize = *o.MarginSize
+	}
+	if o.TextAlign != nil {
+		merged.TextAlign = *o.TextAlign
+	}
+	merged.DocumentOverride = o
+	return &merged
 }
 
 type UserPreferencesService interface {
-	GetPreferences(userID string, token string) (*UserPreferences, error)
-	UpdatePreferences(userID string, prefs *UserPreferences, token string) error
-	GetReadingPosition(userID, documentID string, token string) (*ReadingPosition, error)
-	GetAllReadingPositions(userID string, token string) (map[string]*ReadingPosition, error)
-	UpdateReadingPosition(userID, documentID string, position *ReadingPosition, token string) error
+	GetPreferences(ctx context.Context, principal Principal) (*UserPreferences, error)
+	UpdatePreferences(ctx context.Context, principal Principal, prefs *UserPreferences) error
+	GetReadingPosition(ctx context.Context, principal Principal, documentID string) (*ReadingPosition, error)
+	GetAllReadingPositions(ctx context.Context, principal Principal) (map[string]*ReadingPosition, error)
+	// GetReadingPositions returns the saved positions of the given documents in one
+	// round trip, keyed by document ID; documents without a position are absent.
+	GetReadingPositions(ctx context.Context, principal Principal, documentIDs []string) (map[string]*ReadingPosition, error)
+	UpdateReadingPosition(ctx context.Context, principal Principal, documentID string, position *ReadingPosition) error
+	// GetPositionHistory returns the document's sampled positions of the last days
+	// with a per-day summary.
+	GetPositionHistory(ctx context.Context, principal Principal, documentID string, days int) (*PositionHistory, error)
+	// GetDocumentPreferences returns the global preferences merged with the document's overrides.
+	GetDocumentPreferences(ctx context.Context, principal Principal, documentID string) (*UserPreferences, error)
+	// UpdateDocumentPreferences replaces the document's overrides; an empty override removes them.
+	UpdateDocumentPreferences(ctx context.Context, principal Principal, documentID string, override *DocumentPreferences) (*UserPreferences, error)
+	// DisableAccount persists account_disabled so every device of the user is blocked.
+	DisableAccount(ctx context.Context, principal Principal) error
 }
 
 type UserPreferencesRepository interface {
-	GetPreferences(userID string, token string) (*UserPreferences, error)
-	UpdatePreferences(prefs *UserPreferences, token string) error
-	GetReadingPosition(userID, documentID string, token string) (*ReadingPosition, error)
-	GetAllReadingPositions(userID string, token string) (map[string]*ReadingPosition, error)
-	UpdateReadingPosition(position *ReadingPosition, token string) error
+	GetPreferences(ctx context.Context, principal Principal) (*UserPreferences, error)
+	UpdatePreferences(ctx context.Context, principal Principal, prefs *UserPreferences) error
+	GetReadingPosition(ctx context.Context, principal Principal, documentID string) (*ReadingPosition, error)
+	GetAllReadingPositions(ctx context.Context, principal Principal) (map[string]*ReadingPosition, error)
+	GetReadingPositions(ctx context.Context, principal Principal, documentIDs []string) (map[string]*ReadingPosition, error)
+	UpdateReadingPosition(ctx context.Context, principal Principal, position *ReadingPosition) error
+	// LatestPositionSample returns nil when the document has no position history.
+	LatestPositionSample(ctx context.Context, principal Principal, documentID string) (*PositionSample, error)
+	AddPositionSample(ctx context.Context, principal Principal, sample *PositionSample) error
+	// GetPositionHistory returns the samples recorded since the given time, oldest first.
+	GetPositionHistory(ctx context.Context, principal Principal, documentID string, since time.Time) ([]*PositionSample, error)
+	// GetDocumentPreferences returns nil when the document has no overrides.
+	GetDocumentPreferences(ctx context.Context, principal Principal, documentID string) (*DocumentPreferences, error)
+	UpsertDocumentPreferences(ctx context.Context, principal Principal, override *DocumentPreferences) error
+	DeleteDocumentPreferences(ctx context.Context, principal Principal, documentID string) error
+	SetAccountDisabled(ctx context.Context, principal Principal, disabled bool) error
 }
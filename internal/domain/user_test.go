@@ -264,3 +264,46 @@ func TestUser_EmailValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestUserPreferences_WithOverride(t *testing.T) {
+	prefs := &UserPreferences{UserID: "user-1", FontSize: 16, FontFamily: "system-ui", Theme: "light"}
+	size, theme := 22, "sepia"
+
+	merged := prefs.WithOverride(&DocumentPreferences{FontSize: &size, Theme: &theme})
+	if merged.FontSize != 22 || merged.Theme != "sepia" || merged.FontFamily != "system-ui" {
+		t.Fatalf("unexpected merged preferences: %+v", merged)
+	}
+	if merged.DocumentOverride == nil {
+		t.Fatalf("expected merged preferences to carry the override")
+	}
+	if prefs.FontSize != 16 || prefs.Theme != "light" {
+		t.Fatalf("WithOverride must not modify the global preferences: %+v", prefs)
+	}
+
+	unchanged := prefs.WithOverride(nil)
+	if unchanged == prefs || unchanged.FontSize != 16 || unchanged.DocumentOverride != nil {
+		t.Fatalf("expected an unchanged copy, got %+v", unchanged)
+	}
+}
+
+func TestDocumentPreferences_Validate(t *testing.T) {
+	zero, empty := 0, ""
+	tests := []struct {
+		name     string
+		override DocumentPreferences
+		wantErr  bool
+	}{
+		{"empty override", DocumentPreferences{}, false},
+		{"zero font size", DocumentPreferences{FontSize: &zero}, true},
+		{"empty font family", DocumentPreferences{FontFamily: &empty}, true},
+		{"empty theme", DocumentPreferences{Theme: &empty}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.override.Validate(); (err != nil) != tt.wantErr {
+				t.Fatalf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
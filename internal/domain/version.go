@@ -0,0 +1,41 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// Reasons recorded on a document version.
+const (
+	VersionReasonUpdate    = "update"    // Snapshot taken before details were edited
+	VersionReasonRestore   = "restore"   // Snapshot taken before an older version was restored
+	VersionReasonEnrich    = "enrich"    // Snapshot taken before catalog details were filled in
+	VersionReasonReprocess = "reprocess" // Snapshot taken before the original file was re-extracted
+)
+
+// DocumentVersion is a snapshot of a document taken before it was overwritten.
+// Version numbers start at 1 and increase per document.
+type DocumentVersion struct {
+	ID         string           `json:"id"`
+	DocumentID string           `json:"document_id"`
+	UserID     string           `json:"user_id"`
+	Version    int              `json:"version"`
+	Title      string           `json:"title"`
+	Author     *string          `json:"author,omitempty"`
+	Content    json.RawMessage  `json:"content,omitempty"` // Omitted when listing versions
+	Metadata   DocumentMetadata `json:"metadata"`
+	Reason     string           `json:"reason"`
+	CreatedAt  time.Time        `json:"created_at"`
+}
+
+// DocumentVersionRepository defines persistence operations for document versions.
+type DocumentVersionRepository interface {
+	// Create stores a snapshot and assigns it the next version number.
+	Create(ctx context.Context, principal Principal, version *DocumentVersion) (*DocumentVersion, error)
+	// ListByDocument returns versions newest first, without their content.
+	ListByDocument(ctx context.Context, principal Principal, documentID string) ([]*DocumentVersion, error)
+	Get(ctx context.Context, principal Principal, documentID string, version int) (*DocumentVersion, error)
+	// DeleteBefore removes versions numbered below the given version.
+	DeleteBefore(ctx context.Context, principal Principal, documentID string, version int) error
+}
@@ -0,0 +1,267 @@
+package graphqlserver
+
+import (
+	"context"
+	"errors"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+type loaderContextKey struct{}
+
+func withLoader(ctx context.Context, l *loader) context.Context {
+	return context.WithValue(ctx, loaderContextKey{}, l)
+}
+
+func loaderFrom(ctx context.Context) (*loader, error) {
+	l, ok := ctx.Value(loaderContextKey{}).(*loader)
+	if !ok {
+		return nil, errors.New("unauthorized")
+	}
+	return l, nil
+}
+
+func (l *loader) readingPositions(ctx context.Context) (map[string]*domain.ReadingPosition, error) {
+	l.positionsOnce.Do(func() {
+		l.positions, l.positionsErr = l.root.preferenceService.GetAllReadingPositions(ctx, l.principal)
+		if l.positionsErr != nil {
+			l.root.logger.Error("Failed to get reading positions", l.positionsErr, "user_id", l.principal.UserID)
+			l.positionsErr = errors.New("failed to load reading positions")
+		}
+	})
+	return l.positions, l.positionsErr
+}
+
+func (l *loader) highlightsByDocument(ctx context.Context) (map[string][]*domain.Highlight, error) {
+	l.highlightsOnce.Do(func() {
+		highlights, err := l.root.highlightService.ListHighlights(ctx, l.principal, nil)
+		if err != nil {
+			l.root.logger.Error("Failed to list highlights", err, "user_id", l.principal.UserID)
+			l.highlightsErr = errors.New("failed to load highlights")
+			return
+		}
+		l.highlights = make(map[string][]*domain.Highlight)
+		for _, h := range highlights {
+			l.highlights[h.DocumentID] = append(l.highlights[h.DocumentID], h)
+		}
+	})
+	return l.highlights, l.highlightsErr
+}
+
+type rootResolver struct {
+	documentService   domain.DocumentService
+	preferenceService domain.UserPreferencesService
+	highlightService  domain.HighlightService
+	logger            domain.Logger
+}
+
+func (r *rootResolver) Library(ctx context.Context) (*libraryResolver, error) {
+	l, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return &libraryResolver{loader: l}, nil
+}
+
+// Document returns null for documents that do not exist or belong to someone else.
+func (r *rootResolver) Document(ctx context.Context, args struct{ ID graphql.ID }) (*documentResolver, error) {
+	l, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := r.documentService.GetDocument(ctx, l.principal, string(args.ID))
+	if errors.Is(err, domain.ErrDocumentNotFound) || errors.Is(err, domain.ErrAccessDenied) {
+		return nil, nil
+	}
+	if err != nil {
+		r.logger.Error("Failed to get document", err, "user_id", l.principal.UserID, "document_id", string(args.ID))
+		return nil, errors.New("failed to load document")
+	}
+	return &documentResolver{doc: doc, loader: l}, nil
+}
+
+func (r *rootResolver) Highlights(ctx context.Context, args struct{ DocumentID *graphql.ID }) ([]*highlightResolver, error) {
+	l, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	byDocument, err := l.highlightsByDocument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if args.DocumentID != nil {
+		return highlightResolvers(byDocument[string(*args.DocumentID)]), nil
+	}
+	var all []*domain.Highlight
+	for _, highlights := range byDocument {
+		all = append(all, highlights...)
+	}
+	return highlightResolvers(all), nil
+}
+
+func (r *rootResolver) ReadingPositions(ctx context.Context) ([]*readingPositionResolver, error) {
+	l, err := loaderFrom(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := l.readingPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]*readingPositionResolver, 0, len(positions))
+	for documentID, position := range positions {
+		out = append(out, &readingPositionResolver{documentID: documentID, position: position})
+	}
+	return out, nil
+}
+
+type libraryResolver struct {
+	loader *loader
+}
+
+func (r *libraryResolver) Documents(ctx context.Context, args struct {
+	Tag      *string
+	Favorite *bool
+}) ([]*documentResolver, error) {
+	root := r.loader.root
+	docs, err := root.documentService.GetDocumentsByUserID(ctx, r.loader.principal)
+	if err != nil {
+		root.logger.Error("Failed to list documents", err, "user_id", r.loader.principal.UserID)
+		return nil, errors.New("failed to load documents")
+	}
+
+	out := make([]*documentResolver, 0, len(docs))
+	for _, doc := range docs {
+		if args.Tag != nil && (doc.Tag == nil || *doc.Tag != *args.Tag) {
+			continue
+		}
+		if args.Favorite != nil && doc.IsFavorite != *args.Favorite {
+			continue
+		}
+		out = append(out, &documentResolver{doc: doc, loader: r.loader})
+	}
+	return out, nil
+}
+
+func (r *libraryResolver) Tags(ctx context.Context) ([]string, error) {
+	root := r.loader.root
+	tags, err := root.documentService.GetDocumentTags(ctx, r.loader.principal)
+	if err != nil {
+		root.logger.Error("Failed to get document tags", err, "user_id", r.loader.principal.UserID)
+		return nil, errors.New("failed to load tags")
+	}
+	if tags == nil {
+		tags = []string{}
+	}
+	return tags, nil
+}
+
+type documentResolver struct {
+	doc    *domain.Document
+	loader *loader
+}
+
+func (r *documentResolver) ID() graphql.ID          { return graphql.ID(r.doc.ID) }
+func (r *documentResolver) Title() string           { return r.doc.Title }
+func (r *documentResolver) Author() *string         { return r.doc.Author }
+func (r *documentResolver) Description() *string    { return r.doc.Description }
+func (r *documentResolver) Tag() *string            { return r.doc.Tag }
+func (r *documentResolver) IsFavorite() bool        { return r.doc.IsFavorite }
+func (r *documentResolver) CreatedAt() graphql.Time { return graphql.Time{Time: r.doc.CreatedAt} }
+func (r *documentResolver) UpdatedAt() graphql.Time { return graphql.Time{Time: r.doc.UpdatedAt} }
+
+func (r *documentResolver) Metadata() *metadataResolver {
+	return &metadataResolver{metadata: &r.doc.Metadata}
+}
+
+func (r *documentResolver) ReadingPosition(ctx context.Context) (*readingPositionResolver, error) {
+	positions, err := r.loader.readingPositions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	position, ok := positions[r.doc.ID]
+	if !ok {
+		return nil, nil
+	}
+	return &readingPositionResolver{documentID: r.doc.ID, position: position}, nil
+}
+
+func (r *documentResolver) Highlights(ctx context.Context) ([]*highlightResolver, error) {
+	byDocument, err := r.loader.highlightsByDocument(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return highlightResolvers(byDocument[r.doc.ID]), nil
+}
+
+type metadataResolver struct {
+	metadata *domain.DocumentMetadata
+}
+
+func (r *metadataResolver) OriginalTitle() *string  { return optional(r.metadata.OriginalTitle) }
+func (r *metadataResolver) OriginalAuthor() *string { return optional(r.metadata.OriginalAuthor) }
+func (r *metadataResolver) Language() *string       { return optional(r.metadata.Language) }
+func (r *metadataResolver) PageCount() int32        { return int32(r.metadata.PageCount) }
+func (r *metadataResolver) WordCount() int32        { return int32(r.metadata.WordCount) }
+func (r *metadataResolver) FileSize() float64       { return float64(r.metadata.FileSize) }
+func (r *metadataResolver) Format() *string         { return optional(r.metadata.Format) }
+func (r *metadataResolver) Source() *string         { return optional(r.metadata.Source) }
+
+type readingPositionResolver struct {
+	documentID string // Map key of GetAllReadingPositions; rows may omit document_id
+	position   *domain.ReadingPosition
+}
+
+func (r *readingPositionResolver) DocumentID() graphql.ID { return graphql.ID(r.documentID) }
+func (r *readingPositionResolver) Progress() float64      { return float64(r.position.Progress) }
+func (r *readingPositionResolver) PageNumber() int32      { return int32(r.position.PageNumber) }
+func (r *readingPositionResolver) UpdatedAt() graphql.Time {
+	return graphql.Time{Time: r.position.UpdatedAt}
+}
+
+type highlightResolver struct {
+	highlight *domain.Highlight
+}
+
+func highlightResolvers(highlights []*domain.Highlight) []*highlightResolver {
+	out := make([]*highlightResolver, 0, len(highlights))
+	for _, h := range highlights {
+		out = append(out, &highlightResolver{highlight: h})
+	}
+	return out
+}
+
+func (r *highlightResolver) ID() graphql.ID         { return graphql.ID(r.highlight.ID) }
+func (r *highlightResolver) DocumentID() graphql.ID { return graphql.ID(r.highlight.DocumentID) }
+func (r *highlightResolver) Quote() string          { return r.highlight.Quote }
+func (r *highlightResolver) CreatedAt() graphql.Time {
+	return graphql.Time{Time: r.highlight.CreatedAt}
+}
+
+func (r *highlightResolver) PageNumber() *int32 {
+	if r.highlight.PageNumber == nil {
+		return nil
+	}
+	page := int32(*r.highlight.PageNumber)
+	return &page
+}
+
+func (r *highlightResolver) Progress() *float64 {
+	if r.highlight.Progress == nil {
+		return nil
+	}
+	progress := float64(*r.highlight.Progress)
+	return &progress
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,94 @@
+// Package graphqlserver serves a read-only GraphQL view of the library, so the
+// library screen can fetch documents with their reading positions and highlights in
+// one round trip. It runs behind the HTTP auth middleware and uses the same services
+// as the JSON API.
+package graphqlserver
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	"pdf-text-reader/internal/authctx"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/graph-gophers/graphql-go"
+)
+
+//go:embed schema.graphql
+var schemaSDL string
+
+const (
+	// maxDepth bounds nesting; the deepest useful query is
+	// library → documents → highlights → field.
+	maxDepth        = 8
+	maxRequestBytes = 64 << 10
+)
+
+// NewHandler returns the /graphql endpoint. Requests must carry a principal, i.e. be
+// routed through the auth middleware.
+func NewHandler(
+	documentService domain.DocumentService,
+	preferenceService domain.UserPreferencesService,
+	highlightService domain.HighlightService,
+	logger domain.Logger,
+) http.Handler {
+	root := &rootResolver{
+		documentService:   documentService,
+		preferenceService: preferenceService,
+		highlightService:  highlightService,
+		logger:            logger,
+	}
+	schema := graphql.MustParseSchema(schemaSDL, root, graphql.MaxDepth(maxDepth))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, ok := authctx.Principal(r.Context())
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "User not found in context")
+			return
+		}
+
+		var params struct {
+			Query         string                 `json:"query"`
+			OperationName string                 `json:"operationName"`
+			Variables     map[string]interface{} `json:"variables"`
+		}
+		if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxRequestBytes)).Decode(&params); err != nil {
+			writeError(w, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		ctx := withLoader(r.Context(), newLoader(root, principal))
+		response := schema.Exec(ctx, params.Query, params.OperationName, params.Variables)
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(response)
+	})
+}
+
+func writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+// loader fetches what nested fields share once per request: listing the library
+// with positions and highlights costs one call per kind, not one per document.
+// Fields resolve concurrently, hence the sync.Once guards.
+type loader struct {
+	root      *rootResolver
+	principal domain.Principal
+
+	positionsOnce sync.Once
+	positions     map[string]*domain.ReadingPosition
+	positionsErr  error
+
+	highlightsOnce sync.Once
+	highlights     map[string][]*domain.Highlight
+	highlightsErr  error
+}
+
+func newLoader(root *rootResolver, principal domain.Principal) *loader {
+	return &loader{root: root, principal: principal}
+}
@@ -0,0 +1,180 @@
+package graphqlserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"pdf-text-reader/internal/authctx"
+	"pdf-text-reader/internal/domain"
+)
+
+// mockDocumentService implements the calls the resolvers make; the embedded
+// interface panics on anything else.
+type mockDocumentService struct {
+	domain.DocumentService
+	docs []*domain.Document
+}
+
+func (m *mockDocumentService) GetDocumentsByUserID(ctx context.Context, principal domain.Principal) ([]*domain.DocumentData, error) {
+	return m.docs, nil
+}
+
+func (m *mockDocumentService) GetDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	for _, doc := range m.docs {
+		if doc.ID == documentID {
+			return doc, nil
+		}
+	}
+	return nil, domain.ErrAccessDenied
+}
+
+func (m *mockDocumentService) GetDocumentTags(ctx context.Context, principal domain.Principal) ([]string, error) {
+	return []string{"classics"}, nil
+}
+
+type mockPreferenceService struct {
+	domain.UserPreferencesService
+	calls atomic.Int32
+}
+
+func (m *mockPreferenceService) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
+	m.calls.Add(1)
+	return map[string]*domain.ReadingPosition{
+		"doc-1": {Progress: 0.5, PageNumber: 12},
+	}, nil
+}
+
+type mockHighlightService struct {
+	domain.HighlightService
+	calls atomic.Int32
+}
+
+func (m *mockHighlightService) ListHighlights(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
+	m.calls.Add(1)
+	page := 3
+	return []*domain.Highlight{
+		{ID: "h1", DocumentID: "doc-1", Quote: "It is a truth universally acknowledged", PageNumber: &page},
+		{ID: "h2", DocumentID: "doc-2", Quote: "Down the rabbit hole"},
+	}, nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, fields ...interface{})             {}
+func (nopLogger) Error(msg string, err error, fields ...interface{}) {}
+func (nopLogger) Debug(msg string, fields ...interface{})            {}
+func (nopLogger) Warn(msg string, fields ...interface{})             {}
+
+func newTestHandler() (http.Handler, *mockPreferenceService, *mockHighlightService) {
+	tag := "classics"
+	docs := &mockDocumentService{docs: []*domain.Document{
+		{ID: "doc-1", Title: "Pride and Prejudice", Tag: &tag, IsFavorite: true},
+		{ID: "doc-2", Title: "Alice's Adventures in Wonderland"},
+	}}
+	prefs := &mockPreferenceService{}
+	highlights := &mockHighlightService{}
+	return NewHandler(docs, prefs, highlights, nopLogger{}), prefs, highlights
+}
+
+func execute(t *testing.T, h http.Handler, query string, withPrincipal bool) (*httptest.ResponseRecorder, map[string]interface{}) {
+	t.Helper()
+	body, _ := json.Marshal(map[string]string{"query": query})
+	req := httptest.NewRequest(http.MethodPost, "/graphql", strings.NewReader(string(body)))
+	if withPrincipal {
+		req = req.WithContext(authctx.WithPrincipal(req.Context(), domain.Principal{UserID: "user-1", Token: "t0ken"}))
+	}
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	var resp map[string]interface{}
+	if rr.Code == http.StatusOK {
+		if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", rr.Body.String(), err)
+		}
+	}
+	return rr, resp
+}
+
+func TestHandler_Library(t *testing.T) {
+	h, prefs, highlights := newTestHandler()
+
+	rr, resp := execute(t, h, `{
+		library {
+			tags
+			documents {
+				id
+				title
+				readingPosition { progress pageNumber }
+				highlights { id quote pageNumber }
+			}
+		}
+	}`, true)
+	if rr.Code != http.StatusOK || resp["errors"] != nil {
+		t.Fatalf("unexpected response %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var got struct {
+		Data struct {
+			Library struct {
+				Tags      []string
+				Documents []struct {
+					ID              string
+					ReadingPosition *struct {
+						Progress   float64
+						PageNumber int
+					}
+					Highlights []struct {
+						ID         string
+						PageNumber *int
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	docs := got.Data.Library.Documents
+	if len(docs) != 2 || len(got.Data.Library.Tags) != 1 {
+		t.Fatalf("unexpected library: %s", rr.Body.String())
+	}
+	if docs[0].ReadingPosition == nil || docs[0].ReadingPosition.PageNumber != 12 || docs[1].ReadingPosition != nil {
+		t.Fatalf("unexpected reading positions: %s", rr.Body.String())
+	}
+	if len(docs[0].Highlights) != 1 || *docs[0].Highlights[0].PageNumber != 3 || len(docs[1].Highlights) != 1 {
+		t.Fatalf("unexpected highlights: %s", rr.Body.String())
+	}
+
+	// Nested fields share one load per request instead of one per document.
+	if prefs.calls.Load() != 1 || highlights.calls.Load() != 1 {
+		t.Fatalf("expected one positions and one highlights call, got %d and %d", prefs.calls.Load(), highlights.calls.Load())
+	}
+}
+
+func TestHandler_Filters(t *testing.T) {
+	h, _, _ := newTestHandler()
+
+	rr, _ := execute(t, h, `{ library { documents(favorite: false) { id } } }`, true)
+	if !strings.Contains(rr.Body.String(), `"doc-2"`) || strings.Contains(rr.Body.String(), `"doc-1"`) {
+		t.Fatalf("expected only the non-favorite document, got %s", rr.Body.String())
+	}
+
+	rr, _ = execute(t, h, `{ document(id: "missing") { id } }`, true)
+	if !strings.Contains(rr.Body.String(), `"document":null`) {
+		t.Fatalf("expected null for an inaccessible document, got %s", rr.Body.String())
+	}
+}
+
+func TestHandler_RequiresPrincipal(t *testing.T) {
+	h, _, _ := newTestHandler()
+
+	rr, _ := execute(t, h, `{ library { tags } }`, false)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a principal, got %d", rr.Code)
+	}
+}
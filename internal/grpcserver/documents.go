@@ -0,0 +1,140 @@
+package grpcserver
+
+import (
+	"context"
+
+	"pdf-text-reader/internal/domain"
+	lectorv1 "pdf-text-reader/pkg/api/lector/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type documentServer struct {
+	lectorv1.UnimplementedDocumentServiceServer
+
+	documentService domain.DocumentService
+	logger          domain.Logger
+}
+
+func (s *documentServer) ListDocuments(ctx context.Context, _ *emptypb.Empty) (*lectorv1.ListDocumentsResponse, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	docs, err := s.documentService.GetDocumentsByUserID(ctx, p)
+	if err != nil {
+		s.logger.Error("Failed to list documents", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+	return documentList(docs), nil
+}
+
+func (s *documentServer) GetDocument(ctx context.Context, req *lectorv1.GetDocumentRequest) (*lectorv1.Document, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "document ID is required")
+	}
+
+	doc, err := s.documentService.OpenDocument(ctx, p, req.GetId())
+	if err != nil {
+		return nil, serviceError(s.logger, err)
+	}
+	return documentToProto(doc, true), nil
+}
+
+func (s *documentServer) SearchDocuments(ctx context.Context, req *lectorv1.SearchDocumentsRequest) (*lectorv1.ListDocumentsResponse, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetQuery() == "" {
+		return nil, status.Error(codes.InvalidArgument, "search query is required")
+	}
+
+	docs, err := s.documentService.SearchDocuments(ctx, p, req.GetQuery())
+	if err != nil {
+		s.logger.Error("Failed to search documents", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+	return documentList(docs), nil
+}
+
+func (s *documentServer) SetFavorite(ctx context.Context, req *lectorv1.SetFavoriteRequest) (*emptypb.Empty, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "document ID is required")
+	}
+
+	if err := s.documentService.SetFavorite(ctx, p, req.GetId(), req.GetFavorite()); err != nil {
+		return nil, serviceError(s.logger, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *documentServer) DeleteDocument(ctx context.Context, req *lectorv1.DeleteDocumentRequest) (*emptypb.Empty, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "document ID is required")
+	}
+
+	if err := s.documentService.DeleteDocument(ctx, p, req.GetId()); err != nil {
+		s.logger.Error("Failed to delete document", err, "user_id", p.UserID, "document_id", req.GetId())
+		return nil, serviceError(s.logger, err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+// documentList converts a library listing; page content is left out like in the
+// JSON library response.
+func documentList(docs []*domain.DocumentData) *lectorv1.ListDocumentsResponse {
+	resp := &lectorv1.ListDocumentsResponse{Documents: make([]*lectorv1.Document, 0, len(docs))}
+	for _, doc := range docs {
+		resp.Documents = append(resp.Documents, documentToProto(doc, false))
+	}
+	return resp
+}
+
+func documentToProto(doc *domain.DocumentData, withContent bool) *lectorv1.Document {
+	out := &lectorv1.Document{
+		Id:          doc.ID,
+		UserId:      doc.UserID,
+		Title:       doc.Title,
+		Author:      doc.Author,
+		Description: doc.Description,
+		Tag:         doc.Tag,
+		IsFavorite:  doc.IsFavorite,
+		Metadata: &lectorv1.DocumentMetadata{
+			OriginalTitle:  doc.Metadata.OriginalTitle,
+			OriginalAuthor: doc.Metadata.OriginalAuthor,
+			Language:       doc.Metadata.Language,
+			PageCount:      int32(doc.Metadata.PageCount),
+			WordCount:      int32(doc.Metadata.WordCount),
+			FileSize:       doc.Metadata.FileSize,
+			Format:         doc.Metadata.Format,
+			Source:         doc.Metadata.Source,
+			HasPassword:    doc.Metadata.HasPassword,
+		},
+		CreatedAt: timestamppb.New(doc.CreatedAt),
+		UpdatedAt: timestamppb.New(doc.UpdatedAt),
+	}
+	if withContent {
+		out.ContentJson = doc.Content
+	}
+	if doc.ReadingPosition != nil {
+		out.ReadingPosition = readingPositionToProto(doc.ReadingPosition)
+	}
+	return out
+}
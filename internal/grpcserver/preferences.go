@@ -0,0 +1,206 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"pdf-text-reader/internal/domain"
+	lectorv1 "pdf-text-reader/pkg/api/lector/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+type preferenceServer struct {
+	lectorv1.UnimplementedPreferenceServiceServer
+
+	preferenceService domain.UserPreferencesService
+	logger            domain.Logger
+}
+
+func (s *preferenceServer) GetPreferences(ctx context.Context, _ *emptypb.Empty) (*lectorv1.UserPreferences, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	prefs, err := s.preferenceService.GetPreferences(ctx, p)
+	if err != nil {
+		s.logger.Error("Failed to get preferences", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+	return preferencesToProto(prefs), nil
+}
+
+// UpdatePreferences replaces the reading preferences with the request's. Subscription
+// and account fields are managed elsewhere and ignored.
+func (s *preferenceServer) UpdatePreferences(ctx context.Context, req *lectorv1.UserPreferences) (*lectorv1.UserPreferences, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	update := preferencesUpdateFromProto(req)
+	if err := update.Validate(); err != nil {
+		return nil, serviceError(s.logger, err)
+	}
+
+	prefs, err := s.preferenceService.GetPreferences(ctx, p)
+	if err != nil {
+		s.logger.Error("Failed to get current preferences", err, "user_id", p.UserID)
+		prefs = domain.DefaultUserPreferences(p.UserID)
+	}
+	update.ApplyTo(prefs)
+
+	if err := s.preferenceService.UpdatePreferences(ctx, p, prefs); err != nil {
+		s.logger.Error("Failed to update preferences", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+
+	updated, err := s.preferenceService.GetPreferences(ctx, p)
+	if err != nil {
+		s.logger.Error("Failed to get updated preferences", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+	return preferencesToProto(updated), nil
+}
+
+func (s *preferenceServer) ListReadingPositions(ctx context.Context, _ *emptypb.Empty) (*lectorv1.ListReadingPositionsResponse, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	positions, err := s.preferenceService.GetAllReadingPositions(ctx, p)
+	if err != nil {
+		s.logger.Error("Failed to get reading positions", err, "user_id", p.UserID)
+		return nil, serviceError(s.logger, err)
+	}
+
+	resp := &lectorv1.ListReadingPositionsResponse{Positions: make([]*lectorv1.ReadingPosition, 0, len(positions))}
+	for documentID, position := range positions {
+		out := readingPositionToProto(position)
+		out.DocumentId = documentID
+		resp.Positions = append(resp.Positions, out)
+	}
+	sort.Slice(resp.Positions, func(i, j int) bool {
+		return resp.Positions[i].DocumentId < resp.Positions[j].DocumentId
+	})
+	return resp, nil
+}
+
+func (s *preferenceServer) GetReadingPosition(ctx context.Context, req *lectorv1.GetReadingPositionRequest) (*lectorv1.ReadingPosition, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetDocumentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "document ID is required")
+	}
+
+	return s.readingPosition(ctx, p, req.GetDocumentId())
+}
+
+func (s *preferenceServer) UpdateReadingPosition(ctx context.Context, req *lectorv1.ReadingPosition) (*lectorv1.ReadingPosition, error) {
+	p, err := principal(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.GetDocumentId() == "" {
+		return nil, status.Error(codes.InvalidArgument, "document ID is required")
+	}
+
+	position := &domain.ReadingPosition{
+		UserID:     p.UserID,
+		DocumentID: req.GetDocumentId(),
+		Progress:   req.GetProgress(),
+		PageNumber: int(req.GetPageNumber()),
+	}
+	if err := position.Validate(); err != nil {
+		return nil, serviceError(s.logger, err)
+	}
+
+	if err := s.preferenceService.UpdateReadingPosition(ctx, p, req.GetDocumentId(), position); err != nil {
+		s.logger.Error("Failed to update reading position", err, "user_id", p.UserID, "document_id", req.GetDocumentId())
+		return nil, serviceError(s.logger, err)
+	}
+	return s.readingPosition(ctx, p, req.GetDocumentId())
+}
+
+func (s *preferenceServer) readingPosition(ctx context.Context, p domain.Principal, documentID string) (*lectorv1.ReadingPosition, error) {
+	position, err := s.preferenceService.GetReadingPosition(ctx, p, documentID)
+	if err == nil && position == nil {
+		err = domain.ErrReadingPositionNotFound
+	}
+	if err != nil {
+		if !errors.Is(err, domain.ErrReadingPositionNotFound) {
+			s.logger.Error("Failed to get reading position", err, "user_id", p.UserID, "document_id", documentID)
+		}
+		return nil, serviceError(s.logger, err)
+	}
+	return readingPositionToProto(position), nil
+}
+
+func readingPositionToProto(position *domain.ReadingPosition) *lectorv1.ReadingPosition {
+	return &lectorv1.ReadingPosition{
+		DocumentId: position.DocumentID,
+		Progress:   position.Progress,
+		PageNumber: int32(position.PageNumber),
+		UpdatedAt:  timestamppb.New(position.UpdatedAt),
+	}
+}
+
+func preferencesToProto(prefs *domain.UserPreferences) *lectorv1.UserPreferences {
+	return &lectorv1.UserPreferences{
+		FontSize:          int32(prefs.FontSize),
+		FontFamily:        prefs.FontFamily,
+		Theme:             prefs.Theme,
+		HighlightColor:    prefs.HighlightColor,
+		MarginSize:        prefs.MarginSize,
+		TextAlign:         prefs.TextAlign,
+		Hyphenation:       prefs.Hyphenation,
+		ReadingMode:       prefs.ReadingMode,
+		PageTurnAnimation: prefs.PageTurnAnimation,
+		Brightness:        prefs.Brightness,
+		SubscriptionPlan:  prefs.SubscriptionPlan,
+		StorageLimitBytes: prefs.StorageLimitBytes,
+		AccountDisabled:   prefs.AccountDisabled,
+		Tags:              prefs.Tags,
+		UpdatedAt:         timestamppb.New(prefs.UpdatedAt),
+	}
+}
+
+// preferencesUpdateFromProto sets every reading preference; proto3 scalars cannot
+// tell an unset field from its zero value, so UpdatePreferences is a full replace.
+func preferencesUpdateFromProto(req *lectorv1.UserPreferences) *domain.PreferencesUpdate {
+	fontSize := int(req.GetFontSize())
+	fontFamily := req.GetFontFamily()
+	theme := req.GetTheme()
+	highlightColor := req.GetHighlightColor()
+	marginSize := req.GetMarginSize()
+	textAlign := req.GetTextAlign()
+	hyphenation := req.GetHyphenation()
+	readingMode := req.GetReadingMode()
+	pageTurnAnimation := req.GetPageTurnAnimation()
+	brightness := req.GetBrightness()
+	tags := req.GetTags()
+	if tags == nil {
+		tags = []string{}
+	}
+	return &domain.PreferencesUpdate{
+		FontSize:          &fontSize,
+		FontFamily:        &fontFamily,
+		Theme:             &theme,
+		HighlightColor:    &highlightColor,
+		MarginSize:        &marginSize,
+		TextAlign:         &textAlign,
+		Hyphenation:       &hyphenation,
+		ReadingMode:       &readingMode,
+		PageTurnAnimation: &pageTurnAnimation,
+		Brightness:        &brightness,
+		Tags:              &tags,
+	}
+}
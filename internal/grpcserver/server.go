@@ -0,0 +1,151 @@
+// Package grpcserver serves the document and preference services over gRPC, next to
+// the JSON API. Both APIs share the same services, authentication and row level
+// security; only the transport differs.
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"pdf-text-reader/internal/authctx"
+	"pdf-text-reader/internal/domain"
+	lectorv1 "pdf-text-reader/pkg/api/lector/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// NewServer returns a gRPC server with the document and preference services
+// registered behind the token authentication interceptor and, when maintenance is
+// set, the maintenance interceptor.
+func NewServer(
+	documentService domain.DocumentService,
+	preferenceService domain.UserPreferencesService,
+	authService domain.AuthService,
+	maintenance domain.MaintenanceMode,
+	logger domain.Logger,
+) *grpc.Server {
+	server := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		authInterceptor(authService, logger),
+		maintenanceInterceptor(maintenance),
+	))
+	lectorv1.RegisterDocumentServiceServer(server, &documentServer{
+		documentService: documentService,
+		logger:          logger,
+	})
+	lectorv1.RegisterPreferenceServiceServer(server, &preferenceServer{
+		preferenceService: preferenceService,
+		logger:            logger,
+	})
+	return server
+}
+
+// authInterceptor validates the bearer token in the "authorization" metadata the same
+// way the HTTP auth middleware validates the Authorization header, and stores the
+// principal in the context.
+func authInterceptor(authService domain.AuthService, logger domain.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+		}
+
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok || token == "" {
+			return nil, status.Error(codes.Unauthenticated, "invalid authorization metadata format")
+		}
+
+		user, err := authService.ValidateToken(ctx, token)
+		if err != nil {
+			logger.Error("Token validation failed", err, "method", info.FullMethod)
+			if errors.Is(err, domain.ErrUpstreamUnavailable) {
+				return nil, serviceError(logger, err)
+			}
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+
+		disabled, err := authService.IsAccountDisabled(ctx, user.ID, token)
+		if err != nil {
+			logger.Error("Failed to check account status", err, "user_id", user.ID)
+			if errors.Is(err, domain.ErrUpstreamUnavailable) {
+				return nil, serviceError(logger, err)
+			}
+			return nil, status.Error(codes.Internal, "failed to validate account status")
+		}
+		if disabled {
+			return nil, status.Error(codes.PermissionDenied, "account disabled")
+		}
+
+		return handler(authctx.WithCaller(ctx, user, token), req)
+	}
+}
+
+// writeMethods are the RPCs that change data, refused during maintenance like the
+// JSON API's writes.
+var writeMethods = map[string]bool{
+	lectorv1.DocumentService_SetFavorite_FullMethodName:             true,
+	lectorv1.DocumentService_DeleteDocument_FullMethodName:          true,
+	lectorv1.PreferenceService_UpdatePreferences_FullMethodName:     true,
+	lectorv1.PreferenceService_UpdateReadingPosition_FullMethodName: true,
+}
+
+// maintenanceInterceptor refuses writeMethods while maintenance is on and marks the
+// context of other calls read-only, as the HTTP maintenance guard does.
+func maintenanceInterceptor(maintenance domain.MaintenanceMode) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if maintenance == nil {
+			return handler(ctx, req)
+		}
+		state := maintenance.Status()
+		if !state.Enabled {
+			return handler(ctx, req)
+		}
+		if writeMethods[info.FullMethod] {
+			return nil, status.Error(codes.Unavailable, state.Message)
+		}
+		return handler(domain.ContextWithReadOnly(ctx), req)
+	}
+}
+
+// principal returns the caller stored by the auth interceptor.
+func principal(ctx context.Context) (domain.Principal, error) {
+	p, ok := authctx.Principal(ctx)
+	if !ok {
+		return domain.Principal{}, status.Error(codes.Unauthenticated, "user not found in context")
+	}
+	return p, nil
+}
+
+// serviceError maps domain errors to gRPC status codes, following the status codes
+// the JSON API uses for the same errors. Like the JSON API's error envelope it keeps
+// the text of unexpected errors, which may name tables or storage paths, in the log.
+func serviceError(logger domain.Logger, err error) error {
+	var validationErr *domain.ValidationError
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErr), errors.As(err, &validationErrs):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, domain.ErrDocumentNotFound),
+		errors.Is(err, domain.ErrReadingPositionNotFound),
+		errors.Is(err, domain.ErrPageNotFound),
+		errors.Is(err, domain.ErrVersionNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, domain.ErrAccessDenied):
+		return status.Error(codes.PermissionDenied, "access denied")
+	case errors.Is(err, domain.ErrAccountDisabled):
+		return status.Error(codes.PermissionDenied, "account disabled")
+	case errors.Is(err, domain.ErrDocumentQuarantined):
+		return status.Error(codes.FailedPrecondition, "document is held for security review")
+	case errors.Is(err, domain.ErrStaleUpdate):
+		return status.Error(codes.Aborted, err.Error())
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return status.Error(codes.Unavailable, domain.ErrUpstreamUnavailable.Error())
+	default:
+		logger.Error("gRPC call failed", err)
+		return status.Error(codes.Internal, "internal error")
+	}
+}
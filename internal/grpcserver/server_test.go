@@ -0,0 +1,339 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/service"
+	lectorv1 "pdf-text-reader/pkg/api/lector/v1"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type mockAuthService struct {
+	disabled bool
+	err      error
+}
+
+func (m *mockAuthService) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if token != "t0ken" {
+		return nil, domain.ErrInvalidToken
+	}
+	return &domain.SupabaseUser{ID: "user-1", Email: "reader@example.com"}, nil
+}
+
+func (m *mockAuthService) IsAccountDisabled(ctx context.Context, userID string, token string) (bool, error) {
+	return m.disabled, nil
+}
+
+// mockDocumentService implements the calls the gRPC API makes; the embedded
+// interface panics on anything else.
+type mockDocumentService struct {
+	domain.DocumentService
+	docs      map[string]*domain.Document
+	principal domain.Principal
+	readOnly  bool // Whether the last OpenDocument was marked read-only
+}
+
+func (m *mockDocumentService) GetDocumentsByUserID(ctx context.Context, principal domain.Principal) ([]*domain.DocumentData, error) {
+	m.principal = principal
+	var docs []*domain.DocumentData
+	for _, doc := range m.docs {
+		docs = append(docs, doc)
+	}
+	return docs, nil
+}
+
+func (m *mockDocumentService) GetDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	doc, ok := m.docs[documentID]
+	if !ok {
+		return nil, domain.ErrAccessDenied
+	}
+	return doc, nil
+}
+
+func (m *mockDocumentService) OpenDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	m.readOnly = domain.ReadOnlyFromContext(ctx)
+	return m.GetDocument(ctx, principal, documentID)
+}
+
+func (m *mockDocumentService) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
+	doc, ok := m.docs[documentID]
+	if !ok {
+		return domain.ErrAccessDenied
+	}
+	doc.IsFavorite = isFavorite
+	return nil
+}
+
+func (m *mockDocumentService) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
+	doc, ok := m.docs[documentID]
+	if !ok {
+		return domain.ErrAccessDenied
+	}
+	doc.IsArchived = archived
+	return nil
+}
+
+type mockPreferenceService struct {
+	domain.UserPreferencesService
+	prefs     *domain.UserPreferences
+	positions map[string]*domain.ReadingPosition
+}
+
+func (m *mockPreferenceService) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
+	copied := *m.prefs
+	return &copied, nil
+}
+
+func (m *mockPreferenceService) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
+	m.prefs = prefs
+	return nil
+}
+
+func (m *mockPreferenceService) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
+	position, ok := m.positions[documentID]
+	if !ok {
+		return nil, domain.ErrReadingPositionNotFound
+	}
+	return position, nil
+}
+
+func (m *mockPreferenceService) UpdateReadingPosition(ctx context.Context, principal domain.Principal, documentID string, position *domain.ReadingPosition) error {
+	m.positions[documentID] = position
+	return nil
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Info(msg string, fields ...interface{})             {}
+func (nopLogger) Error(msg string, err error, fields ...interface{}) {}
+func (nopLogger) Debug(msg string, fields ...interface{})            {}
+func (nopLogger) Warn(msg string, fields ...interface{})             {}
+
+func newTestConn(t *testing.T, docs *mockDocumentService, prefs *mockPreferenceService, auth *mockAuthService, maintenance domain.MaintenanceMode) *grpc.ClientConn {
+	t.Helper()
+	listener := bufconn.Listen(1 << 20)
+	server := NewServer(docs, prefs, auth, maintenance, nopLogger{})
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return conn
+}
+
+func withToken(token string) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	docs := &mockDocumentService{docs: map[string]*domain.Document{}}
+	auth := &mockAuthService{}
+	client := lectorv1.NewDocumentServiceClient(newTestConn(t, docs, &mockPreferenceService{}, auth, nil))
+
+	_, err := client.ListDocuments(context.Background(), &emptypb.Empty{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated without metadata, got %v", err)
+	}
+
+	_, err = client.ListDocuments(withToken("wrong"), &emptypb.Empty{})
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated for an invalid token, got %v", err)
+	}
+
+	if _, err := client.ListDocuments(withToken("t0ken"), &emptypb.Empty{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if docs.principal.UserID != "user-1" || docs.principal.Token != "t0ken" {
+		t.Fatalf("expected the principal of the token, got %+v", docs.principal)
+	}
+
+	auth.disabled = true
+	_, err = client.ListDocuments(withToken("t0ken"), &emptypb.Empty{})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied for a disabled account, got %v", err)
+	}
+
+	auth.err = &domain.UpstreamError{Err: errors.New("gotrue unreachable")}
+	_, err = client.ListDocuments(withToken("t0ken"), &emptypb.Empty{})
+	if status.Code(err) != codes.Unavailable {
+		t.Fatalf("expected Unavailable while tokens cannot be checked, got %v", err)
+	}
+}
+
+func TestDocumentService(t *testing.T) {
+	author := "Jane Austen"
+	docs := &mockDocumentService{docs: map[string]*domain.Document{
+		"doc-1": {
+			ID:       "doc-1",
+			UserID:   "user-1",
+			Title:    "Pride and Prejudice",
+			Author:   &author,
+			Content:  []byte(`[{"page":1}]`),
+			Metadata: domain.DocumentMetadata{PageCount: 3, Format: "pdf"},
+		},
+	}}
+	client := lectorv1.NewDocumentServiceClient(newTestConn(t, docs, &mockPreferenceService{}, &mockAuthService{}, nil))
+	ctx := withToken("t0ken")
+
+	list, err := client.ListDocuments(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(list.Documents) != 1 || list.Documents[0].GetAuthor() != author || len(list.Documents[0].ContentJson) != 0 {
+		t.Fatalf("unexpected listing: %v", list)
+	}
+
+	doc, err := client.GetDocument(ctx, &lectorv1.GetDocumentRequest{Id: "doc-1"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(doc.ContentJson) != `[{"page":1}]` || doc.Metadata.PageCount != 3 || doc.GetTag() != "" {
+		t.Fatalf("unexpected document: %v", doc)
+	}
+
+	_, err = client.GetDocument(ctx, &lectorv1.GetDocumentRequest{Id: "doc-2"})
+	if status.Code(err) != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+	_, err = client.GetDocument(ctx, &lectorv1.GetDocumentRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument without an ID, got %v", err)
+	}
+
+	if _, err := client.SetFavorite(ctx, &lectorv1.SetFavoriteRequest{Id: "doc-1", Favorite: true}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !docs.docs["doc-1"].IsFavorite {
+		t.Fatalf("expected the document to be a favorite")
+	}
+}
+
+func TestPreferenceService(t *testing.T) {
+	prefs := &mockPreferenceService{
+		prefs:     domain.DefaultUserPreferences("user-1"),
+		positions: map[string]*domain.ReadingPosition{},
+	}
+	client := lectorv1.NewPreferenceServiceClient(newTestConn(t, &mockDocumentService{}, prefs, &mockAuthService{}, nil))
+	ctx := withToken("t0ken")
+
+	current, err := client.GetPreferences(ctx, &emptypb.Empty{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	current.Theme = domain.ThemeSepia
+	current.FontSize = 20
+	current.SubscriptionPlan = "pro"
+
+	updated, err := client.UpdatePreferences(ctx, current)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if updated.Theme != domain.ThemeSepia || updated.FontSize != 20 || updated.SubscriptionPlan != "free" {
+		t.Fatalf("unexpected preferences: %v", updated)
+	}
+
+	current.FontSize = 500
+	_, err = client.UpdatePreferences(ctx, current)
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for an out of range font size, got %v", err)
+	}
+
+	_, err = client.GetReadingPosition(ctx, &lectorv1.GetReadingPositionRequest{DocumentId: "doc-1"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound before the first update, got %v", err)
+	}
+
+	position, err := client.UpdateReadingPosition(ctx, &lectorv1.ReadingPosition{DocumentId: "doc-1", Progress: 0.5, PageNumber: 12})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if position.Progress != 0.5 || position.PageNumber != 12 {
+		t.Fatalf("unexpected position: %v", position)
+	}
+
+	_, err = client.UpdateReadingPosition(ctx, &lectorv1.ReadingPosition{DocumentId: "doc-1", Progress: 2})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for progress above 1, got %v", err)
+	}
+}
+
+func TestMaintenanceInterceptor(t *testing.T) {
+	docs := &mockDocumentService{docs: map[string]*domain.Document{"doc-1": {ID: "doc-1", UserID: "user-1"}}}
+	maintenance := service.NewMaintenanceMode()
+	maintenance.Set(true, "Migrating documents")
+	client := lectorv1.NewDocumentServiceClient(newTestConn(t, docs, &mockPreferenceService{}, &mockAuthService{}, maintenance))
+	ctx := withToken("t0ken")
+
+	_, err := client.SetFavorite(ctx, &lectorv1.SetFavoriteRequest{Id: "doc-1", Favorite: true})
+	if status.Code(err) != codes.Unavailable || docs.docs["doc-1"].IsFavorite {
+		t.Fatalf("expected the write to be refused with Unavailable, got %v", err)
+	}
+	if _, err := client.GetDocument(ctx, &lectorv1.GetDocumentRequest{Id: "doc-1"}); err != nil || !docs.readOnly {
+		t.Fatalf("expected reads to go on without recording the open, got %v", err)
+	}
+
+	maintenance.Set(false, "")
+	if _, err := client.SetFavorite(ctx, &lectorv1.SetFavoriteRequest{Id: "doc-1", Favorite: true}); err != nil || !docs.docs["doc-1"].IsFavorite {
+		t.Fatalf("expected writes to resume after maintenance, got %v", err)
+	}
+}
+
+func TestServiceError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want codes.Code
+	}{
+		{domain.ErrDocumentNotFound, codes.NotFound},
+		{domain.ErrAccessDenied, codes.PermissionDenied},
+		{domain.ErrStaleUpdate, codes.Aborted},
+		{&domain.ValidationError{Field: "title", Message: "title is required"}, codes.InvalidArgument},
+		{errors.New("boom"), codes.Internal},
+	}
+	for _, tt := range tests {
+		if got := status.Code(serviceError(nopLogger{}, tt.err)); got != tt.want {
+			t.Errorf("serviceError(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+
+	// Unexpected errors are logged, and clients only learn that something failed.
+	logger := &errorLogger{}
+	err := serviceError(logger, errors.New(`relation "documents" does not exist`))
+	if msg := status.Convert(err).Message(); msg != "internal error" {
+		t.Errorf("expected a generic message, got %q", msg)
+	}
+	if len(logger.errs) != 1 {
+		t.Errorf("expected the error to be logged, got %v", logger.errs)
+	}
+}
+
+// errorLogger records the errors it is given.
+type errorLogger struct {
+	nopLogger
+	errs []error
+}
+
+func (l *errorLogger) Error(msg string, err error, fields ...interface{}) {
+	l.errs = append(l.errs, err)
+}
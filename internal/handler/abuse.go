@@ -0,0 +1,183 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// uploadRoutes are the routes that store files, whether sent or fetched for the
+// caller, counted as uploads per user and per IP.
+var uploadRoutes = map[string]bool{
+	"/api/v1/documents":                           true,
+	"/api/v1/catalog/import/{gutenbergId:[0-9]+}": true,
+	"/api/v1/import/calibre":                      true,
+	"/api/v1/import/highlights":                   true,
+	"/api/v1/import/{provider}":                   true,
+	"/api/v1/import/{service:pocket|instapaper}":  true,
+	"/api/v1/preferences/fonts":                   true,
+}
+
+// credentialRoute reports whether the route mux matched for r checks credentials:
+// the local sign-in and the admin endpoints, which check X-Admin-Secret. Only their
+// failures count as auth failures; an expired token elsewhere is not an attack.
+func credentialRoute(r *http.Request) bool {
+	template := routeTemplate(r)
+	return template == "/api/v1/auth/login" || strings.HasPrefix(template, "/api/v1/admin/")
+}
+
+// abuseGuard enforces the throttles of an AbuseMonitor. The zero value, without a
+// monitor, lets everything through.
+type abuseGuard struct {
+	monitor domain.AbuseMonitor
+}
+
+// authFailures counts 401 responses of credentialRoute routes per IP. An IP
+// throttled for them is refused before its credentials are checked again; the rest
+// of the API stays available to it, e.g. to other users behind the same NAT.
+func (g abuseGuard) authFailures(next http.Handler) http.Handler {
+	if g.monitor == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !credentialRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := "ip:" + clientIP(r)
+		if wait := g.monitor.Throttled(domain.AbuseSignalAuthFailure, key); wait > 0 {
+			writeThrottled(w, wait)
+			return
+		}
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+		if recorder.status == http.StatusUnauthorized {
+			g.monitor.Record(domain.AbuseSignalAuthFailure, key)
+		}
+	})
+}
+
+// uploads counts requests to uploadRoutes per user and per IP, and refuses them
+// while either is throttled. It runs behind the auth middleware.
+func (g abuseGuard) uploads(next http.Handler) http.Handler {
+	if g.monitor == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !uploadRoutes[routeTemplate(r)] {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		wait := g.monitor.Record(domain.AbuseSignalUpload, "ip:"+clientIP(r))
+		if principal, ok := GetPrincipalFromContext(r); ok {
+			wait = max(wait, g.monitor.Record(domain.AbuseSignalUpload, "user:"+principal.UserID))
+		}
+		if wait > 0 {
+			writeThrottled(w, wait)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeThrottled answers a throttled request with 429 and when to retry.
+func writeThrottled(w http.ResponseWriter, wait time.Duration) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(wait.Seconds())), 1)))
+	w.WriteHeader(http.StatusTooManyRequests)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "Too many requests, please try again later", "code": "throttled"})
+}
+
+// routeTemplate returns the path template of the route mux matched for r, or "".
+func routeTemplate(r *http.Request) string {
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return ""
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return ""
+	}
+	return template
+}
+
+type clientIPKey struct{}
+
+// ClientAddress stores the address of each request's client on its context for
+// clientIP. Behind trustedProxies reverse proxies that is the entry of
+// X-Forwarded-For the outermost proxy appended, trustedProxies hops from the right;
+// entries left of it come from the client and could be forged to dodge a throttle.
+// Without trusted proxies, or when the header has fewer hops, it is the connection's
+// address.
+func ClientAddress(trustedProxies int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := remoteIP(r)
+			if trustedProxies > 0 {
+				var hops []string
+				for _, header := range r.Header.Values("X-Forwarded-For") {
+					hops = append(hops, strings.Split(header, ",")...)
+				}
+				if len(hops) >= trustedProxies {
+					if hop := strings.TrimSpace(hops[len(hops)-trustedProxies]); hop != "" {
+						ip = hop
+					}
+				}
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), clientIPKey{}, ip)))
+		})
+	}
+}
+
+// clientIP returns the address ClientAddress resolved for r, or the connection's
+// address outside it.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPKey{}).(string); ok {
+		return ip
+	}
+	return remoteIP(r)
+}
+
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// statusRecorder remembers the status code and body size written through it.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	bytes  int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(p []byte) (int, error) {
+	n, err := s.ResponseWriter.Write(p)
+	s.bytes += int64(n)
+	return n, err
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer, e.g. to flush
+// event streams.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+)
+
+// stubAbuseMonitor throttles a key for a minute once it is recorded more than max
+// times for a signal.
+type stubAbuseMonitor struct {
+	max       int
+	counts    map[string]int
+	throttled map[string]bool
+}
+
+func newStubAbuseMonitor(max int) *stubAbuseMonitor {
+	return &stubAbuseMonitor{max: max, counts: map[string]int{}, throttled: map[string]bool{}}
+}
+
+func (m *stubAbuseMonitor) Record(signal domain.AbuseSignal, key string) time.Duration {
+	id := string(signal) + "|" + key
+	m.counts[id]++
+	if m.counts[id] > m.max {
+		m.throttled[id] = true
+	}
+	return m.Throttled(signal, key)
+}
+
+func (m *stubAbuseMonitor) Throttled(signal domain.AbuseSignal, key string) time.Duration {
+	if m.throttled[string(signal)+"|"+key] {
+		return time.Minute
+	}
+	return 0
+}
+
+func (m *stubAbuseMonitor) Throttles() []domain.Throttle {
+	var throttles []domain.Throttle
+	for id := range m.throttled {
+		signal, key, _ := strings.Cut(id, "|")
+		throttles = append(throttles, domain.Throttle{Key: key, Signal: domain.AbuseSignal(signal), Count: m.counts[id]})
+	}
+	return throttles
+}
+
+func (m *stubAbuseMonitor) Release(key string) bool {
+	released := false
+	for id := range m.throttled {
+		if strings.HasSuffix(id, "|"+key) {
+			delete(m.throttled, id)
+			delete(m.counts, id)
+			released = true
+		}
+	}
+	return released
+}
+
+// withTestPrincipal signs every request in as user-1.
+func withTestPrincipal(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, createContextWithPrincipal(r, testHandlerPrincipal("user-1")))
+	})
+}
+
+type uploadRouteModule struct{}
+
+func (uploadRouteModule) RegisterRoutes(routes Routes) {
+	ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusCreated) }
+	routes.Protected.HandleFunc("/documents", ok).Methods(http.MethodPost)
+	routes.Protected.HandleFunc("/tags", ok).Methods(http.MethodPost)
+}
+
+func TestAbuseGuard_ThrottlesUploads(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+	monitor := newStubAbuseMonitor(2)
+	router := NewRouter(withTestPrincipal, nil, Guards{Abuse: monitor}, NewAdminHandler(nil, nil, nil, nil, monitor), uploadRouteModule{})
+
+	post := func(path, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		req.RemoteAddr = ip + ":4000"
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	for i := 0; i < 2; i++ {
+		if rr := post("/api/v1/documents", "10.0.0.1"); rr.Code != http.StatusCreated {
+			t.Fatalf("upload %d: expected 201, got %d", i+1, rr.Code)
+		}
+	}
+	rr := post("/api/v1/documents", "10.0.0.2")
+	if rr.Code != http.StatusTooManyRequests || rr.Header().Get("Retry-After") != "60" {
+		t.Fatalf("expected the user to be throttled with Retry-After, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+	if !strings.Contains(rr.Body.String(), `"code":"throttled"`) {
+		t.Fatalf("unexpected body: %s", rr.Body.String())
+	}
+	if rr := post("/api/v1/tags", "10.0.0.1"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected other writes to stay available, got %d", rr.Code)
+	}
+
+	admin := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("X-Admin-Secret", "s3cret")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+	rr = admin(http.MethodGet, "/api/v1/admin/throttles")
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"key":"user:user-1"`) {
+		t.Fatalf("list throttles: %d %s", rr.Code, rr.Body.String())
+	}
+	if rr := admin(http.MethodDelete, "/api/v1/admin/throttles/user:user-1"); rr.Code != http.StatusNoContent {
+		t.Fatalf("release: expected 204, got %d", rr.Code)
+	}
+	if rr := admin(http.MethodDelete, "/api/v1/admin/throttles/user:user-1"); rr.Code != http.StatusNotFound {
+		t.Fatalf("second release: expected 404, got %d", rr.Code)
+	}
+	if rr := post("/api/v1/documents", "10.0.0.3"); rr.Code != http.StatusCreated {
+		t.Fatalf("expected uploads to resume after release, got %d", rr.Code)
+	}
+}
+
+// Every route that stores what it is sent or fetches is throttled, checked against
+// the routes the handlers register.
+func TestAbuseGuard_ThrottlesEveryUploadRoute(t *testing.T) {
+	router := NewRouter(withTestPrincipal, nil, Guards{Abuse: newStubAbuseMonitor(0)},
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+	for _, path := range []string{
+		"/api/v1/documents",
+		"/api/v1/import/calibre",
+		"/api/v1/import/dropbox",
+		"/api/v1/import/pocket",
+		"/api/v1/import/instapaper",
+		"/api/v1/catalog/import/1342",
+		"/api/v1/preferences/fonts",
+		"/api/v1/import/highlights",
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, path, nil))
+		if rr.Code != http.StatusTooManyRequests {
+			t.Errorf("POST %s: expected 429, got %d", path, rr.Code)
+		}
+	}
+}
+
+func TestAbuseGuard_ThrottlesAuthFailures(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+	rejectTokens := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			writeError(w, http.StatusUnauthorized, "Invalid token")
+		})
+	}
+	monitor := newStubAbuseMonitor(1)
+	router := ClientAddress(1)(NewRouter(rejectTokens, nil, Guards{Abuse: monitor}, NewAdminHandler(nil, nil, nil, nil, monitor), uploadRouteModule{}))
+
+	send := func(method, path, ip, forwardedFor string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.RemoteAddr = ip + ":4000"
+		req.Header.Set("X-Forwarded-For", forwardedFor)
+		req.Header.Set("X-Admin-Secret", "wrong")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	get := func(ip, forwardedFor string) int {
+		return send(http.MethodGet, "/api/v1/admin/throttles", ip, forwardedFor)
+	}
+
+	// Rejected tokens are not credential checks, however many there are.
+	for i := 0; i < 3; i++ {
+		if code := send(http.MethodPost, "/api/v1/tags", "10.0.0.1", "203.0.113.7"); code != http.StatusUnauthorized {
+			t.Fatalf("expired token %d: expected 401, got %d", i+1, code)
+		}
+	}
+	if code := get("10.0.0.1", "203.0.113.7"); code != http.StatusUnauthorized {
+		t.Fatalf("first failure: expected 401, got %d", code)
+	}
+	if code := get("10.0.0.1", "203.0.113.7"); code != http.StatusUnauthorized {
+		t.Fatalf("second failure: expected 401, got %d", code)
+	}
+	// A forged first hop does not hide the address the proxy appended.
+	if code := get("10.0.0.1", "198.51.100.1, 203.0.113.7"); code != http.StatusTooManyRequests {
+		t.Fatalf("expected the IP to be throttled, got %d", code)
+	}
+	if code := send(http.MethodPost, "/api/v1/tags", "10.0.0.1", "203.0.113.7"); code != http.StatusUnauthorized {
+		t.Fatalf("expected the rest of the API to stay available, got %d", code)
+	}
+	if code := get("10.0.0.1", "198.51.100.1"); code != http.StatusUnauthorized {
+		t.Fatalf("expected other IPs to be unaffected, got %d", code)
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	tests := []struct {
+		name           string
+		trustedProxies int
+		forwardedFor   []string
+		want           string
+	}{
+		{"no proxies ignores the header", 0, []string{"203.0.113.7"}, "10.0.0.1"},
+		{"one proxy", 1, []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"two proxies", 2, []string{"198.51.100.1, 203.0.113.7", "10.1.0.1"}, "203.0.113.7"},
+		{"fewer hops than proxies", 3, []string{"203.0.113.7"}, "10.0.0.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got string
+			h := ClientAddress(tt.trustedProxies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			}))
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = "10.0.0.1:4000"
+			for _, header := range tt.forwardedFor {
+				req.Header.Add("X-Forwarded-For", header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), req)
+			if got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
@@ -3,25 +3,58 @@ package handler
 import (
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
+	"strings"
 	"sync"
 
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
+
 	"github.com/gorilla/mux"
+	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
 )
 
 // AdminHandler exposes admin-only endpoints protected by X-Admin-Secret.
 // These endpoints are intended for internal use (support tooling) and should not be exposed publicly without additional safeguards.
 type AdminHandler struct {
+	migrator   domain.SchemaMigrator // Nil without DATABASE_URL
+	pdfMetrics domain.PDFProcessingMetrics
+	storage    domain.BlobStore // Where originals are stored, for RecomputeUsage
+
 	clientOnce sync.Once
 	client     *supabase.Client
 	clientErr  error
+
+	maintenance domain.MaintenanceMode // Nil when not switchable
+	abuse       domain.AbuseMonitor    // Nil when nothing is throttled
+}
+
+// NewAdminHandler creates the admin handler. maintenance and abuse are the switch
+// and the monitor the router's Guards enforce; this handler only manages them.
+func NewAdminHandler(migrator domain.SchemaMigrator, pdfMetrics domain.PDFProcessingMetrics, storage domain.BlobStore, maintenance domain.MaintenanceMode, abuse domain.AbuseMonitor) *AdminHandler {
+	return &AdminHandler{migrator: migrator, pdfMetrics: pdfMetrics, storage: storage, maintenance: maintenance, abuse: abuse}
 }
 
-func NewAdminHandler() *AdminHandler {
-	return &AdminHandler{}
+// RegisterRoutes adds the admin endpoints. They are not behind the auth middleware;
+// every handler checks X-Admin-Secret itself.
+func (h *AdminHandler) RegisterRoutes(routes Routes) {
+	admin := routes.Public.PathPrefix("/admin").Subrouter()
+	admin.HandleFunc("/users/{id}/account-disabled", h.SetAccountDisabled).Methods(http.MethodPost)
+	admin.HandleFunc("/users/{id}/recompute-usage", h.RecomputeUsage).Methods(http.MethodPost)
+	admin.HandleFunc("/resilience", h.ResilienceStats).Methods(http.MethodGet)
+	admin.HandleFunc("/pdf-processing", h.PDFProcessingStats).Methods(http.MethodGet)
+	admin.HandleFunc("/migrations", h.ListMigrations).Methods(http.MethodGet)
+	admin.HandleFunc("/migrations", h.ApplyMigrations).Methods(http.MethodPost)
+	admin.HandleFunc("/quarantine", h.ListQuarantined).Methods(http.MethodGet)
+	admin.HandleFunc("/quarantine/{id}/release", h.ReleaseQuarantine).Methods(http.MethodPost)
+	admin.HandleFunc("/maintenance", h.GetMaintenance).Methods(http.MethodGet)
+	admin.HandleFunc("/maintenance", h.SetMaintenanceMode).Methods(http.MethodPut)
+	admin.HandleFunc("/throttles", h.ListThrottles).Methods(http.MethodGet)
+	admin.HandleFunc("/throttles/{key}", h.ReleaseThrottle).Methods(http.MethodDelete)
 }
 
 func (h *AdminHandler) serviceRoleClient() (*supabase.Client, error) {
@@ -39,6 +72,17 @@ func (h *AdminHandler) serviceRoleClient() (*supabase.Client, error) {
 	return h.client, h.clientErr
 }
 
+// authorizeAdmin checks X-Admin-Secret against env ADMIN_API_SECRET and writes 401 on mismatch.
+func authorizeAdmin(w http.ResponseWriter, r *http.Request) bool {
+	secret := r.Header.Get("X-Admin-Secret")
+	expected := os.Getenv("ADMIN_API_SECRET")
+	if expected == "" || secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
+		writeError(w, http.StatusUnauthorized, "Unauthorized")
+		return false
+	}
+	return true
+}
+
 type setAccountDisabledRequest struct {
 	AccountDisabled bool `json:"account_disabled"`
 }
@@ -48,10 +92,7 @@ type setAccountDisabledRequest struct {
 // Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
 // DB: uses env `SUPABASE_URL` + `SUPABASE_SERVICE_ROLE_KEY` to bypass RLS.
 func (h *AdminHandler) SetAccountDisabled(w http.ResponseWriter, r *http.Request) {
-	secret := r.Header.Get("X-Admin-Secret")
-	expected := os.Getenv("ADMIN_API_SECRET")
-	if expected == "" || secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(expected)) != 1 {
-		writeError(w, http.StatusUnauthorized, "Unauthorized")
+	if !authorizeAdmin(w, r) {
 		return
 	}
 
@@ -91,3 +132,470 @@ func (h *AdminHandler) SetAccountDisabled(w http.ResponseWriter, r *http.Request
 		"account_disabled": req.AccountDisabled,
 	})
 }
+
+// storageUsage is a user's storage usage against their quota.
+type storageUsage struct {
+	UsedBytes  int64 `json:"used_bytes"`
+	LimitBytes int64 `json:"limit_bytes"`
+	Documents  int   `json:"documents"`
+}
+
+// usageCorrection is a document whose recorded file size did not match its stored original.
+type usageCorrection struct {
+	DocumentID    string `json:"document_id"`
+	RecordedBytes int64  `json:"recorded_bytes"`
+	StoredBytes   int64  `json:"stored_bytes"`
+}
+
+// RecomputeUsage recalculates a user's storage usage from the originals in storage,
+// for quota disputes. Documents whose recorded file size differs from the stored
+// object are corrected; documents whose original is missing keep their recorded size
+// and are listed for follow-up. A storage limit below the one of the user's plan is
+// raised to it. The response compares usage before and after.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+// DB: uses env `SUPABASE_URL` + `SUPABASE_SERVICE_ROLE_KEY` to bypass RLS.
+func (h *AdminHandler) RecomputeUsage(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	if h.storage == nil {
+		writeError(w, http.StatusServiceUnavailable, "Storage is not available")
+		return
+	}
+
+	userID := mux.Vars(r)["id"]
+	if userID == "" {
+		writeError(w, http.StatusBadRequest, "User id is required")
+		return
+	}
+
+	client, err := h.serviceRoleClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Server misconfigured")
+		return
+	}
+
+	data, _, err := client.From("user_preferences").
+		Select("subscription_plan,storage_limit_bytes", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load preferences: %v", err))
+		return
+	}
+	var prefs []struct {
+		SubscriptionPlan  string `json:"subscription_plan"`
+		StorageLimitBytes int64  `json:"storage_limit_bytes"`
+	}
+	if err := json.Unmarshal(data, &prefs); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to decode preferences")
+		return
+	}
+	plan, limit := "free", int64(0)
+	if len(prefs) > 0 {
+		plan, limit = prefs[0].SubscriptionPlan, prefs[0].StorageLimitBytes
+	}
+
+	data, _, err = client.From("documents").
+		Select("id,metadata", "", false).
+		Eq("user_id", userID).
+		Execute()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load documents: %v", err))
+		return
+	}
+	var docs []struct {
+		ID       string          `json:"id"`
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &docs); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to decode documents")
+		return
+	}
+
+	before := storageUsage{LimitBytes: limit, Documents: len(docs)}
+	corrections := []usageCorrection{}
+	missing := []string{}
+	for _, doc := range docs {
+		metadata, err := decodeMetadataObject(doc.Metadata)
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, "Failed to decode document metadata")
+			return
+		}
+		recorded, _ := metadata["file_size"].(float64)
+		before.UsedBytes += int64(recorded)
+
+		// The service role key passes storage RLS; the S3 and local stores ignore it.
+		size, err := h.storage.Stat(r.Context(), originalPath(userID, doc.ID, metadata), os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
+		if errors.Is(err, domain.ErrBlobNotFound) {
+			missing = append(missing, doc.ID)
+			continue
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to check stored file of %s: %v", doc.ID, err))
+			return
+		}
+		if size == int64(recorded) {
+			continue
+		}
+		metadata["file_size"] = size
+		_, _, err = client.From("documents").
+			Update(map[string]interface{}{"metadata": metadata}, "", "").
+			Eq("id", doc.ID).
+			Execute()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update document %s: %v", doc.ID, err))
+			return
+		}
+		corrections = append(corrections, usageCorrection{DocumentID: doc.ID, RecordedBytes: int64(recorded), StoredBytes: size})
+	}
+
+	after := before
+	for _, c := range corrections {
+		after.UsedBytes += c.StoredBytes - c.RecordedBytes
+	}
+	if planLimit := domain.StorageLimitBytesForPlan(plan); limit < planLimit {
+		_, _, err = client.From("user_preferences").
+			Upsert(map[string]interface{}{"user_id": userID, "storage_limit_bytes": planLimit}, "", "", "").
+			Execute()
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to update storage limit: %v", err))
+			return
+		}
+		after.LimitBytes = planLimit
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"user_id":       userID,
+		"before":        before,
+		"after":         after,
+		"corrections":   corrections,
+		"missing_files": missing,
+	})
+}
+
+// originalPath is where uploads store a document's original: <user>/<id>.<format>.
+// Documents from before the format was recorded are PDFs.
+func originalPath(userID, documentID string, metadata map[string]interface{}) string {
+	format, _ := metadata["format"].(string)
+	if format == "" {
+		format = "pdf"
+	}
+	return userID + "/" + documentID + "." + format
+}
+
+// ResilienceStats returns retry and circuit breaker metrics for external dependencies.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) ResilienceStats(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"executors": resilience.AllStats(),
+	})
+}
+
+// PDFProcessingStats returns PDF extraction metrics: jobs processed, failed, rejected
+// by the size limits or timed out, and how many are running or waiting for a slot.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) PDFProcessingStats(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.pdfMetrics == nil {
+		writeError(w, http.StatusServiceUnavailable, "PDF processing metrics are not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"pdf_processing": h.pdfMetrics.PDFProcessingStats(),
+	})
+}
+
+// ListMigrations reports which embedded schema migrations have been applied.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+// DB: connects with env `DATABASE_URL`.
+func (h *AdminHandler) ListMigrations(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.migrator == nil {
+		writeError(w, http.StatusServiceUnavailable, "Database migrations require DATABASE_URL")
+		return
+	}
+
+	migrations, err := h.migrator.Status(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to read migrations: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"migrations": migrations,
+	})
+}
+
+// ApplyMigrations applies every pending schema migration and returns the ones applied.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+// DB: connects with env `DATABASE_URL`.
+func (h *AdminHandler) ApplyMigrations(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.migrator == nil {
+		writeError(w, http.StatusServiceUnavailable, "Database migrations require DATABASE_URL")
+		return
+	}
+
+	applied, err := h.migrator.Migrate(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to apply migrations: %v", err))
+		return
+	}
+	if applied == nil {
+		applied = []domain.MigrationStatus{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"applied": applied,
+	})
+}
+
+// GetMaintenance reports whether maintenance mode is on.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) GetMaintenance(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "Maintenance mode is not available")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(h.maintenance.Status())
+}
+
+type setMaintenanceRequest struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"`
+}
+
+// SetMaintenanceMode switches maintenance mode on or off. While it is on, write
+// requests get a 503 with the message and reads keep working, e.g. while the
+// documents schema is migrated.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) SetMaintenanceMode(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.maintenance == nil {
+		writeError(w, http.StatusServiceUnavailable, "Maintenance mode is not available")
+		return
+	}
+
+	var req setMaintenanceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(h.maintenance.Set(req.Enabled, strings.TrimSpace(req.Message)))
+}
+
+// ListThrottles returns the users and IPs currently throttled for too many uploads or
+// failed sign-ins, newest first.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) ListThrottles(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.abuse == nil {
+		writeError(w, http.StatusServiceUnavailable, "Abuse monitoring is not enabled")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"throttles": h.abuse.Throttles(),
+	})
+}
+
+// ReleaseThrottle lifts the throttles on a key ("user:<id>" or "ip:<address>") that
+// was blocked by mistake, and resets its counters.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+func (h *AdminHandler) ReleaseThrottle(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+	if h.abuse == nil {
+		writeError(w, http.StatusServiceUnavailable, "Abuse monitoring is not enabled")
+		return
+	}
+
+	if !h.abuse.Release(mux.Vars(r)["key"]) {
+		writeError(w, http.StatusNotFound, "No active throttle for this key")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// quarantinedDocument is the review view of a quarantined upload.
+type quarantinedDocument struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Title     string          `json:"title"`
+	Metadata  json.RawMessage `json:"metadata"`
+	CreatedAt string          `json:"created_at"`
+}
+
+// ListQuarantined returns uploads held for malware review, newest first.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+// DB: uses env `SUPABASE_URL` + `SUPABASE_SERVICE_ROLE_KEY` to bypass RLS.
+func (h *AdminHandler) ListQuarantined(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	client, err := h.serviceRoleClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Server misconfigured")
+		return
+	}
+
+	data, _, err := client.From("documents").
+		Select("id,user_id,title,metadata,created_at", "", false).
+		Not("metadata->quarantine", "is", "null").
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Execute()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to list quarantined documents: %v", err))
+		return
+	}
+
+	var docs []quarantinedDocument
+	if err := json.Unmarshal(data, &docs); err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to decode quarantined documents")
+		return
+	}
+	if docs == nil {
+		docs = []quarantinedDocument{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"documents": docs,
+	})
+}
+
+// ReleaseQuarantine clears the quarantine flag so the owner can open the document again.
+// Text extraction is skipped for quarantined uploads and is not re-run on release.
+//
+// Auth: requires `X-Admin-Secret` header matching env `ADMIN_API_SECRET`.
+// DB: uses env `SUPABASE_URL` + `SUPABASE_SERVICE_ROLE_KEY` to bypass RLS.
+func (h *AdminHandler) ReleaseQuarantine(w http.ResponseWriter, r *http.Request) {
+	if !authorizeAdmin(w, r) {
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		writeError(w, http.StatusBadRequest, "Document id is required")
+		return
+	}
+
+	client, err := h.serviceRoleClient()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Server misconfigured")
+		return
+	}
+
+	data, _, err := client.From("documents").
+		Select("metadata", "", false).
+		Eq("id", documentID).
+		Execute()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to load document: %v", err))
+		return
+	}
+
+	var rows []struct {
+		Metadata json.RawMessage `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil || len(rows) == 0 {
+		writeError(w, http.StatusNotFound, "Document not found")
+		return
+	}
+
+	metadata, err := decodeMetadataObject(rows[0].Metadata)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, "Failed to decode document metadata")
+		return
+	}
+	if _, ok := metadata["quarantine"]; !ok {
+		writeError(w, http.StatusConflict, "Document is not quarantined")
+		return
+	}
+	delete(metadata, "quarantine")
+
+	_, _, err = client.From("documents").
+		Update(map[string]interface{}{"metadata": metadata}, "", "").
+		Eq("id", documentID).
+		Execute()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to release document: %v", err))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]interface{}{
+		"document_id": documentID,
+		"released":    true,
+	})
+}
+
+// decodeMetadataObject decodes a metadata JSONB column, which PostgREST may return
+// inline or as a JSON-encoded string.
+func decodeMetadataObject(raw json.RawMessage) (map[string]interface{}, error) {
+	var encoded string
+	if err := json.Unmarshal(raw, &encoded); err == nil {
+		raw = json.RawMessage(encoded)
+	}
+
+	metadata := map[string]interface{}{}
+	if len(raw) == 0 || string(raw) == "null" {
+		return metadata, nil
+	}
+	if err := json.Unmarshal(raw, &metadata); err != nil {
+		return nil, err
+	}
+	return metadata, nil
+}
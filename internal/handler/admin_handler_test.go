@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/blobstore"
+)
+
+// fakeSupabase serves the PostgREST calls of RecomputeUsage.
+type fakeSupabase struct {
+	mu      sync.Mutex
+	updates map[string]string // Request path and query -> body
+}
+
+func (f *fakeSupabase) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/v1/user_preferences":
+		_, _ = w.Write([]byte(`[{"subscription_plan":"pro","storage_limit_bytes":0}]`))
+	case r.Method == http.MethodGet && r.URL.Path == "/rest/v1/documents":
+		_, _ = w.Write([]byte(`[
+			{"id":"doc-1","metadata":{"format":"pdf","file_size":100}},
+			{"id":"doc-2","metadata":"{\"format\":\"epub\",\"file_size\":50}"},
+			{"id":"doc-3","metadata":{"format":"txt","file_size":7}}
+		]`))
+	default:
+		f.mu.Lock()
+		f.updates[r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery] = string(body)
+		f.mu.Unlock()
+		_, _ = w.Write([]byte(`[]`))
+	}
+}
+
+func TestAdminHandler_RecomputeUsage(t *testing.T) {
+	supabase := &fakeSupabase{updates: make(map[string]string)}
+	server := httptest.NewServer(supabase)
+	defer server.Close()
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+	t.Setenv("SUPABASE_URL", server.URL)
+	t.Setenv("SUPABASE_SERVICE_ROLE_KEY", "service-role")
+
+	// Originals are read through the blob store, whichever backend it is.
+	storage, err := blobstore.NewLocal(t.TempDir(), "http://localhost", "signing-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for path, size := range map[string]int{"user-1/doc-1.pdf": 100, "user-1/doc-2.epub": 80, "user-1/doc-2/cover.jpg": 9} {
+		if err := storage.Upload(context.Background(), path, strings.NewReader(strings.Repeat("x", size)), "", ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	router := NewRouter(func(next http.Handler) http.Handler { return next }, nil, Guards{}, NewAdminHandler(nil, nil, storage, nil, nil))
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/recompute-usage", nil)
+	req.Header.Set("X-Admin-Secret", "s3cret")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	var report struct {
+		Before       storageUsage      `json:"before"`
+		After        storageUsage      `json:"after"`
+		Corrections  []usageCorrection `json:"corrections"`
+		MissingFiles []string          `json:"missing_files"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	proLimit := domain.StorageLimitBytesForPlan("pro")
+	if report.Before != (storageUsage{UsedBytes: 157, LimitBytes: 0, Documents: 3}) {
+		t.Errorf("before = %+v", report.Before)
+	}
+	if report.After != (storageUsage{UsedBytes: 187, LimitBytes: proLimit, Documents: 3}) {
+		t.Errorf("after = %+v", report.After)
+	}
+	if len(report.Corrections) != 1 || report.Corrections[0] != (usageCorrection{DocumentID: "doc-2", RecordedBytes: 50, StoredBytes: 80}) {
+		t.Errorf("corrections = %+v", report.Corrections)
+	}
+	if len(report.MissingFiles) != 1 || report.MissingFiles[0] != "doc-3" {
+		t.Errorf("missing files = %v", report.MissingFiles)
+	}
+
+	update, ok := supabase.updates["PATCH /rest/v1/documents?id=eq.doc-2"]
+	if !ok || !strings.Contains(update, `"file_size":80`) || !strings.Contains(update, `"format":"epub"`) {
+		t.Errorf("expected doc-2's metadata to be rewritten with the stored size, got %v", supabase.updates)
+	}
+	if update := supabase.updates["POST /rest/v1/user_preferences?"]; !strings.Contains(update, `"storage_limit_bytes"`) {
+		t.Errorf("expected the storage limit to be raised to the plan's, got %v", supabase.updates)
+	}
+	if len(supabase.updates) != 2 {
+		t.Errorf("unexpected writes: %v", supabase.updates)
+	}
+}
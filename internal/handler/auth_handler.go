@@ -2,9 +2,11 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 
 	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
 )
 
 // AuthHandler handles authentication-related requests
@@ -19,6 +21,20 @@ func NewAuthHandler(container *config.Container) *AuthHandler {
 	}
 }
 
+// RegisterRoutes adds the auth endpoints.
+func (h *AuthHandler) RegisterRoutes(routes Routes) {
+	// Local sign-up/sign-in (public; only enabled when self-hosted)
+	routes.Public.HandleFunc("/auth/register", h.Register).Methods(http.MethodPost)
+	routes.Public.HandleFunc("/auth/login", h.Login).Methods(http.MethodPost)
+
+	r := routes.Protected
+	r.HandleFunc("/auth/profile", h.GetProfile).Methods(http.MethodGet)
+	r.HandleFunc("/auth/profile", h.UpdateProfile).Methods(http.MethodPut)
+	r.HandleFunc("/auth/validate", h.ValidateToken).Methods(http.MethodGet)
+	r.HandleFunc("/auth/onboarding", h.Onboard).Methods(http.MethodPost)
+	r.HandleFunc("/auth/account-deletion-request", h.RequestAccountDeletion).Methods(http.MethodPost)
+}
+
 // GetProfile returns the current user's profile information
 func (h *AuthHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 	user, ok := GetUserFromContext(r)
@@ -74,36 +90,123 @@ func (h *AuthHandler) ValidateToken(w http.ResponseWriter, r *http.Request) {
 // RequestAccountDeletion marks the account as disabled (persisted) so all devices are blocked.
 // The client is expected to also notify support via email (or future automation).
 func (h *AuthHandler) RequestAccountDeletion(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	if err := h.container.UserPreferencesService.DisableAccount(r.Context(), principal); err != nil {
+		writeServerError(w, err, "Failed to disable account")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(map[string]string{"message": "Account disabled"})
+}
+
+// Onboard adds the starter content to the caller's account if it was queued at
+// sign-up. Clients that sign up with Supabase Auth call it once after signing up;
+// it answers 204 whether or not there was anything to add.
+func (h *AuthHandler) Onboard(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		writeError(w, http.StatusUnauthorized, "Token not found in context")
+		writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	client, err := h.container.SupabaseClient.GetClientWithToken(token)
-	if err != nil || client == nil {
-		writeError(w, http.StatusInternalServerError, "Failed to initialize database client")
+	if h.container.OnboardingService != nil {
+		if err := h.container.OnboardingService.OnboardUser(r.Context(), principal); err != nil {
+			h.container.Logger.Error("Failed to onboard user", err, "user_id", principal.UserID)
+			writeServerError(w, err, "Failed to onboard account")
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type registerRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+}
+
+type loginRequest struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// Register creates an account with the local auth backend (self-hosted mode only)
+// and returns its session.
+func (h *AuthHandler) Register(w http.ResponseWriter, r *http.Request) {
+	if h.container.LocalAuthService == nil {
+		writeError(w, http.StatusNotFound, "Local authentication is disabled")
 		return
 	}
 
-	// Upsert preferences row with account_disabled=true (do not touch other fields).
-	data := map[string]interface{}{
-		"user_id":          user.ID,
-		"account_disabled": true,
+	var req registerRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
 	}
-	_, _, err = client.From("user_preferences").Upsert(data, "", "", "").Execute()
+
+	session, err := h.container.LocalAuthService.Register(r.Context(), req.Email, req.Password, req.Name)
 	if err != nil {
-		writeError(w, http.StatusInternalServerError, "Failed to disable account")
+		var validationErrs domain.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusBadRequest)
+			_ = json.NewEncoder(w).Encode(map[string]any{"error": "Invalid registration", "fields": validationErrs})
+		case errors.Is(err, domain.ErrEmailTaken):
+			writeError(w, http.StatusConflict, "Email already registered")
+		default:
+			h.container.Logger.Error("Failed to register user", err)
+			writeServerError(w, err, "Failed to register")
+		}
+		return
+	}
+
+	// Onboarding never fails the sign-up; the account simply starts without the
+	// starter content.
+	if h.container.OnboardingService != nil {
+		principal := domain.NewPrincipal(session.User, session.AccessToken)
+		if err := h.container.OnboardingService.OnboardUser(r.Context(), principal); err != nil {
+			h.container.Logger.Error("Failed to onboard user", err, "user_id", principal.UserID)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusCreated)
+	_ = json.NewEncoder(w).Encode(session)
+}
+
+// Login signs in with the local auth backend (self-hosted mode only).
+func (h *AuthHandler) Login(w http.ResponseWriter, r *http.Request) {
+	if h.container.LocalAuthService == nil {
+		writeError(w, http.StatusNotFound, "Local authentication is disabled")
+		return
+	}
+
+	var req loginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	session, err := h.container.LocalAuthService.Login(r.Context(), req.Email, req.Password)
+	if err != nil {
+		if errors.Is(err, domain.ErrInvalidCredentials) {
+			writeError(w, http.StatusUnauthorized, "Invalid email or password")
+			return
+		}
+		h.container.Logger.Error("Failed to sign in", err)
+		writeServerError(w, err, "Failed to sign in")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(map[string]string{"message": "Account disabled"})
+	_ = json.NewEncoder(w).Encode(session)
 }
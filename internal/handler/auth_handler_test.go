@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -98,3 +100,44 @@ func TestAuthHandler_ValidateToken_OK(t *testing.T) {
 		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
 	}
 }
+
+type mockOnboardingService struct {
+	principals []domain.Principal
+	err        error
+}
+
+func (m *mockOnboardingService) OnboardUser(ctx context.Context, principal domain.Principal) error {
+	m.principals = append(m.principals, principal)
+	return m.err
+}
+
+func TestAuthHandler_Onboard(t *testing.T) {
+	onboarding := &mockOnboardingService{}
+	handler := NewAuthHandler(&config.Container{OnboardingService: onboarding, Logger: NewMockHandlerLogger()})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/onboarding", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+	rr := httptest.NewRecorder()
+	handler.Onboard(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if len(onboarding.principals) != 1 || onboarding.principals[0].UserID != "user-1" || onboarding.principals[0].Token != "test-token" {
+		t.Fatalf("expected the caller onboarded, got %+v", onboarding.principals)
+	}
+
+	onboarding.err = errors.New("storage unavailable")
+	rr = httptest.NewRecorder()
+	handler.Onboard(rr, req)
+	if rr.Code != http.StatusInternalServerError {
+		t.Fatalf("expected status %d, got %d", http.StatusInternalServerError, rr.Code)
+	}
+
+	// Without starter content there is nothing to do.
+	rr = httptest.NewRecorder()
+	NewAuthHandler(&config.Container{}).Onboard(rr, req)
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+}
@@ -3,30 +3,146 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"pdf-text-reader/internal/domain"
 
 	"github.com/gorilla/mux"
 )
 
+// multipartOverheadBytes is allowed on top of the file limit for multipart boundaries
+// and form fields.
+const multipartOverheadBytes = 1 << 20
+
+// uploadFormMemoryBytes is how much of a multipart upload is parsed into memory; the
+// rest of the file goes to a temp file, matching the service's own spooling.
+const uploadFormMemoryBytes = 4 << 20
+
+// uploadIDHeader names the progress session of a direct upload.
+const uploadIDHeader = "X-Upload-ID"
+
 // DocumentHandler handles document-related HTTP requests
 type DocumentHandler struct {
-	documentService   domain.DocumentService
-	preferenceService domain.UserPreferencesService
-	logger            domain.Logger
+	documentService    domain.DocumentService
+	preferenceService  domain.UserPreferencesService
+	savedSearchService domain.SavedSearchService
+	logger             domain.Logger
 }
 
 // NewDocumentHandler creates a new document handler
-func NewDocumentHandler(documentService domain.DocumentService, preferenceService domain.UserPreferencesService, logger domain.Logger) *DocumentHandler {
+func NewDocumentHandler(documentService domain.DocumentService, preferenceService domain.UserPreferencesService, savedSearchService domain.SavedSearchService, logger domain.Logger) *DocumentHandler {
 	return &DocumentHandler{
-		documentService:   documentService,
-		preferenceService: preferenceService,
-		logger:            logger,
+		documentService:    documentService,
+		preferenceService:  preferenceService,
+		savedSearchService: savedSearchService,
+		logger:             logger,
 	}
 }
 
+// RegisterRoutes adds the document endpoints.
+func (h *DocumentHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	// Gets all the card information
+	r.HandleFunc("/documents/library", h.GetLibrary).Methods(http.MethodGet)
+
+	// Get all the doc information
+	r.HandleFunc("/documents", h.UploadDocument).Methods(http.MethodPost)
+
+	// Progress of an upload sent with an X-Upload-ID header
+	r.HandleFunc("/uploads/{id}/progress", h.GetUploadProgress).Methods(http.MethodGet)
+
+	// Compare two docs, sent in the body or, for GET, in the query
+	r.HandleFunc("/documents/compare", h.CompareDocuments).Methods(http.MethodGet, http.MethodPost)
+
+	// Search docs
+	r.HandleFunc("/documents/search", h.SearchDocuments).Methods(http.MethodGet)
+
+	// Find text in a doc, paginated like the v2 listings
+	r.HandleFunc("/documents/{id}/find", h.FindInDocument).Methods(http.MethodGet)
+
+	// Get doc data by ID. mux takes the first route that matches, so the literal
+	// /documents/<word> routes above must stay ahead of this one.
+	r.HandleFunc("/documents/{id}", h.GetDocument).Methods(http.MethodGet)
+
+	// Table of contents for a doc
+	r.HandleFunc("/documents/{id}/outline", h.GetOutline).Methods(http.MethodGet)
+
+	// Parsed references of a scientific paper
+	r.HandleFunc("/documents/{id}/references", h.GetReferences).Methods(http.MethodGet)
+
+	// Signed image URL for a comic page
+	r.HandleFunc("/documents/{id}/pages/{page}", h.GetPageImage).Methods(http.MethodGet)
+
+	// Saved versions of a doc, and restoring one
+	r.HandleFunc("/documents/{id}/versions", h.ListVersions).Methods(http.MethodGet)
+	r.HandleFunc("/documents/{id}/versions/{version}/restore", h.RestoreVersion).Methods(http.MethodPost)
+
+	// Update doc by ID
+	r.HandleFunc("/documents/{id}", h.UpdateDocument).Methods(http.MethodPut)
+
+	// Favorite/unfavorite doc
+	r.HandleFunc("/documents/{id}/favorite", h.SetFavorite).Methods(http.MethodPut)
+
+	// Archive/unarchive doc (kept intact, hidden from the default listings)
+	r.HandleFunc("/documents/{id}/archive", h.ArchiveDocument).Methods(http.MethodPost)
+	r.HandleFunc("/documents/{id}/unarchive", h.UnarchiveDocument).Methods(http.MethodPost)
+
+	// Convert between character offset, progress and page in a doc's text
+	r.HandleFunc("/documents/{id}/anchor", h.ResolveAnchor).Methods(http.MethodGet)
+
+	// Regenerate a clean EPUB/PDF/TXT file from a doc's extracted text
+	r.HandleFunc("/documents/{id}/export", h.ExportDocument).Methods(http.MethodPost)
+
+	// Download a doc's raw extracted text
+	r.HandleFunc("/documents/{id}/plaintext", h.GetPlainText).Methods(http.MethodGet)
+
+	// Check a doc's content against its files; re-extract it from the original
+	r.HandleFunc("/documents/{id}/verify", h.VerifyDocument).Methods(http.MethodGet)
+	r.HandleFunc("/documents/{id}/reprocess", h.ReprocessDocument).Methods(http.MethodPost)
+
+	// Delete doc by ID
+	r.HandleFunc("/documents/{id}", h.DeleteDocument).Methods(http.MethodDelete)
+
+	// Saved searches, listed as smart collections with ?collection=smart:{id}
+	r.HandleFunc("/saved-searches", h.ListSavedSearches).Methods(http.MethodGet)
+	r.HandleFunc("/saved-searches", h.CreateSavedSearch).Methods(http.MethodPost)
+	r.HandleFunc("/saved-searches/{id}", h.GetSavedSearch).Methods(http.MethodGet)
+	r.HandleFunc("/saved-searches/{id}", h.UpdateSavedSearch).Methods(http.MethodPut)
+	r.HandleFunc("/saved-searches/{id}", h.DeleteSavedSearch).Methods(http.MethodDelete)
+
+	// Get all the docs by user ID
+	r.HandleFunc("/documents/user/{id}", h.GetDocumentsByUserID).Methods(http.MethodGet)
+
+	// Storage usage/limit (authenticated user)
+	r.HandleFunc("/storage/usage", h.GetStorageUsage).Methods(http.MethodGet)
+
+	// Get all document tags for the authenticated user
+	r.HandleFunc("/document-tags", h.GetDocumentTags).Methods(http.MethodGet)
+
+	// Create a new document tag for the authenticated user
+	r.HandleFunc("/document-tags", h.CreateTag).Methods(http.MethodPost)
+
+	// Delete a document tag for the authenticated user
+	r.HandleFunc("/document-tags/{name}", h.DeleteTag).Methods(http.MethodDelete)
+
+	// Stream a doc's original file, with Range support, instead of a storage URL
+	r.HandleFunc("/files/{documentId}", h.ServeFile).Methods(http.MethodGet, http.MethodHead)
+
+	// Paginated listings in the v2 envelope
+	v2 := routes.ProtectedV2
+	v2.HandleFunc("/documents", h.ListDocumentsV2).Methods(http.MethodGet)
+	v2.HandleFunc("/document-tags", h.ListDocumentTagsV2).Methods(http.MethodGet)
+	v2.HandleFunc("/documents/{id}/find", h.FindInDocument).Methods(http.MethodGet)
+}
+
 // Get Documents by User ID
 
 func (h *DocumentHandler) GetDocumentsByUserID(w http.ResponseWriter, r *http.Request) {
@@ -39,19 +155,60 @@ func (h *DocumentHandler) GetDocumentsByUserID(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
 		return
 	}
 
+	// The caller's token can only read the caller's own library.
+	if userID != principal.UserID {
+		h.writeError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+
+	filter, err := domain.ParseLibraryQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	documents, err := h.libraryDocuments(r, principal, filter)
+	if errors.Is(err, domain.ErrSavedSearchNotFound) {
+		h.writeError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if err != nil {
+		writeServerError(w, err, err.Error())
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, documents)
+}
+
+// listDocuments returns the documents filter selects from the caller's library, or
+// from the smart collection it names, evaluated afresh.
+func (h *DocumentHandler) listDocuments(r *http.Request, principal domain.Principal, filter domain.LibraryQuery) ([]*domain.DocumentData, error) {
+	if filter.Collection != "" {
+		return h.savedSearchService.CollectionDocuments(r.Context(), principal, filter.Collection, r.URL.Query())
+	}
+	documents, err := h.documentService.GetDocumentsByUserID(r.Context(), principal)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(documents), nil
+}
+
+// libraryDocuments returns the documents of the caller's library that filter
+// selects, each with its reading position attached inline.
+func (h *DocumentHandler) libraryDocuments(r *http.Request, principal domain.Principal, filter domain.LibraryQuery) ([]*domain.DocumentData, error) {
 	// Fetch documents and reading positions in parallel.
 	documentsChan := make(chan []*domain.DocumentData, 1)
 	positionsChan := make(chan map[string]*domain.ReadingPosition, 1)
 	errChan := make(chan error, 2)
 
 	go func() {
-		docs, err := h.documentService.GetDocumentsByUserID(userID, token)
+		docs, err := h.listDocuments(r, principal, filter)
 		if err != nil {
 			errChan <- err
 			return
@@ -60,7 +217,7 @@ func (h *DocumentHandler) GetDocumentsByUserID(w http.ResponseWriter, r *http.Re
 	}()
 
 	go func() {
-		positions, err := h.preferenceService.GetAllReadingPositions(userID, token)
+		positions, err := h.preferenceService.GetAllReadingPositions(r.Context(), principal)
 		if err != nil {
 			// Non-blocking: return empty map if positions fail.
 			positionsChan <- make(map[string]*domain.ReadingPosition)
@@ -91,8 +248,7 @@ func (h *DocumentHandler) GetDocumentsByUserID(w http.ResponseWriter, r *http.Re
 	}
 
 	if firstErr != nil {
-		h.writeError(w, http.StatusInternalServerError, firstErr.Error())
-		return
+		return nil, firstErr
 	}
 
 	// Ensure JSON is [] not null when there are no documents.
@@ -112,21 +268,50 @@ func (h *DocumentHandler) GetDocumentsByUserID(w http.ResponseWriter, r *http.Re
 		}
 	}
 
-	h.writeJSON(w, http.StatusOK, documents)
+	return documents, nil
+}
+
+// ListDocumentsV2 handles GET /api/v2/documents: a page of the caller's library with
+// the filters and order of GET /documents/user/{id}.
+func (h *DocumentHandler) ListDocumentsV2(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	filter, filterErr := domain.ParseLibraryQuery(r.URL.Query())
+	page, pageErr := parsePagination(r.URL.Query())
+	if err := queryErrors(filterErr, pageErr); err != nil {
+		writeValidationErrors(w, "Invalid query", err)
+		return
+	}
+
+	documents, err := h.libraryDocuments(r, principal, filter)
+	if errors.Is(err, domain.ErrSavedSearchNotFound) {
+		h.writeError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to list documents", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to list documents")
+		return
+	}
+	writeList(w, r, documents, page)
 }
 
 // GetLibrary handles getting the complete library data (documents + positions)
 // DEPRECATED: Use getDocumentsByUserID instead
 func (h *DocumentHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	filter, err := domain.ParseLibraryQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
@@ -136,7 +321,7 @@ func (h *DocumentHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
 	errChan := make(chan error, 2)
 
 	go func() {
-		docs, err := h.documentService.GetDocumentsByUserID(user.ID, token)
+		docs, err := h.listDocuments(r, principal, filter)
 		if err != nil {
 			errChan <- err
 			return
@@ -145,7 +330,7 @@ func (h *DocumentHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
 	}()
 
 	go func() {
-		positions, err := h.preferenceService.GetAllReadingPositions(user.ID, token)
+		positions, err := h.preferenceService.GetAllReadingPositions(r.Context(), principal)
 		if err != nil {
 			// If positions fail, return empty map (not critical)
 			positionsChan <- make(map[string]*domain.ReadingPosition)
@@ -176,9 +361,13 @@ func (h *DocumentHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
+	if errors.Is(firstErr, domain.ErrSavedSearchNotFound) {
+		h.writeError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
 	if firstErr != nil {
-		h.logger.Error("Failed to load library data", firstErr, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to load library data")
+		h.logger.Error("Failed to load library data", firstErr, "user_id", principal.UserID)
+		writeServerError(w, firstErr, "Failed to load library data")
 		return
 	}
 
@@ -201,75 +390,178 @@ func (h *DocumentHandler) GetLibrary(w http.ResponseWriter, r *http.Request) {
 	h.writeJSON(w, http.StatusOK, response)
 }
 
-// UploadDocument handles document upload
+// UploadDocument handles document upload. A client that sends an X-Upload-ID
+// header can follow the upload at GET /uploads/{id}/progress while it runs.
 func (h *DocumentHandler) UploadDocument(w http.ResponseWriter, r *http.Request) {
 
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "Unauthorized")
 		return
 	}
 
+	var (
+		doc       *domain.DocumentData
+		uploadErr error
+	)
+	uploadID := r.Header.Get(uploadIDHeader)
+	if uploadID != "" {
+		if err := h.documentService.StartUpload(principal, uploadID, r.ContentLength); err != nil {
+			var validationErrs domain.ValidationErrors
+			switch {
+			case errors.As(err, &validationErrs):
+				h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid upload ID", "fields": validationErrs})
+			case errors.Is(err, domain.ErrUploadInProgress):
+				h.writeError(w, http.StatusConflict, err.Error())
+			case errors.Is(err, domain.ErrTooManyUploads):
+				h.writeError(w, http.StatusTooManyRequests, err.Error())
+			default:
+				h.writeServiceError(w, err)
+			}
+			return
+		}
+		r.Body = &progressReader{ReadCloser: r.Body, received: func(n int) {
+			h.documentService.UploadReceived(principal, uploadID, int64(n))
+		}}
+		defer func() {
+			if doc != nil {
+				h.documentService.FinishUpload(principal, uploadID, doc.ID, nil)
+			} else {
+				h.documentService.FinishUpload(principal, uploadID, "", uploadErr)
+			}
+		}()
+	}
+
+	// Cap the request body at the plan-wide limit before parsing the form so oversized
+	// uploads are rejected without being buffered.
+	limit := h.documentService.UploadLimit(r.Context(), principal, "")
+	r.Body = http.MaxBytesReader(w, r.Body, limit.MaxBytes+multipartOverheadBytes)
+
 	// Validate file is present
-	file, header, err := r.FormFile("file")
+	err := r.ParseMultipartForm(uploadFormMemoryBytes)
+	var (
+		file   multipart.File
+		header *multipart.FileHeader
+	)
+	if err == nil {
+		file, header, err = r.FormFile("file")
+	}
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			uploadErr = domain.ErrFileTooLarge
+			h.writeFileTooLarge(w, limit)
+			return
+		}
+		uploadErr = domain.ErrInvalidFile
 		h.writeError(w, 400, "File is required")
 		return
 	}
 	defer file.Close()
 
-	// Validate file size
-	if header.Size > 15<<20 { // 15MB single file limit
-		h.writeError(w, http.StatusBadRequest, "File too large. Maximum single file size is 15MB.")
-		return
+	// Validate file size against the format-specific limit
+	if format := domain.UploadFormat(header.Filename); format != "" {
+		limit = h.documentService.UploadLimit(r.Context(), principal, format)
 	}
-
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	if header.Size > limit.MaxBytes {
+		uploadErr = domain.ErrFileTooLarge
+		h.writeFileTooLarge(w, limit)
 		return
 	}
 
-	doc, err := h.documentService.Upload(
+	if uploadID != "" {
+		h.documentService.UploadProcessing(principal, uploadID)
+	}
+	doc, err = h.documentService.Upload(
 		r.Context(),
-		user.ID,
+		principal,
 		file,
-		token,
 		header.Filename,
 	)
 	if err != nil {
+		uploadErr = err
 		// If the error message mentions storage limit, return 400 with friendly text
 		if strings.Contains(err.Error(), "storage limit exceeded") {
 			h.writeError(w, http.StatusBadRequest, "Storage limit reached. Please delete some documents or contact support to increase your storage.")
 			return
 		}
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+		if errors.Is(err, domain.ErrFileTooLarge) {
+			h.writeFileTooLarge(w, limit)
+			return
+		}
+		var pdfErr *domain.PDFValidationError
+		if errors.As(err, &pdfErr) {
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{
+				"error": pdfErr.Message,
+				"code":  pdfErr.Code,
+			})
+			return
+		}
+		if errors.Is(err, domain.ErrUnsupportedFileType) || errors.Is(err, domain.ErrInvalidFile) {
+			h.writeError(w, http.StatusUnsupportedMediaType, err.Error())
+			return
+		}
+		writeServerError(w, err, err.Error())
 		return
 	}
 
 	// Clean the document content before returning to avoid JSON serialization errors
 	// The document is already saved in the database, we just need to return a safe version
 	cleanDoc := h.cleanDocumentForResponse(doc)
+	if doc.IsQuarantined() {
+		// Stored, but held for malware review until an admin releases it.
+		h.writeJSON(w, http.StatusAccepted, cleanDoc)
+		return
+	}
 	h.writeJSON(w, 201, cleanDoc)
 }
 
-// GetStorageUsage returns current storage usage and limit for authenticated user.
-func (h *DocumentHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// GetUploadProgress reports how much of a direct upload has been received and
+// whether it has been processed yet.
+func (h *DocumentHandler) GetUploadProgress(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	progress, err := h.documentService.GetUploadProgress(r.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		if errors.Is(err, domain.ErrUploadNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, progress)
+}
+
+// progressReader reports every chunk of a request body as it is read.
+type progressReader struct {
+	io.ReadCloser
+	received func(n int)
+}
+
+func (p *progressReader) Read(b []byte) (int, error) {
+	n, err := p.ReadCloser.Read(b)
+	if n > 0 {
+		p.received(n)
+	}
+	return n, err
+}
+
+// GetStorageUsage returns current storage usage and limit for authenticated user.
+func (h *DocumentHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	prefs, err := h.preferenceService.GetPreferences(user.ID, token)
+	prefs, err := h.preferenceService.GetPreferences(r.Context(), principal)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve preferences")
+		writeServerError(w, err, "Failed to retrieve preferences")
 		return
 	}
 
@@ -278,9 +570,9 @@ func (h *DocumentHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request
 		limit = domain.StorageLimitBytesForPlan(prefs.SubscriptionPlan)
 	}
 
-	docs, err := h.documentService.GetDocumentsByUserID(user.ID, token)
+	docs, err := h.documentService.GetDocumentsByUserID(r.Context(), principal)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve documents")
+		writeServerError(w, err, "Failed to retrieve documents")
 		return
 	}
 
@@ -316,9 +608,10 @@ func (h *DocumentHandler) GetStorageUsage(w http.ResponseWriter, r *http.Request
 	})
 }
 
-// GetDocument handles getting a specific document
+// GetDocument handles getting a specific document. This is how readers open a
+// document, so it is recorded as the document's last_opened_at.
 func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
@@ -332,42 +625,21 @@ func (h *DocumentHandler) GetDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
-		return
-	}
-
-	document, err := h.documentService.GetDocument(documentID, token)
+	document, err := h.documentService.OpenDocument(r.Context(), principal, documentID)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, err.Error())
-		return
-	}
-
-	// Verify the document belongs to the user
-	if document.UserID != user.ID {
-		h.writeError(w, http.StatusForbidden, "Access denied")
+		h.writeServiceError(w, err)
 		return
 	}
 
 	// Clean the document content before returning
 	cleanDoc := h.cleanDocumentForResponse(document)
+	setETag(w, document.UpdatedAt)
 	h.writeJSON(w, http.StatusOK, cleanDoc)
 }
 
-type updateDocumentRequest struct {
-	Title  *string `json:"title"`
-	Author *string `json:"author"`
-	Tag    *string `json:"tag"` // Single tag (document can only have one tag)
-}
-
-type setFavoriteRequest struct {
-	IsFavorite bool `json:"is_favorite"`
-}
-
-// SetFavorite marks/unmarks a document as favorite for the authenticated user.
-func (h *DocumentHandler) SetFavorite(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// GetOutline returns the table of contents for a document
+func (h *DocumentHandler) GetOutline(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
@@ -380,225 +652,934 @@ func (h *DocumentHandler) SetFavorite(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
-		return
-	}
-
-	var req setFavoriteRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-
-	if err := h.documentService.SetFavorite(user.ID, documentID, req.IsFavorite, token); err != nil {
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+	outline, err := h.documentService.GetOutline(r.Context(), principal, documentID)
+	if err != nil {
+		h.writeServiceError(w, err)
 		return
 	}
 
 	h.writeJSON(w, http.StatusOK, map[string]any{
 		"document_id": documentID,
-		"is_favorite": req.IsFavorite,
-		"updated":     true,
+		"outline":     outline,
 	})
 }
 
-// UpdateDocument updates title/author/tag for a document
-func (h *DocumentHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "User not found in context")
-		return
-	}
+type compareDocumentsRequest struct {
+	LeftDocumentID  string `json:"left_document_id" validate:"required"`
+	RightDocumentID string `json:"right_document_id" validate:"required"`
+}
 
-	vars := mux.Vars(r)
-	documentID := vars["id"]
-	if documentID == "" {
-		h.writeError(w, http.StatusBadRequest, "Document ID is required")
-		return
+func (req *compareDocumentsRequest) Validate() domain.ValidationErrors {
+	if req.LeftDocumentID == req.RightDocumentID {
+		return domain.ValidationErrors{{Field: "right_document_id", Message: "cannot compare a document with itself"}}
 	}
+	return nil
+}
 
-	token, ok := GetTokenFromContext(r)
+// CompareDocuments returns a page-aligned diff of two documents. GET takes the IDs
+// as left_document_id and right_document_id query parameters.
+func (h *DocumentHandler) CompareDocuments(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
-		return
-	}
-
-	var req updateDocumentRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	if req.Title == nil && req.Author == nil && req.Tag == nil {
-		h.writeError(w, http.StatusBadRequest, "No updates provided")
+	var req compareDocumentsRequest
+	if r.Method == http.MethodGet {
+		req.LeftDocumentID = r.URL.Query().Get("left_document_id")
+		req.RightDocumentID = r.URL.Query().Get("right_document_id")
+		errs := validateRequest(&req)
+		if len(errs) == 0 {
+			errs = req.Validate()
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, "Invalid comparison", errs)
+			return
+		}
+	} else if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid comparison", err)
 		return
 	}
 
-	updated, err := h.documentService.UpdateDocumentDetails(user.ID, documentID, req.Title, req.Author, req.Tag, token)
+	comparison, err := h.documentService.CompareDocuments(r.Context(), principal, req.LeftDocumentID, req.RightDocumentID)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+		h.writeServiceError(w, err)
 		return
 	}
 
-	cleanDoc := h.cleanDocumentForResponse(updated)
-	h.writeJSON(w, http.StatusOK, cleanDoc)
+	h.writeJSON(w, http.StatusOK, comparison)
 }
 
-// DeleteDocument handles document deletion
-func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+// GetReferences returns the parsed bibliography of a scientific paper
+func (h *DocumentHandler) GetReferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
 	vars := mux.Vars(r)
 	documentID := vars["id"]
-
 	if documentID == "" {
 		h.writeError(w, http.StatusBadRequest, "Document ID is required")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
-		return
-	}
-
-	err := h.documentService.DeleteDocument(documentID, token)
+	references, err := h.documentService.GetReferences(r.Context(), principal, documentID)
 	if err != nil {
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+		h.writeServiceError(w, err)
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, "Document deleted successfully")
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"document_id": documentID,
+		"references":  references,
+	})
 }
 
-// SearchDocuments handles document search
-func (h *DocumentHandler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// GetPageImage returns a signed image URL for one page of a comic
+func (h *DocumentHandler) GetPageImage(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	query := r.URL.Query().Get("q")
-	if query == "" {
-		h.writeError(w, http.StatusBadRequest, "Search query is required")
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	page, err := strconv.Atoi(vars["page"])
+	if err != nil || page < 1 {
+		h.writeError(w, http.StatusBadRequest, "Page must be a positive integer")
 		return
 	}
 
-	documents, err := h.documentService.SearchDocuments(user.ID, query, token)
+	image, err := h.documentService.GetPageImage(r.Context(), principal, documentID, page)
 	if err != nil {
-		h.logger.Error("Failed to search documents", err, "user_id", user.ID, "query", query)
-		h.writeError(w, http.StatusInternalServerError, "Failed to search documents")
+		h.writeServiceError(w, err)
 		return
 	}
 
-	// Clean documents before returning
-	var cleanDocs []*domain.DocumentData
-	for _, doc := range documents {
-		cleanDocs = append(cleanDocs, h.cleanDocumentForResponse(doc))
-	}
-
-	// Ensure JSON is [] not null when empty.
-	if cleanDocs == nil {
-		cleanDocs = make([]*domain.DocumentData, 0)
-	}
-
-	h.writeJSON(w, http.StatusOK, cleanDocs)
+	h.writeJSON(w, http.StatusOK, image)
 }
 
-// GetDocumentTags handles getting all document tags for the authenticated user
-func (h *DocumentHandler) GetDocumentTags(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// ListVersions returns the saved versions of a document, newest first
+func (h *DocumentHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
 		return
 	}
 
-	tags, err := h.documentService.GetDocumentTags(user.ID, token)
+	versions, err := h.documentService.ListVersions(r.Context(), principal, documentID)
 	if err != nil {
-		h.logger.Error("Failed to get document tags", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to get document tags")
+		h.writeServiceError(w, err)
 		return
 	}
 
-	// Ensure JSON is [] not null when empty.
-	if tags == nil {
-		tags = make([]string, 0)
-	}
-
-	h.writeJSON(w, http.StatusOK, tags)
-}
-
-type createTagRequest struct {
-	Name string `json:"name"`
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"document_id": documentID,
+		"versions":    versions,
+	})
 }
 
-// CreateTag handles creating a new tag for the authenticated user
-func (h *DocumentHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// RestoreVersion overwrites a document with one of its saved versions
+func (h *DocumentHandler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
 		return
 	}
 
-	var req createTagRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+	version, err := strconv.Atoi(vars["version"])
+	if err != nil || version < 1 {
+		h.writeError(w, http.StatusBadRequest, "Version must be a positive integer")
 		return
 	}
 
-	if req.Name == "" {
-		h.writeError(w, http.StatusBadRequest, "Tag name is required")
+	conditional, err := withPrecondition(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	err := h.documentService.CreateTag(user.ID, req.Name, token)
+	restored, err := h.documentService.RestoreVersion(conditional.Context(), principal, documentID, version)
 	if err != nil {
-		if strings.Contains(err.Error(), "already exists") {
-			h.writeError(w, http.StatusConflict, "Tag already exists")
+		if errors.Is(err, domain.ErrStaleUpdate) {
+			h.writeDocumentConflict(w, r, principal, documentID)
 			return
 		}
-		h.logger.Error("Failed to create tag", err, "user_id", user.ID, "tag_name", req.Name)
-		h.writeError(w, http.StatusInternalServerError, "Failed to create tag")
+		h.writeServiceError(w, err)
 		return
 	}
 
-	// Return the created tag name
-	h.writeJSON(w, http.StatusCreated, map[string]string{"name": req.Name})
+	setETag(w, restored.UpdatedAt)
+	h.writeJSON(w, http.StatusOK, h.cleanDocumentForResponse(restored))
 }
 
-// DeleteTag handles deleting a tag for the authenticated user
-func (h *DocumentHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+type updateDocumentRequest struct {
+	Title  *string `json:"title" validate:"notblank"`
+	Author *string `json:"author"`
+	Tag    *string `json:"tag"` // Single tag (document can only have one tag)
+}
+
+func (req *updateDocumentRequest) Validate() domain.ValidationErrors {
+	if req.Title == nil && req.Author == nil && req.Tag == nil {
+		return domain.ValidationErrors{{Message: "no updates provided"}}
+	}
+	return nil
+}
+
+type setFavoriteRequest struct {
+	IsFavorite bool `json:"is_favorite"`
+}
+
+// SetFavorite marks/unmarks a document as favorite for the authenticated user.
+func (h *DocumentHandler) SetFavorite(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	var req setFavoriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	if err := h.documentService.SetFavorite(r.Context(), principal, documentID, req.IsFavorite); err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"document_id": documentID,
+		"is_favorite": req.IsFavorite,
+		"updated":     true,
+	})
+}
+
+// ArchiveDocument handles POST /documents/{id}/archive: hides the document from the
+// default library listing and the continue-reading shelf, keeping all its data.
+func (h *DocumentHandler) ArchiveDocument(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, true)
+}
+
+// UnarchiveDocument handles POST /documents/{id}/unarchive.
+func (h *DocumentHandler) UnarchiveDocument(w http.ResponseWriter, r *http.Request) {
+	h.setArchived(w, r, false)
+}
+
+func (h *DocumentHandler) setArchived(w http.ResponseWriter, r *http.Request, archived bool) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	if err := h.documentService.SetArchived(r.Context(), principal, documentID, archived); err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]any{
+		"document_id": documentID,
+		"is_archived": archived,
+		"updated":     true,
+	})
+}
+
+// ResolveAnchor handles GET /documents/{id}/anchor with ?char_offset=N, ?progress=F
+// or, for EPUBs, ?spine=S&path=P&offset=N: converts a position in the document's
+// current text between a character offset, a progress, a page and an EPUB locator.
+func (h *DocumentHandler) ResolveAnchor(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	var anchorQuery domain.AnchorQuery
+	query := r.URL.Query()
+	if value := query.Get("char_offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "char_offset must be an integer")
+			return
+		}
+		anchorQuery.CharOffset = &offset
+	}
+	if value := query.Get("progress"); value != "" {
+		parsed, err := strconv.ParseFloat(value, 32)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "progress must be a number")
+			return
+		}
+		fraction := float32(parsed)
+		anchorQuery.Progress = &fraction
+	}
+	if spine := query.Get("spine"); spine != "" {
+		locator := &domain.EPUBLocator{Spine: spine, Path: query.Get("path")}
+		if value := query.Get("offset"); value != "" {
+			offset, err := strconv.Atoi(value)
+			if err != nil {
+				h.writeError(w, http.StatusBadRequest, "offset must be an integer")
+				return
+			}
+			locator.Offset = offset
+		}
+		anchorQuery.Locator = locator
+	}
+
+	anchor, err := h.documentService.ResolveAnchor(r.Context(), principal, documentID, anchorQuery)
+	if err != nil {
+		var validationErrs domain.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid anchor", "fields": validationErrs})
+		case errors.Is(err, domain.ErrDocumentHasNoText):
+			h.writeError(w, http.StatusUnprocessableEntity, "Document has no text to anchor to")
+		case errors.Is(err, domain.ErrLocatorNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, anchor)
+}
+
+// ExportDocument regenerates the document as an EPUB, PDF or plain-text file
+// (?format=epub|pdf|txt) and sends it as a download.
+func (h *DocumentHandler) ExportDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	export, err := h.documentService.ExportDocument(r.Context(), principal, documentID, r.URL.Query().Get("format"))
+	if err != nil {
+		var validationErrs domain.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid export", "fields": validationErrs})
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrDocumentHasNoText):
+			h.writeError(w, http.StatusUnprocessableEntity, "Document has no text to export")
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+
+	h.writeExport(w, export)
+}
+
+// GetPlainText sends the document's raw extracted text as a download.
+func (h *DocumentHandler) GetPlainText(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	text, err := h.documentService.PlainText(r.Context(), principal, documentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrDocumentHasNoText):
+			h.writeError(w, http.StatusUnprocessableEntity, "Document has no extracted text")
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+	h.writeExport(w, text)
+}
+
+// FindInDocument handles GET /api/v2/documents/{id}/find?q=...&limit=N&offset=N:
+// the matches of q in the document's text, with their pages and snippets.
+func (h *DocumentHandler) FindInDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	page, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeValidationErrors(w, "Invalid query", err)
+		return
+	}
+	documentID := mux.Vars(r)["id"]
+
+	matches, err := h.documentService.FindInDocument(r.Context(), principal, documentID, r.URL.Query().Get("q"))
+	if err != nil {
+		var validationErrs domain.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			writeValidationErrors(w, "Invalid query", err)
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrDocumentHasNoText):
+			h.writeError(w, http.StatusUnprocessableEntity, "Document has no extracted text")
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+	writeList(w, r, matches, page)
+}
+
+// VerifyDocument reports inconsistencies between a document's content, metadata
+// and stored files.
+func (h *DocumentHandler) VerifyDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	report, err := h.documentService.VerifyDocument(r.Context(), principal, documentID)
+	if err != nil {
+		if errors.Is(err, domain.ErrDocumentNotFound) {
+			h.writeError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.writeServiceError(w, err)
+		return
+	}
+	h.writeJSON(w, http.StatusOK, report)
+}
+
+// ReprocessDocument re-extracts a document from its original file, repairing what
+// VerifyDocument reports.
+func (h *DocumentHandler) ReprocessDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	doc, err := h.documentService.ReprocessDocument(r.Context(), principal, documentID)
+	if err != nil {
+		var pdfErr *domain.PDFValidationError
+		switch {
+		case errors.As(err, &pdfErr):
+			h.writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": pdfErr.Message, "code": pdfErr.Code})
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrBlobNotFound):
+			h.writeError(w, http.StatusUnprocessableEntity, "The original file is missing from storage")
+		case errors.Is(err, domain.ErrNotReprocessable), errors.Is(err, domain.ErrInvalidFile):
+			h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, h.cleanDocumentForResponse(doc))
+}
+
+// ServeFile streams a document's original file from storage through the server.
+// Range requests are answered with 206 so viewers can seek, and every access is
+// logged with the bytes sent, for auditing and bandwidth accounting.
+func (h *DocumentHandler) ServeFile(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	documentID := mux.Vars(r)["documentId"]
+	file, err := h.documentService.OpenFile(r.Context(), principal, documentID)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, err.Error())
+		case errors.Is(err, domain.ErrBlobNotFound):
+			h.writeError(w, http.StatusNotFound, "The original file is missing from storage")
+		default:
+			h.writeServiceError(w, err)
+		}
+		return
+	}
+	defer file.Content.Close()
+
+	w.Header().Set("Content-Type", file.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", file.Filename))
+	// Clients may cache the file but must revalidate, so revoked access is noticed.
+	w.Header().Set("Cache-Control", "private, no-cache")
+	if file.ETag != "" {
+		w.Header().Set("ETag", file.ETag)
+	}
+
+	if limiter, ok := file.Content.(domain.RangeLimiter); ok {
+		if end, ok := requestedRangeEnd(r.Header.Get("Range"), file.Size); ok {
+			limiter.LimitRange(end)
+		}
+	}
+	// Large originals take longer to send than the server's write timeout allows.
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Error("Failed to clear write deadline", err, "user_id", principal.UserID)
+	}
+
+	recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	http.ServeContent(recorder, r, file.Filename, file.ModTime, file.Content)
+
+	h.logger.Info("Document file accessed",
+		"user_id", principal.UserID,
+		"doc_id", documentID,
+		"ip", clientIP(r),
+		"range", r.Header.Get("Range"),
+		"status", recorder.status,
+		"bytes", recorder.bytes,
+		"request_id", requestID(r),
+	)
+}
+
+// requestedRangeEnd returns the end of the furthest byte range a Range header asks
+// for in a file of size bytes, or false without a header it understands.
+func requestedRangeEnd(header string, size int64) (int64, bool) {
+	specs, ok := strings.CutPrefix(header, "bytes=")
+	if !ok {
+		return 0, false
+	}
+	var furthest int64
+	for _, spec := range strings.Split(specs, ",") {
+		first, last, ok := strings.Cut(strings.TrimSpace(spec), "-")
+		if !ok {
+			return 0, false
+		}
+		end := size
+		if first != "" && last != "" {
+			n, err := strconv.ParseInt(last, 10, 64)
+			if err != nil || n < 0 {
+				return 0, false
+			}
+			end = min(n+1, size)
+		}
+		furthest = max(furthest, end)
+	}
+	return furthest, true
+}
+
+// writeExport sends a generated file as an attachment.
+func (h *DocumentHandler) writeExport(w http.ResponseWriter, export *domain.DocumentExport) {
+	w.Header().Set("Content-Type", export.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", export.Filename))
+	w.Header().Set("Content-Length", strconv.Itoa(len(export.Data)))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(export.Data)
+}
+
+// UpdateDocument updates title/author/tag for a document
+func (h *DocumentHandler) UpdateDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	var req updateDocumentRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid document update", err)
+		return
+	}
+
+	conditional, err := withPrecondition(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updated, err := h.documentService.UpdateDocumentDetails(conditional.Context(), principal, documentID, req.Title, req.Author, req.Tag)
+	if err != nil {
+		if errors.Is(err, domain.ErrStaleUpdate) {
+			h.writeDocumentConflict(w, r, principal, documentID)
+			return
+		}
+		h.writeServiceError(w, err)
+		return
+	}
+
+	cleanDoc := h.cleanDocumentForResponse(updated)
+	setETag(w, updated.UpdatedAt)
+	h.writeJSON(w, http.StatusOK, cleanDoc)
+}
+
+// writeDocumentConflict answers a stale update with 409 and the document as currently
+// stored, so the client can merge and retry with the new ETag.
+func (h *DocumentHandler) writeDocumentConflict(w http.ResponseWriter, r *http.Request, principal domain.Principal, documentID string) {
+	current, err := h.documentService.GetDocument(r.Context(), principal, documentID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+	setETag(w, current.UpdatedAt)
+	h.writeJSON(w, http.StatusConflict, map[string]any{
+		"error":   "Document was modified by another request",
+		"current": h.cleanDocumentForResponse(current),
+	})
+}
+
+// DeleteDocument handles document deletion
+func (h *DocumentHandler) DeleteDocument(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
 		return
 	}
 
+	err := h.documentService.DeleteDocument(r.Context(), principal, documentID)
+	if err != nil {
+		h.writeServiceError(w, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, "Document deleted successfully")
+}
+
+// SearchDocuments handles GET /documents/search?q=...: the user's documents matching
+// a query in the syntax of domain.ParseDocumentQuery.
+func (h *DocumentHandler) SearchDocuments(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	query := r.URL.Query().Get("q")
+	if query == "" {
+		h.writeError(w, http.StatusBadRequest, "Search query is required")
+		return
+	}
+
+	filter, err := domain.ParseLibraryQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	documents, err := h.documentService.SearchDocuments(r.Context(), principal, query)
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		writeValidationErrors(w, "Invalid search", err)
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to search documents", err, "user_id", principal.UserID, "query", query)
+		writeServerError(w, err, "Failed to search documents")
+		return
+	}
+	documents = filter.Apply(documents)
+
+	// Clean documents before returning
+	var cleanDocs []*domain.DocumentData
+	for _, doc := range documents {
+		cleanDocs = append(cleanDocs, h.cleanDocumentForResponse(doc))
+	}
+
+	// Ensure JSON is [] not null when empty.
+	if cleanDocs == nil {
+		cleanDocs = make([]*domain.DocumentData, 0)
+	}
+
+	h.writeJSON(w, http.StatusOK, cleanDocs)
+}
+
+type savedSearchRequest struct {
+	Name    string                    `json:"name"`
+	Query   string                    `json:"query"`
+	Filters domain.SavedSearchFilters `json:"filters"`
+}
+
+// ListSavedSearches handles GET /saved-searches: the caller's saved searches by name.
+func (h *DocumentHandler) ListSavedSearches(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	searches, err := h.savedSearchService.ListSavedSearches(r.Context(), principal)
+	if err != nil {
+		h.writeSavedSearchError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, searches)
+}
+
+// GetSavedSearch handles GET /saved-searches/{id}.
+func (h *DocumentHandler) GetSavedSearch(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	search, err := h.savedSearchService.GetSavedSearch(r.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		h.writeSavedSearchError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, search)
+}
+
+// CreateSavedSearch handles POST /saved-searches: saves a query and filters under a
+// name. The collection lists at ?collection=smart:{id}.
+func (h *DocumentHandler) CreateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var req savedSearchRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid saved search", err)
+		return
+	}
+
+	created, err := h.savedSearchService.CreateSavedSearch(r.Context(), principal, &domain.SavedSearch{
+		Name:    req.Name,
+		Query:   req.Query,
+		Filters: req.Filters,
+	})
+	if err != nil {
+		h.writeSavedSearchError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, created)
+}
+
+// UpdateSavedSearch handles PUT /saved-searches/{id}: replaces the name, query and
+// filters.
+func (h *DocumentHandler) UpdateSavedSearch(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var req savedSearchRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid saved search", err)
+		return
+	}
+
+	updated, err := h.savedSearchService.UpdateSavedSearch(r.Context(), principal, mux.Vars(r)["id"], &domain.SavedSearch{
+		Name:    req.Name,
+		Query:   req.Query,
+		Filters: req.Filters,
+	})
+	if err != nil {
+		h.writeSavedSearchError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteSavedSearch handles DELETE /saved-searches/{id}.
+func (h *DocumentHandler) DeleteSavedSearch(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	if err := h.savedSearchService.DeleteSavedSearch(r.Context(), principal, mux.Vars(r)["id"]); err != nil {
+		h.writeSavedSearchError(w, principal, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *DocumentHandler) writeSavedSearchError(w http.ResponseWriter, principal domain.Principal, err error) {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		writeValidationErrors(w, "Invalid saved search", err)
+	case errors.Is(err, domain.ErrSavedSearchNotFound):
+		h.writeError(w, http.StatusNotFound, "Saved search not found")
+	case errors.Is(err, domain.ErrSavedSearchExists), errors.Is(err, domain.ErrSavedSearchLimitReached):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Saved search operation failed", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to update saved searches")
+	}
+}
+
+// GetDocumentTags handles getting all document tags for the authenticated user
+func (h *DocumentHandler) GetDocumentTags(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	tags, err := h.documentService.GetDocumentTags(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to get document tags", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to get document tags")
+		return
+	}
+
+	// Ensure JSON is [] not null when empty.
+	if tags == nil {
+		tags = make([]string, 0)
+	}
+
+	h.writeJSON(w, http.StatusOK, tags)
+}
+
+// ListDocumentTagsV2 handles GET /api/v2/document-tags: a page of the caller's tags.
+func (h *DocumentHandler) ListDocumentTagsV2(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	page, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeValidationErrors(w, "Invalid query", err)
+		return
+	}
+
+	tags, err := h.documentService.GetDocumentTags(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to get document tags", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to get document tags")
+		return
+	}
+	writeList(w, r, tags, page)
+}
+
+type createTagRequest struct {
+	Name string `json:"name" validate:"required"`
+}
+
+// CreateTag handles creating a new tag for the authenticated user
+func (h *DocumentHandler) CreateTag(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	var req createTagRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid tag", err)
+		return
+	}
+
+	err := h.documentService.CreateTag(r.Context(), principal, req.Name)
+	if err != nil {
+		if strings.Contains(err.Error(), "already exists") {
+			h.writeError(w, http.StatusConflict, "Tag already exists")
+			return
+		}
+		h.logger.Error("Failed to create tag", err, "user_id", principal.UserID, "tag_name", req.Name)
+		writeServerError(w, err, "Failed to create tag")
+		return
+	}
+
+	// Return the created tag name
+	h.writeJSON(w, http.StatusCreated, map[string]string{"name": req.Name})
+}
+
+// DeleteTag handles deleting a tag for the authenticated user
+func (h *DocumentHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
 	vars := mux.Vars(r)
 	tagName := vars["name"]
 	if tagName == "" {
@@ -606,14 +1587,14 @@ func (h *DocumentHandler) DeleteTag(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.documentService.DeleteTag(user.ID, tagName, token)
+	err := h.documentService.DeleteTag(r.Context(), principal, tagName)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			h.writeError(w, http.StatusNotFound, "Tag not found")
 			return
 		}
-		h.logger.Error("Failed to delete tag", err, "user_id", user.ID, "tag_name", tagName)
-		h.writeError(w, http.StatusInternalServerError, "Failed to delete tag")
+		h.logger.Error("Failed to delete tag", err, "user_id", principal.UserID, "tag_name", tagName)
+		writeServerError(w, err, "Failed to delete tag")
 		return
 	}
 
@@ -627,6 +1608,42 @@ func (h *DocumentHandler) writeError(w http.ResponseWriter, statusCode int, mess
 	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
 }
 
+// writeFileTooLarge writes a 413 describing the limit that applied.
+func (h *DocumentHandler) writeFileTooLarge(w http.ResponseWriter, limit domain.UploadLimit) {
+	h.writeJSON(w, http.StatusRequestEntityTooLarge, map[string]any{
+		"error":       fmt.Sprintf("File too large. Maximum single file size is %dMB.", limit.MaxBytes/(1024*1024)),
+		"limit_bytes": limit.MaxBytes,
+		"plan":        limit.Plan,
+		"format":      limit.Format,
+	})
+}
+
+// writeServiceError maps authorization failures to 403, quarantined documents to 423,
+// upstream outages to 503 and everything else to 500.
+func (h *DocumentHandler) writeServiceError(w http.ResponseWriter, err error) {
+	if errors.Is(err, domain.ErrAccessDenied) {
+		h.writeError(w, http.StatusForbidden, "Access denied")
+		return
+	}
+	if errors.Is(err, domain.ErrDocumentQuarantined) {
+		h.writeError(w, http.StatusLocked, "Document is held for security review")
+		return
+	}
+	if errors.Is(err, domain.ErrPageNotFound) || errors.Is(err, domain.ErrVersionNotFound) {
+		h.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	if errors.Is(err, domain.ErrStaleUpdate) {
+		h.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, domain.ErrDocumentNotComparable) {
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+		return
+	}
+	writeServerError(w, err, err.Error())
+}
+
 // cleanDocumentForResponse ensures the document content is safe for JSON serialization
 func (h *DocumentHandler) cleanDocumentForResponse(doc *domain.Document) *domain.Document {
 	// Create a copy to avoid modifying the original
@@ -5,44 +5,145 @@ import (
 	"context"
 	"encoding/json"
 	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"sort"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
+	"pdf-text-reader/internal/authctx"
+	"pdf-text-reader/internal/config"
 	"pdf-text-reader/internal/domain"
 )
 
 // Mock implementations for handler testing
 type MockDocumentService struct {
-	documents map[string]*domain.Document
+	documents    map[string]*domain.Document
+	versions     map[string][]*domain.DocumentVersion
+	uploadLimits domain.UploadLimits
+	uploadErr    error
+	uploads      map[string]*domain.UploadProgress
+	files        map[string][]byte
 }
 
 func NewMockDocumentService() *MockDocumentService {
 	return &MockDocumentService{
 		documents: make(map[string]*domain.Document),
+		versions:  make(map[string][]*domain.DocumentVersion),
+		uploads:   make(map[string]*domain.UploadProgress),
+		files:     make(map[string][]byte),
 	}
 }
 
-func (m *MockDocumentService) GetDocumentsByUserID(userID string, token string) ([]*domain.DocumentData, error) {
+func (m *MockDocumentService) GetDocumentsByUserID(ctx context.Context, principal domain.Principal) ([]*domain.DocumentData, error) {
 	var docs []*domain.DocumentData
 	for _, doc := range m.documents {
-		if doc.UserID == userID {
+		if doc.UserID == principal.UserID {
 			docs = append(docs, doc)
 		}
 	}
 	return docs, nil
 }
 
-func (m *MockDocumentService) GetDocument(documentID string, token string) (*domain.DocumentData, error) {
+func (m *MockDocumentService) GetDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
 	if doc, exists := m.documents[documentID]; exists {
 		return doc, nil
 	}
 	return nil, domain.ErrDocumentNotFound
 }
 
-func (m *MockDocumentService) DeleteDocument(documentID string, token string) error {
+func (m *MockDocumentService) OpenDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	doc, err := m.GetDocument(ctx, principal, documentID)
+	if err != nil || domain.ReadOnlyFromContext(ctx) {
+		return doc, err
+	}
+	now := time.Now().UTC()
+	doc.LastOpenedAt = &now
+	return doc, nil
+}
+
+func (m *MockDocumentService) GetOutline(ctx context.Context, principal domain.Principal, documentID string) ([]domain.OutlineEntry, error) {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if doc.Metadata.Outline == nil {
+		return []domain.OutlineEntry{}, nil
+	}
+	return doc.Metadata.Outline, nil
+}
+
+func (m *MockDocumentService) CompareDocuments(ctx context.Context, principal domain.Principal, leftID, rightID string) (*domain.DocumentComparison, error) {
+	for _, id := range []string{leftID, rightID} {
+		doc, exists := m.documents[id]
+		if !exists {
+			return nil, domain.ErrDocumentNotFound
+		}
+		if len(doc.Content) == 0 {
+			return nil, domain.ErrDocumentNotComparable
+		}
+	}
+	return &domain.DocumentComparison{
+		LeftDocumentID:  leftID,
+		RightDocumentID: rightID,
+		Summary:         domain.ComparisonSummary{PagesChanged: 1, ParagraphsReplaced: 1},
+		Pages: []domain.PageComparison{{Page: 1, Status: domain.PageChanged, Changes: []domain.ChangeBlock{
+			{Type: domain.ChangeReplace, Left: []string{"old"}, Right: []string{"new"}},
+		}}},
+	}, nil
+}
+
+func (m *MockDocumentService) GetReferences(ctx context.Context, principal domain.Principal, documentID string) ([]domain.Reference, error) {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if doc.Metadata.References == nil {
+		return []domain.Reference{}, nil
+	}
+	return doc.Metadata.References, nil
+}
+
+func (m *MockDocumentService) GetPageImage(ctx context.Context, principal domain.Principal, documentID string, page int) (*domain.PageImage, error) {
+	if _, exists := m.documents[documentID]; !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if page > 2 {
+		return nil, domain.ErrPageNotFound
+	}
+	return &domain.PageImage{Page: page, URL: "https://storage.test/signed/" + documentID, ContentType: "image/png"}, nil
+}
+
+func (m *MockDocumentService) ListVersions(ctx context.Context, principal domain.Principal, documentID string) ([]*domain.DocumentVersion, error) {
+	if _, exists := m.documents[documentID]; !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if m.versions[documentID] == nil {
+		return []*domain.DocumentVersion{}, nil
+	}
+	return m.versions[documentID], nil
+}
+
+func (m *MockDocumentService) RestoreVersion(ctx context.Context, principal domain.Principal, documentID string, version int) (*domain.DocumentData, error) {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	for _, v := range m.versions[documentID] {
+		if v.Version == version {
+			doc.Title = v.Title
+			doc.Content = v.Content
+			return doc, nil
+		}
+	}
+	return nil, domain.ErrVersionNotFound
+}
+
+func (m *MockDocumentService) DeleteDocument(ctx context.Context, principal domain.Principal, documentID string) error {
 	if _, exists := m.documents[documentID]; !exists {
 		return domain.ErrDocumentNotFound
 	}
@@ -50,19 +151,23 @@ func (m *MockDocumentService) DeleteDocument(documentID string, token string) er
 	return nil
 }
 
-func (m *MockDocumentService) SearchDocuments(userID, query string, token string) ([]*domain.DocumentData, error) {
+func (m *MockDocumentService) SearchDocuments(ctx context.Context, principal domain.Principal, query string) ([]*domain.DocumentData, error) {
+	parsed, err := domain.ParseDocumentQuery(query)
+	if err != nil {
+		return nil, err
+	}
 	var docs []*domain.DocumentData
 	for _, doc := range m.documents {
-		if doc.UserID == userID && strings.Contains(strings.ToLower(doc.Title), strings.ToLower(query)) {
+		if doc.UserID == principal.UserID && parsed.Match(doc) {
 			docs = append(docs, doc)
 		}
 	}
 	return docs, nil
 }
 
-func (m *MockDocumentService) SetFavorite(userID string, documentID string, isFavorite bool, token string) error {
+func (m *MockDocumentService) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
 	if doc, exists := m.documents[documentID]; exists {
-		if doc.UserID != userID {
+		if doc.UserID != principal.UserID {
 			return domain.ErrAccessDenied
 		}
 		doc.IsFavorite = isFavorite
@@ -71,11 +176,104 @@ func (m *MockDocumentService) SetFavorite(userID string, documentID string, isFa
 	return domain.ErrDocumentNotFound
 }
 
-func (m *MockDocumentService) UpdateDocumentDetails(userID string, documentID string, title *string, author *string, tag *string, token string) (*domain.DocumentData, error) {
+func (m *MockDocumentService) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
 	if doc, exists := m.documents[documentID]; exists {
-		if doc.UserID != userID {
+		if doc.UserID != principal.UserID {
+			return domain.ErrAccessDenied
+		}
+		doc.IsArchived = archived
+		return nil
+	}
+	return domain.ErrDocumentNotFound
+}
+
+func (m *MockDocumentService) ExportDocument(ctx context.Context, principal domain.Principal, documentID string, format string) (*domain.DocumentExport, error) {
+	if err := domain.ValidateExportFormat(format); err != nil {
+		return nil, err
+	}
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if doc.UserID != principal.UserID {
+		return nil, domain.ErrAccessDenied
+	}
+	return &domain.DocumentExport{Filename: "document." + format, ContentType: "text/plain; charset=utf-8", Data: []byte(doc.Title)}, nil
+}
+
+func (m *MockDocumentService) VerifyDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentIntegrity, error) {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	return &domain.DocumentIntegrity{DocumentID: doc.ID, Format: doc.Metadata.Format, OK: true, FileExists: true, Issues: []domain.IntegrityIssue{}}, nil
+}
+
+func (m *MockDocumentService) OpenFile(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentFile, error) {
+	doc, exists := m.documents[documentID]
+	if !exists || doc.UserID != principal.UserID {
+		return nil, domain.ErrDocumentNotFound
+	}
+	data, exists := m.files[documentID]
+	if !exists {
+		return nil, domain.ErrBlobNotFound
+	}
+	return &domain.DocumentFile{
+		Filename:    doc.Title + ".pdf",
+		ContentType: "application/pdf",
+		Size:        int64(len(data)),
+		ModTime:     doc.CreatedAt,
+		ETag:        `"` + doc.Metadata.SHA256 + `"`,
+		Content:     nopSeekCloser{bytes.NewReader(data)},
+	}, nil
+}
+
+type nopSeekCloser struct{ io.ReadSeeker }
+
+func (nopSeekCloser) Close() error { return nil }
+
+func (m *MockDocumentService) ReprocessDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	if doc.Metadata.Format == "txt" {
+		return nil, domain.ErrNotReprocessable
+	}
+	return doc, nil
+}
+
+func (m *MockDocumentService) PlainText(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentExport, error) {
+	return m.ExportDocument(ctx, principal, documentID, domain.ExportFormatTXT)
+}
+
+func (m *MockDocumentService) ResolveAnchor(ctx context.Context, principal domain.Principal, documentID string, query domain.AnchorQuery) (*domain.TextAnchor, error) {
+	return nil, domain.ErrDocumentHasNoText
+}
+
+func (m *MockDocumentService) FindInDocument(ctx context.Context, principal domain.Principal, documentID, query string) ([]*domain.DocumentMatch, error) {
+	if query == "" {
+		return nil, domain.ValidationErrors{{Field: "q", Message: "a search query is required"}}
+	}
+	if _, exists := m.documents[documentID]; !exists {
+		return nil, domain.ErrDocumentNotFound
+	}
+	var matches []*domain.DocumentMatch
+	for i := 0; i < 3; i++ {
+		matches = append(matches, &domain.DocumentMatch{CharOffset: i * 10, Length: len(query), PageNumber: i + 1,
+			Snippet: "a " + query + " b", SnippetStart: 2, SnippetEnd: 2 + len(query)})
+	}
+	return matches, nil
+}
+
+func (m *MockDocumentService) UpdateDocumentDetails(ctx context.Context, principal domain.Principal, documentID string, title *string, author *string, tag *string) (*domain.DocumentData, error) {
+	if doc, exists := m.documents[documentID]; exists {
+		if doc.UserID != principal.UserID {
 			return nil, domain.ErrAccessDenied
 		}
+		if unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(ctx); ok && !domain.PreconditionTime(doc.UpdatedAt).Equal(unmodifiedSince) {
+			return nil, domain.ErrStaleUpdate
+		}
 		if title != nil {
 			doc.Title = *title
 		}
@@ -90,24 +288,27 @@ func (m *MockDocumentService) UpdateDocumentDetails(userID string, documentID st
 	return nil, domain.ErrDocumentNotFound
 }
 
-func (m *MockDocumentService) GetDocumentTags(userID string, token string) ([]string, error) {
+func (m *MockDocumentService) GetDocumentTags(ctx context.Context, principal domain.Principal) ([]string, error) {
 	// Mock implementation
 	return []string{"programming", "tutorial"}, nil
 }
 
-func (m *MockDocumentService) CreateTag(userID string, tagName string, token string) error {
+func (m *MockDocumentService) CreateTag(ctx context.Context, principal domain.Principal, tagName string) error {
 	return nil
 }
 
-func (m *MockDocumentService) DeleteTag(userID string, tagName string, token string) error {
+func (m *MockDocumentService) DeleteTag(ctx context.Context, principal domain.Principal, tagName string) error {
 	return nil
 }
 
-func (m *MockDocumentService) Upload(ctx context.Context, userID string, file io.Reader, token string, originalName string) (*domain.DocumentData, error) {
+func (m *MockDocumentService) Upload(ctx context.Context, principal domain.Principal, file io.Reader, originalName string) (*domain.DocumentData, error) {
+	if m.uploadErr != nil {
+		return nil, m.uploadErr
+	}
 	// Mock implementation
 	doc := &domain.DocumentData{
 		ID:      "new-doc-id",
-		UserID:  userID,
+		UserID:  principal.UserID,
 		Title:   originalName,
 		Content: json.RawMessage(`[]`),
 		Metadata: domain.DocumentMetadata{
@@ -119,36 +320,110 @@ func (m *MockDocumentService) Upload(ctx context.Context, userID string, file io
 	return doc, nil
 }
 
+func (m *MockDocumentService) StartUpload(principal domain.Principal, uploadID string, totalBytes int64) error {
+	if err := domain.ValidateUploadID(uploadID); err != nil {
+		return err
+	}
+	if old, ok := m.uploads[uploadID]; ok && old.Status == domain.UploadStatusReceiving {
+		return domain.ErrUploadInProgress
+	}
+	m.uploads[uploadID] = &domain.UploadProgress{ID: uploadID, UserID: principal.UserID, Status: domain.UploadStatusReceiving, TotalBytes: totalBytes}
+	return nil
+}
+
+func (m *MockDocumentService) UploadReceived(principal domain.Principal, uploadID string, n int64) {
+	m.uploads[uploadID].BytesReceived += n
+}
+
+func (m *MockDocumentService) UploadProcessing(principal domain.Principal, uploadID string) {
+	m.uploads[uploadID].Status = domain.UploadStatusProcessing
+}
+
+func (m *MockDocumentService) FinishUpload(principal domain.Principal, uploadID, documentID string, err error) {
+	upload := m.uploads[uploadID]
+	if err != nil {
+		upload.Status, upload.Error = domain.UploadStatusFailed, err.Error()
+		return
+	}
+	upload.Status, upload.DocumentID = domain.UploadStatusDone, documentID
+}
+
+func (m *MockDocumentService) GetUploadProgress(ctx context.Context, principal domain.Principal, uploadID string) (*domain.UploadProgress, error) {
+	upload, ok := m.uploads[uploadID]
+	if !ok || upload.UserID != principal.UserID {
+		return nil, domain.ErrUploadNotFound
+	}
+	return upload, nil
+}
+
+func (m *MockDocumentService) UploadLimit(ctx context.Context, principal domain.Principal, format string) domain.UploadLimit {
+	return m.uploadLimits.Resolve("free", format)
+}
+
 type MockUserPreferencesService struct {
 	preferences map[string]*domain.UserPreferences
 	positions   map[string]map[string]*domain.ReadingPosition
+	overrides   map[string]*domain.DocumentPreferences // Keyed by document ID
+	disabled    map[string]bool
 }
 
 func NewMockUserPreferencesService() *MockUserPreferencesService {
 	return &MockUserPreferencesService{
 		preferences: make(map[string]*domain.UserPreferences),
 		positions:   make(map[string]map[string]*domain.ReadingPosition),
+		overrides:   make(map[string]*domain.DocumentPreferences),
+		disabled:    make(map[string]bool),
+	}
+}
+
+func (m *MockUserPreferencesService) DisableAccount(ctx context.Context, principal domain.Principal) error {
+	m.disabled[principal.UserID] = true
+	return nil
+}
+
+func (m *MockUserPreferencesService) GetDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) (*domain.UserPreferences, error) {
+	prefs, _ := m.GetPreferences(ctx, principal)
+	return prefs.WithOverride(m.overrides[documentID]), nil
+}
+
+func (m *MockUserPreferencesService) UpdateDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string, override *domain.DocumentPreferences) (*domain.UserPreferences, error) {
+	if err := override.Validate(); err != nil {
+		return nil, err
 	}
+	if override.IsEmpty() {
+		delete(m.overrides, documentID)
+	} else {
+		m.overrides[documentID] = override
+	}
+	return m.GetDocumentPreferences(ctx, principal, documentID)
 }
 
-func (m *MockUserPreferencesService) GetPreferences(userID string, token string) (*domain.UserPreferences, error) {
-	if prefs, exists := m.preferences[userID]; exists {
-		return prefs, nil
+func (m *MockUserPreferencesService) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
+	if prefs, exists := m.preferences[principal.UserID]; exists {
+		stored := *prefs
+		return &stored, nil
 	}
 	return &domain.UserPreferences{
-		UserID:   userID,
+		UserID:   principal.UserID,
 		FontSize: 16,
 		Theme:    "light",
 	}, nil
 }
 
-func (m *MockUserPreferencesService) UpdatePreferences(userID string, prefs *domain.UserPreferences, token string) error {
-	m.preferences[userID] = prefs
+func (m *MockUserPreferencesService) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
+	if unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(ctx); ok {
+		stored, exists := m.preferences[principal.UserID]
+		if !exists || !domain.PreconditionTime(stored.UpdatedAt).Equal(unmodifiedSince) {
+			return domain.ErrStaleUpdate
+		}
+	}
+	prefs.UpdatedAt = time.Now().UTC()
+	m.preferences[principal.UserID] = prefs
 	return nil
 }
 
-func (m *MockUserPreferencesService) GetReadingPosition(userID, documentID string, token string) (*domain.ReadingPosition, error) {
-	if userPositions, exists := m.positions[userID]; exists {
+func (m *MockUserPreferencesService) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
+	if userPositions, exists := m.positions[principal.UserID]; exists {
 		if pos, exists := userPositions[documentID]; exists {
 			return pos, nil
 		}
@@ -156,29 +431,48 @@ func (m *MockUserPreferencesService) GetReadingPosition(userID, documentID strin
 	return nil, domain.ErrReadingPositionNotFound
 }
 
-func (m *MockUserPreferencesService) GetAllReadingPositions(userID string, token string) (map[string]*domain.ReadingPosition, error) {
-	if userPositions, exists := m.positions[userID]; exists {
+func (m *MockUserPreferencesService) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
+	if userPositions, exists := m.positions[principal.UserID]; exists {
 		return userPositions, nil
 	}
 	return make(map[string]*domain.ReadingPosition), nil
 }
 
-func (m *MockUserPreferencesService) UpdateReadingPosition(userID, documentID string, position *domain.ReadingPosition, token string) error {
-	if m.positions[userID] == nil {
-		m.positions[userID] = make(map[string]*domain.ReadingPosition)
+func (m *MockUserPreferencesService) GetReadingPositions(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]*domain.ReadingPosition, error) {
+	positions := make(map[string]*domain.ReadingPosition)
+	for _, id := range documentIDs {
+		if position, ok := m.positions[principal.UserID][id]; ok {
+			positions[id] = position
+		}
+	}
+	return positions, nil
+}
+
+func (m *MockUserPreferencesService) UpdateReadingPosition(ctx context.Context, principal domain.Principal, documentID string, position *domain.ReadingPosition) error {
+	if m.positions[principal.UserID] == nil {
+		m.positions[principal.UserID] = make(map[string]*domain.ReadingPosition)
 	}
-	m.positions[userID][documentID] = position
+	m.positions[principal.UserID][documentID] = position
 	return nil
 }
 
+func (m *MockUserPreferencesService) GetPositionHistory(ctx context.Context, principal domain.Principal, documentID string, days int) (*domain.PositionHistory, error) {
+	history := &domain.PositionHistory{DocumentID: documentID, Samples: []*domain.PositionSample{}, Days: []domain.PositionDay{}}
+	if position, ok := m.positions[principal.UserID][documentID]; ok {
+		history.Samples = append(history.Samples, &domain.PositionSample{
+			DocumentID: documentID, Progress: position.Progress, PageNumber: position.PageNumber, RecordedAt: position.UpdatedAt,
+		})
+	}
+	return history, nil
+}
+
 func createContextWithUser(r *http.Request, user *domain.SupabaseUser) *http.Request {
-	ctx := context.WithValue(r.Context(), userContextKey, user)
+	ctx := authctx.WithUser(r.Context(), user)
 	return r.WithContext(ctx)
 }
 
-func createContextWithToken(r *http.Request, token string) *http.Request {
-	ctx := context.WithValue(r.Context(), tokenContextKey, token)
-	return r.WithContext(ctx)
+func createContextWithPrincipal(r *http.Request, principal domain.Principal) *http.Request {
+	return r.WithContext(authctx.WithPrincipal(r.Context(), principal))
 }
 
 func TestDocumentHandler_GetDocumentsByUserID(t *testing.T) {
@@ -186,7 +480,7 @@ func TestDocumentHandler_GetDocumentsByUserID(t *testing.T) {
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -198,7 +492,7 @@ func TestDocumentHandler_GetDocumentsByUserID(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest("GET", "/api/v1/users/user1/documents", nil)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.Principal{UserID: "user1", Token: "test-token"})
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -231,12 +525,79 @@ func TestDocumentHandler_GetDocumentsByUserID(t *testing.T) {
 	}
 }
 
+func TestDocumentHandler_GetDocumentsByUserID_OtherUser(t *testing.T) {
+	handler := NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+
+	req := httptest.NewRequest("GET", "/api/v1/users/user2/documents", nil)
+	req = createContextWithPrincipal(req, domain.Principal{UserID: "user1", Token: "test-token"})
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/users/{id}/documents", handler.GetDocumentsByUserID).Methods("GET")
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+	}
+}
+
+func TestDocumentHandler_ArchivedDocuments(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Kept"}
+	docService.documents["doc2"] = &domain.Document{ID: "doc2", UserID: "user1", Title: "Done with"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/documents/user/{id}", handler.GetDocumentsByUserID).Methods("GET")
+	router.HandleFunc("/documents/{id}/archive", handler.ArchiveDocument).Methods("POST")
+	principal := domain.Principal{UserID: "user1", Token: "test-token"}
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, createContextWithPrincipal(httptest.NewRequest("POST", "/documents/doc2/archive", nil), principal))
+	if rr.Code != http.StatusOK || !docService.documents["doc2"].IsArchived {
+		t.Fatalf("expected the document to be archived, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	list := func(query string) []string {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, createContextWithPrincipal(httptest.NewRequest("GET", "/documents/user/user1"+query, nil), principal))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("list%s: expected status %d, got %d", query, http.StatusOK, rr.Code)
+		}
+		var docs []*domain.Document
+		if err := json.Unmarshal(rr.Body.Bytes(), &docs); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		var ids []string
+		for _, doc := range docs {
+			ids = append(ids, doc.ID)
+		}
+		sort.Strings(ids)
+		return ids
+	}
+	if ids := list(""); len(ids) != 1 || ids[0] != "doc1" {
+		t.Fatalf("expected archived documents to be hidden by default, got %v", ids)
+	}
+	if ids := list("?archived=true"); len(ids) != 1 || ids[0] != "doc2" {
+		t.Fatalf("expected only archived documents, got %v", ids)
+	}
+	if ids := list("?archived=all"); len(ids) != 2 {
+		t.Fatalf("expected every document, got %v", ids)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, createContextWithPrincipal(httptest.NewRequest("GET", "/documents/user/user1?archived=maybe", nil), principal))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d for an invalid filter, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
 func TestDocumentHandler_GetDocument(t *testing.T) {
 	docService := NewMockDocumentService()
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -250,7 +611,7 @@ func TestDocumentHandler_GetDocument(t *testing.T) {
 	req := httptest.NewRequest("GET", "/api/v1/documents/doc1", nil)
 	user := &domain.SupabaseUser{ID: "user1", Email: "test@example.com"}
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -279,12 +640,548 @@ func TestDocumentHandler_GetDocument(t *testing.T) {
 	}
 }
 
+func TestDocumentHandler_GetOutline(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+
+	docService.documents["doc1"] = &domain.Document{
+		ID:     "doc1",
+		UserID: "user1",
+		Metadata: domain.DocumentMetadata{Outline: []domain.OutlineEntry{
+			{Title: "Chapter 1", Depth: 0, Page: 1, Position: 0},
+			{Title: "Section 1.1", Depth: 1, Page: 1, Position: 3},
+		}},
+	}
+	docService.documents["doc2"] = &domain.Document{ID: "doc2", UserID: "user1"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/outline", handler.GetOutline).Methods("GET")
+
+	tests := []struct {
+		name       string
+		documentID string
+		wantStatus int
+		wantTitles []string
+	}{
+		{"with outline", "doc1", http.StatusOK, []string{"Chapter 1", "Section 1.1"}},
+		{"without outline", "doc2", http.StatusOK, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/documents/"+tt.documentID+"/outline", nil)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+
+			var resp struct {
+				DocumentID string                `json:"document_id"`
+				Outline    []domain.OutlineEntry `json:"outline"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Outline == nil {
+				t.Fatalf("expected outline array, got null")
+			}
+			if len(resp.Outline) != len(tt.wantTitles) {
+				t.Fatalf("expected %d entries, got %d", len(tt.wantTitles), len(resp.Outline))
+			}
+			for i, title := range tt.wantTitles {
+				if resp.Outline[i].Title != title {
+					t.Errorf("entry %d: expected %q, got %q", i, title, resp.Outline[i].Title)
+				}
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_VerifyAndReprocess(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Book", Metadata: domain.DocumentMetadata{Format: "pdf"}}
+	docService.documents["doc2"] = &domain.Document{ID: "doc2", UserID: "user1", Title: "Notes", Metadata: domain.DocumentMetadata{Format: "txt"}}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/verify", handler.VerifyDocument).Methods("GET")
+	router.HandleFunc("/api/v1/documents/{id}/reprocess", handler.ReprocessDocument).Methods("POST")
+
+	tests := []struct {
+		name       string
+		method     string
+		url        string
+		wantStatus int
+	}{
+		{"verify", "GET", "/api/v1/documents/doc1/verify", http.StatusOK},
+		{"verify unknown document", "GET", "/api/v1/documents/nope/verify", http.StatusNotFound},
+		{"reprocess", "POST", "/api/v1/documents/doc1/reprocess", http.StatusOK},
+		{"reprocess text file", "POST", "/api/v1/documents/doc2/reprocess", http.StatusUnprocessableEntity},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.url, nil)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("Expected status code %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_GetPlainText(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/plaintext", handler.GetPlainText).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/v1/documents/doc1/plaintext", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d", http.StatusOK, rr.Code)
+	}
+	if got := rr.Header().Get("Content-Type"); got != "text/plain; charset=utf-8" {
+		t.Errorf("Content-Type = %q", got)
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Disposition"), "attachment;") {
+		t.Errorf("Content-Disposition = %q", rr.Header().Get("Content-Disposition"))
+	}
+}
+
+func TestDocumentHandler_FindInDocument(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v2/documents/{id}/find", handler.FindInDocument).Methods("GET")
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/api/v2/documents/doc1/find?q=whale&limit=2&offset=1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("Expected status code %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Data []domain.DocumentMatch `json:"data"`
+		Meta ResponseMeta           `json:"meta"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 2 || body.Data[0].PageNumber != 2 || body.Data[0].Snippet != "a whale b" {
+		t.Errorf("unexpected matches: %+v", body.Data)
+	}
+	if p := body.Meta.Pagination; p == nil || p.Total != 3 || p.HasMore {
+		t.Errorf("unexpected pagination: %+v", body.Meta.Pagination)
+	}
+
+	for path, want := range map[string]int{
+		"/api/v2/documents/doc1/find":                 http.StatusBadRequest,
+		"/api/v2/documents/doc1/find?q=whale&limit=0": http.StatusBadRequest,
+		"/api/v2/documents/nope/find?q=whale":         http.StatusNotFound,
+	} {
+		if rr := serve(path); rr.Code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, rr.Code)
+		}
+	}
+
+	// The v1 documents API the clients use serves it too.
+	v1 := NewRouter(withTestPrincipal, nil, Guards{}, handler)
+	rr = httptest.NewRecorder()
+	v1.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/documents/doc1/find?q=whale", nil))
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"snippet":"a whale b"`) {
+		t.Errorf("v1 find: %d %s", rr.Code, rr.Body.String())
+	}
+}
+
+func TestDocumentHandler_ServeFile(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes",
+		Metadata: domain.DocumentMetadata{SHA256: "abc123"}}
+	docService.files["doc1"] = []byte("%PDF-1.7 hello world")
+	docService.documents["gone"] = &domain.Document{ID: "gone", UserID: "user1", Title: "Gone"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/files/{documentId}", handler.ServeFile).Methods("GET", "HEAD")
+	serve := func(path, user, rangeHeader string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req = createContextWithPrincipal(req, testHandlerPrincipal(user))
+		if rangeHeader != "" {
+			req.Header.Set("Range", rangeHeader)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve("/api/v1/files/doc1", "user1", "")
+	if rr.Code != http.StatusOK || rr.Body.String() != "%PDF-1.7 hello world" {
+		t.Fatalf("full file: %d %q", rr.Code, rr.Body.String())
+	}
+	if rr.Header().Get("Content-Type") != "application/pdf" || rr.Header().Get("ETag") != `"abc123"` || rr.Header().Get("Accept-Ranges") != "bytes" {
+		t.Errorf("unexpected headers: %v", rr.Header())
+	}
+	if !strings.HasPrefix(rr.Header().Get("Content-Disposition"), "inline;") {
+		t.Errorf("Content-Disposition = %q", rr.Header().Get("Content-Disposition"))
+	}
+
+	rr = serve("/api/v1/files/doc1", "user1", "bytes=9-13")
+	if rr.Code != http.StatusPartialContent || rr.Body.String() != "hello" {
+		t.Fatalf("range: %d %q", rr.Code, rr.Body.String())
+	}
+	if got := rr.Header().Get("Content-Range"); got != "bytes 9-13/20" {
+		t.Errorf("Content-Range = %q", got)
+	}
+
+	if rr := serve("/api/v1/files/doc1", "user2", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("other user: expected 404, got %d", rr.Code)
+	}
+	if rr := serve("/api/v1/files/gone", "user1", ""); rr.Code != http.StatusNotFound {
+		t.Errorf("missing original: expected 404, got %d", rr.Code)
+	}
+}
+
+func TestRequestedRangeEnd(t *testing.T) {
+	tests := []struct {
+		header string
+		want   int64
+		ok     bool
+	}{
+		{"bytes=9-13", 14, true},
+		{"bytes=0-99,200-299", 300, true},
+		{"bytes=500-", 1000, true},
+		{"bytes=-100", 1000, true},
+		{"bytes=900-5000", 1000, true},
+		{"", 0, false},
+		{"items=0-9", 0, false},
+		{"bytes=x-y", 0, false},
+	}
+	for _, tt := range tests {
+		end, ok := requestedRangeEnd(tt.header, 1000)
+		if end != tt.want || ok != tt.ok {
+			t.Errorf("requestedRangeEnd(%q) = %d, %v; want %d, %v", tt.header, end, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestDocumentHandler_ExportDocument(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/export", handler.ExportDocument).Methods("POST")
+
+	tests := []struct {
+		name       string
+		url        string
+		wantStatus int
+	}{
+		{"txt", "/api/v1/documents/doc1/export?format=txt", http.StatusOK},
+		{"missing format", "/api/v1/documents/doc1/export", http.StatusBadRequest},
+		{"unknown document", "/api/v1/documents/nope/export?format=pdf", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.url, nil)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("Expected status code %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="document.txt"` {
+				t.Errorf("Content-Disposition = %q", got)
+			}
+			if rr.Body.String() != "Notes" {
+				t.Errorf("body = %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_CompareDocuments(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.documents["v1"] = &domain.Document{ID: "v1", UserID: "user1", Content: json.RawMessage(`[]`)}
+	docService.documents["v2"] = &domain.Document{ID: "v2", UserID: "user1", Content: json.RawMessage(`[]`)}
+	docService.documents["txt"] = &domain.Document{ID: "txt", UserID: "user1"}
+
+	// Route through NewRouter so /documents/compare is not captured by /documents/{id}.
+	withPrincipal := func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, createContextWithPrincipal(r, testHandlerPrincipal("user1")))
+		})
+	}
+	router := NewRouter(
+		withPrincipal,
+		nil,
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, nil, nil),
+		NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"valid", `{"left_document_id":"v1","right_document_id":"v2"}`, http.StatusOK},
+		{"missing id", `{"left_document_id":"v1"}`, http.StatusBadRequest},
+		{"same document", `{"left_document_id":"v1","right_document_id":"v1"}`, http.StatusBadRequest},
+		{"invalid body", `not json`, http.StatusBadRequest},
+		{"no text", `{"left_document_id":"v1","right_document_id":"txt"}`, http.StatusUnprocessableEntity},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/documents/compare", strings.NewReader(tt.body))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var comparison domain.DocumentComparison
+			if err := json.Unmarshal(rr.Body.Bytes(), &comparison); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if comparison.LeftDocumentID != "v1" || len(comparison.Pages) != 1 || comparison.Pages[0].Changes[0].Type != domain.ChangeReplace {
+				t.Fatalf("unexpected comparison: %+v", comparison)
+			}
+		})
+	}
+
+	// The GET form takes the IDs in the query.
+	for target, want := range map[string]int{
+		"/api/v1/documents/compare?left_document_id=v1&right_document_id=v2": http.StatusOK,
+		"/api/v1/documents/compare?left_document_id=v1":                      http.StatusBadRequest,
+		"/api/v1/documents/compare?left_document_id=v1&right_document_id=v1": http.StatusBadRequest,
+	} {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, target, nil))
+		if rr.Code != want {
+			t.Errorf("GET %s: expected status %d, got %d: %s", target, want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestDocumentHandler_GetReferences(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["paper"] = &domain.Document{
+		ID:     "paper",
+		UserID: "user1",
+		Metadata: domain.DocumentMetadata{References: []domain.Reference{
+			{Number: 1, Raw: "J. Ba. Layer normalization. 2016.", Title: "Layer normalization", Year: 2016},
+		}},
+	}
+
+	req := httptest.NewRequest("GET", "/api/v1/documents/paper/references", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/references", handler.GetReferences).Methods("GET")
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		DocumentID string             `json:"document_id"`
+		References []domain.Reference `json:"references"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.DocumentID != "paper" || len(resp.References) != 1 || resp.References[0].Title != "Layer normalization" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDocumentHandler_GetPageImage(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["comic1"] = &domain.Document{ID: "comic1", UserID: "user1"}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/pages/{page}", handler.GetPageImage).Methods("GET")
+
+	tests := []struct {
+		name       string
+		page       string
+		wantStatus int
+	}{
+		{"valid page", "2", http.StatusOK},
+		{"page out of range", "3", http.StatusNotFound},
+		{"zero page", "0", http.StatusBadRequest},
+		{"non-numeric page", "cover", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/v1/documents/comic1/pages/"+tt.page, nil)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var img domain.PageImage
+			if err := json.Unmarshal(rr.Body.Bytes(), &img); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if img.Page != 2 || img.URL == "" {
+				t.Fatalf("unexpected page image: %+v", img)
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_ListVersions(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Current"}
+	docService.versions["doc1"] = []*domain.DocumentVersion{
+		{DocumentID: "doc1", Version: 2, Title: "Second", Reason: domain.VersionReasonUpdate},
+		{DocumentID: "doc1", Version: 1, Title: "First", Reason: domain.VersionReasonUpdate},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/versions", handler.ListVersions).Methods("GET")
+
+	req := httptest.NewRequest("GET", "/api/v1/documents/doc1/versions", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var resp struct {
+		DocumentID string                   `json:"document_id"`
+		Versions   []domain.DocumentVersion `json:"versions"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to unmarshal response: %v", err)
+	}
+	if resp.DocumentID != "doc1" || len(resp.Versions) != 2 || resp.Versions[0].Version != 2 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDocumentHandler_RestoreVersion(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Current"}
+	docService.versions["doc1"] = []*domain.DocumentVersion{
+		{DocumentID: "doc1", Version: 1, Title: "First", Content: json.RawMessage(`[]`)},
+	}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}/versions/{version}/restore", handler.RestoreVersion).Methods("POST")
+
+	tests := []struct {
+		name       string
+		version    string
+		wantStatus int
+	}{
+		{"existing version", "1", http.StatusOK},
+		{"missing version", "7", http.StatusNotFound},
+		{"zero version", "0", http.StatusBadRequest},
+		{"non-numeric version", "latest", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/v1/documents/doc1/versions/"+tt.version+"/restore", nil)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var doc domain.Document
+			if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if doc.Title != "First" {
+				t.Fatalf("expected restored title %q, got %q", "First", doc.Title)
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_SearchDocuments_Routes(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user-1", Title: "Moby-Dick; or, The Whale"}
+	router := NewRouter(withTestPrincipal, nil, Guards{}, NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger()))
+
+	// /documents/search must not be taken for a document with the ID "search".
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/documents/search?q=whale", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var docs []*domain.Document
+	if err := json.NewDecoder(rr.Body).Decode(&docs); err != nil {
+		t.Fatal(err)
+	}
+	if len(docs) != 1 || docs[0].ID != "doc1" {
+		t.Errorf("expected the search result, got %+v", docs)
+	}
+}
+
 func TestDocumentHandler_SearchDocuments(t *testing.T) {
 	docService := NewMockDocumentService()
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test documents
 	doc1 := &domain.Document{
@@ -304,7 +1201,7 @@ func TestDocumentHandler_SearchDocuments(t *testing.T) {
 	req := httptest.NewRequest("GET", "/api/v1/documents/search?q=Go", nil)
 	user := &domain.SupabaseUser{ID: "user1", Email: "test@example.com"}
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -335,6 +1232,14 @@ func TestDocumentHandler_SearchDocuments(t *testing.T) {
 	if docs[0].ID != "doc1" {
 		t.Errorf("Expected document ID 'doc1', got '%s'", docs[0].ID)
 	}
+
+	req = httptest.NewRequest("GET", "/api/v1/documents/search?q=title:/(/", nil)
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("Expected status code %d for an invalid pattern, got %d", http.StatusBadRequest, rr.Code)
+	}
 }
 
 func TestDocumentHandler_SetFavorite(t *testing.T) {
@@ -342,7 +1247,7 @@ func TestDocumentHandler_SetFavorite(t *testing.T) {
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -361,7 +1266,7 @@ func TestDocumentHandler_SetFavorite(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/api/v1/documents/doc1/favorite", bytes.NewReader(body))
 	user := &domain.SupabaseUser{ID: "user1", Email: "test@example.com"}
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -384,12 +1289,62 @@ func TestDocumentHandler_SetFavorite(t *testing.T) {
 	}
 }
 
+func TestDocumentHandler_UpdateDocument_Precondition(t *testing.T) {
+	docService := NewMockDocumentService()
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 123456000, time.UTC)
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Original", UpdatedAt: updatedAt}
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/documents/{id}", handler.UpdateDocument).Methods("PUT")
+
+	tests := []struct {
+		name       string
+		ifMatch    string
+		wantStatus int
+	}{
+		{"stale etag", etagFor(updatedAt.Add(-time.Minute)), http.StatusConflict},
+		{"malformed etag", "yesterday", http.StatusBadRequest},
+		{"current etag", etagFor(updatedAt), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/v1/documents/doc1", strings.NewReader(`{"title":"Mine"}`))
+			req.Header.Set("If-Match", tt.ifMatch)
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+			router.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusConflict {
+				return
+			}
+
+			var resp struct {
+				Current domain.Document `json:"current"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to unmarshal response: %v", err)
+			}
+			if resp.Current.Title != "Original" {
+				t.Fatalf("expected current title %q, got %q", "Original", resp.Current.Title)
+			}
+			if got := rr.Header().Get("ETag"); got != etagFor(updatedAt) {
+				t.Fatalf("expected ETag %s, got %s", etagFor(updatedAt), got)
+			}
+		})
+	}
+}
+
 func TestDocumentHandler_UpdateDocument(t *testing.T) {
 	docService := NewMockDocumentService()
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -408,7 +1363,7 @@ func TestDocumentHandler_UpdateDocument(t *testing.T) {
 	req := httptest.NewRequest("PUT", "/api/v1/documents/doc1", bytes.NewReader(body))
 	user := &domain.SupabaseUser{ID: "user1", Email: "test@example.com"}
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -442,7 +1397,7 @@ func TestDocumentHandler_DeleteDocument(t *testing.T) {
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -454,7 +1409,7 @@ func TestDocumentHandler_DeleteDocument(t *testing.T) {
 
 	// Create request
 	req := httptest.NewRequest("DELETE", "/api/v1/documents/doc1", nil)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.Principal{UserID: "user1", Token: "test-token"})
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -482,13 +1437,13 @@ func TestDocumentHandler_GetDocumentTags(t *testing.T) {
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
 
-	handler := NewDocumentHandler(docService, prefService, logger)
+	handler := NewDocumentHandler(docService, prefService, nil, logger)
 
 	// Create request
 	req := httptest.NewRequest("GET", "/api/v1/documents/tags", nil)
 	user := &domain.SupabaseUser{ID: "user1", Email: "test@example.com"}
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "test-token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "test-token"))
 
 	// Create response recorder
 	rr := httptest.NewRecorder()
@@ -516,3 +1471,316 @@ func TestDocumentHandler_GetDocumentTags(t *testing.T) {
 		t.Errorf("Expected 2 tags, got %d", len(tags))
 	}
 }
+
+func TestDocumentHandler_UploadDocument_TooLarge(t *testing.T) {
+	tests := []struct {
+		name      string
+		limits    domain.UploadLimits
+		fileSize  int
+		wantLimit int64
+	}{
+		{
+			name:      "format limit",
+			limits:    domain.UploadLimits{PerFormat: map[string]int64{"pdf": 10}},
+			fileSize:  20,
+			wantLimit: 10,
+		},
+		{
+			name:      "body exceeds plan limit",
+			limits:    domain.UploadLimits{MaxFileSize: 1},
+			fileSize:  multipartOverheadBytes + 1024,
+			wantLimit: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docService := NewMockDocumentService()
+			docService.uploadLimits = tt.limits
+			handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+
+			var body bytes.Buffer
+			mw := multipart.NewWriter(&body)
+			part, err := mw.CreateFormFile("file", "book.pdf")
+			if err != nil {
+				t.Fatalf("create form file: %v", err)
+			}
+			_, _ = part.Write(bytes.Repeat([]byte("a"), tt.fileSize))
+			_ = mw.Close()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", &body)
+			req.Header.Set("Content-Type", mw.FormDataContentType())
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+			rr := httptest.NewRecorder()
+
+			handler.UploadDocument(rr, req)
+
+			if rr.Code != http.StatusRequestEntityTooLarge {
+				t.Fatalf("expected status %d, got %d: %s", http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+			}
+			var resp struct {
+				LimitBytes int64  `json:"limit_bytes"`
+				Plan       string `json:"plan"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("unmarshal response: %v", err)
+			}
+			if resp.LimitBytes != tt.wantLimit || resp.Plan != "free" {
+				t.Fatalf("unexpected limit response: %+v", resp)
+			}
+			if len(docService.documents) != 0 {
+				t.Fatalf("expected no document to be uploaded")
+			}
+		})
+	}
+}
+
+func TestDocumentHandler_UploadProgress(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.uploads["busy"] = &domain.UploadProgress{ID: "busy", UserID: "user1", Status: domain.UploadStatusReceiving}
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+
+	upload := func(uploadID string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		part, err := mw.CreateFormFile("file", "book.pdf")
+		if err != nil {
+			t.Fatalf("create form file: %v", err)
+		}
+		_, _ = part.Write(bytes.Repeat([]byte("a"), 64<<10))
+		_ = mw.Close()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		req.Header.Set(uploadIDHeader, uploadID)
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+		rr := httptest.NewRecorder()
+		handler.UploadDocument(rr, req)
+		return rr
+	}
+	progress := func(principal domain.Principal, uploadID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/uploads/"+uploadID+"/progress", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": uploadID})
+		req = createContextWithPrincipal(req, principal)
+		rr := httptest.NewRecorder()
+		handler.GetUploadProgress(rr, req)
+		return rr
+	}
+
+	rr := upload("upload-1")
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	rr = progress(testHandlerPrincipal("user1"), "upload-1")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	var got domain.UploadProgress
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if got.Status != domain.UploadStatusDone || got.DocumentID != "new-doc-id" {
+		t.Errorf("status = %q, document = %q", got.Status, got.DocumentID)
+	}
+	if got.TotalBytes == 0 || got.BytesReceived != got.TotalBytes {
+		t.Errorf("received %d of %d bytes, want the whole body", got.BytesReceived, got.TotalBytes)
+	}
+
+	if rr := progress(testHandlerPrincipal("user2"), "upload-1"); rr.Code != http.StatusNotFound {
+		t.Errorf("other user: expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+	if rr := upload("busy"); rr.Code != http.StatusConflict {
+		t.Errorf("session in progress: expected status %d, got %d", http.StatusConflict, rr.Code)
+	}
+	if rr := upload("not/valid"); rr.Code != http.StatusBadRequest {
+		t.Errorf("invalid ID: expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+}
+
+func TestDocumentHandler_UploadDocument_DamagedPDF(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.uploadErr = &domain.PDFValidationError{Code: domain.PDFErrorCorrupt, Message: "This PDF appears damaged and cannot be opened"}
+	handler := NewDocumentHandler(docService, NewMockUserPreferencesService(), nil, NewMockHandlerLogger())
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "book.pdf")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = part.Write([]byte("%PDF-1.7\n"))
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/documents", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+
+	handler.UploadDocument(rr, req)
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status %d, got %d", http.StatusUnprocessableEntity, rr.Code)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal response: %v", err)
+	}
+	if resp["code"] != domain.PDFErrorCorrupt || resp["error"] == "" {
+		t.Fatalf("unexpected response: %v", resp)
+	}
+}
+
+func testHandlerPrincipal(userID string) domain.Principal {
+	return domain.Principal{UserID: userID, Token: "test-token"}
+}
+
+type MockSavedSearchService struct {
+	documents *MockDocumentService
+	searches  map[string]*domain.SavedSearch // Keyed by ID
+}
+
+func NewMockSavedSearchService(documents *MockDocumentService) *MockSavedSearchService {
+	return &MockSavedSearchService{documents: documents, searches: make(map[string]*domain.SavedSearch)}
+}
+
+func (m *MockSavedSearchService) ListSavedSearches(ctx context.Context, principal domain.Principal) ([]*domain.SavedSearch, error) {
+	searches := []*domain.SavedSearch{}
+	for _, search := range m.searches {
+		searches = append(searches, search)
+	}
+	return searches, nil
+}
+
+func (m *MockSavedSearchService) GetSavedSearch(ctx context.Context, principal domain.Principal, searchID string) (*domain.SavedSearch, error) {
+	if search, ok := m.searches[searchID]; ok {
+		return search, nil
+	}
+	return nil, domain.ErrSavedSearchNotFound
+}
+
+func (m *MockSavedSearchService) CreateSavedSearch(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+	for _, saved := range m.searches {
+		if saved.Name == search.Name {
+			return nil, domain.ErrSavedSearchExists
+		}
+	}
+	search.ID = "search-" + strings.ToLower(search.Name)
+	search.UserID = principal.UserID
+	m.searches[search.ID] = search
+	return search, nil
+}
+
+func (m *MockSavedSearchService) UpdateSavedSearch(ctx context.Context, principal domain.Principal, searchID string, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	if _, ok := m.searches[searchID]; !ok {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+	search.ID = searchID
+	m.searches[searchID] = search
+	return search, nil
+}
+
+func (m *MockSavedSearchService) DeleteSavedSearch(ctx context.Context, principal domain.Principal, searchID string) error {
+	if _, ok := m.searches[searchID]; !ok {
+		return domain.ErrSavedSearchNotFound
+	}
+	delete(m.searches, searchID)
+	return nil
+}
+
+func (m *MockSavedSearchService) CollectionDocuments(ctx context.Context, principal domain.Principal, searchID string, params url.Values) ([]*domain.Document, error) {
+	search, err := m.GetSavedSearch(ctx, principal, searchID)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := search.LibraryQuery(params)
+	if err != nil {
+		return nil, err
+	}
+	documents, err := m.documents.SearchDocuments(ctx, principal, search.Query)
+	if err != nil {
+		return nil, err
+	}
+	return filter.Apply(documents), nil
+}
+
+func TestDocumentHandler_SavedSearches(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user-1", Title: "The Go Programming Language"}
+	docService.documents["doc2"] = &domain.Document{ID: "doc2", UserID: "user-1", Title: "Concurrency in Go", IsArchived: true}
+	docService.documents["doc3"] = &domain.Document{ID: "doc3", UserID: "user-1", Title: "The Rust Book"}
+	savedSearches := NewMockSavedSearchService(docService)
+	router := NewRouter(withTestPrincipal, nil, Guards{}, NewDocumentHandler(docService, NewMockUserPreferencesService(), savedSearches, NewMockHandlerLogger()))
+
+	send := func(method, target, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := send(http.MethodPost, "/api/v1/saved-searches", `{"name":"Go","query":"title:go","filters":{"archived":"all","sort":"title"}}`)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var created domain.SavedSearch
+	if err := json.NewDecoder(rr.Body).Decode(&created); err != nil {
+		t.Fatal(err)
+	}
+
+	for body, want := range map[string]int{
+		`{"name":"Go","query":"golang"}`:                     http.StatusConflict,
+		`{"name":"Broken","query":"(go"}`:                    http.StatusBadRequest,
+		`{"name":"Sorted","filters":{"sort":"by-size"}}`:     http.StatusBadRequest,
+		`{"name":"Typed","filters":{"archived":true}}`:       http.StatusBadRequest,
+		`{"name":"Everything","filters":{"archived":"all"}}`: http.StatusCreated,
+	} {
+		if rr := send(http.MethodPost, "/api/v1/saved-searches", body); rr.Code != want {
+			t.Errorf("%s: expected %d, got %d: %s", body, want, rr.Code, rr.Body.String())
+		}
+	}
+
+	// The listing evaluates the collection, with the saved filters and order.
+	rr = send(http.MethodGet, "/api/v2/documents?collection=smart:"+created.ID, "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var page struct {
+		Data []domain.Document `json:"data"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&page); err != nil {
+		t.Fatal(err)
+	}
+	if len(page.Data) != 2 || page.Data[0].ID != "doc2" || page.Data[1].ID != "doc1" {
+		t.Errorf("expected both Go books by title, got %+v", page.Data)
+	}
+
+	for target, want := range map[string]int{
+		"/api/v1/documents/user/user-1?collection=smart:" + created.ID: http.StatusOK,
+		"/api/v1/documents/library?collection=smart:" + created.ID:     http.StatusOK,
+		"/api/v2/documents?collection=smart:missing":                   http.StatusNotFound,
+		"/api/v2/documents?collection=shelf:1":                         http.StatusBadRequest,
+		"/api/v1/saved-searches/" + created.ID:                         http.StatusOK,
+		"/api/v1/saved-searches/missing":                               http.StatusNotFound,
+	} {
+		if rr := send(http.MethodGet, target, ""); rr.Code != want {
+			t.Errorf("GET %s: expected %d, got %d: %s", target, want, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr = send(http.MethodPut, "/api/v1/saved-searches/"+created.ID, `{"name":"Rust","query":"rust"}`)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if rr := send(http.MethodDelete, "/api/v1/saved-searches/"+created.ID, ""); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rr.Code)
+	}
+	if rr := send(http.MethodGet, "/api/v2/documents?collection=smart:"+created.ID, ""); rr.Code != http.StatusNotFound {
+		t.Errorf("expected 404 for a deleted collection, got %d", rr.Code)
+	}
+}
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+
+	"github.com/google/uuid"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// requestIDHeader carries the ID of a request, echoed back so clients and logs can
+// refer to it. A valid ID sent by the client is kept.
+const requestIDHeader = "X-Request-ID"
+
+var requestIDPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Page sizes of the /api/v2 list endpoints.
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// ListResponse is the body of /api/v2 list endpoints: the page of items under data,
+// and how it was cut from the full list under meta.
+type ListResponse struct {
+	Data any          `json:"data"`
+	Meta ResponseMeta `json:"meta"`
+}
+
+// ResponseMeta describes a response beyond its data.
+type ResponseMeta struct {
+	RequestID  string      `json:"request_id,omitempty"`
+	Pagination *Pagination `json:"pagination,omitempty"`
+}
+
+// Pagination describes a page of a list. Clients ask for the next page with
+// offset=Offset+Limit while HasMore is true.
+type Pagination struct {
+	Limit   int  `json:"limit"`
+	Offset  int  `json:"offset"`
+	Total   int  `json:"total"`
+	HasMore bool `json:"has_more"`
+}
+
+type requestIDKey struct{}
+
+// withRequestID assigns every request an ID, stored on its context and sent back in
+// the X-Request-ID header.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestIDHeader)
+		if !requestIDPattern.MatchString(id) {
+			id = uuid.NewString()
+		}
+		w.Header().Set(requestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestIDKey{}, id)))
+	})
+}
+
+// requestID returns the ID withRequestID gave r, or "" outside the router.
+func requestID(r *http.Request) string {
+	id, _ := r.Context().Value(requestIDKey{}).(string)
+	return id
+}
+
+// parsePagination reads limit and offset from the query, defaulting to the first
+// page of defaultPageLimit items.
+func parsePagination(query url.Values) (Pagination, error) {
+	page := Pagination{Limit: defaultPageLimit}
+	var errs domain.ValidationErrors
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit < 1 || limit > maxPageLimit {
+			errs = append(errs, &domain.ValidationError{Field: "limit", Message: "limit must be between 1 and " + strconv.Itoa(maxPageLimit)})
+		}
+		page.Limit = limit
+	}
+	if value := query.Get("offset"); value != "" {
+		offset, err := strconv.Atoi(value)
+		if err != nil || offset < 0 {
+			errs = append(errs, &domain.ValidationError{Field: "offset", Message: "offset must be a non-negative integer"})
+		}
+		page.Offset = offset
+	}
+	if len(errs) > 0 {
+		return Pagination{}, errs
+	}
+	return page, nil
+}
+
+// queryErrors combines the errors of parsing several query parameters into one
+// ValidationErrors, or returns the first error that is not a validation error.
+func queryErrors(errs ...error) error {
+	var combined domain.ValidationErrors
+	for _, err := range errs {
+		var fieldErrs domain.ValidationErrors
+		var fieldErr *domain.ValidationError
+		switch {
+		case err == nil:
+		case errors.As(err, &fieldErrs):
+			combined = append(combined, fieldErrs...)
+		case errors.As(err, &fieldErr):
+			combined = append(combined, fieldErr)
+		default:
+			return err
+		}
+	}
+	if len(combined) > 0 {
+		return combined
+	}
+	return nil
+}
+
+// writeList answers with the page of items selected by page, in a ListResponse.
+func writeList[T any](w http.ResponseWriter, r *http.Request, items []T, page Pagination) {
+	page.Total = len(items)
+	start := min(page.Offset, len(items))
+	end := min(start+page.Limit, len(items))
+	page.HasMore = end < len(items)
+
+	data := items[start:end:end]
+	if data == nil {
+		data = []T{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(ListResponse{
+		Data: data,
+		Meta: ResponseMeta{RequestID: requestID(r), Pagination: &page},
+	})
+}
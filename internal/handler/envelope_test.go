@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestParsePagination(t *testing.T) {
+	tests := []struct {
+		query   string
+		want    Pagination
+		wantErr bool
+	}{
+		{query: "", want: Pagination{Limit: defaultPageLimit}},
+		{query: "limit=10&offset=20", want: Pagination{Limit: 10, Offset: 20}},
+		{query: "limit=0", wantErr: true},
+		{query: "limit=1000", wantErr: true},
+		{query: "offset=-1", wantErr: true},
+		{query: "offset=abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		values, _ := url.ParseQuery(tt.query)
+		got, err := parsePagination(values)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%q: err = %v, wantErr %v", tt.query, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%q: got %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestNewRouter_V2ListEnvelope(t *testing.T) {
+	router := NewRouter(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, createContextWithPrincipal(r, testHandlerPrincipal("user1")))
+		})
+	}, nil, Guards{}, NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v2/document-tags?limit=1", nil)
+	req.Header.Set(requestIDHeader, "req-123")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rr.Code, rr.Body.String())
+	}
+	var body struct {
+		Data []string     `json:"data"`
+		Meta ResponseMeta `json:"meta"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if len(body.Data) != 1 || body.Data[0] != "programming" {
+		t.Errorf("data = %v, want the first tag only", body.Data)
+	}
+	want := Pagination{Limit: 1, Offset: 0, Total: 2, HasMore: true}
+	if body.Meta.Pagination == nil || *body.Meta.Pagination != want {
+		t.Errorf("pagination = %+v, want %+v", body.Meta.Pagination, want)
+	}
+	if body.Meta.RequestID != "req-123" || rr.Header().Get(requestIDHeader) != "req-123" {
+		t.Errorf("request id = %q (header %q), want the client's", body.Meta.RequestID, rr.Header().Get(requestIDHeader))
+	}
+
+	// Past the end the page is empty, not null, and an invalid ID is replaced.
+	req = httptest.NewRequest(http.MethodGet, "/api/v2/document-tags?offset=5", nil)
+	req.Header.Set(requestIDHeader, "not a valid id")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), `"data":[]`) {
+		t.Errorf("expected an empty page, got %s", rr.Body.String())
+	}
+	if id := rr.Header().Get(requestIDHeader); id == "" || id == "not a valid id" {
+		t.Errorf("expected a generated request id, got %q", id)
+	}
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v2/documents?limit=0&sort=size", nil))
+	var invalid struct {
+		Fields []struct{ Field string } `json:"fields"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &invalid); err != nil || rr.Code != http.StatusBadRequest || len(invalid.Fields) != 2 {
+		t.Errorf("expected both invalid parameters to be reported, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -2,7 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
 
 	"pdf-text-reader/internal/config"
 	"pdf-text-reader/internal/domain"
@@ -15,6 +18,7 @@ type HighlightHandler struct {
 	container        *config.Container
 	logger           domain.Logger
 	highlightService domain.HighlightService
+	shareCardService domain.ShareCardService
 }
 
 func NewHighlightHandler(container *config.Container, logger domain.Logger) *HighlightHandler {
@@ -22,52 +26,72 @@ func NewHighlightHandler(container *config.Container, logger domain.Logger) *Hig
 		container:        container,
 		logger:           logger,
 		highlightService: container.HighlightService,
+		shareCardService: container.ShareCardService,
 	}
 }
 
+// RegisterRoutes adds the highlight endpoints.
+func (h *HighlightHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	r.HandleFunc("/highlights", h.ListHighlights).Methods(http.MethodGet)
+	r.HandleFunc("/highlights", h.CreateHighlight).Methods(http.MethodPost)
+	r.HandleFunc("/highlights/search", h.SearchHighlights).Methods(http.MethodGet)
+
+	// Daily review of past highlights
+	r.HandleFunc("/highlights/review", h.GetReview).Methods(http.MethodGet)
+	r.HandleFunc("/highlights/{id}/review", h.ReviewHighlight).Methods(http.MethodPost)
+	r.HandleFunc("/highlights/{id}/favorite", h.SetFavorite).Methods(http.MethodPut)
+	r.HandleFunc("/highlights/{id}/share-card", h.CreateShareCard).Methods(http.MethodPost)
+	r.HandleFunc("/highlights/{id}", h.DeleteHighlight).Methods(http.MethodDelete)
+
+	// Paginated listing in the v2 envelope
+	routes.ProtectedV2.HandleFunc("/highlights", h.ListHighlightsV2).Methods(http.MethodGet)
+}
+
 type createHighlightRequest struct {
-	DocumentID string   `json:"document_id"`
-	Quote      string   `json:"quote"`
+	DocumentID string   `json:"document_id" validate:"required"`
+	Quote      string   `json:"quote" validate:"required"`
+	Note       string   `json:"note,omitempty"`
 	PageNumber *int     `json:"page_number,omitempty"`
 	Progress   *float32 `json:"progress,omitempty"`
+
+	Locator *domain.EPUBLocator `json:"locator,omitempty"` // EPUBs only
 }
 
 // CreateHighlight handles POST /highlights
 func (h *HighlightHandler) CreateHighlight(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
-		return
-	}
-
 	var req createHighlightRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid request body")
-		return
-	}
-	if req.DocumentID == "" {
-		h.writeError(w, http.StatusBadRequest, "document_id is required")
-		return
-	}
-	if req.Quote == "" {
-		h.writeError(w, http.StatusBadRequest, "quote is required")
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid highlight", err)
 		return
 	}
 
-	created, err := h.highlightService.CreateHighlight(user.ID, &domain.Highlight{
+	created, err := h.highlightService.CreateHighlight(r.Context(), principal, &domain.Highlight{
 		DocumentID: req.DocumentID,
 		Quote:      req.Quote,
+		Note:       req.Note,
 		PageNumber: req.PageNumber,
 		Progress:   req.Progress,
-	}, token)
+		Locator:    req.Locator,
+	})
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid highlight", "fields": validationErrs})
+		return
+	}
+	if errors.Is(err, domain.ErrAccessDenied) {
+		h.writeError(w, http.StatusForbidden, "Access denied")
+		return
+	}
 	if err != nil {
-		h.logger.Error("Failed to create highlight", err, "user_id", user.ID, "document_id", req.DocumentID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to create highlight")
+		h.logger.Error("Failed to create highlight", err, "user_id", principal.UserID, "document_id", req.DocumentID)
+		writeServerError(w, err, "Failed to create highlight")
 		return
 	}
 
@@ -76,27 +100,88 @@ func (h *HighlightHandler) CreateHighlight(w http.ResponseWriter, r *http.Reques
 
 // ListHighlights handles GET /highlights?document_id=...
 func (h *HighlightHandler) ListHighlights(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	documentID := r.URL.Query().Get("document_id")
+	var docPtr *string
+	if documentID != "" {
+		docPtr = &documentID
+	}
+
+	highlights, err := h.highlightService.ListHighlights(r.Context(), principal, docPtr)
+	if err != nil {
+		h.logger.Error("Failed to list highlights", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve highlights")
 		return
 	}
+	if highlights == nil {
+		highlights = make([]*domain.Highlight, 0)
+	}
+	h.writeJSON(w, http.StatusOK, highlights)
+}
 
-	documentID := r.URL.Query().Get("document_id")
+// ListHighlightsV2 handles GET /api/v2/highlights?document_id=...: a page of the
+// caller's highlights.
+func (h *HighlightHandler) ListHighlightsV2(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	page, err := parsePagination(r.URL.Query())
+	if err != nil {
+		writeValidationErrors(w, "Invalid query", err)
+		return
+	}
 	var docPtr *string
-	if documentID != "" {
+	if documentID := r.URL.Query().Get("document_id"); documentID != "" {
 		docPtr = &documentID
 	}
 
-	highlights, err := h.highlightService.ListHighlights(user.ID, docPtr, token)
+	highlights, err := h.highlightService.ListHighlights(r.Context(), principal, docPtr)
 	if err != nil {
-		h.logger.Error("Failed to list highlights", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve highlights")
+		h.logger.Error("Failed to list highlights", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve highlights")
+		return
+	}
+	writeList(w, r, highlights, page)
+}
+
+// SearchHighlights handles GET /highlights/search?q=...&document_id=...&tag=...&limit=N:
+// full-text search over the quotes and notes of the user's highlights.
+func (h *HighlightHandler) SearchHighlights(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	query := r.URL.Query()
+	search := domain.HighlightSearch{
+		Query:      query.Get("q"),
+		DocumentID: query.Get("document_id"),
+		Tag:        query.Get("tag"),
+	}
+	if raw := query.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+		search.Limit = n
+	}
+
+	highlights, err := h.highlightService.SearchHighlights(r.Context(), principal, search)
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid search", "fields": validationErrs})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to search highlights", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to search highlights")
 		return
 	}
 	if highlights == nil {
@@ -105,19 +190,136 @@ func (h *HighlightHandler) ListHighlights(w http.ResponseWriter, r *http.Request
 	h.writeJSON(w, http.StatusOK, highlights)
 }
 
-// DeleteHighlight handles DELETE /highlights/{id}
-func (h *HighlightHandler) DeleteHighlight(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// GetReview handles GET /highlights/review?limit=N: today's highlights to review.
+func (h *HighlightHandler) GetReview(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
-	token, ok := GetTokenFromContext(r)
+	size := domain.DefaultHighlightReviewSize
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "limit must be a number")
+			return
+		}
+		size = n
+	}
+
+	review, err := h.highlightService.GetReview(r.Context(), principal, size)
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid review request", "fields": validationErrs})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to build highlight review", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to load highlight review")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, review)
+}
+
+type reviewHighlightRequest struct {
+	Rating string `json:"rating"` // again, good (default) or easy
+}
+
+// ReviewHighlight handles POST /highlights/{id}/review: marks a highlight reviewed
+// and schedules its next review.
+func (h *HighlightHandler) ReviewHighlight(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	highlightID := mux.Vars(r)["id"]
+	// The body is optional; without one the review is rated good.
+	var req reviewHighlightRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
 		return
 	}
 
+	highlight, err := h.highlightService.ReviewHighlight(r.Context(), principal, highlightID, req.Rating)
+	if !h.writeReviewError(w, err, principal, highlightID) {
+		h.writeJSON(w, http.StatusOK, highlight)
+	}
+}
+
+type setHighlightFavoriteRequest struct {
+	IsFavorite bool `json:"is_favorite"`
+}
+
+// SetFavorite handles PUT /highlights/{id}/favorite
+func (h *HighlightHandler) SetFavorite(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	highlightID := mux.Vars(r)["id"]
+	var req setHighlightFavoriteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	highlight, err := h.highlightService.SetFavorite(r.Context(), principal, highlightID, req.IsFavorite)
+	if !h.writeReviewError(w, err, principal, highlightID) {
+		h.writeJSON(w, http.StatusOK, highlight)
+	}
+}
+
+// writeReviewError writes the response for a failed review or favorite update and
+// reports whether there was an error.
+func (h *HighlightHandler) writeReviewError(w http.ResponseWriter, err error, principal domain.Principal, highlightID string) bool {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case err == nil:
+		return false
+	case errors.As(err, &validationErrs):
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid review", "fields": validationErrs})
+	case errors.Is(err, domain.ErrHighlightNotFound):
+		h.writeError(w, http.StatusNotFound, "Highlight not found")
+	default:
+		h.logger.Error("Failed to update highlight review", err, "user_id", principal.UserID, "highlight_id", highlightID)
+		writeServerError(w, err, "Failed to update highlight")
+	}
+	return true
+}
+
+// CreateShareCard handles POST /highlights/{id}/share-card: renders the quote and
+// book title into a PNG and returns a URL anyone can open.
+func (h *HighlightHandler) CreateShareCard(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	highlightID := mux.Vars(r)["id"]
+
+	card, err := h.shareCardService.CreateShareCard(r.Context(), principal, highlightID)
+	switch {
+	case errors.Is(err, domain.ErrHighlightNotFound), errors.Is(err, domain.ErrDocumentNotFound):
+		h.writeError(w, http.StatusNotFound, "Highlight not found")
+	case errors.Is(err, domain.ErrAccessDenied):
+		h.writeError(w, http.StatusForbidden, "Access denied")
+	case err != nil:
+		h.logger.Error("Failed to create share card", err, "user_id", principal.UserID, "highlight_id", highlightID)
+		writeServerError(w, err, "Failed to create share card")
+	default:
+		h.writeJSON(w, http.StatusCreated, card)
+	}
+}
+
+// DeleteHighlight handles DELETE /highlights/{id}
+func (h *HighlightHandler) DeleteHighlight(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
 	vars := mux.Vars(r)
 	highlightID := vars["id"]
 	if highlightID == "" {
@@ -125,9 +327,9 @@ func (h *HighlightHandler) DeleteHighlight(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	if err := h.highlightService.DeleteHighlight(user.ID, highlightID, token); err != nil {
-		h.logger.Error("Failed to delete highlight", err, "user_id", user.ID, "highlight_id", highlightID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to delete highlight")
+	if err := h.highlightService.DeleteHighlight(r.Context(), principal, highlightID); err != nil {
+		h.logger.Error("Failed to delete highlight", err, "user_id", principal.UserID, "highlight_id", highlightID)
+		writeServerError(w, err, "Failed to delete highlight")
 		return
 	}
 	w.WriteHeader(http.StatusNoContent)
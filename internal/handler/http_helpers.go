@@ -1,27 +1,24 @@
 package handler
 
 import (
+	"encoding/json"
+	"errors"
+	"math"
 	"net/http"
-	"pdf-text-reader/internal/domain"
-)
-
-type contextKey string
+	"strconv"
 
-const (
-	userContextKey  contextKey = "user"
-	tokenContextKey contextKey = "token"
+	"pdf-text-reader/internal/authctx"
+	"pdf-text-reader/internal/domain"
 )
 
 // GetUserFromContext extracts the authenticated user from request context
 func GetUserFromContext(r *http.Request) (*domain.SupabaseUser, bool) {
-	user, ok := r.Context().Value(userContextKey).(*domain.SupabaseUser)
-	return user, ok
+	return authctx.User(r.Context())
 }
 
-// GetTokenFromContext extracts the authentication token from request context
-func GetTokenFromContext(r *http.Request) (string, bool) {
-	token, ok := r.Context().Value(tokenContextKey).(string)
-	return token, ok
+// GetPrincipalFromContext extracts the authenticated principal (user ID + token) from request context
+func GetPrincipalFromContext(r *http.Request) (domain.Principal, bool) {
+	return authctx.Principal(r.Context())
 }
 
 // writeError writes an error response (helper function)
@@ -30,3 +27,35 @@ func writeError(w http.ResponseWriter, statusCode int, message string) {
 	w.WriteHeader(statusCode)
 	_, _ = w.Write([]byte(`{"error":"` + message + `"}`))
 }
+
+// accountDisabledCode tells clients a request was refused because the account is
+// disabled, so they can sign the user out instead of retrying.
+const accountDisabledCode = "account_disabled"
+
+// writeAccountDisabled answers a request from a disabled account with 403.
+func writeAccountDisabled(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": "Account disabled", "code": accountDisabledCode})
+}
+
+// writeServerError writes a 500 with message, unless err means Supabase is throttling
+// or unavailable: then clients get a 503 and a Retry-After header so they back off
+// instead of treating the failure as permanent. A service that refused to act for a
+// disabled account gets the same 403 as the auth middleware.
+func writeServerError(w http.ResponseWriter, err error, message string) {
+	if errors.Is(err, domain.ErrAccountDisabled) {
+		writeAccountDisabled(w)
+		return
+	}
+	status := http.StatusInternalServerError
+	var upstream *domain.UpstreamError
+	if errors.As(err, &upstream) {
+		status = http.StatusServiceUnavailable
+		message = "Service temporarily unavailable, please retry later"
+		w.Header().Set("Retry-After", strconv.Itoa(max(int(math.Ceil(upstream.RetryAfter.Seconds())), 1)))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
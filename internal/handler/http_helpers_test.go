@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
 )
 
 func TestWriteError(t *testing.T) {
@@ -21,3 +26,27 @@ func TestWriteError(t *testing.T) {
 		t.Fatalf("unexpected response body: %s", rr.Body.String())
 	}
 }
+
+func TestWriteServerError(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeServerError(rr, errors.New("boom"), "Failed to load")
+	if rr.Code != http.StatusInternalServerError || rr.Header().Get("Retry-After") != "" {
+		t.Fatalf("expected plain 500, got %d (Retry-After %q)", rr.Code, rr.Header().Get("Retry-After"))
+	}
+
+	rr = httptest.NewRecorder()
+	err := fmt.Errorf("load documents: %w", &domain.UpstreamError{RetryAfter: 2500 * time.Millisecond, Err: errors.New("() API rate limit exceeded")})
+	writeServerError(rr, err, "Failed to load")
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+	if got := rr.Header().Get("Retry-After"); got != "3" {
+		t.Fatalf("expected Retry-After 3, got %q", got)
+	}
+
+	rr = httptest.NewRecorder()
+	writeServerError(rr, fmt.Errorf("upload: %w", domain.ErrAccountDisabled), "Failed to upload")
+	if rr.Code != http.StatusForbidden || !strings.Contains(rr.Body.String(), `"code":"account_disabled"`) {
+		t.Fatalf("expected 403 with the account_disabled code, got %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,416 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"mime/multipart"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// ImportHandler handles importing documents from cloud drives and other reading apps.
+type ImportHandler struct {
+	container            *config.Container
+	logger               domain.Logger
+	cloudImportService   domain.CloudImportService
+	calibreImportService domain.CalibreImportService
+	gutenbergService     domain.GutenbergService
+	readLaterService     domain.ReadLaterImportService
+	highlightService     domain.HighlightImportService
+}
+
+func NewImportHandler(container *config.Container, logger domain.Logger) *ImportHandler {
+	return &ImportHandler{
+		container:            container,
+		logger:               logger,
+		cloudImportService:   container.CloudImportService,
+		calibreImportService: container.CalibreImportService,
+		gutenbergService:     container.GutenbergService,
+		readLaterService:     container.ReadLaterImportService,
+		highlightService:     container.HighlightImportService,
+	}
+}
+
+// RegisterRoutes adds the import endpoints.
+func (h *ImportHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	// Cloud drive and Calibre import
+	r.HandleFunc("/import/providers", h.ListProviders).Methods(http.MethodGet)
+	r.HandleFunc("/import/calibre", h.ImportCalibre).Methods(http.MethodPost)
+	r.HandleFunc("/import/{service:pocket|instapaper}", h.ImportReadLater).Methods(http.MethodPost)
+	r.HandleFunc("/import/highlights", h.ImportHighlights).Methods(http.MethodPost)
+	r.HandleFunc("/import/jobs/{id}", h.GetImportJob).Methods(http.MethodGet)
+	r.HandleFunc("/import/{provider}/auth-url", h.GetAuthURL).Methods(http.MethodGet)
+	r.HandleFunc("/import/{provider}/connect", h.Connect).Methods(http.MethodPost)
+	r.HandleFunc("/import/{provider}/connect", h.Disconnect).Methods(http.MethodDelete)
+	r.HandleFunc("/import/{provider}/files", h.ListFiles).Methods(http.MethodGet)
+	r.HandleFunc("/import/{provider}", h.StartImport).Methods(http.MethodPost)
+
+	// Project Gutenberg
+	r.HandleFunc("/catalog/search", h.SearchCatalog).Methods(http.MethodGet)
+	r.HandleFunc("/catalog/import/{gutenbergId:[0-9]+}", h.ImportGutenbergBook).Methods(http.MethodPost)
+}
+
+// ListProviders handles GET /import/providers
+func (h *ImportHandler) ListProviders(w http.ResponseWriter, r *http.Request) {
+	h.writeJSON(w, http.StatusOK, map[string][]string{"providers": h.cloudImportService.Providers()})
+}
+
+// GetAuthURL handles GET /import/{provider}/auth-url?state=...; the web app sends the
+// user there and checks the state when the provider redirects back.
+func (h *ImportHandler) GetAuthURL(w http.ResponseWriter, r *http.Request) {
+	state := r.URL.Query().Get("state")
+	if state == "" {
+		h.writeError(w, http.StatusBadRequest, "state is required")
+		return
+	}
+	authURL, err := h.cloudImportService.AuthURL(mux.Vars(r)["provider"], state)
+	if err != nil {
+		h.writeImportError(w, err, "", "Failed to start the connection")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]string{"auth_url": authURL})
+}
+
+type connectCloudRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// Connect handles POST /import/{provider}/connect with the code from the redirect
+func (h *ImportHandler) Connect(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req connectCloudRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		writeValidationErrors(w, "Invalid connection", err)
+		return
+	}
+
+	if err := h.cloudImportService.Connect(r.Context(), principal, mux.Vars(r)["provider"], req.Code); err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to connect cloud drive")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Disconnect handles DELETE /import/{provider}/connect
+func (h *ImportHandler) Disconnect(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	if err := h.cloudImportService.Disconnect(r.Context(), principal, mux.Vars(r)["provider"]); err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to disconnect cloud drive")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListFiles handles GET /import/{provider}/files?q=...
+func (h *ImportHandler) ListFiles(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	files, err := h.cloudImportService.ListFiles(r.Context(), principal, mux.Vars(r)["provider"], r.URL.Query().Get("q"))
+	if err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to list cloud files")
+		return
+	}
+	if files == nil {
+		files = make([]domain.CloudFile, 0)
+	}
+	h.writeJSON(w, http.StatusOK, files)
+}
+
+type startImportRequest struct {
+	FileIDs []string `json:"file_ids"`
+}
+
+// StartImport handles POST /import/{provider}; the files are transferred in the
+// background and the job is returned right away.
+func (h *ImportHandler) StartImport(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req startImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	job, err := h.cloudImportService.StartImport(r.Context(), principal, mux.Vars(r)["provider"], req.FileIDs)
+	if err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to start import")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/import/jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// ImportCalibre handles POST /import/calibre with a zip of the Calibre library folder
+// as the "file" form field. With ?dry_run=true it only returns what would be imported;
+// otherwise the books are imported in the background and the job is returned.
+func (h *ImportHandler) ImportCalibre(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxCalibreArchiveSize+multipartOverheadBytes)
+	err := r.ParseMultipartForm(uploadFormMemoryBytes)
+	var file multipart.File
+	if err == nil {
+		file, _, err = r.FormFile("file")
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "Library export is too large")
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	if dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dry_run")); dryRun {
+		preview, err := h.calibreImportService.Preview(r.Context(), principal, file)
+		if err != nil {
+			h.writeImportError(w, err, principal.UserID, "Failed to read Calibre library")
+			return
+		}
+		h.writeJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	job, err := h.calibreImportService.Import(r.Context(), principal, file)
+	if err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to start import")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/import/jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// ImportReadLater handles POST /import/pocket and POST /import/instapaper with the
+// service's export file as the "file" form field. The saved articles are fetched in
+// the background and the job is returned.
+func (h *ImportHandler) ImportReadLater(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxReadLaterExportSize+multipartOverheadBytes)
+	err := r.ParseMultipartForm(uploadFormMemoryBytes)
+	var file multipart.File
+	if err == nil {
+		file, _, err = r.FormFile("file")
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "Export is too large")
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	job, err := h.readLaterService.Import(r.Context(), principal, mux.Vars(r)["service"], file)
+	switch {
+	case errors.Is(err, domain.ErrInvalidFile):
+		h.writeError(w, http.StatusBadRequest, "Not a Pocket or Instapaper export")
+		return
+	case errors.Is(err, domain.ErrFileTooLarge):
+		h.writeError(w, http.StatusRequestEntityTooLarge, "Export is too large")
+		return
+	case err != nil:
+		h.writeImportError(w, err, principal.UserID, "Failed to start import")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/import/jobs/"+job.ID)
+	h.writeJSON(w, http.StatusAccepted, job)
+}
+
+// ImportHighlights handles POST /import/highlights with a Kindle "My Clippings.txt"
+// or a Kobo annotation export as the "file" form field. The highlights are added to
+// the matching library documents and the result lists every book of the export.
+func (h *ImportHandler) ImportHighlights(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxHighlightExportSize+multipartOverheadBytes)
+	err := r.ParseMultipartForm(uploadFormMemoryBytes)
+	var file multipart.File
+	if err == nil {
+		file, _, err = r.FormFile("file")
+	}
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "Export is too large")
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "File is required")
+		return
+	}
+	defer file.Close()
+
+	result, err := h.highlightService.ImportHighlights(r.Context(), principal, file)
+	switch {
+	case errors.Is(err, domain.ErrInvalidFile):
+		h.writeError(w, http.StatusBadRequest, "Not a Kindle or Kobo highlights export")
+		return
+	case errors.Is(err, domain.ErrFileTooLarge):
+		h.writeError(w, http.StatusRequestEntityTooLarge, "Export is too large")
+		return
+	case err != nil:
+		h.writeImportError(w, err, principal.UserID, "Failed to import highlights")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// GetImportJob handles GET /import/jobs/{id}
+func (h *ImportHandler) GetImportJob(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	job, err := h.cloudImportService.GetImportJob(r.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		h.writeImportError(w, err, principal.UserID, "Failed to get import job")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, job)
+}
+
+// SearchCatalog handles GET /catalog/search?q=...&page=...: public-domain books from
+// Project Gutenberg, most downloaded first.
+func (h *ImportHandler) SearchCatalog(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	page := 1
+	if raw := r.URL.Query().Get("page"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, "page must be a number")
+			return
+		}
+		page = n
+	}
+
+	result, err := h.gutenbergService.SearchCatalog(r.Context(), r.URL.Query().Get("q"), page)
+	if err != nil {
+		h.writeGutenbergError(w, err, principal.UserID, "Failed to search Project Gutenberg")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, result)
+}
+
+// ImportGutenbergBook handles POST /catalog/import/{gutenbergId}: the book's EPUB is
+// downloaded from a Gutenberg mirror and added to the library.
+func (h *ImportHandler) ImportGutenbergBook(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	id, err := strconv.Atoi(mux.Vars(r)["gutenbergId"])
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid Gutenberg ID")
+		return
+	}
+
+	doc, err := h.gutenbergService.ImportBook(r.Context(), principal, id)
+	if err != nil {
+		h.writeGutenbergError(w, err, principal.UserID, "Failed to import book")
+		return
+	}
+	w.Header().Set("Location", "/api/v1/documents/"+doc.ID)
+	if doc.IsQuarantined() {
+		// Stored, but held for malware review until an admin releases it.
+		h.writeJSON(w, http.StatusAccepted, doc)
+		return
+	}
+	h.writeJSON(w, http.StatusCreated, doc)
+}
+
+func (h *ImportHandler) writeGutenbergError(w http.ResponseWriter, err error, userID, message string) {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid request", "fields": validationErrs})
+	case errors.Is(err, domain.ErrBookNotFound):
+		h.writeError(w, http.StatusNotFound, "No EPUB of this book in Project Gutenberg")
+	case errors.Is(err, domain.ErrCatalogUnavailable):
+		h.logger.Error("Project Gutenberg unavailable", err, "user_id", userID)
+		h.writeError(w, http.StatusBadGateway, "Project Gutenberg is unavailable, try again later")
+	case errors.Is(err, domain.ErrFileTooLarge):
+		h.writeError(w, http.StatusRequestEntityTooLarge, "Book is larger than your plan allows")
+	case strings.Contains(err.Error(), "storage limit exceeded"):
+		h.writeError(w, http.StatusBadRequest, "Storage limit reached. Please delete some documents or contact support to increase your storage.")
+	default:
+		h.logger.Error(message, err, "user_id", userID)
+		writeServerError(w, err, message)
+	}
+}
+
+func (h *ImportHandler) writeImportError(w http.ResponseWriter, err error, userID, message string) {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid import", "fields": validationErrs})
+	case errors.Is(err, domain.ErrCloudProviderNotFound):
+		h.writeError(w, http.StatusNotFound, "Cloud provider not available")
+	case errors.Is(err, domain.ErrCloudNotConnected):
+		h.writeError(w, http.StatusConflict, "Cloud drive not connected")
+	case errors.Is(err, domain.ErrImportJobNotFound):
+		h.writeError(w, http.StatusNotFound, "Import job not found")
+	case errors.Is(err, domain.ErrInvalidFile):
+		h.writeError(w, http.StatusBadRequest, "Not a Calibre library export")
+	case errors.Is(err, domain.ErrFileTooLarge):
+		h.writeError(w, http.StatusRequestEntityTooLarge, "Library export is too large")
+	default:
+		h.logger.Error(message, err, "user_id", userID)
+		writeServerError(w, err, message)
+	}
+}
+
+func (h *ImportHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (h *ImportHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
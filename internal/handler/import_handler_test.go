@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+)
+
+type disconnectedImportService struct {
+	domain.CloudImportService
+}
+
+func (s *disconnectedImportService) StartImport(ctx context.Context, principal domain.Principal, provider string, fileIDs []string) (*domain.ImportJob, error) {
+	if provider != domain.CloudProviderDropbox {
+		return nil, domain.ErrCloudProviderNotFound
+	}
+	return nil, domain.ErrCloudNotConnected
+}
+
+func TestImportHandler_StartImportErrors(t *testing.T) {
+	h := NewImportHandler(&config.Container{CloudImportService: &disconnectedImportService{}}, NewMockHandlerLogger())
+	router := NewRouter(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, createContextWithPrincipal(r, testHandlerPrincipal("user1")))
+		})
+	}, nil, Guards{}, h)
+
+	tests := []struct {
+		provider string
+		want     int
+	}{
+		{provider: domain.CloudProviderDropbox, want: http.StatusConflict},
+		{provider: "onedrive", want: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/import/"+tt.provider, bytes.NewBufferString(`{"file_ids":["f1"]}`))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.provider, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+type stubGutenbergService struct{}
+
+func (s *stubGutenbergService) SearchCatalog(ctx context.Context, query string, page int) (*domain.GutenbergSearchResult, error) {
+	if query == "offline" {
+		return nil, domain.ErrCatalogUnavailable
+	}
+	return &domain.GutenbergSearchResult{Books: []domain.GutenbergBook{{ID: 1342, Title: "Pride and Prejudice"}}, Total: 1, Page: page}, nil
+}
+
+func (s *stubGutenbergService) ImportBook(ctx context.Context, principal domain.Principal, id int) (*domain.DocumentData, error) {
+	if id != 1342 {
+		return nil, domain.ErrBookNotFound
+	}
+	return &domain.DocumentData{ID: "doc-1", UserID: principal.UserID, Title: "Pride and Prejudice"}, nil
+}
+
+func TestImportHandler_Gutenberg(t *testing.T) {
+	h := NewImportHandler(&config.Container{GutenbergService: &stubGutenbergService{}}, NewMockHandlerLogger())
+	router := NewRouter(withTestPrincipal, nil, Guards{}, h)
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/api/v1/catalog/search?q=austen&page=2", http.StatusOK},
+		{http.MethodGet, "/api/v1/catalog/search?q=austen&page=two", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/catalog/search?q=offline", http.StatusBadGateway},
+		{http.MethodPost, "/api/v1/catalog/import/1342", http.StatusCreated},
+		{http.MethodPost, "/api/v1/catalog/import/7", http.StatusNotFound},
+		{http.MethodPost, "/api/v1/catalog/import/pride", http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s %s: expected status %d, got %d: %s", tt.method, tt.path, tt.want, rr.Code, rr.Body.String())
+		}
+		if tt.want == http.StatusCreated && rr.Header().Get("Location") != "/api/v1/documents/doc-1" {
+			t.Errorf("expected the document's location, got %q", rr.Header().Get("Location"))
+		}
+	}
+}
+
+type stubReadLaterService struct{}
+
+func (s *stubReadLaterService) Import(ctx context.Context, principal domain.Principal, service string, export io.Reader) (*domain.ImportJob, error) {
+	data, _ := io.ReadAll(export)
+	if string(data) != "url\nhttps://example.com/a\n" {
+		return nil, domain.ErrInvalidFile
+	}
+	return &domain.ImportJob{ID: "job-1", Provider: service, Status: domain.ImportStatusPending}, nil
+}
+
+func TestImportHandler_ReadLater(t *testing.T) {
+	h := NewImportHandler(&config.Container{ReadLaterImportService: &stubReadLaterService{}}, NewMockHandlerLogger())
+	router := NewRouter(withTestPrincipal, nil, Guards{}, h)
+
+	tests := []struct {
+		path, export string
+		want         int
+	}{
+		{"/api/v1/import/pocket", "url\nhttps://example.com/a\n", http.StatusAccepted},
+		{"/api/v1/import/instapaper", "url\nhttps://example.com/a\n", http.StatusAccepted},
+		{"/api/v1/import/pocket", "not an export", http.StatusBadRequest},
+		{"/api/v1/import/pocket", "", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if tt.export != "" {
+			part, _ := mw.CreateFormFile("file", "export.csv")
+			_, _ = part.Write([]byte(tt.export))
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, tt.path, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%s: expected status %d, got %d: %s", tt.path, tt.want, rr.Code, rr.Body.String())
+		}
+		if tt.want == http.StatusAccepted && rr.Header().Get("Location") != "/api/v1/import/jobs/job-1" {
+			t.Errorf("expected the job's location, got %q", rr.Header().Get("Location"))
+		}
+	}
+}
+
+type stubHighlightImportService struct{}
+
+func (s *stubHighlightImportService) ImportHighlights(ctx context.Context, principal domain.Principal, export io.Reader) (*domain.HighlightImportResult, error) {
+	data, _ := io.ReadAll(export)
+	if !bytes.Contains(data, []byte("==========")) {
+		return nil, domain.ErrInvalidFile
+	}
+	return &domain.HighlightImportResult{Format: domain.HighlightExportKindle, Imported: 1}, nil
+}
+
+func TestImportHandler_Highlights(t *testing.T) {
+	h := NewImportHandler(&config.Container{HighlightImportService: &stubHighlightImportService{}}, NewMockHandlerLogger())
+	router := NewRouter(withTestPrincipal, nil, Guards{}, h)
+
+	tests := []struct {
+		export string
+		want   int
+	}{
+		{"Persuasion (Jane Austen)\n- Your Highlight on page 3\n\nYou pierce my soul.\n==========\n", http.StatusOK},
+		{"not an export", http.StatusBadRequest},
+		{"", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		if tt.export != "" {
+			part, _ := mw.CreateFormFile("file", "My Clippings.txt")
+			_, _ = part.Write([]byte(tt.export))
+		}
+		_ = mw.Close()
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/import/highlights", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.want {
+			t.Errorf("%q: expected status %d, got %d: %s", tt.export, tt.want, rr.Code, rr.Body.String())
+		}
+	}
+}
@@ -0,0 +1,480 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// Recommendations returned by default and at most.
+const (
+	defaultRecommendations = 5
+	maxRecommendations     = 20
+)
+
+// LibraryHandler serves aggregated views of the user's library and its catalogue
+// metadata.
+type LibraryHandler struct {
+	logger                domain.Logger
+	documentService       domain.DocumentService
+	preferenceService     domain.UserPreferencesService
+	highlightService      domain.HighlightService
+	recommendationService domain.RecommendationService
+	catalogService        domain.CatalogService
+	enrichmentService     domain.EnrichmentService
+	duplicateService      domain.DuplicateService
+	locatorService        domain.LocatorService
+	savedSearchService    domain.SavedSearchService
+}
+
+func NewLibraryHandler(container *config.Container, logger domain.Logger) *LibraryHandler {
+	return &LibraryHandler{
+		logger:                logger,
+		documentService:       container.DocumentService,
+		preferenceService:     container.UserPreferencesService,
+		highlightService:      container.HighlightService,
+		recommendationService: container.RecommendationService,
+		catalogService:        container.CatalogService,
+		enrichmentService:     container.EnrichmentService,
+		duplicateService:      container.DuplicateService,
+		locatorService:        container.LocatorService,
+		savedSearchService:    container.SavedSearchService,
+	}
+}
+
+// RegisterRoutes adds the library endpoints.
+func (h *LibraryHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	// Fill in missing details from external book catalogs
+	r.HandleFunc("/documents/{id}/enrich", h.EnrichDocument).Methods(http.MethodPost)
+
+	// Library screen: documents with positions, highlight counts and status
+	r.HandleFunc("/library/overview", h.GetOverview).Methods(http.MethodGet)
+
+	// Library by author and series, grouped from document metadata
+	r.HandleFunc("/library/authors", h.ListAuthors).Methods(http.MethodGet)
+	r.HandleFunc("/library/authors/merge", h.MergeAuthors).Methods(http.MethodPost)
+	r.HandleFunc("/library/authors/{id}", h.GetAuthor).Methods(http.MethodGet)
+	r.HandleFunc("/library/authors/{id}", h.RenameAuthor).Methods(http.MethodPut)
+	r.HandleFunc("/library/series", h.ListSeries).Methods(http.MethodGet)
+	r.HandleFunc("/library/series/merge", h.MergeSeries).Methods(http.MethodPost)
+	r.HandleFunc("/library/series/{id}", h.GetSeries).Methods(http.MethodGet)
+	r.HandleFunc("/library/series/{id}", h.RenameSeries).Methods(http.MethodPut)
+
+	// Likely duplicates and merging them onto one copy
+	r.HandleFunc("/library/duplicates", h.ListDuplicates).Methods(http.MethodGet)
+	r.HandleFunc("/library/duplicates/merge", h.MergeDuplicates).Methods(http.MethodPost)
+
+	// Backfill EPUB locators onto positions and highlights saved without one
+	r.HandleFunc("/library/locators/migrate", h.MigrateLocators).Methods(http.MethodPost)
+
+	// "Read next" suggestions from the user's unfinished documents
+	r.HandleFunc("/recommendations", h.GetRecommendations).Methods(http.MethodGet)
+}
+
+// GetOverview handles GET /library/overview: every document with its reading
+// position, highlight count and status, so the library screen needs one request.
+// Archived documents are left out unless ?archived=true or ?archived=all; ?sort and
+// ?opened_since order and filter the documents as for the other listings, and
+// ?collection=smart:{id} narrows them to a saved search.
+// After the documents are listed, their positions and highlight counts are fetched
+// in parallel with one batched query each, whatever the size of the library. If
+// either fails the documents are still returned without it.
+func (h *LibraryHandler) GetOverview(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	filter, err := domain.ParseLibraryQuery(r.URL.Query())
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var documents []*domain.DocumentData
+	if filter.Collection != "" {
+		documents, err = h.savedSearchService.CollectionDocuments(r.Context(), principal, filter.Collection, r.URL.Query())
+	} else {
+		documents, err = h.documentService.GetDocumentsByUserID(r.Context(), principal)
+		documents = filter.Apply(documents)
+	}
+	if errors.Is(err, domain.ErrSavedSearchNotFound) {
+		h.writeError(w, http.StatusNotFound, "Collection not found")
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to load library overview", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to load library data")
+		return
+	}
+	documentIDs := make([]string, 0, len(documents))
+	for _, doc := range documents {
+		documentIDs = append(documentIDs, doc.ID)
+	}
+
+	var (
+		wg        sync.WaitGroup
+		positions map[string]*domain.ReadingPosition
+		counts    map[string]int
+	)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		var err error
+		if positions, err = h.preferenceService.GetReadingPositions(r.Context(), principal, documentIDs); err != nil {
+			h.logger.Error("Failed to load reading positions for overview", err, "user_id", principal.UserID)
+		}
+	}()
+	go func() {
+		defer wg.Done()
+		var err error
+		if counts, err = h.highlightService.CountHighlights(r.Context(), principal, documentIDs); err != nil {
+			h.logger.Error("Failed to count highlights for overview", err, "user_id", principal.UserID)
+		}
+	}()
+	wg.Wait()
+
+	response := domain.LibraryResponse{
+		Documents: make([]domain.DocumentWithPosition, 0, len(documents)),
+	}
+	for _, doc := range documents {
+		entry := domain.DocumentWithPosition{
+			DocumentData:    doc,
+			ReadingPosition: positions[doc.ID],
+			Status:          doc.Status(),
+		}
+		if counts != nil {
+			count := counts[doc.ID]
+			entry.HighlightCount = &count
+		}
+		response.Documents = append(response.Documents, entry)
+	}
+
+	h.writeJSON(w, http.StatusOK, response)
+}
+
+// GetRecommendations handles GET /recommendations?limit=N: unfinished documents to
+// read next, each with the reasons it was suggested.
+func (h *LibraryHandler) GetRecommendations(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	limit := defaultRecommendations
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > maxRecommendations {
+			h.writeError(w, http.StatusBadRequest, "limit must be between 1 and 20")
+			return
+		}
+		limit = n
+	}
+
+	recommendations, err := h.recommendationService.Recommend(r.Context(), principal, limit)
+	if err != nil {
+		h.logger.Error("Failed to build recommendations", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to load recommendations")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{
+		"recommendations": recommendations,
+	})
+}
+
+type renameCatalogRequest struct {
+	Name string `json:"name"`
+}
+
+type mergeCatalogRequest struct {
+	IDs  []string `json:"ids"`
+	Into string   `json:"into"`
+}
+
+// ListAuthors handles GET /library/authors
+func (h *LibraryHandler) ListAuthors(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	authors, err := h.catalogService.ListAuthors(r.Context(), principal)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to list authors")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"authors": authors})
+}
+
+// GetAuthor handles GET /library/authors/{id}: the author with their documents
+func (h *LibraryHandler) GetAuthor(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	author, err := h.catalogService.GetAuthor(r.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to get author")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, author)
+}
+
+// RenameAuthor handles PUT /library/authors/{id} with {"name": "..."}
+func (h *LibraryHandler) RenameAuthor(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req renameCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	author, err := h.catalogService.RenameAuthor(r.Context(), principal, mux.Vars(r)["id"], req.Name)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to rename author")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, author)
+}
+
+// MergeAuthors handles POST /library/authors/merge with {"ids": [...], "into": "..."}
+func (h *LibraryHandler) MergeAuthors(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req mergeCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	author, err := h.catalogService.MergeAuthors(r.Context(), principal, req.IDs, req.Into)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to merge authors")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, author)
+}
+
+// ListSeries handles GET /library/series
+func (h *LibraryHandler) ListSeries(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	series, err := h.catalogService.ListSeries(r.Context(), principal)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to list series")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"series": series})
+}
+
+// GetSeries handles GET /library/series/{id}: the series with its documents in order
+func (h *LibraryHandler) GetSeries(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	series, err := h.catalogService.GetSeries(r.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to get series")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, series)
+}
+
+// RenameSeries handles PUT /library/series/{id} with {"name": "..."}
+func (h *LibraryHandler) RenameSeries(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req renameCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	series, err := h.catalogService.RenameSeries(r.Context(), principal, mux.Vars(r)["id"], req.Name)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to rename series")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, series)
+}
+
+// MergeSeries handles POST /library/series/merge with {"ids": [...], "into": "..."}
+func (h *LibraryHandler) MergeSeries(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req mergeCatalogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	series, err := h.catalogService.MergeSeries(r.Context(), principal, req.IDs, req.Into)
+	if err != nil {
+		h.writeCatalogError(w, err, principal.UserID, "Failed to merge series")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, series)
+}
+
+// EnrichDocument handles POST /documents/{id}/enrich: fills in the document's missing
+// details from OpenLibrary or Google Books. Honors If-Match.
+func (h *LibraryHandler) EnrichDocument(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	conditional, err := withPrecondition(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	doc, err := h.enrichmentService.EnrichDocument(conditional.Context(), principal, mux.Vars(r)["id"])
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrBookNotFound):
+			h.writeError(w, http.StatusNotFound, "No matching book found in external catalogs")
+		case errors.Is(err, domain.ErrCatalogUnavailable):
+			h.logger.Error("Book catalogs unavailable", err, "user_id", principal.UserID)
+			h.writeError(w, http.StatusBadGateway, "Book catalogs are unavailable, try again later")
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, "Document not found")
+		case errors.Is(err, domain.ErrAccessDenied):
+			h.writeError(w, http.StatusForbidden, "Access denied")
+		case errors.Is(err, domain.ErrStaleUpdate):
+			h.writeError(w, http.StatusConflict, "Document was modified by another request")
+		default:
+			h.logger.Error("Failed to enrich document", err, "user_id", principal.UserID)
+			writeServerError(w, err, "Failed to enrich document")
+		}
+		return
+	}
+	setETag(w, doc.UpdatedAt)
+	h.writeJSON(w, http.StatusOK, doc)
+}
+
+// ListDuplicates handles GET /library/duplicates: clusters of documents that are
+// likely copies of the same book, most confident first.
+func (h *LibraryHandler) ListDuplicates(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	clusters, err := h.duplicateService.FindDuplicates(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to find duplicates", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to find duplicates")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, map[string]interface{}{"clusters": clusters})
+}
+
+// MigrateLocators handles POST /library/locators/migrate: gives the reading positions
+// and highlights of the user's EPUBs saved with only a page or progress a structural
+// locator. Running it again only picks up records still without one.
+func (h *LibraryHandler) MigrateLocators(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	migration, err := h.locatorService.MigrateLocators(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to migrate locators", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to migrate locators")
+		return
+	}
+	h.writeJSON(w, http.StatusOK, migration)
+}
+
+type mergeDuplicatesRequest struct {
+	CanonicalID  string   `json:"canonical_id"`
+	DuplicateIDs []string `json:"duplicate_ids"`
+}
+
+// MergeDuplicates handles POST /library/duplicates/merge: moves the duplicates'
+// highlights and reading position onto the canonical document and deletes them.
+func (h *LibraryHandler) MergeDuplicates(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req mergeDuplicatesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	merge, err := h.duplicateService.MergeDuplicates(r.Context(), principal, req.CanonicalID, req.DuplicateIDs)
+	if err != nil {
+		var validationErrs domain.ValidationErrors
+		switch {
+		case errors.As(err, &validationErrs):
+			h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid merge", "fields": validationErrs})
+		case errors.Is(err, domain.ErrDocumentNotFound):
+			h.writeError(w, http.StatusNotFound, "Document not found")
+		case errors.Is(err, domain.ErrAccessDenied):
+			h.writeError(w, http.StatusForbidden, "Access denied")
+		default:
+			h.logger.Error("Failed to merge duplicates", err, "user_id", principal.UserID)
+			writeServerError(w, err, "Failed to merge duplicates")
+		}
+		return
+	}
+	h.writeJSON(w, http.StatusOK, merge)
+}
+
+func (h *LibraryHandler) writeCatalogError(w http.ResponseWriter, err error, userID, message string) {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid request", "fields": validationErrs})
+	case errors.Is(err, domain.ErrAuthorNotFound):
+		h.writeError(w, http.StatusNotFound, "Author not found")
+	case errors.Is(err, domain.ErrSeriesNotFound):
+		h.writeError(w, http.StatusNotFound, "Series not found")
+	default:
+		h.logger.Error(message, err, "user_id", userID)
+		writeServerError(w, err, message)
+	}
+}
+
+func (h *LibraryHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *LibraryHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	h.writeJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,132 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+)
+
+type countingHighlightService struct {
+	MockHighlightService
+	counts      map[string]int
+	err         error
+	documentIDs []string
+}
+
+func (m *countingHighlightService) CountHighlights(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	m.documentIDs = documentIDs
+	return m.counts, m.err
+}
+
+func newOverviewRequest(t *testing.T, highlights domain.HighlightService) *httptest.ResponseRecorder {
+	t.Helper()
+	docService := NewMockDocumentService()
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Ready"}
+	docService.documents["doc2"] = &domain.Document{
+		ID:       "doc2",
+		UserID:   "user1",
+		Title:    "Held",
+		Metadata: domain.DocumentMetadata{Quarantine: &domain.Quarantine{Reason: "malware"}},
+	}
+	prefService := NewMockUserPreferencesService()
+	prefService.positions["user1"] = map[string]*domain.ReadingPosition{
+		"doc1": {DocumentID: "doc1", Progress: 0.25, PageNumber: 4},
+	}
+
+	h := NewLibraryHandler(&config.Container{
+		DocumentService:        docService,
+		UserPreferencesService: prefService,
+		HighlightService:       highlights,
+	}, NewMockHandlerLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/library/overview", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+	h.GetOverview(rr, req)
+	return rr
+}
+
+func decodeOverview(t *testing.T, rr *httptest.ResponseRecorder) map[string]domain.DocumentWithPosition {
+	t.Helper()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var response domain.LibraryResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	byID := make(map[string]domain.DocumentWithPosition, len(response.Documents))
+	for _, entry := range response.Documents {
+		byID[entry.DocumentData.ID] = entry
+	}
+	return byID
+}
+
+func TestLibraryHandler_GetOverview(t *testing.T) {
+	highlights := &countingHighlightService{counts: map[string]int{"doc1": 3}}
+	rr := newOverviewRequest(t, highlights)
+	entries := decodeOverview(t, rr)
+
+	if len(highlights.documentIDs) != 2 {
+		t.Fatalf("expected one batched count for both documents, got %v", highlights.documentIDs)
+	}
+
+	ready, held := entries["doc1"], entries["doc2"]
+	if ready.Status != domain.DocumentStatusReady || held.Status != domain.DocumentStatusQuarantined {
+		t.Fatalf("unexpected statuses %q and %q", ready.Status, held.Status)
+	}
+	if ready.ReadingPosition == nil || ready.ReadingPosition.PageNumber != 4 || held.ReadingPosition != nil {
+		t.Fatalf("unexpected reading positions: %s", rr.Body.String())
+	}
+	if ready.HighlightCount == nil || *ready.HighlightCount != 3 || held.HighlightCount == nil || *held.HighlightCount != 0 {
+		t.Fatalf("unexpected highlight counts: %s", rr.Body.String())
+	}
+}
+
+func TestLibraryHandler_GetOverview_CountsUnavailable(t *testing.T) {
+	rr := newOverviewRequest(t, &countingHighlightService{err: errors.New("timeout")})
+	entries := decodeOverview(t, rr)
+
+	if len(entries) != 2 {
+		t.Fatalf("expected the documents despite the failed count, got %s", rr.Body.String())
+	}
+	if entries["doc1"].HighlightCount != nil {
+		t.Fatalf("expected highlight_count to be omitted, got %s", rr.Body.String())
+	}
+}
+
+func TestLibraryHandler_GetOverview_SmartCollection(t *testing.T) {
+	docService := NewMockDocumentService()
+	docService.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Ready"}
+	docService.documents["doc2"] = &domain.Document{ID: "doc2", UserID: "user1", Title: "Finished"}
+	savedSearches := NewMockSavedSearchService(docService)
+	savedSearches.searches["search-1"] = &domain.SavedSearch{ID: "search-1", Name: "Ready", Query: `"ready"`}
+
+	h := NewLibraryHandler(&config.Container{
+		DocumentService:        docService,
+		UserPreferencesService: NewMockUserPreferencesService(),
+		HighlightService:       &countingHighlightService{},
+		SavedSearchService:     savedSearches,
+	}, NewMockHandlerLogger())
+	overview := func(target string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, target, nil)
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+		rr := httptest.NewRecorder()
+		h.GetOverview(rr, req)
+		return rr
+	}
+
+	entries := decodeOverview(t, overview("/api/v1/library/overview?collection=smart:search-1"))
+	if _, ok := entries["doc1"]; !ok || len(entries) != 1 {
+		t.Fatalf("expected only the collection's document, got %v", entries)
+	}
+	if rr := overview("/api/v1/library/overview?collection=smart:missing"); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
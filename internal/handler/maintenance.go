@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// maintenanceRetryAfter is the Retry-After sent with writes refused during maintenance.
+const maintenanceRetryAfter = "60"
+
+// readOnlyPosts are POST routes that change nothing and stay available during
+// maintenance, next to the admin routes used to end it.
+var readOnlyPosts = map[string]bool{
+	"/api/v1/auth/login":            true,
+	"/api/v1/documents/{id}/export": true,
+	"/api/v1/documents/compare":     true,
+	"/graphql":                      true,
+}
+
+// maintenanceGuard refuses writes while maintenance is on, and marks the context
+// of the reads it lets through so services skip their bookkeeping writes. It runs
+// after routing so read-only POST routes can be told apart by their path template.
+// Without a switch nothing is refused.
+func maintenanceGuard(mode domain.MaintenanceMode) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if mode == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			status := mode.Status()
+			if !status.Enabled {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if !isWrite(r) {
+				next.ServeHTTP(w, r.WithContext(domain.ContextWithReadOnly(r.Context())))
+				return
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", maintenanceRetryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_ = json.NewEncoder(w).Encode(map[string]any{
+				"error":       status.Message,
+				"maintenance": true,
+				"since":       status.Since,
+			})
+		})
+	}
+}
+
+// isWrite reports whether r may change data.
+func isWrite(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	route := mux.CurrentRoute(r)
+	if route == nil {
+		return true
+	}
+	template, err := route.GetPathTemplate()
+	if err != nil {
+		return true
+	}
+	if strings.HasPrefix(template, "/api/v1/admin/") {
+		return false
+	}
+	return r.Method != http.MethodPost || !readOnlyPosts[template]
+}
@@ -1,10 +1,11 @@
 package handler
 
 import (
-	"context"
+	"errors"
 	"net/http"
 	"strings"
 
+	"pdf-text-reader/internal/authctx"
 	"pdf-text-reader/internal/domain"
 )
 
@@ -45,26 +46,30 @@ func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
 			return
 		}
 
-		user, err := m.authService.ValidateToken(token)
+		user, err := m.authService.ValidateToken(r.Context(), token)
 		if err != nil {
 			m.logger.Error("Token validation failed", err)
+			// A token that could not be checked is not a rejected one: the client keeps
+			// its session and retries instead of signing the user out.
+			if errors.Is(err, domain.ErrUpstreamUnavailable) {
+				writeServerError(w, err, "Failed to validate token")
+				return
+			}
 			writeError(w, http.StatusUnauthorized, "Invalid token")
 			return
 		}
 
-		disabled, err := m.authService.IsAccountDisabled(user.ID, token)
+		disabled, err := m.authService.IsAccountDisabled(r.Context(), user.ID, token)
 		if err != nil {
 			m.logger.Error("Failed to check account status", err, "user_id", user.ID)
-			writeError(w, http.StatusInternalServerError, "Failed to validate account status")
+			writeServerError(w, err, "Failed to validate account status")
 			return
 		}
 		if disabled {
-			writeError(w, http.StatusForbidden, "Account disabled")
+			writeAccountDisabled(w)
 			return
 		}
 
-		ctx := context.WithValue(r.Context(), userContextKey, user)
-		ctx = context.WithValue(ctx, tokenContextKey, token)
-		next.ServeHTTP(w, r.WithContext(ctx))
+		next.ServeHTTP(w, r.WithContext(authctx.WithCaller(r.Context(), user, token)))
 	})
 }
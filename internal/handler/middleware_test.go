@@ -1,13 +1,16 @@
 package handler
 
 import (
+	"context"
 	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
 )
 
 type mockAuthService struct {
@@ -17,7 +20,7 @@ type mockAuthService struct {
 	disabled  bool
 }
 
-func (m *mockAuthService) ValidateToken(token string) (*domain.SupabaseUser, error) {
+func (m *mockAuthService) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
 	m.lastToken = token
 	if m.err != nil {
 		return nil, m.err
@@ -25,7 +28,7 @@ func (m *mockAuthService) ValidateToken(token string) (*domain.SupabaseUser, err
 	return m.user, nil
 }
 
-func (m *mockAuthService) IsAccountDisabled(userID string, token string) (bool, error) {
+func (m *mockAuthService) IsAccountDisabled(ctx context.Context, userID string, token string) (bool, error) {
 	return m.disabled, nil
 }
 
@@ -120,6 +123,26 @@ func TestAuthMiddleware_InvalidToken(t *testing.T) {
 	}
 }
 
+func TestAuthMiddleware_UpstreamUnavailable(t *testing.T) {
+	authService := &mockAuthService{err: &domain.UpstreamError{RetryAfter: 12 * time.Second, Err: resilience.ErrCircuitOpen}}
+	logger := NewMockHandlerLogger()
+
+	middleware := NewAuthMiddleware(authService, logger).Middleware
+	h := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatalf("expected handler not to be called")
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer good")
+	rr := httptest.NewRecorder()
+
+	h.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") != "12" {
+		t.Fatalf("expected 503 with Retry-After, got %d %q", rr.Code, rr.Header().Get("Retry-After"))
+	}
+}
+
 func TestAuthMiddleware_Success(t *testing.T) {
 	authService := &mockAuthService{user: &domain.SupabaseUser{ID: "user-1", Email: "test@example.com"}}
 	logger := NewMockHandlerLogger()
@@ -132,9 +155,9 @@ func TestAuthMiddleware_Success(t *testing.T) {
 		if !ok || user.ID != "user-1" {
 			t.Fatalf("expected user in context")
 		}
-		token, ok := GetTokenFromContext(r)
-		if !ok || token != "good" {
-			t.Fatalf("expected token in context")
+		principal, ok := GetPrincipalFromContext(r)
+		if !ok || principal.UserID != "user-1" || principal.Token != "good" {
+			t.Fatalf("expected principal in context, got %+v", principal)
 		}
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -174,7 +197,7 @@ func TestAuthMiddleware_AccountDisabled(t *testing.T) {
 	if rr.Code != http.StatusForbidden {
 		t.Fatalf("expected status %d, got %d", http.StatusForbidden, rr.Code)
 	}
-	if !strings.Contains(rr.Body.String(), "Account disabled") {
+	if !strings.Contains(rr.Body.String(), `"code":"account_disabled"`) {
 		t.Fatalf("unexpected response body: %s", rr.Body.String())
 	}
 }
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// eventStreamKeepAlive is how often an idle event stream sends a comment so proxies
+// do not close it.
+const eventStreamKeepAlive = 25 * time.Second
+
+// NotificationHandler handles push device registration and the event stream.
+type NotificationHandler struct {
+	container           *config.Container
+	logger              domain.Logger
+	notificationService domain.NotificationService
+	eventBroker         domain.EventBroker
+}
+
+func NewNotificationHandler(container *config.Container, logger domain.Logger) *NotificationHandler {
+	return &NotificationHandler{
+		container:           container,
+		logger:              logger,
+		notificationService: container.NotificationService,
+		eventBroker:         container.EventBroker,
+	}
+}
+
+// RegisterRoutes adds the device and event endpoints.
+func (h *NotificationHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	// Push notification devices
+	r.HandleFunc("/devices", h.ListDevices).Methods(http.MethodGet)
+	r.HandleFunc("/devices", h.RegisterDevice).Methods(http.MethodPost)
+	r.HandleFunc("/devices/{token}", h.UnregisterDevice).Methods(http.MethodDelete)
+
+	// Live library events for the web app (server-sent events)
+	r.HandleFunc("/events", h.StreamEvents).Methods(http.MethodGet)
+}
+
+type registerDeviceRequest struct {
+	Token    string `json:"token"`
+	Platform string `json:"platform"`
+}
+
+// RegisterDevice handles POST /devices
+func (h *NotificationHandler) RegisterDevice(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	var req registerDeviceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+
+	device, err := h.notificationService.RegisterDevice(r.Context(), principal, &domain.Device{
+		Token:    req.Token,
+		Platform: req.Platform,
+	})
+	var validationErrs domain.ValidationErrors
+	if errors.As(err, &validationErrs) {
+		h.writeJSON(w, http.StatusBadRequest, map[string]any{"error": "Invalid device", "fields": validationErrs})
+		return
+	}
+	if err != nil {
+		h.logger.Error("Failed to register device", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to register device")
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, device)
+}
+
+// ListDevices handles GET /devices
+func (h *NotificationHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	devices, err := h.notificationService.ListDevices(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to list devices", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve devices")
+		return
+	}
+	if devices == nil {
+		devices = make([]*domain.Device, 0)
+	}
+	h.writeJSON(w, http.StatusOK, devices)
+}
+
+// UnregisterDevice handles DELETE /devices/{token}
+func (h *NotificationHandler) UnregisterDevice(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	token := mux.Vars(r)["token"]
+	if token == "" {
+		h.writeError(w, http.StatusBadRequest, "Device token is required")
+		return
+	}
+
+	if err := h.notificationService.UnregisterDevice(r.Context(), principal, token); err != nil {
+		h.logger.Error("Failed to unregister device", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to unregister device")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// StreamEvents handles GET /events, a server-sent event stream of the user's
+// library events (document.processed, ingestion.done, quota.warning).
+func (h *NotificationHandler) StreamEvents(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	// The stream outlives the server's write timeout.
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		h.logger.Error("Failed to clear write deadline", err, "user_id", principal.UserID)
+	}
+
+	events := h.eventBroker.Subscribe(r.Context(), principal)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, _ = fmt.Fprint(w, ": connected\n\n")
+	if err := rc.Flush(); err != nil {
+		h.logger.Error("Event stream is not supported by the response writer", err)
+		return
+	}
+
+	keepAlive := time.NewTicker(eventStreamKeepAlive)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return
+			}
+			var data []byte
+			if data, err = json.Marshal(event); err != nil {
+				h.logger.Error("Failed to encode event", err, "user_id", principal.UserID, "event", event.Type)
+				continue
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+		case <-keepAlive.C:
+			_, err = fmt.Fprint(w, ": keep-alive\n\n")
+		}
+		if err != nil || rc.Flush() != nil {
+			return
+		}
+	}
+}
+
+func (h *NotificationHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": message})
+}
+
+func (h *NotificationHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
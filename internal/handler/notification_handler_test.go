@@ -0,0 +1,78 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+)
+
+// closedEventBroker replays its events to every subscriber and then ends the stream.
+type closedEventBroker struct {
+	events []domain.Event
+}
+
+func (b *closedEventBroker) Publish(ctx context.Context, principal domain.Principal, event domain.Event) {
+}
+
+func (b *closedEventBroker) Subscribe(ctx context.Context, principal domain.Principal) <-chan domain.Event {
+	ch := make(chan domain.Event, len(b.events))
+	for _, event := range b.events {
+		ch <- event
+	}
+	close(ch)
+	return ch
+}
+
+func TestNotificationHandler_StreamEvents(t *testing.T) {
+	broker := &closedEventBroker{events: []domain.Event{
+		{Type: domain.EventDocumentProcessed, Data: domain.DocumentEvent{DocumentID: "doc1", Title: "Middlemarch"}},
+	}}
+	h := NewNotificationHandler(&config.Container{EventBroker: broker}, NewMockHandlerLogger())
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/events", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+	h.StreamEvents(rr, req)
+
+	if got := rr.Header().Get("Content-Type"); got != "text/event-stream" {
+		t.Fatalf("expected an event stream, got %q", got)
+	}
+	if !rr.Flushed {
+		t.Error("expected the stream to be flushed")
+	}
+	body := rr.Body.String()
+	if !strings.HasPrefix(body, ": connected\n\n") {
+		t.Errorf("expected the stream to open with a comment, got %q", body)
+	}
+	event := "event: document.processed\ndata: {\"type\":\"document.processed\",\"data\":{\"document_id\":\"doc1\",\"title\":\"Middlemarch\"}"
+	if !strings.Contains(body, event) {
+		t.Errorf("expected %q in stream, got %q", event, body)
+	}
+}
+
+func TestNotificationHandler_RegisterDeviceValidation(t *testing.T) {
+	h := NewNotificationHandler(&config.Container{NotificationService: &rejectingNotificationService{}}, NewMockHandlerLogger())
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/devices", bytes.NewBufferString(`{"token":"","platform":"ios"}`))
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user1"))
+	rr := httptest.NewRecorder()
+	h.RegisterDevice(rr, req)
+
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), `"field":"token"`) {
+		t.Fatalf("expected 400 with field errors, got %d: %s", rr.Code, rr.Body.String())
+	}
+}
+
+type rejectingNotificationService struct {
+	domain.NotificationService
+}
+
+func (s *rejectingNotificationService) RegisterDevice(ctx context.Context, principal domain.Principal, device *domain.Device) (*domain.Device, error) {
+	return nil, device.Validate()
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Documents and preferences are versioned by their updated_at timestamp. Responses
+// carry it as an ETag, and clients echo it in If-Match so an update made from stale
+// data is rejected with 409 instead of overwriting another device's changes.
+
+var errInvalidIfMatch = errors.New("If-Match must be an ETag returned by this API")
+
+// etagFor formats updatedAt as a strong ETag.
+func etagFor(updatedAt time.Time) string {
+	return `"` + domain.PreconditionTime(updatedAt).Format(time.RFC3339Nano) + `"`
+}
+
+// setETag adds the ETag header unless updatedAt is unset.
+func setETag(w http.ResponseWriter, updatedAt time.Time) {
+	if !updatedAt.IsZero() {
+		w.Header().Set("ETag", etagFor(updatedAt))
+	}
+}
+
+// withPrecondition returns r with the If-Match precondition stored on its context.
+// Requests without If-Match, or with "*", update unconditionally.
+func withPrecondition(r *http.Request) (*http.Request, error) {
+	value := strings.TrimSpace(r.Header.Get("If-Match"))
+	if value == "" || value == "*" {
+		return r, nil
+	}
+	value = strings.TrimPrefix(value, "W/")
+	if len(value) < 2 || value[0] != '"' || value[len(value)-1] != '"' {
+		return nil, errInvalidIfMatch
+	}
+	updatedAt, err := time.Parse(time.RFC3339Nano, value[1:len(value)-1])
+	if err != nil {
+		return nil, errInvalidIfMatch
+	}
+	return r.WithContext(domain.ContextWithUnmodifiedSince(r.Context(), updatedAt)), nil
+}
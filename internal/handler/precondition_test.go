@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestWithPrecondition(t *testing.T) {
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		name     string
+		ifMatch  string
+		wantErr  bool
+		wantTime bool
+	}{
+		{"no header", "", false, false},
+		{"wildcard", "*", false, false},
+		{"strong etag", etagFor(updatedAt), false, true},
+		{"weak etag", "W/" + etagFor(updatedAt), false, true},
+		{"unquoted", "2024-05-01T12:00:00Z", true, false},
+		{"not a timestamp", `"abc"`, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("PUT", "/api/v1/preferences", nil)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+			got, err := withPrecondition(req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withPrecondition() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(got.Context())
+			if ok != tt.wantTime {
+				t.Fatalf("expected precondition %v, got %v", tt.wantTime, ok)
+			}
+			// ETags carry microsecond precision, matching what Postgres stores.
+			if ok && !unmodifiedSince.Equal(domain.PreconditionTime(updatedAt)) {
+				t.Fatalf("expected %v, got %v", domain.PreconditionTime(updatedAt), unmodifiedSince)
+			}
+		})
+	}
+}
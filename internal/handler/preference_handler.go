@@ -2,7 +2,10 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
+	"strconv"
+	"strings"
 
 	"pdf-text-reader/internal/config"
 	"pdf-text-reader/internal/domain"
@@ -15,6 +18,8 @@ type PreferenceHandler struct {
 	container         *config.Container
 	logger            domain.Logger
 	preferenceService domain.UserPreferencesService
+	fontService       domain.FontService
+	themeService      domain.ThemeService
 }
 
 // NewPreferenceHandler creates a new preference handler
@@ -23,146 +28,310 @@ func NewPreferenceHandler(container *config.Container, logger domain.Logger) *Pr
 		container:         container,
 		logger:            logger,
 		preferenceService: container.UserPreferencesService,
+		fontService:       container.FontService,
+		themeService:      container.ThemeService,
 	}
 }
 
+// RegisterRoutes adds the preference endpoints.
+func (h *PreferenceHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	r.HandleFunc("/preferences", h.GetPreferences).Methods(http.MethodGet)
+	r.HandleFunc("/preferences", h.UpdatePreferences).Methods(http.MethodPut)
+	r.HandleFunc("/preferences", h.PatchPreferences).Methods(http.MethodPatch)
+	r.HandleFunc("/preferences/fonts", h.ListFonts).Methods(http.MethodGet)
+	r.HandleFunc("/preferences/fonts", h.UploadFont).Methods(http.MethodPost)
+	r.HandleFunc("/preferences/fonts/{id}", h.DeleteFont).Methods(http.MethodDelete)
+	r.HandleFunc("/preferences/themes", h.ListThemes).Methods(http.MethodGet)
+	r.HandleFunc("/preferences/themes", h.CreateTheme).Methods(http.MethodPost)
+	r.HandleFunc("/preferences/themes/{id}", h.UpdateTheme).Methods(http.MethodPut)
+	r.HandleFunc("/preferences/themes/{id}", h.DeleteTheme).Methods(http.MethodDelete)
+
+	// Per-document preference overrides
+	r.HandleFunc("/documents/{id}/preferences", h.GetDocumentPreferences).Methods(http.MethodGet)
+	r.HandleFunc("/documents/{id}/preferences", h.UpdateDocumentPreferences).Methods(http.MethodPut)
+	r.HandleFunc("/preferences/reading-position/{documentId}", h.GetReadingPosition).Methods(http.MethodGet)
+	r.HandleFunc("/preferences/reading-position/{documentId}", h.UpdateReadingPosition).Methods(http.MethodPut)
+
+	// Get all reading positions for the authenticated user
+	r.HandleFunc("/preferences/reading-positions", h.GetAllReadingPositions).Methods(http.MethodGet)
+
+	// Sampled reading positions of a doc over time
+	r.HandleFunc("/documents/{id}/position-history", h.GetPositionHistory).Methods(http.MethodGet)
+}
+
 // GetPreferences handles getting user preferences
 func (h *PreferenceHandler) GetPreferences(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	// With ?document_id=, resolve the document's overrides on top of the global preferences.
+	if documentID := r.URL.Query().Get("document_id"); documentID != "" {
+		h.writeDocumentPreferences(w, r, principal, documentID)
 		return
 	}
 
-	preferences, err := h.preferenceService.GetPreferences(user.ID, token)
+	preferences, err := h.preferenceService.GetPreferences(r.Context(), principal)
 	if err != nil {
-		h.logger.Error("Failed to get preferences", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve preferences")
+		h.logger.Error("Failed to get preferences", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve preferences")
 		return
 	}
 
+	setETag(w, preferences.UpdatedAt)
 	h.writeJSON(w, http.StatusOK, preferences)
 }
 
-// UpdatePreferences handles updating user preferences
+// UpdatePreferences handles updating user preferences. Like PATCH it only changes the
+// fields sent, but unknown fields are ignored for older clients.
 func (h *PreferenceHandler) UpdatePreferences(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	h.updatePreferences(w, r, false)
+}
+
+// PatchPreferences handles partial preference updates, rejecting unknown fields
+func (h *PreferenceHandler) PatchPreferences(w http.ResponseWriter, r *http.Request) {
+	h.updatePreferences(w, r, true)
+}
+
+func (h *PreferenceHandler) updatePreferences(w http.ResponseWriter, r *http.Request, strict bool) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
-	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+	conditional, err := withPrecondition(r)
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 
-	// Decode partial preferences (only fields that are sent)
-	var prefsUpdate map[string]interface{}
-	if err := json.NewDecoder(r.Body).Decode(&prefsUpdate); err != nil {
-		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+	update, err := decodePreferencesUpdate(r, strict)
+	if err != nil {
+		h.writeInvalidPreferences(w, err)
+		return
+	}
+	if !h.preferencesValid(w, r, principal, update.Validate(), update.FontFamily, update.Theme) {
 		return
 	}
 
 	// Get current preferences first
-	currentPrefs, err := h.preferenceService.GetPreferences(user.ID, token)
+	currentPrefs, err := h.preferenceService.GetPreferences(r.Context(), principal)
 	if err != nil {
-		h.logger.Error("Failed to get current preferences", err, "user_id", user.ID)
+		h.logger.Error("Failed to get current preferences", err, "user_id", principal.UserID)
 		// If no preferences exist, create defaults
-		currentPrefs = &domain.UserPreferences{
-			UserID:     user.ID,
-			FontSize:   16,
-			FontFamily: "system-ui",
-			Theme:      "light",
-			Tags:       []string{},
-		}
+		currentPrefs = domain.DefaultUserPreferences(principal.UserID)
 	}
 
-	// Update only the fields that were sent
-	// Handle font_size (can be int or float64 from JSON)
-	if fontSizeVal, ok := prefsUpdate["font_size"]; ok {
-		switch v := fontSizeVal.(type) {
-		case float64:
-			currentPrefs.FontSize = int(v)
-		case int:
-			currentPrefs.FontSize = v
-		case int64:
-			currentPrefs.FontSize = int(v)
+	update.ApplyTo(currentPrefs)
+
+	// Persist updated preferences
+	if err := h.preferenceService.UpdatePreferences(conditional.Context(), principal, currentPrefs); err != nil {
+		if errors.Is(err, domain.ErrStaleUpdate) {
+			h.writePreferencesConflict(w, r, principal)
+			return
 		}
+		h.logger.Error("Failed to update preferences", err, "user_id", principal.UserID)
+		writeServerError(w, err, err.Error())
+		return
 	}
 
-	// Handle font_family
-	if fontFamily, ok := prefsUpdate["font_family"].(string); ok {
-		currentPrefs.FontFamily = fontFamily
+	// Get updated preferences to return
+	updatedPrefs, err := h.preferenceService.GetPreferences(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to get updated preferences", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve updated preferences")
+		return
 	}
 
-	// Handle theme
-	if theme, ok := prefsUpdate["theme"].(string); ok {
-		currentPrefs.Theme = theme
-	}
+	setETag(w, updatedPrefs.UpdatedAt)
+	h.writeJSON(w, http.StatusOK, updatedPrefs)
+}
 
-	// Handle subscription_plan (server sets storage_limit_bytes based on this).
-	if plan, ok := prefsUpdate["subscription_plan"].(string); ok {
-		currentPrefs.SubscriptionPlan = plan
-		currentPrefs.StorageLimitBytes = storageLimitBytesForPlan(plan)
+// decodePreferencesUpdate reads a partial update. Strict decoding rejects unknown
+// fields.
+func decodePreferencesUpdate(r *http.Request, strict bool) (*domain.PreferencesUpdate, error) {
+	var update domain.PreferencesUpdate
+	if err := decodeRequest(r, &update, strict); err != nil {
+		return nil, err
 	}
+	return &update, nil
+}
 
-	// Handle tags (can be []interface{} or []string from JSON)
-	if tagsVal, ok := prefsUpdate["tags"]; ok {
-		switch v := tagsVal.(type) {
-		case []interface{}:
-			tags := make([]string, 0, len(v))
-			for _, item := range v {
-				if str, ok := item.(string); ok {
-					tags = append(tags, str)
-				}
+// checkReferences reports a custom:<id> font family that does not name one of the
+// user's uploaded fonts, and a theme that is neither a preset nor one of the user's
+// custom themes.
+func (h *PreferenceHandler) checkReferences(r *http.Request, principal domain.Principal, fontFamily, theme *string) (domain.ValidationErrors, error) {
+	var errs domain.ValidationErrors
+	if fontFamily != nil && h.fontService != nil && strings.HasPrefix(*fontFamily, domain.CustomFontPrefix) {
+		found := false
+		if fontID, ok := domain.CustomFontID(*fontFamily); ok {
+			_, err := h.fontService.GetFont(r.Context(), principal, fontID)
+			if err != nil && !errors.Is(err, domain.ErrFontNotFound) {
+				return nil, err
 			}
-			currentPrefs.Tags = tags
-		case []string:
-			currentPrefs.Tags = v
+			found = err == nil
+		}
+		if !found {
+			errs = append(errs, &domain.ValidationError{Field: "font_family", Message: "unknown custom font"})
 		}
 	}
+	if theme != nil && h.themeService != nil && !domain.IsThemePreset(*theme) {
+		_, err := h.themeService.GetTheme(r.Context(), principal, *theme)
+		if errors.Is(err, domain.ErrThemeNotFound) {
+			errs = append(errs, &domain.ValidationError{Field: "theme", Message: "unknown theme"})
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return errs, nil
+}
 
-	// Persist updated preferences
-	if err := h.preferenceService.UpdatePreferences(user.ID, currentPrefs, token); err != nil {
-		h.logger.Error("Failed to update preferences", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, err.Error())
+// preferencesValid combines the result of the field validation with checkReferences
+// for fields that passed it, and writes the error response if anything is invalid.
+func (h *PreferenceHandler) preferencesValid(w http.ResponseWriter, r *http.Request, principal domain.Principal, validationErr error, fontFamily, theme *string) bool {
+	var errs domain.ValidationErrors
+	if validationErr != nil && !errors.As(validationErr, &errs) {
+		h.writeInvalidPreferences(w, validationErr)
+		return false
+	}
+	for _, fieldErr := range errs {
+		switch fieldErr.Field {
+		case "font_family":
+			fontFamily = nil
+		case "theme":
+			theme = nil
+		}
+	}
+
+	refErrs, err := h.checkReferences(r, principal, fontFamily, theme)
+	if err != nil {
+		h.logger.Error("Failed to resolve preference references", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to verify preferences")
+		return false
+	}
+	errs = append(errs, refErrs...)
+	if len(errs) > 0 {
+		h.writeInvalidPreferences(w, errs)
+		return false
+	}
+	return true
+}
+
+// writeInvalidPreferences answers with 400. Validation errors list every invalid
+// field; other errors mean the body was not valid JSON.
+func (h *PreferenceHandler) writeInvalidPreferences(w http.ResponseWriter, err error) {
+	writeValidationErrors(w, "Invalid preferences", err)
+}
+
+// writePreferencesConflict answers a stale update with 409 and the stored preferences.
+func (h *PreferenceHandler) writePreferencesConflict(w http.ResponseWriter, r *http.Request, principal domain.Principal) {
+	current, err := h.preferenceService.GetPreferences(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to get current preferences", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve preferences")
 		return
 	}
+	setETag(w, current.UpdatedAt)
+	h.writeJSON(w, http.StatusConflict, map[string]any{
+		"error":   "Preferences were modified by another request",
+		"current": current,
+	})
+}
 
-	// Get updated preferences to return
-	updatedPrefs, err := h.preferenceService.GetPreferences(user.ID, token)
+// GetDocumentPreferences handles getting the preferences resolved for a document
+func (h *PreferenceHandler) GetDocumentPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	h.writeDocumentPreferences(w, r, principal, documentID)
+}
+
+func (h *PreferenceHandler) writeDocumentPreferences(w http.ResponseWriter, r *http.Request, principal domain.Principal, documentID string) {
+	preferences, err := h.preferenceService.GetDocumentPreferences(r.Context(), principal, documentID)
 	if err != nil {
-		h.logger.Error("Failed to get updated preferences", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve updated preferences")
+		h.logger.Error("Failed to get document preferences", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to retrieve preferences")
 		return
 	}
 
-	h.writeJSON(w, http.StatusOK, updatedPrefs)
+	h.writeJSON(w, http.StatusOK, preferences)
 }
 
-func storageLimitBytesForPlan(plan string) int64 {
-	return domain.StorageLimitBytesForPlan(plan)
+type documentPreferencesRequest struct {
+	FontSize   *int    `json:"font_size"`
+	FontFamily *string `json:"font_family"`
+	Theme      *string `json:"theme"`
+	MarginSize *string `json:"margin_size"`
+	TextAlign  *string `json:"text_align"`
 }
 
-// GetReadingPosition handles getting reading position for a document
-func (h *PreferenceHandler) GetReadingPosition(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// UpdateDocumentPreferences handles replacing a document's preference overrides.
+// Fields left out or null fall back to the global preferences; an empty body clears
+// the overrides.
+func (h *PreferenceHandler) UpdateDocumentPreferences(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	var req documentPreferencesRequest
+	if err := decodeRequest(r, &req, false); err != nil {
+		h.writeInvalidPreferences(w, err)
+		return
+	}
+
+	override := &domain.DocumentPreferences{
+		FontSize:   req.FontSize,
+		FontFamily: req.FontFamily,
+		Theme:      req.Theme,
+		MarginSize: req.MarginSize,
+		TextAlign:  req.TextAlign,
+	}
+	if !h.preferencesValid(w, r, principal, override.Validate(), req.FontFamily, req.Theme) {
+		return
+	}
+
+	preferences, err := h.preferenceService.UpdateDocumentPreferences(r.Context(), principal, documentID, override)
+	if err != nil {
+		var validationErrs domain.ValidationErrors
+		if errors.As(err, &validationErrs) {
+			h.writeInvalidPreferences(w, err)
+			return
+		}
+		h.logger.Error("Failed to update document preferences", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to update preferences")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, preferences)
+}
+
+// GetReadingPosition handles getting reading position for a document
+func (h *PreferenceHandler) GetReadingPosition(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
@@ -174,27 +343,56 @@ func (h *PreferenceHandler) GetReadingPosition(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	position, err := h.preferenceService.GetReadingPosition(user.ID, documentID, token)
+	position, err := h.preferenceService.GetReadingPosition(r.Context(), principal, documentID)
 	if err != nil {
-		h.logger.Error("Failed to get reading position", err, "user_id", user.ID, "document_id", documentID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve reading position")
+		h.logger.Error("Failed to get reading position", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to retrieve reading position")
 		return
 	}
 
 	h.writeJSON(w, http.StatusOK, position)
 }
 
-// UpdateReadingPosition handles updating reading position for a document
-func (h *PreferenceHandler) UpdateReadingPosition(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+// GetPositionHistory handles GET /documents/{id}/position-history?days=N: the
+// document's sampled reading positions with a per-day summary of the pace.
+func (h *PreferenceHandler) GetPositionHistory(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	documentID := mux.Vars(r)["id"]
+	if documentID == "" {
+		h.writeError(w, http.StatusBadRequest, "Document ID is required")
+		return
+	}
+
+	days := domain.DefaultPositionHistoryDays
+	if raw := r.URL.Query().Get("days"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 || n > domain.MaxPositionHistoryDays {
+			h.writeError(w, http.StatusBadRequest, "days must be between 1 and 365")
+			return
+		}
+		days = n
+	}
+
+	history, err := h.preferenceService.GetPositionHistory(r.Context(), principal, documentID, days)
+	if err != nil {
+		h.logger.Error("Failed to get position history", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to retrieve position history")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, history)
+}
+
+// UpdateReadingPosition handles updating reading position for a document
+func (h *PreferenceHandler) UpdateReadingPosition(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
@@ -212,17 +410,17 @@ func (h *PreferenceHandler) UpdateReadingPosition(w http.ResponseWriter, r *http
 		return
 	}
 
-	if err := h.preferenceService.UpdateReadingPosition(user.ID, documentID, &position, token); err != nil {
-		h.logger.Error("Failed to update reading position", err, "user_id", user.ID, "document_id", documentID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to update reading position")
+	if err := h.preferenceService.UpdateReadingPosition(r.Context(), principal, documentID, &position); err != nil {
+		h.logger.Error("Failed to update reading position", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to update reading position")
 		return
 	}
 
 	// Get updated position to return
-	updatedPosition, err := h.preferenceService.GetReadingPosition(user.ID, documentID, token)
+	updatedPosition, err := h.preferenceService.GetReadingPosition(r.Context(), principal, documentID)
 	if err != nil {
-		h.logger.Error("Failed to get updated reading position", err, "user_id", user.ID, "document_id", documentID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve updated reading position")
+		h.logger.Error("Failed to get updated reading position", err, "user_id", principal.UserID, "document_id", documentID)
+		writeServerError(w, err, "Failed to retrieve updated reading position")
 		return
 	}
 
@@ -231,26 +429,262 @@ func (h *PreferenceHandler) UpdateReadingPosition(w http.ResponseWriter, r *http
 
 // GetAllReadingPositions returns all reading positions for the authenticated user.
 func (h *PreferenceHandler) GetAllReadingPositions(w http.ResponseWriter, r *http.Request) {
-	user, ok := GetUserFromContext(r)
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	positions, err := h.preferenceService.GetAllReadingPositions(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to get reading positions", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve reading positions")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, positions)
+}
+
+// ListFonts returns the authenticated user's uploaded fonts.
+func (h *PreferenceHandler) ListFonts(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
 		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	token, ok := GetTokenFromContext(r)
+	fonts, err := h.fontService.ListFonts(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to list fonts", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve fonts")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, fonts)
+}
+
+// UploadFont handles a multipart woff2/ttf upload in the "file" field. Pro plans only.
+func (h *PreferenceHandler) UploadFont(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
 	if !ok {
-		h.writeError(w, http.StatusUnauthorized, "Token not found in context")
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
 		return
 	}
 
-	positions, err := h.preferenceService.GetAllReadingPositions(user.ID, token)
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxFontBytes+multipartOverheadBytes)
+
+	file, header, err := r.FormFile("file")
 	if err != nil {
-		h.logger.Error("Failed to get reading positions", err, "user_id", user.ID)
-		h.writeError(w, http.StatusInternalServerError, "Failed to retrieve reading positions")
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			h.writeError(w, http.StatusRequestEntityTooLarge, "Font file is too large")
+			return
+		}
+		h.writeError(w, http.StatusBadRequest, "No file provided")
 		return
 	}
+	defer file.Close()
 
-	h.writeJSON(w, http.StatusOK, positions)
+	if header.Size > domain.MaxFontBytes {
+		h.writeError(w, http.StatusRequestEntityTooLarge, "Font file is too large")
+		return
+	}
+
+	font, err := h.fontService.UploadFont(r.Context(), principal, file, header.Filename)
+	if err != nil {
+		switch {
+		case errors.Is(err, domain.ErrPlanRequired):
+			h.writeError(w, http.StatusForbidden, "Custom fonts require a Pro plan")
+		case errors.Is(err, domain.ErrFileTooLarge):
+			h.writeError(w, http.StatusRequestEntityTooLarge, "Font file is too large")
+		case errors.Is(err, domain.ErrUnsupportedFileType):
+			h.writeError(w, http.StatusUnsupportedMediaType, "Fonts must be woff2 or ttf files")
+		case errors.Is(err, domain.ErrFontLimitReached):
+			h.writeError(w, http.StatusConflict, err.Error())
+		default:
+			h.logger.Error("Failed to upload font", err, "user_id", principal.UserID)
+			writeServerError(w, err, "Failed to upload font")
+		}
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, font)
+}
+
+// DeleteFont removes an uploaded font. Preferences using it revert to the default font.
+func (h *PreferenceHandler) DeleteFont(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	fontID := mux.Vars(r)["id"]
+	if fontID == "" {
+		h.writeError(w, http.StatusBadRequest, "Font ID is required")
+		return
+	}
+
+	if err := h.fontService.DeleteFont(r.Context(), principal, fontID); err != nil {
+		if errors.Is(err, domain.ErrFontNotFound) {
+			h.writeError(w, http.StatusNotFound, "Font not found")
+			return
+		}
+		h.logger.Error("Failed to delete font", err, "user_id", principal.UserID, "font_id", fontID)
+		writeServerError(w, err, "Failed to delete font")
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type themeRequest struct {
+	Name            string  `json:"name"`
+	BackgroundColor string  `json:"background_color"`
+	TextColor       string  `json:"text_color"`
+	AccentColor     string  `json:"accent_color"`
+	LineHeight      float64 `json:"line_height"`
+	FontFamily      string  `json:"font_family"`
+}
+
+// ListThemes returns the built-in presets followed by the user's custom themes.
+func (h *PreferenceHandler) ListThemes(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	themes, err := h.themeService.ListThemes(r.Context(), principal)
+	if err != nil {
+		h.logger.Error("Failed to list themes", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to retrieve themes")
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, themes)
+}
+
+// CreateTheme saves a custom theme.
+func (h *PreferenceHandler) CreateTheme(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	theme, ok := h.decodeTheme(w, r, principal)
+	if !ok {
+		return
+	}
+
+	created, err := h.themeService.CreateTheme(r.Context(), principal, theme)
+	if err != nil {
+		h.writeThemeError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusCreated, created)
+}
+
+// UpdateTheme replaces a custom theme. Preferences follow a rename.
+func (h *PreferenceHandler) UpdateTheme(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	themeID := mux.Vars(r)["id"]
+	if themeID == "" {
+		h.writeError(w, http.StatusBadRequest, "Theme ID is required")
+		return
+	}
+
+	theme, ok := h.decodeTheme(w, r, principal)
+	if !ok {
+		return
+	}
+
+	updated, err := h.themeService.UpdateTheme(r.Context(), principal, themeID, theme)
+	if err != nil {
+		h.writeThemeError(w, principal, err)
+		return
+	}
+
+	h.writeJSON(w, http.StatusOK, updated)
+}
+
+// DeleteTheme removes a custom theme. Preferences using it revert to the light theme.
+func (h *PreferenceHandler) DeleteTheme(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+
+	themeID := mux.Vars(r)["id"]
+	if themeID == "" {
+		h.writeError(w, http.StatusBadRequest, "Theme ID is required")
+		return
+	}
+
+	if err := h.themeService.DeleteTheme(r.Context(), principal, themeID); err != nil {
+		h.writeThemeError(w, principal, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// decodeTheme reads and validates a theme body, including its font reference.
+func (h *PreferenceHandler) decodeTheme(w http.ResponseWriter, r *http.Request, principal domain.Principal) (*domain.Theme, bool) {
+	var req themeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.writeError(w, http.StatusBadRequest, "Invalid request body")
+		return nil, false
+	}
+
+	theme := &domain.Theme{
+		Name:            req.Name,
+		BackgroundColor: req.BackgroundColor,
+		TextColor:       req.TextColor,
+		AccentColor:     req.AccentColor,
+		LineHeight:      req.LineHeight,
+		FontFamily:      req.FontFamily,
+	}
+
+	var errs domain.ValidationErrors
+	if err := theme.Validate(); err != nil && !errors.As(err, &errs) {
+		h.writeError(w, http.StatusInternalServerError, "Failed to validate theme")
+		return nil, false
+	}
+	refErrs, err := h.checkReferences(r, principal, &theme.FontFamily, nil)
+	if err != nil {
+		h.logger.Error("Failed to resolve theme font", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to verify theme")
+		return nil, false
+	}
+	if errs = append(errs, refErrs...); len(errs) > 0 {
+		writeValidationErrors(w, "Invalid theme", errs)
+		return nil, false
+	}
+	return theme, true
+}
+
+func (h *PreferenceHandler) writeThemeError(w http.ResponseWriter, principal domain.Principal, err error) {
+	var validationErrs domain.ValidationErrors
+	switch {
+	case errors.As(err, &validationErrs):
+		writeValidationErrors(w, "Invalid theme", err)
+	case errors.Is(err, domain.ErrThemeNotFound):
+		h.writeError(w, http.StatusNotFound, "Theme not found")
+	case errors.Is(err, domain.ErrThemeExists), errors.Is(err, domain.ErrThemeLimitReached):
+		h.writeError(w, http.StatusConflict, err.Error())
+	default:
+		h.logger.Error("Theme operation failed", err, "user_id", principal.UserID)
+		writeServerError(w, err, "Failed to update themes")
+	}
 }
 
 // writeError writes an error response
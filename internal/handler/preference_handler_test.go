@@ -1,11 +1,16 @@
 package handler
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"io"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/gorilla/mux"
 	"pdf-text-reader/internal/config"
@@ -22,7 +27,7 @@ func TestPreferenceHandler_GetPreferences_OK(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/preferences", nil)
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "token"))
 
 	rr := httptest.NewRecorder()
 	handler.GetPreferences(rr, req)
@@ -51,7 +56,7 @@ func TestPreferenceHandler_UpdatePreferences_OK(t *testing.T) {
 	body := strings.NewReader(`{"font_size":18,"font_family":"serif","theme":"dark","tags":["one","two"]}`)
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", body)
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "token"))
 
 	rr := httptest.NewRecorder()
 	handler.UpdatePreferences(rr, req)
@@ -78,6 +83,79 @@ func TestPreferenceHandler_UpdatePreferences_OK(t *testing.T) {
 	}
 }
 
+func TestPreferenceHandler_UpdatePreferences_ReadingLayout(t *testing.T) {
+	prefService := NewMockUserPreferencesService()
+	container := &config.Container{UserPreferencesService: prefService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"layout fields", `{"margin_size":"wide","text_align":"justify","hyphenation":true,"reading_mode":"scroll","page_turn_animation":"none","brightness":0.7}`, http.StatusOK},
+		{"unknown reading mode", `{"reading_mode":"vertical"}`, http.StatusBadRequest},
+		{"brightness out of range", `{"brightness":2}`, http.StatusBadRequest},
+		{"wrong type", `{"hyphenation":"yes"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", strings.NewReader(tt.body))
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+			rr := httptest.NewRecorder()
+			handler.UpdatePreferences(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tt.wantStatus != http.StatusOK {
+				return
+			}
+
+			var prefs domain.UserPreferences
+			if err := json.Unmarshal(rr.Body.Bytes(), &prefs); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if prefs.MarginSize != "wide" || prefs.TextAlign != "justify" || !prefs.Hyphenation ||
+				prefs.ReadingMode != "scroll" || prefs.PageTurnAnimation != "none" || prefs.Brightness != 0.7 {
+				t.Fatalf("unexpected preferences: %+v", prefs)
+			}
+			if prefs.FontSize != 16 {
+				t.Fatalf("expected font size to keep its value, got %d", prefs.FontSize)
+			}
+		})
+	}
+}
+
+func TestPreferenceHandler_UpdatePreferences_Stale(t *testing.T) {
+	prefService := NewMockUserPreferencesService()
+	container := &config.Container{UserPreferencesService: prefService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	prefService.preferences["user-1"] = &domain.UserPreferences{UserID: "user-1", FontSize: 20, Theme: "sepia", UpdatedAt: updatedAt}
+
+	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences", strings.NewReader(`{"font_size":12}`))
+	req.Header.Set("If-Match", etagFor(updatedAt.Add(-time.Hour)))
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+
+	rr := httptest.NewRecorder()
+	handler.UpdatePreferences(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusConflict, rr.Code, rr.Body.String())
+	}
+	var resp struct {
+		Current domain.UserPreferences `json:"current"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if resp.Current.FontSize != 20 || prefService.preferences["user-1"].FontSize != 20 {
+		t.Fatalf("stale update should not change stored preferences, got %+v", resp.Current)
+	}
+}
+
 func TestPreferenceHandler_GetReadingPosition_MissingID(t *testing.T) {
 	prefService := NewMockUserPreferencesService()
 	logger := NewMockHandlerLogger()
@@ -88,7 +166,7 @@ func TestPreferenceHandler_GetReadingPosition_MissingID(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/preferences/reading-position/", nil)
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "token"))
 
 	rr := httptest.NewRecorder()
 	handler.GetReadingPosition(rr, req)
@@ -112,7 +190,7 @@ func TestPreferenceHandler_GetReadingPosition_OK(t *testing.T) {
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/preferences/reading-position/doc-1", nil)
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "token"))
 
 	rr := httptest.NewRecorder()
 
@@ -144,7 +222,7 @@ func TestPreferenceHandler_UpdateReadingPosition_OK(t *testing.T) {
 	body := strings.NewReader(`{"progress":0.25,"page_number":2}`)
 	req := httptest.NewRequest(http.MethodPut, "/api/v1/preferences/reading-position/doc-1", body)
 	req = createContextWithUser(req, user)
-	req = createContextWithToken(req, "token")
+	req = createContextWithPrincipal(req, domain.NewPrincipal(user, "token"))
 
 	rr := httptest.NewRecorder()
 
@@ -167,3 +245,372 @@ func TestPreferenceHandler_UpdateReadingPosition_OK(t *testing.T) {
 		t.Fatalf("expected page number 2, got %d", position.PageNumber)
 	}
 }
+
+func TestPreferenceHandler_DocumentPreferences(t *testing.T) {
+	prefService := NewMockUserPreferencesService()
+	container := &config.Container{UserPreferencesService: prefService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+	router := mux.NewRouter()
+	router.HandleFunc("/api/v1/preferences", handler.GetPreferences).Methods(http.MethodGet)
+	router.HandleFunc("/api/v1/documents/{id}/preferences", handler.UpdateDocumentPreferences).Methods(http.MethodPut)
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+	}{
+		{"invalid font size", `{"font_size":-1}`, http.StatusBadRequest},
+		{"override theme", `{"theme":"dark"}`, http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPut, "/api/v1/documents/doc-1/preferences", strings.NewReader(tt.body))
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != tt.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.name, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/preferences?document_id=doc-1", nil)
+	req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	var prefs domain.UserPreferences
+	if err := json.Unmarshal(rr.Body.Bytes(), &prefs); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if prefs.Theme != "dark" || prefs.FontSize != 16 || prefs.DocumentOverride == nil {
+		t.Fatalf("expected global preferences with the document's theme, got %+v", prefs)
+	}
+}
+
+func TestPreferenceHandler_PatchPreferences(t *testing.T) {
+	prefService := NewMockUserPreferencesService()
+	container := &config.Container{UserPreferencesService: prefService, ThemeService: NewMockThemeService()}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+	tests := []struct {
+		name       string
+		body       string
+		wantStatus int
+		wantFields []string
+	}{
+		{"valid patch", `{"font_size":20,"theme":"sepia","highlight_color":"#80CBC4"}`, http.StatusOK, nil},
+		{"several invalid fields", `{"font_size":200,"theme":"","highlight_color":"teal"}`, http.StatusBadRequest, []string{"font_size", "theme", "highlight_color"}},
+		{"unknown theme reported after field errors", `{"font_size":200,"theme":"neon"}`, http.StatusBadRequest, []string{"font_size", "theme"}},
+		{"wrong type", `{"font_size":"large"}`, http.StatusBadRequest, []string{"font_size"}},
+		{"unknown field", `{"font_colour":"#000"}`, http.StatusBadRequest, []string{"font_colour"}},
+		{"malformed body", `{"font_size":`, http.StatusBadRequest, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/api/v1/preferences", strings.NewReader(tt.body))
+			req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+			rr := httptest.NewRecorder()
+			handler.PatchPreferences(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+
+			if tt.wantStatus == http.StatusOK {
+				var prefs domain.UserPreferences
+				if err := json.Unmarshal(rr.Body.Bytes(), &prefs); err != nil {
+					t.Fatalf("failed to decode response: %v", err)
+				}
+				if prefs.FontSize != 20 || prefs.Theme != "sepia" || prefs.HighlightColor != "#80CBC4" {
+					t.Fatalf("unexpected preferences: %+v", prefs)
+				}
+				return
+			}
+
+			var resp struct {
+				Error  string                   `json:"error"`
+				Fields []domain.ValidationError `json:"fields"`
+			}
+			if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if len(resp.Fields) != len(tt.wantFields) {
+				t.Fatalf("expected invalid fields %v, got %+v", tt.wantFields, resp.Fields)
+			}
+			for i, field := range tt.wantFields {
+				if resp.Fields[i].Field != field || resp.Fields[i].Message == "" {
+					t.Fatalf("expected invalid fields %v, got %+v", tt.wantFields, resp.Fields)
+				}
+			}
+		})
+	}
+}
+
+type MockFontService struct {
+	fonts     map[string]*domain.Font
+	uploadErr error
+}
+
+func NewMockFontService() *MockFontService {
+	return &MockFontService{fonts: make(map[string]*domain.Font)}
+}
+
+func (m *MockFontService) UploadFont(ctx context.Context, principal domain.Principal, file io.Reader, filename string) (*domain.Font, error) {
+	if m.uploadErr != nil {
+		return nil, m.uploadErr
+	}
+	data, _ := io.ReadAll(file)
+	font := &domain.Font{ID: "font-1", UserID: principal.UserID, Name: filename, Format: domain.FontFormatWOFF2, SizeBytes: int64(len(data))}
+	m.fonts[font.ID] = font
+	return font, nil
+}
+
+func (m *MockFontService) GetFont(ctx context.Context, principal domain.Principal, fontID string) (*domain.Font, error) {
+	font, ok := m.fonts[fontID]
+	if !ok {
+		return nil, domain.ErrFontNotFound
+	}
+	return font, nil
+}
+
+func (m *MockFontService) ListFonts(ctx context.Context, principal domain.Principal) ([]*domain.Font, error) {
+	var out []*domain.Font
+	for _, font := range m.fonts {
+		out = append(out, font)
+	}
+	return out, nil
+}
+
+func (m *MockFontService) DeleteFont(ctx context.Context, principal domain.Principal, fontID string) error {
+	if _, ok := m.fonts[fontID]; !ok {
+		return domain.ErrFontNotFound
+	}
+	delete(m.fonts, fontID)
+	return nil
+}
+
+func newFontUploadRequest(t *testing.T, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("file", "Inter.woff2")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	_, _ = part.Write(content)
+	_ = mw.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/preferences/fonts", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+}
+
+func TestPreferenceHandler_UploadFont(t *testing.T) {
+	tests := []struct {
+		name       string
+		uploadErr  error
+		content    []byte
+		wantStatus int
+	}{
+		{name: "created", content: []byte("wOF2"), wantStatus: http.StatusCreated},
+		{name: "free plan", uploadErr: domain.ErrPlanRequired, content: []byte("wOF2"), wantStatus: http.StatusForbidden},
+		{name: "unsupported", uploadErr: domain.ErrUnsupportedFileType, content: []byte("%PDF"), wantStatus: http.StatusUnsupportedMediaType},
+		{name: "limit reached", uploadErr: domain.ErrFontLimitReached, content: []byte("wOF2"), wantStatus: http.StatusConflict},
+		{name: "too large", content: bytes.Repeat([]byte("x"), domain.MaxFontBytes+1), wantStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fontService := NewMockFontService()
+			fontService.uploadErr = tt.uploadErr
+			container := &config.Container{UserPreferencesService: NewMockUserPreferencesService(), FontService: fontService}
+			handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+			rr := httptest.NewRecorder()
+			handler.UploadFont(rr, newFontUploadRequest(t, tt.content))
+
+			if rr.Code != tt.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tt.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestPreferenceHandler_DeleteFont(t *testing.T) {
+	fontService := NewMockFontService()
+	fontService.fonts["font-1"] = &domain.Font{ID: "font-1", UserID: "user-1"}
+	container := &config.Container{UserPreferencesService: NewMockUserPreferencesService(), FontService: fontService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+	for _, want := range []int{http.StatusNoContent, http.StatusNotFound} {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/preferences/fonts/font-1", nil)
+		req = mux.SetURLVars(req, map[string]string{"id": "font-1"})
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+		rr := httptest.NewRecorder()
+
+		handler.DeleteFont(rr, req)
+
+		if rr.Code != want {
+			t.Fatalf("expected status %d, got %d", want, rr.Code)
+		}
+	}
+}
+
+func TestPreferenceHandler_PatchPreferences_CustomFont(t *testing.T) {
+	fontService := NewMockFontService()
+	fontService.fonts["font-1"] = &domain.Font{ID: "font-1", UserID: "user-1"}
+	container := &config.Container{UserPreferencesService: NewMockUserPreferencesService(), FontService: fontService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+
+	tests := []struct {
+		fontFamily string
+		wantStatus int
+	}{
+		{fontFamily: "custom:font-1", wantStatus: http.StatusOK},
+		{fontFamily: "custom:missing", wantStatus: http.StatusBadRequest},
+		{fontFamily: "custom:", wantStatus: http.StatusBadRequest},
+		{fontFamily: "serif", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		body := strings.NewReader(`{"font_family":"` + tt.fontFamily + `"}`)
+		req := httptest.NewRequest(http.MethodPatch, "/api/v1/preferences", body)
+		req = createContextWithPrincipal(req, testHandlerPrincipal("user-1"))
+		rr := httptest.NewRecorder()
+
+		handler.PatchPreferences(rr, req)
+
+		if rr.Code != tt.wantStatus {
+			t.Fatalf("%s: expected status %d, got %d: %s", tt.fontFamily, tt.wantStatus, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+type MockThemeService struct {
+	themes map[string]*domain.Theme // Keyed by ID
+}
+
+func NewMockThemeService() *MockThemeService {
+	return &MockThemeService{themes: make(map[string]*domain.Theme)}
+}
+
+func (m *MockThemeService) ListThemes(ctx context.Context, principal domain.Principal) ([]*domain.Theme, error) {
+	themes := domain.ThemePresets()
+	for _, theme := range m.themes {
+		themes = append(themes, theme)
+	}
+	return themes, nil
+}
+
+func (m *MockThemeService) GetTheme(ctx context.Context, principal domain.Principal, name string) (*domain.Theme, error) {
+	if preset, ok := domain.ThemePreset(name); ok {
+		return preset, nil
+	}
+	for _, theme := range m.themes {
+		if theme.Name == name {
+			return theme, nil
+		}
+	}
+	return nil, domain.ErrThemeNotFound
+}
+
+func (m *MockThemeService) CreateTheme(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := m.GetTheme(ctx, principal, theme.Name); err == nil {
+		return nil, domain.ErrThemeExists
+	}
+	theme.ID = "theme-" + theme.Name
+	theme.UserID = principal.UserID
+	m.themes[theme.ID] = theme
+	return theme, nil
+}
+
+func (m *MockThemeService) UpdateTheme(ctx context.Context, principal domain.Principal, themeID string, theme *domain.Theme) (*domain.Theme, error) {
+	if _, ok := m.themes[themeID]; !ok {
+		return nil, domain.ErrThemeNotFound
+	}
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+	theme.ID = themeID
+	m.themes[themeID] = theme
+	return theme, nil
+}
+
+func (m *MockThemeService) DeleteTheme(ctx context.Context, principal domain.Principal, themeID string) error {
+	if _, ok := m.themes[themeID]; !ok {
+		return domain.ErrThemeNotFound
+	}
+	delete(m.themes, themeID)
+	return nil
+}
+
+func TestPreferenceHandler_ThemeCRUD(t *testing.T) {
+	themeService := NewMockThemeService()
+	fontService := NewMockFontService()
+	container := &config.Container{UserPreferencesService: NewMockUserPreferencesService(), ThemeService: themeService, FontService: fontService}
+	handler := NewPreferenceHandler(container, NewMockHandlerLogger())
+	principal := testHandlerPrincipal("user-1")
+
+	send := func(method, target, id, body string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if id != "" {
+			req = mux.SetURLVars(req, map[string]string{"id": id})
+		}
+		req = createContextWithPrincipal(req, principal)
+		rr := httptest.NewRecorder()
+		fn(rr, req)
+		return rr
+	}
+
+	theme := `{"name":"Night Owl","background_color":"#011627","text_color":"#D6DEEB","accent_color":"#82AAFF","line_height":1.6}`
+	if rr := send(http.MethodPost, "/api/v1/preferences/themes", "", theme, handler.CreateTheme); rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	if rr := send(http.MethodPost, "/api/v1/preferences/themes", "", theme, handler.CreateTheme); rr.Code != http.StatusConflict {
+		t.Fatalf("expected duplicate name to conflict, got %d", rr.Code)
+	}
+
+	invalid := `{"name":"sepia","background_color":"navy","text_color":"#000","accent_color":"#000","line_height":1.5,"font_family":"custom:missing"}`
+	rr := send(http.MethodPost, "/api/v1/preferences/themes", "", invalid, handler.CreateTheme)
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d", http.StatusBadRequest, rr.Code)
+	}
+	var resp struct {
+		Fields []domain.ValidationError `json:"fields"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(resp.Fields) != 3 || resp.Fields[2].Field != "font_family" {
+		t.Fatalf("expected name, background_color and font_family errors, got %+v", resp.Fields)
+	}
+
+	rr = send(http.MethodGet, "/api/v1/preferences/themes", "", "", handler.ListThemes)
+	var themes []domain.Theme
+	if err := json.Unmarshal(rr.Body.Bytes(), &themes); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if len(themes) != len(domain.ThemePresets())+1 {
+		t.Fatalf("expected presets plus one custom theme, got %d", len(themes))
+	}
+
+	// The saved theme can now be selected by name.
+	if rr := send(http.MethodPatch, "/api/v1/preferences", "", `{"theme":"Night Owl"}`, handler.PatchPreferences); rr.Code != http.StatusOK {
+		t.Fatalf("expected custom theme to be accepted, got %d: %s", rr.Code, rr.Body.String())
+	}
+
+	renamed := strings.Replace(theme, "Night Owl", "Day Owl", 1)
+	if rr := send(http.MethodPut, "/api/v1/preferences/themes/theme-Night%20Owl", "theme-Night Owl", renamed, handler.UpdateTheme); rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d: %s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	if rr := send(http.MethodDelete, "/api/v1/preferences/themes/theme-Night%20Owl", "theme-Night Owl", "", handler.DeleteTheme); rr.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, rr.Code)
+	}
+	if rr := send(http.MethodDelete, "/api/v1/preferences/themes/theme-Night%20Owl", "theme-Night Owl", "", handler.DeleteTheme); rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, rr.Code)
+	}
+}
@@ -2,22 +2,52 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/rs/cors"
+
+	"pdf-text-reader/internal/domain"
 )
 
-func NewRouter(
-	authHandler *AuthHandler,
-	adminHandler *AdminHandler,
-	documentHandler *DocumentHandler,
-	preferenceHandler *PreferenceHandler,
-	highlightHandler *HighlightHandler,
-	authMiddleware func(http.Handler) http.Handler,
+// Routes are the routers a handler registers its endpoints on.
+type Routes struct {
+	Root      *mux.Router // Outside /api/v1, e.g. /graphql
+	Public    *mux.Router // /api/v1 without authentication
+	Protected *mux.Router // /api/v1 behind the auth middleware
+
+	// ProtectedV2 is /api/v2 behind the auth middleware. Its list endpoints answer
+	// with a ListResponse.
+	ProtectedV2 *mux.Router
 
-) http.Handler {
+	// Authenticate wraps a handler mounted outside Protected in the auth middleware.
+	Authenticate func(http.Handler) http.Handler
+}
+
+// Guards are the checks NewRouter puts in front of every module's routes. The zero
+// value guards nothing.
+type Guards struct {
+	// Maintenance refuses writes while maintenance mode is on.
+	Maintenance domain.MaintenanceMode
+	// Abuse throttles IPs that fail credential checks, and users and IPs that
+	// upload too much.
+	Abuse domain.AbuseMonitor
+}
 
+// RouteModule is a subsystem that serves HTTP endpoints. Each handler registers its
+// own routes, so adding one does not change NewRouter.
+type RouteModule interface {
+	RegisterRoutes(routes Routes)
+}
+
+// NewRouter builds the HTTP API from the given modules, behind guards. Modules
+// register in order, and within the same router the first matching route wins.
+func NewRouter(authMiddleware func(http.Handler) http.Handler, allowedOrigins []string, guards Guards, modules ...RouteModule) http.Handler {
 	router := mux.NewRouter()
+	abuse := abuseGuard{monitor: guards.Abuse}
+	// Router middleware runs after routing, on every route including those of the
+	// subrouters below, so the guards can tell routes apart by their path template.
+	router.Use(maintenanceGuard(guards.Maintenance), abuse.authFailures)
 
 	// Health check (public)
 	router.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
@@ -28,88 +58,27 @@ func NewRouter(
 
 	// API v1
 	api := router.PathPrefix("/api/v1").Subrouter()
-
-	// Admin routes (NOT behind auth middleware; protected by X-Admin-Secret)
-	admin := api.PathPrefix("/admin").Subrouter()
-	admin.HandleFunc("/users/{id}/account-disabled", adminHandler.SetAccountDisabled).Methods(http.MethodPost)
-
-	// Protected routes
 	protected := api.PathPrefix("").Subrouter()
-	protected.Use(authMiddleware)
-
-	// Auth
-	protected.HandleFunc("/auth/profile", authHandler.GetProfile).Methods(http.MethodGet)
-	protected.HandleFunc("/auth/profile", authHandler.UpdateProfile).Methods(http.MethodPut)
-	protected.HandleFunc("/auth/validate", authHandler.ValidateToken).Methods(http.MethodGet)
-	protected.HandleFunc("/auth/account-deletion-request", authHandler.RequestAccountDeletion).Methods(http.MethodPost)
-
-	// Documents
-	// Gets all the card information
-	protected.HandleFunc("/documents/library", documentHandler.GetLibrary).Methods(http.MethodGet)
-
-	// Get all the doc information
-	protected.HandleFunc("/documents", documentHandler.UploadDocument).Methods(http.MethodPost)
-
-	// Get doc data by ID
-	protected.HandleFunc("/documents/{id}", documentHandler.GetDocument).Methods(http.MethodGet)
-
-	// Update doc by ID
-	protected.HandleFunc("/documents/{id}", documentHandler.UpdateDocument).Methods(http.MethodPut)
-
-	// Favorite/unfavorite doc
-	protected.HandleFunc("/documents/{id}/favorite", documentHandler.SetFavorite).Methods(http.MethodPut)
-
-	// Delete doc by ID
-	protected.HandleFunc("/documents/{id}", documentHandler.DeleteDocument).Methods(http.MethodDelete)
-
-	// Search docs
-	protected.HandleFunc("/documents/search", documentHandler.SearchDocuments).Methods(http.MethodGet)
-
-	// Get all the docs by user ID
-	protected.HandleFunc("/documents/user/{id}", documentHandler.GetDocumentsByUserID).Methods(http.MethodGet)
+	protected.Use(authMiddleware, abuse.uploads, uploadDeadline)
 
-	// Storage usage/limit (authenticated user)
-	protected.HandleFunc("/storage/usage", documentHandler.GetStorageUsage).Methods(http.MethodGet)
+	// API v2
+	protectedV2 := router.PathPrefix("/api/v2").Subrouter()
+	protectedV2.Use(authMiddleware)
 
-	// Get all document tags for the authenticated user
-	protected.HandleFunc("/document-tags", documentHandler.GetDocumentTags).Methods(http.MethodGet)
-
-	// Create a new document tag for the authenticated user
-	protected.HandleFunc("/document-tags", documentHandler.CreateTag).Methods(http.MethodPost)
-
-	// Delete a document tag for the authenticated user
-	protected.HandleFunc("/document-tags/{name}", documentHandler.DeleteTag).Methods(http.MethodDelete)
-
-	// Preferences
-	protected.HandleFunc("/preferences", preferenceHandler.GetPreferences).Methods(http.MethodGet)
-	protected.HandleFunc("/preferences", preferenceHandler.UpdatePreferences).Methods(http.MethodPut)
-
-	protected.HandleFunc("/preferences/reading-position/{documentId}", preferenceHandler.GetReadingPosition).Methods(http.MethodGet)
-
-	protected.HandleFunc("/preferences/reading-position/{documentId}", preferenceHandler.UpdateReadingPosition).Methods(http.MethodPut)
-
-	// Get all reading positions for the authenticated user
-	protected.HandleFunc("/preferences/reading-positions", preferenceHandler.GetAllReadingPositions).Methods(http.MethodGet)
-
-	// Highlights
-	protected.HandleFunc("/highlights", highlightHandler.ListHighlights).Methods(http.MethodGet)
-	protected.HandleFunc("/highlights", highlightHandler.CreateHighlight).Methods(http.MethodPost)
-	protected.HandleFunc("/highlights/{id}", highlightHandler.DeleteHighlight).Methods(http.MethodDelete)
+	routes := Routes{Root: router, Public: api, Protected: protected, ProtectedV2: protectedV2, Authenticate: authMiddleware}
+	for _, module := range modules {
+		module.RegisterRoutes(routes)
+	}
 
 	// CORS
 	c := cors.New(cors.Options{
-		AllowedOrigins: []string{
-			// Local development
-			"http://localhost:5173",
-			"http://localhost:4173",
-			"http://localhost:3000",
-			// Production frontend
-			"https://lector.thefndrs.com",
-		},
+		// Configured via CORS_ALLOWED_ORIGINS; "*" wildcards are matched by rs/cors.
+		AllowedOrigins: allowedOrigins,
 		AllowedMethods: []string{
 			http.MethodGet,
 			http.MethodPost,
 			http.MethodPut,
+			http.MethodPatch,
 			http.MethodDelete,
 			http.MethodOptions,
 		},
@@ -117,10 +86,50 @@ func NewRouter(
 			"Accept",
 			"Authorization",
 			"Content-Type",
+			"If-Match",
+			"Range",
+			"X-Request-ID",
+			"X-Upload-ID",
 		},
+		// Clients read the ETag to send it back in If-Match on updates; viewers read
+		// Accept-Ranges and Content-Range to seek in streamed originals.
+		ExposedHeaders:   []string{"Accept-Ranges", "Content-Range", "ETag", "Retry-After", "X-Request-ID"},
 		AllowCredentials: true,
 		MaxAge:           300,
 	})
 
-	return c.Handler(router)
+	return c.Handler(withRequestID(router))
+}
+
+// uploadTimeout is how long a request to uploadRoutes has to send its body and get
+// the response; the server's own timeouts are sized for ordinary requests.
+const uploadTimeout = 30 * time.Minute
+
+// uploadDeadline extends the read and write deadlines of requests to uploadRoutes,
+// so a large file on a slow connection is not cut off mid-upload.
+func uploadDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPost && uploadRoutes[routeTemplate(r)] {
+			// Writers without deadlines (http.ErrNotSupported) have no timeouts to extend.
+			deadline := time.Now().Add(uploadTimeout)
+			rc := http.NewResponseController(w)
+			_ = rc.SetReadDeadline(deadline)
+			_ = rc.SetWriteDeadline(deadline)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// graphQLRoutes mounts the read-only GraphQL view of the library at /graphql.
+type graphQLRoutes struct {
+	handler http.Handler
+}
+
+// GraphQLRoutes serves h at POST /graphql behind the auth middleware.
+func GraphQLRoutes(h http.Handler) RouteModule {
+	return graphQLRoutes{handler: h}
+}
+
+func (g graphQLRoutes) RegisterRoutes(routes Routes) {
+	routes.Root.Handle("/graphql", routes.Authenticate(g.handler)).Methods(http.MethodPost)
 }
@@ -1,10 +1,13 @@
 package handler
 
 import (
+	"context"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"pdf-text-reader/internal/config"
 	"pdf-text-reader/internal/domain"
@@ -12,13 +15,28 @@ import (
 
 type MockHighlightService struct{}
 
-func (m *MockHighlightService) CreateHighlight(userID string, highlight *domain.Highlight, token string) (*domain.Highlight, error) {
-	return &domain.Highlight{ID: "h1", UserID: userID, DocumentID: highlight.DocumentID, Quote: highlight.Quote}, nil
+func (m *MockHighlightService) CreateHighlight(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) (*domain.Highlight, error) {
+	return &domain.Highlight{ID: "h1", UserID: principal.UserID, DocumentID: highlight.DocumentID, Quote: highlight.Quote}, nil
 }
-func (m *MockHighlightService) ListHighlights(userID string, documentID *string, token string) ([]*domain.Highlight, error) {
+func (m *MockHighlightService) ListHighlights(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
 	return []*domain.Highlight{}, nil
 }
-func (m *MockHighlightService) DeleteHighlight(userID string, highlightID string, token string) error { return nil }
+func (m *MockHighlightService) CountHighlights(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	return map[string]int{}, nil
+}
+func (m *MockHighlightService) DeleteHighlight(ctx context.Context, principal domain.Principal, highlightID string) error { return nil }
+func (m *MockHighlightService) SearchHighlights(ctx context.Context, principal domain.Principal, search domain.HighlightSearch) ([]*domain.Highlight, error) {
+	return []*domain.Highlight{}, nil
+}
+func (m *MockHighlightService) GetReview(ctx context.Context, principal domain.Principal, size int) (*domain.HighlightReview, error) {
+	return &domain.HighlightReview{Highlights: []*domain.Highlight{}}, nil
+}
+func (m *MockHighlightService) ReviewHighlight(ctx context.Context, principal domain.Principal, highlightID, rating string) (*domain.Highlight, error) {
+	return &domain.Highlight{ID: highlightID}, nil
+}
+func (m *MockHighlightService) SetFavorite(ctx context.Context, principal domain.Principal, highlightID string, isFavorite bool) (*domain.Highlight, error) {
+	return &domain.Highlight{ID: highlightID, IsFavorite: isFavorite}, nil
+}
 
 func TestNewRouter_Health(t *testing.T) {
 	docService := NewMockDocumentService()
@@ -27,12 +45,12 @@ func TestNewRouter_Health(t *testing.T) {
 	highlightService := &MockHighlightService{}
 
 	authHandler := NewAuthHandler(&config.Container{})
-	adminHandler := NewAdminHandler()
-	documentHandler := NewDocumentHandler(docService, prefService, logger)
+	adminHandler := NewAdminHandler(nil, nil, nil, nil, nil)
+	documentHandler := NewDocumentHandler(docService, prefService, nil, logger)
 	preferenceHandler := NewPreferenceHandler(&config.Container{UserPreferencesService: prefService}, logger)
 	highlightHandler := NewHighlightHandler(&config.Container{HighlightService: highlightService}, logger)
 
-	router := NewRouter(authHandler, adminHandler, documentHandler, preferenceHandler, highlightHandler, func(next http.Handler) http.Handler { return next })
+	router := NewRouter(func(next http.Handler) http.Handler { return next }, nil, Guards{}, authHandler, adminHandler, documentHandler, preferenceHandler, highlightHandler, NewLibraryHandler(&config.Container{}, logger), NewNotificationHandler(&config.Container{}, logger), NewImportHandler(&config.Container{}, logger))
 
 	req := httptest.NewRequest(http.MethodGet, "/health", nil)
 	rr := httptest.NewRecorder()
@@ -46,3 +64,362 @@ func TestNewRouter_Health(t *testing.T) {
 		t.Fatalf("unexpected response body: %s", rr.Body.String())
 	}
 }
+
+func TestNewRouter_AdminResilienceStats(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+
+	router := NewRouter(
+		func(next http.Handler) http.Handler { return next },
+		nil,
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, nil, nil),
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/resilience", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d without secret, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/resilience", nil)
+	req.Header.Set("X-Admin-Secret", "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `"executors"`) {
+		t.Fatalf("unexpected response body: %s", rr.Body.String())
+	}
+}
+
+func TestNewRouter_CORSAllowedOrigins(t *testing.T) {
+	router := NewRouter(
+		func(next http.Handler) http.Handler { return next },
+		[]string{"https://lector.thefndrs.com", "https://*.vercel.app"},
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, nil, nil),
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	tests := []struct {
+		origin  string
+		allowed bool
+	}{
+		{"https://lector.thefndrs.com", true},
+		{"https://lector-pr-42.vercel.app", true},
+		{"https://evil.example.com", false},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/health", nil)
+		req.Header.Set("Origin", tt.origin)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+
+		got := rr.Header().Get("Access-Control-Allow-Origin")
+		if tt.allowed && got != tt.origin {
+			t.Fatalf("expected origin %s to be allowed, got %q", tt.origin, got)
+		}
+		if !tt.allowed && got != "" {
+			t.Fatalf("expected origin %s to be rejected, got %q", tt.origin, got)
+		}
+	}
+}
+
+func TestNewRouter_AdminQuarantineRequiresSecret(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+
+	router := NewRouter(
+		func(next http.Handler) http.Handler { return next },
+		nil,
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, nil, nil),
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	for _, tc := range []struct{ method, path string }{
+		{http.MethodGet, "/api/v1/admin/quarantine"},
+		{http.MethodPost, "/api/v1/admin/quarantine/doc1/release"},
+	} {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		if rr.Code != http.StatusUnauthorized {
+			t.Fatalf("%s %s: expected status %d, got %d", tc.method, tc.path, http.StatusUnauthorized, rr.Code)
+		}
+	}
+}
+
+func TestNewRouter_CORSPreconditionHeaders(t *testing.T) {
+	router := NewRouter(
+		func(next http.Handler) http.Handler { return next },
+		[]string{"https://lector.thefndrs.com"},
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, nil, nil),
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	req := httptest.NewRequest(http.MethodOptions, "/api/v1/preferences", nil)
+	req.Header.Set("Origin", "https://lector.thefndrs.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPut)
+	req.Header.Set("Access-Control-Request-Headers", "if-match") // Browsers send lower-case names
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(got), "if-match") {
+		t.Fatalf("expected If-Match to be allowed, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/health", nil)
+	req.Header.Set("Origin", "https://lector.thefndrs.com")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Etag") && !strings.Contains(got, "ETag") {
+		t.Fatalf("expected ETag to be exposed, got %q", got)
+	}
+	if got := rr.Header().Get("Access-Control-Expose-Headers"); !strings.Contains(got, "Content-Range") || !strings.Contains(got, "Accept-Ranges") {
+		t.Fatalf("expected the range headers to be exposed, got %q", got)
+	}
+
+	req = httptest.NewRequest(http.MethodOptions, "/api/v1/files/doc1", nil)
+	req.Header.Set("Origin", "https://lector.thefndrs.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	req.Header.Set("Access-Control-Request-Headers", "range")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if got := rr.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(got), "range") {
+		t.Fatalf("expected Range to be allowed, got %q", got)
+	}
+}
+
+type mockMigrator struct {
+	statuses []domain.MigrationStatus
+	migrated bool
+}
+
+func (m *mockMigrator) Migrate(ctx context.Context) ([]domain.MigrationStatus, error) {
+	m.migrated = true
+	return nil, nil
+}
+
+func (m *mockMigrator) Status(ctx context.Context) ([]domain.MigrationStatus, error) {
+	return m.statuses, nil
+}
+
+func TestNewRouter_AdminMigrations(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+
+	migrator := &mockMigrator{statuses: []domain.MigrationStatus{{Version: 1, Name: "initial_schema"}}}
+	router := NewRouter(
+		func(next http.Handler) http.Handler { return next },
+		nil,
+		Guards{},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(migrator, nil, nil, nil, nil),
+		NewDocumentHandler(NewMockDocumentService(), NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/migrations", nil)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized || migrator.migrated {
+		t.Fatalf("expected status %d without secret, got %d", http.StatusUnauthorized, rr.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/v1/admin/migrations", nil)
+	req.Header.Set("X-Admin-Secret", "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"name":"initial_schema"`) {
+		t.Fatalf("unexpected status response %d: %s", rr.Code, rr.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/migrations", nil)
+	req.Header.Set("X-Admin-Secret", "s3cret")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK || !migrator.migrated {
+		t.Fatalf("expected migrations to be applied, got %d: %s", rr.Code, rr.Body.String())
+	}
+	if !strings.Contains(rr.Body.String(), `"applied":[]`) {
+		t.Fatalf("unexpected apply response: %s", rr.Body.String())
+	}
+}
+
+func TestNewRouter_MaintenanceMode(t *testing.T) {
+	t.Setenv("ADMIN_API_SECRET", "s3cret")
+
+	maintenance := &stubMaintenanceMode{}
+	documents := NewMockDocumentService()
+	documents.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user-1", Title: "Notes"}
+	router := NewRouter(
+		withTestPrincipal,
+		nil,
+		Guards{Maintenance: maintenance},
+		NewAuthHandler(&config.Container{}),
+		NewAdminHandler(nil, nil, nil, maintenance, nil),
+		NewDocumentHandler(documents, NewMockUserPreferencesService(), nil, NewMockHandlerLogger()),
+		NewPreferenceHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewHighlightHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewLibraryHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewNotificationHandler(&config.Container{}, NewMockHandlerLogger()),
+		NewImportHandler(&config.Container{}, NewMockHandlerLogger()),
+	)
+	serve := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("X-Admin-Secret", "s3cret")
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	rr := serve(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled":true,"message":"Migrating documents"}`)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), `"enabled":true`) {
+		t.Fatalf("enable: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = serve(http.MethodDelete, "/api/v1/documents/doc-1", "")
+	if rr.Code != http.StatusServiceUnavailable || rr.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected write to be refused with 503, got %d", rr.Code)
+	}
+	if !strings.Contains(rr.Body.String(), `"error":"Migrating documents"`) || !strings.Contains(rr.Body.String(), `"maintenance":true`) {
+		t.Fatalf("unexpected response body: %s", rr.Body.String())
+	}
+	for _, path := range []string{"/api/v1/documents/doc-1", "/api/v1/admin/maintenance"} {
+		if rr := serve(http.MethodGet, path, ""); rr.Code == http.StatusServiceUnavailable {
+			t.Fatalf("expected GET %s to stay available", path)
+		}
+	}
+	// Opening a document during maintenance does not record it.
+	if rr := serve(http.MethodGet, "/api/v1/documents/doc-1", ""); rr.Code != http.StatusOK || documents.documents["doc-1"].LastOpenedAt != nil {
+		t.Fatalf("expected the open not to be recorded during maintenance")
+	}
+	if rr := serve(http.MethodPost, "/api/v1/documents/compare", `{}`); rr.Code == http.StatusServiceUnavailable {
+		t.Fatalf("expected read-only POST to stay available")
+	}
+
+	serve(http.MethodPut, "/api/v1/admin/maintenance", `{"enabled":false}`)
+	if rr := serve(http.MethodDelete, "/api/v1/documents/doc-1", ""); rr.Code == http.StatusServiceUnavailable {
+		t.Fatalf("expected writes to resume after maintenance")
+	}
+}
+
+// stubMaintenanceMode is an in-memory maintenance switch.
+type stubMaintenanceMode struct {
+	status domain.MaintenanceStatus
+}
+
+func (m *stubMaintenanceMode) Status() domain.MaintenanceStatus {
+	return m.status
+}
+
+func (m *stubMaintenanceMode) Set(enabled bool, message string) domain.MaintenanceStatus {
+	m.status = domain.MaintenanceStatus{Enabled: enabled, Message: message}
+	return m.status
+}
+
+type testRouteModule struct{}
+
+func (testRouteModule) RegisterRoutes(routes Routes) {
+	routes.Protected.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}).Methods(http.MethodGet)
+}
+
+func TestNewRouter_RegistersModules(t *testing.T) {
+	authenticated := false
+	router := NewRouter(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authenticated = true
+			next.ServeHTTP(w, r)
+		})
+	}, nil, Guards{}, testRouteModule{})
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/ping", nil))
+	if rr.Code != http.StatusNoContent || !authenticated {
+		t.Fatalf("expected module route behind auth, got %d (authenticated=%v)", rr.Code, authenticated)
+	}
+}
+
+// slowUploadModule accepts uploads at POST /documents and echoes how much was read.
+type slowUploadModule struct{}
+
+func (slowUploadModule) RegisterRoutes(routes Routes) {
+	echo := func(w http.ResponseWriter, r *http.Request) {
+		n, err := io.Copy(io.Discard, r.Body)
+		if err != nil {
+			w.WriteHeader(http.StatusRequestTimeout)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = io.WriteString(w, strings.Repeat("x", int(n)))
+	}
+	routes.Protected.HandleFunc("/documents", echo).Methods(http.MethodPost)
+	routes.Protected.HandleFunc("/tags", echo).Methods(http.MethodPost)
+}
+
+func TestNewRouter_UploadsOutliveServerTimeouts(t *testing.T) {
+	srv := httptest.NewUnstartedServer(NewRouter(withTestPrincipal, nil, Guards{}, slowUploadModule{}))
+	srv.Config.ReadTimeout = 100 * time.Millisecond
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	post := func(path string) (int, error) {
+		body, send := io.Pipe()
+		go func() {
+			_, _ = io.WriteString(send, "first half ")
+			time.Sleep(300 * time.Millisecond)
+			_, _ = io.WriteString(send, "second half")
+			send.Close()
+		}()
+		resp, err := http.Post(srv.URL+path, "application/octet-stream", body)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		return resp.StatusCode, nil
+	}
+
+	if code, err := post("/api/v1/documents"); err != nil || code != http.StatusCreated {
+		t.Fatalf("expected the slow upload to complete, got %d (%v)", code, err)
+	}
+	if code, err := post("/api/v1/tags"); err == nil && code == http.StatusCreated {
+		t.Fatal("expected other routes to keep the server's read timeout")
+	}
+}
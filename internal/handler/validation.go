@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Request bodies declare their rules in a validate tag, checked by decodeRequest
+// after decoding:
+//
+//	required   present, and not empty for strings and lists
+//	notblank   not empty for strings and lists, but may be left out
+//	min=N      at least N characters, items or, for numbers, N
+//	max=N      at most N characters, items or, for numbers, N
+//	oneof=a b  one of the listed values
+//
+// Rules apply to the pointed-to value of pointer fields and are skipped when the
+// pointer is nil, so optional fields only need required when they must be sent.
+// Rules that span fields go in a Validate method on the request.
+
+// requestValidator is implemented by requests with rules that span several fields.
+type requestValidator interface {
+	Validate() domain.ValidationErrors
+}
+
+// decodeRequest decodes the JSON body into dst and checks its validate tags.
+// Strict decoding rejects unknown fields. Type mismatches, unknown fields and
+// failed rules are returned as ValidationErrors; any other error means the body
+// was not valid JSON.
+func decodeRequest(r *http.Request, dst any, strict bool) error {
+	decoder := json.NewDecoder(r.Body)
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(dst); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) && typeErr.Field != "" {
+			return domain.ValidationErrors{{Field: typeErr.Field, Message: "must be " + jsonTypeName(typeErr.Type)}}
+		}
+		if field, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+			return domain.ValidationErrors{{Field: strings.Trim(field, `"`), Message: "unknown field"}}
+		}
+		return err
+	}
+
+	errs := validateRequest(dst)
+	if v, ok := dst.(requestValidator); ok && len(errs) == 0 {
+		errs = v.Validate()
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateRequest checks the validate tags of the struct v points to.
+func validateRequest(v any) domain.ValidationErrors {
+	value := reflect.Indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var errs domain.ValidationErrors
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		rules, ok := field.Tag.Lookup("validate")
+		if !ok || !field.IsExported() {
+			continue
+		}
+		name := jsonFieldName(field)
+		if message := checkRules(value.Field(i), rules); message != "" {
+			errs = append(errs, &domain.ValidationError{Field: name, Message: name + " " + message})
+		}
+	}
+	return errs
+}
+
+// checkRules returns why v breaks the comma separated rules, or "" if it doesn't.
+func checkRules(v reflect.Value, rules string) string {
+	required := false
+	for _, rule := range strings.Split(rules, ",") {
+		if rule == "required" {
+			required = true
+		}
+	}
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if required {
+				return "is required"
+			}
+			return ""
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range strings.Split(rules, ",") {
+		name, arg, _ := strings.Cut(rule, "=")
+		switch name {
+		case "required":
+			if isEmpty(v) {
+				return "is required"
+			}
+		case "notblank":
+			if isEmpty(v) {
+				return "cannot be empty"
+			}
+		case "min", "max":
+			limit, err := strconv.ParseFloat(arg, 64)
+			if err != nil {
+				panic(fmt.Sprintf("validate: invalid %s rule %q", name, rule))
+			}
+			size, unit := measure(v)
+			if name == "min" && size < limit {
+				return fmt.Sprintf("must be at least %s%s", arg, unit)
+			}
+			if name == "max" && size > limit {
+				return fmt.Sprintf("must be at most %s%s", arg, unit)
+			}
+		case "oneof":
+			options := strings.Fields(arg)
+			if v.Kind() == reflect.String && !isEmpty(v) && !contains(options, v.String()) {
+				return "must be " + listOptions(options)
+			}
+		default:
+			panic(fmt.Sprintf("validate: unknown rule %q", rule))
+		}
+	}
+	return ""
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.String:
+		return strings.TrimSpace(v.String()) == ""
+	case reflect.Slice, reflect.Map:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// measure returns the size min and max compare against: the length of strings and
+// lists, or the value of numbers.
+func measure(v reflect.Value) (float64, string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	panic("validate: min and max need a string, list or number, got " + v.Kind().String())
+}
+
+func contains(options []string, s string) bool {
+	for _, option := range options {
+		if option == s {
+			return true
+		}
+	}
+	return false
+}
+
+// listOptions joins options the way the domain validation messages do: "a, b or c".
+func listOptions(options []string) string {
+	if len(options) == 1 {
+		return options[0]
+	}
+	return strings.Join(options[:len(options)-1], ", ") + " or " + options[len(options)-1]
+}
+
+// jsonFieldName is the name clients use for the field.
+func jsonFieldName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// jsonTypeName describes a Go type the way API clients see it in JSON.
+func jsonTypeName(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "a list"
+	case reflect.Int, reflect.Int64:
+		return "an integer"
+	case reflect.Float64:
+		return "a number"
+	default:
+		return "an object"
+	}
+}
+
+// writeValidationErrors answers a request rejected by decodeRequest with 400.
+// Validation errors list every invalid field under "fields"; other errors mean the
+// body was not valid JSON.
+func writeValidationErrors(w http.ResponseWriter, message string, err error) {
+	var validationErrs domain.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		writeError(w, http.StatusBadRequest, "Invalid request body")
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusBadRequest)
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"error":  message,
+		"fields": validationErrs,
+	})
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type testValidatedRequest struct {
+	Name     string   `json:"name" validate:"required,max=5"`
+	Title    *string  `json:"title" validate:"notblank"`
+	Platform string   `json:"platform" validate:"oneof=ios android web"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Count    *int     `json:"count" validate:"min=1"`
+	Other    string   `json:"other"`
+}
+
+func (req *testValidatedRequest) Validate() domain.ValidationErrors {
+	if req.Other == req.Name {
+		return domain.ValidationErrors{{Field: "other", Message: "other must differ from name"}}
+	}
+	return nil
+}
+
+func TestDecodeRequest(t *testing.T) {
+	tests := []struct {
+		name       string
+		body       string
+		strict     bool
+		wantFields []string
+		wantJSON   bool
+	}{
+		{name: "valid", body: `{"name":"ok","platform":"ios","count":1}`},
+		{name: "missing required", body: `{}`, wantFields: []string{"name"}},
+		{name: "every invalid field", body: `{"name":"toolong","title":" ","platform":"mac","tags":["a","b","c"],"count":0}`,
+			wantFields: []string{"name", "title", "platform", "tags", "count"}},
+		{name: "cross-field rule", body: `{"name":"same","other":"same"}`, wantFields: []string{"other"}},
+		{name: "wrong type", body: `{"name":1}`, wantFields: []string{"name"}},
+		{name: "unknown field when strict", body: `{"name":"ok","extra":true}`, strict: true, wantFields: []string{"extra"}},
+		{name: "malformed", body: `{`, wantJSON: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(tt.body))
+			err := decodeRequest(req, &testValidatedRequest{}, tt.strict)
+
+			var errs domain.ValidationErrors
+			if tt.wantJSON {
+				if err == nil || errors.As(err, &errs) {
+					t.Fatalf("expected a JSON error, got %v", err)
+				}
+				return
+			}
+			if len(tt.wantFields) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+			if !errors.As(err, &errs) || len(errs) != len(tt.wantFields) {
+				t.Fatalf("expected errors for %v, got %v", tt.wantFields, err)
+			}
+			for i, field := range tt.wantFields {
+				if errs[i].Field != field {
+					t.Errorf("errors[%d] is for %q, want %q", i, errs[i].Field, field)
+				}
+			}
+		})
+	}
+}
+
+func TestWriteValidationErrors(t *testing.T) {
+	rr := httptest.NewRecorder()
+	writeValidationErrors(rr, "Invalid highlight", domain.ValidationErrors{{Field: "quote", Message: "quote is required"}})
+
+	var body struct {
+		Error  string                    `json:"error"`
+		Fields []*domain.ValidationError `json:"fields"`
+	}
+	if err := json.Unmarshal(rr.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusBadRequest || body.Error != "Invalid highlight" || len(body.Fields) != 1 || body.Fields[0].Field != "quote" {
+		t.Fatalf("unexpected response: %d %s", rr.Code, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	writeValidationErrors(rr, "Invalid highlight", errors.New("unexpected EOF"))
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "Invalid request body") {
+		t.Fatalf("unexpected response for malformed body: %d %s", rr.Code, rr.Body.String())
+	}
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gorilla/mux"
+)
+
+// tileURLTemplate is where clients fetch the tiles listed in a render manifest.
+const tileURLTemplate = "/api/v1/documents/%s/render/tiles/{page}/{level}/{column}/{row}"
+
+// ViewerHandler serves the fixed-layout viewer, which shows PDF pages as rendered
+// images instead of reflowed text.
+type ViewerHandler struct {
+	logger        domain.Logger
+	renderService domain.PageRenderService
+}
+
+func NewViewerHandler(container *config.Container, logger domain.Logger) *ViewerHandler {
+	return &ViewerHandler{
+		logger:        logger,
+		renderService: container.PageRenderService,
+	}
+}
+
+// RegisterRoutes adds the fixed-layout viewer endpoints.
+func (h *ViewerHandler) RegisterRoutes(routes Routes) {
+	r := routes.Protected
+
+	// Render a PDF's pages to image tiles, and the manifest describing them
+	r.HandleFunc("/documents/{id}/render", h.RenderPages).Methods(http.MethodPost)
+	r.HandleFunc("/documents/{id}/render", h.GetRenderManifest).Methods(http.MethodGet)
+	r.HandleFunc("/documents/{id}/render/tiles/{page}/{level}/{column}/{row}", h.GetTile).Methods(http.MethodGet)
+
+	// Word boxes of a page, for selecting text on the rendered images
+	r.HandleFunc("/documents/{id}/pages/{page}/layout", h.GetPageLayout).Methods(http.MethodGet)
+}
+
+// RenderPages handles POST /documents/{id}/render: starts rendering in the background
+// and answers 202 with the manifest so far, or 200 once the pages are rendered.
+func (h *ViewerHandler) RenderPages(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	documentID := mux.Vars(r)["id"]
+
+	manifest, err := h.renderService.RenderPages(r.Context(), principal, documentID)
+	if err != nil {
+		h.writeRenderError(w, err, principal, documentID)
+		return
+	}
+	status := http.StatusOK
+	if manifest.Status == domain.PageRenderRendering {
+		status = http.StatusAccepted
+	}
+	h.writeManifest(w, status, manifest)
+}
+
+// GetRenderManifest handles GET /documents/{id}/render.
+func (h *ViewerHandler) GetRenderManifest(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	documentID := mux.Vars(r)["id"]
+
+	manifest, err := h.renderService.GetRenderManifest(r.Context(), principal, documentID)
+	if err != nil {
+		h.writeRenderError(w, err, principal, documentID)
+		return
+	}
+	h.writeManifest(w, http.StatusOK, manifest)
+}
+
+// GetTile handles GET /documents/{id}/render/tiles/{page}/{level}/{column}/{row}
+// and sends the PNG tile.
+func (h *ViewerHandler) GetTile(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+
+	var coords [4]int
+	for i, name := range []string{"page", "level", "column", "row"} {
+		n, err := strconv.Atoi(vars[name])
+		if err != nil {
+			h.writeError(w, http.StatusBadRequest, name+" must be an integer")
+			return
+		}
+		coords[i] = n
+	}
+
+	tile, err := h.renderService.OpenTile(r.Context(), principal, documentID, coords[0], coords[1], coords[2], coords[3])
+	if err != nil {
+		h.writeRenderError(w, err, principal, documentID)
+		return
+	}
+	defer tile.Close()
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	w.WriteHeader(http.StatusOK)
+	_, _ = io.Copy(w, tile)
+}
+
+// GetPageLayout handles GET /documents/{id}/pages/{page}/layout: the page's text
+// and the box of each word, in points from the top-left corner of the page.
+func (h *ViewerHandler) GetPageLayout(w http.ResponseWriter, r *http.Request) {
+	principal, ok := GetPrincipalFromContext(r)
+	if !ok {
+		h.writeError(w, http.StatusUnauthorized, "User not found in context")
+		return
+	}
+	vars := mux.Vars(r)
+	documentID := vars["id"]
+	page, err := strconv.Atoi(vars["page"])
+	if err != nil {
+		h.writeError(w, http.StatusBadRequest, "page must be an integer")
+		return
+	}
+
+	layout, err := h.renderService.GetPageLayout(r.Context(), principal, documentID, page)
+	if err != nil {
+		h.writeRenderError(w, err, principal, documentID)
+		return
+	}
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	h.writeJSON(w, http.StatusOK, layout)
+}
+
+func (h *ViewerHandler) writeManifest(w http.ResponseWriter, status int, manifest *domain.PageRenderManifest) {
+	manifest.TileURL = fmt.Sprintf(tileURLTemplate, manifest.DocumentID)
+	h.writeJSON(w, status, manifest)
+}
+
+func (h *ViewerHandler) writeRenderError(w http.ResponseWriter, err error, principal domain.Principal, documentID string) {
+	switch {
+	case errors.Is(err, domain.ErrDocumentNotFound), errors.Is(err, domain.ErrRenderNotFound), errors.Is(err, domain.ErrPageNotFound):
+		h.writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, domain.ErrAccessDenied):
+		h.writeError(w, http.StatusForbidden, "Access denied")
+	case errors.Is(err, domain.ErrDocumentQuarantined):
+		h.writeError(w, http.StatusLocked, "Document is held for security review")
+	case errors.Is(err, domain.ErrNotRenderable):
+		h.writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case errors.Is(err, domain.ErrPageRenderDisabled):
+		h.writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		h.logger.Error("Page render request failed", err, "user_id", principal.UserID, "doc_id", documentID)
+		writeServerError(w, err, "Failed to render pages")
+	}
+}
+
+func (h *ViewerHandler) writeJSON(w http.ResponseWriter, statusCode int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(data)
+}
+
+func (h *ViewerHandler) writeError(w http.ResponseWriter, statusCode int, message string) {
+	h.writeJSON(w, statusCode, map[string]string{"error": message})
+}
@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/config"
+	"pdf-text-reader/internal/domain"
+)
+
+type stubPageRenderService struct{}
+
+func (stubPageRenderService) RenderPages(ctx context.Context, principal domain.Principal, documentID string) (*domain.PageRenderManifest, error) {
+	switch documentID {
+	case "book":
+		return nil, domain.ErrNotRenderable
+	case "paper":
+		return &domain.PageRenderManifest{DocumentID: documentID, Status: domain.PageRenderRendering}, nil
+	}
+	return nil, domain.ErrDocumentNotFound
+}
+
+func (stubPageRenderService) GetRenderManifest(ctx context.Context, principal domain.Principal, documentID string) (*domain.PageRenderManifest, error) {
+	return nil, domain.ErrRenderNotFound
+}
+
+func (stubPageRenderService) OpenTile(ctx context.Context, principal domain.Principal, documentID string, page, level, column, row int) (io.ReadCloser, error) {
+	if page != 1 || level != 0 || column != 0 || row != 0 {
+		return nil, domain.ErrPageNotFound
+	}
+	return io.NopCloser(strings.NewReader("\x89PNG")), nil
+}
+
+func (stubPageRenderService) GetPageLayout(ctx context.Context, principal domain.Principal, documentID string, page int) (*domain.PageLayout, error) {
+	if page != 1 {
+		return nil, domain.ErrPageNotFound
+	}
+	return &domain.PageLayout{DocumentID: documentID, Page: page, Text: "Hello world", Words: []domain.LayoutWord{
+		{Text: "Hello", X: 72, Y: 72, Width: 24, Height: 11},
+		{Text: "world", Offset: 6, X: 99, Y: 72, Width: 25, Height: 11},
+	}}, nil
+}
+
+func TestViewerHandler_Render(t *testing.T) {
+	h := NewViewerHandler(&config.Container{PageRenderService: stubPageRenderService{}}, NewMockHandlerLogger())
+	router := NewRouter(withTestPrincipal, nil, Guards{}, h)
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, httptest.NewRequest(method, path, nil))
+		return rr
+	}
+
+	rr := serve(http.MethodPost, "/api/v1/documents/paper/render")
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("render: expected 202, got %d", rr.Code)
+	}
+	var manifest domain.PageRenderManifest
+	if err := json.NewDecoder(rr.Body).Decode(&manifest); err != nil {
+		t.Fatal(err)
+	}
+	if manifest.TileURL != "/api/v1/documents/paper/render/tiles/{page}/{level}/{column}/{row}" {
+		t.Errorf("tile_url = %q", manifest.TileURL)
+	}
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodPost, "/api/v1/documents/book/render", http.StatusUnprocessableEntity},
+		{http.MethodPost, "/api/v1/documents/nope/render", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/documents/paper/render", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/documents/paper/render/tiles/1/0/0/0", http.StatusOK},
+		{http.MethodGet, "/api/v1/documents/paper/render/tiles/1/0/0/9", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/documents/paper/render/tiles/one/0/0/0", http.StatusBadRequest},
+		{http.MethodGet, "/api/v1/documents/paper/pages/2/layout", http.StatusNotFound},
+		{http.MethodGet, "/api/v1/documents/paper/pages/one/layout", http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if rr := serve(tt.method, tt.path); rr.Code != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, rr.Code)
+		}
+	}
+}
+
+func TestViewerHandler_GetPageLayout(t *testing.T) {
+	h := NewViewerHandler(&config.Container{PageRenderService: stubPageRenderService{}}, NewMockHandlerLogger())
+	router := NewRouter(withTestPrincipal, nil, Guards{}, h)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/documents/paper/pages/1/layout", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rr.Code)
+	}
+	var layout domain.PageLayout
+	if err := json.NewDecoder(rr.Body).Decode(&layout); err != nil {
+		t.Fatal(err)
+	}
+	if len(layout.Words) != 2 || layout.Words[1].Offset != 6 || layout.Words[1].X != 99 {
+		t.Errorf("unexpected layout: %+v", layout)
+	}
+}
@@ -0,0 +1,62 @@
+// Package blobstore provides domain.BlobStore implementations.
+package blobstore
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// New builds the blob store selected by STORAGE_BACKEND ("supabase", "s3" or "local").
+// An empty backend means Supabase.
+func New(config domain.Config, logger domain.Logger) (domain.BlobStore, error) {
+	switch config.GetStorageBackend() {
+	case "", "supabase":
+		return NewSupabase(config.GetSupabaseURL(), config.GetSupabaseKey()), nil
+	case "s3":
+		store, err := NewS3(config.GetS3Config())
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "local":
+		store, err := NewLocal(config.GetLocalStorageDir(), config.GetPublicURL(), config.GetJWTSecret())
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Storing files on the local filesystem", "dir", config.GetLocalStorageDir())
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", config.GetStorageBackend())
+	}
+}
+
+// byteRange is the Range header asking for length bytes from offset.
+func byteRange(offset, length int64) string {
+	return fmt.Sprintf("bytes=%d-%d", offset, offset+length-1)
+}
+
+// rangedBody returns length bytes of a successful response body from offset; a
+// negative length reads to the end. A 206 body already starts at offset, while a
+// 200 body is the whole file, as sent by servers that ignore Range.
+func rangedBody(resp *http.Response, offset, length int64) (io.ReadCloser, error) {
+	body := resp.Body
+	if resp.StatusCode == http.StatusOK && offset > 0 {
+		if _, err := io.CopyN(io.Discard, body, offset); err != nil {
+			body.Close()
+			return nil, fmt.Errorf("failed to skip to offset %d: %w", offset, err)
+		}
+	}
+	if length < 0 {
+		return body, nil
+	}
+	return limitedBody{Reader: io.LimitReader(body, length), Closer: body}, nil
+}
+
+// limitedBody reads part of a file and closes the whole of it.
+type limitedBody struct {
+	io.Reader
+	io.Closer
+}
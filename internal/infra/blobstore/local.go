@@ -0,0 +1,212 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// LocalFilesPrefix is the URL path under which Local serves signed downloads.
+const LocalFilesPrefix = "/files/"
+
+// Local stores blobs on the local filesystem for self-hosted installs. Signed URLs
+// point back at this server and are verified with an HMAC, so Local is also the
+// http.Handler that serves them under LocalFilesPrefix.
+type Local struct {
+	root      string
+	publicURL string
+	key       []byte
+	now       func() time.Time
+}
+
+// NewLocal stores files under dir. publicURL is the server's externally reachable
+// base URL and signingKey authenticates the download links.
+func NewLocal(dir, publicURL, signingKey string) (*Local, error) {
+	if signingKey == "" {
+		return nil, fmt.Errorf("a signing key is required for the local storage backend")
+	}
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory: %w", err)
+	}
+	return &Local{
+		root:      dir,
+		publicURL: strings.TrimRight(publicURL, "/"),
+		key:       []byte(signingKey),
+		now:       time.Now,
+	}, nil
+}
+
+// Upload implements domain.BlobStore. Files are written to a temporary name and
+// renamed so readers never see a partial file.
+func (l *Local) Upload(ctx context.Context, path string, file io.Reader, contentType string, token string) error {
+	target, err := l.resolve(path)
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o750); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".upload-*")
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, file); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
+
+// SignedURL implements domain.BlobStore.
+func (l *Local) SignedURL(ctx context.Context, path string, expiresIn time.Duration, token string) (string, error) {
+	if _, err := l.resolve(path); err != nil {
+		return "", fmt.Errorf("failed to sign file url: %w", err)
+	}
+	expires := strconv.FormatInt(l.now().Add(expiresIn).Unix(), 10)
+	return fmt.Sprintf("%s%s%s?expires=%s&signature=%s",
+		l.publicURL, LocalFilesPrefix, uriEncode(path, false), expires, l.sign(path, expires)), nil
+}
+
+// Delete implements domain.BlobStore. Deleting a missing file succeeds.
+func (l *Local) Delete(ctx context.Context, path string, token string) error {
+	target, err := l.resolve(path)
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Exists implements domain.BlobStore.
+func (l *Local) Exists(ctx context.Context, path string, token string) (bool, error) {
+	_, err := l.Stat(ctx, path, token)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Stat implements domain.BlobStore.
+func (l *Local) Stat(ctx context.Context, path string, token string) (int64, error) {
+	target, err := l.resolve(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to check file: %w", err)
+	}
+	info, err := os.Stat(target)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.Mode().IsRegular()) {
+		return 0, domain.ErrBlobNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to check file: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// Download implements domain.BlobStore.
+func (l *Local) Download(ctx context.Context, path string, token string) (io.ReadCloser, error) {
+	return l.open(path)
+}
+
+// DownloadRange implements domain.RangeDownloader by seeking in the file.
+func (l *Local) DownloadRange(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error) {
+	file, err := l.open(path)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := file.Seek(offset, io.SeekStart); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return limitedBody{Reader: io.LimitReader(file, length), Closer: file}, nil
+}
+
+func (l *Local) open(path string) (*os.File, error) {
+	target, err := l.resolve(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	file, err := os.Open(target)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", domain.ErrBlobNotFound, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return file, nil
+}
+
+// ServeHTTP serves a file for a URL produced by SignedURL. The link is the
+// credential, so any origin may fetch it (fonts loaded via @font-face need CORS).
+func (l *Local) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, LocalFilesPrefix)
+	expires := r.URL.Query().Get("expires")
+	signature := r.URL.Query().Get("signature")
+	expiresAt, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || l.now().Unix() > expiresAt || !hmac.Equal([]byte(signature), []byte(l.sign(path, expires))) {
+		http.Error(w, "invalid or expired link", http.StatusForbidden)
+		return
+	}
+
+	target, err := l.resolve(path)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	file, err := os.Open(target)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || !info.Mode().IsRegular() {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// resolve maps a store path to a file below the root, rejecting paths that would
+// escape it.
+func (l *Local) resolve(path string) (string, error) {
+	local := filepath.FromSlash(path)
+	if path == "" || !filepath.IsLocal(local) {
+		return "", fmt.Errorf("invalid path %q", path)
+	}
+	return filepath.Join(l.root, local), nil
+}
+
+func (l *Local) sign(path, expires string) string {
+	return hex.EncodeToString(hmacSHA256(l.key, path+"\n"+expires))
+}
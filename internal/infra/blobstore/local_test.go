@@ -0,0 +1,133 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestLocal_RoundTrip(t *testing.T) {
+	store, err := NewLocal(t.TempDir(), "http://localhost:8080/", "secret")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+	path := "user-1/fonts/inter.woff2"
+
+	if ok, _ := store.Exists(ctx, path, ""); ok {
+		t.Fatalf("expected file to be missing before upload")
+	}
+	if err := store.Upload(ctx, path, strings.NewReader("wOF2"), "font/woff2", ""); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if ok, err := store.Exists(ctx, path, ""); err != nil || !ok {
+		t.Fatalf("Exists() = %v, %v; want true", ok, err)
+	}
+	if size, err := store.Stat(ctx, path, ""); err != nil || size != 4 {
+		t.Fatalf("Stat() = %d, %v; want 4", size, err)
+	}
+
+	file, err := store.Download(ctx, path, "")
+	if err != nil {
+		t.Fatalf("Download: %v", err)
+	}
+	if body, _ := io.ReadAll(file); string(body) != "wOF2" {
+		t.Fatalf("unexpected download %q", body)
+	}
+	file.Close()
+
+	part, err := store.DownloadRange(ctx, path, 1, 2, "")
+	if err != nil {
+		t.Fatalf("DownloadRange: %v", err)
+	}
+	if body, _ := io.ReadAll(part); string(body) != "OF" {
+		t.Fatalf("unexpected range %q", body)
+	}
+	part.Close()
+
+	signed, err := store.SignedURL(ctx, path, time.Minute, "")
+	if err != nil {
+		t.Fatalf("SignedURL: %v", err)
+	}
+	if !strings.HasPrefix(signed, "http://localhost:8080/files/user-1/fonts/inter.woff2?") {
+		t.Fatalf("unexpected signed url %s", signed)
+	}
+
+	rr := httptest.NewRecorder()
+	store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, signed, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d", http.StatusOK, rr.Code)
+	}
+	if body, _ := io.ReadAll(rr.Body); string(body) != "wOF2" {
+		t.Fatalf("unexpected body %q", body)
+	}
+
+	if err := store.Delete(ctx, path, ""); err != nil {
+		t.Fatalf("Delete: %v", err)
+	}
+	if err := store.Delete(ctx, path, ""); err != nil {
+		t.Fatalf("expected deleting a missing file to succeed, got %v", err)
+	}
+	if ok, _ := store.Exists(ctx, path, ""); ok {
+		t.Fatalf("expected file to be gone after delete")
+	}
+	if _, err := store.Download(ctx, path, ""); !errors.Is(err, domain.ErrBlobNotFound) {
+		t.Fatalf("Download after delete = %v; want ErrBlobNotFound", err)
+	}
+}
+
+func TestLocal_ServeHTTP_RejectsBadLinks(t *testing.T) {
+	store, err := NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	ctx := context.Background()
+	if err := store.Upload(ctx, "user-1/book.pdf", strings.NewReader("%PDF"), "application/pdf", ""); err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	signed, _ := store.SignedURL(ctx, "user-1/book.pdf", time.Minute, "")
+
+	tests := []struct {
+		name string
+		url  string
+	}{
+		{"tampered path", strings.Replace(signed, "user-1", "user-2", 1)},
+		{"tampered expiry", strings.Replace(signed, "expires=", "expires=9", 1)},
+		{"missing signature", strings.Split(signed, "&signature=")[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rr.Code != http.StatusForbidden {
+				t.Fatalf("expected status %d, got %d", http.StatusForbidden, rr.Code)
+			}
+		})
+	}
+
+	store.now = func() time.Time { return time.Now().Add(time.Hour) }
+	rr := httptest.NewRecorder()
+	store.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, signed, nil))
+	if rr.Code != http.StatusForbidden {
+		t.Fatalf("expected expired link to be rejected, got %d", rr.Code)
+	}
+}
+
+func TestLocal_RejectsEscapingPaths(t *testing.T) {
+	store, err := NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	if err != nil {
+		t.Fatalf("NewLocal: %v", err)
+	}
+	for _, path := range []string{"../etc/passwd", "/etc/passwd", "user-1/../../x", ""} {
+		if err := store.Upload(context.Background(), path, strings.NewReader("x"), "", ""); err == nil {
+			t.Fatalf("expected upload to %q to be rejected", path)
+		}
+	}
+}
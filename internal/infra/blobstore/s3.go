@@ -0,0 +1,367 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
+)
+
+// maxPresignExpiry is the longest validity S3 accepts for a presigned URL.
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+const unsignedPayload = "UNSIGNED-PAYLOAD"
+
+// S3 stores blobs in an S3-compatible bucket, signing requests with AWS Signature
+// Version 4. It uses server credentials, so access control is left to the API layer,
+// which only hands out paths under the caller's user ID.
+type S3 struct {
+	config     domain.S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+	guard      *resilience.Executor
+	now        func() time.Time
+}
+
+// NewS3 creates an S3 blob store. Endpoint, bucket and credentials are required.
+func NewS3(config domain.S3Config) (*S3, error) {
+	if config.Endpoint == "" || config.Bucket == "" || config.AccessKeyID == "" || config.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3_ENDPOINT, S3_BUCKET, S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for the s3 storage backend")
+	}
+	endpoint, err := url.Parse(strings.TrimRight(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3_ENDPOINT %q", config.Endpoint)
+	}
+	if config.Region == "" {
+		config.Region = "us-east-1"
+	}
+
+	return &S3{
+		config:     config,
+		endpoint:   endpoint,
+		httpClient: &http.Client{Timeout: 60 * time.Second},
+		guard:      resilience.NewExecutor("storage", resilience.DefaultPolicy()),
+		now:        time.Now,
+	}, nil
+}
+
+// Upload implements domain.BlobStore. The body is buffered to compute the payload hash.
+func (s *S3) Upload(ctx context.Context, path string, file io.Reader, contentType string, token string) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file: %w", err)
+	}
+	sum := sha256.Sum256(data)
+
+	err = s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.objectURL(path).String(), bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		if contentType != "" {
+			req.Header.Set("Content-Type", contentType)
+		}
+		return s.do(req, hex.EncodeToString(sum[:]), http.StatusOK)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+	return nil
+}
+
+// SignedURL implements domain.BlobStore with a presigned GET URL.
+func (s *S3) SignedURL(ctx context.Context, path string, expiresIn time.Duration, token string) (string, error) {
+	if expiresIn <= 0 || expiresIn > maxPresignExpiry {
+		return "", fmt.Errorf("failed to sign file url: expiry must be between 1s and %s", maxPresignExpiry)
+	}
+	return s.presign(http.MethodGet, path, expiresIn), nil
+}
+
+// Delete implements domain.BlobStore. Deleting a missing object succeeds.
+func (s *S3) Delete(ctx context.Context, path string, token string) error {
+	err := s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.objectURL(path).String(), nil)
+		if err != nil {
+			return err
+		}
+		return s.do(req, emptySHA256, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+	return nil
+}
+
+// Exists implements domain.BlobStore with a HEAD request.
+func (s *S3) Exists(ctx context.Context, path string, token string) (bool, error) {
+	_, err := s.head(ctx, path, token)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Stat implements domain.BlobStore with a HEAD request.
+func (s *S3) Stat(ctx context.Context, path string, token string) (int64, error) {
+	size, err := s.head(ctx, path, token)
+	if err == nil && size < 0 {
+		err = fmt.Errorf("failed to check file: no content length")
+	}
+	return size, err
+}
+
+// head sends a HEAD request for path and returns the object's size, -1 when the
+// response does not say.
+func (s *S3) head(ctx context.Context, path string, token string) (int64, error) {
+	var size int64
+	err := s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.objectURL(path).String(), nil)
+		if err != nil {
+			return err
+		}
+		s.sign(req, emptySHA256)
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			size = resp.ContentLength
+			return nil
+		case http.StatusNotFound:
+			return domain.ErrBlobNotFound
+		default:
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to check file: %w", err)
+	}
+	return size, nil
+}
+
+// Download implements domain.BlobStore with a GET request. The body is returned
+// unread; retries only cover getting a response.
+func (s *S3) Download(ctx context.Context, path string, token string) (io.ReadCloser, error) {
+	return s.download(ctx, path, 0, -1)
+}
+
+// DownloadRange implements domain.RangeDownloader with a ranged GET request.
+func (s *S3) DownloadRange(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error) {
+	return s.download(ctx, path, offset, length)
+}
+
+// download gets length bytes of the object from offset; a negative length gets the
+// whole object without a Range header.
+func (s *S3) download(ctx context.Context, path string, offset, length int64) (io.ReadCloser, error) {
+	var body io.ReadCloser
+	err := s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.objectURL(path).String(), nil)
+		if err != nil {
+			return err
+		}
+		if length >= 0 {
+			req.Header.Set("Range", byteRange(offset, length))
+		}
+		s.sign(req, emptySHA256)
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		if resp.StatusCode == http.StatusOK || resp.StatusCode == http.StatusPartialContent {
+			body, err = rangedBody(resp, offset, length)
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return domain.ErrBlobNotFound
+		}
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return body, nil
+}
+
+// do signs and sends req, failing unless the response status is one of ok.
+func (s *S3) do(req *http.Request, payloadHash string, ok ...int) error {
+	s.sign(req, payloadHash)
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	for _, status := range ok {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 request failed with status %d: %s", resp.StatusCode, body)
+}
+
+// objectURL addresses key path-style or virtual-hosted-style.
+func (s *S3) objectURL(key string) *url.URL {
+	host := s.endpoint.Host
+	path := s.endpoint.EscapedPath()
+	if s.config.PathStyle {
+		path += "/" + uriEncode(s.config.Bucket, true)
+	} else {
+		host = s.config.Bucket + "." + host
+	}
+	// The escaped form is what gets signed; url.Parse keeps it as RawPath.
+	u, _ := url.Parse(s.endpoint.Scheme + "://" + host + path + "/" + uriEncode(key, false))
+	return u
+}
+
+// emptySHA256 is the hex SHA-256 of an empty payload.
+const emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+// sign adds SigV4 authorization headers to req.
+func (s *S3) sign(req *http.Request, payloadHash string) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	headers := map[string]string{
+		"host":                 req.URL.Host,
+		"x-amz-content-sha256": payloadHash,
+		"x-amz-date":           amzDate,
+	}
+	if contentType := req.Header.Get("Content-Type"); contentType != "" {
+		headers["content-type"] = contentType
+	}
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(headers)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := s.scope(now)
+	signature := s.signature(now, scope, amzDate, canonicalRequest)
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// presign returns a URL for method on key authorized by query parameters.
+func (s *S3) presign(method, key string, expiresIn time.Duration) string {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	scope := s.scope(now)
+	u := s.objectURL(key)
+
+	query := url.Values{}
+	query.Set("X-Amz-Algorithm", "AWS4-HMAC-SHA256")
+	query.Set("X-Amz-Credential", s.config.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", amzDate)
+	query.Set("X-Amz-Expires", strconv.Itoa(int(expiresIn.Seconds())))
+	query.Set("X-Amz-SignedHeaders", "host")
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		u.EscapedPath(),
+		canonicalQuery(query),
+		"host:" + u.Host + "\n",
+		"host",
+		unsignedPayload,
+	}, "\n")
+
+	u.RawQuery = canonicalQuery(query) + "&X-Amz-Signature=" + s.signature(now, scope, amzDate, canonicalRequest)
+	return u.String()
+}
+
+func (s *S3) scope(now time.Time) string {
+	return now.Format("20060102") + "/" + s.config.Region + "/s3/aws4_request"
+}
+
+func (s *S3) signature(now time.Time, scope, amzDate, canonicalRequest string) string {
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(hash[:])
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), now.Format("20060102"))
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+func canonicalizeHeaders(headers map[string]string) (canonical, signed string) {
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	return b.String(), strings.Join(names, ";")
+}
+
+// canonicalQuery encodes query sorted by key, as SigV4 requires.
+func canonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var parts []string
+	for _, key := range keys {
+		values := append([]string(nil), query[key]...)
+		sort.Strings(values)
+		for _, value := range values {
+			parts = append(parts, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but unreserved characters (RFC 3986), and
+// '/' unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9',
+			c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
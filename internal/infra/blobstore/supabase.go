@@ -0,0 +1,239 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
+
+	storage_go "github.com/supabase-community/storage-go"
+)
+
+// storageBucket is the bucket holding uploaded documents and their extracted assets.
+const storageBucket = "documents"
+
+// Supabase stores blobs in Supabase Storage. Requests carry the user's token so
+// storage RLS policies apply.
+type Supabase struct {
+	baseURL       string
+	apiKey        string
+	storageClient *storage_go.Client
+	httpClient    *http.Client
+	guard         *resilience.Executor
+}
+
+func NewSupabase(
+	baseURL string,
+	apiKey string,
+) *Supabase {
+	storageURL := baseURL + "/storage/v1"
+	storageClient := storage_go.NewClient(storageURL, apiKey, nil)
+
+	return &Supabase{
+		baseURL:       baseURL,
+		apiKey:        apiKey,
+		storageClient: storageClient,
+		httpClient:    &http.Client{Timeout: 30 * time.Second},
+		guard:         resilience.NewExecutor("storage", resilience.DefaultPolicy()),
+	}
+}
+
+func (s *Supabase) Upload(
+	ctx context.Context,
+	path string,
+	file io.Reader,
+	contentType string,
+	token string,
+) error {
+	storageClient := s.userClient(token)
+
+	var opts []storage_go.FileOptions
+	if contentType != "" {
+		opts = append(opts, storage_go.FileOptions{ContentType: &contentType})
+	}
+
+	upload := func() error {
+		_, err := storageClient.UploadFile(storageBucket, path, file, opts...)
+		return err
+	}
+
+	// The body can only be replayed when it is seekable; otherwise upload once.
+	var err error
+	if seeker, ok := file.(io.Seeker); ok {
+		err = s.guard.Do(ctx, func() error {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return err
+			}
+			return upload()
+		})
+	} else {
+		err = s.guard.Once(ctx, upload)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to upload file: %w", err)
+	}
+
+	return nil
+}
+
+// SignedURL creates a signed download URL for path. The user's token is used so
+// storage RLS policies decide whether the object may be shared.
+func (s *Supabase) SignedURL(
+	ctx context.Context,
+	path string,
+	expiresIn time.Duration,
+	token string,
+) (string, error) {
+	storageClient := s.userClient(token)
+
+	var signedURL string
+	err := s.guard.Do(ctx, func() error {
+		resp, err := storageClient.CreateSignedUrl(storageBucket, path, int(expiresIn.Seconds()))
+		if err != nil {
+			return err
+		}
+		signedURL = resp.SignedURL
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to sign file url: %w", err)
+	}
+
+	return signedURL, nil
+}
+
+// Delete removes the object at path.
+func (s *Supabase) Delete(ctx context.Context, path string, token string) error {
+	storageClient := s.userClient(token)
+
+	err := s.guard.Do(ctx, func() error {
+		_, err := storageClient.RemoveFile(storageBucket, []string{path})
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	return nil
+}
+
+// Exists reports whether an object is stored at path.
+func (s *Supabase) Exists(ctx context.Context, path string, token string) (bool, error) {
+	_, err := s.head(ctx, path, token)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// Stat returns the size of the object at path.
+func (s *Supabase) Stat(ctx context.Context, path string, token string) (int64, error) {
+	size, err := s.head(ctx, path, token)
+	if err == nil && size < 0 {
+		err = fmt.Errorf("failed to check file: no content length")
+	}
+	return size, err
+}
+
+// head sends a HEAD request for path and returns the object's size, -1 when the
+// response does not say. storage-go has no HEAD call, so this asks the storage API
+// directly; Supabase answers 400 or 404 for missing objects.
+func (s *Supabase) head(ctx context.Context, path string, token string) (int64, error) {
+	objectURL := s.baseURL + "/storage/v1/object/authenticated/" + storageBucket + "/" + uriEncode(path, false)
+
+	var size int64
+	err := s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodHead, objectURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("apikey", s.apiKey)
+		req.Header.Set("Authorization", "Bearer "+token)
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		switch resp.StatusCode {
+		case http.StatusOK:
+			size = resp.ContentLength
+			return nil
+		case http.StatusBadRequest, http.StatusNotFound:
+			return domain.ErrBlobNotFound
+		default:
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to check file: %w", err)
+	}
+
+	return size, nil
+}
+
+// Download fetches the object at path through the authenticated object endpoint, so
+// storage RLS policies apply. The body is returned unread.
+func (s *Supabase) Download(ctx context.Context, path string, token string) (io.ReadCloser, error) {
+	return s.download(ctx, path, 0, -1, token)
+}
+
+// DownloadRange implements domain.RangeDownloader like Download, with a Range header.
+func (s *Supabase) DownloadRange(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error) {
+	return s.download(ctx, path, offset, length, token)
+}
+
+// download gets length bytes of the object from offset; a negative length gets the
+// whole object without a Range header.
+func (s *Supabase) download(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error) {
+	objectURL := s.baseURL + "/storage/v1/object/authenticated/" + storageBucket + "/" + uriEncode(path, false)
+
+	var body io.ReadCloser
+	err := s.guard.Do(ctx, func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, objectURL, nil)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("apikey", s.apiKey)
+		req.Header.Set("Authorization", "Bearer "+token)
+		if length >= 0 {
+			req.Header.Set("Range", byteRange(offset, length))
+		}
+
+		resp, err := s.httpClient.Do(req)
+		if err != nil {
+			return err
+		}
+		switch resp.StatusCode {
+		case http.StatusOK, http.StatusPartialContent:
+			body, err = rangedBody(resp, offset, length)
+			return err
+		case http.StatusBadRequest, http.StatusNotFound:
+			resp.Body.Close()
+			return domain.ErrBlobNotFound
+		default:
+			resp.Body.Close()
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+	return body, nil
+}
+
+// userClient creates a client with the user's access token for RLS policies.
+// Use anon key (not service role) when using user token.
+func (s *Supabase) userClient(token string) *storage_go.Client {
+	storageURL := s.baseURL + "/storage/v1"
+	headers := map[string]string{
+		"Authorization": "Bearer " + token,
+	}
+	return storage_go.NewClient(storageURL, s.apiKey, headers)
+}
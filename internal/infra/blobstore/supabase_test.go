@@ -0,0 +1,56 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestNewSupabase(t *testing.T) {
+	svc := NewSupabase("http://localhost:54321", "test-key")
+	if svc.baseURL != "http://localhost:54321" {
+		t.Fatalf("expected base url to be set, got %s", svc.baseURL)
+	}
+	if svc.apiKey != "test-key" {
+		t.Fatalf("expected api key to be set, got %s", svc.apiKey)
+	}
+	if svc.storageClient == nil {
+		t.Fatalf("expected storage client to be initialized")
+	}
+}
+
+func TestSupabase_DownloadRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/storage/v1/object/authenticated/documents/user-1/book.pdf" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer user-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		http.ServeContent(w, r, "book.pdf", time.Time{}, strings.NewReader("%PDF-1.7 hello world"))
+	}))
+	defer srv.Close()
+
+	store := NewSupabase(srv.URL, "test-key")
+	file, err := store.DownloadRange(context.Background(), "user-1/book.pdf", 9, 5, "user-token")
+	if err != nil {
+		t.Fatalf("DownloadRange: %v", err)
+	}
+	defer file.Close()
+	if body, _ := io.ReadAll(file); string(body) != "hello" {
+		t.Fatalf("unexpected range %q", body)
+	}
+
+	if _, err := store.DownloadRange(context.Background(), "user-1/missing.pdf", 0, 5, "user-token"); !errors.Is(err, domain.ErrBlobNotFound) {
+		t.Fatalf("missing file = %v; want ErrBlobNotFound", err)
+	}
+}
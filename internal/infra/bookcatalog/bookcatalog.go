@@ -0,0 +1,106 @@
+// Package bookcatalog provides domain.BookCatalog clients for OpenLibrary and
+// Google Books.
+package bookcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	// requestTimeout bounds each catalog call.
+	requestTimeout = 15 * time.Second
+	// maxCoverSize bounds downloaded cover images.
+	maxCoverSize = 5 << 20 // 5MB
+)
+
+// New returns the catalogs in the order they are tried.
+func New(config domain.BookCatalogConfig) []domain.BookCatalog {
+	client := &http.Client{Timeout: requestTimeout}
+	return []domain.BookCatalog{
+		NewOpenLibrary(client),
+		NewGoogleBooks(client, config.GoogleBooksAPIKey),
+	}
+}
+
+// getJSON fetches a URL and decodes the JSON response into out.
+func getJSON(ctx context.Context, client *http.Client, url string, out any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// fetchCover downloads a cover image, rejecting responses that are not images.
+func fetchCover(ctx context.Context, client *http.Client, url string) ([]byte, string, error) {
+	if url == "" {
+		return nil, "", fmt.Errorf("no cover available")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return nil, "", err
+	}
+
+	contentType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, "", fmt.Errorf("cover is %q, not an image", contentType)
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxCoverSize+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(data) > maxCoverSize {
+		return nil, "", fmt.Errorf("cover exceeds %d bytes", maxCoverSize)
+	}
+	return data, contentType, nil
+}
+
+// checkResponse turns an error status into an error carrying the catalog's message.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+var yearRe = regexp.MustCompile(`\b(1[0-9]{3}|20[0-9]{2})\b`)
+
+// parseYear returns the year in a free-form publication date such as "June 1969" or
+// "2001-05-01", or 0.
+func parseYear(date string) int {
+	year, _ := strconv.Atoi(yearRe.FindString(date))
+	return year
+}
+
+// normalizeISBN strips the hyphens and spaces an ISBN is often written with.
+func normalizeISBN(isbn string) string {
+	return strings.ToUpper(strings.NewReplacer("-", "", " ", "").Replace(isbn))
+}
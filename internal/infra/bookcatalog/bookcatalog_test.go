@@ -0,0 +1,91 @@
+package bookcatalog
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestOpenLibrary(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/search.json":
+			switch {
+			case r.URL.Query().Get("isbn") == "9780441478125":
+				_, _ = io.WriteString(w, `{"docs":[{"key":"/works/OL59863W","title":"The Left Hand of Darkness",
+					"author_name":["Ursula K. Le Guin"],"first_publish_year":1969,"cover_i":42}]}`)
+			case r.URL.Query().Get("title") == "Unknown Book":
+				_, _ = io.WriteString(w, `{"docs":[]}`)
+			default:
+				t.Errorf("unexpected search %q", r.URL.RawQuery)
+				http.NotFound(w, r)
+			}
+		case "/works/OL59863W.json":
+			_, _ = io.WriteString(w, `{"description":{"type":"/type/text","value":"A story of Gethen."}}`)
+		case "/b/id/42-L.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = io.WriteString(w, "jpeg")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	catalog := NewOpenLibrary(server.Client())
+	catalog.baseURL = server.URL
+	catalog.coversURL = server.URL
+
+	info, err := catalog.Lookup(context.Background(), domain.BookQuery{ISBN: "978-0-441-47812-5"})
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if info.SourceID != "/works/OL59863W" || info.PublicationYear != 1969 || info.Description != "A story of Gethen." || info.ISBN != "9780441478125" {
+		t.Fatalf("unexpected book %+v", info)
+	}
+
+	cover, contentType, err := catalog.FetchCover(context.Background(), info)
+	if err != nil || string(cover) != "jpeg" || contentType != "image/jpeg" {
+		t.Fatalf("unexpected cover %q %q (%v)", cover, contentType, err)
+	}
+
+	if _, err := catalog.Lookup(context.Background(), domain.BookQuery{Title: "Unknown Book"}); !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+}
+
+func TestGoogleBooks(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if q := r.URL.Query().Get("q"); q != "intitle:Dune inauthor:Frank Herbert" || r.URL.Query().Get("key") != "key" {
+			t.Errorf("unexpected query %q", r.URL.RawQuery)
+		}
+		_, _ = io.WriteString(w, `{"items":[{"id":"B1","volumeInfo":{"title":"Dune","authors":["Frank Herbert"],
+			"publishedDate":"1990-09-01","description":"Desert planet.",
+			"industryIdentifiers":[{"type":"ISBN_10","identifier":"0441172717"},{"type":"ISBN_13","identifier":"9780441172719"}],
+			"imageLinks":{"thumbnail":"http://books.google.com/cover?id=B1"}}}]}`)
+	}))
+	defer server.Close()
+
+	catalog := NewGoogleBooks(server.Client(), "key")
+	catalog.baseURL = server.URL
+
+	info, err := catalog.Lookup(context.Background(), domain.BookQuery{Title: "Dune", Author: "Frank Herbert"})
+	if err != nil {
+		t.Fatalf("lookup failed: %v", err)
+	}
+	if info.SourceID != "B1" || info.PublicationYear != 1990 || info.ISBN != "9780441172719" || info.CoverURL != "https://books.google.com/cover?id=B1" {
+		t.Fatalf("unexpected book %+v", info)
+	}
+}
+
+func TestParseYear(t *testing.T) {
+	for date, want := range map[string]int{"June 1969": 1969, "2001-05-01": 2001, "c. 1850?": 1850, "n.d.": 0} {
+		if got := parseYear(date); got != want {
+			t.Errorf("parseYear(%q) = %d, want %d", date, got, want)
+		}
+	}
+}
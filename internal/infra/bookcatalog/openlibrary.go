@@ -0,0 +1,113 @@
+package bookcatalog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	openLibraryURL       = "https://openlibrary.org"
+	openLibraryCoversURL = "https://covers.openlibrary.org"
+)
+
+// OpenLibrary looks books up in the Internet Archive's OpenLibrary.
+type OpenLibrary struct {
+	client    *http.Client
+	baseURL   string
+	coversURL string
+}
+
+func NewOpenLibrary(client *http.Client) *OpenLibrary {
+	return &OpenLibrary{client: client, baseURL: openLibraryURL, coversURL: openLibraryCoversURL}
+}
+
+func (o *OpenLibrary) Name() string { return "openlibrary" }
+
+type openLibrarySearch struct {
+	Docs []struct {
+		Key              string   `json:"key"` // "/works/OL...W"
+		Title            string   `json:"title"`
+		AuthorName       []string `json:"author_name"`
+		FirstPublishYear int      `json:"first_publish_year"`
+		CoverID          int      `json:"cover_i"`
+		ISBN             []string `json:"isbn"`
+	} `json:"docs"`
+}
+
+type openLibraryWork struct {
+	// Description is either a string or {"type": ..., "value": ...}.
+	Description json.RawMessage `json:"description"`
+}
+
+// Lookup searches by ISBN when the query has one, by title and author otherwise,
+// then reads the description from the matching work.
+func (o *OpenLibrary) Lookup(ctx context.Context, query domain.BookQuery) (*domain.BookInfo, error) {
+	params := url.Values{"limit": {"1"}, "fields": {"key,title,author_name,first_publish_year,cover_i,isbn"}}
+	if isbn := normalizeISBN(query.ISBN); isbn != "" {
+		params.Set("isbn", isbn)
+	} else {
+		params.Set("title", query.Title)
+		if query.Author != "" {
+			params.Set("author", query.Author)
+		}
+	}
+
+	var search openLibrarySearch
+	if err := getJSON(ctx, o.client, o.baseURL+"/search.json?"+params.Encode(), &search); err != nil {
+		return nil, fmt.Errorf("openlibrary search failed: %w", err)
+	}
+	if len(search.Docs) == 0 {
+		return nil, domain.ErrBookNotFound
+	}
+	doc := search.Docs[0]
+
+	info := &domain.BookInfo{
+		Source:          o.Name(),
+		SourceID:        doc.Key,
+		Title:           doc.Title,
+		Authors:         doc.AuthorName,
+		PublicationYear: doc.FirstPublishYear,
+	}
+	if isbn := normalizeISBN(query.ISBN); isbn != "" {
+		info.ISBN = isbn
+	} else if len(doc.ISBN) > 0 {
+		info.ISBN = doc.ISBN[0]
+	}
+	if doc.CoverID > 0 {
+		info.CoverURL = fmt.Sprintf("%s/b/id/%d-L.jpg", o.coversURL, doc.CoverID)
+	}
+
+	// The description is only on the work; a book without one is still a match.
+	if strings.HasPrefix(doc.Key, "/works/") {
+		var work openLibraryWork
+		if err := getJSON(ctx, o.client, o.baseURL+doc.Key+".json", &work); err == nil {
+			info.Description = openLibraryText(work.Description)
+		}
+	}
+	return info, nil
+}
+
+// openLibraryText reads a text field that is either a string or a typed value.
+func openLibraryText(raw json.RawMessage) string {
+	var text string
+	if json.Unmarshal(raw, &text) == nil {
+		return strings.TrimSpace(text)
+	}
+	var typed struct {
+		Value string `json:"value"`
+	}
+	if json.Unmarshal(raw, &typed) == nil {
+		return strings.TrimSpace(typed.Value)
+	}
+	return ""
+}
+
+func (o *OpenLibrary) FetchCover(ctx context.Context, info *domain.BookInfo) ([]byte, string, error) {
+	return fetchCover(ctx, o.client, info.CoverURL)
+}
@@ -0,0 +1,133 @@
+// Package clouddrive provides domain.CloudDrive connectors for Google Drive and
+// Dropbox using the OAuth 2.0 authorization code flow.
+package clouddrive
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// requestTimeout bounds API calls; downloads are bounded by the caller's context.
+const requestTimeout = 30 * time.Second
+
+// New returns the connectors whose client credentials are configured, by provider.
+func New(config domain.CloudImportConfig) (map[string]domain.CloudDrive, error) {
+	drives := make(map[string]domain.CloudDrive)
+	if config.GoogleClientID != "" || config.DropboxClientID != "" {
+		if config.RedirectURL == "" {
+			return nil, fmt.Errorf("CLOUD_IMPORT_REDIRECT_URL is required with cloud drive credentials")
+		}
+	}
+	if config.GoogleClientID != "" {
+		drives[domain.CloudProviderGoogleDrive] = NewGoogleDrive(config.GoogleClientID, config.GoogleClientSecret, config.RedirectURL)
+	}
+	if config.DropboxClientID != "" {
+		drives[domain.CloudProviderDropbox] = NewDropbox(config.DropboxClientID, config.DropboxClientSecret, config.RedirectURL)
+	}
+	return drives, nil
+}
+
+// oauthClient holds what both providers need for the authorization code flow.
+type oauthClient struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	tokenURL     string
+	client       *http.Client
+}
+
+type tokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int64  `json:"expires_in"`
+}
+
+// exchange trades a code for a token.
+func (c *oauthClient) exchange(ctx context.Context, code string) (*domain.CloudToken, error) {
+	return c.requestToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.redirectURL},
+	})
+}
+
+// refresh returns a new access token; providers that do not rotate the refresh
+// token leave it out of the response, so the old one is kept.
+func (c *oauthClient) refresh(ctx context.Context, token *domain.CloudToken) (*domain.CloudToken, error) {
+	if token.RefreshToken == "" {
+		return nil, fmt.Errorf("access token expired and no refresh token was granted")
+	}
+	refreshed, err := c.requestToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token.RefreshToken},
+	})
+	if err != nil {
+		return nil, err
+	}
+	if refreshed.RefreshToken == "" {
+		refreshed.RefreshToken = token.RefreshToken
+	}
+	return refreshed, nil
+}
+
+func (c *oauthClient) requestToken(ctx context.Context, form url.Values) (*domain.CloudToken, error) {
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var body tokenResponse
+	if err := c.do(req, &body); err != nil {
+		return nil, fmt.Errorf("token request failed: %w", err)
+	}
+	if body.AccessToken == "" {
+		return nil, fmt.Errorf("token request failed: no access token in response")
+	}
+	token := &domain.CloudToken{AccessToken: body.AccessToken, RefreshToken: body.RefreshToken}
+	if body.ExpiresIn > 0 {
+		token.Expiry = time.Now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return token, nil
+}
+
+// do sends the request and decodes a JSON response into out.
+func (c *oauthClient) do(req *http.Request, out any) error {
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkResponse turns an error status into an error carrying the provider's message.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+// importable reports whether a file name has an extension the library accepts.
+func importable(name string) bool {
+	name = strings.ToLower(name)
+	return strings.HasSuffix(name, ".pdf") || strings.HasSuffix(name, ".epub")
+}
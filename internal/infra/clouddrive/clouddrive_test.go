@@ -0,0 +1,155 @@
+package clouddrive
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestNew(t *testing.T) {
+	drives, err := New(domain.CloudImportConfig{})
+	if err != nil || len(drives) != 0 {
+		t.Fatalf("expected no drives without credentials, got %v (%v)", drives, err)
+	}
+	if _, err := New(domain.CloudImportConfig{DropboxClientID: "id"}); err == nil {
+		t.Fatal("expected an error without a redirect URL")
+	}
+	drives, err = New(domain.CloudImportConfig{RedirectURL: "https://app.example.com/import", GoogleClientID: "id"})
+	if err != nil || drives[domain.CloudProviderGoogleDrive] == nil || drives[domain.CloudProviderDropbox] != nil {
+		t.Fatalf("expected only Google Drive, got %v (%v)", drives, err)
+	}
+}
+
+func TestGoogleDrive(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			_ = r.ParseForm()
+			if r.PostForm.Get("code") != "the-code" || r.PostForm.Get("client_secret") != "secret" {
+				http.Error(w, "bad code", http.StatusBadRequest)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"access","refresh_token":"refresh","expires_in":3600}`)
+		case r.Header.Get("Authorization") != "Bearer access":
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		case r.URL.Path == "/files":
+			if q := r.URL.Query().Get("q"); !strings.Contains(q, `name contains 'O\'Brien'`) {
+				t.Errorf("unexpected query %q", q)
+			}
+			_, _ = io.WriteString(w, `{"files":[{"id":"f1","name":"O'Brien.pdf","size":"1024","modifiedTime":"2026-01-02T03:04:05Z"}]}`)
+		case r.URL.Path == "/files/f1" && r.URL.Query().Get("alt") == "media":
+			_, _ = io.WriteString(w, "%PDF-1.7")
+		case r.URL.Path == "/files/f1":
+			_, _ = io.WriteString(w, `{"id":"f1","name":"O'Brien.pdf","size":"8"}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	drive := NewGoogleDrive("client", "secret", "https://app.example.com/import")
+	drive.tokenURL = server.URL + "/token"
+	drive.apiURL = server.URL
+
+	authURL, err := url.Parse(drive.AuthURL("xyz"))
+	if err != nil || authURL.Query().Get("state") != "xyz" || authURL.Query().Get("access_type") != "offline" {
+		t.Fatalf("unexpected auth URL %v (%v)", authURL, err)
+	}
+
+	token, err := drive.Exchange(context.Background(), "the-code")
+	if err != nil || token.RefreshToken != "refresh" || token.Expired() {
+		t.Fatalf("unexpected token %+v (%v)", token, err)
+	}
+
+	files, err := drive.ListFiles(context.Background(), token, "O'Brien")
+	if err != nil || len(files) != 1 || files[0].Size != 1024 {
+		t.Fatalf("unexpected files %+v (%v)", files, err)
+	}
+
+	file, body, err := drive.Download(context.Background(), token, "f1")
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	defer body.Close()
+	content, _ := io.ReadAll(body)
+	if file.Name != "O'Brien.pdf" || string(content) != "%PDF-1.7" {
+		t.Fatalf("unexpected download %+v %q", file, content)
+	}
+
+	if _, err := drive.ListFiles(context.Background(), &domain.CloudToken{AccessToken: "revoked"}, ""); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Fatalf("expected the provider's status in the error, got %v", err)
+	}
+}
+
+func TestDropbox(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = r.ParseForm()
+			if r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != "refresh" {
+				http.Error(w, "bad grant", http.StatusBadRequest)
+				return
+			}
+			_, _ = io.WriteString(w, `{"access_token":"access","expires_in":14400}`)
+		case "/files/list_folder":
+			_, _ = io.WriteString(w, `{"entries":[
+				{".tag":"folder","id":"id:dir","name":"Books"},
+				{".tag":"file","id":"id:old","name":"Old Book.epub","size":10,"server_modified":"2025-01-01T00:00:00Z"},
+				{".tag":"file","id":"id:photo","name":"book.jpg","size":10}
+			],"cursor":"c1","has_more":true}`)
+		case "/files/list_folder/continue":
+			var args map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&args)
+			if args["cursor"] != "c1" {
+				t.Errorf("unexpected cursor %q", args["cursor"])
+			}
+			_, _ = io.WriteString(w, `{"entries":[
+				{".tag":"file","id":"id:new","name":"New BOOK.pdf","size":20,"server_modified":"2026-01-01T00:00:00Z"},
+				{".tag":"file","id":"id:other","name":"Notes.pdf","size":20,"server_modified":"2026-02-01T00:00:00Z"}
+			],"has_more":false}`)
+		case "/files/download":
+			if arg := r.Header.Get("Dropbox-API-Arg"); arg != `{"path":"id:new"}` {
+				t.Errorf("unexpected download argument %q", arg)
+			}
+			w.Header().Set("Dropbox-API-Result", `{"id":"id:new","name":"New BOOK.pdf","size":8}`)
+			_, _ = io.WriteString(w, "%PDF-1.7")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	drive := NewDropbox("client", "secret", "https://app.example.com/import")
+	drive.tokenURL = server.URL + "/token"
+	drive.apiURL = server.URL
+	drive.contentURL = server.URL
+
+	token, err := drive.Refresh(context.Background(), &domain.CloudToken{AccessToken: "expired", RefreshToken: "refresh"})
+	if err != nil || token.AccessToken != "access" || token.RefreshToken != "refresh" {
+		t.Fatalf("unexpected token %+v (%v)", token, err)
+	}
+
+	files, err := drive.ListFiles(context.Background(), token, "book")
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(files) != 2 || files[0].ID != "id:new" || files[1].ID != "id:old" {
+		t.Fatalf("expected matching books newest first, got %+v", files)
+	}
+
+	file, body, err := drive.Download(context.Background(), token, "id:new")
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	defer body.Close()
+	if file.Name != "New BOOK.pdf" || file.Size != 8 {
+		t.Fatalf("unexpected file %+v", file)
+	}
+}
@@ -0,0 +1,163 @@
+package clouddrive
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	dropboxAuthURL    = "https://www.dropbox.com/oauth2/authorize"
+	dropboxTokenURL   = "https://api.dropboxapi.com/oauth2/token"
+	dropboxAPI        = "https://api.dropboxapi.com/2"
+	dropboxContentAPI = "https://content.dropboxapi.com/2"
+
+	// dropboxMaxEntries bounds how much of a large Dropbox is walked for one listing.
+	dropboxMaxEntries = 10000
+)
+
+// Dropbox imports files through the Dropbox v2 API.
+type Dropbox struct {
+	oauthClient
+	authURL    string
+	apiURL     string
+	contentURL string
+}
+
+func NewDropbox(clientID, clientSecret, redirectURL string) *Dropbox {
+	return &Dropbox{
+		oauthClient: oauthClient{
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			redirectURL:  redirectURL,
+			tokenURL:     dropboxTokenURL,
+			client:       &http.Client{},
+		},
+		authURL:    dropboxAuthURL,
+		apiURL:     dropboxAPI,
+		contentURL: dropboxContentAPI,
+	}
+}
+
+// AuthURL asks for offline access so a refresh token is granted.
+func (d *Dropbox) AuthURL(state string) string {
+	return d.authURL + "?" + url.Values{
+		"client_id":         {d.clientID},
+		"redirect_uri":      {d.redirectURL},
+		"response_type":     {"code"},
+		"token_access_type": {"offline"},
+		"state":             {state},
+	}.Encode()
+}
+
+func (d *Dropbox) Exchange(ctx context.Context, code string) (*domain.CloudToken, error) {
+	return d.exchange(ctx, code)
+}
+
+func (d *Dropbox) Refresh(ctx context.Context, token *domain.CloudToken) (*domain.CloudToken, error) {
+	return d.refresh(ctx, token)
+}
+
+type dropboxEntry struct {
+	Tag            string    `json:".tag"`
+	ID             string    `json:"id"`
+	Name           string    `json:"name"`
+	Size           int64     `json:"size"`
+	ServerModified time.Time `json:"server_modified"`
+}
+
+func (e *dropboxEntry) toDomain() domain.CloudFile {
+	return domain.CloudFile{ID: e.ID, Name: e.Name, Size: e.Size, ModifiedAt: e.ServerModified}
+}
+
+type dropboxListResponse struct {
+	Entries []dropboxEntry `json:"entries"`
+	Cursor  string         `json:"cursor"`
+	HasMore bool           `json:"has_more"`
+}
+
+// ListFiles walks the whole Dropbox, since search needs a non-empty query, and keeps
+// the PDFs and EPUBs whose name matches.
+func (d *Dropbox) ListFiles(ctx context.Context, token *domain.CloudToken, query string) ([]domain.CloudFile, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+
+	var files []domain.CloudFile
+	endpoint, args := "/files/list_folder", map[string]any{"path": "", "recursive": true, "limit": 2000}
+	for seen := 0; seen < dropboxMaxEntries; {
+		var page dropboxListResponse
+		if err := d.call(ctx, token, endpoint, args, &page); err != nil {
+			return nil, fmt.Errorf("failed to list Dropbox files: %w", err)
+		}
+		for i := range page.Entries {
+			entry := &page.Entries[i]
+			if entry.Tag == "file" && importable(entry.Name) && strings.Contains(strings.ToLower(entry.Name), query) {
+				files = append(files, entry.toDomain())
+			}
+		}
+		if !page.HasMore {
+			break
+		}
+		seen += len(page.Entries)
+		endpoint, args = "/files/list_folder/continue", map[string]any{"cursor": page.Cursor}
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].ModifiedAt.After(files[j].ModifiedAt) })
+	return files, nil
+}
+
+// call posts a JSON RPC request to the Dropbox API.
+func (d *Dropbox) call(ctx context.Context, token *domain.CloudToken, endpoint string, args any, out any) error {
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.apiURL+endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Content-Type", "application/json")
+	return d.do(req, out)
+}
+
+func (d *Dropbox) Download(ctx context.Context, token *domain.CloudToken, fileID string) (*domain.CloudFile, io.ReadCloser, error) {
+	arg, err := json.Marshal(map[string]string{"path": fileID})
+	if err != nil {
+		return nil, nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.contentURL+"/files/download", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Dropbox-API-Arg", string(arg))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download Dropbox file: %w", err)
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to download Dropbox file: %w", err)
+	}
+
+	// The file's metadata comes back in a header next to the content.
+	var entry dropboxEntry
+	if err := json.Unmarshal([]byte(resp.Header.Get("Dropbox-API-Result")), &entry); err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("invalid Dropbox file metadata: %w", err)
+	}
+	file := entry.toDomain()
+	return &file, resp.Body, nil
+}
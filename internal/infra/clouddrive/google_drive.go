@@ -0,0 +1,141 @@
+package clouddrive
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	googleAuthURL  = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL = "https://oauth2.googleapis.com/token"
+	googleDriveAPI = "https://www.googleapis.com/drive/v3"
+	googleScope    = "https://www.googleapis.com/auth/drive.readonly"
+)
+
+// GoogleDrive imports files through the Google Drive v3 API.
+type GoogleDrive struct {
+	oauthClient
+	authURL string
+	apiURL  string
+}
+
+func NewGoogleDrive(clientID, clientSecret, redirectURL string) *GoogleDrive {
+	return &GoogleDrive{
+		oauthClient: oauthClient{
+			clientID:     clientID,
+			clientSecret: clientSecret,
+			redirectURL:  redirectURL,
+			tokenURL:     googleTokenURL,
+			client:       &http.Client{},
+		},
+		authURL: googleAuthURL,
+		apiURL:  googleDriveAPI,
+	}
+}
+
+// AuthURL asks for offline access so a refresh token is granted.
+func (d *GoogleDrive) AuthURL(state string) string {
+	return d.authURL + "?" + url.Values{
+		"client_id":     {d.clientID},
+		"redirect_uri":  {d.redirectURL},
+		"response_type": {"code"},
+		"scope":         {googleScope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}.Encode()
+}
+
+func (d *GoogleDrive) Exchange(ctx context.Context, code string) (*domain.CloudToken, error) {
+	return d.exchange(ctx, code)
+}
+
+func (d *GoogleDrive) Refresh(ctx context.Context, token *domain.CloudToken) (*domain.CloudToken, error) {
+	return d.refresh(ctx, token)
+}
+
+type googleFile struct {
+	ID           string    `json:"id"`
+	Name         string    `json:"name"`
+	Size         string    `json:"size"` // int64 encoded as a string
+	ModifiedTime time.Time `json:"modifiedTime"`
+}
+
+func (f *googleFile) toDomain() domain.CloudFile {
+	size, _ := strconv.ParseInt(f.Size, 10, 64)
+	return domain.CloudFile{ID: f.ID, Name: f.Name, Size: size, ModifiedAt: f.ModifiedTime}
+}
+
+func (d *GoogleDrive) ListFiles(ctx context.Context, token *domain.CloudToken, query string) ([]domain.CloudFile, error) {
+	q := "(mimeType = 'application/pdf' or mimeType = 'application/epub+zip') and trashed = false"
+	if query = strings.TrimSpace(query); query != "" {
+		escaped := strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(query)
+		q += " and name contains '" + escaped + "'"
+	}
+	params := url.Values{
+		"q":        {q},
+		"fields":   {"files(id,name,size,modifiedTime)"},
+		"orderBy":  {"modifiedTime desc"},
+		"pageSize": {"100"},
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, d.apiURL+"/files?"+params.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+
+	var body struct {
+		Files []googleFile `json:"files"`
+	}
+	if err := d.do(req, &body); err != nil {
+		return nil, fmt.Errorf("failed to list Google Drive files: %w", err)
+	}
+	files := make([]domain.CloudFile, 0, len(body.Files))
+	for i := range body.Files {
+		files = append(files, body.Files[i].toDomain())
+	}
+	return files, nil
+}
+
+func (d *GoogleDrive) Download(ctx context.Context, token *domain.CloudToken, fileID string) (*domain.CloudFile, io.ReadCloser, error) {
+	fileURL := d.apiURL + "/files/" + url.PathEscape(fileID)
+
+	metaCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(metaCtx, http.MethodGet, fileURL+"?fields=id,name,size,modifiedTime", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	var meta googleFile
+	if err := d.do(req, &meta); err != nil {
+		return nil, nil, fmt.Errorf("failed to get Google Drive file: %w", err)
+	}
+
+	req, err = http.NewRequestWithContext(ctx, http.MethodGet, fileURL+"?alt=media", nil)
+	if err != nil {
+		return nil, nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to download Google Drive file: %w", err)
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, nil, fmt.Errorf("failed to download Google Drive file: %w", err)
+	}
+	file := meta.toDomain()
+	return &file, resp.Body, nil
+}
@@ -0,0 +1,31 @@
+// Package email provides domain.EmailSender implementations.
+package email
+
+import (
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// New builds the sender selected by EMAIL_BACKEND ("smtp" or "sendgrid").
+func New(config domain.EmailConfig) (domain.EmailSender, error) {
+	if config.From == "" {
+		return nil, fmt.Errorf("EMAIL_FROM is required to send e-mail")
+	}
+	switch config.Backend {
+	case "smtp":
+		if config.SMTPAddress == "" {
+			return nil, fmt.Errorf("SMTP_ADDRESS is required for the smtp e-mail backend")
+		}
+		return NewSMTP(config.SMTPAddress, config.SMTPUsername, config.SMTPPassword, config.From), nil
+	case "sendgrid":
+		if config.SendGridAPIKey == "" {
+			return nil, fmt.Errorf("SENDGRID_API_KEY is required for the sendgrid e-mail backend")
+		}
+		return NewSendGrid(config.SendGridAPIKey, config.From), nil
+	case "":
+		return nil, fmt.Errorf("EMAIL_BACKEND is not set")
+	default:
+		return nil, fmt.Errorf("unknown e-mail backend %q", config.Backend)
+	}
+}
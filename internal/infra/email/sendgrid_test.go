@@ -0,0 +1,49 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestSendGrid_Send(t *testing.T) {
+	var got sendGridRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	sender := NewSendGrid("key", "Lector <digest@example.com>")
+	sender.endpoint = srv.URL
+
+	msg := &domain.EmailMessage{To: "reader@example.com", Subject: "Your week", Text: "plain", HTML: "<p>html</p>"}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.From.Email != "digest@example.com" || got.From.Name != "Lector" || got.Subject != "Your week" {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(got.Personalizations) != 1 || got.Personalizations[0].To[0].Email != "reader@example.com" {
+		t.Fatalf("unexpected recipients: %+v", got.Personalizations)
+	}
+	if len(got.Content) != 2 || got.Content[0].Type != "text/plain" || got.Content[1].Value != "<p>html</p>" {
+		t.Fatalf("unexpected content: %+v", got.Content)
+	}
+
+	sender.apiKey = "wrong"
+	if err := sender.Send(context.Background(), msg); err == nil {
+		t.Fatalf("expected error for non-2xx response")
+	}
+}
@@ -0,0 +1,78 @@
+package email
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestBuildMessage(t *testing.T) {
+	from := &mail.Address{Name: "Lector", Address: "digest@example.com"}
+	to := &mail.Address{Address: "reader@example.com"}
+	msg := &domain.EmailMessage{Subject: "Your week in books ✓", Text: "You read 2 books", HTML: "<p>You read 2 books</p>"}
+
+	raw, err := buildMessage(from, to, msg, time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("invalid message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+	if err != nil || subject != msg.Subject {
+		t.Fatalf("unexpected subject %q (%v)", subject, err)
+	}
+	if parsed.Header.Get("To") != "<reader@example.com>" {
+		t.Fatalf("unexpected recipient %q", parsed.Header.Get("To"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("unexpected content type %q (%v)", mediaType, err)
+	}
+	reader := multipart.NewReader(parsed.Body, params["boundary"])
+	var bodies []string
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid part: %v", err)
+		}
+		body, _ := io.ReadAll(part) // NextPart decodes quoted-printable
+		bodies = append(bodies, string(body))
+	}
+	if len(bodies) != 2 || bodies[0] != msg.Text || bodies[1] != msg.HTML {
+		t.Fatalf("unexpected parts: %q", bodies)
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name   string
+		config domain.EmailConfig
+		ok     bool
+	}{
+		{"smtp", domain.EmailConfig{Backend: "smtp", From: "a@example.com", SMTPAddress: "localhost:25"}, true},
+		{"sendgrid", domain.EmailConfig{Backend: "sendgrid", From: "a@example.com", SendGridAPIKey: "key"}, true},
+		{"no sender", domain.EmailConfig{Backend: "smtp", SMTPAddress: "localhost:25"}, false},
+		{"smtp without address", domain.EmailConfig{Backend: "smtp", From: "a@example.com"}, false},
+		{"no backend", domain.EmailConfig{From: "a@example.com"}, false},
+		{"unknown backend", domain.EmailConfig{Backend: "pigeon", From: "a@example.com"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.config); (err == nil) != tt.ok {
+				t.Fatalf("expected ok=%v, got %v", tt.ok, err)
+			}
+		})
+	}
+}
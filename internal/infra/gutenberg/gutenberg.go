@@ -0,0 +1,189 @@
+// Package gutenberg provides the domain.GutenbergCatalog client: searches go to a
+// Gutendex instance and EPUBs are downloaded from Project Gutenberg mirrors.
+package gutenberg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// requestTimeout bounds catalog calls; downloads are bounded by the caller's context.
+const requestTimeout = 15 * time.Second
+
+// Catalog searches Project Gutenberg through Gutendex.
+type Catalog struct {
+	client     *http.Client
+	catalogURL string
+	mirrors    []string
+}
+
+func New(config domain.GutenbergConfig) *Catalog {
+	return &Catalog{
+		client:     &http.Client{},
+		catalogURL: strings.TrimRight(config.CatalogURL, "/"),
+		mirrors:    config.Mirrors,
+	}
+}
+
+type gutendexBook struct {
+	ID      int    `json:"id"`
+	Title   string `json:"title"`
+	Authors []struct {
+		Name string `json:"name"`
+	} `json:"authors"`
+	Subjects      []string          `json:"subjects"`
+	Languages     []string          `json:"languages"`
+	Formats       map[string]string `json:"formats"`
+	DownloadCount int               `json:"download_count"`
+}
+
+type gutendexPage struct {
+	Count   int            `json:"count"`
+	Next    *string        `json:"next"`
+	Results []gutendexBook `json:"results"`
+}
+
+func (c *Catalog) Search(ctx context.Context, query string, page int) (*domain.GutenbergSearchResult, error) {
+	params := url.Values{"page": {strconv.Itoa(page)}}
+	if query != "" {
+		params.Set("search", query)
+	}
+	var found gutendexPage
+	if err := c.getJSON(ctx, c.catalogURL+"/books?"+params.Encode(), &found); err != nil {
+		return nil, fmt.Errorf("gutenberg search failed: %w", err)
+	}
+
+	result := &domain.GutenbergSearchResult{
+		Books: make([]domain.GutenbergBook, 0, len(found.Results)),
+		Total: found.Count,
+		Page:  page,
+	}
+	for _, book := range found.Results {
+		result.Books = append(result.Books, book.toDomain())
+	}
+	if found.Next != nil {
+		result.NextPage = page + 1
+	}
+	return result, nil
+}
+
+func (c *Catalog) Book(ctx context.Context, id int) (*domain.GutenbergBook, error) {
+	var found gutendexBook
+	err := c.getJSON(ctx, fmt.Sprintf("%s/books/%d", c.catalogURL, id), &found)
+	if errors.Is(err, errNotFound) {
+		return nil, domain.ErrBookNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("gutenberg lookup failed: %w", err)
+	}
+	book := found.toDomain()
+	return &book, nil
+}
+
+// DownloadEPUB tries each mirror in turn. The EPUB 3 edition with images is the one
+// Gutenberg offers as its default download.
+func (c *Catalog) DownloadEPUB(ctx context.Context, id int) (io.ReadCloser, error) {
+	var errs []error
+	for _, mirror := range c.mirrors {
+		body, err := c.download(ctx, fmt.Sprintf("%s/cache/epub/%d/pg%d-images-3.epub", mirror, id, id))
+		if err == nil {
+			return body, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", mirror, err))
+	}
+	if len(errs) == 0 {
+		return nil, fmt.Errorf("no gutenberg mirrors configured")
+	}
+	return nil, fmt.Errorf("gutenberg download failed: %w", errors.Join(errs...))
+}
+
+func (c *Catalog) download(ctx context.Context, url string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkResponse(resp); err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// errNotFound marks a 404 from the catalog.
+var errNotFound = errors.New("not found")
+
+func (c *Catalog) getJSON(ctx context.Context, url string, out any) error {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp); err != nil {
+		return err
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// checkResponse turns an error status into an error carrying the server's message.
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode < 300 {
+		return nil
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return errNotFound
+	}
+	message, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(message)))
+}
+
+func (b gutendexBook) toDomain() domain.GutenbergBook {
+	book := domain.GutenbergBook{
+		ID:            b.ID,
+		Title:         strings.TrimSpace(b.Title),
+		Authors:       make([]string, 0, len(b.Authors)),
+		Subjects:      b.Subjects,
+		Languages:     b.Languages,
+		DownloadCount: b.DownloadCount,
+		CoverURL:      b.Formats["image/jpeg"],
+	}
+	for _, author := range b.Authors {
+		book.Authors = append(book.Authors, authorName(author.Name))
+	}
+	_, book.HasEPUB = b.Formats["application/epub+zip"]
+	return book
+}
+
+// authorName turns the catalog's "Austen, Jane" into "Jane Austen". Names with
+// more parts, such as "Tolstoy, Leo, graf", are kept as written.
+func authorName(name string) string {
+	parts := strings.Split(name, ", ")
+	if len(parts) != 2 {
+		return strings.TrimSpace(name)
+	}
+	return strings.TrimSpace(parts[1]) + " " + strings.TrimSpace(parts[0])
+}
@@ -0,0 +1,93 @@
+package gutenberg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const gutendexPride = `{"id":1342,"title":"Pride and Prejudice","authors":[{"name":"Austen, Jane"}],
+"subjects":["England -- Fiction"],"languages":["en"],"download_count":70000,
+"formats":{"application/epub+zip":"https://www.gutenberg.org/ebooks/1342.epub3.images","image/jpeg":"https://www.gutenberg.org/cache/epub/1342/pg1342.cover.medium.jpg"}}`
+
+func TestCatalog(t *testing.T) {
+	catalog := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/books":
+			if r.URL.Query().Get("search") != "austen" || r.URL.Query().Get("page") != "1" {
+				t.Errorf("unexpected search %q", r.URL.RawQuery)
+			}
+			_, _ = io.WriteString(w, `{"count":40,"next":"https://gutendex.com/books/?page=2&search=austen","results":[`+gutendexPride+`]}`)
+		case "/books/1342":
+			_, _ = io.WriteString(w, gutendexPride)
+		default:
+			http.Error(w, `{"detail":"Not found."}`, http.StatusNotFound)
+		}
+	}))
+	defer catalog.Close()
+
+	c := New(domain.GutenbergConfig{CatalogURL: catalog.URL + "/"})
+
+	result, err := c.Search(context.Background(), "austen", 1)
+	if err != nil || result.Total != 40 || result.NextPage != 2 || len(result.Books) != 1 {
+		t.Fatalf("unexpected search result %+v (%v)", result, err)
+	}
+	book := result.Books[0]
+	if book.ID != 1342 || book.Authors[0] != "Jane Austen" || !book.HasEPUB || book.CoverURL == "" {
+		t.Fatalf("unexpected book %+v", book)
+	}
+
+	if got, err := c.Book(context.Background(), 1342); err != nil || got.Title != "Pride and Prejudice" {
+		t.Fatalf("unexpected book %+v (%v)", got, err)
+	}
+	if _, err := c.Book(context.Background(), 99999999); !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("expected ErrBookNotFound, got %v", err)
+	}
+}
+
+func TestCatalog_DownloadEPUB(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "maintenance", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	mirror := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/cache/epub/1342/pg1342-images-3.epub" {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, "PK epub")
+	}))
+	defer mirror.Close()
+
+	// A mirror that fails is skipped for the next one.
+	c := New(domain.GutenbergConfig{Mirrors: []string{down.URL, mirror.URL}})
+	body, err := c.DownloadEPUB(context.Background(), 1342)
+	if err != nil {
+		t.Fatalf("download failed: %v", err)
+	}
+	defer body.Close()
+	if data, _ := io.ReadAll(body); string(data) != "PK epub" {
+		t.Fatalf("unexpected content %q", data)
+	}
+
+	if _, err := c.DownloadEPUB(context.Background(), 7); err == nil {
+		t.Fatal("expected an error when no mirror has the book")
+	}
+}
+
+func TestAuthorName(t *testing.T) {
+	for name, want := range map[string]string{
+		"Austen, Jane":       "Jane Austen",
+		"Tolstoy, Leo, graf": "Tolstoy, Leo, graf",
+		"Homer":              "Homer",
+	} {
+		if got := authorName(name); got != want {
+			t.Errorf("authorName(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
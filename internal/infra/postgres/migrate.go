@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the advisory lock that keeps concurrently starting instances from
+// applying the same migration twice.
+const migrationLockID = 7_146_221_830
+
+// Migration is one versioned SQL file from the migrations directory, named
+// <version>_<name>.sql.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	return loadMigrations(migrationFiles, "migrations")
+}
+
+func loadMigrations(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	migrations := make([]Migration, 0, len(entries))
+	seen := make(map[int]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".sql" {
+			continue
+		}
+		base := strings.TrimSuffix(entry.Name(), ".sql")
+		prefix, name, ok := strings.Cut(base, "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 || name == "" {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+		if other, dup := seen[version]; dup {
+			return nil, fmt.Errorf("migrations %q and %q share version %d", other, entry.Name(), version)
+		}
+		seen[version] = entry.Name()
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", entry.Name(), err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: name, SQL: string(content)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Migrator implements domain.SchemaMigrator. Applied versions are recorded in the
+// schema_migrations table. Without a pool it connects to databaseURL for each call,
+// so deployments on the PostgREST backend can migrate without keeping a pool open.
+type Migrator struct {
+	pool        *pgxpool.Pool
+	databaseURL string
+	logger      domain.Logger
+}
+
+// NewMigrator creates a migrator that uses pool when it is not nil and otherwise
+// connects to databaseURL on demand.
+func NewMigrator(pool *pgxpool.Pool, databaseURL string, logger domain.Logger) *Migrator {
+	return &Migrator{
+		pool:        pool,
+		databaseURL: databaseURL,
+		logger:      logger,
+	}
+}
+
+// Migrate applies every pending migration, each in its own transaction.
+func (m *Migrator) Migrate(ctx context.Context) ([]domain.MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var applied []domain.MigrationStatus
+	err = m.withConn(ctx, func(conn *pgxpool.Conn) error {
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %w", err)
+		}
+		defer func() {
+			_, _ = conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", migrationLockID)
+		}()
+
+		done, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			appliedAt, err := applyMigration(ctx, conn, migration)
+			if err != nil {
+				return err
+			}
+			m.logger.Info("Applied database migration", "version", migration.Version, "name", migration.Name)
+			applied = append(applied, domain.MigrationStatus{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				AppliedAt: &appliedAt,
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return applied, nil
+}
+
+// Status lists every embedded migration with the time it was applied, if it was.
+func (m *Migrator) Status(ctx context.Context) ([]domain.MigrationStatus, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var done map[int]time.Time
+	err = m.withConn(ctx, func(conn *pgxpool.Conn) error {
+		var err error
+		done, err = appliedVersions(ctx, conn)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]domain.MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := domain.MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if appliedAt, ok := done[migration.Version]; ok {
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (m *Migrator) withConn(ctx context.Context, fn func(conn *pgxpool.Conn) error) error {
+	pool := m.pool
+	if pool == nil {
+		var err error
+		pool, err = NewPool(ctx, m.databaseURL)
+		if err != nil {
+			return err
+		}
+		defer pool.Close()
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection: %w", err)
+	}
+	defer conn.Release()
+
+	return fn(conn)
+}
+
+func appliedVersions(ctx context.Context, conn *pgxpool.Conn) (map[int]time.Time, error) {
+	if _, err := conn.Exec(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    integer PRIMARY KEY,
+			name       text NOT NULL,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)`); err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	rows, err := conn.Query(ctx, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+		}
+		done[version] = appliedAt
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read applied migrations: %w", err)
+	}
+	return done, nil
+}
+
+func applyMigration(ctx context.Context, conn *pgxpool.Conn, migration Migration) (time.Time, error) {
+	var appliedAt time.Time
+	err := pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		// Without arguments pgx uses the simple protocol, which accepts several statements.
+		if _, err := tx.Exec(ctx, migration.SQL); err != nil {
+			return err
+		}
+		return tx.QueryRow(ctx,
+			"INSERT INTO schema_migrations (version, name) VALUES ($1, $2) RETURNING applied_at",
+			migration.Version, migration.Name,
+		).Scan(&appliedAt)
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to apply migration %d_%s: %w", migration.Version, migration.Name, err)
+	}
+	return appliedAt, nil
+}
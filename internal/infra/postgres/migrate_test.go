@@ -0,0 +1,60 @@
+package postgres
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoadMigrations_Ordered(t *testing.T) {
+	fsys := fstest.MapFS{
+		"m/0010_add_index.sql":      {Data: []byte("CREATE INDEX x ON t (c);")},
+		"m/0002_add_table.sql":      {Data: []byte("CREATE TABLE t (c int);")},
+		"m/0001_initial_schema.sql": {Data: []byte("SELECT 1;")},
+		"m/README.md":               {Data: []byte("ignored")},
+	}
+
+	migrations, err := loadMigrations(fsys, "m")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 3 {
+		t.Fatalf("expected 3 migrations, got %d", len(migrations))
+	}
+	for i, want := range []struct {
+		version int
+		name    string
+	}{{1, "initial_schema"}, {2, "add_table"}, {10, "add_index"}} {
+		if migrations[i].Version != want.version || migrations[i].Name != want.name {
+			t.Fatalf("migration %d: expected %d_%s, got %d_%s", i, want.version, want.name, migrations[i].Version, migrations[i].Name)
+		}
+	}
+}
+
+func TestLoadMigrations_Invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		fsys fstest.MapFS
+	}{
+		{"No version", fstest.MapFS{"m/initial.sql": {}}},
+		{"No name", fstest.MapFS{"m/0001.sql": {}}},
+		{"Zero version", fstest.MapFS{"m/0000_initial.sql": {}}},
+		{"Duplicate version", fstest.MapFS{"m/0001_a.sql": {}, "m/001_b.sql": {}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := loadMigrations(tt.fsys, "m"); err == nil {
+				t.Fatalf("expected error")
+			}
+		})
+	}
+}
+
+func TestMigrations_Embedded(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) == 0 || migrations[0].Version != 1 {
+		t.Fatalf("expected the baseline migration first, got %+v", migrations)
+	}
+}
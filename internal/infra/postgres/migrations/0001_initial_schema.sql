@@ -0,0 +1,166 @@
+-- Baseline schema. Tables are created only if missing so databases that predate the
+-- migration runner adopt it without changes.
+
+CREATE TABLE IF NOT EXISTS documents (
+	id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	title       text NOT NULL,
+	author      text,
+	description text,
+	content     jsonb NOT NULL DEFAULT '[]',
+	metadata    jsonb NOT NULL DEFAULT '{}',
+	created_at  timestamptz NOT NULL DEFAULT now(),
+	updated_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS documents_user_id_idx ON documents (user_id);
+
+CREATE TABLE IF NOT EXISTS user_tags (
+	id      uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	name    text NOT NULL,
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS document_tags (
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	tag_id      uuid NOT NULL REFERENCES user_tags (id) ON DELETE CASCADE,
+	PRIMARY KEY (document_id, tag_id)
+);
+
+CREATE TABLE IF NOT EXISTS document_favorites (
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	created_at  timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, document_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_preferences (
+	user_id             uuid PRIMARY KEY REFERENCES auth.users (id) ON DELETE CASCADE,
+	font_size           integer NOT NULL DEFAULT 16,
+	font_family         text NOT NULL DEFAULT 'system-ui',
+	theme               text NOT NULL DEFAULT 'light',
+	highlight_color     text NOT NULL DEFAULT '',
+	margin_size         text NOT NULL DEFAULT '',
+	text_align          text NOT NULL DEFAULT '',
+	hyphenation         boolean NOT NULL DEFAULT false,
+	reading_mode        text NOT NULL DEFAULT '',
+	page_turn_animation text NOT NULL DEFAULT '',
+	brightness          double precision,
+	subscription_plan   text NOT NULL DEFAULT 'free',
+	storage_limit_bytes bigint NOT NULL DEFAULT 0,
+	account_disabled    boolean NOT NULL DEFAULT false,
+	created_at          timestamptz NOT NULL DEFAULT now(),
+	updated_at          timestamptz NOT NULL DEFAULT now()
+);
+
+-- The PostgREST repository relies on updated_at being bumped by the database for
+-- If-Unmodified-Since checks.
+CREATE OR REPLACE FUNCTION lector_set_updated_at() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	NEW.updated_at = now();
+	RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS user_preferences_updated_at ON user_preferences;
+CREATE TRIGGER user_preferences_updated_at BEFORE UPDATE ON user_preferences
+	FOR EACH ROW EXECUTE FUNCTION lector_set_updated_at();
+
+CREATE TABLE IF NOT EXISTS reading_positions (
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	progress    real NOT NULL DEFAULT 0,
+	page_number integer NOT NULL DEFAULT 1,
+	updated_at  timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, document_id)
+);
+
+CREATE TABLE IF NOT EXISTS document_preferences (
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	font_size   integer,
+	font_family text,
+	theme       text,
+	margin_size text,
+	text_align  text,
+	updated_at  timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, document_id)
+);
+
+CREATE TABLE IF NOT EXISTS document_versions (
+	id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	version     integer NOT NULL,
+	title       text NOT NULL,
+	author      text,
+	content     jsonb NOT NULL DEFAULT '[]',
+	metadata    jsonb NOT NULL DEFAULT '{}',
+	reason      text NOT NULL DEFAULT '',
+	created_at  timestamptz NOT NULL DEFAULT now(),
+	UNIQUE (document_id, version)
+);
+
+CREATE TABLE IF NOT EXISTS user_fonts (
+	id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id    uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	name       text NOT NULL,
+	format     text NOT NULL,
+	size_bytes bigint NOT NULL,
+	path       text NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS user_themes (
+	id               uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id          uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	name             text NOT NULL,
+	background_color text NOT NULL,
+	text_color       text NOT NULL,
+	accent_color     text NOT NULL,
+	line_height      double precision NOT NULL,
+	font_family      text,
+	created_at       timestamptz NOT NULL DEFAULT now(),
+	updated_at       timestamptz NOT NULL DEFAULT now(),
+	UNIQUE (user_id, name)
+);
+
+CREATE TABLE IF NOT EXISTS highlights (
+	id          uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	quote       text NOT NULL,
+	page_number integer,
+	progress    real,
+	created_at  timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS highlights_user_id_idx ON highlights (user_id, document_id);
+
+-- Row level security: users only ever see their own rows.
+DO $$
+DECLARE
+	t text;
+BEGIN
+	FOREACH t IN ARRAY ARRAY[
+		'documents', 'user_tags', 'document_favorites', 'user_preferences', 'reading_positions',
+		'document_preferences', 'document_versions', 'user_fonts', 'user_themes', 'highlights'
+	] LOOP
+		EXECUTE format('GRANT SELECT, INSERT, UPDATE, DELETE ON %I TO authenticated', t);
+		EXECUTE format('ALTER TABLE %I ENABLE ROW LEVEL SECURITY', t);
+		EXECUTE format('DROP POLICY IF EXISTS owner_access ON %I', t);
+		EXECUTE format(
+			'CREATE POLICY owner_access ON %I TO authenticated USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid())',
+			t
+		);
+	END LOOP;
+END
+$$;
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON document_tags TO authenticated;
+ALTER TABLE document_tags ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON document_tags;
+CREATE POLICY owner_access ON document_tags TO authenticated
+	USING (EXISTS (SELECT 1 FROM documents d WHERE d.id = document_id AND d.user_id = auth.uid()))
+	WITH CHECK (EXISTS (SELECT 1 FROM documents d WHERE d.id = document_id AND d.user_id = auth.uid()));
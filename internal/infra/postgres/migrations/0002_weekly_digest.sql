@@ -0,0 +1,12 @@
+-- Opt-in weekly reading digest e-mails.
+ALTER TABLE user_preferences ADD COLUMN IF NOT EXISTS weekly_digest boolean NOT NULL DEFAULT false;
+
+-- When each user was last sent a digest. Kept out of user_preferences so sending one
+-- does not bump its updated_at. Only the server's own connection (the digest job)
+-- uses it, so authenticated gets no access.
+CREATE TABLE IF NOT EXISTS digest_deliveries (
+	user_id      uuid PRIMARY KEY REFERENCES auth.users (id) ON DELETE CASCADE,
+	last_sent_at timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS highlights_created_at_idx ON highlights (user_id, created_at);
@@ -0,0 +1,16 @@
+-- Push notification tokens registered by the user's apps. Apps unregister their token
+-- on sign-out, so a shared device does not keep notifying the previous user.
+CREATE TABLE IF NOT EXISTS devices (
+	user_id    uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	token      text NOT NULL,
+	platform   text NOT NULL,
+	created_at timestamptz NOT NULL DEFAULT now(),
+	updated_at timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, token)
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON devices TO authenticated;
+ALTER TABLE devices ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON devices;
+CREATE POLICY owner_access ON devices TO authenticated
+	USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
@@ -0,0 +1,17 @@
+-- OAuth tokens for the cloud drives a user connected to import documents from.
+CREATE TABLE IF NOT EXISTS cloud_connections (
+	user_id       uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	provider      text NOT NULL,
+	access_token  text NOT NULL,
+	refresh_token text NOT NULL DEFAULT '',
+	expires_at    timestamptz,
+	created_at    timestamptz NOT NULL DEFAULT now(),
+	updated_at    timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY (user_id, provider)
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON cloud_connections TO authenticated;
+ALTER TABLE cloud_connections ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON cloud_connections;
+CREATE POLICY owner_access ON cloud_connections TO authenticated
+	USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
@@ -0,0 +1,2 @@
+-- Archived documents stay in the library but drop out of its default listing.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS archived boolean NOT NULL DEFAULT false;
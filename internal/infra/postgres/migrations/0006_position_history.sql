@@ -0,0 +1,18 @@
+-- Sampled reading positions, so users can see their pace and go back to an earlier
+-- place. The service decides which saved positions are kept.
+CREATE TABLE IF NOT EXISTS reading_position_history (
+	id          bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+	user_id     uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	document_id uuid NOT NULL REFERENCES documents (id) ON DELETE CASCADE,
+	progress    real NOT NULL,
+	page_number integer NOT NULL,
+	recorded_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX IF NOT EXISTS reading_position_history_document_idx
+	ON reading_position_history (user_id, document_id, recorded_at);
+
+GRANT SELECT, INSERT, DELETE ON reading_position_history TO authenticated;
+ALTER TABLE reading_position_history ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON reading_position_history;
+CREATE POLICY owner_access ON reading_position_history TO authenticated
+	USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
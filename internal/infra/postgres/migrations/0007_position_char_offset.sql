@@ -0,0 +1,4 @@
+-- Reading positions are anchored to a character offset in the document's text, which
+-- survives re-pagination and reprocessing; progress is derived from it.
+ALTER TABLE reading_positions ADD COLUMN IF NOT EXISTS char_offset integer;
+ALTER TABLE reading_position_history ADD COLUMN IF NOT EXISTS char_offset integer;
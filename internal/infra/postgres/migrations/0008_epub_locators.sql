@@ -0,0 +1,4 @@
+-- EPUB reading positions and highlights are placed by a structural locator (spine
+-- document, element path and offset) that survives changes to text extraction.
+ALTER TABLE reading_positions ADD COLUMN IF NOT EXISTS locator jsonb;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS locator jsonb;
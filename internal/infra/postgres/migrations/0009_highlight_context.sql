@@ -0,0 +1,7 @@
+-- Highlights keep the text around their quote and the content version they were
+-- placed against, so they can be relocated after the document's text changes.
+-- Highlights whose quote can no longer be found are kept, marked orphaned.
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS context_before text NOT NULL DEFAULT '';
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS context_after text NOT NULL DEFAULT '';
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS content_version integer NOT NULL DEFAULT 0;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS orphaned boolean NOT NULL DEFAULT false;
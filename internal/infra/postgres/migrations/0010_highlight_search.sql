@@ -0,0 +1,6 @@
+-- Highlights can carry a note, and quotes and notes are searchable as full text.
+-- The "simple" configuration does not stem, so it suits highlights in any language.
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS note text NOT NULL DEFAULT '';
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS search tsvector
+	GENERATED ALWAYS AS (to_tsvector('simple', quote || ' ' || note)) STORED;
+CREATE INDEX IF NOT EXISTS highlights_search_idx ON highlights USING gin (search);
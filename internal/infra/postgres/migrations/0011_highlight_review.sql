@@ -0,0 +1,7 @@
+-- Daily review: highlights resurface on a spaced-repetition schedule, and readers can
+-- mark favorites, which keep coming back.
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS is_favorite boolean NOT NULL DEFAULT false;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS review_count integer NOT NULL DEFAULT 0;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS review_interval_days integer NOT NULL DEFAULT 0;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS review_due_at timestamptz;
+ALTER TABLE highlights ADD COLUMN IF NOT EXISTS reviewed_at timestamptz;
@@ -0,0 +1,3 @@
+-- When a document was last opened in a reader, kept apart from updated_at so edits
+-- do not reorder the "recently opened" shelf.
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS last_opened_at timestamptz;
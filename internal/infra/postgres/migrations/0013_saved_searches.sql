@@ -0,0 +1,18 @@
+-- Searches saved as smart collections. The query and filters are stored as given and
+-- run again each time the collection is listed.
+CREATE TABLE IF NOT EXISTS saved_searches (
+	id         uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id    uuid NOT NULL REFERENCES auth.users (id) ON DELETE CASCADE,
+	name       text NOT NULL,
+	query      text NOT NULL DEFAULT '',
+	filters    jsonb NOT NULL DEFAULT '{}',
+	created_at timestamptz NOT NULL DEFAULT now(),
+	updated_at timestamptz NOT NULL DEFAULT now(),
+	UNIQUE (user_id, name)
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON saved_searches TO authenticated;
+ALTER TABLE saved_searches ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON saved_searches;
+CREATE POLICY owner_access ON saved_searches TO authenticated
+	USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
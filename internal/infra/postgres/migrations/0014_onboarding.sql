@@ -0,0 +1,12 @@
+-- Accounts that have been onboarded. A row is written on an account's first request
+-- when starter content is enabled, so the content is added once.
+CREATE TABLE IF NOT EXISTS user_onboarding (
+	user_id      uuid PRIMARY KEY REFERENCES auth.users (id) ON DELETE CASCADE,
+	onboarded_at timestamptz NOT NULL DEFAULT now()
+);
+
+GRANT SELECT, INSERT, UPDATE, DELETE ON user_onboarding TO authenticated;
+ALTER TABLE user_onboarding ENABLE ROW LEVEL SECURITY;
+DROP POLICY IF EXISTS owner_access ON user_onboarding;
+CREATE POLICY owner_access ON user_onboarding TO authenticated
+	USING (user_id = auth.uid()) WITH CHECK (user_id = auth.uid());
@@ -0,0 +1,27 @@
+-- The plan, the storage limit and the disabled flag live in user_preferences, which
+-- users write with their own token (through PostgREST or WithUserTx). Only privileged
+-- connections may change them: for the authenticated role a new row starts from the
+-- defaults and an update keeps the stored values. A user may still disable their own
+-- account, but not enable it again.
+CREATE OR REPLACE FUNCTION lector_guard_entitlements() RETURNS trigger
+LANGUAGE plpgsql
+AS $$
+BEGIN
+	IF current_user <> 'authenticated' THEN
+		RETURN NEW;
+	END IF;
+	IF TG_OP = 'INSERT' THEN
+		NEW.subscription_plan = 'free';
+		NEW.storage_limit_bytes = 0;
+	ELSE
+		NEW.subscription_plan = OLD.subscription_plan;
+		NEW.storage_limit_bytes = OLD.storage_limit_bytes;
+		NEW.account_disabled = OLD.account_disabled OR NEW.account_disabled;
+	END IF;
+	RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS user_preferences_entitlements ON user_preferences;
+CREATE TRIGGER user_preferences_entitlements BEFORE INSERT OR UPDATE ON user_preferences
+	FOR EACH ROW EXECUTE FUNCTION lector_guard_entitlements();
@@ -0,0 +1,24 @@
+-- Starter content is for accounts created while it is enabled, not for accounts that
+-- already existed with an empty library. Signing up now queues the account with a
+-- row whose onboarded_at is NULL, and the server sets it when it adds the content.
+-- Accounts without a row, like those created before this migration, are never
+-- onboarded.
+ALTER TABLE user_onboarding ALTER COLUMN onboarded_at DROP NOT NULL;
+ALTER TABLE user_onboarding ALTER COLUMN onboarded_at DROP DEFAULT;
+
+-- Runs as its owner: the role that inserts into auth.users has no access to
+-- public tables.
+CREATE OR REPLACE FUNCTION lector_queue_onboarding() RETURNS trigger
+LANGUAGE plpgsql
+SECURITY DEFINER
+SET search_path = ''
+AS $$
+BEGIN
+	INSERT INTO public.user_onboarding (user_id) VALUES (NEW.id) ON CONFLICT (user_id) DO NOTHING;
+	RETURN NEW;
+END
+$$;
+
+DROP TRIGGER IF EXISTS lector_queue_onboarding ON auth.users;
+CREATE TRIGGER lector_queue_onboarding AFTER INSERT ON auth.users
+	FOR EACH ROW EXECUTE FUNCTION lector_queue_onboarding();
@@ -0,0 +1,99 @@
+// Package postgres provides an optional direct Postgres connection pool used for
+// heavy operations that would be slow through PostgREST.
+package postgres
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// NewPool creates a pgx connection pool and verifies connectivity.
+func NewPool(ctx context.Context, databaseURL string) (*pgxpool.Pool, error) {
+	if databaseURL == "" {
+		return nil, fmt.Errorf("database URL must be provided")
+	}
+
+	cfg, err := pgxpool.ParseConfig(databaseURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database URL: %w", err)
+	}
+	cfg.MaxConnIdleTime = 5 * time.Minute
+
+	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create connection pool: %w", err)
+	}
+
+	pingCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	if err := pool.Ping(pingCtx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+
+	return pool, nil
+}
+
+// WithUserTx runs fn inside a transaction that impersonates the user who owns token.
+//
+// The transaction switches to the `authenticated` role and sets `request.jwt.claims`
+// exactly like PostgREST does, so Supabase RLS policies (auth.uid()) keep applying.
+// The token must already have been validated by the auth middleware.
+func WithUserTx(ctx context.Context, pool *pgxpool.Pool, token string, fn func(tx pgx.Tx) error) error {
+	claims, err := ClaimsFromToken(token)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() { _ = tx.Rollback(ctx) }()
+
+	if _, err := tx.Exec(ctx, "SET LOCAL ROLE authenticated"); err != nil {
+		return fmt.Errorf("failed to set role: %w", err)
+	}
+	if _, err := tx.Exec(ctx, "SELECT set_config('request.jwt.claims', $1, true)", claims); err != nil {
+		return fmt.Errorf("failed to set jwt claims: %w", err)
+	}
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// ClaimsFromToken returns the JSON claims segment of a JWT without verifying the signature.
+func ClaimsFromToken(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("invalid token format")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("invalid token payload: %w", err)
+	}
+
+	var claims map[string]interface{}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return "", fmt.Errorf("invalid token claims: %w", err)
+	}
+	if sub, _ := claims["sub"].(string); sub == "" {
+		return "", fmt.Errorf("token has no subject")
+	}
+
+	return string(payload), nil
+}
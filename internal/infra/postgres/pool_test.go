@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func makeToken(payload string) string {
+	return "header." + base64.RawURLEncoding.EncodeToString([]byte(payload)) + ".signature"
+}
+
+func TestClaimsFromToken(t *testing.T) {
+	payload := `{"sub":"user-1","role":"authenticated"}`
+	claims, err := ClaimsFromToken(makeToken(payload))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims != payload {
+		t.Fatalf("expected claims %s, got %s", payload, claims)
+	}
+}
+
+func TestClaimsFromToken_Invalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{"Not a JWT", "opaque-token"},
+		{"Bad base64", "a.!!!.c"},
+		{"Not JSON", makeToken("not-json")},
+		{"Missing subject", makeToken(`{"role":"authenticated"}`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ClaimsFromToken(tt.token); err == nil {
+				t.Fatalf("expected error for %q", tt.token)
+			}
+		})
+	}
+}
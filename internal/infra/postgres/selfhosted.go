@@ -0,0 +1,23 @@
+package postgres
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+//go:embed selfhosted.sql
+var selfHostedSQL string
+
+// EnsureSelfHosted creates the roles, auth.uid() and auth.users that Supabase would
+// otherwise provide, so the migrations and RLS policies work on plain Postgres. It must
+// not be used against a Supabase database.
+func EnsureSelfHosted(ctx context.Context, pool *pgxpool.Pool) error {
+	// Without arguments pgx uses the simple protocol, which accepts several statements.
+	if _, err := pool.Exec(ctx, selfHostedSQL); err != nil {
+		return fmt.Errorf("failed to prepare self-hosted database: %w", err)
+	}
+	return nil
+}
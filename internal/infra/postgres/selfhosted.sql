@@ -0,0 +1,40 @@
+-- Supabase stand-ins for self-hosted mode (SELF_HOSTED=true), applied before the
+-- migrations on each start. Every statement is idempotent.
+--
+-- Supabase provides the `authenticated` role, auth.uid() and auth.users that the
+-- migrations, RLS policies and WithUserTx rely on; plain Postgres gets minimal
+-- versions here. Never apply this file to a Supabase database.
+
+CREATE SCHEMA IF NOT EXISTS auth;
+
+DO $$
+BEGIN
+	IF NOT EXISTS (SELECT 1 FROM pg_roles WHERE rolname = 'authenticated') THEN
+		CREATE ROLE authenticated NOLOGIN;
+	END IF;
+END
+$$;
+
+GRANT authenticated TO CURRENT_USER;
+
+-- auth.uid() returns the subject of the claims WithUserTx stores for the transaction.
+CREATE OR REPLACE FUNCTION auth.uid() RETURNS uuid
+LANGUAGE sql STABLE
+AS $$
+	SELECT nullif(current_setting('request.jwt.claims', true)::jsonb ->> 'sub', '')::uuid
+$$;
+
+GRANT USAGE ON SCHEMA auth TO authenticated;
+GRANT USAGE ON SCHEMA public TO authenticated;
+
+-- Accounts of the local auth backend. Only the server's own connection reads them.
+CREATE TABLE IF NOT EXISTS auth.users (
+	id            uuid PRIMARY KEY DEFAULT gen_random_uuid(),
+	email         text NOT NULL UNIQUE,
+	password_hash text NOT NULL,
+	name          text NOT NULL DEFAULT '',
+	created_at    timestamptz NOT NULL DEFAULT now(),
+	updated_at    timestamptz NOT NULL DEFAULT now()
+);
+
+REVOKE ALL ON auth.users FROM authenticated;
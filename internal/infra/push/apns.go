@@ -0,0 +1,166 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	apnsProduction = "https://api.push.apple.com"
+	apnsSandbox    = "https://api.sandbox.push.apple.com"
+
+	// apnsTokenTTL is how long a provider token is reused; Apple rejects tokens older
+	// than an hour and throttles refreshes more often than every 20 minutes.
+	apnsTokenTTL = 50 * time.Minute
+)
+
+// APNs sends notifications to iOS devices with token-based (.p8 key) authentication.
+// The default HTTP client negotiates the HTTP/2 connection APNs requires.
+type APNs struct {
+	host   string
+	keyID  string
+	teamID string
+	topic  string
+	key    *ecdsa.PrivateKey
+	client *http.Client
+
+	mu       sync.Mutex
+	token    string
+	issuedAt time.Time
+}
+
+// NewAPNsFromFile creates a sender with the signing key at path.
+func NewAPNsFromFile(path, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read APNs key: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("invalid APNs key: not PEM encoded")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs key: %w", err)
+	}
+	key, ok := parsed.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("invalid APNs key: not an ECDSA key")
+	}
+	return NewAPNs(key, keyID, teamID, topic, sandbox), nil
+}
+
+// NewAPNs creates a sender for the app identified by topic (its bundle ID).
+func NewAPNs(key *ecdsa.PrivateKey, keyID, teamID, topic string, sandbox bool) *APNs {
+	host := apnsProduction
+	if sandbox {
+		host = apnsSandbox
+	}
+	return &APNs{
+		host:   host,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		key:    key,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Send implements domain.PushSender.
+func (a *APNs) Send(ctx context.Context, device *domain.Device, notification *domain.PushNotification) error {
+	token, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": notification.Title, "body": notification.Body},
+		},
+	}
+	for k, v := range notification.Data {
+		if k != "aps" {
+			payload[k] = v
+		}
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode APNs payload: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.host+"/3/device/"+url.PathEscape(device.Token), bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to build APNs request: %w", err)
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("APNs request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+
+	var reason struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 512)).Decode(&reason)
+	if resp.StatusCode == http.StatusGone || reason.Reason == "BadDeviceToken" || reason.Reason == "Unregistered" {
+		return domain.ErrDeviceUnregistered
+	}
+	return fmt.Errorf("APNs request failed with status %d: %s", resp.StatusCode, reason.Reason)
+}
+
+// providerToken returns the ES256 JWT APNs authenticates requests with, signing a
+// new one when the cached token is older than apnsTokenTTL.
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.token != "" && time.Since(a.issuedAt) < apnsTokenTTL {
+		return a.token, nil
+	}
+
+	now := time.Now()
+	header, err := json.Marshal(map[string]string{"alg": "ES256", "kid": a.keyID})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]any{"iss": a.teamID, "iat": now.Unix()})
+	if err != nil {
+		return "", err
+	}
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	r, s, err := ecdsa.Sign(rand.Reader, a.key, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign APNs token: %w", err)
+	}
+	// JWS wants the raw 32-byte r and s, not the ASN.1 form.
+	signature := make([]byte, 64)
+	r.FillBytes(signature[:32])
+	s.FillBytes(signature[32:])
+
+	a.token = unsigned + "." + base64.RawURLEncoding.EncodeToString(signature)
+	a.issuedAt = now
+	return a.token, nil
+}
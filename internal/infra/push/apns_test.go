@@ -0,0 +1,84 @@
+package push
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestAPNs_Send(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var payload map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !validAPNsToken(&key.PublicKey, strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")) {
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"reason":"InvalidProviderToken"}`))
+			return
+		}
+		if r.Header.Get("apns-topic") != "com.example.lector" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"reason":"TopicDisallowed"}`))
+			return
+		}
+		if r.URL.Path == "/3/device/stale" {
+			w.WriteHeader(http.StatusGone)
+			_, _ = w.Write([]byte(`{"reason":"Unregistered"}`))
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+	}))
+	defer srv.Close()
+
+	apns := NewAPNs(key, "KEY123", "TEAM456", "com.example.lector", true)
+	apns.host = srv.URL
+
+	notification := &domain.PushNotification{Title: "Ready", Body: "Middlemarch", Data: map[string]string{"document_id": "doc-1"}}
+	if err := apns.Send(context.Background(), &domain.Device{Token: "device", Platform: domain.DevicePlatformIOS}, notification); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	alert := payload["aps"].(map[string]any)["alert"].(map[string]any)
+	if alert["title"] != "Ready" || payload["document_id"] != "doc-1" {
+		t.Fatalf("unexpected payload: %v", payload)
+	}
+
+	err = apns.Send(context.Background(), &domain.Device{Token: "stale", Platform: domain.DevicePlatformIOS}, notification)
+	if !errors.Is(err, domain.ErrDeviceUnregistered) {
+		t.Fatalf("expected ErrDeviceUnregistered, got %v", err)
+	}
+}
+
+// validAPNsToken checks the ES256 signature and the key and team IDs of a provider token.
+func validAPNsToken(pub *ecdsa.PublicKey, token string) bool {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	header, _ := base64.RawURLEncoding.DecodeString(parts[0])
+	claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	if !strings.Contains(string(header), `"kid":"KEY123"`) || !strings.Contains(string(claims), `"iss":"TEAM456"`) {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || len(signature) != 64 {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	r, s := new(big.Int).SetBytes(signature[:32]), new(big.Int).SetBytes(signature[32:])
+	return ecdsa.Verify(pub, digest[:], r, s)
+}
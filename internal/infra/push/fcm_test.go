@@ -0,0 +1,78 @@
+package push
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestFCM_Send(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	tokenRequests := 0
+	var sent fcmMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			if r.FormValue("assertion") == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			_, _ = w.Write([]byte(`{"access_token":"access","expires_in":3600}`))
+		case "/send":
+			if r.Header.Get("Authorization") != "Bearer access" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			_ = json.NewDecoder(r.Body).Decode(&sent)
+			if sent.Message.Token == "stale" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"error":{"status":"NOT_FOUND"}}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"name":"projects/lector/messages/1"}`))
+		}
+	}))
+	defer srv.Close()
+
+	fcm, err := newFCM(serviceAccount{
+		ProjectID:   "lector",
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail: "push@lector.iam.gserviceaccount.com",
+		TokenURI:    srv.URL + "/token",
+	})
+	if err != nil {
+		t.Fatalf("newFCM: %v", err)
+	}
+	fcm.endpoint = srv.URL + "/send"
+
+	notification := &domain.PushNotification{Title: "Ready", Body: "Middlemarch", Data: map[string]string{"document_id": "doc-1"}}
+	if err := fcm.Send(context.Background(), &domain.Device{Token: "device", Platform: domain.DevicePlatformAndroid}, notification); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	if sent.Message.Token != "device" || sent.Message.Notification.Title != "Ready" || sent.Message.Data["document_id"] != "doc-1" {
+		t.Fatalf("unexpected message: %+v", sent)
+	}
+
+	err = fcm.Send(context.Background(), &domain.Device{Token: "stale", Platform: domain.DevicePlatformAndroid}, notification)
+	if !errors.Is(err, domain.ErrDeviceUnregistered) {
+		t.Fatalf("expected ErrDeviceUnregistered, got %v", err)
+	}
+	if tokenRequests != 1 {
+		t.Fatalf("expected the access token to be reused, got %d token requests", tokenRequests)
+	}
+}
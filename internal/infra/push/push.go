@@ -0,0 +1,54 @@
+// Package push provides domain.PushSender implementations for FCM and APNs.
+package push
+
+import (
+	"context"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// New builds a sender that routes iOS devices to APNs and Android and web devices to
+// FCM. It returns nil when neither provider is configured.
+func New(config domain.PushConfig) (domain.PushSender, error) {
+	var router Router
+	if config.FCMCredentialsFile != "" {
+		fcm, err := NewFCMFromFile(config.FCMCredentialsFile)
+		if err != nil {
+			return nil, err
+		}
+		router.FCM = fcm
+	}
+	if config.APNsKeyFile != "" {
+		if config.APNsKeyID == "" || config.APNsTeamID == "" || config.APNsTopic == "" {
+			return nil, fmt.Errorf("APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required with APNS_KEY_FILE")
+		}
+		apns, err := NewAPNsFromFile(config.APNsKeyFile, config.APNsKeyID, config.APNsTeamID, config.APNsTopic, config.APNsSandbox)
+		if err != nil {
+			return nil, err
+		}
+		router.APNs = apns
+	}
+	if router.FCM == nil && router.APNs == nil {
+		return nil, nil
+	}
+	return &router, nil
+}
+
+// Router sends each device through the provider of its platform.
+type Router struct {
+	FCM  domain.PushSender // android and web
+	APNs domain.PushSender // ios
+}
+
+// Send implements domain.PushSender.
+func (r *Router) Send(ctx context.Context, device *domain.Device, notification *domain.PushNotification) error {
+	sender := r.FCM
+	if device.Platform == domain.DevicePlatformIOS {
+		sender = r.APNs
+	}
+	if sender == nil {
+		return fmt.Errorf("no push provider configured for %s devices", device.Platform)
+	}
+	return sender.Send(ctx, device, notification)
+}
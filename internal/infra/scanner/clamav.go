@@ -0,0 +1,105 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	clamAVChunkSize = 64 * 1024
+	clamAVTimeout   = 60 * time.Second
+)
+
+// ClamAV scans uploads with a clamd daemon using the INSTREAM command.
+type ClamAV struct {
+	network string
+	address string
+}
+
+// NewClamAV creates a clamd scanner. address is "tcp://host:port", "unix:///path/clamd.sock"
+// or a bare "host:port".
+func NewClamAV(address string) *ClamAV {
+	network, addr := "tcp", address
+	if rest, ok := strings.CutPrefix(address, "unix://"); ok {
+		network, addr = "unix", rest
+	} else if rest, ok := strings.CutPrefix(address, "tcp://"); ok {
+		addr = rest
+	}
+	return &ClamAV{network: network, address: addr}
+}
+
+// Scan implements domain.UploadScanner.
+func (c *ClamAV) Scan(ctx context.Context, filename string, content io.Reader) (*domain.ScanResult, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	deadline := time.Now().Add(clamAVTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to start clamd stream: %w", err)
+	}
+
+	var size [4]byte
+	chunk := make([]byte, clamAVChunkSize)
+	for {
+		n, err := io.ReadFull(content, chunk)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := conn.Write(size[:]); err != nil {
+				return nil, fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+			if _, err := conn.Write(chunk[:n]); err != nil {
+				return nil, fmt.Errorf("failed to stream to clamd: %w", err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload for clamd: %w", err)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := conn.Write(size[:]); err != nil {
+		return nil, fmt.Errorf("failed to finish clamd stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && reply == "" {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// parseClamAVReply interprets "stream: OK", "stream: <signature> FOUND" and "... ERROR".
+func parseClamAVReply(reply string) (*domain.ScanResult, error) {
+	result := strings.TrimSpace(strings.TrimPrefix(reply, "stream:"))
+	switch {
+	case result == "OK":
+		return &domain.ScanResult{Clean: true, Engine: "clamav"}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &domain.ScanResult{
+			Clean:     false,
+			Signature: strings.TrimSuffix(result, " FOUND"),
+			Engine:    "clamav",
+		}, nil
+	default:
+		return nil, fmt.Errorf("clamd error: %s", reply)
+	}
+}
@@ -0,0 +1,106 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// fakeClamd accepts one INSTREAM session, records the streamed bytes and writes reply.
+func fakeClamd(t *testing.T, reply string) (string, <-chan []byte) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { _ = ln.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		if cmd, err := r.ReadString(0); err != nil || cmd != "zINSTREAM\x00" {
+			return
+		}
+
+		var body bytes.Buffer
+		for {
+			var size uint32
+			if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+				return
+			}
+			if size == 0 {
+				break
+			}
+			if _, err := io.CopyN(&body, r, int64(size)); err != nil {
+				return
+			}
+		}
+		received <- body.Bytes()
+		_, _ = conn.Write([]byte(reply + "\x00"))
+	}()
+
+	return "tcp://" + ln.Addr().String(), received
+}
+
+func TestClamAV_Scan(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		clean     bool
+		signature string
+		wantErr   bool
+	}{
+		{name: "clean", reply: "stream: OK", clean: true},
+		{name: "infected", reply: "stream: Eicar-Test-Signature FOUND", signature: "Eicar-Test-Signature"},
+		{name: "error", reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, received := fakeClamd(t, tt.reply)
+			data := bytes.Repeat([]byte("x"), clamAVChunkSize+10)
+
+			result, err := NewClamAV(addr).Scan(context.Background(), "book.pdf", bytes.NewReader(data))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.Clean != tt.clean || result.Signature != tt.signature || result.Engine != "clamav" {
+				t.Fatalf("unexpected result: %+v", result)
+			}
+			if got := <-received; !bytes.Equal(got, data) {
+				t.Fatalf("clamd received %d bytes, want %d", len(got), len(data))
+			}
+		})
+	}
+}
+
+func TestNewClamAV_Address(t *testing.T) {
+	tests := map[string][2]string{
+		"tcp://clamd:3310":             {"tcp", "clamd:3310"},
+		"clamd:3310":                   {"tcp", "clamd:3310"},
+		"unix:///run/clamd/clamd.sock": {"unix", "/run/clamd/clamd.sock"},
+	}
+	for address, want := range tests {
+		c := NewClamAV(address)
+		if c.network != want[0] || c.address != want[1] {
+			t.Fatalf("NewClamAV(%q) = %s %s, want %s %s", address, c.network, c.address, want[0], want[1])
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package scanner
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// HTTP scans uploads with a cloud scanning API. The file is POSTed as the request
+// body and the service must answer {"clean": bool, "signature": "..."}.
+type HTTP struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewHTTP creates a scanner for the API at url. apiKey is sent as a bearer token when set.
+func NewHTTP(url, apiKey string) *HTTP {
+	return &HTTP{
+		url:    url,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+type httpScanResponse struct {
+	Clean     bool   `json:"clean"`
+	Signature string `json:"signature"`
+	Engine    string `json:"engine"`
+}
+
+// Scan implements domain.UploadScanner.
+func (s *HTTP) Scan(ctx context.Context, filename string, content io.Reader) (*domain.ScanResult, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build scan request: %w", err)
+	}
+	// Send a Content-Length when the size is known; some APIs reject chunked bodies.
+	if sized, ok := content.(interface{ Size() int64 }); ok {
+		req.ContentLength = sized.Size()
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("X-Filename", filename)
+	if s.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+s.apiKey)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("scan request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return nil, fmt.Errorf("scan request failed with status %d: %s", resp.StatusCode, body)
+	}
+
+	var out httpScanResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, fmt.Errorf("invalid scan response: %w", err)
+	}
+
+	engine := out.Engine
+	if engine == "" {
+		engine = "http"
+	}
+	return &domain.ScanResult{Clean: out.Clean, Signature: out.Signature, Engine: engine}, nil
+}
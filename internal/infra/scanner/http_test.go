@@ -0,0 +1,45 @@
+package scanner
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestHTTP_Scan(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "infected" {
+			_, _ = w.Write([]byte(`{"clean":false,"signature":"Trojan.PDF","engine":"cloud"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"clean":true}`))
+	}))
+	defer srv.Close()
+
+	scanner := NewHTTP(srv.URL, "key")
+
+	result, err := scanner.Scan(context.Background(), "book.pdf", strings.NewReader("infected"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.Clean || result.Signature != "Trojan.PDF" || result.Engine != "cloud" {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+
+	result, err = scanner.Scan(context.Background(), "book.pdf", strings.NewReader("fine"))
+	if err != nil || !result.Clean || result.Engine != "http" {
+		t.Fatalf("unexpected result: %+v, %v", result, err)
+	}
+
+	if _, err := NewHTTP(srv.URL, "wrong").Scan(context.Background(), "book.pdf", strings.NewReader("fine")); err == nil {
+		t.Fatalf("expected error for non-200 response")
+	}
+}
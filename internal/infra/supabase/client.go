@@ -1,18 +1,36 @@
 package supabase
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unsafe"
 
 	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
 
+	"github.com/supabase-community/gotrue-go/types"
+	"github.com/supabase-community/postgrest-go"
 	"github.com/supabase-community/supabase-go"
 )
 
+// requestTimeout bounds every HTTP call to Supabase, response body included. It is
+// longer than the repositories' own budgets, so it only ends calls their callers have
+// already given up on: postgrest-go takes no context, so nothing else would.
+const requestTimeout = 45 * time.Second
+
 // SupabaseClient implements the domain.SupabaseClient interface
 type SupabaseClient struct {
 	client *supabase.Client
 	config domain.Config
 	logger domain.Logger
+	guard  *resilience.Executor
+	tokens *tokenClientCache
 }
 
 // SupabaseUser represents a user from Supabase Auth
@@ -26,10 +44,52 @@ type SupabaseUser struct {
 
 // NewSupabaseClient creates a new Supabase client instance
 func NewSupabaseClient(config domain.Config, logger domain.Logger) domain.SupabaseClient {
+	policy := resilience.DefaultPolicy()
+	policy.Retryable = IsTransient
+
+	guard := resilience.NewExecutor("supabase", policy)
+	guard.OnStateChange = func(name string, from, to resilience.State) {
+		logger.Warn("Circuit breaker state changed", "name", name, "from", from, "to", to)
+	}
+
 	return &SupabaseClient{
 		config: config,
 		logger: logger,
+		guard:  guard,
+		tokens: newTokenClientCache(tokenClientCacheSize),
+	}
+}
+
+// Guard returns the retry/circuit-breaker executor shared by all Supabase calls
+func (s *SupabaseClient) Guard() *resilience.Executor {
+	return s.guard
+}
+
+// IsTransient reports whether a Supabase/PostgREST error is worth retrying.
+// Besides network failures this covers gateway errors (non-JSON 5xx bodies),
+// throttling by the API gateway (429) and PostgREST/Postgres connection errors.
+func IsTransient(err error) bool {
+	if resilience.IsTransient(err) {
+		return true
+	}
+	if err == nil {
+		return false
+	}
+	msg := err.Error()
+	for _, marker := range []string{
+		"error parsing error response",             // HTML/empty body from a 502/503/504 gateway
+		"rate limit exceeded", "Too Many Requests", // 429 from the API gateway
+		"(PGRST000)", "(PGRST001)", "(PGRST002)", "(PGRST003)", // PostgREST cannot reach the database or its pool
+		"(57P01)", "(57P03)", "(53300)", // admin shutdown, cannot connect now, too many connections
+		"response status code 429", "response status code 502", // GoTrue throttling or behind a failing gateway
+		"response status code 503", "response status code 504",
+		"connection reset", "connection refused", "i/o timeout",
+	} {
+		if strings.Contains(msg, marker) {
+			return true
+		}
 	}
+	return false
 }
 
 func (s *SupabaseClient) DB() *supabase.Client {
@@ -49,6 +109,7 @@ func (s *SupabaseClient) Initialize() error {
 	if err != nil {
 		return fmt.Errorf("failed to create Supabase client: %w", err)
 	}
+	withTimeout(client, requestTimeout)
 
 	s.client = client
 	s.logger.Info("Supabase client initialized successfully", "url", supabaseURL)
@@ -65,13 +126,18 @@ func (s *SupabaseClient) GetSupabaseClient() *supabase.Client {
 	return s.client
 }
 
-// GetClientWithToken returns a new Supabase client configured with the user's token
-// This is needed for RLS policies to work correctly
+// GetClientWithToken returns a Supabase client configured with the user's token
+// This is needed for RLS policies to work correctly. Clients are cached per token
+// until the token expires.
 func (s *SupabaseClient) GetClientWithToken(token string) (*supabase.Client, error) {
 	if s.client == nil {
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
+	if client, ok := s.tokens.get(token); ok {
+		return client, nil
+	}
+
 	supabaseURL := s.config.GetSupabaseURL()
 	supabaseKey := s.config.GetSupabaseKey()
 
@@ -86,21 +152,75 @@ func (s *SupabaseClient) GetClientWithToken(token string) (*supabase.Client, err
 	if err != nil {
 		return nil, fmt.Errorf("failed to create Supabase client with token: %w", err)
 	}
+	withTimeout(client, requestTimeout)
 
+	s.tokens.add(token, client)
 	return client, nil
 }
 
+// withTimeout bounds the HTTP calls of a Supabase client to timeout. supabase-go keeps
+// its PostgREST client in an unexported field, so its transport is reached by
+// reflection; without a parent transport it would use http.DefaultTransport.
+func withTimeout(client *supabase.Client, timeout time.Duration) {
+	client.Auth = client.Auth.WithClient(http.Client{Timeout: timeout})
+	rest := reflect.ValueOf(client).Elem().FieldByName("rest")
+	if !rest.IsValid() || rest.Kind() != reflect.Pointer || rest.IsNil() {
+		return
+	}
+	if restClient := (*postgrest.Client)(unsafe.Pointer(rest.Pointer())); restClient.Transport != nil {
+		restClient.Transport.Parent = deadlineTransport{parent: http.DefaultTransport, timeout: timeout}
+	}
+}
+
+// deadlineTransport gives each request a deadline covering its response body, the
+// way http.Client.Timeout does for clients we cannot configure.
+type deadlineTransport struct {
+	parent  http.RoundTripper
+	timeout time.Duration
+}
+
+func (t deadlineTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), t.timeout)
+	resp, err := t.parent.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases a request's deadline once its body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
 // ValidateToken validates a Supabase JWT token and returns user info
-func (s *SupabaseClient) ValidateToken(token string) (*domain.SupabaseUser, error) {
+func (s *SupabaseClient) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
 	if s.client == nil {
 		return nil, fmt.Errorf("Supabase client not initialized")
 	}
 
 	// Get user info using an auth client with the access token.
 	// Note: passing "Authorization" via Supabase client headers does not affect GoTrue requests.
-	user, err := s.client.Auth.WithToken(token).GetUser()
-	if err != nil {
+	var user *types.UserResponse
+	err := s.guard.Do(ctx, func() error {
+		var err error
+		user, err = s.client.Auth.WithToken(token).GetUser()
+		return err
+	})
+	if err := domain.UpstreamFailure(s.guard, err); err != nil {
 		s.logger.Error("Failed to validate token with Supabase", err)
+		if errors.Is(err, domain.ErrUpstreamUnavailable) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 
@@ -113,6 +233,7 @@ func (s *SupabaseClient) ValidateToken(token string) (*domain.SupabaseUser, erro
 		ID:           user.ID.String(),
 		Email:        user.Email,
 		UserMetadata: user.UserMetadata,
+		AppMetadata:  user.AppMetadata,
 		CreatedAt:    user.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
 		UpdatedAt:    user.UpdatedAt.Format("2006-01-02T15:04:05Z07:00"),
 	}
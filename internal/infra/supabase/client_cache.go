@@ -0,0 +1,121 @@
+package supabase
+
+import (
+	"container/list"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+const (
+	// tokenClientCacheSize bounds how many per-token clients are kept alive.
+	tokenClientCacheSize = 512
+	// tokenClientMaxTTL caps the lifetime of an entry when the token has no usable exp claim.
+	tokenClientMaxTTL = 5 * time.Minute
+)
+
+type tokenClientEntry struct {
+	token     string
+	client    *supabase.Client
+	expiresAt time.Time
+}
+
+// tokenClientCache is an LRU cache of token-scoped Supabase clients. Entries expire
+// together with the JWT they were built for, so a client never outlives its token.
+type tokenClientCache struct {
+	mu       sync.Mutex
+	capacity int
+	ll       *list.List
+	items    map[string]*list.Element
+	now      func() time.Time
+}
+
+func newTokenClientCache(capacity int) *tokenClientCache {
+	return &tokenClientCache{
+		capacity: capacity,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached client for token, dropping it if it has expired.
+func (c *tokenClientCache) get(token string) (*supabase.Client, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[token]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*tokenClientEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.removeElement(el)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.client, true
+}
+
+// add stores client for token until the token expires. Already expired tokens are not cached.
+func (c *tokenClientCache) add(token string, client *supabase.Client) {
+	now := c.now()
+	expiresAt := now.Add(tokenClientMaxTTL)
+	if exp, ok := tokenExpiry(token); ok && exp.Before(expiresAt) {
+		expiresAt = exp
+	}
+	if !now.Before(expiresAt) {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[token]; ok {
+		entry := el.Value.(*tokenClientEntry)
+		entry.client = client
+		entry.expiresAt = expiresAt
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	c.items[token] = c.ll.PushFront(&tokenClientEntry{token: token, client: client, expiresAt: expiresAt})
+	for c.ll.Len() > c.capacity {
+		c.removeElement(c.ll.Back())
+	}
+}
+
+func (c *tokenClientCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func (c *tokenClientCache) removeElement(el *list.Element) {
+	c.ll.Remove(el)
+	delete(c.items, el.Value.(*tokenClientEntry).token)
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it. The token is
+// validated by the auth middleware before it ever reaches the repositories.
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == 0 {
+		return time.Time{}, false
+	}
+	return time.Unix(claims.Exp, 0), true
+}
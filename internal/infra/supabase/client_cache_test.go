@@ -0,0 +1,89 @@
+package supabase
+
+import (
+	"encoding/base64"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+func testToken(exp int64) string {
+	payload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"sub":"user-1","exp":%d}`, exp)))
+	return "header." + payload + ".sig"
+}
+
+func newTestCache(capacity int, now time.Time) (*tokenClientCache, *time.Time) {
+	c := newTokenClientCache(capacity)
+	c.now = func() time.Time { return now }
+	return c, &now
+}
+
+func TestTokenClientCache_ExpiresWithToken(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	c, now := newTestCache(4, start)
+
+	token := testToken(start.Add(time.Minute).Unix())
+	client := &supabase.Client{}
+	c.add(token, client)
+
+	if got, ok := c.get(token); !ok || got != client {
+		t.Fatalf("expected cached client")
+	}
+
+	*now = start.Add(2 * time.Minute)
+	if _, ok := c.get(token); ok {
+		t.Fatalf("expected entry to expire with the token")
+	}
+	if c.len() != 0 {
+		t.Fatalf("expected expired entry to be evicted, got %d entries", c.len())
+	}
+}
+
+func TestTokenClientCache_SkipsExpiredTokens(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	c, _ := newTestCache(4, start)
+
+	c.add(testToken(start.Add(-time.Second).Unix()), &supabase.Client{})
+	if c.len() != 0 {
+		t.Fatalf("expected expired token not to be cached")
+	}
+}
+
+func TestTokenClientCache_CapsTTLWithoutExp(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	c, now := newTestCache(4, start)
+
+	c.add("opaque-token", &supabase.Client{})
+	if _, ok := c.get("opaque-token"); !ok {
+		t.Fatalf("expected token without exp to be cached")
+	}
+
+	*now = start.Add(tokenClientMaxTTL)
+	if _, ok := c.get("opaque-token"); ok {
+		t.Fatalf("expected entry to expire after max TTL")
+	}
+}
+
+func TestTokenClientCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	start := time.Unix(1_700_000_000, 0)
+	c, _ := newTestCache(2, start)
+	exp := start.Add(time.Hour).Unix()
+
+	a, b, d := testToken(exp)+"a", testToken(exp)+"b", testToken(exp)+"d"
+	c.add(a, &supabase.Client{})
+	c.add(b, &supabase.Client{})
+	c.get(a) // a becomes most recently used
+	c.add(d, &supabase.Client{})
+
+	if _, ok := c.get(b); ok {
+		t.Fatalf("expected least recently used entry to be evicted")
+	}
+	if _, ok := c.get(a); !ok {
+		t.Fatalf("expected recently used entry to be kept")
+	}
+	if _, ok := c.get(d); !ok {
+		t.Fatalf("expected newest entry to be kept")
+	}
+}
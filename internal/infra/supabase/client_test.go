@@ -0,0 +1,46 @@
+package supabase
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/supabase-community/supabase-go"
+)
+
+func TestWithTimeout_EndsHungCalls(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client, err := supabase.NewClient(server.URL, "key", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	withTimeout(client, 100*time.Millisecond)
+
+	done := make(chan error, 1)
+	go func() {
+		_, _, err := client.From("documents").Select("*", "", false).Execute()
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Fatal("expected the hung call to fail")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the hung call was not ended by the timeout")
+	}
+
+	if _, err := client.Auth.WithToken("token").GetUser(); err == nil {
+		t.Fatal("expected the hung auth call to fail")
+	}
+}
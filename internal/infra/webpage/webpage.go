@@ -0,0 +1,123 @@
+// Package webpage provides the domain.WebPageFetcher used to import saved web
+// articles. Pages are only fetched from public addresses, since the URLs come from
+// user uploads.
+package webpage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	// requestTimeout bounds each page download, redirects included.
+	requestTimeout = 30 * time.Second
+	// maxPageSize bounds downloaded pages.
+	maxPageSize = 10 << 20 // 10MB
+	// maxRedirects bounds the redirects followed for one page.
+	maxRedirects = 5
+
+	userAgent = "Lector/1.0 (+https://lector.thefndrs.com)"
+)
+
+// errPrivateAddress is returned for pages on loopback, private or link-local addresses.
+var errPrivateAddress = errors.New("address is not on the public internet")
+
+// Fetcher downloads HTML pages over HTTP(S).
+type Fetcher struct {
+	client *http.Client
+	// allowPrivate lets tests fetch from httptest servers on loopback.
+	allowPrivate bool
+}
+
+func New() *Fetcher {
+	f := &Fetcher{}
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		// Checked on the resolved address, so a public name pointing at a private
+		// address is refused too.
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || (!f.allowPrivate && !isPublic(ip)) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	f.client = &http.Client{
+		Timeout:   requestTimeout,
+		Transport: &http.Transport{DialContext: dialer.DialContext},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return checkScheme(req.URL)
+		},
+	}
+	return f
+}
+
+func (f *Fetcher) Fetch(ctx context.Context, rawURL string) (*domain.WebPage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URL: %w", err)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("status %d", resp.StatusCode)
+	}
+
+	contentType := strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0])
+	if contentType != "text/html" && contentType != "application/xhtml+xml" {
+		return nil, fmt.Errorf("page is %q, not HTML", contentType)
+	}
+	html, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(html) > maxPageSize {
+		return nil, fmt.Errorf("page exceeds %d bytes", maxPageSize)
+	}
+	return &domain.WebPage{URL: resp.Request.URL.String(), HTML: html}, nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("unsupported URL scheme %q", u.Scheme)
+	}
+	return nil
+}
+
+// isPublic reports whether ip is a unicast address on the public internet.
+func isPublic(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !ip.IsLoopback() && !cgnat.Contains(ip)
+}
+
+// cgnat is the shared address space of carrier-grade NAT (RFC 6598).
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
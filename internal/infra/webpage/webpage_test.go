@@ -0,0 +1,74 @@
+package webpage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestFetcher(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/moved":
+			http.Redirect(w, r, "/article", http.StatusMovedPermanently)
+		case "/article":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			_, _ = io.WriteString(w, "<html><body><p>Hello</p></body></html>")
+		case "/paper.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			_, _ = io.WriteString(w, "%PDF-1.7")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	f := New()
+	f.allowPrivate = true
+
+	page, err := f.Fetch(context.Background(), server.URL+"/moved")
+	if err != nil || page.URL != server.URL+"/article" || string(page.HTML) != "<html><body><p>Hello</p></body></html>" {
+		t.Fatalf("unexpected page %+v (%v)", page, err)
+	}
+	for _, path := range []string{"/paper.pdf", "/gone"} {
+		if _, err := f.Fetch(context.Background(), server.URL+path); err == nil {
+			t.Errorf("expected %s to be refused", path)
+		}
+	}
+	if _, err := f.Fetch(context.Background(), "file:///etc/passwd"); err == nil {
+		t.Error("expected a file URL to be refused")
+	}
+}
+
+func TestFetcher_RefusesPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("expected no request to reach a loopback server")
+	}))
+	defer server.Close()
+
+	if _, err := New().Fetch(context.Background(), server.URL); !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("expected errPrivateAddress, got %v", err)
+	}
+}
+
+func TestIsPublic(t *testing.T) {
+	for addr, want := range map[string]bool{
+		"93.184.215.14":   true,
+		"2606:4700::1111": true,
+		"127.0.0.1":       false,
+		"10.0.0.8":        false,
+		"192.168.1.1":     false,
+		"169.254.169.254": false,
+		"100.64.0.1":      false,
+		"::1":             false,
+		"fd00::1":         false,
+	} {
+		if got := isPublic(net.ParseIP(addr)); got != want {
+			t.Errorf("isPublic(%s) = %v, want %v", addr, got, want)
+		}
+	}
+}
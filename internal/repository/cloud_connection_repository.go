@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// CloudConnectionRepository implements the domain.CloudConnectionRepository interface
+// using Supabase.
+type CloudConnectionRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewCloudConnectionRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.CloudConnectionRepository {
+	return &CloudConnectionRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func (r *CloudConnectionRepository) Get(ctx context.Context, principal domain.Principal, provider string) (*domain.CloudConnection, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("cloud_connections").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Eq("provider", provider))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cloud connection: %w", err)
+	}
+
+	var rows []cloudConnectionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrCloudNotConnected
+	}
+	return rows[0].toDomain(), nil
+}
+
+func (r *CloudConnectionRepository) Upsert(ctx context.Context, principal domain.Principal, connection *domain.CloudConnection) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	row := map[string]interface{}{
+		"user_id":       principal.UserID,
+		"provider":      connection.Provider,
+		"access_token":  connection.Token.AccessToken,
+		"refresh_token": connection.Token.RefreshToken,
+		"expires_at":    nil,
+		"updated_at":    time.Now().UTC(),
+	}
+	if !connection.Token.Expiry.IsZero() {
+		row["expires_at"] = connection.Token.Expiry.UTC()
+	}
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("cloud_connections").
+		Upsert(row, "user_id,provider", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("failed to save cloud connection: %w", err)
+	}
+
+	var rows []cloudConnectionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) > 0 {
+		*connection = *rows[0].toDomain()
+	}
+	return nil
+}
+
+func (r *CloudConnectionRepository) Delete(ctx context.Context, principal domain.Principal, provider string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("cloud_connections").
+		Delete("", "").
+		Eq("user_id", principal.UserID).
+		Eq("provider", provider))
+	if err != nil {
+		return fmt.Errorf("failed to delete cloud connection: %w", err)
+	}
+	return nil
+}
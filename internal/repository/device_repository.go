@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// DeviceRepository implements the domain.DeviceRepository interface using Supabase.
+type DeviceRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewDeviceRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.DeviceRepository {
+	return &DeviceRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func (r *DeviceRepository) Upsert(ctx context.Context, principal domain.Principal, device *domain.Device) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	row := map[string]interface{}{
+		"user_id":    principal.UserID,
+		"token":      device.Token,
+		"platform":   device.Platform,
+		"updated_at": time.Now().UTC(),
+	}
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("devices").
+		Upsert(row, "user_id,token", "representation", ""))
+	if err != nil {
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+
+	var rows []deviceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) > 0 {
+		*device = *rows[0].toDomain()
+	}
+	return nil
+}
+
+func (r *DeviceRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Device, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("devices").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Order("updated_at", &postgrest.OrderOpts{Ascending: false}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	var rows []deviceRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	devices := make([]*domain.Device, 0, len(rows))
+	for i := range rows {
+		devices = append(devices, rows[i].toDomain())
+	}
+	return devices, nil
+}
+
+func (r *DeviceRepository) Delete(ctx context.Context, principal domain.Principal, token string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("devices").
+		Delete("", "").
+		Eq("user_id", principal.UserID).
+		Eq("token", token))
+	if err != nil {
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+	return nil
+}
@@ -1,13 +1,15 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
 	"strings"
 	"time"
 
 	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/supabase-go"
 )
 
 // DocumentRepository implements the domain.DocumentRepository interface
@@ -27,12 +29,13 @@ func NewDocumentRepository(supabaseClient domain.SupabaseClient, logger domain.L
 
 // Create a new document in Supabase
 func (r *DocumentRepository) Create(
+	ctx context.Context,
+	principal domain.Principal,
 	document *domain.Document,
-	token string,
 ) error {
 
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -176,7 +179,7 @@ func (r *DocumentRepository) Create(
 	}
 
 	// Use the cleaned and validated data
-	_, _, err = client.From("documents").Insert(finalData, false, "", "", "").Execute()
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("documents").Insert(finalData, false, "", "", ""))
 	if err != nil {
 		// Log the error details for debugging
 		r.logger.Error("Failed to insert document in Supabase", err,
@@ -200,59 +203,19 @@ func (r *DocumentRepository) Create(
 // Specifically targets \u0000 and other sequences that cause PostgreSQL 22P05 errors
 // PostgreSQL is very strict about Unicode escape sequences in JSONB
 func (r *DocumentRepository) removeProblematicUnicode(jsonStr string) string {
-	// First, remove all control character Unicode escapes (0000-001F)
+	// Remove all control character escapes (0000-001F) and surrogates (D800-DFFF)
 	// These are the most common cause of PostgreSQL 22P05 errors
-	reControlChars := regexp.MustCompile(`\\u00[0-1][0-9a-fA-F]`)
-	jsonStr = reControlChars.ReplaceAllString(jsonStr, "")
-
-	// Remove surrogate pairs (0xD800-0xDFFF) which are invalid in JSON
-	reSurrogates := regexp.MustCompile(`\\u[dD][89aAbBcCdDeEfF][0-9a-fA-F]{2}`)
-	jsonStr = reSurrogates.ReplaceAllString(jsonStr, "")
-
-	// Remove any remaining problematic Unicode escapes that might cause issues
-	// Specifically target sequences that PostgreSQL might reject
-	reProblematic := regexp.MustCompile(`\\u000[0-9a-fA-F]|\\u00[01][0-9a-fA-F]|\\u001[0-9a-fA-F]`)
-	jsonStr = reProblematic.ReplaceAllString(jsonStr, "")
+	jsonStr = domain.StripUnsafeUnicodeEscapes(jsonStr)
 
-	// Remove any literal NULL bytes and other control characters
+	// Remove any literal NULL bytes
 	jsonStr = strings.ReplaceAll(jsonStr, "\x00", "")
-	jsonStr = strings.ReplaceAll(jsonStr, "\u0000", "")
-
-	// Verify the cleaned JSON is still valid
-	var verify interface{}
-	if err := json.Unmarshal([]byte(jsonStr), &verify); err != nil {
-		// If invalid, try to recover by removing ALL Unicode escape sequences
-		// and re-encoding properly
-		reAllUnicode := regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
-		matches := reAllUnicode.FindAllString(jsonStr, -1)
-		for _, match := range matches {
-			// Extract the hex value
-			hexStr := match[2:] // Remove \u prefix
-			// Check if it's a control character or surrogate
-			if len(hexStr) == 4 {
-				// Remove control characters (0000-001F) and surrogates (D800-DFFF)
-				if (hexStr[0] == '0' && hexStr[1] == '0' && hexStr[2] <= '1') ||
-					(hexStr[0] == 'd' || hexStr[0] == 'D') && (hexStr[1] >= '8' && hexStr[1] <= 'f' || hexStr[1] >= '8' && hexStr[1] <= 'F') {
-					jsonStr = strings.ReplaceAll(jsonStr, match, "")
-				}
-			}
-		}
-
-		// Try to unmarshal again
-		if err := json.Unmarshal([]byte(jsonStr), &verify); err != nil {
-			// Last resort: remove ALL Unicode escapes and let Go re-encode
-			jsonStr = reAllUnicode.ReplaceAllStringFunc(jsonStr, func(match string) string {
-				return "" // Remove all Unicode escapes
-			})
-		}
-	}
 
 	return jsonStr
 }
 
 // GetByID retrieves a document by ID
-func (r *DocumentRepository) GetByID(id string, token string) (*domain.Document, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) GetByID(ctx context.Context, principal domain.Principal, id string) (*domain.Document, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -260,56 +223,55 @@ func (r *DocumentRepository) GetByID(id string, token string) (*domain.Document,
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("documents").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("documents").
 		Select("*", "", false).
-		Eq("id", id).
-		Execute()
+		Eq("id", id))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get document: %w", err)
 	}
 
-	var documents []map[string]interface{}
-	if err := json.Unmarshal(data, &documents); err != nil {
+	var rows []documentRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	if len(documents) == 0 {
+	if len(rows) == 0 {
 		return nil, fmt.Errorf("document not found")
 	}
 
-	docData := documents[0]
+	document, err := rows[0].toDomain()
+	if err != nil {
+		return nil, err
+	}
 
 	// Best-effort: populate favorite flag.
 	// We only have document_id here; we can read user_id from the document row and check favorites.
-	if userID := getString(docData, "user_id"); userID != "" {
-		isFav, favErr := r.isFavorite(userID, id, token)
+	if document.UserID != "" {
+		isFav, favErr := r.isFavorite(ctx, principal, id)
 		if favErr == nil {
-			docData["is_favorite"] = isFav
+			document.IsFavorite = isFav
 		}
 	}
 
 	// Fetch tag for this document - get tag_id first, then get tag name
-	docTagsData, _, err := client.From("document_tags").
+	docTagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_tags").
 		Select("tag_id", "", false).
 		Eq("document_id", id).
-		Limit(1, "").
-		Execute()
+		Limit(1, ""))
 	if err == nil {
-		var docTagsList []map[string]interface{}
-		if err := json.Unmarshal(docTagsData, &docTagsList); err == nil && len(docTagsList) > 0 {
-			tagID := getString(docTagsList[0], "tag_id")
+		var docTags []documentTagRow
+		if err := json.Unmarshal(docTagsData, &docTags); err == nil && len(docTags) > 0 {
+			tagID := docTags[0].TagID
 			if tagID != "" {
 				// Get tag name from user_tags
-				tagData, _, err := client.From("user_tags").
+				tagData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 					Select("name", "", false).
-					Eq("id", tagID).
-					Execute()
+					Eq("id", tagID))
 				if err == nil {
-					var tags []map[string]interface{}
+					var tags []tagRow
 					if err := json.Unmarshal(tagData, &tags); err == nil && len(tags) > 0 {
-						tagName := getString(tags[0], "name")
-						if tagName != "" {
-							docData["tag"] = tagName
+						if tagName := tags[0].Name; tagName != "" {
+							document.Tag = &tagName
 						}
 					}
 				}
@@ -317,12 +279,12 @@ func (r *DocumentRepository) GetByID(id string, token string) (*domain.Document,
 		}
 	}
 
-	return r.mapToDocument(docData)
+	return document, nil
 }
 
 // GetByUserID retrieves all documents for a user
-func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain.Document, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) GetByUserID(ctx context.Context, principal domain.Principal) ([]*domain.Document, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -332,30 +294,29 @@ func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain
 
 	// Select all fields except content to reduce payload size when listing documents
 	// Content is only needed when opening a specific document for reading
-	data, _, err := client.From("documents").
-		Select("id,user_id,title,author,description,metadata,created_at,updated_at", "", false).
-		Eq("user_id", userID).
-		Execute()
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("documents").
+		Select("id,user_id,title,author,description,metadata,archived,created_at,updated_at,last_opened_at", "", false).
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get documents: %w", err)
 	}
 
-	var documentsData []map[string]interface{}
-	if err := json.Unmarshal(data, &documentsData); err != nil {
+	var rows []documentRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	// Fetch favorites for user once and mark docs.
-	favIDs, favErr := r.favoriteIDsByUser(userID, token)
+	favIDs, favErr := r.favoriteIDsByUser(ctx, principal)
 	if favErr != nil {
-		r.logger.Warn("Failed to fetch favorites for user", "error", favErr, "user_id", userID)
+		r.logger.Warn("Failed to fetch favorites for user", "error", favErr, "user_id", principal.UserID)
 	}
 
 	// Get all document IDs to fetch tags
-	documentIDs := make([]string, 0, len(documentsData))
-	for _, docData := range documentsData {
-		if docID, ok := docData["id"].(string); ok && docID != "" {
-			documentIDs = append(documentIDs, docID)
+	documentIDs := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.ID != "" {
+			documentIDs = append(documentIDs, row.ID)
 		}
 	}
 
@@ -363,18 +324,17 @@ func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain
 	tagsMap := make(map[string]string)
 	if len(documentIDs) > 0 {
 		// First, get all document_tag relationships
-		docTagsData, _, err := client.From("document_tags").
+		docTagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_tags").
 			Select("document_id,tag_id", "", false).
-			In("document_id", documentIDs).
-			Execute()
+			In("document_id", documentIDs))
 		if err == nil {
-			var docTagsList []map[string]interface{}
-			if err := json.Unmarshal(docTagsData, &docTagsList); err == nil {
+			var docTags []documentTagRow
+			if err := json.Unmarshal(docTagsData, &docTags); err == nil {
 				// Group by document_id to get only first tag per document
 				docTagMap := make(map[string]string) // document_id -> tag_id (only first)
-				for _, docTagData := range docTagsList {
-					docID := getString(docTagData, "document_id")
-					tagID := getString(docTagData, "tag_id")
+				for _, docTag := range docTags {
+					docID := docTag.DocumentID
+					tagID := docTag.TagID
 					if docID != "" && tagID != "" {
 						// Only store first tag for each document
 						if _, exists := docTagMap[docID]; !exists {
@@ -395,20 +355,17 @@ func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain
 
 				// Get tag names from user_tags
 				if len(tagIDs) > 0 {
-					tagsData, _, err := client.From("user_tags").
+					tagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 						Select("id,name", "", false).
-						In("id", tagIDs).
-						Execute()
+						In("id", tagIDs))
 					if err == nil {
-						var tagsList []map[string]interface{}
-						if err := json.Unmarshal(tagsData, &tagsList); err == nil {
+						var tags []tagRow
+						if err := json.Unmarshal(tagsData, &tags); err == nil {
 							// Create map of tag_id -> tag_name
 							tagNameMap := make(map[string]string)
-							for _, tagData := range tagsList {
-								tagID := getString(tagData, "id")
-								tagName := getString(tagData, "name")
-								if tagID != "" && tagName != "" {
-									tagNameMap[tagID] = tagName
+							for _, tag := range tags {
+								if tag.ID != "" && tag.Name != "" {
+									tagNameMap[tag.ID] = tag.Name
 								}
 							}
 
@@ -428,31 +385,24 @@ func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain
 	}
 
 	var documents []*domain.Document
-	for _, docData := range documentsData {
-		// Set content to empty JSON array since we didn't fetch it
-		docData["content"] = json.RawMessage("[]")
+	for _, row := range rows {
+		// Content was not selected, so it decodes to an empty JSON array
+		doc, err := row.toDomain()
+		if err != nil {
+			r.logger.Error("Failed to map document", err, "doc_id", row.ID)
+			continue
+		}
 
 		// Add favorite flag.
-		if favErr == nil {
-			if docID, ok := docData["id"].(string); ok && docID != "" {
-				if favIDs[docID] {
-					docData["is_favorite"] = true
-				}
-			}
+		if favErr == nil && favIDs[doc.ID] {
+			doc.IsFavorite = true
 		}
 
 		// Add tag to document data (single tag only)
-		if docID, ok := docData["id"].(string); ok {
-			if tag, exists := tagsMap[docID]; exists {
-				docData["tag"] = tag
-			}
+		if tag, exists := tagsMap[doc.ID]; exists {
+			doc.Tag = &tag
 		}
 
-		doc, err := r.mapToDocument(docData)
-		if err != nil {
-			r.logger.Error("Failed to map document", err, "doc_id", docData["id"])
-			continue
-		}
 		documents = append(documents, doc)
 	}
 
@@ -460,8 +410,8 @@ func (r *DocumentRepository) GetByUserID(userID string, token string) ([]*domain
 }
 
 // SetFavorite inserts/deletes the favorite relationship for a (user, document).
-func (r *DocumentRepository) SetFavorite(userID string, documentID string, isFavorite bool, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -471,13 +421,13 @@ func (r *DocumentRepository) SetFavorite(userID string, documentID string, isFav
 
 	if isFavorite {
 		row := map[string]interface{}{
-			"user_id":     userID,
+			"user_id":     principal.UserID,
 			"document_id": documentID,
 		}
 		// Insert is idempotent due to PK (user_id, document_id). If it already exists, Supabase may return 409.
 		// We treat 409 as success; supabase-go doesn't expose status cleanly here, so we just attempt insert and
 		// ignore "duplicate key" style errors.
-		_, _, err = client.From("document_favorites").Insert(row, false, "", "", "").Execute()
+		_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_favorites").Insert(row, false, "", "", ""))
 		if err != nil {
 			// Best-effort duplicate detection.
 			if strings.Contains(strings.ToLower(err.Error()), "duplicate") ||
@@ -489,20 +439,59 @@ func (r *DocumentRepository) SetFavorite(userID string, documentID string, isFav
 		return nil
 	}
 
-	_, _, err = client.From("document_favorites").
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_favorites").
 		Delete("", "").
-		Eq("user_id", userID).
-		Eq("document_id", documentID).
-		Execute()
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID))
 	if err != nil {
 		return fmt.Errorf("failed to unset favorite: %w", err)
 	}
 	return nil
 }
 
+// SetArchived flips the document's archived flag. Only that column is written, so
+// the document's content and updated_at are left as they are.
+func (r *DocumentRepository) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("documents").
+		Update(map[string]interface{}{"archived": archived}, "minimal", "").
+		Eq("id", documentID))
+	if err != nil {
+		return fmt.Errorf("failed to set archived: %w", err)
+	}
+	return nil
+}
+
+// SetLastOpened records when the document was opened in a reader. Like archiving,
+// it leaves updated_at alone.
+func (r *DocumentRepository) SetLastOpened(ctx context.Context, principal domain.Principal, documentID string, at time.Time) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("documents").
+		Update(map[string]interface{}{"last_opened_at": at}, "minimal", "").
+		Eq("id", documentID))
+	if err != nil {
+		return fmt.Errorf("failed to set last opened: %w", err)
+	}
+	return nil
+}
+
 // Update a document in Supabase
-func (r *DocumentRepository) Update(document *domain.Document, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) Update(ctx context.Context, principal domain.Principal, document *domain.Document) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -555,79 +544,130 @@ func (r *DocumentRepository) Update(document *domain.Document, token string) err
 		data["description"] = nil
 	}
 
-	_, _, err = client.From("documents").
-		Update(data, "", "").
-		Eq("id", document.ID).
-		Execute()
+	update := client.From("documents").
+		Update(data, "representation", "").
+		Eq("id", document.ID)
+	unmodifiedSince, conditional := domain.UnmodifiedSinceFromContext(ctx)
+	if conditional {
+		update = update.Eq("updated_at", unmodifiedSince.Format(time.RFC3339Nano))
+	}
+	updated, err := executeWrite(ctx, r.supabaseClient.Guard(), update)
 	if err != nil {
 		return fmt.Errorf("failed to update document: %w", err)
 	}
-
-	// Update tag relationship in document_tags table
-	// First, get user_id to find the tag
-	userID := document.UserID
-	if userID == "" {
-		// Try to get user_id from the document if not set
-		docData, _, err := client.From("documents").
-			Select("user_id", "", false).
-			Eq("id", document.ID).
-			Execute()
-		if err == nil {
-			var docs []map[string]interface{}
-			if err := json.Unmarshal(docData, &docs); err == nil && len(docs) > 0 {
-				userID = getString(docs[0], "user_id")
-			}
+	if conditional {
+		var rows []documentRow
+		if err := json.Unmarshal(updated, &rows); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(rows) == 0 {
+			return domain.ErrStaleUpdate
 		}
 	}
 
-	if userID != "" {
-		// Delete existing tag relationships for this document
-		_, _, err = client.From("document_tags").
-			Delete("", "").
-			Eq("document_id", document.ID).
-			Execute()
-		if err != nil {
-			r.logger.Warn("Failed to delete existing document tags", "error", err, "document_id", document.ID)
-		}
+	tagName := ""
+	if document.Tag != nil {
+		tagName = *document.Tag
+	}
+	r.replaceDocumentTag(ctx, client, principal, document.ID, tagName)
+	return nil
+}
 
-		// If tag is provided, create new relationship
-		if document.Tag != nil && *document.Tag != "" {
-			// Find the tag_id from user_tags table
-			tagData, _, err := client.From("user_tags").
-				Select("id", "", false).
-				Eq("user_id", userID).
-				Eq("name", *document.Tag).
-				Execute()
-			if err == nil {
-				var tags []map[string]interface{}
-				if err := json.Unmarshal(tagData, &tags); err == nil && len(tags) > 0 {
-					tagID := getString(tags[0], "id")
-					if tagID != "" {
-						// Create new relationship
-						docTagData := map[string]interface{}{
-							"document_id": document.ID,
-							"tag_id":      tagID,
-						}
-						_, _, err = client.From("document_tags").
-							Insert(docTagData, false, "", "", "").
-							Execute()
-						if err != nil {
-							r.logger.Warn("Failed to create document tag relationship", "error", err, "document_id", document.ID, "tag", *document.Tag)
-						}
-					}
-				}
-			} else {
-				r.logger.Warn("Failed to find tag", "error", err, "tag_name", *document.Tag, "user_id", userID)
-			}
+// Patch updates only the patched columns. Tags live in document_tags, so a tag
+// change leaves the documents row alone apart from updated_at.
+func (r *DocumentRepository) Patch(ctx context.Context, principal domain.Principal, documentID string, patch domain.DocumentPatch) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	data := map[string]interface{}{"updated_at": patch.UpdatedAt}
+	if patch.Title != nil {
+		data["title"] = *patch.Title
+	}
+	if patch.Author != nil {
+		data["author"] = *patch.Author
+	}
+	update := client.From("documents").
+		Update(data, "representation", "").
+		Eq("id", documentID)
+	unmodifiedSince, conditional := domain.UnmodifiedSinceFromContext(ctx)
+	if conditional {
+		update = update.Eq("updated_at", unmodifiedSince.Format(time.RFC3339Nano))
+	}
+	updated, err := executeWrite(ctx, r.supabaseClient.Guard(), update)
+	if err != nil {
+		return fmt.Errorf("failed to patch document: %w", err)
+	}
+	var rows []documentRow
+	if err := json.Unmarshal(updated, &rows); err != nil {
+		return fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		if conditional {
+			return domain.ErrStaleUpdate
 		}
+		return domain.ErrDocumentNotFound
 	}
 
+	if patch.Tag != nil {
+		r.replaceDocumentTag(ctx, client, principal, documentID, *patch.Tag)
+	}
 	return nil
 }
 
+// replaceDocumentTag sets the document's single tag by name; an empty name or one
+// the user has not created leaves it untagged. Failures are logged, as the tag is
+// secondary to the document update that carries it.
+func (r *DocumentRepository) replaceDocumentTag(ctx context.Context, client *supabase.Client, principal domain.Principal, documentID, tagName string) {
+	userID := principal.UserID
+	if userID == "" {
+		return
+	}
+
+	// Delete existing tag relationships for this document
+	_, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_tags").
+		Delete("", "").
+		Eq("document_id", documentID))
+	if err != nil {
+		r.logger.Warn("Failed to delete existing document tags", "error", err, "document_id", documentID)
+	}
+	if tagName == "" {
+		return
+	}
+
+	// Find the tag_id from user_tags table
+	tagData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
+		Select("id", "", false).
+		Eq("user_id", userID).
+		Eq("name", tagName))
+	if err != nil {
+		r.logger.Warn("Failed to find tag", "error", err, "tag_name", tagName, "user_id", userID)
+		return
+	}
+	var tags []tagRow
+	if err := json.Unmarshal(tagData, &tags); err != nil || len(tags) == 0 || tags[0].ID == "" {
+		return
+	}
+
+	// Create new relationship
+	docTagData := map[string]interface{}{
+		"document_id": documentID,
+		"tag_id":      tags[0].ID,
+	}
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_tags").
+		Insert(docTagData, false, "", "", ""))
+	if err != nil {
+		r.logger.Warn("Failed to create document tag relationship", "error", err, "document_id", documentID, "tag", tagName)
+	}
+}
+
 // Delete deletes a document from Supabase
-func (r *DocumentRepository) Delete(id string, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) Delete(ctx context.Context, principal domain.Principal, id string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -635,10 +675,9 @@ func (r *DocumentRepository) Delete(id string, token string) error {
 		return fmt.Errorf("supabase client not initialized")
 	}
 
-	_, _, err = client.From("documents").
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("documents").
 		Delete("", "").
-		Eq("id", id).
-		Execute()
+		Eq("id", id))
 	if err != nil {
 		return fmt.Errorf("failed to delete document: %w", err)
 	}
@@ -646,9 +685,10 @@ func (r *DocumentRepository) Delete(id string, token string) error {
 	return nil
 }
 
-// Search searches documents by title or content
-func (r *DocumentRepository) Search(userID, query string, token string) ([]*domain.Document, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+// Search evaluates the query over the user's documents here, since PostgREST
+// cannot express it. Tags are looked up only when the query filters on them.
+func (r *DocumentRepository) Search(ctx context.Context, principal domain.Principal, query *domain.DocumentQuery) ([]*domain.Document, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -656,50 +696,38 @@ func (r *DocumentRepository) Search(userID, query string, token string) ([]*doma
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	// Get all user documents first (Supabase doesn't have full-text search by default)
-	data, _, err := client.From("documents").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("documents").
 		Select("*", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to search documents: %w", err)
 	}
 
-	var documentsData []map[string]interface{}
-	if err := json.Unmarshal(data, &documentsData); err != nil {
+	var rows []documentRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	// Filter documents by query (case-insensitive search in title and content)
-	queryLower := strings.ToLower(query)
-	var documents []*domain.Document
-	for _, docData := range documentsData {
-		doc, err := r.mapToDocument(docData)
+	tags := make(map[string]*string)
+	if query.Uses(domain.SearchFieldTag) {
+		listed, err := r.GetByUserID(ctx, principal)
 		if err != nil {
-			r.logger.Error("Failed to map document", err, "doc_id", docData["id"])
-			continue
+			return nil, fmt.Errorf("failed to search documents: %w", err)
 		}
-
-		// Search in title
-		titleMatch := strings.Contains(strings.ToLower(doc.Title), queryLower)
-
-		// Search in content (first 1000 chars for performance)
-		contentMatch := false
-		if len(doc.Content) > 0 {
-			contentStr := strings.ToLower(string(doc.Content))
-			if len(contentStr) > 1000 {
-				contentStr = contentStr[:1000]
-			}
-			contentMatch = strings.Contains(contentStr, queryLower)
+		for _, doc := range listed {
+			tags[doc.ID] = doc.Tag
 		}
+	}
 
-		// Search in author
-		authorMatch := false
-		if doc.Author != nil {
-			authorMatch = strings.Contains(strings.ToLower(*doc.Author), queryLower)
+	var documents []*domain.Document
+	for _, row := range rows {
+		doc, err := row.toDomain()
+		if err != nil {
+			r.logger.Error("Failed to map document", err, "doc_id", row.ID)
+			continue
 		}
-
-		if titleMatch || contentMatch || authorMatch {
+		doc.Tag = tags[doc.ID]
+		if query.Match(doc) {
 			documents = append(documents, doc)
 		}
 	}
@@ -707,97 +735,9 @@ func (r *DocumentRepository) Search(userID, query string, token string) ([]*doma
 	return documents, nil
 }
 
-// mapToDocument converts a map to a Document struct
-func (r *DocumentRepository) mapToDocument(data map[string]interface{}) (*domain.Document, error) {
-	document := &domain.Document{
-		ID:          getString(data, "id"),
-		UserID:      getString(data, "user_id"),
-		Title:       getString(data, "title"),
-		Author:      getStringPointer(data, "author"),
-		Description: getStringPointer(data, "description"),
-	}
-
-	// Favorites (optional field in list endpoints)
-	if val, ok := data["is_favorite"]; ok && val != nil {
-		switch v := val.(type) {
-		case bool:
-			document.IsFavorite = v
-		case string:
-			document.IsFavorite = strings.EqualFold(v, "true")
-		case float64:
-			document.IsFavorite = v != 0
-		default:
-			document.IsFavorite = false
-		}
-	}
-
-	// Parse timestamps
-	if createdAt := getString(data, "created_at"); createdAt != "" {
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			document.CreatedAt = t
-		} else if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
-			document.CreatedAt = t
-		}
-	}
-	if updatedAt := getString(data, "updated_at"); updatedAt != "" {
-		if t, err := time.Parse(time.RFC3339, updatedAt); err == nil {
-			document.UpdatedAt = t
-		} else if t, err := time.Parse(time.RFC3339Nano, updatedAt); err == nil {
-			document.UpdatedAt = t
-		}
-	}
-
-	// Parse JSON fields - handle both string and object/array formats
-	if contentVal, ok := data["content"]; ok && contentVal != nil {
-		// Content can come as string (JSON) or as array/object (JSONB)
-		if contentStr, ok := contentVal.(string); ok {
-			// It's a string, use it directly
-			document.Content = json.RawMessage(contentStr)
-		} else {
-			// It's already an object/array, marshal it back to JSON
-			contentJSON, err := json.Marshal(contentVal)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal content: %w", err)
-			}
-			document.Content = json.RawMessage(contentJSON)
-		}
-	} else {
-		// Empty content
-		document.Content = json.RawMessage("[]")
-	}
-
-	if metadataVal, ok := data["metadata"]; ok && metadataVal != nil {
-		// Metadata can come as string (JSON) or as object (JSONB)
-		if metadataStr, ok := metadataVal.(string); ok {
-			// It's a string, unmarshal it
-			if err := json.Unmarshal([]byte(metadataStr), &document.Metadata); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
-			}
-		} else {
-			// It's already an object, marshal and unmarshal to convert
-			metadataJSON, err := json.Marshal(metadataVal)
-			if err != nil {
-				return nil, fmt.Errorf("failed to marshal metadata: %w", err)
-			}
-			if err := json.Unmarshal(metadataJSON, &document.Metadata); err != nil {
-				return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
-			}
-		}
-	}
-
-	// Parse tag (single tag only)
-	if tagVal, ok := data["tag"]; ok && tagVal != nil {
-		if tagStr, ok := tagVal.(string); ok && tagStr != "" {
-			document.Tag = &tagStr
-		}
-	}
-
-	return document, nil
-}
-
 // favoriteIDsByUser returns a set of document_id that are favorited by user.
-func (r *DocumentRepository) favoriteIDsByUser(userID string, token string) (map[string]bool, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) favoriteIDsByUser(ctx context.Context, principal domain.Principal) (map[string]bool, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -805,63 +745,38 @@ func (r *DocumentRepository) favoriteIDsByUser(userID string, token string) (map
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("document_favorites").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_favorites").
 		Select("document_id", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, err
 	}
 
-	var rows []map[string]interface{}
+	var rows []documentFavoriteRow
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, err
 	}
 
 	set := make(map[string]bool, len(rows))
 	for _, row := range rows {
-		if id := getString(row, "document_id"); id != "" {
-			set[id] = true
+		if row.DocumentID != "" {
+			set[row.DocumentID] = true
 		}
 	}
 	return set, nil
 }
 
-func (r *DocumentRepository) isFavorite(userID string, documentID string, token string) (bool, error) {
-	set, err := r.favoriteIDsByUser(userID, token)
+func (r *DocumentRepository) isFavorite(ctx context.Context, principal domain.Principal, documentID string) (bool, error) {
+	set, err := r.favoriteIDsByUser(ctx, principal)
 	if err != nil {
 		return false, err
 	}
 	return set[documentID], nil
 }
 
-// Helper functions for type conversion
-func getString(data map[string]interface{}, key string) string {
-	if val, ok := data[key]; ok && val != nil {
-		if str, ok := val.(string); ok {
-			return str
-		}
-	}
-	return ""
-}
-
-func getStringPointer(data map[string]interface{}, key string) *string {
-	if val, ok := data[key]; ok && val != nil {
-		if str, ok := val.(string); ok {
-			if str == "" {
-				return nil
-			}
-			return &str
-		}
-	}
-	return nil
-}
-
-// getStringArray is defined in user_preference_repository.go and shared across the package
-
 // GetTagsByUserID retrieves all tags for a user from the user_tags table
-func (r *DocumentRepository) GetTagsByUserID(userID string, token string) ([]string, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) GetTagsByUserID(ctx context.Context, principal domain.Principal) ([]string, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -870,23 +785,22 @@ func (r *DocumentRepository) GetTagsByUserID(userID string, token string) ([]str
 	}
 
 	// Fetch tags from user_tags table
-	tagsData, _, err := client.From("user_tags").
+	tagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Select("name", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tags: %w", err)
 	}
 
-	var tagsList []map[string]interface{}
-	if err := json.Unmarshal(tagsData, &tagsList); err != nil {
+	var rows []tagRow
+	if err := json.Unmarshal(tagsData, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal tags: %w", err)
 	}
 
-	tags := make([]string, 0, len(tagsList))
-	for _, tagData := range tagsList {
-		if tagName := getString(tagData, "name"); tagName != "" {
-			tags = append(tags, tagName)
+	tags := make([]string, 0, len(rows))
+	for _, row := range rows {
+		if row.Name != "" {
+			tags = append(tags, row.Name)
 		}
 	}
 
@@ -894,8 +808,8 @@ func (r *DocumentRepository) GetTagsByUserID(userID string, token string) ([]str
 }
 
 // CreateTag creates a new tag for a user in the user_tags table
-func (r *DocumentRepository) CreateTag(userID string, tagName string, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) CreateTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -904,16 +818,15 @@ func (r *DocumentRepository) CreateTag(userID string, tagName string, token stri
 	}
 
 	// Check if tag already exists for this user
-	existingTagsData, _, err := client.From("user_tags").
+	existingTagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Select("id", "", false).
-		Eq("user_id", userID).
-		Eq("name", tagName).
-		Execute()
+		Eq("user_id", principal.UserID).
+		Eq("name", tagName))
 	if err != nil {
 		return fmt.Errorf("failed to check existing tag: %w", err)
 	}
 
-	var existingTags []map[string]interface{}
+	var existingTags []tagRow
 	if err := json.Unmarshal(existingTagsData, &existingTags); err != nil {
 		return fmt.Errorf("failed to unmarshal existing tags: %w", err)
 	}
@@ -924,24 +837,23 @@ func (r *DocumentRepository) CreateTag(userID string, tagName string, token stri
 
 	// Create new tag
 	tagData := map[string]interface{}{
-		"user_id": userID,
+		"user_id": principal.UserID,
 		"name":    tagName,
 	}
 
-	_, _, err = client.From("user_tags").
-		Insert(tagData, false, "", "", "").
-		Execute()
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_tags").
+		Insert(tagData, false, "", "", ""))
 	if err != nil {
 		return fmt.Errorf("failed to create tag: %w", err)
 	}
 
-	r.logger.Info("Tag created successfully", "user_id", userID, "tag_name", tagName)
+	r.logger.Info("Tag created successfully", "user_id", principal.UserID, "tag_name", tagName)
 	return nil
 }
 
 // DeleteTag deletes a tag for a user from the user_tags table
-func (r *DocumentRepository) DeleteTag(userID string, tagName string, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *DocumentRepository) DeleteTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -950,16 +862,15 @@ func (r *DocumentRepository) DeleteTag(userID string, tagName string, token stri
 	}
 
 	// First, find the tag ID
-	tagData, _, err := client.From("user_tags").
+	tagData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Select("id", "", false).
-		Eq("user_id", userID).
-		Eq("name", tagName).
-		Execute()
+		Eq("user_id", principal.UserID).
+		Eq("name", tagName))
 	if err != nil {
 		return fmt.Errorf("failed to find tag: %w", err)
 	}
 
-	var tags []map[string]interface{}
+	var tags []tagRow
 	if err := json.Unmarshal(tagData, &tags); err != nil {
 		return fmt.Errorf("failed to unmarshal tag data: %w", err)
 	}
@@ -968,30 +879,28 @@ func (r *DocumentRepository) DeleteTag(userID string, tagName string, token stri
 		return fmt.Errorf("tag not found")
 	}
 
-	tagID := getString(tags[0], "id")
+	tagID := tags[0].ID
 	if tagID == "" {
 		return fmt.Errorf("tag ID not found")
 	}
 
 	// Delete all document_tag relationships first (CASCADE should handle this, but being explicit)
-	_, _, err = client.From("document_tags").
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_tags").
 		Delete("", "").
-		Eq("tag_id", tagID).
-		Execute()
+		Eq("tag_id", tagID))
 	if err != nil {
 		r.logger.Warn("Failed to delete document_tag relationships", "error", err, "tag_id", tagID)
 		// Continue anyway, CASCADE should handle it
 	}
 
 	// Delete the tag
-	_, _, err = client.From("user_tags").
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Delete("", "").
-		Eq("id", tagID).
-		Execute()
+		Eq("id", tagID))
 	if err != nil {
 		return fmt.Errorf("failed to delete tag: %w", err)
 	}
 
-	r.logger.Info("Tag deleted successfully", "user_id", userID, "tag_name", tagName)
+	r.logger.Info("Tag deleted successfully", "user_id", principal.UserID, "tag_name", tagName)
 	return nil
 }
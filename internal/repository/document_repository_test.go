@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"unicode/utf8"
+)
+
+// assertCleanJSONValue fails the test if any string inside v contains characters that
+// PostgreSQL rejects in JSONB (control characters other than \t, \n, \r, and surrogates).
+func assertCleanJSONValue(t *testing.T, v interface{}) {
+	t.Helper()
+	switch val := v.(type) {
+	case string:
+		for _, r := range val {
+			if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+				t.Fatalf("value contains control character %U: %q", r, val)
+			}
+			if r >= 0xD800 && r <= 0xDFFF {
+				t.Fatalf("value contains surrogate %U: %q", r, val)
+			}
+		}
+	case []interface{}:
+		for _, item := range val {
+			assertCleanJSONValue(t, item)
+		}
+	case map[string]interface{}:
+		for key, item := range val {
+			assertCleanJSONValue(t, key)
+			assertCleanJSONValue(t, item)
+		}
+	}
+}
+
+func FuzzDocumentRepository_RemoveProblematicUnicode(f *testing.F) {
+	seeds := []string{
+		"",
+		"plain text",
+		"null\x00byte",
+		"tab\tnewline\ncarriage\r",
+		"bell\x07escape\x1b form\ffeed",
+		"invalid \xff\xfe utf8",
+		`literal \u0000 escape`,
+		`\u0009u0001`,
+		`\\u0001`,
+		`"quoted" and \backslash`,
+		"emoji 📚 and accents áéíóú",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	r := &DocumentRepository{}
+
+	f.Fuzz(func(t *testing.T, content string) {
+		input, err := json.Marshal([]map[string]interface{}{
+			{"type": "paragraph", "content": content, "page_number": 1},
+		})
+		if err != nil {
+			t.Skip()
+		}
+
+		out := r.removeProblematicUnicode(string(input))
+		if !utf8.ValidString(out) {
+			t.Fatalf("output is not valid UTF-8: %q", out)
+		}
+
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(out), &decoded); err != nil {
+			t.Fatalf("output is not valid JSON: %v (%s)", err, out)
+		}
+		assertCleanJSONValue(t, decoded)
+	})
+}
@@ -0,0 +1,182 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// versionListColumns leaves out content, which can be megabytes per version.
+const versionListColumns = "id,document_id,user_id,version,title,author,metadata,reason,created_at"
+
+// DocumentVersionRepository implements the domain.DocumentVersionRepository interface
+// using Supabase. Versions live in the document_versions table, unique on
+// (document_id, version).
+type DocumentVersionRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewDocumentVersionRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.DocumentVersionRepository {
+	return &DocumentVersionRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func (r *DocumentVersionRepository) Create(ctx context.Context, principal domain.Principal, version *domain.DocumentVersion) (*domain.DocumentVersion, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_versions").
+		Select("version", "", false).
+		Eq("document_id", version.DocumentID).
+		Order("version", &postgrest.OrderOpts{Ascending: false}).
+		Limit(1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read latest version: %w", err)
+	}
+	var latest []documentVersionRow
+	if err := json.Unmarshal(data, &latest); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	next := 1
+	if len(latest) > 0 {
+		next = latest[0].Version + 1
+	}
+
+	// Pass JSONB columns as decoded values so they are not stored as strings.
+	var contentData interface{} = []interface{}{}
+	if len(version.Content) > 0 {
+		if err := json.Unmarshal(version.Content, &contentData); err != nil {
+			return nil, fmt.Errorf("invalid version content: %w", err)
+		}
+	}
+	metadataJSON, err := json.Marshal(version.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+	var metadataData interface{}
+	if err := json.Unmarshal(metadataJSON, &metadataData); err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	row := map[string]interface{}{
+		"document_id": version.DocumentID,
+		"user_id":     principal.UserID,
+		"version":     next,
+		"title":       version.Title,
+		"content":     contentData,
+		"metadata":    metadataData,
+		"reason":      version.Reason,
+	}
+	if version.Author != nil {
+		row["author"] = *version.Author
+	}
+
+	data, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_versions").
+		Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document version: %w", err)
+	}
+
+	var rows []documentVersionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("failed to create document version: empty response")
+	}
+
+	return rows[0].toDomain()
+}
+
+func (r *DocumentVersionRepository) ListByDocument(ctx context.Context, principal domain.Principal, documentID string) ([]*domain.DocumentVersion, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_versions").
+		Select(versionListColumns, "", false).
+		Eq("document_id", documentID).
+		Order("version", &postgrest.OrderOpts{Ascending: false}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document versions: %w", err)
+	}
+
+	var rows []documentVersionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	out := make([]*domain.DocumentVersion, 0, len(rows))
+	for i := range rows {
+		version, err := rows[i].toDomain()
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, version)
+	}
+	return out, nil
+}
+
+func (r *DocumentVersionRepository) Get(ctx context.Context, principal domain.Principal, documentID string, version int) (*domain.DocumentVersion, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_versions").
+		Select("*", "", false).
+		Eq("document_id", documentID).
+		Eq("version", strconv.Itoa(version)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document version: %w", err)
+	}
+
+	var rows []documentVersionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("%w: version %d", domain.ErrVersionNotFound, version)
+	}
+
+	return rows[0].toDomain()
+}
+
+func (r *DocumentVersionRepository) DeleteBefore(ctx context.Context, principal domain.Principal, documentID string, version int) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_versions").
+		Delete("", "").
+		Eq("document_id", documentID).
+		Lt("version", strconv.Itoa(version)))
+	if err != nil {
+		return fmt.Errorf("failed to prune document versions: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// FontRepository implements the domain.FontRepository interface using Supabase.
+// Font metadata lives in the user_fonts table; the files are in storage.
+type FontRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewFontRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.FontRepository {
+	return &FontRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func (r *FontRepository) Create(ctx context.Context, principal domain.Principal, font *domain.Font) (*domain.Font, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	row := map[string]interface{}{
+		"id":         font.ID,
+		"user_id":    principal.UserID,
+		"name":       sanitizeText(font.Name),
+		"format":     font.Format,
+		"size_bytes": font.SizeBytes,
+		"path":       font.Path,
+	}
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_fonts").
+		Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font: %w", err)
+	}
+
+	var rows []fontRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("failed to create font: empty response")
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *FontRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Font, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_fonts").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Order("created_at", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fonts: %w", err)
+	}
+
+	var rows []fontRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	out := make([]*domain.Font, 0, len(rows))
+	for i := range rows {
+		out = append(out, rows[i].toDomain())
+	}
+	return out, nil
+}
+
+func (r *FontRepository) Get(ctx context.Context, principal domain.Principal, fontID string) (*domain.Font, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_fonts").
+		Select("*", "", false).
+		Eq("id", fontID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get font: %w", err)
+	}
+
+	var rows []fontRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrFontNotFound
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *FontRepository) Delete(ctx context.Context, principal domain.Principal, fontID string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_fonts").
+		Delete("", "").
+		Eq("id", fontID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to delete font: %w", err)
+	}
+	return nil
+}
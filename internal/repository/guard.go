@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
+)
+
+// Timeout budgets for a single repository call, retries included. They bound how long
+// a request handler can be held up by a slow or unresponsive Supabase.
+const (
+	readTimeout = 10 * time.Second
+	// Writes get more time because document inserts carry the full extracted content.
+	writeTimeout = 30 * time.Second
+)
+
+// postgrestQuery is satisfied by the postgrest-go filter builders. Builders can be
+// executed more than once, which is what makes retrying them possible.
+type postgrestQuery interface {
+	Execute() ([]byte, int64, error)
+}
+
+// executeRead runs an idempotent query, retrying transient failures with backoff.
+func executeRead(ctx context.Context, guard *resilience.Executor, q postgrestQuery) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var data []byte
+	err := guard.Do(ctx, func() error {
+		var err error
+		data, err = executeContext(ctx, q)
+		return err
+	})
+	return data, domain.UpstreamFailure(guard, err)
+}
+
+// executeWrite runs a write through the circuit breaker without retrying it, since
+// inserts are not idempotent and a retried request may already have been applied.
+func executeWrite(ctx context.Context, guard *resilience.Executor, q postgrestQuery) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var data []byte
+	err := guard.Once(ctx, func() error {
+		var err error
+		data, err = executeContext(ctx, q)
+		return err
+	})
+	return data, domain.UpstreamFailure(guard, err)
+}
+
+// executeContext runs q but stops waiting once ctx is done. postgrest-go does not accept
+// a context, so the underlying HTTP request is left to the Supabase client's own
+// request timeout, which ends it shortly after.
+func executeContext(ctx context.Context, q postgrestQuery) ([]byte, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	type result struct {
+		data []byte
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		data, _, err := q.Execute()
+		done <- result{data: data, err: err}
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-done:
+		return res.data, res.err
+	}
+}
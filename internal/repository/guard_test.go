@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
+)
+
+type stubQuery struct {
+	calls   int
+	block   chan struct{}
+	results []error
+}
+
+func (q *stubQuery) Execute() ([]byte, int64, error) {
+	q.calls++
+	if q.block != nil {
+		<-q.block
+	}
+	var err error
+	if len(q.results) > 0 {
+		err, q.results = q.results[0], q.results[1:]
+	}
+	return []byte(`[]`), 0, err
+}
+
+func TestExecuteContext_StopsWaitingWhenContextDone(t *testing.T) {
+	q := &stubQuery{block: make(chan struct{})}
+	defer close(q.block)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := executeContext(ctx, q)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected deadline exceeded, got %v", err)
+	}
+}
+
+func TestExecuteContext_SkipsCancelledContext(t *testing.T) {
+	q := &stubQuery{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := executeContext(ctx, q); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected canceled, got %v", err)
+	}
+	if q.calls != 0 {
+		t.Fatalf("expected query not to run, got %d calls", q.calls)
+	}
+}
+
+func TestExecuteWrite_DoesNotRetry(t *testing.T) {
+	guard := resilience.NewExecutor(t.Name(), resilience.Policy{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	q := &stubQuery{results: []error{context.DeadlineExceeded, nil}}
+
+	if _, err := executeWrite(context.Background(), guard, q); err == nil {
+		t.Fatalf("expected write error to be returned")
+	}
+	if q.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", q.calls)
+	}
+}
+
+func TestExecuteRead_MarksUpstreamFailures(t *testing.T) {
+	guard := resilience.NewExecutor(t.Name(), resilience.Policy{MaxAttempts: 2, BaseDelay: time.Millisecond, FailureThreshold: 2, OpenTimeout: time.Minute})
+	q := &stubQuery{results: []error{context.DeadlineExceeded, context.DeadlineExceeded}}
+
+	_, err := executeRead(context.Background(), guard, q)
+	var upstream *domain.UpstreamError
+	if !errors.As(err, &upstream) || upstream.RetryAfter != domain.UpstreamRetryAfter {
+		t.Fatalf("expected upstream error after retries, got %v", err)
+	}
+	if q.calls != 2 {
+		t.Fatalf("expected 2 calls, got %d", q.calls)
+	}
+
+	// Two failures opened the breaker; the hint is now the time until its trial call.
+	_, err = executeRead(context.Background(), guard, q)
+	if !errors.As(err, &upstream) || !errors.Is(err, resilience.ErrCircuitOpen) || upstream.RetryAfter <= 50*time.Second {
+		t.Fatalf("expected circuit open upstream error, got %v", err)
+	}
+
+	permanent := errors.New("(23505) duplicate key value")
+	if _, err := executeWrite(context.Background(), resilience.NewExecutor(t.Name()+"/write", resilience.Policy{}), &stubQuery{results: []error{permanent}}); err != permanent {
+		t.Fatalf("expected permanent error unchanged, got %v", err)
+	}
+}
@@ -1,12 +1,12 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"pdf-text-reader/internal/domain"
 	"regexp"
 	"strings"
-	"time"
 
 	"github.com/supabase-community/postgrest-go"
 )
@@ -24,8 +24,8 @@ func NewHighlightRepository(supabaseClient domain.SupabaseClient, logger domain.
 	}
 }
 
-func (r *HighlightRepository) Create(highlight *domain.Highlight, token string) (*domain.Highlight, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *HighlightRepository) Create(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) (*domain.Highlight, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -36,9 +36,13 @@ func (r *HighlightRepository) Create(highlight *domain.Highlight, token string)
 	quote := sanitizeText(highlight.Quote)
 
 	row := map[string]interface{}{
-		"user_id":     highlight.UserID,
-		"document_id": highlight.DocumentID,
-		"quote":       quote,
+		"user_id":         principal.UserID,
+		"document_id":     highlight.DocumentID,
+		"quote":           quote,
+		"note":            sanitizeText(highlight.Note),
+		"context_before":  sanitizeText(highlight.ContextBefore),
+		"context_after":   sanitizeText(highlight.ContextAfter),
+		"content_version": highlight.ContentVersion,
 	}
 	if highlight.PageNumber != nil {
 		row["page_number"] = *highlight.PageNumber
@@ -46,16 +50,18 @@ func (r *HighlightRepository) Create(highlight *domain.Highlight, token string)
 	if highlight.Progress != nil {
 		row["progress"] = *highlight.Progress
 	}
+	if highlight.Locator != nil {
+		row["locator"] = highlight.Locator
+	}
 
 	// Request "representation" so PostgREST returns the inserted row.
-	data, _, err := client.From("highlights").
-		Insert(row, false, "", "representation", "").
-		Execute()
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Insert(row, false, "", "representation", ""))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create highlight: %w", err)
 	}
 
-	var rows []map[string]interface{}
+	var rows []highlightRow
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
@@ -63,11 +69,11 @@ func (r *HighlightRepository) Create(highlight *domain.Highlight, token string)
 		return nil, fmt.Errorf("failed to create highlight: empty response")
 	}
 
-	return mapToHighlight(rows[0]), nil
+	return rows[0].toDomain(), nil
 }
 
-func (r *HighlightRepository) ListByUser(userID string, documentID *string, token string) ([]*domain.Highlight, error) {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+func (r *HighlightRepository) ListByUser(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -77,32 +83,65 @@ func (r *HighlightRepository) ListByUser(userID string, documentID *string, toke
 
 	q := client.From("highlights").
 		Select("*", "", false).
-		Eq("user_id", userID).
+		Eq("user_id", principal.UserID).
 		Order("created_at", &postgrest.OrderOpts{Ascending: false})
 
 	if documentID != nil && *documentID != "" {
 		q = q.Eq("document_id", *documentID)
 	}
 
-	data, _, err := q.Execute()
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), q)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list highlights: %w", err)
 	}
 
-	var rows []map[string]interface{}
+	var rows []highlightRow
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	out := make([]*domain.Highlight, 0, len(rows))
-	for _, row := range rows {
-		out = append(out, mapToHighlight(row))
+	for i := range rows {
+		out = append(out, rows[i].toDomain())
 	}
 	return out, nil
 }
 
-func (r *HighlightRepository) Delete(userID string, highlightID string, token string) error {
-	client, err := r.supabaseClient.GetClientWithToken(token)
+// CountByDocuments tallies the highlights of the given documents. Only document_id is
+// selected, so quotes are not transferred.
+func (r *HighlightRepository) CountByDocuments(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Select("document_id", "", false).
+		Eq("user_id", principal.UserID).
+		In("document_id", documentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to count highlights: %w", err)
+	}
+
+	var rows []struct {
+		DocumentID string `json:"document_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	counts := make(map[string]int)
+	for _, row := range rows {
+		counts[row.DocumentID]++
+	}
+	return counts, nil
+}
+
+func (r *HighlightRepository) Delete(ctx context.Context, principal domain.Principal, highlightID string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -110,79 +149,226 @@ func (r *HighlightRepository) Delete(userID string, highlightID string, token st
 		return fmt.Errorf("supabase client not initialized")
 	}
 
-	_, _, err = client.From("highlights").
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("highlights").
 		Delete("", "").
 		Eq("id", highlightID).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return fmt.Errorf("failed to delete highlight: %w", err)
 	}
 	return nil
 }
 
-func mapToHighlight(data map[string]interface{}) *domain.Highlight {
-	h := &domain.Highlight{
-		ID:         getString(data, "id"),
-		UserID:     getString(data, "user_id"),
-		DocumentID: getString(data, "document_id"),
-		Quote:      getString(data, "quote"),
-	}
-
-	if pn, ok := data["page_number"]; ok && pn != nil {
-		switch v := pn.(type) {
-		case float64:
-			val := int(v)
-			h.PageNumber = &val
-		case int:
-			val := v
-			h.PageNumber = &val
-		case int64:
-			val := int(v)
-			h.PageNumber = &val
-		}
+// MoveToDocument reassigns highlights; the returned representation is only used to
+// count them.
+func (r *HighlightRepository) MoveToDocument(ctx context.Context, principal domain.Principal, fromDocumentIDs []string, toDocumentID string) (int, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return 0, fmt.Errorf("supabase client not initialized")
 	}
 
-	if p, ok := data["progress"]; ok && p != nil {
-		switch v := p.(type) {
-		case float64:
-			val := float32(v)
-			h.Progress = &val
-		case float32:
-			val := v
-			h.Progress = &val
-		case int:
-			val := float32(v)
-			h.Progress = &val
-		case int64:
-			val := float32(v)
-			h.Progress = &val
-		}
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Update(map[string]interface{}{"document_id": toDocumentID}, "representation", "").
+		Eq("user_id", principal.UserID).
+		In("document_id", fromDocumentIDs))
+	if err != nil {
+		return 0, fmt.Errorf("failed to move highlights: %w", err)
 	}
 
-	if createdAt := getString(data, "created_at"); createdAt != "" {
-		if t, err := time.Parse(time.RFC3339, createdAt); err == nil {
-			h.CreatedAt = t
-		} else if t, err := time.Parse(time.RFC3339Nano, createdAt); err == nil {
-			h.CreatedAt = t
+	var rows []struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return len(rows), nil
+}
+
+func (r *HighlightRepository) UpdateAnchor(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Update(map[string]interface{}{
+			"locator":         highlight.Locator,
+			"page_number":     highlight.PageNumber,
+			"progress":        highlight.Progress,
+			"context_before":  sanitizeText(highlight.ContextBefore),
+			"context_after":   sanitizeText(highlight.ContextAfter),
+			"content_version": highlight.ContentVersion,
+			"orphaned":        highlight.Orphaned,
+		}, "minimal", "").
+		Eq("id", highlight.ID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to update highlight anchor: %w", err)
+	}
+	return nil
+}
+
+// Search matches the generated search column with websearch_to_tsquery. PostgREST
+// cannot order by rank, so results are newest first. A tag filter is resolved to its
+// documents first.
+func (r *HighlightRepository) Search(ctx context.Context, principal domain.Principal, search domain.HighlightSearch) ([]*domain.Highlight, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	q := client.From("highlights").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		TextSearch("search", search.Query, highlightSearchConfig, "websearch")
+	if search.DocumentID != "" {
+		q = q.Eq("document_id", search.DocumentID)
+	}
+	if search.Tag != "" {
+		documentIDs, err := r.taggedDocumentIDs(ctx, principal, search.Tag)
+		if err != nil {
+			return nil, fmt.Errorf("failed to search highlights: %w", err)
 		}
+		if len(documentIDs) == 0 {
+			return []*domain.Highlight{}, nil
+		}
+		q = q.In("document_id", documentIDs)
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), q.
+		Order("created_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(search.Limit, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search highlights: %w", err)
+	}
+
+	var rows []highlightRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	out := make([]*domain.Highlight, 0, len(rows))
+	for i := range rows {
+		out = append(out, rows[i].toDomain())
+	}
+	return out, nil
+}
+
+// taggedDocumentIDs returns the IDs of the user's documents with a tag.
+func (r *HighlightRepository) taggedDocumentIDs(ctx context.Context, principal domain.Principal, tag string) ([]string, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	tagData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
+		Select("id", "", false).
+		Eq("user_id", principal.UserID).
+		Eq("name", tag))
+	if err != nil {
+		return nil, err
+	}
+	var tags []tagRow
+	if err := json.Unmarshal(tagData, &tags); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(tags) == 0 {
+		return nil, nil
 	}
 
-	return h
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_tags").
+		Select("document_id", "", false).
+		Eq("tag_id", tags[0].ID))
+	if err != nil {
+		return nil, err
+	}
+	var rows []documentTagRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	ids := make([]string, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.DocumentID)
+	}
+	return ids, nil
 }
 
-var reControl = regexp.MustCompile(`[\x00]`)
+func (r *HighlightRepository) Get(ctx context.Context, principal domain.Principal, highlightID string) (*domain.Highlight, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Select("*", "", false).
+		Eq("id", highlightID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get highlight: %w", err)
+	}
+
+	var rows []highlightRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrHighlightNotFound
+	}
+	return rows[0].toDomain(), nil
+}
 
-// sanitizeText removes characters that PostgreSQL rejects in text fields (notably NUL bytes).
+func (r *HighlightRepository) UpdateReview(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("highlights").
+		Update(map[string]interface{}{
+			"is_favorite":          highlight.IsFavorite,
+			"review_count":         highlight.ReviewCount,
+			"review_interval_days": highlight.ReviewInterval,
+			"review_due_at":        highlight.ReviewDueAt,
+			"reviewed_at":          highlight.ReviewedAt,
+		}, "minimal", "").
+		Eq("id", highlight.ID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to update highlight review: %w", err)
+	}
+	return nil
+}
+
+var reControl = regexp.MustCompile(`[\x00-\x08\x0B\x0C\x0E-\x1F]`)
+
+// sanitizeText removes characters that PostgreSQL rejects in text fields (notably NUL bytes),
+// along with invalid UTF-8 and control characters other than tab and line breaks.
 func sanitizeText(s string) string {
 	if s == "" {
 		return s
 	}
-	// Remove any NUL bytes.
+	// Drop invalid UTF-8 sequences (PostgreSQL rejects them with 22021).
+	s = strings.ToValidUTF8(s, "")
+	// Remove NUL bytes and other control characters.
 	s = reControl.ReplaceAllString(s, "")
 	// Also remove escaped unicode NUL sequences that can appear in some extracted content.
 	s = strings.ReplaceAll(s, "\\u0000", "")
-	s = strings.ReplaceAll(s, "\u0000", "")
 	return s
 }
-
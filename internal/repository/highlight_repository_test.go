@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func FuzzSanitizeText(f *testing.F) {
+	seeds := []string{
+		"",
+		"a highlighted quote",
+		"null\x00byte",
+		"tab\tnewline\ncarriage\r",
+		"bell\x07escape\x1b",
+		"invalid \xff\xfe utf8",
+		`literal \u0000 escape`,
+		"emoji 📚 and accents áéíóú",
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		out := sanitizeText(input)
+		if !utf8.ValidString(out) {
+			t.Fatalf("output is not valid UTF-8: %q", out)
+		}
+		for _, r := range out {
+			if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+				t.Fatalf("output contains control character %U: %q", r, out)
+			}
+		}
+	})
+}
@@ -0,0 +1,957 @@
+//go:build integration
+
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+	"pdf-text-reader/internal/infra/supabase"
+	"pdf-text-reader/internal/service"
+	"pdf-text-reader/pkg/logger"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// The integration suite runs the real repositories against Postgres and PostgREST
+// containers with the migrated schema, so row mapping and RLS are exercised end to
+// end. Run it with `make test-integration` (needs a Docker daemon).
+
+const integrationJWTSecret = "integration-secret-at-least-32-characters"
+
+var integration struct {
+	pool     *pgxpool.Pool
+	supabase domain.SupabaseClient
+	auth     domain.LocalAuthService
+	logger   domain.Logger
+}
+
+// integrationConfig feeds the Supabase client the PostgREST proxy URL; the client
+// reads nothing else from its config.
+type integrationConfig struct {
+	domain.Config
+	url string
+}
+
+func (c integrationConfig) GetSupabaseURL() string { return c.url }
+func (c integrationConfig) GetSupabaseKey() string { return "integration-anon-key" }
+
+func TestMain(m *testing.M) {
+	os.Exit(runIntegration(m))
+}
+
+func runIntegration(m *testing.M) int {
+	if _, err := exec.LookPath("docker"); err != nil {
+		log.Printf("integration tests need the docker CLI: %v", err)
+		return 1
+	}
+
+	suffix := uuid.NewString()[:8]
+	network := "lector-it-" + suffix
+	if _, err := docker("network", "create", network); err != nil {
+		log.Printf("failed to create network: %v", err)
+		return 1
+	}
+	defer func() { _, _ = docker("network", "rm", network) }()
+
+	dbName := "lector-it-db-" + suffix
+	db, err := startContainer(dbName, network, "5432/tcp", "postgres:16-alpine",
+		"POSTGRES_PASSWORD=postgres", "POSTGRES_DB=lector")
+	if err != nil {
+		log.Printf("failed to start postgres: %v", err)
+		return 1
+	}
+	defer removeContainer(db.name)
+
+	databaseURL := fmt.Sprintf("postgres://postgres:postgres@%s/lector?sslmode=disable", db.hostPort)
+	if err := retry(func() error {
+		pool, err := postgres.NewPool(context.Background(), databaseURL)
+		if err != nil {
+			return err
+		}
+		integration.pool = pool
+		return nil
+	}); err != nil {
+		log.Printf("postgres did not become ready: %v", err)
+		return 1
+	}
+	defer integration.pool.Close()
+
+	if err := postgres.EnsureSelfHosted(context.Background(), integration.pool); err != nil {
+		log.Printf("failed to prepare database: %v", err)
+		return 1
+	}
+	if _, err := postgres.NewMigrator(integration.pool, "", logger.NewLogger("error")).Migrate(context.Background()); err != nil {
+		log.Printf("failed to apply migrations: %v", err)
+		return 1
+	}
+
+	rest, err := startContainer("lector-it-rest-"+suffix, network, "3000/tcp", "postgrest/postgrest:v12.2.3",
+		"PGRST_DB_URI=postgres://postgres:postgres@"+dbName+":5432/lector",
+		"PGRST_DB_SCHEMAS=public",
+		"PGRST_DB_ANON_ROLE=authenticated",
+		"PGRST_JWT_SECRET="+integrationJWTSecret,
+	)
+	if err != nil {
+		log.Printf("failed to start postgrest: %v", err)
+		return 1
+	}
+	defer removeContainer(rest.name)
+
+	restURL, _ := url.Parse("http://" + rest.hostPort)
+	if err := retry(func() error {
+		resp, err := http.Get(restURL.String() + "/documents?limit=0")
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("postgrest returned %d", resp.StatusCode)
+		}
+		return nil
+	}); err != nil {
+		log.Printf("postgrest did not become ready: %v", err)
+		return 1
+	}
+
+	// supabase-go talks to <url>/rest/v1; strip the prefix in front of plain PostgREST.
+	proxy := httputil.NewSingleHostReverseProxy(restURL)
+	gateway := httptest.NewServer(http.StripPrefix("/rest/v1", proxy))
+	defer gateway.Close()
+
+	integration.logger = logger.NewLogger("error")
+	integration.supabase = supabase.NewSupabaseClient(integrationConfig{url: gateway.URL}, integration.logger)
+	if err := integration.supabase.Initialize(); err != nil {
+		log.Printf("failed to initialize supabase client: %v", err)
+		return 1
+	}
+	integration.auth = service.NewLocalAuthService(
+		NewPgUserRepository(integration.pool, integration.logger),
+		NewPgUserPreferencesRepository(integration.pool, integration.logger),
+		integrationJWTSecret,
+		integration.logger,
+	)
+
+	return m.Run()
+}
+
+type container struct {
+	name     string
+	hostPort string
+}
+
+// startContainer runs image detached on network and publishes port on a random
+// loopback port.
+func startContainer(name, network, port, image string, env ...string) (*container, error) {
+	args := []string{"run", "-d", "--rm", "--name", name, "--network", network, "-p", "127.0.0.1::" + port}
+	for _, e := range env {
+		args = append(args, "-e", e)
+	}
+	if _, err := docker(append(args, image)...); err != nil {
+		return nil, err
+	}
+
+	out, err := docker("port", name, port)
+	if err != nil {
+		removeContainer(name)
+		return nil, err
+	}
+	// `docker port` prints one line per address family; the first is the IPv4 binding.
+	hostPort := strings.TrimSpace(strings.SplitN(out, "\n", 2)[0])
+	return &container{name: name, hostPort: hostPort}, nil
+}
+
+func removeContainer(name string) {
+	_, _ = docker("rm", "-f", name)
+}
+
+func docker(args ...string) (string, error) {
+	out, err := exec.Command("docker", args...).CombinedOutput()
+	if err != nil {
+		return "", fmt.Errorf("docker %s: %w: %s", args[0], err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
+
+// retry calls fn until it succeeds or two minutes have passed.
+func retry(fn func() error) error {
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		err := fn()
+		if err == nil || time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// integrationBackend builds one implementation of every repository under test.
+type integrationBackend struct {
+	name        string
+	documents   func() domain.DocumentRepository
+	preferences func() domain.UserPreferencesRepository
+	highlights  func() domain.HighlightRepository
+	devices     func() domain.DeviceRepository
+	clouds      func() domain.CloudConnectionRepository
+	searches    func() domain.SavedSearchRepository
+	onboarding  func() domain.OnboardingRepository
+}
+
+func integrationBackends() []integrationBackend {
+	return []integrationBackend{
+		{
+			name: "postgrest",
+			documents: func() domain.DocumentRepository {
+				return NewDocumentRepository(integration.supabase, integration.logger)
+			},
+			preferences: func() domain.UserPreferencesRepository {
+				return NewUserPreferencesRepository(integration.supabase, integration.logger)
+			},
+			highlights: func() domain.HighlightRepository {
+				return NewHighlightRepository(integration.supabase, integration.logger)
+			},
+			devices: func() domain.DeviceRepository {
+				return NewDeviceRepository(integration.supabase, integration.logger)
+			},
+			clouds: func() domain.CloudConnectionRepository {
+				return NewCloudConnectionRepository(integration.supabase, integration.logger)
+			},
+			searches: func() domain.SavedSearchRepository {
+				return NewSavedSearchRepository(integration.supabase, integration.logger)
+			},
+			onboarding: func() domain.OnboardingRepository {
+				return NewOnboardingRepository(integration.supabase, integration.logger)
+			},
+		},
+		{
+			name:      "pgx",
+			documents: func() domain.DocumentRepository { return NewPgDocumentRepository(integration.pool, integration.logger) },
+			preferences: func() domain.UserPreferencesRepository {
+				return NewPgUserPreferencesRepository(integration.pool, integration.logger)
+			},
+			highlights: func() domain.HighlightRepository {
+				return NewPgHighlightRepository(integration.pool, integration.logger)
+			},
+			devices: func() domain.DeviceRepository {
+				return NewPgDeviceRepository(integration.pool, integration.logger)
+			},
+			clouds: func() domain.CloudConnectionRepository {
+				return NewPgCloudConnectionRepository(integration.pool, integration.logger)
+			},
+			searches: func() domain.SavedSearchRepository {
+				return NewPgSavedSearchRepository(integration.pool, integration.logger)
+			},
+			onboarding: func() domain.OnboardingRepository {
+				return NewPgOnboardingRepository(integration.pool, integration.logger)
+			},
+		},
+	}
+}
+
+// newPrincipal registers a fresh account and returns it as an authenticated principal.
+func newPrincipal(t *testing.T) domain.Principal {
+	t.Helper()
+	email := "reader-" + uuid.NewString()[:8] + "@example.com"
+	session, err := integration.auth.Register(context.Background(), email, "integration-password", "Reader")
+	if err != nil {
+		t.Fatalf("failed to register %s: %v", email, err)
+	}
+	return domain.NewPrincipal(session.User, session.AccessToken)
+}
+
+func newDocument(principal domain.Principal, title string) *domain.Document {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	author := "Integration Author"
+	return &domain.Document{
+		ID:        uuid.NewString(),
+		UserID:    principal.UserID,
+		Title:     title,
+		Author:    &author,
+		Content:   []byte(`[{"page":1,"content":"hello"}]`),
+		Metadata:  domain.DocumentMetadata{Format: "pdf", PageCount: 1},
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+func TestIntegration_Documents(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.documents()
+			owner, stranger := newPrincipal(t), newPrincipal(t)
+
+			doc := newDocument(owner, "Integration "+backend.name)
+			if err := repo.Create(ctx, owner, doc); err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+
+			got, err := repo.GetByID(ctx, owner, doc.ID)
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			if got.Title != doc.Title || got.Author == nil || *got.Author != *doc.Author {
+				t.Fatalf("unexpected document: %+v", got)
+			}
+			if got.Metadata.Format != "pdf" || got.Metadata.PageCount != 1 {
+				t.Fatalf("metadata not round-tripped: %+v", got.Metadata)
+			}
+			if !strings.Contains(string(got.Content), "hello") {
+				t.Fatalf("content not round-tripped: %s", got.Content)
+			}
+
+			// RLS: other users must not see or delete the document.
+			if _, err := repo.GetByID(ctx, stranger, doc.ID); !errors.Is(err, domain.ErrDocumentNotFound) {
+				t.Fatalf("expected ErrDocumentNotFound for another user, got %v", err)
+			}
+			if docs, err := repo.GetByUserID(ctx, stranger); err != nil || len(docs) != 0 {
+				t.Fatalf("expected no documents for another user, got %d (%v)", len(docs), err)
+			}
+
+			if err := repo.SetFavorite(ctx, owner, doc.ID, true); err != nil {
+				t.Fatalf("set favorite failed: %v", err)
+			}
+			if err := repo.SetArchived(ctx, owner, doc.ID, true); err != nil {
+				t.Fatalf("set archived failed: %v", err)
+			}
+			if err := repo.SetLastOpened(ctx, owner, doc.ID, time.Now().UTC()); err != nil {
+				t.Fatalf("set last opened failed: %v", err)
+			}
+			if err := repo.CreateTag(ctx, owner, "classics"); err != nil {
+				t.Fatalf("create tag failed: %v", err)
+			}
+			tag := "classics"
+			doc.Tag = &tag
+			doc.Title = "Renamed " + backend.name
+			if err := repo.Update(ctx, owner, doc); err != nil {
+				t.Fatalf("update failed: %v", err)
+			}
+
+			docs, err := repo.GetByUserID(ctx, owner)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if len(docs) != 1 {
+				t.Fatalf("expected 1 document, got %d", len(docs))
+			}
+			if docs[0].Title != doc.Title || !docs[0].IsFavorite || !docs[0].IsArchived || docs[0].Tag == nil || *docs[0].Tag != tag || docs[0].LastOpenedAt == nil {
+				t.Fatalf("unexpected listed document: %+v", docs[0])
+			}
+
+			tags, err := repo.GetTagsByUserID(ctx, owner)
+			if err != nil || len(tags) != 1 || tags[0] != tag {
+				t.Fatalf("unexpected tags %v (%v)", tags, err)
+			}
+
+			for q, want := range map[string]int{
+				"hello":                          1,
+				`"integration author" renamed`:   1,
+				"author:integration -tag:poetry": 1,
+				"tag:classics title:/^renamed/":  1,
+				"title:hello OR tag:poetry":      0,
+				"(missing OR hello) -hello":      0,
+			} {
+				query, err := domain.ParseDocumentQuery(q)
+				if err != nil {
+					t.Fatalf("parse %q: %v", q, err)
+				}
+				if found, err := repo.Search(ctx, owner, query); err != nil || len(found) != want {
+					t.Fatalf("search %q: expected %d documents, got %d (%v)", q, want, len(found), err)
+				}
+				if found, err := repo.Search(ctx, stranger, query); err != nil || len(found) != 0 {
+					t.Fatalf("search %q: expected no documents for another user, got %d (%v)", q, len(found), err)
+				}
+			}
+
+			// Go accepts \pL but Postgres does not: a bad pattern is the caller's mistake.
+			query, err := domain.ParseDocumentQuery(`/\pL/`)
+			if err != nil {
+				t.Fatalf("parse: %v", err)
+			}
+			if _, err := repo.Search(ctx, owner, query); err != nil {
+				var validationErrs domain.ValidationErrors
+				if !errors.As(err, &validationErrs) {
+					t.Fatalf("expected a validation error for a pattern Postgres rejects, got %v", err)
+				}
+			}
+
+			// Depending on the backend a foreign delete errors or affects no rows.
+			_ = repo.Delete(ctx, stranger, doc.ID)
+			if _, err := repo.GetByID(ctx, owner, doc.ID); err != nil {
+				t.Fatalf("document deleted by another user: %v", err)
+			}
+			if err := repo.Delete(ctx, owner, doc.ID); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if _, err := repo.GetByID(ctx, owner, doc.ID); !errors.Is(err, domain.ErrDocumentNotFound) {
+				t.Fatalf("expected ErrDocumentNotFound after delete, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIntegration_DocumentPatch(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.documents()
+			owner := newPrincipal(t)
+
+			doc := newDocument(owner, "Patch "+backend.name)
+			if err := repo.Create(ctx, owner, doc); err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			if err := repo.CreateTag(ctx, owner, "poetry"); err != nil {
+				t.Fatalf("create tag failed: %v", err)
+			}
+
+			// Another device rewrites the content after this one read the document.
+			other, err := repo.GetByID(ctx, owner, doc.ID)
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			other.Content = []byte(`[{"page":1,"content":"reprocessed"}]`)
+			other.Metadata.PageCount = 2
+			if err := repo.Update(ctx, owner, other); err != nil {
+				t.Fatalf("update failed: %v", err)
+			}
+
+			tag := "poetry"
+			if err := repo.Patch(ctx, owner, doc.ID, domain.DocumentPatch{Tag: &tag, UpdatedAt: time.Now().UTC()}); err != nil {
+				t.Fatalf("patch failed: %v", err)
+			}
+			got, err := repo.GetByID(ctx, owner, doc.ID)
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			if got.Tag == nil || *got.Tag != tag || got.Title != doc.Title {
+				t.Fatalf("unexpected patched document: %+v", got)
+			}
+			if !strings.Contains(string(got.Content), "reprocessed") || got.Metadata.PageCount != 2 {
+				t.Fatalf("patch overwrote the other device's edit: %s %+v", got.Content, got.Metadata)
+			}
+
+			untag := ""
+			if err := repo.Patch(ctx, owner, doc.ID, domain.DocumentPatch{Tag: &untag, UpdatedAt: time.Now().UTC()}); err != nil {
+				t.Fatalf("untag failed: %v", err)
+			}
+			if got, _ := repo.GetByID(ctx, owner, doc.ID); got.Tag != nil {
+				t.Fatalf("expected the tag to be removed, got %q", *got.Tag)
+			}
+
+			stale := domain.ContextWithUnmodifiedSince(ctx, doc.UpdatedAt.Add(-time.Hour))
+			title := "Stale"
+			if err := repo.Patch(stale, owner, doc.ID, domain.DocumentPatch{Title: &title, UpdatedAt: time.Now().UTC()}); !errors.Is(err, domain.ErrStaleUpdate) {
+				t.Fatalf("expected ErrStaleUpdate, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Preferences(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.preferences()
+			owner := newPrincipal(t)
+
+			prefs, err := repo.GetPreferences(ctx, owner)
+			if err != nil {
+				t.Fatalf("get defaults failed: %v", err)
+			}
+			if prefs.FontSize != domain.DefaultUserPreferences(owner.UserID).FontSize {
+				t.Fatalf("expected default preferences, got %+v", prefs)
+			}
+
+			prefs.FontSize = 20
+			prefs.Theme = "sepia"
+			prefs.Tags = []string{"to-read"}
+			if err := repo.UpdatePreferences(ctx, owner, prefs); err != nil {
+				t.Fatalf("update failed: %v", err)
+			}
+
+			stored, err := repo.GetPreferences(ctx, owner)
+			if err != nil {
+				t.Fatalf("get failed: %v", err)
+			}
+			if stored.FontSize != 20 || stored.Theme != "sepia" || len(stored.Tags) != 1 || stored.Tags[0] != "to-read" {
+				t.Fatalf("preferences not round-tripped: %+v", stored)
+			}
+
+			// A second write invalidates the timestamp the client read.
+			stored.FontSize = 22
+			if err := repo.UpdatePreferences(ctx, owner, stored); err != nil {
+				t.Fatalf("second update failed: %v", err)
+			}
+			stale := domain.ContextWithUnmodifiedSince(ctx, stored.UpdatedAt)
+			if err := repo.UpdatePreferences(stale, owner, stored); !errors.Is(err, domain.ErrStaleUpdate) {
+				t.Fatalf("expected ErrStaleUpdate, got %v", err)
+			}
+
+			doc := newDocument(owner, "Positions "+backend.name)
+			if err := backend.documents().Create(ctx, owner, doc); err != nil {
+				t.Fatalf("create document failed: %v", err)
+			}
+			charOffset := 4096
+			position := &domain.ReadingPosition{
+				UserID:     owner.UserID,
+				DocumentID: doc.ID,
+				Progress:   0.5,
+				PageNumber: 12,
+				CharOffset: &charOffset,
+				Locator:    &domain.EPUBLocator{Spine: "OEBPS/ch2.xhtml", Path: "/4/6", Offset: 12},
+				UpdatedAt:  time.Now(),
+			}
+			if err := repo.UpdateReadingPosition(ctx, owner, position); err != nil {
+				t.Fatalf("update position failed: %v", err)
+			}
+			got, err := repo.GetReadingPosition(ctx, owner, doc.ID)
+			if err != nil {
+				t.Fatalf("get position failed: %v", err)
+			}
+			if got.PageNumber != 12 || got.Progress != 0.5 || got.CharOffset == nil || *got.CharOffset != charOffset ||
+				got.Locator == nil || *got.Locator != *position.Locator {
+				t.Fatalf("position not round-tripped: %+v", got)
+			}
+			batch, err := repo.GetReadingPositions(ctx, owner, []string{doc.ID, uuid.NewString()})
+			if err != nil || len(batch) != 1 || batch[doc.ID] == nil || batch[doc.ID].PageNumber != 12 {
+				t.Fatalf("unexpected batched positions %+v (%v)", batch, err)
+			}
+
+			if latest, err := repo.LatestPositionSample(ctx, owner, doc.ID); err != nil || latest != nil {
+				t.Fatalf("expected no position history yet, got %+v (%v)", latest, err)
+			}
+			for _, page := range []int{12, 30} {
+				sample := &domain.PositionSample{DocumentID: doc.ID, Progress: float32(page) / 100, PageNumber: page, CharOffset: &charOffset, RecordedAt: time.Now()}
+				if err := repo.AddPositionSample(ctx, owner, sample); err != nil {
+					t.Fatalf("add position sample failed: %v", err)
+				}
+			}
+			if latest, err := repo.LatestPositionSample(ctx, owner, doc.ID); err != nil || latest == nil || latest.PageNumber != 30 {
+				t.Fatalf("unexpected latest sample %+v (%v)", latest, err)
+			}
+			samples, err := repo.GetPositionHistory(ctx, owner, doc.ID, time.Now().Add(-time.Hour))
+			if err != nil || len(samples) != 2 || samples[0].PageNumber != 12 || samples[0].CharOffset == nil {
+				t.Fatalf("unexpected position history %+v (%v)", samples, err)
+			}
+
+			if err := repo.SetAccountDisabled(ctx, owner, true); err != nil {
+				t.Fatalf("disable account failed: %v", err)
+			}
+			if stored, err := repo.GetPreferences(ctx, owner); err != nil || !stored.AccountDisabled {
+				t.Fatalf("expected disabled account, got %+v (%v)", stored, err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Entitlements(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.preferences()
+			owner := newPrincipal(t)
+
+			prefs := domain.DefaultUserPreferences(owner.UserID)
+			prefs.SubscriptionPlan = "founder_lifetime"
+			prefs.StorageLimitBytes = 1 << 50
+			if err := repo.UpdatePreferences(ctx, owner, prefs); err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			stored, err := repo.GetPreferences(ctx, owner)
+			if err != nil || stored.SubscriptionPlan != "free" || stored.StorageLimitBytes != domain.StorageLimitBytesForPlan("free") {
+				t.Fatalf("expected a new row to start on the free plan, got %+v (%v)", stored, err)
+			}
+
+			stored.SubscriptionPlan = "pro_yearly"
+			stored.StorageLimitBytes = 1 << 50
+			stored.FontSize = 18
+			if err := repo.UpdatePreferences(ctx, owner, stored); err != nil {
+				t.Fatalf("update failed: %v", err)
+			}
+			stored, err = repo.GetPreferences(ctx, owner)
+			if err != nil || stored.SubscriptionPlan != "free" || stored.StorageLimitBytes != domain.StorageLimitBytesForPlan("free") || stored.FontSize != 18 {
+				t.Fatalf("expected only the font size to change, got %+v (%v)", stored, err)
+			}
+
+			if err := repo.SetAccountDisabled(ctx, owner, true); err != nil {
+				t.Fatalf("disable account failed: %v", err)
+			}
+			if err := repo.SetAccountDisabled(ctx, owner, false); err != nil {
+				t.Fatalf("enable account failed: %v", err)
+			}
+			if stored, err := repo.GetPreferences(ctx, owner); err != nil || !stored.AccountDisabled {
+				t.Fatalf("expected the account to stay disabled, got %+v (%v)", stored, err)
+			}
+
+			// The server's own connection still manages the entitlements.
+			if _, err := integration.pool.Exec(ctx,
+				`UPDATE user_preferences SET subscription_plan = 'pro_yearly', account_disabled = false WHERE user_id = $1`,
+				owner.UserID,
+			); err != nil {
+				t.Fatalf("privileged update failed: %v", err)
+			}
+			if stored, err := repo.GetPreferences(ctx, owner); err != nil || stored.SubscriptionPlan != "pro_yearly" || stored.AccountDisabled {
+				t.Fatalf("expected the privileged update to apply, got %+v (%v)", stored, err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Highlights(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.highlights()
+			owner, stranger := newPrincipal(t), newPrincipal(t)
+
+			doc := newDocument(owner, "Highlights "+backend.name)
+			if err := backend.documents().Create(ctx, owner, doc); err != nil {
+				t.Fatalf("create document failed: %v", err)
+			}
+
+			page := 3
+			created, err := repo.Create(ctx, owner, &domain.Highlight{DocumentID: doc.ID, Quote: "a quote", Note: "on entropy", PageNumber: &page,
+				ContextBefore: "before ", ContextAfter: " after", ContentVersion: 2})
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			if created.ID == "" || created.PageNumber == nil || *created.PageNumber != page ||
+				created.ContextBefore != "before " || created.ContextAfter != " after" || created.ContentVersion != 2 {
+				t.Fatalf("unexpected highlight: %+v", created)
+			}
+
+			listed, err := repo.ListByUser(ctx, owner, &doc.ID)
+			if err != nil || len(listed) != 1 || listed[0].Quote != "a quote" {
+				t.Fatalf("unexpected highlights %+v (%v)", listed, err)
+			}
+			located := *listed[0]
+			located.Locator = &domain.EPUBLocator{Spine: "OEBPS/ch1.xhtml", Path: "/4/2", Offset: 7}
+			located.ContextBefore, located.ContextAfter = "moved ", ""
+			located.ContentVersion = 3
+			located.Orphaned = true
+			if err := repo.UpdateAnchor(ctx, owner, &located); err != nil {
+				t.Fatalf("update anchor failed: %v", err)
+			}
+			if listed, err := repo.ListByUser(ctx, owner, &doc.ID); err != nil || len(listed) != 1 || listed[0].Locator == nil || *listed[0].Locator != *located.Locator ||
+				listed[0].ContextBefore != "moved " || listed[0].ContextAfter != "" || listed[0].ContentVersion != 3 || !listed[0].Orphaned {
+				t.Fatalf("anchor not round-tripped %+v (%v)", listed, err)
+			}
+			reviewed := located
+			due := time.Now().UTC().Truncate(24*time.Hour).AddDate(0, 0, 3)
+			reviewedAt := time.Now().UTC().Truncate(time.Second)
+			reviewed.IsFavorite, reviewed.ReviewCount, reviewed.ReviewInterval = true, 1, 3
+			reviewed.ReviewDueAt, reviewed.ReviewedAt = &due, &reviewedAt
+			if err := repo.UpdateReview(ctx, owner, &reviewed); err != nil {
+				t.Fatalf("update review failed: %v", err)
+			}
+			got, err := repo.Get(ctx, owner, created.ID)
+			if err != nil || !got.IsFavorite || got.ReviewCount != 1 || got.ReviewInterval != 3 ||
+				got.ReviewDueAt == nil || !got.ReviewDueAt.Equal(due) || got.ReviewedAt == nil || !got.ReviewedAt.Equal(reviewedAt) {
+				t.Fatalf("review not round-tripped %+v (%v)", got, err)
+			}
+			if _, err := repo.Get(ctx, stranger, created.ID); !errors.Is(err, domain.ErrHighlightNotFound) {
+				t.Fatalf("expected ErrHighlightNotFound for another user, got %v", err)
+			}
+			if listed, err := repo.ListByUser(ctx, stranger, nil); err != nil || len(listed) != 0 {
+				t.Fatalf("expected no highlights for another user, got %d (%v)", len(listed), err)
+			}
+			if counts, err := repo.CountByDocuments(ctx, owner, []string{doc.ID}); err != nil || counts[doc.ID] != 1 {
+				t.Fatalf("unexpected highlight counts %v (%v)", counts, err)
+			}
+			for _, tt := range []struct {
+				search domain.HighlightSearch
+				want   int
+			}{
+				{domain.HighlightSearch{Query: "entropy", Limit: 10}, 1},
+				{domain.HighlightSearch{Query: "quote -entropy", Limit: 10}, 0},
+				{domain.HighlightSearch{Query: "quote", DocumentID: doc.ID, Limit: 10}, 1},
+				{domain.HighlightSearch{Query: "quote", Tag: "no such tag", Limit: 10}, 0},
+			} {
+				if found, err := repo.Search(ctx, owner, tt.search); err != nil || len(found) != tt.want {
+					t.Fatalf("search %+v: expected %d highlights, got %+v (%v)", tt.search, tt.want, found, err)
+				}
+			}
+			if found, err := repo.Search(ctx, stranger, domain.HighlightSearch{Query: "entropy", Limit: 10}); err != nil || len(found) != 0 {
+				t.Fatalf("expected no highlights found for another user, got %d (%v)", len(found), err)
+			}
+
+			if err := repo.Delete(ctx, owner, created.ID); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if listed, err := repo.ListByUser(ctx, owner, nil); err != nil || len(listed) != 0 {
+				t.Fatalf("expected highlights to be deleted, got %d (%v)", len(listed), err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Devices(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.devices()
+			owner, stranger := newPrincipal(t), newPrincipal(t)
+
+			device := &domain.Device{UserID: owner.UserID, Token: "token-" + backend.name, Platform: domain.DevicePlatformAndroid}
+			if err := repo.Upsert(ctx, owner, device); err != nil {
+				t.Fatalf("upsert failed: %v", err)
+			}
+			// Registering the same token again refreshes it instead of failing.
+			device.Platform = domain.DevicePlatformWeb
+			if err := repo.Upsert(ctx, owner, device); err != nil {
+				t.Fatalf("second upsert failed: %v", err)
+			}
+
+			listed, err := repo.ListByUser(ctx, owner)
+			if err != nil || len(listed) != 1 || listed[0].Platform != domain.DevicePlatformWeb {
+				t.Fatalf("unexpected devices %+v (%v)", listed, err)
+			}
+			if listed, err := repo.ListByUser(ctx, stranger); err != nil || len(listed) != 0 {
+				t.Fatalf("expected no devices for another user, got %d (%v)", len(listed), err)
+			}
+
+			if err := repo.Delete(ctx, owner, device.Token); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if listed, err := repo.ListByUser(ctx, owner); err != nil || len(listed) != 0 {
+				t.Fatalf("expected devices to be deleted, got %d (%v)", len(listed), err)
+			}
+		})
+	}
+}
+
+func TestIntegration_CloudConnections(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.clouds()
+			owner, stranger := newPrincipal(t), newPrincipal(t)
+
+			if _, err := repo.Get(ctx, owner, domain.CloudProviderDropbox); !errors.Is(err, domain.ErrCloudNotConnected) {
+				t.Fatalf("expected not connected, got %v", err)
+			}
+
+			connection := &domain.CloudConnection{Provider: domain.CloudProviderDropbox, Token: domain.CloudToken{AccessToken: "a1", RefreshToken: "r1"}}
+			if err := repo.Upsert(ctx, owner, connection); err != nil {
+				t.Fatalf("upsert failed: %v", err)
+			}
+			expiry := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+			connection.Token = domain.CloudToken{AccessToken: "a2", RefreshToken: "r1", Expiry: expiry}
+			if err := repo.Upsert(ctx, owner, connection); err != nil {
+				t.Fatalf("second upsert failed: %v", err)
+			}
+
+			got, err := repo.Get(ctx, owner, domain.CloudProviderDropbox)
+			if err != nil || got.Token.AccessToken != "a2" || !got.Token.Expiry.Equal(expiry) {
+				t.Fatalf("unexpected connection %+v (%v)", got, err)
+			}
+			if _, err := repo.Get(ctx, stranger, domain.CloudProviderDropbox); !errors.Is(err, domain.ErrCloudNotConnected) {
+				t.Fatalf("expected another user not to see the connection, got %v", err)
+			}
+
+			if err := repo.Delete(ctx, owner, domain.CloudProviderDropbox); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if _, err := repo.Get(ctx, owner, domain.CloudProviderDropbox); !errors.Is(err, domain.ErrCloudNotConnected) {
+				t.Fatalf("expected the connection to be deleted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIntegration_SavedSearches(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.searches()
+			owner, stranger := newPrincipal(t), newPrincipal(t)
+
+			since := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+			created, err := repo.Create(ctx, owner, &domain.SavedSearch{
+				Name:    "Unread Tolkien",
+				Query:   `author:tolkien -"the hobbit"`,
+				Filters: domain.SavedSearchFilters{Archived: "all", Sort: "title", OpenedSince: &since},
+			})
+			if err != nil {
+				t.Fatalf("create failed: %v", err)
+			}
+			got, err := repo.Get(ctx, owner, created.ID)
+			if err != nil || got.Query != `author:tolkien -"the hobbit"` || got.Filters.Sort != "title" ||
+				got.Filters.OpenedSince == nil || !got.Filters.OpenedSince.Equal(since) {
+				t.Fatalf("unexpected saved search %+v (%v)", got, err)
+			}
+			if _, err := repo.Create(ctx, owner, &domain.SavedSearch{Name: "Unread Tolkien"}); err == nil {
+				t.Fatalf("expected a duplicate name to be refused")
+			}
+			if _, err := repo.Get(ctx, stranger, created.ID); !errors.Is(err, domain.ErrSavedSearchNotFound) {
+				t.Fatalf("expected another user not to see the search, got %v", err)
+			}
+
+			created.Name, created.Query, created.Filters = "Tolkien", "author:tolkien", domain.SavedSearchFilters{}
+			updated, err := repo.Update(ctx, owner, created)
+			if err != nil || updated.Name != "Tolkien" || updated.Filters.OpenedSince != nil {
+				t.Fatalf("unexpected update %+v (%v)", updated, err)
+			}
+			if listed, err := repo.ListByUser(ctx, owner); err != nil || len(listed) != 1 {
+				t.Fatalf("expected one saved search, got %d (%v)", len(listed), err)
+			}
+
+			if err := repo.Delete(ctx, owner, created.ID); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if _, err := repo.Get(ctx, owner, created.ID); !errors.Is(err, domain.ErrSavedSearchNotFound) {
+				t.Fatalf("expected the search to be deleted, got %v", err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Onboarding(t *testing.T) {
+	for _, backend := range integrationBackends() {
+		t.Run(backend.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := backend.onboarding()
+			owner, other := newPrincipal(t), newPrincipal(t)
+
+			// Signing up queued both accounts.
+			if claimed, err := repo.ClaimOnboarding(ctx, owner); err != nil || !claimed {
+				t.Fatalf("expected the first claim to onboard, got %v (%v)", claimed, err)
+			}
+			if claimed, err := repo.ClaimOnboarding(ctx, owner); err != nil || claimed {
+				t.Fatalf("expected a second claim to report already onboarded, got %v (%v)", claimed, err)
+			}
+			if claimed, err := repo.ClaimOnboarding(ctx, other); err != nil || !claimed {
+				t.Fatalf("expected another user to be onboarded separately, got %v (%v)", claimed, err)
+			}
+
+			// An account that was never queued, like one that existed before, is not onboarded.
+			existing := newPrincipal(t)
+			if _, err := integration.pool.Exec(ctx, `DELETE FROM user_onboarding WHERE user_id = $1`, existing.UserID); err != nil {
+				t.Fatalf("delete failed: %v", err)
+			}
+			if claimed, err := repo.ClaimOnboarding(ctx, existing); err != nil || claimed {
+				t.Fatalf("expected an account without a queued onboarding to be skipped, got %v (%v)", claimed, err)
+			}
+		})
+	}
+}
+
+func TestIntegration_Digest(t *testing.T) {
+	ctx := context.Background()
+	prefsRepo := NewPgUserPreferencesRepository(integration.pool, integration.logger)
+	repo := NewPgDigestRepository(integration.pool, integration.logger)
+	reader, optedOut := newPrincipal(t), newPrincipal(t)
+
+	prefs := domain.DefaultUserPreferences(reader.UserID)
+	prefs.WeeklyDigest = true
+	if err := prefsRepo.UpdatePreferences(ctx, reader, prefs); err != nil {
+		t.Fatalf("opt in failed: %v", err)
+	}
+	if err := prefsRepo.UpdatePreferences(ctx, optedOut, domain.DefaultUserPreferences(optedOut.UserID)); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+
+	doc := newDocument(reader, "Digest")
+	if err := NewPgDocumentRepository(integration.pool, integration.logger).Create(ctx, reader, doc); err != nil {
+		t.Fatalf("create document failed: %v", err)
+	}
+	position := &domain.ReadingPosition{UserID: reader.UserID, DocumentID: doc.ID, Progress: 0.4, PageNumber: 7}
+	if err := prefsRepo.UpdateReadingPosition(ctx, reader, position); err != nil {
+		t.Fatalf("update position failed: %v", err)
+	}
+
+	since := time.Now().Add(-domain.DigestPeriod)
+	isRecipient := func() bool {
+		recipients, err := repo.ListDigestRecipients(ctx, since)
+		if err != nil {
+			t.Fatalf("list recipients failed: %v", err)
+		}
+		found := false
+		for _, recipient := range recipients {
+			if recipient.UserID == optedOut.UserID {
+				t.Fatalf("listed a user who did not opt in")
+			}
+			found = found || (recipient.UserID == reader.UserID && recipient.Email == reader.Email)
+		}
+		return found
+	}
+	if !isRecipient() {
+		t.Fatalf("expected the opted-in user to be due")
+	}
+
+	digest, err := repo.GetReadingDigest(ctx, reader.UserID, since)
+	if err != nil {
+		t.Fatalf("get digest failed: %v", err)
+	}
+	if len(digest.Books) != 1 || digest.Books[0].PageNumber != 7 || digest.Current == nil || digest.Current.DocumentID != doc.ID {
+		t.Fatalf("unexpected digest: %+v", digest)
+	}
+
+	if err := repo.MarkDigestSent(ctx, reader.UserID, time.Now()); err != nil {
+		t.Fatalf("mark sent failed: %v", err)
+	}
+	if isRecipient() {
+		t.Fatalf("expected no second digest within the period")
+	}
+}
+
+func TestIntegration_ProcessingRecovery(t *testing.T) {
+	ctx := context.Background()
+	docs := NewPgDocumentRepository(integration.pool, integration.logger)
+	repo := NewPgProcessingRecoveryRepository(integration.pool, integration.logger)
+	owner := newPrincipal(t)
+
+	create := func(title string, metadata domain.DocumentMetadata) *domain.Document {
+		doc := newDocument(owner, title)
+		doc.Content = []byte(`[]`)
+		doc.Metadata = metadata
+		doc.CreatedAt = doc.CreatedAt.Add(-time.Hour)
+		if err := docs.Create(ctx, owner, doc); err != nil {
+			t.Fatalf("create document failed: %v", err)
+		}
+		return doc
+	}
+	lost := create("Lost", domain.DocumentMetadata{Format: "pdf"})
+	create("Given up", domain.DocumentMetadata{Format: "pdf", ProcessingAttempts: domain.MaxProcessingAttempts})
+	create("Held", domain.DocumentMetadata{Format: "pdf", Quarantine: &domain.Quarantine{Reason: "malware"}})
+	create("Blank", domain.DocumentMetadata{Format: "pdf", PageCount: 3})
+
+	unprocessed, err := repo.ListUnprocessed(ctx, time.Now(), 1000)
+	if err != nil {
+		t.Fatalf("list unprocessed failed: %v", err)
+	}
+	var found []string
+	for _, doc := range unprocessed {
+		if doc.UserID == owner.UserID {
+			found = append(found, doc.DocumentID)
+		}
+	}
+	if len(found) != 1 || found[0] != lost.ID {
+		t.Fatalf("expected only %s, got %v", lost.ID, found)
+	}
+
+	recent, err := repo.ListUnprocessed(ctx, time.Now().Add(-2*time.Hour), 1000)
+	if err != nil {
+		t.Fatalf("list unprocessed failed: %v", err)
+	}
+	for _, doc := range recent {
+		if doc.DocumentID == lost.ID {
+			t.Fatalf("listed a document created within the grace period")
+		}
+	}
+}
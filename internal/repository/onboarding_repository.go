@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// OnboardingRepository implements the domain.OnboardingRepository interface using
+// Supabase, over the user_onboarding table.
+type OnboardingRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewOnboardingRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.OnboardingRepository {
+	return &OnboardingRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+// ClaimOnboarding sets onboarded_at on the account's queued row. Only one of
+// concurrent updates matches the NULL filter, and only it gets the row back.
+func (r *OnboardingRepository) ClaimOnboarding(ctx context.Context, principal domain.Principal) (bool, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return false, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return false, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_onboarding").
+		Update(map[string]interface{}{"onboarded_at": time.Now().UTC()}, "representation", "").
+		Eq("user_id", principal.UserID).
+		Is("onboarded_at", "null"))
+	if err != nil {
+		return false, fmt.Errorf("failed to claim onboarding: %w", err)
+	}
+
+	var rows []struct {
+		UserID string `json:"user_id"`
+	}
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return false, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return len(rows) == 1, nil
+}
@@ -0,0 +1,125 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const cloudConnectionColumns = "user_id, provider, access_token, refresh_token, expires_at, created_at, updated_at"
+
+// PgCloudConnectionRepository implements the domain.CloudConnectionRepository
+// interface over a pgx pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgCloudConnectionRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgCloudConnectionRepository(pool *pgxpool.Pool, logger domain.Logger) domain.CloudConnectionRepository {
+	return &PgCloudConnectionRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgCloudConnectionRepository) Get(ctx context.Context, principal domain.Principal, provider string) (*domain.CloudConnection, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var connection *domain.CloudConnection
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+cloudConnectionColumns+`
+			FROM cloud_connections
+			WHERE user_id = $1 AND provider = $2`,
+			principal.UserID, provider,
+		)
+		if err != nil {
+			return err
+		}
+		connection, err = pgx.CollectExactlyOneRow(rows, scanCloudConnection)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrCloudNotConnected
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get cloud connection: %w", err)
+	}
+	return connection, nil
+}
+
+func (r *PgCloudConnectionRepository) Upsert(ctx context.Context, principal domain.Principal, connection *domain.CloudConnection) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var expiresAt *time.Time
+	if !connection.Token.Expiry.IsZero() {
+		expiresAt = &connection.Token.Expiry
+	}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO cloud_connections (user_id, provider, access_token, refresh_token, expires_at)
+			VALUES ($1, $2, $3, $4, $5)
+			ON CONFLICT (user_id, provider) DO UPDATE
+			SET access_token = $3, refresh_token = $4, expires_at = $5, updated_at = now()
+			RETURNING `+cloudConnectionColumns,
+			principal.UserID, connection.Provider, connection.Token.AccessToken, connection.Token.RefreshToken, expiresAt,
+		)
+		if err != nil {
+			return err
+		}
+		stored, err := pgx.CollectExactlyOneRow(rows, scanCloudConnection)
+		if err != nil {
+			return err
+		}
+		*connection = *stored
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save cloud connection: %w", err)
+	}
+	return nil
+}
+
+func (r *PgCloudConnectionRepository) Delete(ctx context.Context, principal domain.Principal, provider string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM cloud_connections WHERE user_id = $1 AND provider = $2`, principal.UserID, provider)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete cloud connection: %w", err)
+	}
+	return nil
+}
+
+func scanCloudConnection(row pgx.CollectableRow) (*domain.CloudConnection, error) {
+	var connection cloudConnectionRow
+	var expiresAt *time.Time
+	err := row.Scan(
+		&connection.UserID,
+		&connection.Provider,
+		&connection.AccessToken,
+		&connection.RefreshToken,
+		&expiresAt,
+		&connection.CreatedAt.Time,
+		&connection.UpdatedAt.Time,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if expiresAt != nil {
+		connection.ExpiresAt.Time = *expiresAt
+	}
+	return connection.toDomain(), nil
+}
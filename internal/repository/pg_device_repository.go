@@ -0,0 +1,102 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const deviceColumns = "user_id, token, platform, created_at, updated_at"
+
+// PgDeviceRepository implements the domain.DeviceRepository interface over a pgx
+// pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgDeviceRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgDeviceRepository(pool *pgxpool.Pool, logger domain.Logger) domain.DeviceRepository {
+	return &PgDeviceRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgDeviceRepository) Upsert(ctx context.Context, principal domain.Principal, device *domain.Device) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO devices (user_id, token, platform) VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, token) DO UPDATE SET platform = $3, updated_at = now()
+			RETURNING `+deviceColumns,
+			principal.UserID, device.Token, device.Platform,
+		)
+		if err != nil {
+			return err
+		}
+		stored, err := pgx.CollectExactlyOneRow(rows, scanDevice)
+		if err != nil {
+			return err
+		}
+		*device = *stored
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to register device: %w", err)
+	}
+	return nil
+}
+
+func (r *PgDeviceRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Device, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var devices []*domain.Device
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+deviceColumns+`
+			FROM devices
+			WHERE user_id = $1
+			ORDER BY updated_at DESC`,
+			principal.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		devices, err = pgx.CollectRows(rows, scanDevice)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	return devices, nil
+}
+
+func (r *PgDeviceRepository) Delete(ctx context.Context, principal domain.Principal, token string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM devices WHERE user_id = $1 AND token = $2`, principal.UserID, token)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+	return nil
+}
+
+func scanDevice(row pgx.CollectableRow) (*domain.Device, error) {
+	var device deviceRow
+	if err := row.Scan(&device.UserID, &device.Token, &device.Platform, &device.CreatedAt.Time, &device.UpdatedAt.Time); err != nil {
+		return nil, err
+	}
+	return device.toDomain(), nil
+}
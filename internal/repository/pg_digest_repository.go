@@ -0,0 +1,135 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgDigestRepository implements the domain.DigestRepository interface. The digest job
+// reads every opted-in user's activity, so like PgUserRepository it queries with the
+// pool's own role instead of impersonating a user.
+type PgDigestRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgDigestRepository(pool *pgxpool.Pool, logger domain.Logger) domain.DigestRepository {
+	return &PgDigestRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// ListDigestRecipients returns opted-in users with an active account whose last
+// digest was sent before sentBefore, or who never got one.
+func (r *PgDigestRepository) ListDigestRecipients(ctx context.Context, sentBefore time.Time) ([]domain.DigestRecipient, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT p.user_id::text, u.email
+		FROM user_preferences p
+		JOIN auth.users u ON u.id = p.user_id
+		LEFT JOIN digest_deliveries d ON d.user_id = p.user_id
+		WHERE p.weekly_digest AND NOT p.account_disabled AND u.email <> ''
+		  AND (d.last_sent_at IS NULL OR d.last_sent_at < $1)
+		ORDER BY p.user_id`,
+		sentBefore,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest recipients: %w", err)
+	}
+	recipients, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.DigestRecipient, error) {
+		var recipient domain.DigestRecipient
+		err := row.Scan(&recipient.UserID, &recipient.Email)
+		return recipient, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list digest recipients: %w", err)
+	}
+
+	return recipients, nil
+}
+
+// GetReadingDigest returns the books the user read since the given time, the number
+// of highlights made and the unfinished book read most recently.
+func (r *PgDigestRepository) GetReadingDigest(ctx context.Context, userID string, since time.Time) (*domain.ReadingDigest, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	digest := &domain.ReadingDigest{Since: since, Until: time.Now()}
+	scanBook := func(row pgx.CollectableRow) (domain.DigestBook, error) {
+		var book domain.DigestBook
+		err := row.Scan(&book.DocumentID, &book.Title, &book.Progress, &book.PageNumber, &book.ReadAt)
+		return book, err
+	}
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT d.id::text, d.title, rp.progress, rp.page_number, rp.updated_at
+		FROM reading_positions rp
+		JOIN documents d ON d.id = rp.document_id
+		WHERE rp.user_id = $1 AND rp.updated_at >= $2
+		ORDER BY rp.updated_at DESC`,
+		userID, since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading activity: %w", err)
+	}
+	if digest.Books, err = pgx.CollectRows(rows, scanBook); err != nil {
+		return nil, fmt.Errorf("failed to get reading activity: %w", err)
+	}
+
+	err = r.pool.QueryRow(ctx, `
+		SELECT count(*) FROM highlights WHERE user_id = $1 AND created_at >= $2`,
+		userID, since,
+	).Scan(&digest.Highlights)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count highlights: %w", err)
+	}
+
+	rows, err = r.pool.Query(ctx, `
+		SELECT d.id::text, d.title, rp.progress, rp.page_number, rp.updated_at
+		FROM reading_positions rp
+		JOIN documents d ON d.id = rp.document_id
+		WHERE rp.user_id = $1 AND rp.progress > 0 AND rp.progress < $2
+		ORDER BY rp.updated_at DESC
+		LIMIT 1`,
+		userID, domain.FinishedProgress,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get current book: %w", err)
+	}
+	current, err := pgx.CollectExactlyOneRow(rows, scanBook)
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+	case err != nil:
+		return nil, fmt.Errorf("failed to get current book: %w", err)
+	default:
+		digest.Current = &current
+	}
+
+	return digest, nil
+}
+
+// MarkDigestSent records when the user's digest was sent.
+func (r *PgDigestRepository) MarkDigestSent(ctx context.Context, userID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	_, err := r.pool.Exec(ctx, `
+		INSERT INTO digest_deliveries (user_id, last_sent_at) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET last_sent_at = $2`,
+		userID, at,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record digest delivery: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,529 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// documentColumns are the documents columns read by scanDocument, in order.
+const documentColumns = "id, user_id, title, author, description, content, metadata, archived, created_at, updated_at, last_opened_at"
+
+// PgDocumentRepository talks to Postgres directly through a pgx pool instead of
+// PostgREST. Content inserts, updates and searches stay in the database, and it is
+// the document repository of self-hosted mode.
+//
+// RLS still applies: every statement runs inside postgres.WithUserTx, which impersonates
+// the token's user the same way PostgREST does.
+type PgDocumentRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+// NewPgDocumentRepository creates a document repository backed by a pgx pool.
+func NewPgDocumentRepository(pool *pgxpool.Pool, logger domain.Logger) domain.DocumentRepository {
+	return &PgDocumentRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// Create inserts a document (including its full content) in a single round trip.
+func (r *PgDocumentRepository) Create(ctx context.Context, principal domain.Principal, document *domain.Document) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	metadataJSON, err := json.Marshal(document.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	err = postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO documents (id, user_id, title, author, description, content, metadata, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6::jsonb, $7::jsonb, $8, $9)`,
+			document.ID,
+			document.UserID,
+			document.Title,
+			document.Author,
+			document.Description,
+			cleanJSONB(document.Content, "[]"),
+			cleanJSONB(metadataJSON, "{}"),
+			document.CreatedAt,
+			document.UpdatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		r.logger.Error("Failed to insert document in Postgres", err, "doc_id", document.ID)
+		return fmt.Errorf("failed to create document: %w", err)
+	}
+
+	r.logger.Info("Document created", "id", document.ID, "user_id", document.UserID)
+	return nil
+}
+
+// Update writes the document fields and its tag relationship in one transaction.
+func (r *PgDocumentRepository) Update(ctx context.Context, principal domain.Principal, document *domain.Document) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	metadataJSON, err := json.Marshal(document.Metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	var unmodifiedSince *time.Time
+	if t, ok := domain.UnmodifiedSinceFromContext(ctx); ok {
+		unmodifiedSince = &t
+	}
+
+	err = postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE documents
+			SET title = $2, author = $3, description = $4, content = $5::jsonb, metadata = $6::jsonb, updated_at = $7
+			WHERE id = $1 AND ($8::timestamptz IS NULL OR updated_at = $8)`,
+			document.ID,
+			document.Title,
+			document.Author,
+			document.Description,
+			cleanJSONB(document.Content, "[]"),
+			cleanJSONB(metadataJSON, "{}"),
+			document.UpdatedAt,
+			unmodifiedSince,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			if unmodifiedSince != nil {
+				return domain.ErrStaleUpdate
+			}
+			return domain.ErrDocumentNotFound
+		}
+
+		tagName := ""
+		if document.Tag != nil {
+			tagName = *document.Tag
+		}
+		return replaceDocumentTag(ctx, tx, document.ID, tagName)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update document: %w", err)
+	}
+
+	return nil
+}
+
+// Patch updates only the patched columns. Tags live in document_tags, so a tag
+// change leaves the documents row alone apart from updated_at.
+func (r *PgDocumentRepository) Patch(ctx context.Context, principal domain.Principal, documentID string, patch domain.DocumentPatch) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var unmodifiedSince *time.Time
+	if t, ok := domain.UnmodifiedSinceFromContext(ctx); ok {
+		unmodifiedSince = &t
+	}
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE documents
+			SET title = COALESCE($2, title), author = COALESCE($3, author), updated_at = $4
+			WHERE id = $1 AND ($5::timestamptz IS NULL OR updated_at = $5)`,
+			documentID,
+			patch.Title,
+			patch.Author,
+			patch.UpdatedAt,
+			unmodifiedSince,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			if unmodifiedSince != nil {
+				return domain.ErrStaleUpdate
+			}
+			return domain.ErrDocumentNotFound
+		}
+		if patch.Tag == nil {
+			return nil
+		}
+		return replaceDocumentTag(ctx, tx, documentID, *patch.Tag)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to patch document: %w", err)
+	}
+	return nil
+}
+
+// replaceDocumentTag sets the document's single tag by name; an empty name or one
+// the user has not created leaves it untagged.
+func replaceDocumentTag(ctx context.Context, tx pgx.Tx, documentID, tagName string) error {
+	if _, err := tx.Exec(ctx, `DELETE FROM document_tags WHERE document_id = $1`, documentID); err != nil {
+		return err
+	}
+	if tagName == "" {
+		return nil
+	}
+	_, err := tx.Exec(ctx, `
+		INSERT INTO document_tags (document_id, tag_id)
+		SELECT d.id, t.id
+		FROM documents d
+		JOIN user_tags t ON t.user_id = d.user_id AND t.name = $2
+		WHERE d.id = $1
+		LIMIT 1`,
+		documentID, tagName,
+	)
+	return err
+}
+
+// Search filters documents in the database instead of downloading every document's content.
+func (r *PgDocumentRepository) Search(ctx context.Context, principal domain.Principal, query *domain.DocumentQuery) ([]*domain.Document, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+	args := []any{principal.UserID}
+	condition := documentQuerySQL(query, &args)
+
+	var documents []*domain.Document
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+documentColumns+`
+			FROM documents
+			WHERE user_id = $1 AND `+condition,
+			args...,
+		)
+		if err != nil {
+			return err
+		}
+		documents, err = r.collectDocuments(rows)
+		return err
+	})
+	if err != nil {
+		// Postgres and Go disagree on some regex syntax, so a pattern that parsed
+		// here can still be refused by the database.
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgInvalidRegex {
+			return nil, domain.ValidationErrors{{Field: "q", Message: "invalid regular expression: " + pgErr.Message}}
+		}
+		return nil, fmt.Errorf("failed to search documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// GetByID reads a document with its favorite flag and tag.
+func (r *PgDocumentRepository) GetByID(ctx context.Context, principal domain.Principal, id string) (*domain.Document, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var document *domain.Document
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+documentColumns+` FROM documents WHERE id = $1`, id)
+		if err != nil {
+			return err
+		}
+		documents, err := r.collectDocuments(rows)
+		if err != nil {
+			return err
+		}
+		if len(documents) == 0 {
+			return domain.ErrDocumentNotFound
+		}
+		document = documents[0]
+
+		return tx.QueryRow(ctx, `
+			SELECT
+				EXISTS (SELECT 1 FROM document_favorites WHERE user_id = $2 AND document_id = $1),
+				(SELECT t.name FROM document_tags dt JOIN user_tags t ON t.id = dt.tag_id WHERE dt.document_id = $1 LIMIT 1)`,
+			id, principal.UserID,
+		).Scan(&document.IsFavorite, &document.Tag)
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrDocumentNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get document: %w", err)
+	}
+
+	return document, nil
+}
+
+// GetByUserID lists the user's documents without their content.
+func (r *PgDocumentRepository) GetByUserID(ctx context.Context, principal domain.Principal) ([]*domain.Document, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var documents []*domain.Document
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT d.id, d.user_id, d.title, d.author, d.description, NULL::jsonb, d.metadata, d.archived, d.created_at, d.updated_at, d.last_opened_at,
+			       f.document_id IS NOT NULL,
+			       (SELECT t.name FROM document_tags dt JOIN user_tags t ON t.id = dt.tag_id WHERE dt.document_id = d.id LIMIT 1)
+			FROM documents d
+			LEFT JOIN document_favorites f ON f.document_id = d.id AND f.user_id = d.user_id
+			WHERE d.user_id = $1`,
+			principal.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row documentRow
+			var createdAt, updatedAt, lastOpenedAt *time.Time
+			var isFavorite bool
+			var tag *string
+			if err := rows.Scan(
+				&row.ID, &row.UserID, &row.Title, &row.Author, &row.Description,
+				&row.Content, &row.Metadata, &row.Archived, &createdAt, &updatedAt, &lastOpenedAt, &isFavorite, &tag,
+			); err != nil {
+				return err
+			}
+			if createdAt != nil {
+				row.CreatedAt.Time = *createdAt
+			}
+			if updatedAt != nil {
+				row.UpdatedAt.Time = *updatedAt
+			}
+			if lastOpenedAt != nil {
+				row.LastOpenedAt.Time = *lastOpenedAt
+			}
+
+			doc, err := row.toDomain()
+			if err != nil {
+				r.logger.Error("Failed to map document", err, "doc_id", row.ID)
+				continue
+			}
+			doc.IsFavorite = isFavorite
+			doc.Tag = nonEmpty(tag)
+			documents = append(documents, doc)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get documents: %w", err)
+	}
+
+	return documents, nil
+}
+
+// Delete removes a document; its tags, favorites, versions and positions cascade.
+func (r *PgDocumentRepository) Delete(ctx context.Context, principal domain.Principal, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM documents WHERE id = $1`, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete document: %w", err)
+	}
+	return nil
+}
+
+// SetFavorite inserts or deletes the favorite relationship for a (user, document).
+func (r *PgDocumentRepository) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		if isFavorite {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO document_favorites (user_id, document_id) VALUES ($1, $2)
+				ON CONFLICT DO NOTHING`,
+				principal.UserID, documentID,
+			)
+			return err
+		}
+		_, err := tx.Exec(ctx, `DELETE FROM document_favorites WHERE user_id = $1 AND document_id = $2`,
+			principal.UserID, documentID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set favorite: %w", err)
+	}
+	return nil
+}
+
+// SetArchived flips the document's archived flag without touching updated_at, so
+// archiving is not an edit of the document.
+func (r *PgDocumentRepository) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `UPDATE documents SET archived = $2 WHERE id = $1`, documentID, archived)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrDocumentNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrDocumentNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to set archived: %w", err)
+	}
+	return nil
+}
+
+// SetLastOpened records when the document was opened in a reader. Like archiving,
+// it leaves updated_at alone.
+func (r *PgDocumentRepository) SetLastOpened(ctx context.Context, principal domain.Principal, documentID string, at time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `UPDATE documents SET last_opened_at = $2 WHERE id = $1`, documentID, at)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set last opened: %w", err)
+	}
+	return nil
+}
+
+// GetTagsByUserID lists the names of the user's tags.
+func (r *PgDocumentRepository) GetTagsByUserID(ctx context.Context, principal domain.Principal) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	tags := []string{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT name FROM user_tags WHERE user_id = $1 AND name <> ''`, principal.UserID)
+		if err != nil {
+			return err
+		}
+		tags, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	return tags, nil
+}
+
+// CreateTag adds a tag for the user, or returns ErrTagAlreadyExists.
+func (r *PgDocumentRepository) CreateTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			INSERT INTO user_tags (user_id, name) VALUES ($1, $2)
+			ON CONFLICT (user_id, name) DO NOTHING`,
+			principal.UserID, tagName,
+		)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrTagAlreadyExists
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrTagAlreadyExists) {
+			return err
+		}
+		return fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	r.logger.Info("Tag created successfully", "user_id", principal.UserID, "tag_name", tagName)
+	return nil
+}
+
+// DeleteTag removes one of the user's tags; document_tags rows cascade.
+func (r *PgDocumentRepository) DeleteTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `DELETE FROM user_tags WHERE user_id = $1 AND name = $2`, principal.UserID, tagName)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return domain.ErrTagNotFound
+		}
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrTagNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+
+	r.logger.Info("Tag deleted successfully", "user_id", principal.UserID, "tag_name", tagName)
+	return nil
+}
+
+// collectDocuments maps rows selected with documentColumns, skipping rows that fail to map.
+func (r *PgDocumentRepository) collectDocuments(rows pgx.Rows) ([]*domain.Document, error) {
+	defer rows.Close()
+
+	var documents []*domain.Document
+	for rows.Next() {
+		var row documentRow
+		var createdAt, updatedAt, lastOpenedAt *time.Time
+		if err := rows.Scan(
+			&row.ID, &row.UserID, &row.Title, &row.Author, &row.Description,
+			&row.Content, &row.Metadata, &row.Archived, &createdAt, &updatedAt, &lastOpenedAt,
+		); err != nil {
+			return nil, err
+		}
+		if createdAt != nil {
+			row.CreatedAt.Time = *createdAt
+		}
+		if updatedAt != nil {
+			row.UpdatedAt.Time = *updatedAt
+		}
+		if lastOpenedAt != nil {
+			row.LastOpenedAt.Time = *lastOpenedAt
+		}
+
+		doc, err := row.toDomain()
+		if err != nil {
+			r.logger.Error("Failed to map document", err, "doc_id", row.ID)
+			continue
+		}
+		documents = append(documents, doc)
+	}
+	return documents, rows.Err()
+}
+
+// cleanJSONB strips sequences PostgreSQL rejects in JSONB and falls back when the result is invalid.
+func cleanJSONB(raw []byte, fallback string) string {
+	if len(raw) == 0 {
+		return fallback
+	}
+	cleaned := domain.StripUnsafeUnicodeEscapes(string(raw))
+	cleaned = strings.ReplaceAll(cleaned, "\x00", "")
+	if !json.Valid([]byte(cleaned)) {
+		return fallback
+	}
+	return cleaned
+}
+
+// escapeLike escapes LIKE wildcards so user input is matched literally.
+func escapeLike(s string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+	return replacer.Replace(s)
+}
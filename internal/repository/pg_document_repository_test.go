@@ -0,0 +1,54 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestCleanJSONB(t *testing.T) {
+	tests := []struct {
+		name     string
+		raw      string
+		fallback string
+		want     string
+	}{
+		{"Empty uses fallback", "", "[]", "[]"},
+		{"Valid JSON kept", `[{"content":"hi"}]`, "[]", `[{"content":"hi"}]`},
+		{"NUL escape removed", `[{"content":"a\u0000b"}]`, "[]", `[{"content":"ab"}]`},
+		{"Invalid JSON uses fallback", `{"broken"`, "{}", "{}"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cleanJSONB([]byte(tt.raw), tt.fallback); got != tt.want {
+				t.Fatalf("expected %s, got %s", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`50%_off\`); got != `50\%\_off\\` {
+		t.Fatalf("unexpected escaped value: %s", got)
+	}
+}
+
+func TestDocumentQuerySQL(t *testing.T) {
+	q, err := domain.ParseDocumentQuery(`"white whale" OR (author:melville -tag:/^draft/)`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []any{"user-1"}
+	got := strings.Join(strings.Fields(documentQuerySQL(q, &args)), " ")
+	want := "((lower(title) LIKE $2 OR lower(coalesce(author, '')) LIKE $2 OR lower(left(content::text, 1000)) LIKE $2)" +
+		" OR (lower(coalesce(author, '')) LIKE $3 AND NOT EXISTS (SELECT 1 FROM document_tags dt JOIN user_tags t ON t.id = dt.tag_id" +
+		" WHERE dt.document_id = documents.id AND t.name ~* $4)))"
+	if got != want {
+		t.Errorf("condition:\n got %s\nwant %s", got, want)
+	}
+	if len(args) != 4 || args[1] != "%white whale%" || args[2] != "%melville%" || args[3] != "^draft" {
+		t.Errorf("unexpected args %v", args)
+	}
+}
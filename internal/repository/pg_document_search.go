@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"strconv"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// pgInvalidRegex is the SQLSTATE Postgres returns for a regex it cannot compile.
+const pgInvalidRegex = "2201B"
+
+// documentQuerySQL compiles a library search into a condition on the documents
+// table, appending the values it needs to args. Words and phrases become LIKE
+// patterns on lowercased columns and /patterns/ case-insensitive regex matches.
+func documentQuerySQL(q *domain.DocumentQuery, args *[]any) string {
+	switch q.Op {
+	case domain.SearchAnd, domain.SearchOr:
+		parts := make([]string, 0, len(q.Children))
+		for _, child := range q.Children {
+			parts = append(parts, documentQuerySQL(child, args))
+		}
+		return "(" + strings.Join(parts, " "+strings.ToUpper(q.Op)+" ") + ")"
+	case domain.SearchNot:
+		return "NOT " + documentQuerySQL(q.Children[0], args)
+	}
+
+	param := func(value any) string {
+		*args = append(*args, value)
+		return "$" + strconv.Itoa(len(*args))
+	}
+
+	if q.Field == domain.SearchFieldTag {
+		var condition string
+		if q.Regex != nil {
+			condition = "t.name ~* " + param(q.Text)
+		} else {
+			condition = "lower(t.name) = " + param(strings.ToLower(q.Text))
+		}
+		return `EXISTS (SELECT 1 FROM document_tags dt JOIN user_tags t ON t.id = dt.tag_id
+			WHERE dt.document_id = documents.id AND ` + condition + `)`
+	}
+
+	var match func(column string) string
+	if q.Regex != nil {
+		p := param(q.Text)
+		match = func(column string) string { return column + " ~* " + p }
+	} else {
+		p := param("%" + escapeLike(strings.ToLower(q.Text)) + "%")
+		match = func(column string) string { return "lower(" + column + ") LIKE " + p }
+	}
+	switch q.Field {
+	case domain.SearchFieldTitle:
+		return match("title")
+	case domain.SearchFieldAuthor:
+		return match("coalesce(author, '')")
+	}
+	return "(" + match("title") +
+		" OR " + match("coalesce(author, '')") +
+		" OR " + match("left(content::text, "+strconv.Itoa(domain.SearchContentPrefix)+")") + ")"
+}
@@ -0,0 +1,180 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgDocumentVersionRepository implements the domain.DocumentVersionRepository interface
+// over a pgx pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgDocumentVersionRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgDocumentVersionRepository(pool *pgxpool.Pool, logger domain.Logger) domain.DocumentVersionRepository {
+	return &PgDocumentVersionRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgDocumentVersionRepository) Create(ctx context.Context, principal domain.Principal, version *domain.DocumentVersion) (*domain.DocumentVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	metadataJSON, err := json.Marshal(version.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	var created *domain.DocumentVersion
+	err = postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		// The (document_id, version) unique constraint rejects a concurrent snapshot
+		// that picked the same number.
+		rows, err := tx.Query(ctx, `
+			INSERT INTO document_versions (document_id, user_id, version, title, author, content, metadata, reason)
+			SELECT $1::uuid, $2::uuid, coalesce(max(version), 0) + 1, $3::text, $4::text, $5::jsonb, $6::jsonb, $7::text
+			FROM document_versions
+			WHERE document_id = $1
+			RETURNING id, document_id, user_id, version, title, author, content, metadata, reason, created_at`,
+			version.DocumentID,
+			principal.UserID,
+			version.Title,
+			version.Author,
+			cleanJSONB(version.Content, "[]"),
+			cleanJSONB(metadataJSON, "{}"),
+			version.Reason,
+		)
+		if err != nil {
+			return err
+		}
+		versions, err := collectVersions(rows, true)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return fmt.Errorf("empty response")
+		}
+		created = versions[0]
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create document version: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *PgDocumentVersionRepository) ListByDocument(ctx context.Context, principal domain.Principal, documentID string) ([]*domain.DocumentVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var versions []*domain.DocumentVersion
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		// Content is left out; it can be megabytes per version.
+		rows, err := tx.Query(ctx, `
+			SELECT id, document_id, user_id, version, title, author, metadata, reason, created_at
+			FROM document_versions
+			WHERE document_id = $1
+			ORDER BY version DESC`,
+			documentID,
+		)
+		if err != nil {
+			return err
+		}
+		versions, err = collectVersions(rows, false)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list document versions: %w", err)
+	}
+
+	if versions == nil {
+		versions = []*domain.DocumentVersion{}
+	}
+	return versions, nil
+}
+
+func (r *PgDocumentVersionRepository) Get(ctx context.Context, principal domain.Principal, documentID string, version int) (*domain.DocumentVersion, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var found *domain.DocumentVersion
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT id, document_id, user_id, version, title, author, content, metadata, reason, created_at
+			FROM document_versions
+			WHERE document_id = $1 AND version = $2`,
+			documentID, version,
+		)
+		if err != nil {
+			return err
+		}
+		versions, err := collectVersions(rows, true)
+		if err != nil {
+			return err
+		}
+		if len(versions) == 0 {
+			return fmt.Errorf("%w: version %d", domain.ErrVersionNotFound, version)
+		}
+		found = versions[0]
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrVersionNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get document version: %w", err)
+	}
+
+	return found, nil
+}
+
+func (r *PgDocumentVersionRepository) DeleteBefore(ctx context.Context, principal domain.Principal, documentID string, version int) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM document_versions WHERE document_id = $1 AND version < $2`, documentID, version)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to prune document versions: %w", err)
+	}
+	return nil
+}
+
+// collectVersions maps document_versions rows; withContent tells whether the content
+// column was selected (after author, before metadata).
+func collectVersions(rows pgx.Rows, withContent bool) ([]*domain.DocumentVersion, error) {
+	defer rows.Close()
+
+	var versions []*domain.DocumentVersion
+	for rows.Next() {
+		var row documentVersionRow
+		dest := []any{&row.ID, &row.DocumentID, &row.UserID, &row.Version, &row.Title, &row.Author}
+		if withContent {
+			dest = append(dest, &row.Content)
+		}
+		dest = append(dest, &row.Metadata, &row.Reason, &row.CreatedAt.Time)
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		version, err := row.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	return versions, rows.Err()
+}
@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const fontColumns = "id, user_id, name, format, size_bytes, path, created_at"
+
+// PgFontRepository implements the domain.FontRepository interface over a pgx pool.
+// Statements run inside postgres.WithUserTx, so RLS applies.
+type PgFontRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgFontRepository(pool *pgxpool.Pool, logger domain.Logger) domain.FontRepository {
+	return &PgFontRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgFontRepository) Create(ctx context.Context, principal domain.Principal, font *domain.Font) (*domain.Font, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var created *domain.Font
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO user_fonts (id, user_id, name, format, size_bytes, path)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING `+fontColumns,
+			font.ID, principal.UserID, sanitizeText(font.Name), font.Format, font.SizeBytes, font.Path,
+		)
+		if err != nil {
+			return err
+		}
+		created, err = pgx.CollectExactlyOneRow(rows, scanFont)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create font: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *PgFontRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Font, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	fonts := []*domain.Font{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+fontColumns+` FROM user_fonts WHERE user_id = $1 ORDER BY created_at`, principal.UserID)
+		if err != nil {
+			return err
+		}
+		fonts, err = pgx.CollectRows(rows, scanFont)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list fonts: %w", err)
+	}
+
+	return fonts, nil
+}
+
+func (r *PgFontRepository) Get(ctx context.Context, principal domain.Principal, fontID string) (*domain.Font, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var font *domain.Font
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+fontColumns+` FROM user_fonts WHERE id = $1 AND user_id = $2`, fontID, principal.UserID)
+		if err != nil {
+			return err
+		}
+		font, err = pgx.CollectExactlyOneRow(rows, scanFont)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrFontNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get font: %w", err)
+	}
+
+	return font, nil
+}
+
+func (r *PgFontRepository) Delete(ctx context.Context, principal domain.Principal, fontID string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM user_fonts WHERE id = $1 AND user_id = $2`, fontID, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete font: %w", err)
+	}
+	return nil
+}
+
+func scanFont(row pgx.CollectableRow) (*domain.Font, error) {
+	var font fontRow
+	if err := row.Scan(&font.ID, &font.UserID, &font.Name, &font.Format, &font.SizeBytes, &font.Path, &font.CreatedAt.Time); err != nil {
+		return nil, err
+	}
+	return font.toDomain(), nil
+}
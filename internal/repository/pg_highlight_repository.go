@@ -0,0 +1,291 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// highlightSearchConfig is the text search configuration of highlights.search. It
+// does not stem, so it works for highlights in any language.
+const highlightSearchConfig = "simple"
+
+const highlightColumns = "id, user_id, document_id, quote, note, page_number, progress, locator, " +
+	"context_before, context_after, content_version, orphaned, " +
+	"is_favorite, review_count, review_interval_days, review_due_at, reviewed_at, created_at"
+
+// PgHighlightRepository implements the domain.HighlightRepository interface over a pgx
+// pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgHighlightRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgHighlightRepository(pool *pgxpool.Pool, logger domain.Logger) domain.HighlightRepository {
+	return &PgHighlightRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgHighlightRepository) Create(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) (*domain.Highlight, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var created *domain.Highlight
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO highlights (user_id, document_id, quote, note, page_number, progress, locator,
+				context_before, context_after, content_version)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+			RETURNING `+highlightColumns,
+			principal.UserID, highlight.DocumentID, sanitizeText(highlight.Quote), sanitizeText(highlight.Note),
+			highlight.PageNumber, highlight.Progress, encodeLocator(highlight.Locator),
+			sanitizeText(highlight.ContextBefore), sanitizeText(highlight.ContextAfter), highlight.ContentVersion,
+		)
+		if err != nil {
+			return err
+		}
+		created, err = pgx.CollectExactlyOneRow(rows, scanHighlight)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create highlight: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *PgHighlightRepository) ListByUser(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var filter *string
+	if documentID != nil && *documentID != "" {
+		filter = documentID
+	}
+
+	highlights := []*domain.Highlight{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+highlightColumns+`
+			FROM highlights
+			WHERE user_id = $1 AND ($2::uuid IS NULL OR document_id = $2)
+			ORDER BY created_at DESC`,
+			principal.UserID, filter,
+		)
+		if err != nil {
+			return err
+		}
+		highlights, err = pgx.CollectRows(rows, scanHighlight)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list highlights: %w", err)
+	}
+
+	return highlights, nil
+}
+
+func (r *PgHighlightRepository) CountByDocuments(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	counts := make(map[string]int)
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT document_id, count(*)
+			FROM highlights
+			WHERE user_id = $1 AND document_id = ANY($2::uuid[])
+			GROUP BY document_id`,
+			principal.UserID, documentIDs,
+		)
+		if err != nil {
+			return err
+		}
+		var documentID string
+		var count int
+		_, err = pgx.ForEachRow(rows, []any{&documentID, &count}, func() error {
+			counts[documentID] = count
+			return nil
+		})
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to count highlights: %w", err)
+	}
+
+	return counts, nil
+}
+
+func (r *PgHighlightRepository) Delete(ctx context.Context, principal domain.Principal, highlightID string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM highlights WHERE id = $1 AND user_id = $2`, highlightID, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete highlight: %w", err)
+	}
+	return nil
+}
+
+func (r *PgHighlightRepository) MoveToDocument(ctx context.Context, principal domain.Principal, fromDocumentIDs []string, toDocumentID string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var moved int64
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE highlights SET document_id = $3
+			WHERE user_id = $1 AND document_id = ANY($2::uuid[])`,
+			principal.UserID, fromDocumentIDs, toDocumentID,
+		)
+		moved = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to move highlights: %w", err)
+	}
+	return int(moved), nil
+}
+
+func (r *PgHighlightRepository) UpdateAnchor(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			UPDATE highlights
+			SET locator = $3, page_number = $4, progress = $5,
+				context_before = $6, context_after = $7, content_version = $8, orphaned = $9
+			WHERE id = $1 AND user_id = $2`,
+			highlight.ID, principal.UserID, encodeLocator(highlight.Locator), highlight.PageNumber, highlight.Progress,
+			sanitizeText(highlight.ContextBefore), sanitizeText(highlight.ContextAfter), highlight.ContentVersion, highlight.Orphaned,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update highlight anchor: %w", err)
+	}
+	return nil
+}
+
+// Search ranks matches of the generated search column, then orders them newest first.
+func (r *PgHighlightRepository) Search(ctx context.Context, principal domain.Principal, search domain.HighlightSearch) ([]*domain.Highlight, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var documentID, tag *string
+	if search.DocumentID != "" {
+		documentID = &search.DocumentID
+	}
+	if search.Tag != "" {
+		tag = &search.Tag
+	}
+
+	highlights := []*domain.Highlight{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT `+highlightColumns+`
+			FROM highlights, websearch_to_tsquery('`+highlightSearchConfig+`', $2) query
+			WHERE user_id = $1 AND search @@ query
+			  AND ($3::uuid IS NULL OR document_id = $3)
+			  AND ($4::text IS NULL OR document_id IN (
+			       SELECT dt.document_id FROM document_tags dt JOIN user_tags t ON t.id = dt.tag_id
+			       WHERE t.user_id = $1 AND t.name = $4))
+			ORDER BY ts_rank(search, query) DESC, created_at DESC
+			LIMIT $5`,
+			principal.UserID, search.Query, documentID, tag, search.Limit,
+		)
+		if err != nil {
+			return err
+		}
+		highlights, err = pgx.CollectRows(rows, scanHighlight)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search highlights: %w", err)
+	}
+
+	return highlights, nil
+}
+
+func (r *PgHighlightRepository) Get(ctx context.Context, principal domain.Principal, highlightID string) (*domain.Highlight, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var highlight *domain.Highlight
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+highlightColumns+` FROM highlights WHERE id = $1 AND user_id = $2`, highlightID, principal.UserID)
+		if err != nil {
+			return err
+		}
+		highlight, err = pgx.CollectExactlyOneRow(rows, scanHighlight)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrHighlightNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get highlight: %w", err)
+	}
+
+	return highlight, nil
+}
+
+func (r *PgHighlightRepository) UpdateReview(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE highlights
+			SET is_favorite = $3, review_count = $4, review_interval_days = $5, review_due_at = $6, reviewed_at = $7
+			WHERE id = $1 AND user_id = $2`,
+			highlight.ID, principal.UserID, highlight.IsFavorite, highlight.ReviewCount, highlight.ReviewInterval,
+			highlight.ReviewDueAt, highlight.ReviewedAt,
+		)
+		if err == nil && tag.RowsAffected() == 0 {
+			return domain.ErrHighlightNotFound
+		}
+		return err
+	})
+	if errors.Is(err, domain.ErrHighlightNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update highlight review: %w", err)
+	}
+	return nil
+}
+
+func scanHighlight(row pgx.CollectableRow) (*domain.Highlight, error) {
+	var highlight highlightRow
+	var dueAt, reviewedAt *time.Time
+	if err := row.Scan(
+		&highlight.ID, &highlight.UserID, &highlight.DocumentID, &highlight.Quote, &highlight.Note,
+		&highlight.PageNumber, &highlight.Progress, &highlight.Locator,
+		&highlight.ContextBefore, &highlight.ContextAfter, &highlight.ContentVersion, &highlight.Orphaned,
+		&highlight.IsFavorite, &highlight.ReviewCount, &highlight.ReviewInterval, &dueAt, &reviewedAt,
+		&highlight.CreatedAt.Time,
+	); err != nil {
+		return nil, err
+	}
+	if dueAt != nil {
+		highlight.ReviewDueAt.Time = *dueAt
+	}
+	if reviewedAt != nil {
+		highlight.ReviewedAt.Time = *reviewedAt
+	}
+	return highlight.toDomain(), nil
+}
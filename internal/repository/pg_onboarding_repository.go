@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgOnboardingRepository implements the domain.OnboardingRepository interface over a
+// pgx pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgOnboardingRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgOnboardingRepository(pool *pgxpool.Pool, logger domain.Logger) domain.OnboardingRepository {
+	return &PgOnboardingRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgOnboardingRepository) ClaimOnboarding(ctx context.Context, principal domain.Principal) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var claimed bool
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx, `
+			UPDATE user_onboarding SET onboarded_at = now()
+			WHERE user_id = $1 AND onboarded_at IS NULL`,
+			principal.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		claimed = tag.RowsAffected() == 1
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to claim onboarding: %w", err)
+	}
+	return claimed, nil
+}
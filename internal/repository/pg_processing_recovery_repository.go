@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgProcessingRecoveryRepository implements the domain.ProcessingRecoveryRepository
+// interface. Recovery looks at every user's documents, so like PgDigestRepository it
+// queries with the pool's own role instead of impersonating a user.
+type PgProcessingRecoveryRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgProcessingRecoveryRepository(pool *pgxpool.Pool, logger domain.Logger) domain.ProcessingRecoveryRepository {
+	return &PgProcessingRecoveryRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// ListUnprocessed returns PDFs that still have the empty content Upload stores
+// before background extraction, no page count and fewer than
+// domain.MaxProcessingAttempts failed extractions. Quarantined documents are
+// never processed and are left out.
+func (r *PgProcessingRecoveryRepository) ListUnprocessed(ctx context.Context, createdBefore time.Time, limit int) ([]domain.UnprocessedDocument, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	rows, err := r.pool.Query(ctx, `
+		SELECT id::text, user_id::text
+		FROM documents
+		WHERE content = '[]'::jsonb
+		  AND metadata->>'format' = 'pdf'
+		  AND COALESCE((metadata->>'page_count')::int, 0) = 0
+		  AND COALESCE((metadata->>'processing_attempts')::int, 0) < $2
+		  AND metadata->'quarantine' IS NULL
+		  AND created_at < $1
+		ORDER BY created_at
+		LIMIT $3`,
+		createdBefore, domain.MaxProcessingAttempts, limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unprocessed documents: %w", err)
+	}
+	docs, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (domain.UnprocessedDocument, error) {
+		var doc domain.UnprocessedDocument
+		err := row.Scan(&doc.DocumentID, &doc.UserID)
+		return doc, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list unprocessed documents: %w", err)
+	}
+	return docs, nil
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const savedSearchColumns = "id, user_id, name, query, filters, created_at, updated_at"
+
+// PgSavedSearchRepository implements the domain.SavedSearchRepository interface over a
+// pgx pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgSavedSearchRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgSavedSearchRepository(pool *pgxpool.Pool, logger domain.Logger) domain.SavedSearchRepository {
+	return &PgSavedSearchRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgSavedSearchRepository) Create(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var created *domain.SavedSearch
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO saved_searches (user_id, name, query, filters)
+			VALUES ($1, $2, $3, $4)
+			RETURNING `+savedSearchColumns,
+			principal.UserID, sanitizeText(search.Name), sanitizeText(search.Query), encodeSavedSearchFilters(search.Filters),
+		)
+		if err != nil {
+			return err
+		}
+		created, err = pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *PgSavedSearchRepository) Update(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var updated *domain.SavedSearch
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE saved_searches
+			SET name = $3, query = $4, filters = $5, updated_at = now()
+			WHERE id = $1 AND user_id = $2
+			RETURNING `+savedSearchColumns,
+			search.ID, principal.UserID, sanitizeText(search.Name), sanitizeText(search.Query), encodeSavedSearchFilters(search.Filters),
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *PgSavedSearchRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	searches := []*domain.SavedSearch{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+savedSearchColumns+` FROM saved_searches WHERE user_id = $1 ORDER BY name`, principal.UserID)
+		if err != nil {
+			return err
+		}
+		searches, err = pgx.CollectRows(rows, scanSavedSearch)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+
+	return searches, nil
+}
+
+func (r *PgSavedSearchRepository) Get(ctx context.Context, principal domain.Principal, searchID string) (*domain.SavedSearch, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var search *domain.SavedSearch
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+savedSearchColumns+` FROM saved_searches WHERE id = $1 AND user_id = $2`, searchID, principal.UserID)
+		if err != nil {
+			return err
+		}
+		search, err = pgx.CollectExactlyOneRow(rows, scanSavedSearch)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	return search, nil
+}
+
+func (r *PgSavedSearchRepository) Delete(ctx context.Context, principal domain.Principal, searchID string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM saved_searches WHERE id = $1 AND user_id = $2`, searchID, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
+
+func scanSavedSearch(row pgx.CollectableRow) (*domain.SavedSearch, error) {
+	var search savedSearchRow
+	if err := row.Scan(
+		&search.ID, &search.UserID, &search.Name, &search.Query, &search.Filters,
+		&search.CreatedAt.Time, &search.UpdatedAt.Time,
+	); err != nil {
+		return nil, err
+	}
+	return search.toDomain(), nil
+}
+
+// encodeSavedSearchFilters encodes filters for the jsonb column.
+func encodeSavedSearchFilters(filters domain.SavedSearchFilters) []byte {
+	data, _ := json.Marshal(filters)
+	return data
+}
@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const themeColumns = "id, user_id, name, background_color, text_color, accent_color, line_height, font_family, created_at, updated_at"
+
+// PgThemeRepository implements the domain.ThemeRepository interface over a pgx pool.
+// Statements run inside postgres.WithUserTx, so RLS applies.
+type PgThemeRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgThemeRepository(pool *pgxpool.Pool, logger domain.Logger) domain.ThemeRepository {
+	return &PgThemeRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (r *PgThemeRepository) Create(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var created *domain.Theme
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			INSERT INTO user_themes (user_id, name, background_color, text_color, accent_color, line_height, font_family)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING `+themeColumns,
+			principal.UserID, sanitizeText(theme.Name), theme.BackgroundColor, theme.TextColor,
+			theme.AccentColor, theme.LineHeight, nonEmpty(&theme.FontFamily),
+		)
+		if err != nil {
+			return err
+		}
+		created, err = pgx.CollectExactlyOneRow(rows, scanTheme)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create theme: %w", err)
+	}
+
+	return created, nil
+}
+
+func (r *PgThemeRepository) Update(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	var updated *domain.Theme
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			UPDATE user_themes
+			SET name = $3, background_color = $4, text_color = $5, accent_color = $6,
+			    line_height = $7, font_family = $8, updated_at = now()
+			WHERE id = $1 AND user_id = $2
+			RETURNING `+themeColumns,
+			theme.ID, principal.UserID, sanitizeText(theme.Name), theme.BackgroundColor, theme.TextColor,
+			theme.AccentColor, theme.LineHeight, nonEmpty(&theme.FontFamily),
+		)
+		if err != nil {
+			return err
+		}
+		updated, err = pgx.CollectExactlyOneRow(rows, scanTheme)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrThemeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update theme: %w", err)
+	}
+
+	return updated, nil
+}
+
+func (r *PgThemeRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Theme, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	themes := []*domain.Theme{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+themeColumns+` FROM user_themes WHERE user_id = $1 ORDER BY name`, principal.UserID)
+		if err != nil {
+			return err
+		}
+		themes, err = pgx.CollectRows(rows, scanTheme)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list themes: %w", err)
+	}
+
+	return themes, nil
+}
+
+func (r *PgThemeRepository) Get(ctx context.Context, principal domain.Principal, themeID string) (*domain.Theme, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var theme *domain.Theme
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `SELECT `+themeColumns+` FROM user_themes WHERE id = $1 AND user_id = $2`, themeID, principal.UserID)
+		if err != nil {
+			return err
+		}
+		theme, err = pgx.CollectExactlyOneRow(rows, scanTheme)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrThemeNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get theme: %w", err)
+	}
+
+	return theme, nil
+}
+
+func (r *PgThemeRepository) Delete(ctx context.Context, principal domain.Principal, themeID string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM user_themes WHERE id = $1 AND user_id = $2`, themeID, principal.UserID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete theme: %w", err)
+	}
+	return nil
+}
+
+func scanTheme(row pgx.CollectableRow) (*domain.Theme, error) {
+	var theme themeRow
+	if err := row.Scan(
+		&theme.ID, &theme.UserID, &theme.Name, &theme.BackgroundColor, &theme.TextColor, &theme.AccentColor,
+		&theme.LineHeight, &theme.FontFamily, &theme.CreatedAt.Time, &theme.UpdatedAt.Time,
+	); err != nil {
+		return nil, err
+	}
+	return theme.toDomain(), nil
+}
@@ -0,0 +1,456 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/internal/infra/postgres"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PgUserPreferencesRepository implements the domain.UserPreferencesRepository interface
+// over a pgx pool. Statements run inside postgres.WithUserTx, so RLS applies.
+type PgUserPreferencesRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgUserPreferencesRepository(pool *pgxpool.Pool, logger domain.Logger) domain.UserPreferencesRepository {
+	return &PgUserPreferencesRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// GetPreferences returns the stored preferences with the user's tags, or the defaults
+// when the user has no preferences row yet.
+func (r *PgUserPreferencesRepository) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var prefs *domain.UserPreferences
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		var row userPreferencesRow
+		err := tx.QueryRow(ctx, `
+			SELECT user_id, font_size, font_family, theme, highlight_color, margin_size, text_align,
+			       hyphenation, reading_mode, page_turn_animation, brightness, subscription_plan,
+			       storage_limit_bytes, account_disabled, weekly_digest, updated_at
+			FROM user_preferences
+			WHERE user_id = $1`,
+			principal.UserID,
+		).Scan(
+			&row.UserID, &row.FontSize, &row.FontFamily, &row.Theme, &row.HighlightColor, &row.MarginSize, &row.TextAlign,
+			&row.Hyphenation, &row.ReadingMode, &row.PageTurnAnimation, &row.Brightness, &row.SubscriptionPlan,
+			&row.StorageLimitBytes, &row.AccountDisabled, &row.WeeklyDigest, &row.UpdatedAt.Time,
+		)
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			prefs = domain.DefaultUserPreferences(principal.UserID)
+		case err != nil:
+			return err
+		default:
+			prefs = row.toDomain()
+		}
+
+		rows, err := tx.Query(ctx, `SELECT name FROM user_tags WHERE user_id = $1 AND name <> ''`, principal.UserID)
+		if err != nil {
+			return err
+		}
+		prefs.Tags, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+// UpdatePreferences writes the preferences and replaces the user's tags in one transaction
+func (r *PgUserPreferencesRepository) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	args := []any{
+		principal.UserID, prefs.FontSize, prefs.FontFamily, prefs.Theme, prefs.HighlightColor,
+		prefs.MarginSize, prefs.TextAlign, prefs.Hyphenation, prefs.ReadingMode,
+		prefs.PageTurnAnimation, prefs.Brightness, prefs.SubscriptionPlan, prefs.StorageLimitBytes,
+		prefs.WeeklyDigest,
+	}
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		if unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(ctx); ok {
+			// A conditional update only applies to the row the client read, so it cannot
+			// insert; a missing row counts as stale as well.
+			tag, err := tx.Exec(ctx, `
+				UPDATE user_preferences
+				SET font_size = $2, font_family = $3, theme = $4, highlight_color = $5, margin_size = $6,
+				    text_align = $7, hyphenation = $8, reading_mode = $9, page_turn_animation = $10,
+				    brightness = $11, subscription_plan = $12, storage_limit_bytes = $13, weekly_digest = $14,
+				    updated_at = now()
+				WHERE user_id = $1 AND updated_at = $15`,
+				append(args, unmodifiedSince)...,
+			)
+			if err != nil {
+				return err
+			}
+			if tag.RowsAffected() == 0 {
+				return domain.ErrStaleUpdate
+			}
+		} else {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO user_preferences (
+					user_id, font_size, font_family, theme, highlight_color, margin_size, text_align,
+					hyphenation, reading_mode, page_turn_animation, brightness, subscription_plan, storage_limit_bytes,
+					weekly_digest
+				)
+				VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+				ON CONFLICT (user_id) DO UPDATE
+				SET font_size = $2, font_family = $3, theme = $4, highlight_color = $5, margin_size = $6,
+				    text_align = $7, hyphenation = $8, reading_mode = $9, page_turn_animation = $10,
+				    brightness = $11, subscription_plan = $12, storage_limit_bytes = $13, weekly_digest = $14,
+				    updated_at = now()`,
+				args...,
+			)
+			if err != nil {
+				return err
+			}
+		}
+
+		tags := make([]string, 0, len(prefs.Tags))
+		for _, tag := range prefs.Tags {
+			if tag != "" {
+				tags = append(tags, tag)
+			}
+		}
+		// document_tags rows of removed tags cascade.
+		if _, err := tx.Exec(ctx, `DELETE FROM user_tags WHERE user_id = $1 AND NOT (name = ANY($2))`, principal.UserID, tags); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_tags (user_id, name)
+			SELECT $1::uuid, unnest($2::text[])
+			ON CONFLICT (user_id, name) DO NOTHING`,
+			principal.UserID, tags,
+		)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, domain.ErrStaleUpdate) {
+			return err
+		}
+		return fmt.Errorf("failed to update preferences: %w", err)
+	}
+
+	r.logger.Info("Preferences updated successfully", "user_id", principal.UserID)
+	return nil
+}
+
+// GetReadingPosition returns the saved position, or the start of the document
+func (r *PgUserPreferencesRepository) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	position := &domain.ReadingPosition{
+		UserID:     principal.UserID,
+		DocumentID: documentID,
+		Progress:   0.0,
+		PageNumber: 1,
+		UpdatedAt:  time.Now(),
+	}
+	var locator []byte
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx, `
+			SELECT progress, page_number, char_offset, locator, updated_at
+			FROM reading_positions
+			WHERE user_id = $1 AND document_id = $2`,
+			principal.UserID, documentID,
+		).Scan(&position.Progress, &position.PageNumber, &position.CharOffset, &locator, &position.UpdatedAt)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading position: %w", err)
+	}
+	position.Locator = decodeLocator(locator)
+
+	return position, nil
+}
+
+// GetAllReadingPositions returns the user's positions keyed by document ID
+func (r *PgUserPreferencesRepository) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	positions := make(map[string]*domain.ReadingPosition)
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT user_id, document_id, progress, page_number, char_offset, locator, updated_at
+			FROM reading_positions
+			WHERE user_id = $1`,
+			principal.UserID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row readingPositionRow
+			if err := rows.Scan(&row.UserID, &row.DocumentID, &row.Progress, &row.PageNumber, &row.CharOffset, &row.Locator, &row.UpdatedAt.Time); err != nil {
+				return err
+			}
+			positions[row.DocumentID] = row.toDomain()
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading positions: %w", err)
+	}
+
+	return positions, nil
+}
+
+// GetReadingPositions returns the saved positions of the given documents
+func (r *PgUserPreferencesRepository) GetReadingPositions(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]*domain.ReadingPosition, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	positions := make(map[string]*domain.ReadingPosition, len(documentIDs))
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT user_id, document_id, progress, page_number, char_offset, locator, updated_at
+			FROM reading_positions
+			WHERE user_id = $1 AND document_id = ANY($2::uuid[])`,
+			principal.UserID, documentIDs,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row readingPositionRow
+			if err := rows.Scan(&row.UserID, &row.DocumentID, &row.Progress, &row.PageNumber, &row.CharOffset, &row.Locator, &row.UpdatedAt.Time); err != nil {
+				return err
+			}
+			positions[row.DocumentID] = row.toDomain()
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading positions: %w", err)
+	}
+
+	return positions, nil
+}
+
+// UpdateReadingPosition inserts or replaces the position for a document
+func (r *PgUserPreferencesRepository) UpdateReadingPosition(ctx context.Context, principal domain.Principal, position *domain.ReadingPosition) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO reading_positions (user_id, document_id, progress, page_number, char_offset, locator, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (user_id, document_id) DO UPDATE
+			SET progress = $3, page_number = $4, char_offset = $5, locator = $6, updated_at = $7`,
+			principal.UserID, position.DocumentID, position.Progress, position.PageNumber, position.CharOffset,
+			encodeLocator(position.Locator), position.UpdatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update reading position: %w", err)
+	}
+
+	r.logger.Info("Reading position updated successfully",
+		"user_id", principal.UserID,
+		"document_id", position.DocumentID,
+		"progress", position.Progress,
+		"page_number", position.PageNumber)
+	return nil
+}
+
+// LatestPositionSample returns the document's most recent position sample, or nil
+func (r *PgUserPreferencesRepository) LatestPositionSample(ctx context.Context, principal domain.Principal, documentID string) (*domain.PositionSample, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var sample *domain.PositionSample
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		var row positionSampleRow
+		err := tx.QueryRow(ctx, `
+			SELECT document_id, progress, page_number, char_offset, recorded_at
+			FROM reading_position_history
+			WHERE user_id = $1 AND document_id = $2
+			ORDER BY recorded_at DESC
+			LIMIT 1`,
+			principal.UserID, documentID,
+		).Scan(&row.DocumentID, &row.Progress, &row.PageNumber, &row.CharOffset, &row.RecordedAt.Time)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		sample = row.toDomain()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position sample: %w", err)
+	}
+	return sample, nil
+}
+
+// AddPositionSample appends a sample to the document's position history
+func (r *PgUserPreferencesRepository) AddPositionSample(ctx context.Context, principal domain.Principal, sample *domain.PositionSample) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO reading_position_history (user_id, document_id, progress, page_number, char_offset, recorded_at)
+			VALUES ($1, $2, $3, $4, $5, $6)`,
+			principal.UserID, sample.DocumentID, sample.Progress, sample.PageNumber, sample.CharOffset, sample.RecordedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to add position sample: %w", err)
+	}
+	return nil
+}
+
+// GetPositionHistory lists the document's position samples since a time, oldest first
+func (r *PgUserPreferencesRepository) GetPositionHistory(ctx context.Context, principal domain.Principal, documentID string, since time.Time) ([]*domain.PositionSample, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	samples := []*domain.PositionSample{}
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		rows, err := tx.Query(ctx, `
+			SELECT document_id, progress, page_number, char_offset, recorded_at
+			FROM reading_position_history
+			WHERE user_id = $1 AND document_id = $2 AND recorded_at >= $3
+			ORDER BY recorded_at`,
+			principal.UserID, documentID, since,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var row positionSampleRow
+			if err := rows.Scan(&row.DocumentID, &row.Progress, &row.PageNumber, &row.CharOffset, &row.RecordedAt.Time); err != nil {
+				return err
+			}
+			samples = append(samples, row.toDomain())
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position history: %w", err)
+	}
+	return samples, nil
+}
+
+// GetDocumentPreferences returns the user's overrides for one document, or nil
+func (r *PgUserPreferencesRepository) GetDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentPreferences, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var override *domain.DocumentPreferences
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		var row documentPreferencesRow
+		err := tx.QueryRow(ctx, `
+			SELECT user_id, document_id, font_size, font_family, theme, margin_size, text_align, updated_at
+			FROM document_preferences
+			WHERE user_id = $1 AND document_id = $2`,
+			principal.UserID, documentID,
+		).Scan(
+			&row.UserID, &row.DocumentID, &row.FontSize, &row.FontFamily, &row.Theme,
+			&row.MarginSize, &row.TextAlign, &row.UpdatedAt.Time,
+		)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		override = row.toDomain()
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document preferences: %w", err)
+	}
+
+	return override, nil
+}
+
+// UpsertDocumentPreferences creates or replaces the user's overrides for a document
+func (r *PgUserPreferencesRepository) UpsertDocumentPreferences(ctx context.Context, principal domain.Principal, override *domain.DocumentPreferences) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	// Unset fields are written as null so a replaced override drops them.
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO document_preferences (user_id, document_id, font_size, font_family, theme, margin_size, text_align)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (user_id, document_id) DO UPDATE
+			SET font_size = $3, font_family = $4, theme = $5, margin_size = $6, text_align = $7, updated_at = now()`,
+			principal.UserID, override.DocumentID, override.FontSize, override.FontFamily,
+			override.Theme, override.MarginSize, override.TextAlign,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update document preferences: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocumentPreferences removes the user's overrides for a document
+func (r *PgUserPreferencesRepository) DeleteDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `DELETE FROM document_preferences WHERE user_id = $1 AND document_id = $2`,
+			principal.UserID, documentID)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete document preferences: %w", err)
+	}
+	return nil
+}
+
+// SetAccountDisabled upserts the preferences row's account_disabled flag without touching other fields
+func (r *PgUserPreferencesRepository) SetAccountDisabled(ctx context.Context, principal domain.Principal, disabled bool) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := postgres.WithUserTx(ctx, r.pool, principal.Token, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, `
+			INSERT INTO user_preferences (user_id, account_disabled) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET account_disabled = $2`,
+			principal.UserID, disabled,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update account status: %w", err)
+	}
+	return nil
+}
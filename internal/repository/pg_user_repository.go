@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// pgUniqueViolation is the SQLSTATE Postgres returns when a unique constraint fails.
+const pgUniqueViolation = "23505"
+
+// PgUserRepository implements the domain.UserRepository interface for the local auth
+// backend of self-hosted mode. The auth.users table is not exposed to the authenticated
+// role, so unlike the other pgx repositories it queries with the pool's own role.
+// User.Password holds the password hash.
+type PgUserRepository struct {
+	pool   *pgxpool.Pool
+	logger domain.Logger
+}
+
+func NewPgUserRepository(pool *pgxpool.Pool, logger domain.Logger) domain.UserRepository {
+	return &PgUserRepository{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+// Create stores a new account, filling in its ID and timestamps. Emails are unique
+// regardless of case.
+func (r *PgUserRepository) Create(ctx context.Context, user *domain.User) error {
+	ctx, cancel := context.WithTimeout(ctx, writeTimeout)
+	defer cancel()
+
+	err := r.pool.QueryRow(ctx, `
+		INSERT INTO auth.users (email, password_hash, name)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at`,
+		strings.ToLower(user.Email), user.Password, sanitizeText(user.Name),
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	if err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgUniqueViolation {
+			return domain.ErrEmailTaken
+		}
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+
+	r.logger.Info("User created", "user_id", user.ID)
+	return nil
+}
+
+func (r *PgUserRepository) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.get(ctx, "email", strings.ToLower(email))
+}
+
+func (r *PgUserRepository) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	return r.get(ctx, "id", id)
+}
+
+// get reads the account whose column equals value; column is never user input.
+func (r *PgUserRepository) get(ctx context.Context, column string, value string) (*domain.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, readTimeout)
+	defer cancel()
+
+	var user domain.User
+	err := r.pool.QueryRow(ctx, `
+		SELECT id, email, password_hash, name, created_at, updated_at
+		FROM auth.users
+		WHERE `+column+` = $1`,
+		value,
+	).Scan(&user.ID, &user.Email, &user.Password, &user.Name, &user.CreatedAt, &user.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, domain.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,535 @@
+package repository
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Row types mirror the PostgREST JSON representation of each table so responses can be
+// unmarshaled directly instead of walking map[string]interface{} values by hand.
+
+// dbTime parses the timestamp formats PostgREST returns for timestamp/timestamptz columns.
+type dbTime struct {
+	time.Time
+}
+
+var dbTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ptr returns the time, or nil for a null column.
+func (t dbTime) ptr() *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t.Time
+}
+
+// UnmarshalJSON accepts null, RFC3339 and timezone-less timestamps.
+func (t *dbTime) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("invalid timestamp: %w", err)
+	}
+	if s == "" {
+		return nil
+	}
+	for _, layout := range dbTimeLayouts {
+		if parsed, err := time.Parse(layout, s); err == nil {
+			t.Time = parsed
+			return nil
+		}
+	}
+	return fmt.Errorf("unsupported timestamp format %q", s)
+}
+
+// decodeJSONB normalizes a JSONB column that PostgREST may return either inline
+// or as a JSON-encoded string. Missing values decode to nil.
+func decodeJSONB(raw json.RawMessage) (json.RawMessage, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, []byte("null")) {
+		return nil, nil
+	}
+	if raw[0] == '"' {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return nil, err
+		}
+		if s == "" {
+			return nil, nil
+		}
+		return json.RawMessage(s), nil
+	}
+	return raw, nil
+}
+
+// decodeLocator decodes a nullable locator column. A malformed locator is dropped
+// rather than failing the read; the record still has its page and progress.
+func decodeLocator(raw json.RawMessage) *domain.EPUBLocator {
+	raw, err := decodeJSONB(raw)
+	if err != nil || raw == nil {
+		return nil
+	}
+	var locator domain.EPUBLocator
+	if err := json.Unmarshal(raw, &locator); err != nil {
+		return nil
+	}
+	return &locator
+}
+
+// encodeLocator encodes an optional locator for a jsonb column; nil stays NULL.
+func encodeLocator(locator *domain.EPUBLocator) []byte {
+	if locator == nil {
+		return nil
+	}
+	data, _ := json.Marshal(locator)
+	return data
+}
+
+// nonEmpty returns nil for nil or empty strings so optional columns stay omitted.
+func nonEmpty(s *string) *string {
+	if s == nil || *s == "" {
+		return nil
+	}
+	return s
+}
+
+// documentRow is a row of the documents table.
+type documentRow struct {
+	ID           string          `json:"id"`
+	UserID       string          `json:"user_id"`
+	Title        string          `json:"title"`
+	Author       *string         `json:"author"`
+	Description  *string         `json:"description"`
+	Content      json.RawMessage `json:"content"`
+	Metadata     json.RawMessage `json:"metadata"`
+	Archived     bool            `json:"archived"`
+	CreatedAt    dbTime          `json:"created_at"`
+	UpdatedAt    dbTime          `json:"updated_at"`
+	LastOpenedAt dbTime          `json:"last_opened_at"`
+}
+
+func (row *documentRow) toDomain() (*domain.Document, error) {
+	document := &domain.Document{
+		ID:           row.ID,
+		UserID:       row.UserID,
+		Title:        row.Title,
+		Author:       nonEmpty(row.Author),
+		Description:  nonEmpty(row.Description),
+		IsArchived:   row.Archived,
+		CreatedAt:    row.CreatedAt.Time,
+		UpdatedAt:    row.UpdatedAt.Time,
+		LastOpenedAt: row.LastOpenedAt.ptr(),
+	}
+
+	content, err := decodeJSONB(row.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	if content == nil {
+		content = json.RawMessage("[]")
+	}
+	document.Content = content
+
+	metadata, err := decodeJSONB(row.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if metadata != nil {
+		if err := json.Unmarshal(metadata, &document.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	return document, nil
+}
+
+// userPreferencesRow is a row of the user_preferences table.
+type userPreferencesRow struct {
+	UserID            string   `json:"user_id"`
+	FontSize          int      `json:"font_size"`
+	FontFamily        string   `json:"font_family"`
+	Theme             string   `json:"theme"`
+	HighlightColor    string   `json:"highlight_color"`
+	MarginSize        string   `json:"margin_size"`
+	TextAlign         string   `json:"text_align"`
+	Hyphenation       bool     `json:"hyphenation"`
+	ReadingMode       string   `json:"reading_mode"`
+	PageTurnAnimation string   `json:"page_turn_animation"`
+	Brightness        *float64 `json:"brightness"`
+	SubscriptionPlan  string   `json:"subscription_plan"`
+	StorageLimitBytes int64    `json:"storage_limit_bytes"`
+	AccountDisabled   bool     `json:"account_disabled"`
+	WeeklyDigest      bool     `json:"weekly_digest"`
+	UpdatedAt         dbTime   `json:"updated_at"`
+}
+
+// toDomain converts the row, backfilling defaults for columns older rows leave empty.
+func (row *userPreferencesRow) toDomain() *domain.UserPreferences {
+	prefs := &domain.UserPreferences{
+		UserID:            row.UserID,
+		FontSize:          row.FontSize,
+		FontFamily:        row.FontFamily,
+		Theme:             row.Theme,
+		HighlightColor:    row.HighlightColor,
+		MarginSize:        row.MarginSize,
+		TextAlign:         row.TextAlign,
+		Hyphenation:       row.Hyphenation,
+		ReadingMode:       row.ReadingMode,
+		PageTurnAnimation: row.PageTurnAnimation,
+		SubscriptionPlan:  row.SubscriptionPlan,
+		StorageLimitBytes: row.StorageLimitBytes,
+		AccountDisabled:   row.AccountDisabled,
+		WeeklyDigest:      row.WeeklyDigest,
+		Tags:              []string{}, // Tags are loaded separately from user_tags table
+		UpdatedAt:         row.UpdatedAt.Time,
+	}
+
+	// Backfill defaults for older rows.
+	defaults := domain.DefaultUserPreferences(row.UserID)
+	if prefs.HighlightColor == "" {
+		prefs.HighlightColor = defaults.HighlightColor
+	}
+	if prefs.MarginSize == "" {
+		prefs.MarginSize = defaults.MarginSize
+	}
+	if prefs.TextAlign == "" {
+		prefs.TextAlign = defaults.TextAlign
+	}
+	if prefs.ReadingMode == "" {
+		prefs.ReadingMode = defaults.ReadingMode
+	}
+	if prefs.PageTurnAnimation == "" {
+		prefs.PageTurnAnimation = defaults.PageTurnAnimation
+	}
+	prefs.Brightness = defaults.Brightness
+	if row.Brightness != nil {
+		prefs.Brightness = *row.Brightness
+	}
+	if prefs.SubscriptionPlan == "" {
+		prefs.SubscriptionPlan = "free"
+	}
+	if prefs.StorageLimitBytes <= 0 {
+		// If storage_limit_bytes is missing, derive it from the plan so Pro users
+		// still get the correct quota.
+		prefs.StorageLimitBytes = domain.StorageLimitBytesForPlan(prefs.SubscriptionPlan)
+	}
+
+	return prefs
+}
+
+// documentPreferencesRow is a row of the document_preferences table.
+type documentPreferencesRow struct {
+	UserID     string  `json:"user_id"`
+	DocumentID string  `json:"document_id"`
+	FontSize   *int    `json:"font_size"`
+	FontFamily *string `json:"font_family"`
+	Theme      *string `json:"theme"`
+	MarginSize *string `json:"margin_size"`
+	TextAlign  *string `json:"text_align"`
+	UpdatedAt  dbTime  `json:"updated_at"`
+}
+
+func (row *documentPreferencesRow) toDomain() *domain.DocumentPreferences {
+	return &domain.DocumentPreferences{
+		UserID:     row.UserID,
+		DocumentID: row.DocumentID,
+		FontSize:   row.FontSize,
+		FontFamily: nonEmpty(row.FontFamily),
+		Theme:      nonEmpty(row.Theme),
+		MarginSize: nonEmpty(row.MarginSize),
+		TextAlign:  nonEmpty(row.TextAlign),
+		UpdatedAt:  row.UpdatedAt.Time,
+	}
+}
+
+// readingPositionRow is a row of the reading_positions table.
+type readingPositionRow struct {
+	UserID     string          `json:"user_id"`
+	DocumentID string          `json:"document_id"`
+	Progress   float32         `json:"progress"`
+	PageNumber int             `json:"page_number"`
+	CharOffset *int            `json:"char_offset"`
+	Locator    json.RawMessage `json:"locator"`
+	UpdatedAt  dbTime          `json:"updated_at"`
+}
+
+func (row *readingPositionRow) toDomain() *domain.ReadingPosition {
+	return &domain.ReadingPosition{
+		UserID:     row.UserID,
+		DocumentID: row.DocumentID,
+		Progress:   row.Progress,
+		PageNumber: row.PageNumber,
+		CharOffset: row.CharOffset,
+		Locator:    decodeLocator(row.Locator),
+		UpdatedAt:  row.UpdatedAt.Time,
+	}
+}
+
+// positionSampleRow is a row of the reading_position_history table.
+type positionSampleRow struct {
+	DocumentID string  `json:"document_id"`
+	Progress   float32 `json:"progress"`
+	PageNumber int     `json:"page_number"`
+	CharOffset *int    `json:"char_offset"`
+	RecordedAt dbTime  `json:"recorded_at"`
+}
+
+func (row *positionSampleRow) toDomain() *domain.PositionSample {
+	return &domain.PositionSample{
+		DocumentID: row.DocumentID,
+		Progress:   row.Progress,
+		PageNumber: row.PageNumber,
+		CharOffset: row.CharOffset,
+		RecordedAt: row.RecordedAt.Time,
+	}
+}
+
+// highlightRow is a row of the highlights table.
+type highlightRow struct {
+	ID             string          `json:"id"`
+	UserID         string          `json:"user_id"`
+	DocumentID     string          `json:"document_id"`
+	Quote          string          `json:"quote"`
+	Note           string          `json:"note"`
+	PageNumber     *int            `json:"page_number"`
+	Progress       *float32        `json:"progress"`
+	Locator        json.RawMessage `json:"locator"`
+	ContextBefore  string          `json:"context_before"`
+	ContextAfter   string          `json:"context_after"`
+	ContentVersion int             `json:"content_version"`
+	Orphaned       bool            `json:"orphaned"`
+	IsFavorite     bool            `json:"is_favorite"`
+	ReviewCount    int             `json:"review_count"`
+	ReviewInterval int             `json:"review_interval_days"`
+	ReviewDueAt    dbTime          `json:"review_due_at"`
+	ReviewedAt     dbTime          `json:"reviewed_at"`
+	CreatedAt      dbTime          `json:"created_at"`
+}
+
+func (row *highlightRow) toDomain() *domain.Highlight {
+	return &domain.Highlight{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		DocumentID:     row.DocumentID,
+		Quote:          row.Quote,
+		Note:           row.Note,
+		PageNumber:     row.PageNumber,
+		Progress:       row.Progress,
+		Locator:        decodeLocator(row.Locator),
+		ContextBefore:  row.ContextBefore,
+		ContextAfter:   row.ContextAfter,
+		ContentVersion: row.ContentVersion,
+		Orphaned:       row.Orphaned,
+		IsFavorite:     row.IsFavorite,
+		ReviewCount:    row.ReviewCount,
+		ReviewInterval: row.ReviewInterval,
+		ReviewDueAt:    row.ReviewDueAt.ptr(),
+		ReviewedAt:     row.ReviewedAt.ptr(),
+		CreatedAt:      row.CreatedAt.Time,
+	}
+}
+
+// deviceRow is a row of the devices table.
+type deviceRow struct {
+	UserID    string `json:"user_id"`
+	Token     string `json:"token"`
+	Platform  string `json:"platform"`
+	CreatedAt dbTime `json:"created_at"`
+	UpdatedAt dbTime `json:"updated_at"`
+}
+
+func (row *deviceRow) toDomain() *domain.Device {
+	return &domain.Device{
+		UserID:    row.UserID,
+		Token:     row.Token,
+		Platform:  row.Platform,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+}
+
+// cloudConnectionRow is a row of the cloud_connections table.
+type cloudConnectionRow struct {
+	UserID       string `json:"user_id"`
+	Provider     string `json:"provider"`
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresAt    dbTime `json:"expires_at"`
+	CreatedAt    dbTime `json:"created_at"`
+	UpdatedAt    dbTime `json:"updated_at"`
+}
+
+func (row *cloudConnectionRow) toDomain() *domain.CloudConnection {
+	return &domain.CloudConnection{
+		UserID:   row.UserID,
+		Provider: row.Provider,
+		Token: domain.CloudToken{
+			AccessToken:  row.AccessToken,
+			RefreshToken: row.RefreshToken,
+			Expiry:       row.ExpiresAt.Time,
+		},
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+}
+
+// tagRow is a row of the user_tags table.
+type tagRow struct {
+	ID     string `json:"id"`
+	UserID string `json:"user_id"`
+	Name   string `json:"name"`
+}
+
+// documentTagRow is a row of the document_tags join table.
+type documentTagRow struct {
+	DocumentID string `json:"document_id"`
+	TagID      string `json:"tag_id"`
+}
+
+// documentFavoriteRow is a row of the document_favorites join table.
+type documentFavoriteRow struct {
+	UserID     string `json:"user_id"`
+	DocumentID string `json:"document_id"`
+}
+
+// documentVersionRow is a row of the document_versions table.
+type documentVersionRow struct {
+	ID         string          `json:"id"`
+	DocumentID string          `json:"document_id"`
+	UserID     string          `json:"user_id"`
+	Version    int             `json:"version"`
+	Title      string          `json:"title"`
+	Author     *string         `json:"author"`
+	Content    json.RawMessage `json:"content"`
+	Metadata   json.RawMessage `json:"metadata"`
+	Reason     string          `json:"reason"`
+	CreatedAt  dbTime          `json:"created_at"`
+}
+
+func (row *documentVersionRow) toDomain() (*domain.DocumentVersion, error) {
+	version := &domain.DocumentVersion{
+		ID:         row.ID,
+		DocumentID: row.DocumentID,
+		UserID:     row.UserID,
+		Version:    row.Version,
+		Title:      row.Title,
+		Author:     nonEmpty(row.Author),
+		Reason:     row.Reason,
+		CreatedAt:  row.CreatedAt.Time,
+	}
+
+	content, err := decodeJSONB(row.Content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode content: %w", err)
+	}
+	version.Content = content
+
+	metadata, err := decodeJSONB(row.Metadata)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	if metadata != nil {
+		if err := json.Unmarshal(metadata, &version.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+	}
+
+	return version, nil
+}
+
+// fontRow is a row of the user_fonts table.
+type fontRow struct {
+	ID        string `json:"id"`
+	UserID    string `json:"user_id"`
+	Name      string `json:"name"`
+	Format    string `json:"format"`
+	SizeBytes int64  `json:"size_bytes"`
+	Path      string `json:"path"`
+	CreatedAt dbTime `json:"created_at"`
+}
+
+func (row *fontRow) toDomain() *domain.Font {
+	return &domain.Font{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Name:      row.Name,
+		Format:    row.Format,
+		SizeBytes: row.SizeBytes,
+		Path:      row.Path,
+		CreatedAt: row.CreatedAt.Time,
+	}
+}
+
+// themeRow is a row of the user_themes table.
+type themeRow struct {
+	ID              string  `json:"id"`
+	UserID          string  `json:"user_id"`
+	Name            string  `json:"name"`
+	BackgroundColor string  `json:"background_color"`
+	TextColor       string  `json:"text_color"`
+	AccentColor     string  `json:"accent_color"`
+	LineHeight      float64 `json:"line_height"`
+	FontFamily      *string `json:"font_family"`
+	CreatedAt       dbTime  `json:"created_at"`
+	UpdatedAt       dbTime  `json:"updated_at"`
+}
+
+func (row *themeRow) toDomain() *domain.Theme {
+	theme := &domain.Theme{
+		ID:              row.ID,
+		UserID:          row.UserID,
+		Name:            row.Name,
+		BackgroundColor: row.BackgroundColor,
+		TextColor:       row.TextColor,
+		AccentColor:     row.AccentColor,
+		LineHeight:      row.LineHeight,
+		CreatedAt:       row.CreatedAt.Time,
+		UpdatedAt:       row.UpdatedAt.Time,
+	}
+	if row.FontFamily != nil {
+		theme.FontFamily = *row.FontFamily
+	}
+	return theme
+}
+
+// savedSearchRow is a row of the saved_searches table.
+type savedSearchRow struct {
+	ID        string          `json:"id"`
+	UserID    string          `json:"user_id"`
+	Name      string          `json:"name"`
+	Query     string          `json:"query"`
+	Filters   json.RawMessage `json:"filters"`
+	CreatedAt dbTime          `json:"created_at"`
+	UpdatedAt dbTime          `json:"updated_at"`
+}
+
+// toDomain converts the row. Filters that no longer decode are dropped rather than
+// failing the read; the query still selects the collection.
+func (row *savedSearchRow) toDomain() *domain.SavedSearch {
+	search := &domain.SavedSearch{
+		ID:        row.ID,
+		UserID:    row.UserID,
+		Name:      row.Name,
+		Query:     row.Query,
+		CreatedAt: row.CreatedAt.Time,
+		UpdatedAt: row.UpdatedAt.Time,
+	}
+	if raw, err := decodeJSONB(row.Filters); err == nil && raw != nil {
+		var filters domain.SavedSearchFilters
+		if json.Unmarshal(raw, &filters) == nil {
+			search.Filters = filters
+		}
+	}
+	return search
+}
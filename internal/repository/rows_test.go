@@ -0,0 +1,151 @@
+package repository
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDBTime_UnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+	}{
+		{"RFC3339 with offset", `"2024-03-01T10:20:30+00:00"`, time.Date(2024, 3, 1, 10, 20, 30, 0, time.UTC)},
+		{"Fractional seconds", `"2024-03-01T10:20:30.123456+00:00"`, time.Date(2024, 3, 1, 10, 20, 30, 123456000, time.UTC)},
+		{"Without timezone", `"2024-03-01T10:20:30.5"`, time.Date(2024, 3, 1, 10, 20, 30, 500000000, time.UTC)},
+		{"Null", `null`, time.Time{}},
+		{"Empty string", `""`, time.Time{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got dbTime
+			if err := json.Unmarshal([]byte(tt.input), &got); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !got.Equal(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got.Time)
+			}
+		})
+	}
+
+	var invalid dbTime
+	if err := json.Unmarshal([]byte(`"yesterday"`), &invalid); err == nil {
+		t.Fatalf("expected error for unsupported timestamp")
+	}
+}
+
+func TestDocumentRow_ToDomain(t *testing.T) {
+	data := []byte(`[{
+		"id": "doc-1",
+		"user_id": "user-1",
+		"title": "Title",
+		"author": "",
+		"description": null,
+		"content": "[{\"type\":\"paragraph\",\"content\":\"hi\"}]",
+		"metadata": {"page_count": 3, "file_size": 2048, "format": "pdf"},
+		"created_at": "2024-01-02T03:04:05+00:00",
+		"updated_at": "2024-01-03T03:04:05.678+00:00"
+	}]`)
+
+	var rows []documentRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	doc, err := rows[0].toDomain()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if doc.Author != nil {
+		t.Fatalf("expected empty author to map to nil, got %q", *doc.Author)
+	}
+	if string(doc.Content) != `[{"type":"paragraph","content":"hi"}]` {
+		t.Fatalf("unexpected content: %s", doc.Content)
+	}
+	if doc.Metadata.PageCount != 3 || doc.Metadata.FileSize != 2048 || doc.Metadata.Format != "pdf" {
+		t.Fatalf("unexpected metadata: %+v", doc.Metadata)
+	}
+	if doc.CreatedAt.IsZero() || doc.UpdatedAt.IsZero() {
+		t.Fatalf("expected timestamps to be parsed, got created=%v updated=%v", doc.CreatedAt, doc.UpdatedAt)
+	}
+}
+
+func TestDocumentRow_ToDomain_MissingContent(t *testing.T) {
+	row := documentRow{ID: "doc-1", UserID: "user-1", Title: "Title"}
+	doc, err := row.toDomain()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(doc.Content) != "[]" {
+		t.Fatalf("expected empty content array, got %s", doc.Content)
+	}
+}
+
+func TestReadingPositionRow_ToDomain_KeepsUpdatedAt(t *testing.T) {
+	var row readingPositionRow
+	data := []byte(`{"user_id":"u","document_id":"d","progress":0.25,"page_number":4,"updated_at":"2024-05-06T07:08:09+00:00"}`)
+	if err := json.Unmarshal(data, &row); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pos := row.toDomain()
+	want := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	if !pos.UpdatedAt.Equal(want) {
+		t.Fatalf("expected updated_at %v, got %v", want, pos.UpdatedAt)
+	}
+	if pos.Progress != 0.25 || pos.PageNumber != 4 {
+		t.Fatalf("unexpected position: %+v", pos)
+	}
+}
+
+func TestDocumentVersionRow_ToDomain_ListColumns(t *testing.T) {
+	// Listing selects every column except content.
+	data := []byte(`[{
+		"id": "ver-1",
+		"document_id": "doc-1",
+		"user_id": "user-1",
+		"version": 4,
+		"title": "Title",
+		"author": "",
+		"metadata": "{\"page_count\": 3}",
+		"reason": "update",
+		"created_at": "2024-01-02T03:04:05+00:00"
+	}]`)
+
+	var rows []documentVersionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	version, err := rows[0].toDomain()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if version.Version != 4 || version.Reason != "update" || version.Metadata.PageCount != 3 {
+		t.Fatalf("unexpected version: %+v", version)
+	}
+	if version.Author != nil || version.Content != nil {
+		t.Fatalf("expected empty author and content, got %v / %s", version.Author, version.Content)
+	}
+}
+
+func TestMapToPreferences_BackfillsLayoutDefaults(t *testing.T) {
+	// Rows written before the layout columns existed.
+	data := []byte(`[{"user_id": "user-1", "font_size": 18, "font_family": "serif", "theme": "dark", "brightness": null}]`)
+
+	var rows []userPreferencesRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	prefs := (&UserPreferencesRepository{}).mapToPreferences(&rows[0])
+
+	if prefs.FontSize != 18 || prefs.Theme != "dark" {
+		t.Fatalf("expected stored values kept, got %+v", prefs)
+	}
+	if prefs.MarginSize != "normal" || prefs.TextAlign != "left" || prefs.ReadingMode != "paginated" ||
+		prefs.PageTurnAnimation != "slide" || prefs.Brightness != 1 {
+		t.Fatalf("expected layout defaults, got %+v", prefs)
+	}
+}
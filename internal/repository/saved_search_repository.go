@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// SavedSearchRepository implements the domain.SavedSearchRepository interface using
+// Supabase. Saved searches live in the saved_searches table, unique on (user_id, name).
+type SavedSearchRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewSavedSearchRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.SavedSearchRepository {
+	return &SavedSearchRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func savedSearchValues(search *domain.SavedSearch) map[string]interface{} {
+	return map[string]interface{}{
+		"name":    sanitizeText(search.Name),
+		"query":   sanitizeText(search.Query),
+		"filters": search.Filters,
+	}
+}
+
+func (r *SavedSearchRepository) Create(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	row := savedSearchValues(search)
+	row["user_id"] = principal.UserID
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("saved_searches").
+		Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create saved search: %w", err)
+	}
+
+	var rows []savedSearchRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("failed to create saved search: empty response")
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *SavedSearchRepository) Update(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	row := savedSearchValues(search)
+	row["updated_at"] = time.Now().UTC()
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("saved_searches").
+		Update(row, "representation", "").
+		Eq("id", search.ID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update saved search: %w", err)
+	}
+
+	var rows []savedSearchRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *SavedSearchRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.SavedSearch, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("saved_searches").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Order("name", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved searches: %w", err)
+	}
+
+	var rows []savedSearchRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	out := make([]*domain.SavedSearch, 0, len(rows))
+	for i := range rows {
+		out = append(out, rows[i].toDomain())
+	}
+	return out, nil
+}
+
+func (r *SavedSearchRepository) Get(ctx context.Context, principal domain.Principal, searchID string) (*domain.SavedSearch, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("saved_searches").
+		Select("*", "", false).
+		Eq("id", searchID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get saved search: %w", err)
+	}
+
+	var rows []savedSearchRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *SavedSearchRepository) Delete(ctx context.Context, principal domain.Principal, searchID string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("saved_searches").
+		Delete("", "").
+		Eq("id", searchID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to delete saved search: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
+)
+
+// ThemeRepository implements the domain.ThemeRepository interface using Supabase.
+// Custom themes live in the user_themes table, unique on (user_id, name).
+type ThemeRepository struct {
+	supabaseClient domain.SupabaseClient
+	logger         domain.Logger
+}
+
+func NewThemeRepository(supabaseClient domain.SupabaseClient, logger domain.Logger) domain.ThemeRepository {
+	return &ThemeRepository{
+		supabaseClient: supabaseClient,
+		logger:         logger,
+	}
+}
+
+func themeValues(theme *domain.Theme) map[string]interface{} {
+	return map[string]interface{}{
+		"name":             sanitizeText(theme.Name),
+		"background_color": theme.BackgroundColor,
+		"text_color":       theme.TextColor,
+		"accent_color":     theme.AccentColor,
+		"line_height":      theme.LineHeight,
+		"font_family":      nonEmpty(&theme.FontFamily),
+	}
+}
+
+func (r *ThemeRepository) Create(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	row := themeValues(theme)
+	row["user_id"] = principal.UserID
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_themes").
+		Insert(row, false, "", "representation", ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create theme: %w", err)
+	}
+
+	var rows []themeRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("failed to create theme: empty response")
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *ThemeRepository) Update(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	row := themeValues(theme)
+	row["updated_at"] = time.Now().UTC()
+
+	data, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_themes").
+		Update(row, "representation", "").
+		Eq("id", theme.ID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to update theme: %w", err)
+	}
+
+	var rows []themeRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrThemeNotFound
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *ThemeRepository) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Theme, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_themes").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Order("name", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list themes: %w", err)
+	}
+
+	var rows []themeRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	out := make([]*domain.Theme, 0, len(rows))
+	for i := range rows {
+		out = append(out, rows[i].toDomain())
+	}
+	return out, nil
+}
+
+func (r *ThemeRepository) Get(ctx context.Context, principal domain.Principal, themeID string) (*domain.Theme, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_themes").
+		Select("*", "", false).
+		Eq("id", themeID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get theme: %w", err)
+	}
+
+	var rows []themeRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, domain.ErrThemeNotFound
+	}
+
+	return rows[0].toDomain(), nil
+}
+
+func (r *ThemeRepository) Delete(ctx context.Context, principal domain.Principal, themeID string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_themes").
+		Delete("", "").
+		Eq("id", themeID).
+		Eq("user_id", principal.UserID))
+	if err != nil {
+		return fmt.Errorf("failed to delete theme: %w", err)
+	}
+	return nil
+}
@@ -1,11 +1,14 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"time"
 
 	"pdf-text-reader/internal/domain"
+
+	"github.com/supabase-community/postgrest-go"
 )
 
 // UserPreferencesRepository implements the domain.UserPreferencesRepository interface
@@ -25,9 +28,9 @@ func NewUserPreferencesRepository(supabaseClient domain.SupabaseClient, logger d
 
 // GetPreferences retrieves user preferences from Supabase
 
-func (r *UserPreferencesRepository) GetPreferences(userID string, token string) (*domain.UserPreferences, error) {
+func (r *UserPreferencesRepository) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -35,50 +38,37 @@ func (r *UserPreferencesRepository) GetPreferences(userID string, token string)
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("user_preferences").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_preferences").
 		Select("*", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get preferences: %w", err)
 	}
 
-	var prefsData []map[string]interface{}
-	if err := json.Unmarshal(data, &prefsData); err != nil {
+	var rows []userPreferencesRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
 	var prefs *domain.UserPreferences
-	if len(prefsData) == 0 {
+	if len(rows) == 0 {
 		// Return default preferences if none exist
-		prefs = &domain.UserPreferences{
-			UserID:            userID,
-			FontSize:          16,
-			FontFamily:        "system-ui",
-			Theme:             "light",
-			SubscriptionPlan:  "free",
-			StorageLimitBytes: 15 * 1024 * 1024,
-			Tags:              []string{},
-		}
+		prefs = domain.DefaultUserPreferences(principal.UserID)
 	} else {
-		prefs, err = r.mapToPreferences(prefsData[0])
-		if err != nil {
-			return nil, err
-		}
+		prefs = r.mapToPreferences(&rows[0])
 	}
 
 	// Fetch tags from user_tags table
-	tagsData, _, err := client.From("user_tags").
+	tagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Select("name", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err == nil {
-		var tagsList []map[string]interface{}
-		if err := json.Unmarshal(tagsData, &tagsList); err == nil {
-			tags := make([]string, 0, len(tagsList))
-			for _, tagData := range tagsList {
-				if tagName := getString(tagData, "name"); tagName != "" {
-					tags = append(tags, tagName)
+		var tagRows []tagRow
+		if err := json.Unmarshal(tagsData, &tagRows); err == nil {
+			tags := make([]string, 0, len(tagRows))
+			for _, tag := range tagRows {
+				if tag.Name != "" {
+					tags = append(tags, tag.Name)
 				}
 			}
 			prefs.Tags = tags
@@ -89,9 +79,9 @@ func (r *UserPreferencesRepository) GetPreferences(userID string, token string)
 }
 
 // UpdatePreferences updates or creates user preferences in Supabase
-func (r *UserPreferencesRepository) UpdatePreferences(prefs *domain.UserPreferences, token string) error {
+func (r *UserPreferencesRepository) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -101,45 +91,68 @@ func (r *UserPreferencesRepository) UpdatePreferences(prefs *domain.UserPreferen
 
 	// Update user_preferences (without tags - tags are in separate table)
 	data := map[string]interface{}{
-		"user_id":             prefs.UserID,
+		"user_id":             principal.UserID,
 		"font_size":           prefs.FontSize,
 		"font_family":         prefs.FontFamily,
 		"theme":               prefs.Theme,
+		"highlight_color":     prefs.HighlightColor,
+		"margin_size":         prefs.MarginSize,
+		"text_align":          prefs.TextAlign,
+		"hyphenation":         prefs.Hyphenation,
+		"reading_mode":        prefs.ReadingMode,
+		"page_turn_animation": prefs.PageTurnAnimation,
+		"brightness":          prefs.Brightness,
 		"subscription_plan":   prefs.SubscriptionPlan,
 		"storage_limit_bytes": prefs.StorageLimitBytes,
+		"weekly_digest":       prefs.WeeklyDigest,
 		// Don't send updated_at - the database trigger will handle it
 	}
 
-	// Use upsert to insert or update
-	_, _, err = client.From("user_preferences").
-		Upsert(data, "", "", "").
-		Execute()
-	if err != nil {
-		return fmt.Errorf("failed to update preferences: %w", err)
+	if unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(ctx); ok {
+		// A conditional update only applies to the row the client read, so it cannot
+		// insert; a missing row counts as stale as well.
+		updated, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_preferences").
+			Update(data, "representation", "").
+			Eq("user_id", principal.UserID).
+			Eq("updated_at", unmodifiedSince.Format(time.RFC3339Nano)))
+		if err != nil {
+			return fmt.Errorf("failed to update preferences: %w", err)
+		}
+		var rows []userPreferencesRow
+		if err := json.Unmarshal(updated, &rows); err != nil {
+			return fmt.Errorf("failed to unmarshal response: %w", err)
+		}
+		if len(rows) == 0 {
+			return domain.ErrStaleUpdate
+		}
+	} else {
+		// Use upsert to insert or update
+		_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_preferences").
+			Upsert(data, "", "", ""))
+		if err != nil {
+			return fmt.Errorf("failed to update preferences: %w", err)
+		}
 	}
 
 	// Update tags in user_tags table
 	// First, get existing tags
-	existingTagsData, _, err := client.From("user_tags").
+	existingTagsData, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 		Select("id,name", "", false).
-		Eq("user_id", prefs.UserID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return fmt.Errorf("failed to get existing tags: %w", err)
 	}
 
-	var existingTags []map[string]interface{}
+	var existingTags []tagRow
 	if err := json.Unmarshal(existingTagsData, &existingTags); err != nil {
 		return fmt.Errorf("failed to unmarshal existing tags: %w", err)
 	}
 
 	// Create a map of existing tag names
 	existingTagMap := make(map[string]string) // name -> id
-	for _, tagData := range existingTags {
-		tagName := getString(tagData, "name")
-		tagID := getString(tagData, "id")
-		if tagName != "" && tagID != "" {
-			existingTagMap[tagName] = tagID
+	for _, tag := range existingTags {
+		if tag.Name != "" && tag.ID != "" {
+			existingTagMap[tag.Name] = tag.ID
 		}
 	}
 
@@ -156,19 +169,17 @@ func (r *UserPreferencesRepository) UpdatePreferences(prefs *domain.UserPreferen
 		if !newTagSet[tagName] {
 			// First, delete all document_tag relationships for this tag
 			// (CASCADE should handle this, but we'll do it explicitly to be safe)
-			_, _, err := client.From("document_tags").
+			_, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_tags").
 				Delete("", "").
-				Eq("tag_id", tagID).
-				Execute()
+				Eq("tag_id", tagID))
 			if err != nil {
 				r.logger.Warn("Failed to delete document_tag relationships", "error", err, "tag_id", tagID)
 			}
 
 			// Then delete the tag itself
-			_, _, err = client.From("user_tags").
+			_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_tags").
 				Delete("", "").
-				Eq("id", tagID).
-				Execute()
+				Eq("id", tagID))
 			if err != nil {
 				r.logger.Warn("Failed to delete tag", "error", err, "tag_id", tagID, "tag_name", tagName)
 			}
@@ -182,12 +193,11 @@ func (r *UserPreferencesRepository) UpdatePreferences(prefs *domain.UserPreferen
 		}
 		if _, exists := existingTagMap[tagName]; !exists {
 			tagData := map[string]interface{}{
-				"user_id": prefs.UserID,
+				"user_id": principal.UserID,
 				"name":    tagName,
 			}
-			_, _, err := client.From("user_tags").
-				Insert(tagData, false, "", "", "").
-				Execute()
+			_, err := executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_tags").
+				Insert(tagData, false, "", "", ""))
 			if err != nil {
 				r.logger.Warn("Failed to insert tag", "error", err, "tag_name", tagName)
 				// Continue with other tags even if one fails
@@ -195,14 +205,14 @@ func (r *UserPreferencesRepository) UpdatePreferences(prefs *domain.UserPreferen
 		}
 	}
 
-	r.logger.Info("Preferences updated successfully", "user_id", prefs.UserID)
+	r.logger.Info("Preferences updated successfully", "user_id", principal.UserID)
 	return nil
 }
 
 // GetReadingPosition retrieves reading position for a document from Supabase
-func (r *UserPreferencesRepository) GetReadingPosition(userID, documentID string, token string) (*domain.ReadingPosition, error) {
+func (r *UserPreferencesRepository) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -210,24 +220,23 @@ func (r *UserPreferencesRepository) GetReadingPosition(userID, documentID string
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("reading_positions").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("reading_positions").
 		Select("*", "", false).
-		Eq("user_id", userID).
-		Eq("document_id", documentID).
-		Execute()
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reading position: %w", err)
 	}
 
-	var positionsData []map[string]interface{}
-	if err := json.Unmarshal(data, &positionsData); err != nil {
+	var rows []readingPositionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	if len(positionsData) == 0 {
+	if len(rows) == 0 {
 		// Return default position if none exists
 		return &domain.ReadingPosition{
-			UserID:     userID,
+			UserID:     principal.UserID,
 			DocumentID: documentID,
 			Progress:   0.0,
 			PageNumber: 1,
@@ -235,13 +244,13 @@ func (r *UserPreferencesRepository) GetReadingPosition(userID, documentID string
 		}, nil
 	}
 
-	return r.mapToReadingPosition(positionsData[0])
+	return rows[0].toDomain(), nil
 }
 
 // GetAllReadingPositions retrieves all reading positions for a user from Supabase
-func (r *UserPreferencesRepository) GetAllReadingPositions(userID string, token string) (map[string]*domain.ReadingPosition, error) {
+func (r *UserPreferencesRepository) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -249,36 +258,63 @@ func (r *UserPreferencesRepository) GetAllReadingPositions(userID string, token
 		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("reading_positions").
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("reading_positions").
 		Select("*", "", false).
-		Eq("user_id", userID).
-		Execute()
+		Eq("user_id", principal.UserID))
 	if err != nil {
 		return nil, fmt.Errorf("failed to get reading positions: %w", err)
 	}
 
-	var positionsData []map[string]interface{}
-	if err := json.Unmarshal(data, &positionsData); err != nil {
+	var rows []readingPositionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	positionsMap := make(map[string]*domain.ReadingPosition)
-	for _, posData := range positionsData {
-		position, err := r.mapToReadingPosition(posData)
-		if err != nil {
-			r.logger.Warn("Failed to map reading position", "error", err)
-			continue
-		}
+	positionsMap := make(map[string]*domain.ReadingPosition, len(rows))
+	for i := range rows {
+		position := rows[i].toDomain()
 		positionsMap[position.DocumentID] = position
 	}
 
 	return positionsMap, nil
 }
 
+// GetReadingPositions retrieves the saved positions of the given documents with one
+// IN query
+func (r *UserPreferencesRepository) GetReadingPositions(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]*domain.ReadingPosition, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("reading_positions").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		In("document_id", documentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading positions: %w", err)
+	}
+
+	var rows []readingPositionRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	positions := make(map[string]*domain.ReadingPosition, len(rows))
+	for i := range rows {
+		position := rows[i].toDomain()
+		positions[position.DocumentID] = position
+	}
+	return positions, nil
+}
+
 // UpdateReadingPosition updates or creates reading position in Supabase
-func (r *UserPreferencesRepository) UpdateReadingPosition(position *domain.ReadingPosition, token string) error {
+func (r *UserPreferencesRepository) UpdateReadingPosition(ctx context.Context, principal domain.Principal, position *domain.ReadingPosition) error {
 	// Use client with token for RLS policies
-	client, err := r.supabaseClient.GetClientWithToken(token)
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
 	if err != nil {
 		return fmt.Errorf("failed to get client with token: %w", err)
 	}
@@ -287,24 +323,25 @@ func (r *UserPreferencesRepository) UpdateReadingPosition(position *domain.Readi
 	}
 
 	data := map[string]interface{}{
-		"user_id":     position.UserID,
+		"user_id":     principal.UserID,
 		"document_id": position.DocumentID,
 		"progress":    position.Progress,
 		"page_number": position.PageNumber,
+		"char_offset": position.CharOffset,
+		"locator":     position.Locator,
 		"updated_at":  position.UpdatedAt,
 		// Don't send updated_at - the database trigger will handle it
 	}
 
 	// Use upsert to insert or update
-	_, _, err = client.From("reading_positions").
-		Upsert(data, "", "", "").
-		Execute()
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("reading_positions").
+		Upsert(data, "", "", ""))
 	if err != nil {
 		return fmt.Errorf("failed to update reading position: %w", err)
 	}
 
 	r.logger.Info("Reading position updated successfully",
-		"user_id", position.UserID,
+		"user_id", principal.UserID,
 		"document_id", position.DocumentID,
 		"progress", position.Progress,
 		"page_number", position.PageNumber,
@@ -312,112 +349,194 @@ func (r *UserPreferencesRepository) UpdateReadingPosition(position *domain.Readi
 	return nil
 }
 
-// mapToPreferences converts a map to a UserPreferences struct
-func (r *UserPreferencesRepository) mapToPreferences(data map[string]interface{}) (*domain.UserPreferences, error) {
-	prefs := &domain.UserPreferences{
-		UserID:            getString(data, "user_id"),
-		FontSize:          getInt(data, "font_size"),
-		FontFamily:        getString(data, "font_family"),
-		Theme:             getString(data, "theme"),
-		SubscriptionPlan:  getString(data, "subscription_plan"),
-		StorageLimitBytes: getInt64(data, "storage_limit_bytes"),
-		AccountDisabled:   getBool(data, "account_disabled"),
-		Tags:              []string{}, // Tags are loaded separately from user_tags table
-		UpdatedAt:         time.Now(),
-	}
-
-	// Backfill defaults for older rows.
-	if prefs.SubscriptionPlan == "" {
-		prefs.SubscriptionPlan = "free"
-	}
-	if prefs.StorageLimitBytes <= 0 {
-		// If storage_limit_bytes is missing, derive it from the plan so Pro users
-		// still get the correct quota.
-		prefs.StorageLimitBytes = domain.StorageLimitBytesForPlan(prefs.SubscriptionPlan)
-	}
-
-	// Parse updated_at if available
-	if updatedAtStr := getString(data, "updated_at"); updatedAtStr != "" {
-		if t, err := time.Parse(time.RFC3339, updatedAtStr); err == nil {
-			prefs.UpdatedAt = t
-		} else if t, err := time.Parse(time.RFC3339Nano, updatedAtStr); err == nil {
-			prefs.UpdatedAt = t
-		}
+// LatestPositionSample reads the document's most recent position sample, or nil
+func (r *UserPreferencesRepository) LatestPositionSample(ctx context.Context, principal domain.Principal, documentID string) (*domain.PositionSample, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
 	}
 
-	return prefs, nil
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("reading_position_history").
+		Select("document_id,progress,page_number,char_offset,recorded_at", "", false).
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID).
+		Order("recorded_at", &postgrest.OrderOpts{Ascending: false}).
+		Limit(1, ""))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position sample: %w", err)
+	}
+
+	var rows []positionSampleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+	return rows[0].toDomain(), nil
 }
 
-func getInt64(data map[string]interface{}, key string) int64 {
-	if val, ok := data[key]; ok && val != nil {
-		switch v := val.(type) {
-		case int:
-			return int64(v)
-		case int64:
-			return v
-		case float64:
-			return int64(v)
-		}
+// AddPositionSample appends a sample to the document's position history
+func (r *UserPreferencesRepository) AddPositionSample(ctx context.Context, principal domain.Principal, sample *domain.PositionSample) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	data := map[string]interface{}{
+		"user_id":     principal.UserID,
+		"document_id": sample.DocumentID,
+		"progress":    sample.Progress,
+		"page_number": sample.PageNumber,
+		"char_offset": sample.CharOffset,
+		"recorded_at": sample.RecordedAt,
+	}
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("reading_position_history").
+		Insert(data, false, "", "minimal", ""))
+	if err != nil {
+		return fmt.Errorf("failed to add position sample: %w", err)
+	}
+	return nil
+}
+
+// GetPositionHistory lists the document's position samples since a time, oldest first
+func (r *UserPreferencesRepository) GetPositionHistory(ctx context.Context, principal domain.Principal, documentID string, since time.Time) ([]*domain.PositionSample, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("reading_position_history").
+		Select("document_id,progress,page_number,char_offset,recorded_at", "", false).
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID).
+		Gte("recorded_at", since.UTC().Format(time.RFC3339Nano)).
+		Order("recorded_at", &postgrest.OrderOpts{Ascending: true}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get position history: %w", err)
+	}
+
+	var rows []positionSampleRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	return 0
+	samples := make([]*domain.PositionSample, 0, len(rows))
+	for i := range rows {
+		samples = append(samples, rows[i].toDomain())
+	}
+	return samples, nil
 }
 
-// mapToReadingPosition converts a map to a ReadingPosition struct
-func (r *UserPreferencesRepository) mapToReadingPosition(data map[string]interface{}) (*domain.ReadingPosition, error) {
-	position := &domain.ReadingPosition{
-		UserID:     getString(data, "user_id"),
-		DocumentID: getString(data, "document_id"),
-		Progress:   float32(getFloat64(data, "progress")),
-		PageNumber: getInt(data, "page_number"),
-		UpdatedAt:  time.Now(),
+// GetDocumentPreferences retrieves the user's overrides for one document from Supabase
+func (r *UserPreferencesRepository) GetDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentPreferences, error) {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return nil, fmt.Errorf("supabase client not initialized")
+	}
+
+	data, err := executeRead(ctx, r.supabaseClient.Guard(), client.From("document_preferences").
+		Select("*", "", false).
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get document preferences: %w", err)
+	}
+
+	var rows []documentPreferencesRow
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(rows) == 0 {
+		return nil, nil
 	}
 
-	return position, nil
+	return rows[0].toDomain(), nil
 }
 
-// Helper functions for type conversion
-func getInt(data map[string]interface{}, key string) int {
-	if val, ok := data[key]; ok && val != nil {
-		switch v := val.(type) {
-		case int:
-			return v
-		case int64:
-			return int(v)
-		case float64:
-			return int(v)
-		}
+// UpsertDocumentPreferences creates or replaces the user's overrides for a document
+func (r *UserPreferencesRepository) UpsertDocumentPreferences(ctx context.Context, principal domain.Principal, override *domain.DocumentPreferences) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
 	}
-	return 0
+
+	// Unset fields are written as null so a replaced override drops them.
+	data := map[string]interface{}{
+		"user_id":     principal.UserID,
+		"document_id": override.DocumentID,
+		"font_size":   override.FontSize,
+		"font_family": override.FontFamily,
+		"theme":       override.Theme,
+		"margin_size": override.MarginSize,
+		"text_align":  override.TextAlign,
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_preferences").
+		Upsert(data, "user_id,document_id", "", ""))
+	if err != nil {
+		return fmt.Errorf("failed to update document preferences: %w", err)
+	}
+	return nil
 }
 
-func getFloat64(data map[string]interface{}, key string) float64 {
-	if val, ok := data[key]; ok && val != nil {
-		switch v := val.(type) {
-		case float64:
-			return v
-		case int:
-			return float64(v)
-		case int64:
-			return float64(v)
-		}
+// DeleteDocumentPreferences removes the user's overrides for a document
+func (r *UserPreferencesRepository) DeleteDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
+	}
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("document_preferences").
+		Delete("", "").
+		Eq("user_id", principal.UserID).
+		Eq("document_id", documentID))
+	if err != nil {
+		return fmt.Errorf("failed to delete document preferences: %w", err)
 	}
-	return 0.0
+	return nil
 }
 
-func getBool(data map[string]interface{}, key string) bool {
-	if val, ok := data[key]; ok && val != nil {
-		switch v := val.(type) {
-		case bool:
-			return v
-		case string:
-			return v == "true" || v == "1"
-		case float64:
-			return v != 0
-		case int:
-			return v != 0
-		case int64:
-			return v != 0
-		}
+// SetAccountDisabled upserts the preferences row's account_disabled flag without touching other fields
+func (r *UserPreferencesRepository) SetAccountDisabled(ctx context.Context, principal domain.Principal, disabled bool) error {
+	client, err := r.supabaseClient.GetClientWithToken(principal.Token)
+	if err != nil {
+		return fmt.Errorf("failed to get client with token: %w", err)
 	}
-	return false
+	if client == nil {
+		return fmt.Errorf("supabase client not initialized")
+	}
+
+	data := map[string]interface{}{
+		"user_id":          principal.UserID,
+		"account_disabled": disabled,
+	}
+	_, err = executeWrite(ctx, r.supabaseClient.Guard(), client.From("user_preferences").
+		Upsert(data, "", "", ""))
+	if err != nil {
+		return fmt.Errorf("failed to update account status: %w", err)
+	}
+	return nil
+}
+
+// mapToPreferences converts a user_preferences row to a UserPreferences struct
+func (r *UserPreferencesRepository) mapToPreferences(row *userPreferencesRow) *domain.UserPreferences {
+	return row.toDomain()
 }
@@ -0,0 +1,153 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// DefaultAbuseLimits are generous enough for a person importing a library by hand
+// and stop scripts filling storage or guessing credentials.
+func DefaultAbuseLimits() map[domain.AbuseSignal]domain.AbuseLimit {
+	return map[domain.AbuseSignal]domain.AbuseLimit{
+		domain.AbuseSignalUpload:      {Max: 60, Window: time.Hour, Cooldown: time.Hour},
+		domain.AbuseSignalAuthFailure: {Max: 20, Window: 10 * time.Minute, Cooldown: 15 * time.Minute},
+	}
+}
+
+type abuseKey struct {
+	signal domain.AbuseSignal
+	key    string
+}
+
+// AbuseMonitor keeps the events of the last window and the throttles in memory, so
+// each replica enforces the limits on the requests it serves.
+type AbuseMonitor struct {
+	limits map[domain.AbuseSignal]domain.AbuseLimit
+	logger domain.Logger
+
+	mu        sync.Mutex
+	events    map[abuseKey][]time.Time
+	throttles map[abuseKey]domain.Throttle
+	lastSweep time.Time
+
+	now func() time.Time
+}
+
+// NewAbuseMonitor creates a monitor enforcing limits. Signals without a limit are
+// never throttled.
+func NewAbuseMonitor(limits map[domain.AbuseSignal]domain.AbuseLimit, logger domain.Logger) domain.AbuseMonitor {
+	return &AbuseMonitor{
+		limits:    limits,
+		logger:    logger,
+		events:    make(map[abuseKey][]time.Time),
+		throttles: make(map[abuseKey]domain.Throttle),
+		now:       time.Now,
+	}
+}
+
+func (m *AbuseMonitor) Record(signal domain.AbuseSignal, key string) time.Duration {
+	limit, ok := m.limits[signal]
+	if !ok {
+		return 0
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	m.sweep(now)
+	k := abuseKey{signal, key}
+	if throttle, ok := m.throttles[k]; ok && now.Before(throttle.Until) {
+		return throttle.Until.Sub(now)
+	}
+
+	events := append(recent(m.events[k], now.Add(-limit.Window)), now)
+	if len(events) <= limit.Max {
+		m.events[k] = events
+		return 0
+	}
+
+	delete(m.events, k)
+	throttle := domain.Throttle{Key: key, Signal: signal, Count: len(events), Since: now, Until: now.Add(limit.Cooldown)}
+	m.throttles[k] = throttle
+	m.logger.Warn("Throttling after too many requests", "key", key, "signal", signal, "count", len(events), "until", throttle.Until)
+	return limit.Cooldown
+}
+
+func (m *AbuseMonitor) Throttled(signal domain.AbuseSignal, key string) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	if throttle, ok := m.throttles[abuseKey{signal, key}]; ok && now.Before(throttle.Until) {
+		return throttle.Until.Sub(now)
+	}
+	return 0
+}
+
+func (m *AbuseMonitor) Throttles() []domain.Throttle {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := m.now()
+	throttles := make([]domain.Throttle, 0, len(m.throttles))
+	for _, throttle := range m.throttles {
+		if now.Before(throttle.Until) {
+			throttles = append(throttles, throttle)
+		}
+	}
+	sort.Slice(throttles, func(i, j int) bool { return throttles[i].Since.After(throttles[j].Since) })
+	return throttles
+}
+
+func (m *AbuseMonitor) Release(key string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	released := false
+	for k := range m.throttles {
+		if k.key == key {
+			delete(m.throttles, k)
+			released = true
+		}
+	}
+	for k := range m.events {
+		if k.key == key {
+			delete(m.events, k)
+		}
+	}
+	if released {
+		m.logger.Info("Throttle released", "key", key)
+	}
+	return released
+}
+
+// sweep drops expired throttles and counters that have gone quiet, at most once a
+// minute. The caller holds m.mu.
+func (m *AbuseMonitor) sweep(now time.Time) {
+	if now.Sub(m.lastSweep) < time.Minute {
+		return
+	}
+	m.lastSweep = now
+	for k, throttle := range m.throttles {
+		if !now.Before(throttle.Until) {
+			delete(m.throttles, k)
+		}
+	}
+	for k, events := range m.events {
+		if events = recent(events, now.Add(-m.limits[k.signal].Window)); len(events) == 0 {
+			delete(m.events, k)
+		} else {
+			m.events[k] = events
+		}
+	}
+}
+
+// recent returns the events after since; events are in order.
+func recent(events []time.Time, since time.Time) []time.Time {
+	i := sort.Search(len(events), func(i int) bool { return events[i].After(since) })
+	return events[i:]
+}
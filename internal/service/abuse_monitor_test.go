@@ -0,0 +1,80 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestAbuseMonitor_ThrottlesAfterLimit(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	m := NewAbuseMonitor(map[domain.AbuseSignal]domain.AbuseLimit{
+		domain.AbuseSignalUpload: {Max: 2, Window: time.Minute, Cooldown: 10 * time.Minute},
+	}, NewMockLogger()).(*AbuseMonitor)
+	m.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if wait := m.Record(domain.AbuseSignalUpload, "user:u1"); wait != 0 {
+			t.Fatalf("event %d throttled for %s", i+1, wait)
+		}
+	}
+	// Events that left the window no longer count.
+	now = now.Add(2 * time.Minute)
+	m.Record(domain.AbuseSignalUpload, "user:u1")
+	if wait := m.Record(domain.AbuseSignalUpload, "user:u1"); wait != 0 {
+		t.Fatalf("expected old events to expire, throttled for %s", wait)
+	}
+
+	if wait := m.Record(domain.AbuseSignalUpload, "user:u1"); wait != 10*time.Minute {
+		t.Fatalf("expected a 10m throttle, got %s", wait)
+	}
+	if wait := m.Throttled(domain.AbuseSignalUpload, "user:u1"); wait != 10*time.Minute {
+		t.Fatalf("Throttled = %s", wait)
+	}
+	if wait := m.Throttled(domain.AbuseSignalAuthFailure, "user:u1"); wait != 0 {
+		t.Fatalf("other signals should not be throttled, got %s", wait)
+	}
+	if wait := m.Record(domain.AbuseSignalUpload, "user:u2"); wait != 0 {
+		t.Fatalf("other keys should not be throttled, got %s", wait)
+	}
+	// Signals without a limit are never throttled.
+	for i := 0; i < 10; i++ {
+		if wait := m.Record(domain.AbuseSignalAuthFailure, "ip:1.2.3.4"); wait != 0 {
+			t.Fatalf("unlimited signal throttled for %s", wait)
+		}
+	}
+
+	throttles := m.Throttles()
+	if len(throttles) != 1 || throttles[0].Key != "user:u1" || throttles[0].Count != 3 {
+		t.Fatalf("throttles = %+v", throttles)
+	}
+
+	now = now.Add(4 * time.Minute)
+	if wait := m.Throttled(domain.AbuseSignalUpload, "user:u1"); wait != 6*time.Minute {
+		t.Fatalf("expected 6m left, got %s", wait)
+	}
+	now = now.Add(6 * time.Minute)
+	if wait := m.Record(domain.AbuseSignalUpload, "user:u1"); wait != 0 || len(m.Throttles()) != 0 {
+		t.Fatalf("expected the throttle to expire, got %s", wait)
+	}
+}
+
+func TestAbuseMonitor_Release(t *testing.T) {
+	m := NewAbuseMonitor(map[domain.AbuseSignal]domain.AbuseLimit{
+		domain.AbuseSignalAuthFailure: {Max: 0, Window: time.Minute, Cooldown: time.Hour},
+	}, NewMockLogger())
+
+	if wait := m.Record(domain.AbuseSignalAuthFailure, "ip:1.2.3.4"); wait == 0 {
+		t.Fatal("expected the first failure over a zero limit to throttle")
+	}
+	if !m.Release("ip:1.2.3.4") {
+		t.Fatal("expected Release to find the throttle")
+	}
+	if m.Throttled(domain.AbuseSignalAuthFailure, "ip:1.2.3.4") != 0 {
+		t.Fatal("expected the throttle to be lifted")
+	}
+	if m.Release("ip:1.2.3.4") {
+		t.Fatal("expected a second Release to find nothing")
+	}
+}
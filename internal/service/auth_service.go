@@ -1,7 +1,9 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -9,7 +11,12 @@ import (
 	"pdf-text-reader/internal/domain"
 )
 
-const accountDisabledCacheTTL = 30 * time.Second
+const (
+	accountDisabledCacheTTL = 30 * time.Second
+	// accountStatusTimeout bounds the account status lookup made on every request,
+	// retries included.
+	accountStatusTimeout = 5 * time.Second
+)
 
 type accountDisabledCacheEntry struct {
 	disabled  bool
@@ -37,10 +44,13 @@ func NewAuthService(
 }
 
 // ValidateToken validates a token and returns user info (for frontend validation)
-func (s *authService) ValidateToken(token string) (*domain.SupabaseUser, error) {
-	user, err := s.supabaseClient.ValidateToken(token)
+func (s *authService) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
+	user, err := s.supabaseClient.ValidateToken(ctx, token)
 	if err != nil {
 		s.logger.Error("Failed to validate token with Supabase", err)
+		if errors.Is(err, domain.ErrUpstreamUnavailable) {
+			return nil, err
+		}
 		return nil, fmt.Errorf("invalid token: %w", err)
 	}
 	return user, nil
@@ -48,7 +58,7 @@ func (s *authService) ValidateToken(token string) (*domain.SupabaseUser, error)
 
 // IsAccountDisabled checks the persisted flag in `user_preferences.account_disabled`.
 // If the user has no preferences row yet, it defaults to false.
-func (s *authService) IsAccountDisabled(userID string, token string) (bool, error) {
+func (s *authService) IsAccountDisabled(ctx context.Context, userID string, token string) (bool, error) {
 	now := time.Now()
 	s.accountDisabledCacheMu.RLock()
 	entry, ok := s.accountDisabledCache[userID]
@@ -65,32 +75,28 @@ func (s *authService) IsAccountDisabled(userID string, token string) (bool, erro
 		return false, fmt.Errorf("supabase client not initialized")
 	}
 
-	data, _, err := client.From("user_preferences").
-		Select("account_disabled", "", false).
-		Eq("user_id", userID).
-		Execute()
+	ctx, cancel := context.WithTimeout(ctx, accountStatusTimeout)
+	defer cancel()
+	var data []byte
+	err = s.supabaseClient.Guard().Do(ctx, func() error {
+		var err error
+		data, _, err = client.From("user_preferences").
+			Select("account_disabled", "", false).
+			Eq("user_id", userID).
+			Execute()
+		return err
+	})
 	if err != nil {
-		return false, fmt.Errorf("failed to get account status: %w", err)
+		return false, fmt.Errorf("failed to get account status: %w", domain.UpstreamFailure(s.supabaseClient.Guard(), err))
 	}
 
-	disabled := false
-	var rows []map[string]interface{}
+	var rows []struct {
+		AccountDisabled bool `json:"account_disabled"`
+	}
 	if err := json.Unmarshal(data, &rows); err != nil {
 		return false, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-	if len(rows) > 0 {
-		val, ok := rows[0]["account_disabled"]
-		if ok && val != nil {
-			switch v := val.(type) {
-			case bool:
-				disabled = v
-			case string:
-				disabled = v == "true" || v == "1"
-			case float64:
-				disabled = v != 0
-			}
-		}
-	}
+	disabled := len(rows) > 0 && rows[0].AccountDisabled
 
 	s.accountDisabledCacheMu.Lock()
 	s.accountDisabledCache[userID] = accountDisabledCacheEntry{disabled: disabled, expiresAt: now.Add(accountDisabledCacheTTL)}
@@ -1,11 +1,13 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
 
 	"github.com/supabase-community/supabase-go"
 	"pdf-text-reader/internal/domain"
+	"pdf-text-reader/pkg/resilience"
 )
 
 // MockSupabaseClient for testing
@@ -23,7 +25,7 @@ func (m *MockSupabaseClient) Initialize() error {
 	return nil
 }
 
-func (m *MockSupabaseClient) ValidateToken(token string) (*domain.SupabaseUser, error) {
+func (m *MockSupabaseClient) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
 	// Simple mock: if token is "valid-token", return a user
 	if token == "valid-token" {
 		return &domain.SupabaseUser{
@@ -49,6 +51,10 @@ func (m *MockSupabaseClient) GetClientWithToken(token string) (*supabase.Client,
 	return nil, nil // Mock implementation
 }
 
+func (m *MockSupabaseClient) Guard() *resilience.Executor {
+	return resilience.NewExecutor("mock-supabase", resilience.Policy{})
+}
+
 func TestAuthService_ValidateToken(t *testing.T) {
 	client := NewMockSupabaseClient()
 	logger := NewMockLogger()
@@ -56,7 +62,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 	service := NewAuthService(client, logger)
 
 	// Test valid token
-	user, err := service.ValidateToken("valid-token")
+	user, err := service.ValidateToken(context.Background(), "valid-token")
 	if err != nil {
 		t.Errorf("Expected no error for valid token, got %v", err)
 	}
@@ -70,7 +76,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 	}
 
 	// Test invalid token
-	_, err = service.ValidateToken("invalid-token")
+	_, err = service.ValidateToken(context.Background(), "invalid-token")
 	if err == nil {
 		t.Error("Expected error for invalid token")
 	}
@@ -81,7 +87,7 @@ func TestAuthService_ValidateToken(t *testing.T) {
 	}
 
 	// Test empty token
-	_, err = service.ValidateToken("")
+	_, err = service.ValidateToken(context.Background(), "")
 	if err == nil {
 		t.Error("Expected error for empty token")
 	}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type authorizationService struct {
+	logger domain.Logger
+}
+
+// NewAuthorizationService creates the central authorization service.
+func NewAuthorizationService(logger domain.Logger) domain.AuthorizationService {
+	return &authorizationService{logger: logger}
+}
+
+// DocumentRole resolves the principal's role on a document. Ownership wins over the
+// admin flag so admins keep full rights on their own documents. Documents cannot be
+// shared yet, so nobody is a collaborator.
+func (s *authorizationService) DocumentRole(ctx context.Context, principal domain.Principal, document *domain.Document) domain.Role {
+	if document == nil || principal.UserID == "" {
+		return domain.RoleNone
+	}
+	if document.UserID == principal.UserID {
+		return domain.RoleOwner
+	}
+	if principal.Admin {
+		return domain.RoleAdmin
+	}
+	return domain.RoleNone
+}
+
+// AuthorizeDocument returns domain.ErrAccessDenied unless the principal's role grants perm.
+func (s *authorizationService) AuthorizeDocument(ctx context.Context, principal domain.Principal, document *domain.Document, perm domain.Permission) error {
+	role := s.DocumentRole(ctx, principal, document)
+	if role.Can(perm) {
+		return nil
+	}
+
+	docID := ""
+	if document != nil {
+		docID = document.ID
+	}
+	s.logger.Warn("Access denied", "user_id", principal.UserID, "document_id", docID, "role", role, "permission", perm)
+	return domain.ErrAccessDenied
+}
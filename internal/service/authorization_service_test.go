@@ -0,0 +1,84 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestAuthorizationService_DocumentRole(t *testing.T) {
+	authz := NewAuthorizationService(NewMockLogger())
+	doc := &domain.Document{ID: "doc1", UserID: "user1"}
+
+	tests := []struct {
+		name      string
+		principal domain.Principal
+		document  *domain.Document
+		want      domain.Role
+	}{
+		{"owner", domain.Principal{UserID: "user1"}, doc, domain.RoleOwner},
+		{"admin owner", domain.Principal{UserID: "user1", Admin: true}, doc, domain.RoleOwner},
+		{"admin", domain.Principal{UserID: "user2", Admin: true}, doc, domain.RoleAdmin},
+		{"stranger", domain.Principal{UserID: "user2"}, doc, domain.RoleNone},
+		{"anonymous", domain.Principal{}, doc, domain.RoleNone},
+		{"nil document", domain.Principal{UserID: "user1"}, nil, domain.RoleNone},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := authz.DocumentRole(context.Background(), tt.principal, tt.document); got != tt.want {
+				t.Fatalf("expected role %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestAuthorizationService_AuthorizeDocument(t *testing.T) {
+	authz := NewAuthorizationService(NewMockLogger())
+	doc := &domain.Document{ID: "doc1", UserID: "user1"}
+
+	owner := domain.Principal{UserID: "user1"}
+	admin := domain.Principal{UserID: "admin", Admin: true}
+	stranger := domain.Principal{UserID: "user2"}
+
+	tests := []struct {
+		name      string
+		principal domain.Principal
+		perm      domain.Permission
+		allowed   bool
+	}{
+		{"owner read", owner, domain.PermissionRead, true},
+		{"owner annotate", owner, domain.PermissionAnnotate, true},
+		{"owner write", owner, domain.PermissionWrite, true},
+		{"owner delete", owner, domain.PermissionDelete, true},
+		{"admin read", admin, domain.PermissionRead, true},
+		{"admin annotate", admin, domain.PermissionAnnotate, false},
+		{"admin write", admin, domain.PermissionWrite, false},
+		{"admin delete", admin, domain.PermissionDelete, true},
+		{"stranger read", stranger, domain.PermissionRead, false},
+		{"stranger delete", stranger, domain.PermissionDelete, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := authz.AuthorizeDocument(context.Background(), tt.principal, doc, tt.perm)
+			if tt.allowed && err != nil {
+				t.Fatalf("expected access, got %v", err)
+			}
+			if !tt.allowed && !errors.Is(err, domain.ErrAccessDenied) {
+				t.Fatalf("expected ErrAccessDenied, got %v", err)
+			}
+		})
+	}
+}
+
+func TestRole_CollaboratorPermissions(t *testing.T) {
+	if !domain.RoleCollaborator.Can(domain.PermissionRead) || !domain.RoleCollaborator.Can(domain.PermissionAnnotate) {
+		t.Fatal("collaborator should be able to read and annotate")
+	}
+	if domain.RoleCollaborator.Can(domain.PermissionWrite) || domain.RoleCollaborator.Can(domain.PermissionDelete) {
+		t.Fatal("collaborator should not be able to write or delete")
+	}
+}
@@ -0,0 +1,315 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const (
+	// CalibreImportProvider is the provider recorded on Calibre import jobs.
+	CalibreImportProvider = "calibre"
+
+	maxCalibreOPFSize   = 1 << 20  // 1MB
+	maxCalibreCoverSize = 10 << 20 // 10MB
+)
+
+// calibreFormats are the book formats that can be imported, in order of preference
+// when Calibre keeps several formats of a book.
+var calibreFormats = []string{"epub", "pdf", "cbz", "txt"}
+
+// CalibreImportService imports a Calibre library from a zip of its folder. Calibre
+// writes a metadata.opf next to every book, mirroring metadata.db, so the catalogue
+// is read from those rather than from the SQLite database.
+type CalibreImportService struct {
+	documents *DocumentService
+	repo      domain.DocumentRepository
+	jobs      *ImportJobTracker
+	logger    domain.Logger
+}
+
+func NewCalibreImportService(
+	documents *DocumentService,
+	repo domain.DocumentRepository,
+	jobs *ImportJobTracker,
+	logger domain.Logger,
+) domain.CalibreImportService {
+	return &CalibreImportService{
+		documents: documents,
+		repo:      repo,
+		jobs:      jobs,
+		logger:    logger,
+	}
+}
+
+// calibreEntry is a book found in the export with the archive entries it imports.
+type calibreEntry struct {
+	book  domain.CalibreBook
+	file  *zip.File
+	cover *zip.File
+}
+
+func (s *CalibreImportService) Preview(ctx context.Context, principal domain.Principal, archive io.Reader) (*domain.CalibrePreview, error) {
+	upload, err := spoolUpload(archive, domain.MaxCalibreArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+	defer upload.Close()
+
+	entries, err := readCalibreLibrary(upload)
+	if err != nil {
+		return nil, err
+	}
+	newTags, err := s.newTags(ctx, principal, entries)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &domain.CalibrePreview{
+		Books:   make([]domain.CalibreBook, 0, len(entries)),
+		NewTags: newTags,
+	}
+	for _, entry := range entries {
+		preview.Books = append(preview.Books, entry.book)
+	}
+	return preview, nil
+}
+
+func (s *CalibreImportService) Import(ctx context.Context, principal domain.Principal, archive io.Reader) (*domain.ImportJob, error) {
+	upload, err := spoolUpload(archive, domain.MaxCalibreArchiveSize)
+	if err != nil {
+		return nil, err
+	}
+
+	entries, err := readCalibreLibrary(upload)
+	if err != nil {
+		upload.Close()
+		return nil, err
+	}
+	entries = slices.DeleteFunc(entries, func(entry calibreEntry) bool { return entry.book.Skipped != "" })
+	if len(entries) == 0 {
+		upload.Close()
+		return nil, domain.ValidationErrors{{Field: "file", Message: "the export contains no importable books"}}
+	}
+
+	files := make([]domain.ImportFile, len(entries))
+	for i, entry := range entries {
+		files[i] = domain.ImportFile{FileID: entry.book.Path, Name: entry.book.Title, Status: domain.ImportStatusPending}
+	}
+	job, snapshot := s.jobs.start(principal, CalibreImportProvider, files)
+
+	// The import outlives the request, so detach it from the request's cancellation.
+	go func() {
+		defer upload.Close()
+		s.runImport(context.WithoutCancel(ctx), principal, job, entries)
+	}()
+
+	return snapshot, nil
+}
+
+// runImport creates the library's tags, then imports the books one at a time. A book
+// that fails is recorded and the others still go ahead.
+func (s *CalibreImportService) runImport(ctx context.Context, principal domain.Principal, job *domain.ImportJob, entries []calibreEntry) {
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusRunning })
+
+	newTags, err := s.newTags(ctx, principal, entries)
+	if err != nil {
+		s.logger.Warn("Failed to list tags for Calibre import", "user_id", principal.UserID, "error", err)
+	}
+	for _, tag := range newTags {
+		if err := s.repo.CreateTag(ctx, principal, tag); err != nil {
+			s.logger.Warn("Failed to create Calibre tag", "user_id", principal.UserID, "tag", tag, "error", err)
+		}
+	}
+
+	for i, entry := range entries {
+		s.jobs.update(job, func() { job.Files[i].Status = domain.ImportStatusRunning })
+
+		doc, err := s.importBook(ctx, principal, entry)
+		s.jobs.finishFile(job, i, "", doc, err)
+		if err != nil {
+			s.logger.Warn("Failed to import Calibre book", "user_id", principal.UserID, "path", entry.book.Path, "error", err)
+		}
+	}
+
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusDone })
+	s.logger.Info("Calibre import finished", "user_id", principal.UserID,
+		"completed", job.Completed, "failed", job.Failed)
+}
+
+func (s *CalibreImportService) importBook(ctx context.Context, principal domain.Principal, entry calibreEntry) (*domain.DocumentData, error) {
+	book := entry.book.ImportedBook
+	book.Source = CalibreImportProvider
+	if entry.cover != nil {
+		cover, err := readZipFile(entry.cover, maxCalibreCoverSize)
+		if err != nil {
+			s.logger.Warn("Skipping unreadable Calibre cover", "path", entry.book.Path, "error", err)
+		} else {
+			book.Cover = cover
+			book.CoverType = mime.TypeByExtension(path.Ext(entry.cover.Name))
+		}
+	}
+
+	rc, err := entry.file.Open()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+	}
+	defer rc.Close()
+
+	return s.documents.uploadImported(ctx, principal, rc, path.Base(entry.file.Name), &book)
+}
+
+// newTags returns the tags of the books to import that the user does not have yet.
+func (s *CalibreImportService) newTags(ctx context.Context, principal domain.Principal, entries []calibreEntry) ([]string, error) {
+	existing, err := s.repo.GetTagsByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	tags := make([]string, 0)
+	for _, entry := range entries {
+		if entry.book.Skipped != "" {
+			continue
+		}
+		for _, tag := range entry.book.Tags {
+			if !slices.Contains(existing, tag) && !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags, nil
+}
+
+// readCalibreLibrary lists the books of a zipped Calibre library: every directory
+// holding a metadata.opf is a book. Books without a supported format are kept with
+// the reason they are skipped.
+func readCalibreLibrary(upload *spooledUpload) ([]calibreEntry, error) {
+	archive, err := zip.NewReader(upload.ReaderAt(), upload.size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a zip archive", domain.ErrInvalidFile)
+	}
+
+	dirs := make(map[string][]*zip.File)
+	for _, f := range archive.File {
+		if !f.FileInfo().IsDir() {
+			dir := path.Dir(f.Name)
+			dirs[dir] = append(dirs[dir], f)
+		}
+	}
+
+	var entries []calibreEntry
+	for _, f := range archive.File {
+		if path.Base(f.Name) != "metadata.opf" {
+			continue
+		}
+		dir := path.Dir(f.Name)
+		entry := calibreEntry{book: domain.CalibreBook{Path: dir}}
+
+		data, err := readZipFile(f, maxCalibreOPFSize)
+		if err == nil {
+			entry.book.ImportedBook, err = parseCalibreOPF(data)
+		}
+		if err != nil {
+			entry.book.Skipped = "unreadable metadata.opf"
+			entries = append(entries, entry)
+			continue
+		}
+		if entry.book.Title == "" {
+			entry.book.Title = path.Base(dir)
+		}
+
+		for _, file := range dirs[dir] {
+			name := path.Base(file.Name)
+			if strings.HasPrefix(name, "cover.") && strings.HasPrefix(mime.TypeByExtension(path.Ext(name)), "image/") {
+				entry.cover = file
+				entry.book.HasCover = true
+			}
+			format := strings.TrimPrefix(strings.ToLower(path.Ext(name)), ".")
+			rank := slices.Index(calibreFormats, format)
+			if rank >= 0 && (entry.file == nil || rank < slices.Index(calibreFormats, entry.book.Format)) {
+				entry.file = file
+				entry.book.Format = format
+			}
+		}
+		if entry.file == nil {
+			entry.book.Skipped = "no supported book format"
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("%w: no Calibre metadata.opf files found", domain.ErrInvalidFile)
+	}
+	slices.SortFunc(entries, func(a, b calibreEntry) int { return strings.Compare(a.book.Path, b.book.Path) })
+	return entries, nil
+}
+
+// calibreOPF is the part of a Calibre metadata.opf the import reads.
+type calibreOPF struct {
+	Metadata struct {
+		Titles      []string `xml:"title"`
+		Creators    []string `xml:"creator"`
+		Subjects    []string `xml:"subject"`
+		Description string   `xml:"description"`
+		Identifiers []struct {
+			Scheme string `xml:"scheme,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"identifier"`
+		Meta []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+}
+
+func parseCalibreOPF(data []byte) (domain.ImportedBook, error) {
+	var opf calibreOPF
+	if err := xml.NewDecoder(bytes.NewReader(data)).Decode(&opf); err != nil {
+		return domain.ImportedBook{}, err
+	}
+	m := opf.Metadata
+
+	var book domain.ImportedBook
+	if len(m.Titles) > 0 {
+		book.Title = strings.TrimSpace(m.Titles[0])
+	}
+	for _, creator := range m.Creators {
+		if creator = strings.TrimSpace(creator); creator != "" {
+			book.Authors = append(book.Authors, creator)
+		}
+	}
+	for _, subject := range m.Subjects {
+		if subject = strings.TrimSpace(subject); subject != "" && !slices.Contains(book.Tags, subject) {
+			book.Tags = append(book.Tags, subject)
+		}
+	}
+	book.Description = strings.TrimSpace(m.Description)
+	for _, id := range m.Identifiers {
+		if strings.EqualFold(id.Scheme, "isbn") {
+			book.ISBN = strings.TrimSpace(id.Value)
+		}
+	}
+	for _, meta := range m.Meta {
+		switch meta.Name {
+		case "calibre:series":
+			book.Series = meta.Content
+		case "calibre:series_index":
+			if index, err := strconv.ParseFloat(meta.Content, 64); err == nil {
+				book.SeriesIndex = index
+			}
+		}
+	}
+	if book.Series == "" {
+		book.SeriesIndex = 0
+	}
+	return book, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const testCalibreOPF = `<?xml version="1.0" encoding="utf-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" unique-identifier="uuid_id" version="2.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/" xmlns:opf="http://www.idpf.org/2007/opf">
+    <dc:title>The Left Hand of Darkness</dc:title>
+    <dc:creator opf:role="aut">Ursula K. Le Guin</dc:creator>
+    <dc:description>A story of Genly Ai on Gethen.</dc:description>
+    <dc:identifier opf:scheme="ISBN">9780441478125</dc:identifier>
+    <dc:subject>Science Fiction</dc:subject>
+    <dc:subject>Classics</dc:subject>
+    <meta name="calibre:series" content="Hainish Cycle"/>
+    <meta name="calibre:series_index" content="4.0"/>
+  </metadata>
+</package>`
+
+func testCalibreArchive(t *testing.T, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func newTestCalibreImportService() (domain.CalibreImportService, *MockDocumentRepository, *MockStorageService) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	documents := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	return NewCalibreImportService(documents, repo, NewImportJobTracker(), logger), repo, storage
+}
+
+func TestCalibreImportService_Preview(t *testing.T) {
+	s, repo, _ := newTestCalibreImportService()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	repo.tags["user1"] = []string{"Classics"}
+
+	archive := testCalibreArchive(t, map[string]string{
+		"Library/Ursula K. Le Guin/The Left Hand of Darkness (1)/metadata.opf": testCalibreOPF,
+		"Library/Ursula K. Le Guin/The Left Hand of Darkness (1)/book.txt":     "Chapter one",
+		"Library/Ursula K. Le Guin/The Left Hand of Darkness (1)/book.mobi":    "mobi",
+		"Library/Ursula K. Le Guin/The Left Hand of Darkness (1)/cover.jpg":    "jpeg",
+		"Library/Unknown/Scans (2)/metadata.opf":                               `<package><metadata><title>Scans</title></metadata></package>`,
+		"Library/Unknown/Scans (2)/scans.djvu":                                 "djvu",
+	})
+
+	preview, err := s.Preview(ctx, principal, archive)
+	if err != nil {
+		t.Fatalf("preview failed: %v", err)
+	}
+	if len(preview.Books) != 2 {
+		t.Fatalf("expected 2 books, got %+v", preview.Books)
+	}
+
+	book := preview.Books[1]
+	if book.Title != "The Left Hand of Darkness" || book.Format != "txt" || !book.HasCover || book.Skipped != "" {
+		t.Fatalf("unexpected book %+v", book)
+	}
+	if book.Series != "Hainish Cycle" || book.SeriesIndex != 4 || book.ISBN != "9780441478125" {
+		t.Fatalf("unexpected library metadata %+v", book)
+	}
+	if len(book.Authors) != 1 || book.Authors[0] != "Ursula K. Le Guin" {
+		t.Fatalf("unexpected authors %v", book.Authors)
+	}
+	if preview.Books[0].Skipped == "" {
+		t.Fatalf("expected the book without a supported format to be skipped, got %+v", preview.Books[0])
+	}
+	if len(preview.NewTags) != 1 || preview.NewTags[0] != "Science Fiction" {
+		t.Fatalf("expected only the missing tag to be new, got %v", preview.NewTags)
+	}
+	if len(repo.documents) != 0 {
+		t.Fatal("expected a dry run not to create documents")
+	}
+
+	if _, err := s.Preview(ctx, principal, strings.NewReader("not a zip")); !errors.Is(err, domain.ErrInvalidFile) {
+		t.Fatalf("expected an invalid file error, got %v", err)
+	}
+}
+
+func TestCalibreImportService_Import(t *testing.T) {
+	s, repo, storage := newTestCalibreImportService()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	archive := testCalibreArchive(t, map[string]string{
+		"Le Guin/Darkness (1)/metadata.opf": testCalibreOPF,
+		"Le Guin/Darkness (1)/darkness.txt": "Chapter one",
+		"Le Guin/Darkness (1)/cover.jpg":    "jpeg",
+	})
+
+	job, err := s.Import(ctx, principal, archive)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if job.Provider != CalibreImportProvider || len(job.Files) != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	tracker := s.(*CalibreImportService).jobs
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != domain.ImportStatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("import did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if job, err = tracker.get(principal, job.ID); err != nil {
+			t.Fatalf("get job failed: %v", err)
+		}
+	}
+	if job.Completed != 1 {
+		t.Fatalf("expected the book to be imported, got %+v", job)
+	}
+
+	doc := repo.documents[job.Files[0].DocumentID]
+	if doc == nil || doc.Title != "The Left Hand of Darkness" || doc.Author == nil || *doc.Author != "Ursula K. Le Guin" {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if doc.Tag == nil || *doc.Tag != "Science Fiction" || len(repo.tags["user1"]) != 2 {
+		t.Fatalf("expected the tags to be created and the first assigned, got %v and %v", doc.Tag, repo.tags["user1"])
+	}
+	if doc.Metadata.Source != "calibre" || doc.Metadata.Series != "Hainish Cycle" || doc.Metadata.ISBN != "9780441478125" {
+		t.Fatalf("unexpected metadata %+v", doc.Metadata)
+	}
+	if got := string(storage.files[doc.Metadata.CoverPath]); got != "jpeg" {
+		t.Fatalf("expected the Calibre cover at %q, got %q", doc.Metadata.CoverPath, got)
+	}
+
+	empty := testCalibreArchive(t, map[string]string{"A/B (1)/metadata.opf": testCalibreOPF})
+	var validationErrs domain.ValidationErrors
+	if _, err := s.Import(ctx, principal, empty); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error for an export without books, got %v", err)
+	}
+}
@@ -0,0 +1,327 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// CatalogService groups the library by author and series. The groups are derived
+// from the documents on every call, so imports and edits show up without a sync.
+type CatalogService struct {
+	repo   domain.DocumentRepository
+	logger domain.Logger
+}
+
+func NewCatalogService(repo domain.DocumentRepository, logger domain.Logger) domain.CatalogService {
+	return &CatalogService{repo: repo, logger: logger}
+}
+
+// catalogGroup is the documents sharing one author or series key.
+type catalogGroup struct {
+	id    string
+	names map[string]int // spelling variants and how many documents use each
+	docs  []*domain.DocumentData
+}
+
+// name is the group's most used spelling, the first alphabetically on ties.
+func (g *catalogGroup) name() string {
+	best := ""
+	for name, n := range g.names {
+		if best == "" || n > g.names[best] || (n == g.names[best] && name < best) {
+			best = name
+		}
+	}
+	return best
+}
+
+// groupDocuments groups the documents by the names returned for each, ordered by name.
+func groupDocuments(docs []*domain.DocumentData, names func(doc *domain.DocumentData) []string) []*catalogGroup {
+	byID := make(map[string]*catalogGroup)
+	for _, doc := range docs {
+		for _, name := range names(doc) {
+			id := domain.CatalogKey(name)
+			if id == "" {
+				continue
+			}
+			group, ok := byID[id]
+			if !ok {
+				group = &catalogGroup{id: id, names: make(map[string]int)}
+				byID[id] = group
+			}
+			group.names[name]++
+			if !slices.Contains(group.docs, doc) {
+				group.docs = append(group.docs, doc)
+			}
+		}
+	}
+
+	groups := make([]*catalogGroup, 0, len(byID))
+	for _, group := range byID {
+		groups = append(groups, group)
+	}
+	slices.SortFunc(groups, func(a, b *catalogGroup) int {
+		return cmp.Or(strings.Compare(strings.ToLower(a.name()), strings.ToLower(b.name())), strings.Compare(a.id, b.id))
+	})
+	return groups
+}
+
+func documentAuthors(doc *domain.DocumentData) []string {
+	if doc.Author == nil {
+		return nil
+	}
+	return domain.SplitAuthors(*doc.Author)
+}
+
+func documentSeries(doc *domain.DocumentData) []string {
+	if series := strings.TrimSpace(doc.Metadata.Series); series != "" {
+		return []string{series}
+	}
+	return nil
+}
+
+func (s *CatalogService) groups(ctx context.Context, principal domain.Principal, names func(doc *domain.DocumentData) []string) ([]*catalogGroup, error) {
+	docs, err := s.repo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	return groupDocuments(docs, names), nil
+}
+
+// findGroups returns the groups with the given IDs, in the order of ids.
+func findGroups(groups []*catalogGroup, ids []string, notFound error) ([]*catalogGroup, error) {
+	found := make([]*catalogGroup, 0, len(ids))
+	for _, id := range ids {
+		i := slices.IndexFunc(groups, func(g *catalogGroup) bool { return g.id == id })
+		if i < 0 {
+			return nil, notFound
+		}
+		found = append(found, groups[i])
+	}
+	return found, nil
+}
+
+func (s *CatalogService) ListAuthors(ctx context.Context, principal domain.Principal) ([]*domain.Author, error) {
+	groups, err := s.groups(ctx, principal, documentAuthors)
+	if err != nil {
+		return nil, err
+	}
+	authors := make([]*domain.Author, 0, len(groups))
+	for _, group := range groups {
+		authors = append(authors, &domain.Author{ID: group.id, Name: group.name(), DocumentCount: len(group.docs)})
+	}
+	return authors, nil
+}
+
+func (s *CatalogService) GetAuthor(ctx context.Context, principal domain.Principal, id string) (*domain.Author, error) {
+	groups, err := s.groups(ctx, principal, documentAuthors)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, []string{id}, domain.ErrAuthorNotFound)
+	if err != nil {
+		return nil, err
+	}
+	group := found[0]
+	slices.SortFunc(group.docs, func(a, b *domain.DocumentData) int {
+		return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+	})
+	return &domain.Author{ID: group.id, Name: group.name(), DocumentCount: len(group.docs), Documents: group.docs}, nil
+}
+
+func (s *CatalogService) RenameAuthor(ctx context.Context, principal domain.Principal, id, name string) (*domain.Author, error) {
+	name, err := validateCatalogName(name)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groups(ctx, principal, documentAuthors)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, []string{id}, domain.ErrAuthorNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renameAuthors(ctx, principal, found, name); err != nil {
+		return nil, err
+	}
+	return s.GetAuthor(ctx, principal, domain.CatalogKey(name))
+}
+
+func (s *CatalogService) MergeAuthors(ctx context.Context, principal domain.Principal, ids []string, intoID string) (*domain.Author, error) {
+	if err := validateCatalogMerge(ids, intoID); err != nil {
+		return nil, err
+	}
+	groups, err := s.groups(ctx, principal, documentAuthors)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, append([]string{intoID}, ids...), domain.ErrAuthorNotFound)
+	if err != nil {
+		return nil, err
+	}
+	// The target is renamed too, so its spelling variants collapse into one.
+	if err := s.renameAuthors(ctx, principal, found, found[0].name()); err != nil {
+		return nil, err
+	}
+	return s.GetAuthor(ctx, principal, intoID)
+}
+
+// renameAuthors replaces the authors of the groups by name in their documents. A
+// document listing several of them keeps the name once.
+func (s *CatalogService) renameAuthors(ctx context.Context, principal domain.Principal, groups []*catalogGroup, name string) error {
+	ids := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		ids[group.id] = true
+	}
+	return s.rewriteDocuments(ctx, principal, groups, func(doc *domain.DocumentData) {
+		var names []string
+		seen := make(map[string]bool)
+		for _, author := range documentAuthors(doc) {
+			if ids[domain.CatalogKey(author)] {
+				author = name
+			}
+			if key := domain.CatalogKey(author); !seen[key] {
+				seen[key] = true
+				names = append(names, author)
+			}
+		}
+		author := strings.Join(names, domain.AuthorSeparator)
+		doc.Author = &author
+	})
+}
+
+func (s *CatalogService) ListSeries(ctx context.Context, principal domain.Principal) ([]*domain.Series, error) {
+	groups, err := s.groups(ctx, principal, documentSeries)
+	if err != nil {
+		return nil, err
+	}
+	series := make([]*domain.Series, 0, len(groups))
+	for _, group := range groups {
+		series = append(series, &domain.Series{ID: group.id, Name: group.name(), DocumentCount: len(group.docs)})
+	}
+	return series, nil
+}
+
+func (s *CatalogService) GetSeries(ctx context.Context, principal domain.Principal, id string) (*domain.Series, error) {
+	groups, err := s.groups(ctx, principal, documentSeries)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, []string{id}, domain.ErrSeriesNotFound)
+	if err != nil {
+		return nil, err
+	}
+	group := found[0]
+	// Numbered volumes first, in order; unnumbered ones after them by title.
+	slices.SortFunc(group.docs, func(a, b *domain.DocumentData) int {
+		ai, bi := a.Metadata.SeriesIndex, b.Metadata.SeriesIndex
+		switch {
+		case ai != bi && ai == 0:
+			return 1
+		case ai != bi && bi == 0:
+			return -1
+		}
+		return cmp.Or(cmp.Compare(ai, bi), strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title)))
+	})
+	return &domain.Series{ID: group.id, Name: group.name(), DocumentCount: len(group.docs), Documents: group.docs}, nil
+}
+
+func (s *CatalogService) RenameSeries(ctx context.Context, principal domain.Principal, id, name string) (*domain.Series, error) {
+	name, err := validateCatalogName(name)
+	if err != nil {
+		return nil, err
+	}
+	groups, err := s.groups(ctx, principal, documentSeries)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, []string{id}, domain.ErrSeriesNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renameSeries(ctx, principal, found, name); err != nil {
+		return nil, err
+	}
+	return s.GetSeries(ctx, principal, domain.CatalogKey(name))
+}
+
+func (s *CatalogService) MergeSeries(ctx context.Context, principal domain.Principal, ids []string, intoID string) (*domain.Series, error) {
+	if err := validateCatalogMerge(ids, intoID); err != nil {
+		return nil, err
+	}
+	groups, err := s.groups(ctx, principal, documentSeries)
+	if err != nil {
+		return nil, err
+	}
+	found, err := findGroups(groups, append([]string{intoID}, ids...), domain.ErrSeriesNotFound)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.renameSeries(ctx, principal, found, found[0].name()); err != nil {
+		return nil, err
+	}
+	return s.GetSeries(ctx, principal, intoID)
+}
+
+func (s *CatalogService) renameSeries(ctx context.Context, principal domain.Principal, groups []*catalogGroup, name string) error {
+	return s.rewriteDocuments(ctx, principal, groups, func(doc *domain.DocumentData) {
+		doc.Metadata.Series = name
+	})
+}
+
+// rewriteDocuments applies rewrite to every document of the groups. Listings carry no
+// content, so each document is loaded in full before it is saved.
+func (s *CatalogService) rewriteDocuments(ctx context.Context, principal domain.Principal, groups []*catalogGroup, rewrite func(doc *domain.DocumentData)) error {
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, listed := range group.docs {
+			if seen[listed.ID] {
+				continue
+			}
+			seen[listed.ID] = true
+
+			doc, err := s.repo.GetByID(ctx, principal, listed.ID)
+			if err != nil {
+				return fmt.Errorf("failed to load document %s: %w", listed.ID, err)
+			}
+			rewrite(doc)
+			doc.UpdatedAt = time.Now().UTC()
+			if err := s.repo.Update(ctx, principal, doc); err != nil {
+				return fmt.Errorf("failed to update document %s: %w", listed.ID, err)
+			}
+		}
+	}
+	s.logger.Info("Catalog entries rewritten", "user_id", principal.UserID, "documents", len(seen))
+	return nil
+}
+
+func validateCatalogName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if domain.CatalogKey(name) == "" {
+		return "", domain.ValidationErrors{{Field: "name", Message: "name must contain a letter or digit"}}
+	}
+	return name, nil
+}
+
+func validateCatalogMerge(ids []string, intoID string) error {
+	var errs domain.ValidationErrors
+	if intoID == "" {
+		errs = append(errs, &domain.ValidationError{Field: "into", Message: "the ID to merge into is required"})
+	}
+	if len(ids) == 0 {
+		errs = append(errs, &domain.ValidationError{Field: "ids", Message: "at least one ID to merge is required"})
+	}
+	if slices.Contains(ids, intoID) {
+		errs = append(errs, &domain.ValidationError{Field: "ids", Message: "cannot merge an entry into itself"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
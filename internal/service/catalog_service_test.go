@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func newTestCatalog() (domain.CatalogService, *MockDocumentRepository) {
+	repo := NewMockDocumentRepository()
+	author := func(s string) *string { return &s }
+	docs := []*domain.Document{
+		{ID: "d1", UserID: "user1", Title: "A Wizard of Earthsea", Author: author("Ursula K. Le Guin"),
+			Metadata: domain.DocumentMetadata{Series: "Earthsea", SeriesIndex: 1}},
+		{ID: "d2", UserID: "user1", Title: "The Tombs of Atuan", Author: author("Ursula K Le Guin"),
+			Metadata: domain.DocumentMetadata{Series: "Earthsea", SeriesIndex: 2}},
+		{ID: "d3", UserID: "user1", Title: "Tales from Earthsea", Author: author("ursula k. le guin"),
+			Metadata: domain.DocumentMetadata{Series: "The Earthsea Cycle"}},
+		{ID: "d4", UserID: "user1", Title: "Good Omens", Author: author("Terry Pratchett & Neil Gaiman")},
+		{ID: "d5", UserID: "user1", Title: "Untitled"},
+	}
+	for _, doc := range docs {
+		repo.documents[doc.ID] = doc
+	}
+	return NewCatalogService(repo, NewMockLogger()), repo
+}
+
+func TestCatalogService_Authors(t *testing.T) {
+	s, repo := newTestCatalog()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	authors, err := s.ListAuthors(ctx, principal)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(authors) != 3 {
+		t.Fatalf("expected spelling variants to be grouped into 3 authors, got %+v", authors)
+	}
+	if authors[0].Name != "Neil Gaiman" || authors[2].ID != "ursula-k-le-guin" || authors[2].DocumentCount != 3 {
+		t.Fatalf("unexpected authors %+v %+v %+v", authors[0], authors[1], authors[2])
+	}
+
+	author, err := s.GetAuthor(ctx, principal, "ursula-k-le-guin")
+	if err != nil || len(author.Documents) != 3 || author.Documents[0].ID != "d1" {
+		t.Fatalf("unexpected author %+v (%v)", author, err)
+	}
+	if _, err := s.GetAuthor(ctx, principal, "nobody"); !errors.Is(err, domain.ErrAuthorNotFound) {
+		t.Fatalf("expected an author not found error, got %v", err)
+	}
+
+	if author, err = s.RenameAuthor(ctx, principal, "ursula-k-le-guin", "Ursula K. Le Guin"); err != nil {
+		t.Fatalf("rename failed: %v", err)
+	}
+	for _, id := range []string{"d1", "d2", "d3"} {
+		if got := *repo.documents[id].Author; got != "Ursula K. Le Guin" {
+			t.Fatalf("expected %s to use the new spelling, got %q", id, got)
+		}
+	}
+
+	author, err = s.MergeAuthors(ctx, principal, []string{"neil-gaiman"}, "terry-pratchett")
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if got := *repo.documents["d4"].Author; got != "Terry Pratchett" || author.DocumentCount != 1 {
+		t.Fatalf("expected the merged author to be listed once, got %q and %+v", got, author)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.MergeAuthors(ctx, principal, []string{"terry-pratchett"}, "terry-pratchett"); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := s.RenameAuthor(ctx, principal, "terry-pratchett", " - "); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+}
+
+func TestCatalogService_Series(t *testing.T) {
+	s, repo := newTestCatalog()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	series, err := s.ListSeries(ctx, principal)
+	if err != nil || len(series) != 2 {
+		t.Fatalf("unexpected series %+v (%v)", series, err)
+	}
+
+	merged, err := s.MergeSeries(ctx, principal, []string{"the-earthsea-cycle"}, "earthsea")
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if repo.documents["d3"].Metadata.Series != "Earthsea" || len(merged.Documents) != 3 {
+		t.Fatalf("unexpected merged series %+v", merged)
+	}
+	// Numbered volumes come first, in order.
+	if merged.Documents[0].ID != "d1" || merged.Documents[1].ID != "d2" || merged.Documents[2].ID != "d3" {
+		t.Fatalf("unexpected series order %v %v %v", merged.Documents[0].ID, merged.Documents[1].ID, merged.Documents[2].ID)
+	}
+
+	if _, err := s.RenameSeries(ctx, principal, "missing", "Name"); !errors.Is(err, domain.ErrSeriesNotFound) {
+		t.Fatalf("expected a series not found error, got %v", err)
+	}
+}
@@ -0,0 +1,481 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// referenceHeadings are section titles that open a bibliography.
+var referenceHeadings = []string{"references", "bibliography", "works cited", "literature cited", "reference list"}
+
+var (
+	sectionNumberRe   = regexp.MustCompile(`^(?:\d+(?:\.\d+)*|[IVXLC]+)\.?\s+`)
+	arxivIDRe         = regexp.MustCompile(`(?i)arxiv[:\s]\s*(\d{4}\.\d{4,5}(?:v\d+)?|[a-z\-]+(?:\.[a-z]{2})?/\d{7}(?:v\d+)?)`)
+	arxivURLRe        = regexp.MustCompile(`arxiv\.org/(?:abs|pdf)/(\d{4}\.\d{4,5}(?:v\d+)?)`)
+	doiRe             = regexp.MustCompile(`\b10\.\d{4,9}/[^\s"<>]+`)
+	citationURLRe     = regexp.MustCompile(`https?://[^\s<>"]+`)
+	yearRe            = regexp.MustCompile(`\b(?:19|20)\d{2}\b`)
+	parenYearRe       = regexp.MustCompile(`\(((?:19|20)\d{2})[a-z]?\)`)
+	doiPrefixRe       = regexp.MustCompile(`(?i)\b(?:doi|https?://(?:dx\.)?doi\.org/)\s*:?\s*(?:[.,;]|$)`)
+	etAlRe            = regexp.MustCompile(`(?i),?\s*et\s+al\.?$`)
+	initialsRe        = regexp.MustCompile(`^(?:\p{Lu}\.?\s?-?)+$`)
+	numericCitationRe = regexp.MustCompile(`\[(\d+(?:\s*[-–,]\s*\d+)*)\]`)
+	// authorYearEntryRe finds "... . Surname, A. B." where a new author-year entry begins.
+	authorYearEntryRe = regexp.MustCompile(`\.\s+[\p{Lu}][\p{L}'’\-]+,\s+(?:\p{Lu}\.\s?)+`)
+)
+
+// citationAbbreviations end with a period without ending a sentence.
+var citationAbbreviations = map[string]bool{
+	"Proc": true, "Vol": true, "vol": true, "pp": true, "No": true, "no": true,
+	"ed": true, "eds": true, "Ed": true, "Eds": true, "Conf": true, "Int": true, "Trans": true,
+	"Jr": true, "Inc": true, "vs": true,
+}
+
+// maxCitationRange bounds how far a marker like "[3-7]" is expanded.
+const maxCitationRange = 50
+
+// referenceStyle is how entries in a references section are delimited.
+type referenceStyle int
+
+const (
+	referenceStyleBracket    referenceStyle = iota // [1] ...
+	referenceStyleNumbered                         // 1. ...
+	referenceStyleAuthorYear                       // Surname, A. (2017) ...
+)
+
+// extractCitations runs the scientific paper mode: for documents that look like a
+// paper, it parses the references section and adds links from in-text citation
+// markers to the entries. Other documents return nil and blocks are left unchanged.
+func extractCitations(blocks []TextBlock) []domain.Reference {
+	start, lead := findReferencesSection(blocks)
+	if start < 0 || !looksLikePaper(blocks[:start]) {
+		return nil
+	}
+
+	// Paragraph splitting is unreliable inside bibliographies, so the section is
+	// joined and re-split on entry markers, remembering which block each byte came from.
+	type span struct{ offset, block int }
+	var (
+		text  strings.Builder
+		spans []span
+	)
+	add := func(s string, block int) {
+		if s = strings.TrimSpace(s); s == "" {
+			return
+		}
+		if text.Len() > 0 {
+			text.WriteByte(' ')
+		}
+		spans = append(spans, span{text.Len(), block})
+		text.WriteString(s)
+	}
+	add(lead, start)
+	for i := start + 1; i < len(blocks); i++ {
+		if isBackMatterHeading(blocks[i].Content) {
+			break
+		}
+		add(blocks[i].Content, i)
+	}
+	section := text.String()
+	blockAt := func(offset int) TextBlock {
+		block := spans[0].block
+		for _, s := range spans {
+			if s.offset > offset {
+				break
+			}
+			block = s.block
+		}
+		return blocks[block]
+	}
+
+	style, starts, markerLen := splitReferences(section)
+	if len(starts) == 0 {
+		return nil
+	}
+
+	refs := make([]domain.Reference, 0, len(starts))
+	for i, offset := range starts {
+		end := len(section)
+		if i+1 < len(starts) {
+			end = starts[i+1]
+		}
+		raw := strings.TrimSpace(section[offset+markerLen(i+1) : end])
+		if raw == "" {
+			continue
+		}
+		ref := parseReference(len(refs)+1, raw)
+		if style != referenceStyleAuthorYear {
+			ref.Number = i + 1
+		}
+		block := blockAt(offset)
+		ref.Page, ref.Position = block.PageNumber, block.Position
+		refs = append(refs, ref)
+	}
+
+	if style == referenceStyleAuthorYear {
+		linkAuthorYearCitations(blocks[:start], refs)
+	} else {
+		linkNumericCitations(blocks[:start], refs)
+	}
+	return refs
+}
+
+// findReferencesSection returns the index of the last block opening a references
+// section, plus any entry text that shares the block with the heading.
+func findReferencesSection(blocks []TextBlock) (int, string) {
+	for i := len(blocks) - 1; i >= 0; i-- {
+		content := sectionNumberRe.ReplaceAllString(strings.TrimSpace(blocks[i].Content), "")
+		for _, heading := range referenceHeadings {
+			if strings.EqualFold(content, heading) {
+				return i, ""
+			}
+			if len(content) > len(heading)+1 && strings.EqualFold(content[:len(heading)], heading) && content[len(heading)] == ' ' {
+				rest := strings.TrimSpace(content[len(heading):])
+				if strings.HasPrefix(rest, "[1]") || strings.HasPrefix(rest, "1. ") {
+					return i, rest
+				}
+			}
+		}
+	}
+	return -1, ""
+}
+
+// looksLikePaper reports whether the body has an abstract on its first pages or an
+// arXiv identifier on the first page.
+func looksLikePaper(body []TextBlock) bool {
+	for _, block := range body {
+		if block.PageNumber > 2 {
+			break
+		}
+		content := strings.TrimSpace(block.Content)
+		if len(content) >= len("abstract") && strings.EqualFold(content[:len("abstract")], "abstract") {
+			return true
+		}
+		if block.PageNumber == 1 && arxivIDRe.MatchString(content) {
+			return true
+		}
+	}
+	return false
+}
+
+func isBackMatterHeading(content string) bool {
+	content = strings.ToLower(strings.TrimSpace(content))
+	return strings.HasPrefix(content, "appendix") || strings.HasPrefix(content, "supplementary")
+}
+
+// splitReferences finds the start offset of each entry. markerLen returns how many
+// bytes of entry n (1-indexed) belong to its marker.
+func splitReferences(section string) (referenceStyle, []int, func(int) int) {
+	bracket := func(n int) string { return "[" + strconv.Itoa(n) + "]" }
+	numbered := func(n int) string { return strconv.Itoa(n) + ". " }
+
+	if starts := splitNumberedReferences(section, bracket); len(starts) > 0 {
+		return referenceStyleBracket, starts, func(n int) int { return len(bracket(n)) }
+	}
+	if starts := splitNumberedReferences(section, numbered); len(starts) > 0 {
+		return referenceStyleNumbered, starts, func(n int) int { return len(numbered(n)) }
+	}
+
+	if section == "" || unicode.IsDigit(rune(section[0])) {
+		return referenceStyleAuthorYear, nil, nil
+	}
+	starts := []int{0}
+	for _, loc := range authorYearEntryRe.FindAllStringIndex(section, -1) {
+		offset := loc[0] + 1
+		for offset < len(section) && section[offset] == ' ' {
+			offset++
+		}
+		starts = append(starts, offset)
+	}
+	return referenceStyleAuthorYear, starts, func(int) int { return 0 }
+}
+
+// splitNumberedReferences looks for markers 1, 2, 3... in order, each at the start of
+// a word. The section must open with the first marker.
+func splitNumberedReferences(section string, marker func(int) string) []int {
+	var starts []int
+	pos := 0
+	for n := 1; ; n++ {
+		m := marker(n)
+		idx := indexAtWordStart(section[pos:], m)
+		if idx < 0 || (n == 1 && idx != 0) {
+			break
+		}
+		starts = append(starts, pos+idx)
+		pos += idx + len(m)
+	}
+	return starts
+}
+
+func indexAtWordStart(s, sub string) int {
+	for offset := 0; offset < len(s); {
+		i := strings.Index(s[offset:], sub)
+		if i < 0 {
+			return -1
+		}
+		i += offset
+		if i == 0 || s[i-1] == ' ' {
+			return i
+		}
+		offset = i + 1
+	}
+	return -1
+}
+
+// parseReference extracts identifiers, year, authors, title and venue from one entry.
+func parseReference(number int, raw string) domain.Reference {
+	ref := domain.Reference{Number: number, Raw: raw}
+
+	// Identifiers contain digit runs that look like years; drop them (with their
+	// "doi:"/"arXiv:" prefixes) before parsing the rest.
+	clean := raw
+	if ref.URL = trimCitationPunct(citationURLRe.FindString(clean)); ref.URL != "" {
+		clean = strings.ReplaceAll(clean, ref.URL, "")
+	}
+	if m := arxivIDRe.FindStringSubmatch(clean); m != nil {
+		ref.ArXivID = m[1]
+		clean = strings.ReplaceAll(clean, m[0], "")
+	} else if m := arxivURLRe.FindStringSubmatch(raw); m != nil {
+		ref.ArXivID = m[1]
+	}
+	if ref.DOI = trimCitationPunct(doiRe.FindString(raw)); ref.DOI != "" {
+		clean = doiPrefixRe.ReplaceAllString(strings.ReplaceAll(clean, ref.DOI, ""), "")
+	}
+	clean = strings.ReplaceAll(strings.Join(strings.Fields(clean), " "), " ,", ",")
+
+	var authors, title, venue string
+	if loc := parenYearRe.FindStringSubmatchIndex(clean); loc != nil {
+		// Author-year: "Surname, A. (2017). Title. Venue."
+		ref.Year, _ = strconv.Atoi(clean[loc[2]:loc[3]])
+		authors = clean[:loc[0]]
+		sentences := splitSentences(strings.TrimLeft(clean[loc[1]:], ".,:; "))
+		title, venue = nth(sentences, 0), nth(sentences, 1)
+	} else {
+		if years := yearRe.FindAllString(clean, -1); len(years) > 0 {
+			ref.Year, _ = strconv.Atoi(years[len(years)-1])
+		}
+		if qs, qe := quotedSpan(clean); qs >= 0 {
+			// IEEE: A. Author, “Title,” in Venue, 2017.
+			authors = clean[:qs]
+			title = clean[qs+len("“") : qe]
+			venue = clean[qe+len("”"):]
+		} else {
+			// Numeric and ACL: A. Author, B. Author. [2017.] Title. Venue.
+			var sentences []string
+			for _, s := range splitSentences(clean) {
+				if !yearRe.MatchString(s) || len(s) > 5 {
+					sentences = append(sentences, s)
+				}
+			}
+			authors, title, venue = nth(sentences, 0), nth(sentences, 1), strings.Join(sentences[min(2, len(sentences)):], ". ")
+		}
+	}
+
+	ref.Authors = splitAuthors(authors)
+	ref.Title = strings.Trim(strings.TrimSpace(title), " ,.;:“”\"")
+	venue = strings.Trim(strings.TrimSpace(venue), " ,.;:")
+	if strings.HasPrefix(venue, "In ") || strings.HasPrefix(venue, "in ") {
+		venue = venue[len("In "):]
+	}
+	if !yearRe.MatchString(venue) || len(venue) > 4 {
+		ref.Venue = venue
+	}
+	return ref
+}
+
+// splitSentences splits on sentence punctuation, ignoring initials and common
+// citation abbreviations.
+func splitSentences(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c != '.' && c != '?' && c != '!' {
+			continue
+		}
+		if i+1 < len(s) && s[i+1] != ' ' {
+			continue
+		}
+		if c == '.' && isCitationAbbreviation(lastWord(s[start:i])) {
+			continue
+		}
+		end := i
+		if c != '.' {
+			end = i + 1 // keep "?" and "!" in titles
+		}
+		if seg := strings.TrimSpace(s[start:end]); seg != "" {
+			out = append(out, seg)
+		}
+		start = i + 1
+	}
+	if seg := strings.TrimSpace(s[start:]); seg != "" {
+		out = append(out, seg)
+	}
+	return out
+}
+
+func isCitationAbbreviation(word string) bool {
+	if utf8.RuneCountInString(word) == 1 {
+		r, _ := utf8.DecodeRuneInString(word)
+		return unicode.IsUpper(r)
+	}
+	return citationAbbreviations[word] || strings.Contains(word, ".")
+}
+
+func lastWord(s string) string {
+	s = strings.TrimSpace(s)
+	if i := strings.LastIndexAny(s, " ,("); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
+
+// splitAuthors splits an author list, keeping "Surname, A." pairs together.
+func splitAuthors(s string) []string {
+	s = etAlRe.ReplaceAllString(strings.Trim(strings.TrimSpace(s), " ,.;:"), "")
+	if s == "" {
+		return nil
+	}
+
+	sep := ","
+	if strings.Contains(s, ";") {
+		sep = ";"
+	}
+	for _, conj := range []string{", and ", " and ", " & "} {
+		s = strings.ReplaceAll(s, conj, sep+" ")
+	}
+
+	var authors []string
+	for _, part := range strings.Split(s, sep) {
+		part = strings.Trim(strings.TrimSpace(part), ".")
+		if part == "" {
+			continue
+		}
+		if sep == "," && len(authors) > 0 && initialsRe.MatchString(part) {
+			authors[len(authors)-1] += ", " + part + "."
+			continue
+		}
+		authors = append(authors, part)
+	}
+	return authors
+}
+
+// quotedSpan returns the byte offsets of the first “...” pair, or -1.
+func quotedSpan(s string) (int, int) {
+	start := strings.Index(s, "“")
+	if start < 0 {
+		return -1, -1
+	}
+	end := strings.Index(s[start:], "”")
+	if end < 0 {
+		return -1, -1
+	}
+	return start, start + end
+}
+
+func trimCitationPunct(s string) string {
+	return strings.TrimRight(s, ".,;:)]")
+}
+
+func nth(items []string, i int) string {
+	if i < len(items) {
+		return items[i]
+	}
+	return ""
+}
+
+// linkNumericCitations links "[3]", "[1, 4]" and "[2-5]" markers. Markers naming a
+// number outside the bibliography are left alone; they are usually equation refs.
+func linkNumericCitations(body []TextBlock, refs []domain.Reference) {
+	byNumber := make(map[int]domain.Reference, len(refs))
+	for _, ref := range refs {
+		byNumber[ref.Number] = ref
+	}
+
+	for i := range body {
+		for _, m := range numericCitationRe.FindAllStringSubmatch(body[i].Content, -1) {
+			numbers, ok := citationNumbers(m[1])
+			if !ok {
+				continue
+			}
+			var links []BlockLink
+			for _, n := range numbers {
+				ref, found := byNumber[n]
+				if !found {
+					links = nil
+					break
+				}
+				links = append(links, BlockLink{Text: m[0], Page: ref.Page, Position: ref.Position, Reference: n})
+			}
+			body[i].Links = append(body[i].Links, links...)
+		}
+	}
+}
+
+// citationNumbers expands "1, 3-5" into 1, 3, 4, 5.
+func citationNumbers(list string) ([]int, bool) {
+	var numbers []int
+	for _, part := range strings.Split(list, ",") {
+		from, to, isRange := strings.Cut(strings.ReplaceAll(part, "–", "-"), "-")
+		lo, err := strconv.Atoi(strings.TrimSpace(from))
+		if err != nil {
+			return nil, false
+		}
+		hi := lo
+		if isRange {
+			if hi, err = strconv.Atoi(strings.TrimSpace(to)); err != nil || hi < lo || hi-lo > maxCitationRange {
+				return nil, false
+			}
+		}
+		for n := lo; n <= hi; n++ {
+			numbers = append(numbers, n)
+		}
+	}
+	return numbers, true
+}
+
+// linkAuthorYearCitations links "Surname et al. (2017)" and "(Surname, 2017)" style
+// markers by the first author's surname and the year.
+func linkAuthorYearCitations(body []TextBlock, refs []domain.Reference) {
+	for _, ref := range refs {
+		if len(ref.Authors) == 0 || ref.Year == 0 {
+			continue
+		}
+		surname := citationSurname(ref.Authors[0])
+		if utf8.RuneCountInString(surname) < 2 {
+			continue
+		}
+		re, err := regexp.Compile(`\b` + regexp.QuoteMeta(surname) + `\b[^()\[\];]{0,40}?\(?` + strconv.Itoa(ref.Year) + `[a-z]?\b`)
+		if err != nil {
+			continue
+		}
+		for i := range body {
+			content := body[i].Content
+			for _, loc := range re.FindAllStringIndex(content, -1) {
+				// Close "Surname et al. (2017" but not "(Surname, 2017".
+				end := loc[1]
+				if strings.Contains(content[loc[0]:end], "(") && end < len(content) && content[end] == ')' {
+					end++
+				}
+				body[i].Links = append(body[i].Links, BlockLink{Text: content[loc[0]:end], Page: ref.Page, Position: ref.Position, Reference: ref.Number})
+			}
+		}
+	}
+}
+
+// citationSurname returns the family name from "Surname, A." or "A. Surname".
+func citationSurname(author string) string {
+	if surname, _, ok := strings.Cut(author, ","); ok {
+		return strings.TrimSpace(surname)
+	}
+	fields := strings.Fields(author)
+	if len(fields) == 0 {
+		return ""
+	}
+	return fields[len(fields)-1]
+}
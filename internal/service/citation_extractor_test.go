@@ -0,0 +1,138 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestExtractCitations_Numeric(t *testing.T) {
+	blocks := []TextBlock{
+		{Type: "heading", Content: "Attention Is All You Need", PageNumber: 1, Position: 0},
+		{Type: "paragraph", Content: "arXiv:1706.03762v7 [cs.CL] 2 Aug 2023", PageNumber: 1, Position: 1},
+		{Type: "paragraph", Content: "Recurrent models [2] and convolutions [1, 3] are common; see also [1-2] and Eq. [7].", PageNumber: 1, Position: 2},
+		{Type: "heading", Content: "7 References", PageNumber: 2, Position: 0},
+		{Type: "paragraph", Content: "[1] J. Ba, J. R. Kiros, and G. E. Hinton. Layer normalization. arXiv preprint arXiv:1607.06450, 2016.", PageNumber: 2, Position: 1},
+		{Type: "paragraph", Content: "[2] D. Bahdanau, K. Cho, and Y. Bengio. Neural machine translation by jointly learning to align and translate. In Proc. ICLR, 2015. [3] A. Vaswani et al. Attention is all you need. doi:10.5555/3295222.3295349", PageNumber: 2, Position: 2},
+		{Type: "heading", Content: "Appendix A", PageNumber: 3, Position: 0},
+		{Type: "paragraph", Content: "[4] Not a reference.", PageNumber: 3, Position: 1},
+	}
+
+	refs := extractCitations(blocks)
+
+	want := []domain.Reference{
+		{
+			Number: 1, Raw: "J. Ba, J. R. Kiros, and G. E. Hinton. Layer normalization. arXiv preprint arXiv:1607.06450, 2016.",
+			Authors: []string{"J. Ba", "J. R. Kiros", "G. E. Hinton"}, Title: "Layer normalization",
+			Venue: "arXiv preprint, 2016", Year: 2016, ArXivID: "1607.06450", Page: 2, Position: 1,
+		},
+		{
+			Number: 2, Raw: "D. Bahdanau, K. Cho, and Y. Bengio. Neural machine translation by jointly learning to align and translate. In Proc. ICLR, 2015.",
+			Authors: []string{"D. Bahdanau", "K. Cho", "Y. Bengio"}, Title: "Neural machine translation by jointly learning to align and translate",
+			Venue: "Proc. ICLR, 2015", Year: 2015, Page: 2, Position: 2,
+		},
+		{
+			Number: 3, Raw: "A. Vaswani et al. Attention is all you need. doi:10.5555/3295222.3295349",
+			Authors: []string{"A. Vaswani"}, Title: "Attention is all you need",
+			DOI: "10.5555/3295222.3295349", Page: 2, Position: 2,
+		},
+	}
+	if !reflect.DeepEqual(refs, want) {
+		t.Fatalf("unexpected references:\n got %+v\nwant %+v", refs, want)
+	}
+
+	links := blocks[2].Links
+	wantLinks := []BlockLink{
+		{Text: "[2]", Page: 2, Position: 2, Reference: 2},
+		{Text: "[1, 3]", Page: 2, Position: 1, Reference: 1},
+		{Text: "[1, 3]", Page: 2, Position: 2, Reference: 3},
+		{Text: "[1-2]", Page: 2, Position: 1, Reference: 1},
+		{Text: "[1-2]", Page: 2, Position: 2, Reference: 2},
+	}
+	if !reflect.DeepEqual(links, wantLinks) {
+		t.Fatalf("unexpected links:\n got %+v\nwant %+v", links, wantLinks)
+	}
+	if blocks[7].Links != nil {
+		t.Fatalf("expected appendix to be left alone, got %+v", blocks[7].Links)
+	}
+}
+
+func TestExtractCitations_AuthorYear(t *testing.T) {
+	blocks := []TextBlock{
+		{Type: "paragraph", Content: "Abstract We revisit attention.", PageNumber: 1, Position: 0},
+		{Type: "paragraph", Content: "As shown by Vaswani et al. (2017) and earlier work (Bahdanau and Cho, 2015), attention helps.", PageNumber: 1, Position: 1},
+		{Type: "heading", Content: "REFERENCES", PageNumber: 2, Position: 0},
+		{Type: "paragraph", Content: "Bahdanau, D., Cho, K. (2015). Neural machine translation. In ICLR. Vaswani, A., Shazeer, N., & Parmar, N. (2017). Attention is all you need? NeurIPS. https://arxiv.org/abs/1706.03762", PageNumber: 2, Position: 1},
+	}
+
+	refs := extractCitations(blocks)
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 references, got %+v", refs)
+	}
+
+	if got := refs[0]; got.Number != 1 || !reflect.DeepEqual(got.Authors, []string{"Bahdanau, D.", "Cho, K."}) ||
+		got.Year != 2015 || got.Title != "Neural machine translation" || got.Venue != "ICLR" {
+		t.Fatalf("unexpected first reference: %+v", got)
+	}
+	if got := refs[1]; got.Number != 2 || !reflect.DeepEqual(got.Authors, []string{"Vaswani, A.", "Shazeer, N.", "Parmar, N."}) ||
+		got.Year != 2017 || got.Title != "Attention is all you need?" || got.ArXivID != "1706.03762" ||
+		got.URL != "https://arxiv.org/abs/1706.03762" {
+		t.Fatalf("unexpected second reference: %+v", got)
+	}
+
+	wantLinks := []BlockLink{
+		{Text: "Bahdanau and Cho, 2015", Page: 2, Position: 1, Reference: 1},
+		{Text: "Vaswani et al. (2017)", Page: 2, Position: 1, Reference: 2},
+	}
+	if !reflect.DeepEqual(blocks[1].Links, wantLinks) {
+		t.Fatalf("unexpected links:\n got %+v\nwant %+v", blocks[1].Links, wantLinks)
+	}
+}
+
+func TestExtractCitations_NotAPaper(t *testing.T) {
+	blocks := []TextBlock{
+		{Type: "heading", Content: "Chapter One", PageNumber: 1},
+		{Type: "paragraph", Content: "It was a dark night [1].", PageNumber: 1, Position: 1},
+		{Type: "heading", Content: "Bibliography", PageNumber: 2},
+		{Type: "paragraph", Content: "[1] Someone. Some book. 1999.", PageNumber: 2, Position: 1},
+	}
+	if refs := extractCitations(blocks); refs != nil {
+		t.Fatalf("expected no references outside paper mode, got %+v", refs)
+	}
+	if blocks[1].Links != nil {
+		t.Fatalf("expected blocks to be unchanged, got %+v", blocks[1].Links)
+	}
+}
+
+func TestParseReference_IEEE(t *testing.T) {
+	ref := parseReference(4, "K. He, X. Zhang, S. Ren, and J. Sun, “Deep residual learning for image recognition,” in Proc. CVPR, 2016, pp. 770–778.")
+
+	if !reflect.DeepEqual(ref.Authors, []string{"K. He", "X. Zhang", "S. Ren", "J. Sun"}) {
+		t.Fatalf("unexpected authors: %q", ref.Authors)
+	}
+	if ref.Title != "Deep residual learning for image recognition" || ref.Year != 2016 || ref.Venue != "Proc. CVPR, 2016, pp. 770–778" {
+		t.Fatalf("unexpected reference: %+v", ref)
+	}
+}
+
+func TestCitationNumbers(t *testing.T) {
+	tests := []struct {
+		list string
+		want []int
+		ok   bool
+	}{
+		{"3", []int{3}, true},
+		{"1, 4", []int{1, 4}, true},
+		{"2-4", []int{2, 3, 4}, true},
+		{"2–3, 7", []int{2, 3, 7}, true},
+		{"5-2", nil, false},
+		{"1-1000", nil, false},
+	}
+	for _, tt := range tests {
+		got, ok := citationNumbers(tt.list)
+		if ok != tt.ok || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("citationNumbers(%q) = %v, %v; want %v, %v", tt.list, got, ok, tt.want, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// CloudImportService pulls files from the user's cloud drives into the library.
+// Import jobs run in the background on this server.
+type CloudImportService struct {
+	drives      map[string]domain.CloudDrive
+	connections domain.CloudConnectionRepository
+	documents   domain.DocumentService
+	jobs        *ImportJobTracker
+	logger      domain.Logger
+}
+
+func NewCloudImportService(
+	drives map[string]domain.CloudDrive,
+	connections domain.CloudConnectionRepository,
+	documents domain.DocumentService,
+	jobs *ImportJobTracker,
+	logger domain.Logger,
+) domain.CloudImportService {
+	return &CloudImportService{
+		drives:      drives,
+		connections: connections,
+		documents:   documents,
+		jobs:        jobs,
+		logger:      logger,
+	}
+}
+
+func (s *CloudImportService) Providers() []string {
+	providers := make([]string, 0, len(s.drives))
+	for provider := range s.drives {
+		providers = append(providers, provider)
+	}
+	slices.Sort(providers)
+	return providers
+}
+
+func (s *CloudImportService) drive(provider string) (domain.CloudDrive, error) {
+	drive, ok := s.drives[provider]
+	if !ok {
+		return nil, domain.ErrCloudProviderNotFound
+	}
+	return drive, nil
+}
+
+func (s *CloudImportService) AuthURL(provider, state string) (string, error) {
+	drive, err := s.drive(provider)
+	if err != nil {
+		return "", err
+	}
+	return drive.AuthURL(state), nil
+}
+
+// Connect finishes the OAuth flow with the code the provider redirected back with.
+func (s *CloudImportService) Connect(ctx context.Context, principal domain.Principal, provider, code string) error {
+	drive, err := s.drive(provider)
+	if err != nil {
+		return err
+	}
+	token, err := drive.Exchange(ctx, code)
+	if err != nil {
+		return fmt.Errorf("failed to connect %s: %w", provider, err)
+	}
+	return s.connections.Upsert(ctx, principal, &domain.CloudConnection{
+		UserID:   principal.UserID,
+		Provider: provider,
+		Token:    *token,
+	})
+}
+
+func (s *CloudImportService) Disconnect(ctx context.Context, principal domain.Principal, provider string) error {
+	if _, err := s.drive(provider); err != nil {
+		return err
+	}
+	return s.connections.Delete(ctx, principal, provider)
+}
+
+// token returns the user's access token for the provider, refreshing and storing it
+// when it has expired.
+func (s *CloudImportService) token(ctx context.Context, principal domain.Principal, drive domain.CloudDrive, provider string) (*domain.CloudToken, error) {
+	connection, err := s.connections.Get(ctx, principal, provider)
+	if err != nil {
+		return nil, err
+	}
+	if !connection.Token.Expired() {
+		return &connection.Token, nil
+	}
+
+	token, err := drive.Refresh(ctx, &connection.Token)
+	if err != nil {
+		return nil, fmt.Errorf("failed to refresh %s token: %w", provider, err)
+	}
+	connection.Token = *token
+	if err := s.connections.Upsert(ctx, principal, connection); err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (s *CloudImportService) ListFiles(ctx context.Context, principal domain.Principal, provider, query string) ([]domain.CloudFile, error) {
+	drive, err := s.drive(provider)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.token(ctx, principal, drive, provider)
+	if err != nil {
+		return nil, err
+	}
+	return drive.ListFiles(ctx, token, query)
+}
+
+func (s *CloudImportService) StartImport(ctx context.Context, principal domain.Principal, provider string, fileIDs []string) (*domain.ImportJob, error) {
+	drive, err := s.drive(provider)
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool, len(fileIDs))
+	var files []domain.ImportFile
+	for _, id := range fileIDs {
+		if id != "" && !seen[id] {
+			seen[id] = true
+			files = append(files, domain.ImportFile{FileID: id, Status: domain.ImportStatusPending})
+		}
+	}
+	switch {
+	case len(files) == 0:
+		return nil, domain.ValidationErrors{{Field: "file_ids", Message: "at least one file is required"}}
+	case len(files) > domain.MaxImportFiles:
+		return nil, domain.ValidationErrors{{Field: "file_ids", Message: fmt.Sprintf("at most %d files can be imported at once", domain.MaxImportFiles)}}
+	}
+
+	// Fail fast when the drive is not connected instead of failing every file.
+	if _, err := s.token(ctx, principal, drive, provider); err != nil {
+		return nil, err
+	}
+
+	job, snapshot := s.jobs.start(principal, provider, files)
+
+	// The transfer outlives the request, so detach it from the request's cancellation.
+	go s.runImport(context.WithoutCancel(ctx), principal, drive, job)
+
+	return snapshot, nil
+}
+
+// runImport transfers the job's files one at a time. A file that fails is recorded
+// and the others still go ahead.
+func (s *CloudImportService) runImport(ctx context.Context, principal domain.Principal, drive domain.CloudDrive, job *domain.ImportJob) {
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusRunning })
+
+	for i := range job.Files {
+		s.jobs.update(job, func() { job.Files[i].Status = domain.ImportStatusRunning })
+
+		name, doc, err := s.importFile(ctx, principal, drive, job.Provider, job.Files[i].FileID)
+		s.jobs.finishFile(job, i, name, doc, err)
+		if err != nil {
+			s.logger.Warn("Failed to import cloud file", "user_id", principal.UserID, "provider", job.Provider, "error", err)
+		}
+	}
+
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusDone })
+	s.logger.Info("Cloud import finished", "user_id", principal.UserID, "provider", job.Provider,
+		"completed", job.Completed, "failed", job.Failed)
+}
+
+func (s *CloudImportService) importFile(ctx context.Context, principal domain.Principal, drive domain.CloudDrive, provider, fileID string) (string, *domain.DocumentData, error) {
+	token, err := s.token(ctx, principal, drive, provider)
+	if err != nil {
+		return "", nil, err
+	}
+	file, body, err := drive.Download(ctx, token, fileID)
+	if err != nil {
+		return "", nil, err
+	}
+	defer body.Close()
+
+	doc, err := s.documents.Upload(ctx, principal, body, file.Name)
+	return file.Name, doc, err
+}
+
+// GetImportJob returns any importer's job, since they share the tracker.
+func (s *CloudImportService) GetImportJob(ctx context.Context, principal domain.Principal, jobID string) (*domain.ImportJob, error) {
+	return s.jobs.get(principal, jobID)
+}
@@ -0,0 +1,152 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockCloudDrive struct {
+	files     map[string]string // name by file ID; the content is the name too
+	refreshed bool
+}
+
+func (d *mockCloudDrive) AuthURL(state string) string {
+	return "https://drive.example.com/auth?state=" + state
+}
+
+func (d *mockCloudDrive) Exchange(ctx context.Context, code string) (*domain.CloudToken, error) {
+	return &domain.CloudToken{AccessToken: "access-" + code, RefreshToken: "refresh"}, nil
+}
+
+func (d *mockCloudDrive) Refresh(ctx context.Context, token *domain.CloudToken) (*domain.CloudToken, error) {
+	d.refreshed = true
+	return &domain.CloudToken{AccessToken: "fresh", RefreshToken: token.RefreshToken, Expiry: time.Now().Add(time.Hour)}, nil
+}
+
+func (d *mockCloudDrive) ListFiles(ctx context.Context, token *domain.CloudToken, query string) ([]domain.CloudFile, error) {
+	if token.AccessToken != "fresh" {
+		return nil, errors.New("expired token")
+	}
+	return []domain.CloudFile{{ID: "f1", Name: d.files["f1"]}}, nil
+}
+
+func (d *mockCloudDrive) Download(ctx context.Context, token *domain.CloudToken, fileID string) (*domain.CloudFile, io.ReadCloser, error) {
+	name, ok := d.files[fileID]
+	if !ok {
+		return nil, nil, errors.New("file not found")
+	}
+	return &domain.CloudFile{ID: fileID, Name: name}, io.NopCloser(strings.NewReader("Chapter one of " + name)), nil
+}
+
+type mockCloudConnectionRepo struct {
+	connections map[string]*domain.CloudConnection // by provider
+}
+
+func (m *mockCloudConnectionRepo) Get(ctx context.Context, principal domain.Principal, provider string) (*domain.CloudConnection, error) {
+	connection, ok := m.connections[provider]
+	if !ok {
+		return nil, domain.ErrCloudNotConnected
+	}
+	copied := *connection
+	return &copied, nil
+}
+
+func (m *mockCloudConnectionRepo) Upsert(ctx context.Context, principal domain.Principal, connection *domain.CloudConnection) error {
+	m.connections[connection.Provider] = connection
+	return nil
+}
+
+func (m *mockCloudConnectionRepo) Delete(ctx context.Context, principal domain.Principal, provider string) error {
+	delete(m.connections, provider)
+	return nil
+}
+
+func newTestCloudImportService(drive *mockCloudDrive) (domain.CloudImportService, *mockCloudConnectionRepo, *MockDocumentRepository) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	connections := &mockCloudConnectionRepo{connections: map[string]*domain.CloudConnection{}}
+	drives := map[string]domain.CloudDrive{domain.CloudProviderDropbox: drive}
+	return NewCloudImportService(drives, connections, documents, NewImportJobTracker(), logger), connections, repo
+}
+
+func TestCloudImportService_ConnectAndRefresh(t *testing.T) {
+	drive := &mockCloudDrive{files: map[string]string{"f1": "notes.txt"}}
+	s, connections, _ := newTestCloudImportService(drive)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	if _, err := s.AuthURL(domain.CloudProviderGoogleDrive, "state"); !errors.Is(err, domain.ErrCloudProviderNotFound) {
+		t.Fatalf("expected an unconfigured provider error, got %v", err)
+	}
+	if _, err := s.ListFiles(ctx, principal, domain.CloudProviderDropbox, ""); !errors.Is(err, domain.ErrCloudNotConnected) {
+		t.Fatalf("expected a not connected error, got %v", err)
+	}
+
+	if err := s.Connect(ctx, principal, domain.CloudProviderDropbox, "code"); err != nil {
+		t.Fatalf("connect failed: %v", err)
+	}
+	// Expire the stored token: listing refreshes it and stores the new one.
+	connections.connections[domain.CloudProviderDropbox].Token.Expiry = time.Now().Add(-time.Hour)
+
+	files, err := s.ListFiles(ctx, principal, domain.CloudProviderDropbox, "")
+	if err != nil || len(files) != 1 {
+		t.Fatalf("unexpected files %+v (%v)", files, err)
+	}
+	if !drive.refreshed || connections.connections[domain.CloudProviderDropbox].Token.AccessToken != "fresh" {
+		t.Fatal("expected the refreshed token to be stored")
+	}
+}
+
+func TestCloudImportService_StartImport(t *testing.T) {
+	drive := &mockCloudDrive{files: map[string]string{"f1": "notes.txt", "f2": "report.docx"}}
+	s, connections, repo := newTestCloudImportService(drive)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	connections.connections[domain.CloudProviderDropbox] = &domain.CloudConnection{Provider: domain.CloudProviderDropbox, Token: domain.CloudToken{AccessToken: "fresh"}}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.StartImport(ctx, principal, domain.CloudProviderDropbox, []string{""}); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+
+	job, err := s.StartImport(ctx, principal, domain.CloudProviderDropbox, []string{"f1", "f2", "f1", "missing"})
+	if err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	if len(job.Files) != 3 {
+		t.Fatalf("expected duplicate file IDs to be dropped, got %+v", job.Files)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != domain.ImportStatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("import did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if job, err = s.GetImportJob(ctx, principal, job.ID); err != nil {
+			t.Fatalf("get job failed: %v", err)
+		}
+	}
+
+	if job.Completed != 1 || job.Failed != 2 {
+		t.Fatalf("expected 1 completed and 2 failed files, got %+v", job)
+	}
+	imported := job.Files[0]
+	if imported.Status != domain.ImportStatusDone || repo.documents[imported.DocumentID] == nil {
+		t.Fatalf("expected the text file to be imported, got %+v", imported)
+	}
+	if got := job.Files[1]; got.Status != domain.ImportStatusFailed || got.Error != "Unsupported or unreadable file" {
+		t.Fatalf("unexpected failed file %+v", got)
+	}
+
+	if _, err := s.GetImportJob(ctx, testPrincipal("user2"), job.ID); !errors.Is(err, domain.ErrImportJobNotFound) {
+		t.Fatalf("expected other users not to see the job, got %v", err)
+	}
+}
@@ -0,0 +1,206 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"io"
+	"path"
+	"sort"
+	"strings"
+
+	// Decoders used to read page dimensions.
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// comicImageTypes are the page image extensions read from comic archives.
+var comicImageTypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+	".avif": "image/avif",
+}
+
+// rarSignature prefixes both RAR 4 and RAR 5 archives.
+var rarSignature = []byte("Rar!\x1a\x07")
+
+// ComicProcessor unpacks comic book archives into ordered page images
+type ComicProcessor struct {
+	logger domain.Logger
+}
+
+// NewComicProcessor creates a new comic processor
+func NewComicProcessor(logger domain.Logger) *ComicProcessor {
+	return &ComicProcessor{logger: logger}
+}
+
+// ComicPage is one page image inside the archive. Width and Height are zero for
+// formats the standard library cannot decode (WebP, AVIF).
+type ComicPage struct {
+	Name        string
+	ContentType string
+	Width       int
+	Height      int
+	Data        []byte
+}
+
+// ComicResult is the output of comic extraction, with pages in reading order.
+type ComicResult struct {
+	Pages    []ComicPage
+	Title    string
+	Author   string
+	Language string
+}
+
+// comicInfo is the subset of the ComicRack ComicInfo.xml schema we read.
+type comicInfo struct {
+	Title       string `xml:"Title"`
+	Series      string `xml:"Series"`
+	Number      string `xml:"Number"`
+	Writer      string `xml:"Writer"`
+	LanguageISO string `xml:"LanguageISO"`
+}
+
+// ProcessComic extracts the page images of a CBZ archive, ordered by file name with
+// numeric runs compared by value so "page2" sorts before "page10".
+func (p *ComicProcessor) ProcessComic(r io.ReaderAt, size int64) (*ComicResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to open comic archive: %v", domain.ErrInvalidFile, err)
+	}
+
+	result := &ComicResult{}
+	var pages []*zip.File
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() || isHiddenArchivePath(f.Name) {
+			continue
+		}
+		if strings.EqualFold(path.Base(f.Name), "ComicInfo.xml") {
+			p.readComicInfo(f, result)
+			continue
+		}
+		if _, ok := comicImageTypes[strings.ToLower(path.Ext(f.Name))]; ok {
+			pages = append(pages, f)
+		}
+	}
+	if len(pages) == 0 {
+		return nil, fmt.Errorf("%w: comic archive contains no page images", domain.ErrInvalidFile)
+	}
+
+	sort.SliceStable(pages, func(i, j int) bool {
+		return naturalLess(strings.ToLower(pages[i].Name), strings.ToLower(pages[j].Name))
+	})
+
+	for _, f := range pages {
+		img, err := readZipFile(f, maxEPUBEntrySize)
+		if err != nil {
+			p.logger.Warn("Skipping unreadable comic page", "page", f.Name, "error", err)
+			continue
+		}
+		page := ComicPage{
+			Name:        f.Name,
+			ContentType: comicImageTypes[strings.ToLower(path.Ext(f.Name))],
+			Data:        img,
+		}
+		if cfg, _, err := image.DecodeConfig(bytes.NewReader(img)); err == nil {
+			page.Width, page.Height = cfg.Width, cfg.Height
+		}
+		result.Pages = append(result.Pages, page)
+	}
+	if len(result.Pages) == 0 {
+		return nil, fmt.Errorf("%w: comic archive pages could not be read", domain.ErrInvalidFile)
+	}
+
+	return result, nil
+}
+
+func (p *ComicProcessor) readComicInfo(f *zip.File, result *ComicResult) {
+	data, err := readZipFile(f, maxEPUBEntrySize)
+	if err != nil {
+		return
+	}
+	var info comicInfo
+	if err := xml.Unmarshal(data, &info); err != nil {
+		p.logger.Warn("Ignoring invalid ComicInfo.xml", "error", err)
+		return
+	}
+
+	result.Title = strings.TrimSpace(info.Title)
+	if result.Title == "" && strings.TrimSpace(info.Series) != "" {
+		result.Title = strings.TrimSpace(info.Series)
+		if number := strings.TrimSpace(info.Number); number != "" {
+			result.Title += " #" + number
+		}
+	}
+	result.Author = strings.TrimSpace(info.Writer)
+	result.Language = strings.TrimSpace(info.LanguageISO)
+}
+
+// isComicArchive reports whether the file is a zip archive holding at least one page image.
+func isComicArchive(r io.ReaderAt, size int64) bool {
+	if !bytes.HasPrefix(readHead(r, size, 4), []byte("PK\x03\x04")) {
+		return false
+	}
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return false
+	}
+	for _, f := range zr.File {
+		if _, ok := comicImageTypes[strings.ToLower(path.Ext(f.Name))]; ok && !isHiddenArchivePath(f.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+// isHiddenArchivePath skips macOS resource forks and dotfiles bundled by archivers.
+func isHiddenArchivePath(name string) bool {
+	for _, part := range strings.Split(name, "/") {
+		if strings.HasPrefix(part, ".") || part == "__MACOSX" {
+			return true
+		}
+	}
+	return false
+}
+
+// naturalLess compares strings treating runs of digits as numbers.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, restA := splitDigits(a)
+			nb, restB := splitDigits(b)
+			ta, tb := strings.TrimLeft(na, "0"), strings.TrimLeft(nb, "0")
+			if len(ta) != len(tb) {
+				return len(ta) < len(tb)
+			}
+			if ta != tb {
+				return ta < tb
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func splitDigits(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"image"
+	"image/png"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// testPNG encodes a blank PNG of the given size.
+func testPNG(t *testing.T, width, height int) string {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.String()
+}
+
+// testCBZ builds a zip archive with entries written in the given order.
+func testCBZ(t *testing.T, files ...[2]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range files {
+		w, err := zw.Create(file[0])
+		if err != nil {
+			t.Fatalf("create %s: %v", file[0], err)
+		}
+		_, _ = w.Write([]byte(file[1]))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestComicProcessor_ProcessComic(t *testing.T) {
+	data := testCBZ(t,
+		[2]string{"Issue 1/page10.jpg", "jpeg-bytes"},
+		[2]string{"Issue 1/page2.png", testPNG(t, 80, 120)},
+		[2]string{"Issue 1/Page1.png", testPNG(t, 40, 60)},
+		[2]string{"__MACOSX/Issue 1/._page2.png", "resource fork"},
+		[2]string{"Issue 1/.thumb.png", "hidden"},
+		[2]string{"Issue 1/notes.txt", "not a page"},
+		[2]string{"ComicInfo.xml", `<?xml version="1.0"?>
+<ComicInfo><Series>Moby Dick</Series><Number>1</Number><Writer>Herman Melville</Writer><LanguageISO>en</LanguageISO></ComicInfo>`},
+	)
+
+	comic, err := NewComicProcessor(NewMockLogger()).ProcessComic(zipReader(data))
+	if err != nil {
+		t.Fatalf("ProcessComic: %v", err)
+	}
+
+	if comic.Title != "Moby Dick #1" || comic.Author != "Herman Melville" || comic.Language != "en" {
+		t.Fatalf("unexpected metadata: %q, %q, %q", comic.Title, comic.Author, comic.Language)
+	}
+
+	want := []struct {
+		name          string
+		contentType   string
+		width, height int
+	}{
+		{"Issue 1/Page1.png", "image/png", 40, 60},
+		{"Issue 1/page2.png", "image/png", 80, 120},
+		{"Issue 1/page10.jpg", "image/jpeg", 0, 0},
+	}
+	if len(comic.Pages) != len(want) {
+		t.Fatalf("expected %d pages, got %d", len(want), len(comic.Pages))
+	}
+	for i, w := range want {
+		got := comic.Pages[i]
+		if got.Name != w.name || got.ContentType != w.contentType || got.Width != w.width || got.Height != w.height {
+			t.Fatalf("page %d: expected %+v, got %s %s %dx%d", i, w, got.Name, got.ContentType, got.Width, got.Height)
+		}
+	}
+}
+
+func TestComicProcessor_ProcessComic_NoPages(t *testing.T) {
+	p := NewComicProcessor(NewMockLogger())
+	if _, err := p.ProcessComic(zipReader([]byte("not a zip"))); !errors.Is(err, domain.ErrInvalidFile) {
+		t.Fatalf("expected ErrInvalidFile for non-zip input, got %v", err)
+	}
+	if _, err := p.ProcessComic(zipReader(testCBZ(t, [2]string{"readme.txt", "hello"}))); !errors.Is(err, domain.ErrInvalidFile) {
+		t.Fatalf("expected ErrInvalidFile for archive without images, got %v", err)
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"page2", "page10", true},
+		{"page10", "page2", false},
+		{"page002", "page10", true},
+		{"ch1/p9", "ch2/p1", true},
+		{"a", "a1", true},
+		{"a1", "a1", false},
+		{"cover", "page1", true},
+	}
+	for _, tt := range tests {
+		if got := naturalLess(tt.a, tt.b); got != tt.want {
+			t.Errorf("naturalLess(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type DigestService struct {
+	repo     domain.DigestRepository
+	sender   domain.EmailSender
+	interval time.Duration
+	logger   domain.Logger
+	now      func() time.Time
+}
+
+func NewDigestService(
+	repo domain.DigestRepository,
+	sender domain.EmailSender,
+	config domain.DigestConfig,
+	logger domain.Logger,
+) domain.DigestService {
+	return &DigestService{
+		repo:     repo,
+		sender:   sender,
+		interval: config.CheckInterval,
+		logger:   logger,
+		now:      time.Now,
+	}
+}
+
+// Run sends the due digests right away and then on every check interval. Digests
+// are at least a week apart per user, so restarting the server never sends twice.
+func (s *DigestService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		sent, err := s.SendDueDigests(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Weekly digest run failed", err)
+		} else if sent > 0 {
+			s.logger.Info("Sent weekly reading digests", "count", sent)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// SendDueDigests e-mails every opted-in user not sent a digest in the last period.
+// A failure for one user is logged and retried on the next run; it does not stop
+// the others. Users with a quiet week are marked as done without an e-mail.
+func (s *DigestService) SendDueDigests(ctx context.Context) (int, error) {
+	now := s.now()
+	since := now.Add(-domain.DigestPeriod)
+
+	recipients, err := s.repo.ListDigestRecipients(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, recipient := range recipients {
+		if err := ctx.Err(); err != nil {
+			return sent, err
+		}
+
+		digest, err := s.repo.GetReadingDigest(ctx, recipient.UserID, since)
+		if err != nil {
+			s.logger.Error("Failed to build reading digest", err, "user_id", recipient.UserID)
+			continue
+		}
+		if len(digest.Books) > 0 || digest.Highlights > 0 {
+			msg, err := renderDigest(recipient.Email, digest)
+			if err != nil {
+				s.logger.Error("Failed to render reading digest", err, "user_id", recipient.UserID)
+				continue
+			}
+			if err := s.sender.Send(ctx, msg); err != nil {
+				s.logger.Error("Failed to send reading digest", err, "user_id", recipient.UserID)
+				continue
+			}
+			sent++
+		}
+		if err := s.repo.MarkDigestSent(ctx, recipient.UserID, now); err != nil {
+			s.logger.Error("Failed to record reading digest", err, "user_id", recipient.UserID)
+		}
+	}
+
+	return sent, nil
+}
+
+// digestBookLine describes one book of the digest, e.g. "Middlemarch: 40% (page 120)".
+func digestBookLine(book domain.DigestBook) string {
+	if book.Progress >= domain.FinishedProgress {
+		return fmt.Sprintf("%s: finished", book.Title)
+	}
+	return fmt.Sprintf("%s: %d%% (page %d)", book.Title, int(book.Progress*100), book.PageNumber)
+}
+
+var digestHTML = template.Must(template.New("digest").Parse(`<h1>Your week in reading</h1>
+{{if .Books}}<p>You read {{len .Books}} {{if eq (len .Books) 1}}book{{else}}books{{end}} this week:</p>
+<ul>{{range .Lines}}<li>{{.}}</li>{{end}}</ul>{{end}}
+{{if .Highlights}}<p>You made {{.Highlights}} {{if eq .Highlights 1}}highlight{{else}}highlights{{end}}.</p>{{end}}
+{{with .Current}}<p>You left off on page {{.PageNumber}} of <strong>{{.Title}}</strong>.</p>{{end}}
+<p><small>You get this e-mail because the weekly digest is on in your Lector settings.</small></p>
+`))
+
+// renderDigest builds the digest e-mail with a plain-text and an HTML body.
+func renderDigest(to string, digest *domain.ReadingDigest) (*domain.EmailMessage, error) {
+	lines := make([]string, len(digest.Books))
+	for i, book := range digest.Books {
+		lines[i] = digestBookLine(book)
+	}
+
+	var text strings.Builder
+	text.WriteString("Your week in reading\n\n")
+	if len(lines) > 0 {
+		fmt.Fprintf(&text, "You read %s this week:\n", plural(len(lines), "book"))
+		for _, line := range lines {
+			fmt.Fprintf(&text, "- %s\n", line)
+		}
+		text.WriteString("\n")
+	}
+	if digest.Highlights > 0 {
+		fmt.Fprintf(&text, "You made %s.\n\n", plural(digest.Highlights, "highlight"))
+	}
+	if book := digest.Current; book != nil {
+		fmt.Fprintf(&text, "You left off on page %d of %s.\n\n", book.PageNumber, book.Title)
+	}
+	text.WriteString("You get this e-mail because the weekly digest is on in your Lector settings.\n")
+
+	var html bytes.Buffer
+	err := digestHTML.Execute(&html, struct {
+		*domain.ReadingDigest
+		Lines []string
+	}{digest, lines})
+	if err != nil {
+		return nil, fmt.Errorf("failed to render digest: %w", err)
+	}
+
+	return &domain.EmailMessage{
+		To:      to,
+		Subject: "Your week in reading",
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
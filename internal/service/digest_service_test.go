@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockDigestRepo struct {
+	recipients []domain.DigestRecipient
+	digests    map[string]*domain.ReadingDigest
+	sentBefore time.Time
+	sent       map[string]time.Time
+}
+
+func (m *mockDigestRepo) ListDigestRecipients(ctx context.Context, sentBefore time.Time) ([]domain.DigestRecipient, error) {
+	m.sentBefore = sentBefore
+	return m.recipients, nil
+}
+
+func (m *mockDigestRepo) GetReadingDigest(ctx context.Context, userID string, since time.Time) (*domain.ReadingDigest, error) {
+	digest, ok := m.digests[userID]
+	if !ok {
+		return nil, errors.New("no activity")
+	}
+	return digest, nil
+}
+
+func (m *mockDigestRepo) MarkDigestSent(ctx context.Context, userID string, at time.Time) error {
+	m.sent[userID] = at
+	return nil
+}
+
+type mockEmailSender struct {
+	messages []*domain.EmailMessage
+	fail     map[string]bool
+}
+
+func (m *mockEmailSender) Send(ctx context.Context, msg *domain.EmailMessage) error {
+	if m.fail[msg.To] {
+		return errors.New("mailbox unavailable")
+	}
+	m.messages = append(m.messages, msg)
+	return nil
+}
+
+func TestDigestService_SendDueDigests(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	current := domain.DigestBook{DocumentID: "doc-1", Title: "Middlemarch", Progress: 0.4, PageNumber: 120}
+	repo := &mockDigestRepo{
+		recipients: []domain.DigestRecipient{
+			{UserID: "reader", Email: "reader@example.com"},
+			{UserID: "quiet", Email: "quiet@example.com"},
+			{UserID: "bouncing", Email: "bouncing@example.com"},
+			{UserID: "broken", Email: "broken@example.com"},
+		},
+		digests: map[string]*domain.ReadingDigest{
+			"reader": {
+				Books: []domain.DigestBook{
+					current,
+					{DocumentID: "doc-2", Title: "Tom & Jerry", Progress: 1, PageNumber: 80},
+				},
+				Highlights: 3,
+				Current:    &current,
+			},
+			"quiet":    {},
+			"bouncing": {Highlights: 1},
+		},
+		sent: map[string]time.Time{},
+	}
+	sender := &mockEmailSender{fail: map[string]bool{"bouncing@example.com": true}}
+
+	s := NewDigestService(repo, sender, domain.DigestConfig{CheckInterval: time.Hour}, NewMockLogger()).(*DigestService)
+	s.now = func() time.Time { return now }
+
+	sent, err := s.SendDueDigests(context.Background())
+	if err != nil {
+		t.Fatalf("SendDueDigests: %v", err)
+	}
+	if sent != 1 || len(sender.messages) != 1 {
+		t.Fatalf("expected one digest sent, got %d", sent)
+	}
+	if !repo.sentBefore.Equal(now.Add(-domain.DigestPeriod)) {
+		t.Fatalf("expected recipients not sent a digest for a week, got %v", repo.sentBefore)
+	}
+
+	// The quiet week is marked as done; failures are retried on the next run.
+	if _, ok := repo.sent["reader"]; !ok {
+		t.Fatalf("expected the delivery to be recorded")
+	}
+	if _, ok := repo.sent["quiet"]; !ok {
+		t.Fatalf("expected a quiet week to be marked as done")
+	}
+	if _, ok := repo.sent["bouncing"]; ok {
+		t.Fatalf("expected a failed delivery to be retried")
+	}
+
+	msg := sender.messages[0]
+	for _, want := range []string{
+		"You read 2 books this week:",
+		"- Middlemarch: 40% (page 120)",
+		"- Tom & Jerry: finished",
+		"You made 3 highlights.",
+		"You left off on page 120 of Middlemarch.",
+	} {
+		if !strings.Contains(msg.Text, want) {
+			t.Fatalf("expected %q in the text body:\n%s", want, msg.Text)
+		}
+	}
+	if !strings.Contains(msg.HTML, "<li>Tom &amp; Jerry: finished</li>") {
+		t.Fatalf("expected escaped titles in the HTML body:\n%s", msg.HTML)
+	}
+}
@@ -0,0 +1,180 @@
+package service
+
+import (
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// maxDiffCells caps the LCS table for a single page. Pages beyond it are reported as
+// one replace block rather than diffed paragraph by paragraph.
+const maxDiffCells = 1 << 20
+
+// diffParagraph is a text block reduced to what the diff compares.
+type diffParagraph struct {
+	text     string
+	key      string // whitespace-normalized text used for equality
+	position int
+}
+
+// compareBlocks diffs two documents page by page: page N of left is compared with
+// page N of right, and pages present on one side only are added or removed whole.
+func compareBlocks(left, right []TextBlock) ([]domain.PageComparison, domain.ComparisonSummary) {
+	leftPages, rightPages := paragraphsByPage(left), paragraphsByPage(right)
+	pageCount := max(len(leftPages), len(rightPages))
+
+	var summary domain.ComparisonSummary
+	pages := make([]domain.PageComparison, 0, pageCount)
+	for i := 0; i < pageCount; i++ {
+		page := domain.PageComparison{Page: i + 1}
+		var l, r []diffParagraph
+		if i < len(leftPages) {
+			l = leftPages[i]
+		}
+		if i < len(rightPages) {
+			r = rightPages[i]
+		}
+
+		switch {
+		case i >= len(leftPages):
+			page.Status = domain.PageAdded
+		case i >= len(rightPages):
+			page.Status = domain.PageRemoved
+		default:
+			page.Status = domain.PageUnchanged
+		}
+
+		changes := diffParagraphs(l, r)
+		changed := false
+		for _, change := range changes {
+			switch change.Type {
+			case domain.ChangeInsert:
+				summary.ParagraphsInserted += len(change.Right)
+			case domain.ChangeDelete:
+				summary.ParagraphsDeleted += len(change.Left)
+			case domain.ChangeReplace:
+				summary.ParagraphsReplaced += len(change.Left)
+			}
+			changed = changed || change.Type != domain.ChangeEqual
+		}
+		if changed {
+			if page.Status == domain.PageUnchanged {
+				page.Status = domain.PageChanged
+			}
+			page.Changes = changes
+			summary.PagesChanged++
+		}
+		pages = append(pages, page)
+	}
+	return pages, summary
+}
+
+// paragraphsByPage groups non-empty blocks by page number. Pages without text keep
+// their slot so page numbers stay aligned.
+func paragraphsByPage(blocks []TextBlock) [][]diffParagraph {
+	var pages [][]diffParagraph
+	for _, block := range blocks {
+		if block.PageNumber < 1 {
+			continue
+		}
+		for len(pages) < block.PageNumber {
+			pages = append(pages, nil)
+		}
+		key := strings.Join(strings.Fields(block.Content), " ")
+		if key == "" {
+			continue
+		}
+		pages[block.PageNumber-1] = append(pages[block.PageNumber-1], diffParagraph{
+			text:     block.Content,
+			key:      key,
+			position: block.Position,
+		})
+	}
+	return pages
+}
+
+// diffParagraphs computes a longest-common-subsequence diff and groups it into change
+// blocks. Deletions directly followed by insertions become a replace block.
+func diffParagraphs(left, right []diffParagraph) []domain.ChangeBlock {
+	if len(left)*len(right) > maxDiffCells {
+		return []domain.ChangeBlock{newChangeBlock(left, right)}
+	}
+
+	// lcs[i][j] is the LCS length of left[i:] and right[j:].
+	lcs := make([][]int, len(left)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(right)+1)
+	}
+	for i := len(left) - 1; i >= 0; i-- {
+		for j := len(right) - 1; j >= 0; j-- {
+			if left[i].key == right[j].key {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var changes []domain.ChangeBlock
+	var dels, ins, eqLeft, eqRight []diffParagraph
+	flushChanged := func() {
+		if len(dels) > 0 || len(ins) > 0 {
+			changes = append(changes, newChangeBlock(dels, ins))
+			dels, ins = nil, nil
+		}
+	}
+	flushEqual := func() {
+		if len(eqLeft) > 0 {
+			block := newChangeBlock(eqLeft, eqRight)
+			block.Type = domain.ChangeEqual
+			changes = append(changes, block)
+			eqLeft, eqRight = nil, nil
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(left) || j < len(right) {
+		switch {
+		case i < len(left) && j < len(right) && left[i].key == right[j].key:
+			flushChanged()
+			eqLeft, eqRight = append(eqLeft, left[i]), append(eqRight, right[j])
+			i, j = i+1, j+1
+		case j == len(right) || (i < len(left) && lcs[i+1][j] >= lcs[i][j+1]):
+			flushEqual()
+			dels = append(dels, left[i])
+			i++
+		default:
+			flushEqual()
+			ins = append(ins, right[j])
+			j++
+		}
+	}
+	flushChanged()
+	flushEqual()
+	return changes
+}
+
+// newChangeBlock builds a delete, insert or replace block depending on which sides
+// have paragraphs.
+func newChangeBlock(left, right []diffParagraph) domain.ChangeBlock {
+	block := domain.ChangeBlock{Type: domain.ChangeReplace}
+	switch {
+	case len(right) == 0:
+		block.Type = domain.ChangeDelete
+	case len(left) == 0:
+		block.Type = domain.ChangeInsert
+	}
+	for _, p := range left {
+		block.Left = append(block.Left, p.text)
+	}
+	for _, p := range right {
+		block.Right = append(block.Right, p.text)
+	}
+	if len(left) > 0 {
+		block.LeftPosition = left[0].position
+	}
+	if len(right) > 0 {
+		block.RightPosition = right[0].position
+	}
+	return block
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"reflect"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestCompareBlocks(t *testing.T) {
+	left := []TextBlock{
+		{Content: "1. Parties", PageNumber: 1, Position: 0},
+		{Content: "The Supplier shall deliver  monthly.", PageNumber: 1, Position: 1},
+		{Content: "Payment is due in 30 days.", PageNumber: 1, Position: 2},
+		{Content: "Governing law: Delaware.", PageNumber: 1, Position: 3},
+		{Content: "2. Term", PageNumber: 2, Position: 0},
+		{Content: "Signatures", PageNumber: 3, Position: 0},
+	}
+	right := []TextBlock{
+		{Content: "1. Parties", PageNumber: 1, Position: 0},
+		{Content: "The Supplier shall deliver monthly.", PageNumber: 1, Position: 1},
+		{Content: "Payment is due in 60 days.", PageNumber: 1, Position: 2},
+		{Content: "Late fees apply.", PageNumber: 1, Position: 3},
+		{Content: "2. Term", PageNumber: 2, Position: 0},
+		{Content: "Renewal is automatic.", PageNumber: 2, Position: 1},
+	}
+
+	pages, summary := compareBlocks(left, right)
+
+	want := []domain.PageComparison{
+		{Page: 1, Status: domain.PageChanged, Changes: []domain.ChangeBlock{
+			{Type: domain.ChangeEqual, Left: []string{"1. Parties", "The Supplier shall deliver  monthly."}, Right: []string{"1. Parties", "The Supplier shall deliver monthly."}},
+			{Type: domain.ChangeReplace, Left: []string{"Payment is due in 30 days.", "Governing law: Delaware."}, Right: []string{"Payment is due in 60 days.", "Late fees apply."}, LeftPosition: 2, RightPosition: 2},
+		}},
+		{Page: 2, Status: domain.PageChanged, Changes: []domain.ChangeBlock{
+			{Type: domain.ChangeEqual, Left: []string{"2. Term"}, Right: []string{"2. Term"}},
+			{Type: domain.ChangeInsert, Right: []string{"Renewal is automatic."}, RightPosition: 1},
+		}},
+		{Page: 3, Status: domain.PageRemoved, Changes: []domain.ChangeBlock{
+			{Type: domain.ChangeDelete, Left: []string{"Signatures"}},
+		}},
+	}
+	if !reflect.DeepEqual(pages, want) {
+		t.Fatalf("unexpected pages:\n got %+v\nwant %+v", pages, want)
+	}
+
+	wantSummary := domain.ComparisonSummary{PagesChanged: 3, ParagraphsInserted: 1, ParagraphsDeleted: 1, ParagraphsReplaced: 2}
+	if summary != wantSummary {
+		t.Fatalf("expected summary %+v, got %+v", wantSummary, summary)
+	}
+}
+
+func TestCompareBlocks_Identical(t *testing.T) {
+	blocks := []TextBlock{
+		{Content: "Same", PageNumber: 1},
+		{Content: "", PageNumber: 2},
+		{Content: "Text", PageNumber: 3},
+	}
+
+	pages, summary := compareBlocks(blocks, blocks)
+	if summary != (domain.ComparisonSummary{}) {
+		t.Fatalf("expected empty summary, got %+v", summary)
+	}
+	if len(pages) != 3 {
+		t.Fatalf("expected 3 aligned pages, got %d", len(pages))
+	}
+	for _, page := range pages {
+		if page.Status != domain.PageUnchanged || page.Changes != nil {
+			t.Fatalf("expected unchanged page without changes, got %+v", page)
+		}
+	}
+}
@@ -0,0 +1,357 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"unicode"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// ExportDocument regenerates a file in the given format from the document's
+// extracted text. Images are not carried over: only their storage path is kept
+// in the text, and exports are built from the text alone.
+func (s *DocumentService) ExportDocument(ctx context.Context, principal domain.Principal, documentID string, format string) (*domain.DocumentExport, error) {
+	if err := domain.ValidateExportFormat(format); err != nil {
+		return nil, err
+	}
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := exportBlocks(document)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &domain.DocumentExport{Filename: exportFilename(document.Title) + "." + format}
+	switch format {
+	case domain.ExportFormatTXT:
+		export.ContentType = "text/plain; charset=utf-8"
+		export.Data = exportText(document, blocks)
+	case domain.ExportFormatEPUB:
+		export.ContentType = "application/epub+zip"
+		export.Data, err = exportEPUB(document, blocks)
+	case domain.ExportFormatPDF:
+		export.ContentType = "application/pdf"
+		export.Data, err = exportPDF(document, blocks)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("export %s: %w", format, err)
+	}
+	s.logger.Info("Document exported", "doc_id", documentID, "format", format, "bytes", len(export.Data))
+	return export, nil
+}
+
+// PlainText returns the document's extracted text as it was read, one block per
+// paragraph and a form feed at each page break, the way pdftotext prints it.
+func (s *DocumentService) PlainText(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentExport, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	blocks, err := exportBlocks(document)
+	if err != nil {
+		return nil, err
+	}
+
+	var b bytes.Buffer
+	for i, block := range blocks {
+		if i > 0 {
+			if block.PageNumber != blocks[i-1].PageNumber {
+				b.WriteString("\f")
+			} else {
+				b.WriteString("\n")
+			}
+		}
+		b.WriteString(block.Content)
+		b.WriteString("\n")
+	}
+	return &domain.DocumentExport{
+		Filename:    exportFilename(document.Title) + ".txt",
+		ContentType: "text/plain; charset=utf-8",
+		Data:        b.Bytes(),
+	}, nil
+}
+
+// exportBlocks returns the document's paragraphs and headings. Comics and
+// documents without extracted text have nothing to export.
+func exportBlocks(doc *domain.DocumentData) ([]TextBlock, error) {
+	if doc.Metadata.Format == fileTypeCBZ.Format {
+		return nil, domain.ErrDocumentHasNoText
+	}
+	var blocks []TextBlock
+	if err := json.Unmarshal(doc.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDocumentHasNoText, err)
+	}
+	text := blocks[:0]
+	for _, block := range blocks {
+		block.Content = strings.TrimSpace(block.Content)
+		if block.Type == "image" || block.Content == "" {
+			continue
+		}
+		text = append(text, block)
+	}
+	if len(text) == 0 {
+		return nil, domain.ErrDocumentHasNoText
+	}
+	return text, nil
+}
+
+// exportFilename turns a title into a file name safe in a Content-Disposition
+// header and on every file system.
+func exportFilename(title string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range title {
+		if r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+	}
+	name := strings.TrimSuffix(b.String(), "-")
+	if len(name) > 80 {
+		name = strings.TrimSuffix(name[:80], "-")
+	}
+	if name == "" {
+		return "document"
+	}
+	return name
+}
+
+func exportAuthor(doc *domain.DocumentData) string {
+	if doc.Author != nil {
+		return strings.TrimSpace(*doc.Author)
+	}
+	return ""
+}
+
+// exportText writes the title and author followed by the blocks, separated by
+// blank lines.
+func exportText(doc *domain.DocumentData, blocks []TextBlock) []byte {
+	var b bytes.Buffer
+	b.WriteString(doc.Title)
+	b.WriteString("\n")
+	if author := exportAuthor(doc); author != "" {
+		b.WriteString(author)
+		b.WriteString("\n")
+	}
+	for _, block := range blocks {
+		b.WriteString("\n")
+		if block.Type == "heading" {
+			b.WriteString("\n")
+		}
+		b.WriteString(block.Content)
+		b.WriteString("\n")
+	}
+	return b.Bytes()
+}
+
+// epubChapter is one XHTML file of an exported EPUB.
+type epubChapter struct {
+	ID     string
+	Title  string
+	Blocks []TextBlock
+}
+
+// splitChapters groups blocks into chapters: by source file for EPUBs, otherwise
+// at each top-level heading.
+func splitChapters(title string, blocks []TextBlock) []epubChapter {
+	topLevel := 0
+	for _, block := range blocks {
+		if block.Type == "heading" && (topLevel == 0 || headingLevel(block.Level) < topLevel) {
+			topLevel = headingLevel(block.Level)
+		}
+	}
+
+	var chapters []epubChapter
+	for i, block := range blocks {
+		split := i == 0
+		if block.Spine != "" {
+			split = split || block.Spine != blocks[i-1].Spine
+		} else if block.Type == "heading" && headingLevel(block.Level) == topLevel {
+			split = split || len(chapters[len(chapters)-1].Blocks) > 0
+		}
+		if split {
+			chapters = append(chapters, epubChapter{ID: fmt.Sprintf("chapter-%d", len(chapters)+1)})
+		}
+		chapter := &chapters[len(chapters)-1]
+		if chapter.Title == "" && block.Type == "heading" {
+			chapter.Title = block.Content
+		}
+		chapter.Blocks = append(chapter.Blocks, block)
+	}
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = title
+			if len(chapters) > 1 {
+				chapters[i].Title = fmt.Sprintf("%s (%d)", title, i+1)
+			}
+		}
+	}
+	return chapters
+}
+
+// headingLevel clamps a stored heading level to h1-h6; PDF headings without a
+// detected level read as h2.
+func headingLevel(level int) int {
+	switch {
+	case level <= 0:
+		return 2
+	case level > 6:
+		return 6
+	}
+	return level
+}
+
+func xmlEscape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+var epubTemplates = template.Must(template.New("epub").Funcs(template.FuncMap{
+	"x":     xmlEscape,
+	"level": headingLevel,
+}).Parse(`{{define "container"}}<?xml version="1.0" encoding="UTF-8"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles>
+    <rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/>
+  </rootfiles>
+</container>
+{{end}}{{define "opf"}}<?xml version="1.0" encoding="UTF-8"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0" unique-identifier="book-id">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:identifier id="book-id">urn:uuid:{{x .ID}}</dc:identifier>
+    <dc:title>{{x .Title}}</dc:title>
+{{- if .Author}}
+    <dc:creator>{{x .Author}}</dc:creator>
+{{- end}}
+    <dc:language>{{x .Language}}</dc:language>
+    <meta property="dcterms:modified">{{.Modified}}</meta>
+  </metadata>
+  <manifest>
+    <item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+{{- range .Chapters}}
+    <item id="{{.ID}}" href="{{.ID}}.xhtml" media-type="application/xhtml+xml"/>
+{{- end}}
+  </manifest>
+  <spine>
+{{- range .Chapters}}
+    <itemref idref="{{.ID}}"/>
+{{- end}}
+  </spine>
+</package>
+{{end}}{{define "nav"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops" lang="{{x .Language}}" xml:lang="{{x .Language}}">
+<head><title>{{x .Title}}</title></head>
+<body>
+<nav epub:type="toc" id="toc">
+<h1>{{x .Title}}</h1>
+<ol>
+{{- range .Chapters}}
+<li><a href="{{.ID}}.xhtml">{{x .Title}}</a></li>
+{{- end}}
+</ol>
+</nav>
+</body>
+</html>
+{{end}}{{define "chapter"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="{{x .Language}}" xml:lang="{{x .Language}}">
+<head><title>{{x .Chapter.Title}}</title></head>
+<body>
+{{- range .Chapter.Blocks}}
+{{if eq .Type "heading"}}{{$l := level .Level}}<h{{$l}}>{{x .Content}}</h{{$l}}>{{else}}<p>{{x .Content}}</p>{{end}}
+{{- end}}
+</body>
+</html>
+{{end}}{{define "article"}}<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE html>
+<html xmlns="http://www.w3.org/1999/xhtml" lang="en" xml:lang="en">
+<head><title>{{x .Title}}</title></head>
+<body>
+<h1>{{x .Title}}</h1>
+{{printf "%s" .Body}}
+</body>
+</html>
+{{end}}`))
+
+type epubFile struct {
+	name, template string
+	data           any
+}
+
+type epubExport struct {
+	ID, Title, Author, Language, Modified string
+	Chapters                              []epubChapter
+}
+
+// exportEPUB assembles an EPUB 3 book with one XHTML file per chapter and a
+// navigation document listing them.
+func exportEPUB(doc *domain.DocumentData, blocks []TextBlock) ([]byte, error) {
+	pkg := epubExport{
+		ID:       doc.ID,
+		Title:    doc.Title,
+		Author:   exportAuthor(doc),
+		Language: doc.Metadata.Language,
+		Modified: doc.UpdatedAt.UTC().Format(time.RFC3339),
+		Chapters: splitChapters(doc.Title, blocks),
+	}
+	if pkg.Language == "" {
+		pkg.Language = "en"
+	}
+	if doc.UpdatedAt.IsZero() {
+		pkg.Modified = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	var chapters []epubFile
+	for _, chapter := range pkg.Chapters {
+		chapters = append(chapters, epubFile{"OEBPS/" + chapter.ID + ".xhtml", "chapter", map[string]any{"Language": pkg.Language, "Chapter": chapter}})
+	}
+	return writeEPUB(pkg, chapters)
+}
+
+// writeEPUB zips the package document, the navigation document and the chapters.
+func writeEPUB(pkg epubExport, chapters []epubFile) ([]byte, error) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	// The mimetype entry must come first and be stored uncompressed.
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := w.Write([]byte("application/epub+zip")); err != nil {
+		return nil, err
+	}
+
+	files := []epubFile{
+		{"META-INF/container.xml", "container", nil},
+		{"OEBPS/content.opf", "opf", pkg},
+		{"OEBPS/nav.xhtml", "nav", pkg},
+	}
+	for _, file := range append(files, chapters...) {
+		w, err := zw.Create(file.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := epubTemplates.ExecuteTemplate(w, file.template, file.data); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,201 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+func newTestExportService(t *testing.T) *DocumentService {
+	t.Helper()
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	long := strings.Repeat("The sea was calm and the whale (a large one) swam on. ", 120)
+	content, err := json.Marshal([]TextBlock{
+		{Type: "heading", Content: "Loomings", Level: 1, PageNumber: 1},
+		{Type: "paragraph", Content: "Call me Ishmael — some years ago.", PageNumber: 1},
+		{Type: "image", Content: "A whale", Src: "u/doc/whale.png", PageNumber: 1},
+		{Type: "heading", Content: "The Carpet-Bag", Level: 1, PageNumber: 2},
+		{Type: "paragraph", Content: long, PageNumber: 2},
+		{Type: "paragraph", Content: "Fish & <chips>", PageNumber: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := "Herman Melville"
+	repo.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user1", Title: "Moby-Dick; or, The Whale", Author: &author, Content: content}
+	repo.documents["comic"] = &domain.Document{ID: "comic", UserID: "user1", Title: "Comic", Content: json.RawMessage(`[]`),
+		Metadata: domain.DocumentMetadata{Format: fileTypeCBZ.Format}}
+	return documents
+}
+
+func TestDocumentService_ExportDocument_Text(t *testing.T) {
+	s := newTestExportService(t)
+
+	export, err := s.ExportDocument(context.Background(), testPrincipal("user1"), "doc-1", domain.ExportFormatTXT)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if export.Filename != "Moby-Dick-or-The-Whale.txt" {
+		t.Errorf("filename = %q", export.Filename)
+	}
+	text := string(export.Data)
+	if !strings.HasPrefix(text, "Moby-Dick; or, The Whale\nHerman Melville\n\n\nLoomings\n\nCall me Ishmael — some years ago.\n") {
+		t.Errorf("unexpected text start: %q", text[:120])
+	}
+	if strings.Contains(text, "A whale") {
+		t.Error("image alt text should not be exported")
+	}
+}
+
+func TestDocumentService_ExportDocument_EPUB(t *testing.T) {
+	s := newTestExportService(t)
+
+	export, err := s.ExportDocument(context.Background(), testPrincipal("user1"), "doc-1", domain.ExportFormatEPUB)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+	if export.ContentType != "application/epub+zip" {
+		t.Errorf("content type = %q", export.ContentType)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(export.Data), int64(len(export.Data)))
+	if err != nil {
+		t.Fatalf("export is not a zip: %v", err)
+	}
+	if first := zr.File[0]; first.Name != "mimetype" || first.Method != zip.Store {
+		t.Errorf("first entry = %q (method %d), want stored mimetype", first.Name, first.Method)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(data)
+	}
+	for _, name := range []string{"META-INF/container.xml", "OEBPS/content.opf", "OEBPS/nav.xhtml", "OEBPS/chapter-1.xhtml", "OEBPS/chapter-2.xhtml"} {
+		if _, ok := files[name]; !ok {
+			t.Errorf("missing %s", name)
+		}
+	}
+	if _, ok := files["OEBPS/chapter-3.xhtml"]; ok {
+		t.Error("expected one chapter per top-level heading")
+	}
+	if !strings.Contains(files["OEBPS/content.opf"], "<dc:creator>Herman Melville</dc:creator>") {
+		t.Errorf("opf lacks the author:\n%s", files["OEBPS/content.opf"])
+	}
+	if !strings.Contains(files["OEBPS/nav.xhtml"], `<a href="chapter-2.xhtml">The Carpet-Bag</a>`) {
+		t.Errorf("nav lacks the second chapter:\n%s", files["OEBPS/nav.xhtml"])
+	}
+	if !strings.Contains(files["OEBPS/chapter-2.xhtml"], "<p>Fish &amp; &lt;chips&gt;</p>") {
+		t.Errorf("chapter text not escaped:\n%s", files["OEBPS/chapter-2.xhtml"])
+	}
+}
+
+func TestDocumentService_ExportDocument_PDF(t *testing.T) {
+	s := newTestExportService(t)
+
+	export, err := s.ExportDocument(context.Background(), testPrincipal("user1"), "doc-1", domain.ExportFormatPDF)
+	if err != nil {
+		t.Fatalf("export failed: %v", err)
+	}
+
+	doc, err := fitz.NewFromMemory(export.Data)
+	if err != nil {
+		t.Fatalf("export does not open as a PDF: %v", err)
+	}
+	defer doc.Close()
+	// Title page, one page per chapter, and the long paragraph runs onto another.
+	if doc.NumPage() < 4 {
+		t.Errorf("pages = %d, want at least 4", doc.NumPage())
+	}
+	var text strings.Builder
+	for i := 0; i < doc.NumPage(); i++ {
+		page, err := doc.Text(i)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text.WriteString(page)
+	}
+	for _, want := range []string{"Moby-Dick; or, The Whale", "Herman Melville", "Call me Ishmael — some years ago.", "Fish & <chips>", "(a large one)"} {
+		if !strings.Contains(text.String(), want) {
+			t.Errorf("PDF text lacks %q", want)
+		}
+	}
+	// MuPDF pads metadata values with NULs.
+	if title := strings.TrimRight(doc.Metadata()["title"], "\x00"); title != "Moby-Dick; or, The Whale" {
+		t.Errorf("PDF title = %q", title)
+	}
+}
+
+func TestDocumentService_PlainText(t *testing.T) {
+	s := newTestExportService(t)
+
+	text, err := s.PlainText(context.Background(), testPrincipal("user1"), "doc-1")
+	if err != nil {
+		t.Fatalf("plain text failed: %v", err)
+	}
+	if text.Filename != "Moby-Dick-or-The-Whale.txt" {
+		t.Errorf("filename = %q", text.Filename)
+	}
+	pages := strings.Split(string(text.Data), "\f")
+	if len(pages) != 3 {
+		t.Fatalf("pages = %d, want 3", len(pages))
+	}
+	if pages[0] != "Loomings\n\nCall me Ishmael — some years ago.\n" {
+		t.Errorf("first page = %q", pages[0])
+	}
+	if pages[2] != "Fish & <chips>\n" {
+		t.Errorf("last page = %q", pages[2])
+	}
+
+	if _, err := s.PlainText(context.Background(), testPrincipal("user1"), "comic"); !errors.Is(err, domain.ErrDocumentHasNoText) {
+		t.Errorf("comic: got %v, want ErrDocumentHasNoText", err)
+	}
+}
+
+func TestDocumentService_ExportDocument_Errors(t *testing.T) {
+	s := newTestExportService(t)
+	ctx := context.Background()
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.ExportDocument(ctx, testPrincipal("user1"), "doc-1", "docx"); !errors.As(err, &validationErrs) {
+		t.Errorf("unknown format: got %v, want validation error", err)
+	}
+	if _, err := s.ExportDocument(ctx, testPrincipal("user1"), "comic", domain.ExportFormatEPUB); !errors.Is(err, domain.ErrDocumentHasNoText) {
+		t.Errorf("comic: got %v, want ErrDocumentHasNoText", err)
+	}
+	if _, err := s.ExportDocument(ctx, testPrincipal("user2"), "doc-1", domain.ExportFormatTXT); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("other user: got %v, want ErrAccessDenied", err)
+	}
+}
+
+func TestPDFFontWrap(t *testing.T) {
+	lines := pdfTimesRoman.wrap(winAnsi("aaaa bbbb cccc"), 10, 45)
+	if len(lines) != 2 || string(lines[0]) != "aaaa bbbb" || string(lines[1]) != "cccc" {
+		t.Errorf("wrap = %q", lines)
+	}
+	long := pdfTimesRoman.wrap(winAnsi(strings.Repeat("m", 20)), 10, 40)
+	for _, line := range long {
+		if w := pdfTimesRoman.width(line, 10); w > 40 {
+			t.Errorf("line %q is %.1f wide", line, w)
+		}
+	}
+	if got := string(winAnsi("“Zoë” 日本")); got != "\x93Zo\xeb\x94 ??" {
+		t.Errorf("winAnsi = %q", got)
+	}
+}
@@ -0,0 +1,171 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// OpenFile opens the stored original of a document for the reader to stream. Access
+// is authorized like GetDocument on every call, so revoking a share or quarantining
+// the document takes effect on the next request.
+func (s *DocumentService) OpenFile(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentFile, error) {
+	doc, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	path, ok := originalPath(doc)
+	if !ok {
+		return nil, fmt.Errorf("%w: unsupported format %q", domain.ErrBlobNotFound, doc.Metadata.Format)
+	}
+	fileType := supportedFileTypes[doc.Metadata.Format]
+
+	file := &domain.DocumentFile{
+		Filename:    downloadFilename(doc.Title, fileType.Extension),
+		ContentType: fileType.ContentType,
+		Size:        doc.Metadata.FileSize,
+		ModTime:     doc.CreatedAt,
+	}
+	if doc.Metadata.SHA256 != "" {
+		file.ETag = `"` + doc.Metadata.SHA256 + `"`
+	}
+
+	// A missing file fails here, before any response is sent, without downloading it.
+	// The stored size also covers documents uploaded before sizes were recorded.
+	if file.Size, err = s.storage.Stat(ctx, path, principal.Token); err != nil {
+		return nil, err
+	}
+	file.Content = &blobReader{ctx: ctx, storage: s.storage, path: path, token: principal.Token, size: file.Size}
+	return file, nil
+}
+
+// downloadFilename names the downloaded original after the document title.
+func downloadFilename(title, extension string) string {
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || strings.ContainsRune(`/\:*?"<>|`, r) {
+			return -1
+		}
+		return r
+	}, strings.TrimSpace(title))
+	if name == "" {
+		name = "document"
+	}
+	if strings.HasSuffix(strings.ToLower(name), extension) {
+		return name
+	}
+	return name + extension
+}
+
+// blobReader reads a stored file of known size. Reads continue the open download
+// while they follow on from it; after a seek elsewhere the download is reopened at
+// the new offset.
+type blobReader struct {
+	ctx     context.Context
+	storage domain.BlobStore
+	path    string
+	token   string
+	size    int64
+	// rangeEnd is where ranged downloads stop, when set by LimitRange.
+	rangeEnd int64
+
+	offset     int64
+	body       io.ReadCloser
+	bodyOffset int64
+	bodyEnd    int64
+}
+
+// LimitRange implements domain.RangeLimiter. Reads past end still succeed; they
+// open another download.
+func (b *blobReader) LimitRange(end int64) {
+	b.rangeEnd = end
+}
+
+func (b *blobReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekStart:
+	case io.SeekCurrent:
+		offset += b.offset
+	case io.SeekEnd:
+		offset += b.size
+	default:
+		return 0, errors.New("blobReader: invalid whence")
+	}
+	if offset < 0 {
+		return 0, errors.New("blobReader: negative position")
+	}
+	b.offset = offset
+	return offset, nil
+}
+
+func (b *blobReader) Read(p []byte) (int, error) {
+	if b.offset >= b.size {
+		return 0, io.EOF
+	}
+	if b.body != nil && (b.bodyOffset != b.offset || b.bodyOffset >= b.bodyEnd) {
+		b.body.Close()
+		b.body = nil
+	}
+	if b.body == nil {
+		if err := b.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	if remaining := b.bodyEnd - b.offset; int64(len(p)) > remaining {
+		p = p[:remaining]
+	}
+	n, err := b.body.Read(p)
+	b.offset += int64(n)
+	b.bodyOffset += int64(n)
+	if err == io.EOF {
+		switch {
+		case b.offset < b.bodyEnd:
+			err = io.ErrUnexpectedEOF
+		case b.offset < b.size:
+			// The download ended at rangeEnd; the next read opens another.
+			err = nil
+		}
+	}
+	return n, err
+}
+
+// open downloads the file from the current offset, as a ranged download up to
+// rangeEnd (or the end of the file) where the store supports one.
+func (b *blobReader) open() error {
+	if ranged, ok := b.storage.(domain.RangeDownloader); ok {
+		end := b.size
+		if b.rangeEnd > b.offset && b.rangeEnd < end {
+			end = b.rangeEnd
+		}
+		body, err := ranged.DownloadRange(b.ctx, b.path, b.offset, end-b.offset, b.token)
+		if err != nil {
+			return err
+		}
+		b.body, b.bodyOffset, b.bodyEnd = body, b.offset, end
+		return nil
+	}
+
+	body, err := b.storage.Download(b.ctx, b.path, b.token)
+	if err != nil {
+		return err
+	}
+	if _, err := io.CopyN(io.Discard, body, b.offset); err != nil {
+		body.Close()
+		return fmt.Errorf("failed to skip to offset %d: %w", b.offset, err)
+	}
+	b.body, b.bodyOffset, b.bodyEnd = body, b.offset, b.size
+	return nil
+}
+
+func (b *blobReader) Close() error {
+	if b.body == nil {
+		return nil
+	}
+	err := b.body.Close()
+	b.body = nil
+	return err
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestDocumentService_OpenFile(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	s := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	ctx := context.Background()
+
+	data := []byte("%PDF-1.7 0123456789")
+	storage.files["user1/doc.pdf"] = data
+	repo.documents["doc"] = &domain.Document{ID: "doc", UserID: "user1", Title: "Field: Notes",
+		Metadata: domain.DocumentMetadata{Format: "pdf", FileSize: int64(len(data)), SHA256: "abc"}}
+
+	file, err := s.OpenFile(ctx, testPrincipal("user1"), "doc")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer file.Content.Close()
+	if file.Filename != "Field Notes.pdf" || file.ContentType != "application/pdf" || file.Size != int64(len(data)) || file.ETag != `"abc"` {
+		t.Fatalf("unexpected file: %+v", file)
+	}
+
+	// Seeking back and forth reopens the download at the new offset.
+	for _, offset := range []int64{9, 0, 14} {
+		if _, err := file.Content.Seek(offset, io.SeekStart); err != nil {
+			t.Fatal(err)
+		}
+		got := make([]byte, 5)
+		if _, err := io.ReadFull(file.Content, got); err != nil {
+			t.Fatalf("read at %d: %v", offset, err)
+		}
+		if string(got) != string(data[offset:offset+5]) {
+			t.Errorf("read at %d = %q, want %q", offset, got, data[offset:offset+5])
+		}
+	}
+	if end, _ := file.Content.Seek(0, io.SeekEnd); end != int64(len(data)) {
+		t.Errorf("size from seek = %d", end)
+	}
+
+	if _, err := s.OpenFile(ctx, testPrincipal("user2"), "doc"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("other user: got %v, want ErrAccessDenied", err)
+	}
+	delete(storage.files, "user1/doc.pdf")
+	if _, err := s.OpenFile(ctx, testPrincipal("user1"), "doc"); !errors.Is(err, domain.ErrBlobNotFound) {
+		t.Errorf("missing original: got %v, want ErrBlobNotFound", err)
+	}
+}
+
+func TestDocumentService_OpenFile_UnknownSize(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	s := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	storage.files["user1/old.epub"] = []byte("PK old upload")
+	repo.documents["old"] = &domain.Document{ID: "old", UserID: "user1", Title: "",
+		Metadata: domain.DocumentMetadata{Format: "epub"}}
+
+	file, err := s.OpenFile(context.Background(), testPrincipal("user1"), "old")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer file.Content.Close()
+	if file.Size != 13 || file.Filename != "document.epub" {
+		t.Fatalf("unexpected file: %+v", file)
+	}
+	body, err := io.ReadAll(file.Content)
+	if err != nil || string(body) != "PK old upload" {
+		t.Fatalf("content = %q (%v)", body, err)
+	}
+}
+
+// rangedStorage is a MockStorageService that also serves ranged downloads.
+type rangedStorage struct {
+	*MockStorageService
+	ranges    []string
+	downloads int
+}
+
+func (s *rangedStorage) Download(ctx context.Context, path string, token string) (io.ReadCloser, error) {
+	s.downloads++
+	return s.MockStorageService.Download(ctx, path, token)
+}
+
+func (s *rangedStorage) DownloadRange(ctx context.Context, path string, offset, length int64, token string) (io.ReadCloser, error) {
+	data, ok := s.files[path]
+	if !ok {
+		return nil, domain.ErrBlobNotFound
+	}
+	s.ranges = append(s.ranges, fmt.Sprintf("%d+%d", offset, length))
+	return io.NopCloser(bytes.NewReader(data[offset : offset+length])), nil
+}
+
+func TestDocumentService_OpenFile_RangedDownloads(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := &rangedStorage{MockStorageService: NewMockStorageService()}
+	s := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	data := []byte("%PDF-1.7 0123456789")
+	storage.files["user1/doc.pdf"] = data
+	repo.documents["doc"] = &domain.Document{ID: "doc", UserID: "user1", Title: "Doc",
+		Metadata: domain.DocumentMetadata{Format: "pdf", FileSize: int64(len(data))}}
+
+	file, err := s.OpenFile(context.Background(), testPrincipal("user1"), "doc")
+	if err != nil {
+		t.Fatalf("open failed: %v", err)
+	}
+	defer file.Content.Close()
+
+	if _, err := file.Content.Seek(14, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	rest, err := io.ReadAll(file.Content)
+	if err != nil || string(rest) != "56789" {
+		t.Fatalf("read from 14 = %q (%v)", rest, err)
+	}
+	if len(storage.ranges) != 1 || storage.ranges[0] != "14+5" {
+		t.Fatalf("expected one ranged download of the rest of the file, got %v", storage.ranges)
+	}
+	if storage.downloads != 0 {
+		t.Fatalf("expected opening the file not to download it, got %d downloads", storage.downloads)
+	}
+
+	// A limited range downloads only its bytes; reading on opens another download.
+	storage.ranges = nil
+	file.Content.(domain.RangeLimiter).LimitRange(12)
+	if _, err := file.Content.Seek(9, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	head := make([]byte, 3)
+	if _, err := io.ReadFull(file.Content, head); err != nil || string(head) != "012" {
+		t.Fatalf("read from 9 = %q (%v)", head, err)
+	}
+	if len(storage.ranges) != 1 || storage.ranges[0] != "9+3" {
+		t.Fatalf("expected a download of the requested range only, got %v", storage.ranges)
+	}
+	if rest, err := io.ReadAll(file.Content); err != nil || string(rest) != "3456789" {
+		t.Fatalf("read on from 12 = %q (%v)", rest, err)
+	}
+	if len(storage.ranges) != 2 || storage.ranges[1] != "12+7" {
+		t.Fatalf("expected a second download past the range, got %v", storage.ranges)
+	}
+}
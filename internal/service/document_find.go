@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"unicode"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// findSnippetContext is how many characters of text a match's snippet shows on each
+// side of it, at most.
+const findSnippetContext = 40
+
+// FindInDocument searches the document's extracted text for query, so a reader can
+// find in a book without downloading its text. Case and runs of whitespace are
+// ignored, as in quote matching, and matches do not overlap.
+func (s *DocumentService) FindInDocument(ctx context.Context, principal domain.Principal, documentID, query string) ([]*domain.DocumentMatch, error) {
+	q := foldQuote(query)
+	if len(q) == 0 {
+		return nil, domain.ValidationErrors{{Field: "q", Message: "a search query is required"}}
+	}
+	doc, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	index, err := newTextIndex(doc)
+	if err != nil {
+		return nil, err
+	}
+	return index.find(q), nil
+}
+
+// find returns the matches of a folded query in reading order.
+func (ix *textIndex) find(q []rune) []*domain.DocumentMatch {
+	f := ix.folded()
+	matches := []*domain.DocumentMatch{}
+	for i := 0; i+len(q) <= len(f.runes); i++ {
+		if f.runes[i] != q[0] || !slices.Equal(f.runes[i:i+len(q)], q) {
+			continue
+		}
+		start, end := f.offsets[i], f.offsets[i+len(q)-1]+1
+		anchor := ix.fromOffset(start)
+		snippet, from := ix.snippet(start, end)
+		matches = append(matches, &domain.DocumentMatch{
+			CharOffset:   start,
+			Length:       end - start,
+			Progress:     anchor.Progress,
+			PageNumber:   anchor.PageNumber,
+			Locator:      anchor.Locator,
+			Snippet:      snippet,
+			SnippetStart: start - from,
+			SnippetEnd:   end - from,
+		})
+		i += len(q) - 1
+	}
+	return matches
+}
+
+// snippet returns the text around a span and the offset it starts at. It is cut at
+// word boundaries where it can be, and line breaks become spaces.
+func (ix *textIndex) snippet(start, end int) (string, int) {
+	text := ix.runes()
+	from, to := max(0, start-findSnippetContext), min(len(text), end+findSnippetContext)
+	if from > 0 {
+		if i := slices.IndexFunc(text[from:start], unicode.IsSpace); i >= 0 {
+			from += i + 1
+		}
+	}
+	if to < len(text) {
+		if i := lastIndexFunc(text[end:to], unicode.IsSpace); i >= 0 {
+			to = end + i
+		}
+	}
+	snippet := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		return r
+	}, string(text[from:to]))
+	return snippet, from
+}
+
+func lastIndexFunc(s []rune, f func(rune) bool) int {
+	for i := len(s) - 1; i >= 0; i-- {
+		if f(s[i]) {
+			return i
+		}
+	}
+	return -1
+}
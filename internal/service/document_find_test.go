@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestDocumentService_FindInDocument(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	s := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	content, err := json.Marshal([]TextBlock{
+		{Type: "heading", Content: "Loomings", PageNumber: 1},
+		{Type: "paragraph", Content: "Call me Ishmael. Some years ago, never mind how long precisely, having little or no money in my purse.", PageNumber: 1},
+		{Type: "paragraph", Content: "It is a way I have of driving off the spleen,\nand regulating the circulation. Whenever I find myself growing grim about the mouth", PageNumber: 2},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user1", Content: content}
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	matches, err := s.FindInDocument(ctx, principal, "doc-1", "THE")
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if len(matches) != 3 {
+		t.Fatalf("got %d matches, want 3", len(matches))
+	}
+	for _, m := range matches {
+		if m.PageNumber != 2 {
+			t.Errorf("match at %d on page %d, want 2", m.CharOffset, m.PageNumber)
+		}
+		if got := string([]rune(m.Snippet)[m.SnippetStart:m.SnippetEnd]); got != "the" {
+			t.Errorf("snippet %q marks %q", m.Snippet, got)
+		}
+	}
+
+	// A query across a line break matches, and the snippet is cut at words.
+	matches, err = s.FindInDocument(ctx, principal, "doc-1", "spleen, and")
+	if err != nil || len(matches) != 1 {
+		t.Fatalf("got %v, %v; want one match", matches, err)
+	}
+	m := matches[0]
+	if m.Length != 11 || m.Snippet != "is a way I have of driving off the spleen, and regulating the circulation. Whenever I" {
+		t.Errorf("unexpected match: %+v", m)
+	}
+
+	if matches, err := s.FindInDocument(ctx, principal, "doc-1", "whale"); err != nil || len(matches) != 0 {
+		t.Errorf("no match: got %v, %v", matches, err)
+	}
+	var validationErrs domain.ValidationErrors
+	if _, err := s.FindInDocument(ctx, principal, "doc-1", "  "); !errors.As(err, &validationErrs) {
+		t.Errorf("blank query: got %v, want validation error", err)
+	}
+	if _, err := s.FindInDocument(ctx, testPrincipal("user2"), "doc-1", "the"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("other user: got %v, want ErrAccessDenied", err)
+	}
+}
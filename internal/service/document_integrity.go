@@ -0,0 +1,261 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// originalPath is where Upload stores a document's original file.
+func originalPath(doc *domain.DocumentData) (string, bool) {
+	fileType, ok := supportedFileTypes[doc.Metadata.Format]
+	if !ok {
+		return "", false
+	}
+	return doc.UserID + "/" + doc.ID + fileType.Extension, true
+}
+
+// VerifyDocument checks that the original file is still stored and that the
+// extracted content agrees with the metadata and the assets it points at. A PDF or
+// EPUB with neither content nor a page count was never processed, e.g. because the
+// server stopped during background extraction.
+func (s *DocumentService) VerifyDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentIntegrity, error) {
+	doc, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &domain.DocumentIntegrity{
+		DocumentID: doc.ID,
+		Format:     doc.Metadata.Format,
+		PageCount:  doc.Metadata.PageCount,
+		Issues:     []domain.IntegrityIssue{},
+	}
+	issue := func(code, message, path string) {
+		report.Issues = append(report.Issues, domain.IntegrityIssue{Code: code, Message: message, Path: path})
+	}
+	exists := func(path string) (bool, error) {
+		return s.storage.Exists(ctx, path, principal.Token)
+	}
+
+	if path, ok := originalPath(doc); ok {
+		if report.FileExists, err = exists(path); err != nil {
+			return nil, err
+		}
+		if !report.FileExists {
+			issue(domain.IntegrityFileMissing, "the original file is missing from storage", path)
+		}
+	}
+
+	var assets []string
+	switch doc.Metadata.Format {
+	case fileTypeCBZ.Format:
+		var pages []domain.ComicPage
+		if err := json.Unmarshal(doc.Content, &pages); err != nil {
+			issue(domain.IntegrityContentUnreadable, err.Error(), "")
+			break
+		}
+		report.BlockCount = len(pages)
+		for _, page := range pages {
+			report.ContentPages = max(report.ContentPages, page.Page)
+			assets = append(assets, page.Path)
+		}
+	case fileTypePDF.Format, fileTypeEPUB.Format:
+		var blocks []TextBlock
+		if err := json.Unmarshal(doc.Content, &blocks); err != nil {
+			issue(domain.IntegrityContentUnreadable, err.Error(), "")
+			break
+		}
+		report.BlockCount = len(blocks)
+		for _, block := range blocks {
+			report.ContentPages = max(report.ContentPages, block.PageNumber)
+			if block.Type == "image" && block.Src != "" {
+				assets = append(assets, block.Src)
+			}
+		}
+		if len(blocks) == 0 && doc.Metadata.PageCount == 0 {
+			issue(domain.IntegrityContentMissing, "no content was extracted; processing may not have finished", "")
+		}
+	}
+	if report.ContentPages > report.PageCount {
+		issue(domain.IntegrityPageCountMismatch,
+			fmt.Sprintf("content reaches page %d but the document has %d pages", report.ContentPages, report.PageCount), "")
+	}
+	if doc.Metadata.CoverPath != "" {
+		assets = append(assets, doc.Metadata.CoverPath)
+	}
+
+	checked := make(map[string]bool, len(assets))
+	for _, path := range assets {
+		if checked[path] {
+			continue
+		}
+		checked[path] = true
+		ok, err := exists(path)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			issue(domain.IntegrityAssetMissing, "a file the content refers to is missing from storage", path)
+		}
+	}
+
+	report.OK = len(report.Issues) == 0
+	report.Repairable = !report.OK && report.FileExists && doc.Metadata.Format != fileTypeTXT.Format
+	return report, nil
+}
+
+// ReprocessDocument extracts the document again from its stored original and
+// replaces the content, page count, outline and assets. Title and author are only
+// filled in when they were never extracted; everything else the user curated is
+// kept. The previous state is snapshotted first.
+func (s *DocumentService) ReprocessDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	if _, err := accountPreferences(ctx, s.prefsRepo, principal); err != nil {
+		return nil, err
+	}
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if doc.IsQuarantined() {
+		return nil, domain.ErrDocumentQuarantined
+	}
+	path, ok := originalPath(doc)
+	if !ok || doc.Metadata.Format == fileTypeTXT.Format {
+		return nil, domain.ErrNotReprocessable
+	}
+
+	file, err := s.storage.Download(ctx, path, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := spoolUpload(file, math.MaxInt64-1)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer upload.Close()
+
+	extracted, err := s.extract(ctx, principal, doc, upload)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.snapshot(ctx, principal, doc, domain.VersionReasonReprocess); err != nil {
+		return nil, err
+	}
+
+	if !bytes.Equal(doc.Content, extracted.Content) {
+		doc.Metadata.ContentVersion++
+	}
+	doc.Content = extracted.Content
+	if doc.Title == doc.Metadata.OriginalTitle && extracted.Title != "" {
+		doc.Title = extracted.Title
+	}
+	if doc.Author == nil && extracted.Metadata.OriginalAuthor != "" {
+		author := extracted.Metadata.OriginalAuthor
+		doc.Author = &author
+	}
+	if doc.Metadata.OriginalAuthor == "" {
+		doc.Metadata.OriginalAuthor = extracted.Metadata.OriginalAuthor
+	}
+	if doc.Metadata.Language == "" {
+		doc.Metadata.Language = extracted.Metadata.Language
+	}
+	if extracted.Metadata.CoverPath != "" {
+		if doc.Metadata.CoverPath == "" {
+			doc.Metadata.CoverPath = extracted.Metadata.CoverPath
+		} else if ok, _ := s.storage.Exists(ctx, doc.Metadata.CoverPath, principal.Token); !ok {
+			doc.Metadata.CoverPath = extracted.Metadata.CoverPath
+		}
+	}
+	doc.Metadata.PageCount = extracted.Metadata.PageCount
+	doc.Metadata.HasPassword = extracted.Metadata.HasPassword
+	doc.Metadata.Outline = extracted.Metadata.Outline
+	doc.Metadata.References = extracted.Metadata.References
+	doc.Metadata.FileSize = upload.size
+	doc.Metadata.SHA256 = upload.sha256
+	doc.Metadata.ProcessingError = ""
+	doc.Metadata.ProcessingAttempts = 0
+	doc.UpdatedAt = time.Now().UTC()
+	if err := s.repo.Update(ctx, principal, doc); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Document reprocessed", "doc_id", documentID, "format", doc.Metadata.Format, "page_count", doc.Metadata.PageCount)
+	s.publish(ctx, principal, domain.EventDocumentProcessed, domain.DocumentEvent{DocumentID: doc.ID, Title: doc.Title})
+	return doc, nil
+}
+
+// extractedDocument is what a processor recovered from an original file.
+type extractedDocument struct {
+	Title    string
+	Content  json.RawMessage
+	Metadata domain.DocumentMetadata
+}
+
+// extract runs the document's processor over its original file. Unlike Upload it
+// fails instead of storing empty content, so a repair never loses text.
+func (s *DocumentService) extract(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, upload *spooledUpload) (*extractedDocument, error) {
+	switch doc.Metadata.Format {
+	case fileTypePDF.Format:
+		blocks, pdfMetadata, err := upload.processPDF(ctx, s.pdfProcessor)
+		if err != nil {
+			return nil, err
+		}
+		content, err := s.pdfProcessor.ConvertToJSON(blocks)
+		if err != nil {
+			return nil, err
+		}
+		return &extractedDocument{Title: pdfMetadata.Title, Content: content, Metadata: domain.DocumentMetadata{
+			OriginalAuthor: pdfMetadata.Author,
+			PageCount:      pdfMetadata.PageCount,
+			HasPassword:    pdfMetadata.HasPassword,
+			Outline:        pdfMetadata.Outline,
+			References:     pdfMetadata.References,
+		}}, nil
+	case fileTypeEPUB.Format:
+		book, err := s.processEPUB(ctx, principal, doc.ID, upload)
+		if err != nil {
+			return nil, err
+		}
+		content, err := s.pdfProcessor.ConvertToJSON(book.Blocks)
+		if err != nil {
+			return nil, err
+		}
+		return &extractedDocument{Title: book.Metadata.Title, Content: content, Metadata: domain.DocumentMetadata{
+			OriginalAuthor: book.Metadata.Author,
+			Language:       book.Metadata.Language,
+			PageCount:      book.Metadata.ChapterCount,
+			CoverPath:      book.Cover,
+			Outline:        book.Outline,
+		}}, nil
+	case fileTypeCBZ.Format:
+		comic, err := s.comicProcessor.ProcessComic(upload.ReaderAt(), upload.size)
+		if err != nil {
+			return nil, err
+		}
+		manifest, err := s.uploadComicPages(ctx, principal, doc.ID, comic)
+		if err != nil {
+			return nil, err
+		}
+		content, err := json.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode page manifest: %w", err)
+		}
+		return &extractedDocument{Title: comic.Title, Content: content, Metadata: domain.DocumentMetadata{
+			OriginalAuthor: comic.Author,
+			Language:       comic.Language,
+			PageCount:      len(manifest),
+			CoverPath:      manifest[0].Path,
+		}}, nil
+	}
+	return nil, domain.ErrNotReprocessable
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func newTestIntegrityService(t *testing.T) (*DocumentService, *MockDocumentRepository, *MockStorageService) {
+	t.Helper()
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	documents := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	// A large PDF whose background extraction never finished: empty content, no
+	// page count and the file name as title.
+	original, err := exportPDF(&domain.DocumentData{Title: "Field Notes"}, []TextBlock{
+		{Type: "heading", Content: "Chapter One", Level: 1},
+		{Type: "paragraph", Content: "The river was high that spring."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.files["user1/unprocessed.pdf"] = original
+	repo.documents["unprocessed"] = &domain.Document{ID: "unprocessed", UserID: "user1", Title: "notes.pdf", Content: json.RawMessage(`[]`),
+		Metadata: domain.DocumentMetadata{Format: "pdf", OriginalTitle: "notes.pdf"}}
+
+	content, err := json.Marshal([]TextBlock{
+		{Type: "paragraph", Content: "Text", PageNumber: 1},
+		{Type: "image", Content: "Map", Src: "user1/book/images/000.png", PageNumber: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.documents["book"] = &domain.Document{ID: "book", UserID: "user1", Title: "Book", Content: content,
+		Metadata: domain.DocumentMetadata{Format: "epub", PageCount: 2}}
+
+	repo.documents["notes"] = &domain.Document{ID: "notes", UserID: "user1", Title: "Notes", Content: json.RawMessage(`[]`),
+		Metadata: domain.DocumentMetadata{Format: "txt"}}
+	storage.files["user1/notes.txt"] = []byte("notes")
+	return documents, repo, storage
+}
+
+func issueCodes(report *domain.DocumentIntegrity) []string {
+	codes := make([]string, len(report.Issues))
+	for i, issue := range report.Issues {
+		codes[i] = issue.Code
+	}
+	return codes
+}
+
+func TestDocumentService_VerifyDocument(t *testing.T) {
+	s, _, _ := newTestIntegrityService(t)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	tests := []struct {
+		id         string
+		wantCodes  []string
+		repairable bool
+	}{
+		{"unprocessed", []string{domain.IntegrityContentMissing}, true},
+		// The EPUB's original is gone, its content outruns the page count and an image is missing.
+		{"book", []string{domain.IntegrityFileMissing, domain.IntegrityPageCountMismatch, domain.IntegrityAssetMissing}, false},
+		{"notes", []string{}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			report, err := s.VerifyDocument(ctx, principal, tt.id)
+			if err != nil {
+				t.Fatalf("verify failed: %v", err)
+			}
+			codes := issueCodes(report)
+			if len(codes) != len(tt.wantCodes) {
+				t.Fatalf("issues = %v, want %v", codes, tt.wantCodes)
+			}
+			for i := range codes {
+				if codes[i] != tt.wantCodes[i] {
+					t.Errorf("issues = %v, want %v", codes, tt.wantCodes)
+				}
+			}
+			if report.OK != (len(tt.wantCodes) == 0) || report.Repairable != tt.repairable {
+				t.Errorf("ok = %v, repairable = %v", report.OK, report.Repairable)
+			}
+		})
+	}
+}
+
+func TestDocumentService_ReprocessDocument(t *testing.T) {
+	s, repo, _ := newTestIntegrityService(t)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	doc, err := s.ReprocessDocument(ctx, principal, "unprocessed")
+	if err != nil {
+		t.Fatalf("reprocess failed: %v", err)
+	}
+	if doc.Metadata.PageCount != 2 {
+		t.Errorf("page count = %d, want 2", doc.Metadata.PageCount)
+	}
+	if doc.Title != "Field Notes" {
+		t.Errorf("title = %q, want the extracted title", doc.Title)
+	}
+	if doc.Metadata.ContentVersion != 1 || doc.Metadata.SHA256 == "" {
+		t.Errorf("content version = %d, sha256 = %q", doc.Metadata.ContentVersion, doc.Metadata.SHA256)
+	}
+	var blocks []TextBlock
+	if err := json.Unmarshal(repo.documents["unprocessed"].Content, &blocks); err != nil || len(blocks) == 0 {
+		t.Fatalf("stored content = %s (%v)", repo.documents["unprocessed"].Content, err)
+	}
+
+	report, err := s.VerifyDocument(ctx, principal, "unprocessed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.OK {
+		t.Errorf("issues after reprocessing: %v", issueCodes(report))
+	}
+
+	if _, err := s.ReprocessDocument(ctx, principal, "book"); !errors.Is(err, domain.ErrBlobNotFound) {
+		t.Errorf("missing original: got %v, want ErrBlobNotFound", err)
+	}
+	if _, err := s.ReprocessDocument(ctx, principal, "notes"); !errors.Is(err, domain.ErrNotReprocessable) {
+		t.Errorf("text file: got %v, want ErrNotReprocessable", err)
+	}
+	if _, err := s.ReprocessDocument(ctx, testPrincipal("user2"), "unprocessed"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("other user: got %v, want ErrAccessDenied", err)
+	}
+}
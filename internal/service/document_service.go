@@ -5,92 +5,312 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"path"
 	"strings"
+	"sync"
 	"time"
 
 	"pdf-text-reader/internal/domain"
 
 	"encoding/json"
+	"errors"
 
 	"github.com/google/uuid"
 )
 
 type DocumentService struct {
-	storage      StorageService
-	repo         domain.DocumentRepository
-	prefsRepo    domain.UserPreferencesRepository
-	logger       domain.Logger
-	pdfProcessor *PDFProcessor
+	storage        domain.BlobStore
+	repo           domain.DocumentRepository
+	prefsRepo      domain.UserPreferencesRepository
+	versions       domain.DocumentVersionRepository
+	authz          domain.AuthorizationService
+	uploadLimits   domain.UploadLimits
+	pdfLimits      domain.PDFLimits
+	scanner        domain.UploadScanner
+	events         domain.EventPublisher
+	logger         domain.Logger
+	pdfProcessor   *PDFProcessor
+	epubProcessor  *EPUBProcessor
+	comicProcessor *ComicProcessor
+	uploads        *uploadTracker
 }
 
+// pageURLTTL is how long signed comic page URLs stay valid.
+const pageURLTTL = 15 * time.Minute
+
+// maxDocumentVersions is how many snapshots are kept per document; older ones are pruned.
+const maxDocumentVersions = 20
+
+// NewDocumentService creates a document service. versions may be nil, which disables
+// snapshots before overwrites; events may be nil, which drops processing events.
+
 func NewDocumentService(
 	repo domain.DocumentRepository,
 	prefsRepo domain.UserPreferencesRepository,
-	storage StorageService,
+	versions domain.DocumentVersionRepository,
+	storage domain.BlobStore,
+	authz domain.AuthorizationService,
+	uploadLimits domain.UploadLimits,
+	pdfLimits domain.PDFLimits,
+	scanner domain.UploadScanner,
+	events domain.EventPublisher,
 	logger domain.Logger,
 ) *DocumentService {
 	return &DocumentService{
-		storage:      storage,
-		repo:         repo,
-		prefsRepo:    prefsRepo,
-		logger:       logger,
-		pdfProcessor: NewPDFProcessor(logger),
+		storage:        storage,
+		repo:           repo,
+		prefsRepo:      prefsRepo,
+		versions:       versions,
+		authz:          authz,
+		uploadLimits:   uploadLimits,
+		pdfLimits:      pdfLimits,
+		scanner:        scanner,
+		events:         events,
+		logger:         logger,
+		pdfProcessor:   NewLimitedPDFProcessor(logger, pdfLimits),
+		epubProcessor:  NewEPUBProcessor(logger),
+		comicProcessor: NewComicProcessor(logger),
+		uploads:        newUploadTracker(),
 	}
 }
 
-func (s *DocumentService) GetDocumentsByUserID(userID string, token string) ([]*domain.DocumentData, error) {
-	documents, err := s.repo.GetByUserID(userID, token)
+func (s *DocumentService) GetDocumentsByUserID(ctx context.Context, principal domain.Principal) ([]*domain.DocumentData, error) {
+	documents, err := s.repo.GetByUserID(ctx, principal)
 	if err != nil {
 		return nil, err
 	}
 	return documents, nil
 }
 
-func (s *DocumentService) GetDocument(documentID string, token string) (*domain.DocumentData, error) {
-	document, err := s.repo.GetByID(documentID, token)
+func (s *DocumentService) GetDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	document, err := s.repo.GetByID(ctx, principal, documentID)
 	if err != nil {
 		return nil, err
 	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, document, domain.PermissionRead); err != nil {
+		return nil, err
+	}
+	if document.IsQuarantined() {
+		return nil, domain.ErrDocumentQuarantined
+	}
 	return document, nil
 }
 
-func (s *DocumentService) DeleteDocument(documentID string, token string) error {
-	err := s.repo.Delete(documentID, token)
+// OpenDocument is GetDocument for a reader opening the document: it also records
+// the time as last_opened_at. Failing to record it does not fail the open, and it
+// is not recorded during maintenance (domain.ReadOnlyFromContext).
+func (s *DocumentService) OpenDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if domain.ReadOnlyFromContext(ctx) {
+		return document, nil
+	}
+	now := time.Now().UTC()
+	if err := s.repo.SetLastOpened(ctx, principal, documentID, now); err != nil {
+		s.logger.Warn("Failed to record document open", "doc_id", documentID, "error", err)
+	} else {
+		document.LastOpenedAt = &now
+	}
+	return document, nil
+}
+
+// GetOutline returns the document's table of contents; documents without one
+// return an empty outline.
+func (s *DocumentService) GetOutline(ctx context.Context, principal domain.Principal, documentID string) ([]domain.OutlineEntry, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.Metadata.Outline == nil {
+		return []domain.OutlineEntry{}, nil
+	}
+	return document.Metadata.Outline, nil
+}
+
+// CompareDocuments loads both documents with read access and diffs their text.
+func (s *DocumentService) CompareDocuments(ctx context.Context, principal domain.Principal, leftID, rightID string) (*domain.DocumentComparison, error) {
+	left, err := s.comparableBlocks(ctx, principal, leftID)
+	if err != nil {
+		return nil, err
+	}
+	right, err := s.comparableBlocks(ctx, principal, rightID)
+	if err != nil {
+		return nil, err
+	}
+
+	pages, summary := compareBlocks(left, right)
+	return &domain.DocumentComparison{
+		LeftDocumentID:  leftID,
+		RightDocumentID: rightID,
+		Summary:         summary,
+		Pages:           pages,
+	}, nil
+}
+
+// comparableBlocks returns a document's text blocks. Comics, plain uploads and
+// documents still processing have no text to compare.
+func (s *DocumentService) comparableBlocks(ctx context.Context, principal domain.Principal, documentID string) ([]TextBlock, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.Metadata.Format == fileTypeCBZ.Format {
+		return nil, fmt.Errorf("%w: %s is a comic", domain.ErrDocumentNotComparable, documentID)
+	}
+
+	var blocks []TextBlock
+	if err := json.Unmarshal(document.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("%w: %s content is not text: %v", domain.ErrDocumentNotComparable, documentID, err)
+	}
+	for _, block := range blocks {
+		if strings.TrimSpace(block.Content) != "" {
+			return blocks, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s has no extracted text", domain.ErrDocumentNotComparable, documentID)
+}
+
+// GetReferences returns the parsed references of a scientific paper; other
+// documents return an empty list.
+func (s *DocumentService) GetReferences(ctx context.Context, principal domain.Principal, documentID string) ([]domain.Reference, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.Metadata.References == nil {
+		return []domain.Reference{}, nil
+	}
+	return document.Metadata.References, nil
+}
+
+// GetPageImage signs the storage URL of one comic page. Documents without a page
+// manifest have no pages to serve.
+func (s *DocumentService) GetPageImage(ctx context.Context, principal domain.Principal, documentID string, page int) (*domain.PageImage, error) {
+	document, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if document.Metadata.Format != fileTypeCBZ.Format {
+		return nil, fmt.Errorf("%w: document has no page images", domain.ErrPageNotFound)
+	}
+
+	var pages []domain.ComicPage
+	if err := json.Unmarshal(document.Content, &pages); err != nil {
+		return nil, fmt.Errorf("failed to decode page manifest: %w", err)
+	}
+	if page < 1 || page > len(pages) {
+		return nil, fmt.Errorf("%w: page %d of %d", domain.ErrPageNotFound, page, len(pages))
+	}
+	entry := pages[page-1]
+
+	url, err := s.storage.SignedURL(ctx, entry.Path, pageURLTTL, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.PageImage{
+		Page:        entry.Page,
+		URL:         url,
+		ContentType: entry.ContentType,
+		Width:       entry.Width,
+		Height:      entry.Height,
+		ExpiresAt:   time.Now().UTC().Add(pageURLTTL),
+	}, nil
+}
+
+func (s *DocumentService) DeleteDocument(ctx context.Context, principal domain.Principal, documentID string) error {
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionDelete); err != nil {
+		return err
+	}
+
+	err = s.repo.Delete(ctx, principal, documentID)
 	if err != nil {
 		return err
 	}
 	return nil
 }
 
-func (s *DocumentService) SearchDocuments(userID, query string, token string) ([]*domain.DocumentData, error) {
-	documents, err := s.repo.Search(userID, query, token)
+// SearchDocuments searches the user's library with the syntax of ParseDocumentQuery.
+func (s *DocumentService) SearchDocuments(ctx context.Context, principal domain.Principal, query string) ([]*domain.DocumentData, error) {
+	parsed, err := domain.ParseDocumentQuery(query)
 	if err != nil {
 		return nil, err
 	}
+	documents, err := s.repo.Search(ctx, principal, parsed)
+	if err != nil {
+		return nil, err
+	}
+	for _, doc := range documents {
+		doc.Snippets = parsed.Snippets(doc, searchText(doc))
+	}
 	return documents, nil
 }
 
-func (s *DocumentService) SetFavorite(userID string, documentID string, isFavorite bool, token string) error {
-	// Verify ownership to prevent cross-user writes.
-	doc, err := s.repo.GetByID(documentID, token)
+// searchText is a document's extracted text with blocks joined by spaces, for search
+// snippets. Comics and documents without text have none.
+func searchText(doc *domain.DocumentData) string {
+	if doc.Metadata.Format == fileTypeCBZ.Format {
+		return ""
+	}
+	var blocks []TextBlock
+	if err := json.Unmarshal(doc.Content, &blocks); err != nil {
+		return ""
+	}
+	parts := make([]string, 0, len(blocks))
+	for _, block := range blocks {
+		if content := strings.TrimSpace(block.Content); content != "" {
+			parts = append(parts, content)
+		}
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s *DocumentService) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
 	if err != nil {
 		return err
 	}
-	if doc.UserID != userID {
-		return fmt.Errorf("access denied")
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionAnnotate); err != nil {
+		return err
 	}
-	return s.repo.SetFavorite(userID, documentID, isFavorite, token)
+	return s.repo.SetFavorite(ctx, principal, documentID, isFavorite)
 }
 
-func (s *DocumentService) GetDocumentTags(userID string, token string) ([]string, error) {
-	tags, err := s.repo.GetTagsByUserID(userID, token)
+// SetArchived archives or unarchives a document. The archived flag lives on the
+// document itself, so changing it takes write access rather than annotate access.
+func (s *DocumentService) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionWrite); err != nil {
+		return err
+	}
+	if doc.IsArchived == archived {
+		return nil
+	}
+	if err := s.repo.SetArchived(ctx, principal, documentID, archived); err != nil {
+		return err
+	}
+	s.logger.Info("Document archive state changed", "doc_id", documentID, "archived", archived)
+	return nil
+}
+
+func (s *DocumentService) GetDocumentTags(ctx context.Context, principal domain.Principal) ([]string, error) {
+	tags, err := s.repo.GetTagsByUserID(ctx, principal)
 	if err != nil {
 		return nil, err
 	}
 	return tags, nil
 }
 
-func (s *DocumentService) CreateTag(userID string, tagName string, token string) error {
+func (s *DocumentService) CreateTag(ctx context.Context, principal domain.Principal, tagName string) error {
 	// Validate tag name
 	if tagName == "" {
 		return fmt.Errorf("tag name cannot be empty")
@@ -102,7 +322,7 @@ func (s *DocumentService) CreateTag(userID string, tagName string, token string)
 		return fmt.Errorf("tag name cannot be empty")
 	}
 
-	err := s.repo.CreateTag(userID, tagName, token)
+	err := s.repo.CreateTag(ctx, principal, tagName)
 	if err != nil {
 		return err
 	}
@@ -110,7 +330,7 @@ func (s *DocumentService) CreateTag(userID string, tagName string, token string)
 	return nil
 }
 
-func (s *DocumentService) DeleteTag(userID string, tagName string, token string) error {
+func (s *DocumentService) DeleteTag(ctx context.Context, principal domain.Principal, tagName string) error {
 	// Validate tag name
 	if tagName == "" {
 		return fmt.Errorf("tag name cannot be empty")
@@ -122,7 +342,7 @@ func (s *DocumentService) DeleteTag(userID string, tagName string, token string)
 		return fmt.Errorf("tag name cannot be empty")
 	}
 
-	err := s.repo.DeleteTag(userID, tagName, token)
+	err := s.repo.DeleteTag(ctx, principal, tagName)
 	if err != nil {
 		return err
 	}
@@ -131,21 +351,34 @@ func (s *DocumentService) DeleteTag(userID string, tagName string, token string)
 }
 
 func (s *DocumentService) UpdateDocumentDetails(
-	userID string,
+	ctx context.Context,
+	principal domain.Principal,
 	documentID string,
 	title *string,
 	author *string,
 	tag *string,
-	token string,
 ) (*domain.DocumentData, error) {
-	doc, err := s.repo.GetByID(documentID, token)
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
 	if err != nil {
 		return nil, err
 	}
-	if doc.UserID != userID {
-		return nil, fmt.Errorf("access denied")
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if err := checkUnmodified(ctx, doc); err != nil {
+		return nil, err
 	}
 
+	if err := s.snapshot(ctx, principal, doc, domain.VersionReasonUpdate); err != nil {
+		return nil, err
+	}
+
+	// Only the edited columns are written, so a concurrent change from another
+	// device, such as a reprocess or a favorite, is not overwritten.
+	patch := domain.DocumentPatch{Title: title, Author: author, Tag: tag, UpdatedAt: time.Now().UTC()}
+	if err := s.repo.Patch(ctx, principal, documentID, patch); err != nil {
+		return nil, err
+	}
 	if title != nil {
 		doc.Title = *title
 	}
@@ -155,63 +388,280 @@ func (s *DocumentService) UpdateDocumentDetails(
 	if tag != nil {
 		doc.Tag = tag
 	}
+	doc.UpdatedAt = patch.UpdatedAt
+
+	updated, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		// If re-fetch fails, at least return our updated in-memory doc.
+		return doc, nil
+	}
+	return updated, nil
+}
 
+// ListVersions returns the saved versions of a document, newest first, without content.
+func (s *DocumentService) ListVersions(ctx context.Context, principal domain.Principal, documentID string) ([]*domain.DocumentVersion, error) {
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionRead); err != nil {
+		return nil, err
+	}
+	if s.versions == nil {
+		return []*domain.DocumentVersion{}, nil
+	}
+	return s.versions.ListByDocument(ctx, principal, documentID)
+}
+
+// RestoreVersion replaces the document's title, author, content and metadata with a
+// saved version. The current state is snapshotted first so a restore can be undone.
+func (s *DocumentService) RestoreVersion(ctx context.Context, principal domain.Principal, documentID string, version int) (*domain.DocumentData, error) {
+	doc, err := s.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if doc.IsQuarantined() {
+		return nil, domain.ErrDocumentQuarantined
+	}
+	if err := checkUnmodified(ctx, doc); err != nil {
+		return nil, err
+	}
+	if s.versions == nil {
+		return nil, fmt.Errorf("%w: version %d", domain.ErrVersionNotFound, version)
+	}
+
+	saved, err := s.versions.Get(ctx, principal, documentID, version)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.snapshot(ctx, principal, doc, domain.VersionReasonRestore); err != nil {
+		return nil, err
+	}
+
+	// Highlights are re-anchored when the text they were made against changes.
+	contentVersion := doc.Metadata.ContentVersion
+	if !bytes.Equal(doc.Content, saved.Content) {
+		contentVersion = max(contentVersion, saved.Metadata.ContentVersion) + 1
+	}
+	doc.Title = saved.Title
+	doc.Author = saved.Author
+	doc.Content = saved.Content
+	doc.Metadata = saved.Metadata
+	doc.Metadata.ContentVersion = contentVersion
 	doc.UpdatedAt = time.Now().UTC()
-	if err := s.repo.Update(doc, token); err != nil {
+	if err := s.repo.Update(ctx, principal, doc); err != nil {
 		return nil, err
 	}
 
-	updated, err := s.repo.GetByID(documentID, token)
+	s.logger.Info("Document version restored", "doc_id", documentID, "version", version)
+
+	restored, err := s.repo.GetByID(ctx, principal, documentID)
 	if err != nil {
-		// If re-fetch fails, at least return our updated in-memory doc.
 		return doc, nil
 	}
-	return updated, nil
+	return restored, nil
+}
+
+// checkUnmodified fails early when the request's precondition no longer matches the
+// stored document. Repositories enforce it again atomically on write.
+func checkUnmodified(ctx context.Context, doc *domain.DocumentData) error {
+	unmodifiedSince, ok := domain.UnmodifiedSinceFromContext(ctx)
+	if ok && !domain.PreconditionTime(doc.UpdatedAt).Equal(unmodifiedSince) {
+		return domain.ErrStaleUpdate
+	}
+	return nil
+}
+
+// snapshot saves the document's current state as a new version and prunes versions
+// beyond maxDocumentVersions. Pruning failures are logged, not returned.
+func (s *DocumentService) snapshot(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, reason string) error {
+	if s.versions == nil {
+		return nil
+	}
+
+	created, err := s.versions.Create(ctx, principal, &domain.DocumentVersion{
+		DocumentID: doc.ID,
+		UserID:     doc.UserID,
+		Title:      doc.Title,
+		Author:     doc.Author,
+		Content:    doc.Content,
+		Metadata:   doc.Metadata,
+		Reason:     reason,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to save document version: %w", err)
+	}
+
+	if oldest := created.Version - maxDocumentVersions + 1; oldest > 1 {
+		if err := s.versions.DeleteBefore(ctx, principal, doc.ID, oldest); err != nil {
+			s.logger.Warn("Failed to prune document versions", "doc_id", doc.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// recordProcessingFailure stores why a document's extraction failed, so recovery
+// can tell failed documents from lost ones and stop retrying them.
+func (s *DocumentService) recordProcessingFailure(ctx context.Context, principal domain.Principal, docID string, cause error) {
+	doc, err := s.repo.GetByID(ctx, principal, docID)
+	if err != nil {
+		s.logger.Warn("Failed to load document to record processing failure", "doc_id", docID, "error", err)
+		return
+	}
+	doc.Metadata.ProcessingError = cause.Error()
+	doc.Metadata.ProcessingAttempts++
+	if err := s.repo.Update(ctx, principal, doc); err != nil {
+		s.logger.Warn("Failed to record processing failure", "doc_id", docID, "error", err)
+	}
+}
+
+// scanUpload runs the configured scanner and returns a quarantine record when the
+// file is flagged or cannot be scanned; nil means the upload is clean.
+func (s *DocumentService) scanUpload(ctx context.Context, docID, filename string, upload *spooledUpload) *domain.Quarantine {
+	if s.scanner == nil {
+		return nil
+	}
+
+	result, err := s.scanner.Scan(ctx, filename, upload.Reader())
+	if err != nil {
+		s.logger.Error("Upload scan failed, quarantining document", err, "doc_id", docID)
+		return &domain.Quarantine{Reason: domain.QuarantineScanFailed, QuarantinedAt: time.Now().UTC()}
+	}
+	if result.Clean {
+		return nil
+	}
+
+	s.logger.Warn("Malware detected in upload, quarantining document",
+		"doc_id", docID,
+		"signature", result.Signature,
+		"engine", result.Engine,
+	)
+	return &domain.Quarantine{
+		Reason:        domain.QuarantineMalwareDetected,
+		Signature:     result.Signature,
+		Engine:        result.Engine,
+		QuarantinedAt: time.Now().UTC(),
+	}
+}
+
+// processEPUB extracts an EPUB and uploads its images next to the book, rewriting
+// image blocks and the cover from archive paths to storage paths. Images that fail
+// to upload are dropped rather than failing the whole document.
+func (s *DocumentService) processEPUB(ctx context.Context, principal domain.Principal, docID string, upload *spooledUpload) (*EPUBResult, error) {
+	book, err := s.epubProcessor.ProcessEPUB(upload.ReaderAt(), upload.size)
+	if err != nil {
+		return nil, err
+	}
+
+	stored := make(map[string]string, len(book.Images))
+	for i, img := range book.Images {
+		storagePath := fmt.Sprintf("%s/%s/images/%03d%s", principal.UserID, docID, i, strings.ToLower(path.Ext(img.Path)))
+		if err := s.storage.Upload(ctx, storagePath, bytes.NewReader(img.Data), img.ContentType, principal.Token); err != nil {
+			s.logger.Warn("Failed to upload EPUB image", "doc_id", docID, "image", img.Path, "error", err)
+			continue
+		}
+		stored[img.Path] = storagePath
+	}
+
+	blocks := book.Blocks[:0]
+	for _, block := range book.Blocks {
+		if block.Type == "image" {
+			if block.Src = stored[block.Src]; block.Src == "" {
+				continue
+			}
+		}
+		blocks = append(blocks, block)
+	}
+	book.Blocks = blocks
+	book.Cover = stored[book.Cover]
+
+	return book, nil
+}
+
+// uploadComicPages stores each page image next to the archive and returns the page
+// manifest. Unlike EPUB images, a missing page breaks reading, so any failure aborts.
+func (s *DocumentService) uploadComicPages(ctx context.Context, principal domain.Principal, docID string, comic *ComicResult) ([]domain.ComicPage, error) {
+	manifest := make([]domain.ComicPage, 0, len(comic.Pages))
+	for i, page := range comic.Pages {
+		storagePath := fmt.Sprintf("%s/%s/pages/%04d%s", principal.UserID, docID, i+1, strings.ToLower(path.Ext(page.Name)))
+		if err := s.storage.Upload(ctx, storagePath, bytes.NewReader(page.Data), page.ContentType, principal.Token); err != nil {
+			return nil, fmt.Errorf("failed to upload comic page %d: %w", i+1, err)
+		}
+		manifest = append(manifest, domain.ComicPage{
+			Page:        i + 1,
+			Path:        storagePath,
+			ContentType: page.ContentType,
+			Width:       page.Width,
+			Height:      page.Height,
+		})
+	}
+	return manifest, nil
+}
+
+// PDFProcessingStats implements domain.PDFProcessingMetrics.
+func (s *DocumentService) PDFProcessingStats() domain.PDFProcessingStats {
+	return s.pdfProcessor.Stats()
+}
+
+// UploadLimit resolves the single-file cap for the principal's plan. Preference lookup
+// failures fall back to the free plan.
+func (s *DocumentService) UploadLimit(ctx context.Context, principal domain.Principal, format string) domain.UploadLimit {
+	plan := "free"
+	if s.prefsRepo != nil {
+		if prefs, err := s.prefsRepo.GetPreferences(ctx, principal); err == nil && prefs != nil && prefs.SubscriptionPlan != "" {
+			plan = prefs.SubscriptionPlan
+		}
+	}
+	return s.uploadLimits.Resolve(plan, format)
 }
 
 func (s *DocumentService) Upload(
 	ctx context.Context,
-	userID string,
+	principal domain.Principal,
 	file io.Reader,
-	token string,
 	originalName string,
+) (*domain.DocumentData, error) {
+	return s.upload(ctx, principal, file, originalName, nil)
+}
+
+// upload stores and processes an uploaded file. apply, when set, fills in details
+// known to the caller (e.g. library metadata of an import); it runs before the
+// document is created and again before background processing overwrites it.
+func (s *DocumentService) upload(
+	ctx context.Context,
+	principal domain.Principal,
+	file io.Reader,
+	originalName string,
+	apply func(doc *domain.DocumentData),
 ) (*domain.DocumentData, error) {
 	// Determine per-user storage quota from preferences.
 	// Default: 15MB (free). Paid: 50GB.
-	maxUserStorage := domain.StorageLimitBytesForPlan("free")
-	if s.prefsRepo != nil {
-		if prefs, err := s.prefsRepo.GetPreferences(userID, token); err == nil && prefs != nil {
-			// Prefer explicit storage_limit_bytes, but fall back to computing from plan.
-			if prefs.StorageLimitBytes > 0 {
-				maxUserStorage = prefs.StorageLimitBytes
-			} else {
-				maxUserStorage = domain.StorageLimitBytesForPlan(prefs.SubscriptionPlan)
-			}
-		}
+	plan := "free"
+	maxUserStorage := domain.StorageLimitBytesForPlan(plan)
+	prefs, err := accountPreferences(ctx, s.prefsRepo, principal)
+	if err != nil {
+		return nil, err
 	}
-
-	docID := uuid.New().String()
-	// Path should be relative to bucket, not include bucket name
-	path := fmt.Sprintf("%s/%s.pdf", userID, docID)
-
-	// Read file to get size and content
-	fileBytes := make([]byte, 0)
-	buf := make([]byte, 1024)
-	var totalSize int64
-	for {
-		n, err := file.Read(buf)
-		if n > 0 {
-			fileBytes = append(fileBytes, buf[:n]...)
-			totalSize += int64(n)
+	if prefs != nil {
+		if prefs.SubscriptionPlan != "" {
+			plan = prefs.SubscriptionPlan
 		}
-		if err != nil {
-			break
+		// Prefer explicit storage_limit_bytes, but fall back to computing from plan.
+		if prefs.StorageLimitBytes > 0 {
+			maxUserStorage = prefs.StorageLimitBytes
+		} else {
+			maxUserStorage = domain.StorageLimitBytesForPlan(prefs.SubscriptionPlan)
 		}
 	}
 
-	// Enforce per-user storage quota BEFORE uploading to storage
-	// Get current documents to calculate total storage used
-	existingDocs, err := s.repo.GetByUserID(userID, token)
+	docID := uuid.New().String()
+
+	// Enforce per-user storage quota BEFORE reading the file: the remaining quota
+	// caps how much of the body is read, like the single-file cap does.
+	existingDocs, err := s.repo.GetByUserID(ctx, principal)
 	if err != nil {
 		return nil, fmt.Errorf("failed to calculate current storage usage: %w", err)
 	}
@@ -221,13 +671,67 @@ func (s *DocumentService) Upload(
 		currentUsage += d.Metadata.FileSize
 	}
 
-	if currentUsage+totalSize > maxUserStorage {
-		return nil, fmt.Errorf("storage limit exceeded: user has %d bytes used, upload would exceed %d bytes", currentUsage, maxUserStorage)
+	// Stream the file into memory or a spool file, sizing and hashing it on the way,
+	// and stop reading as soon as it is over either limit.
+	limit := s.uploadLimits.Resolve(plan, "")
+	remaining := max(maxUserStorage-currentUsage, 0)
+	upload, err := spoolUpload(file, min(limit.MaxBytes, remaining))
+	if errors.Is(err, domain.ErrFileTooLarge) && remaining < limit.MaxBytes {
+		return nil, fmt.Errorf("%w: user has %d bytes used, upload would exceed %d bytes", domain.ErrStorageLimitExceeded, currentUsage, maxUserStorage)
+	}
+	if err != nil {
+		return nil, err
 	}
+	// The background PDF job takes ownership of the spool file; everything else is
+	// done with it when Upload returns.
+	keepSpool := false
+	defer func() {
+		if !keepSpool {
+			upload.Close()
+		}
+	}()
+	totalSize := upload.size
 
-	// Upload file (need to create new reader from bytes)
-	fileReader := bytes.NewReader(fileBytes)
-	if err := s.storage.Upload(ctx, path, fileReader, token); err != nil {
+	// Trust the content, not the filename: the detected type drives the storage
+	// extension, metadata.Format and which processor runs.
+	fileType, err := validateUpload(upload.ReaderAt(), upload.size, originalName)
+	if err != nil {
+		return nil, err
+	}
+
+	// Path should be relative to bucket, not include bucket name
+	path := fmt.Sprintf("%s/%s%s", principal.UserID, docID, fileType.Extension)
+
+	// Enforce the per-format cap; the handler checks it too, but other callers may not.
+	limit = s.uploadLimits.Resolve(plan, fileType.Format)
+	if totalSize > limit.MaxBytes {
+		return nil, fmt.Errorf("%w: %d bytes exceeds the %d byte limit", domain.ErrFileTooLarge, totalSize, limit.MaxBytes)
+	}
+
+	// Scan before persisting. Flagged files are still stored so admins can review
+	// them, but they are never processed or served until released.
+	quarantine := s.scanUpload(ctx, docID, originalName, upload)
+
+	// Reject unreadable PDFs up front so the user gets an actionable error instead of
+	// an empty document. Quarantined files are never opened.
+	if quarantine == nil && fileType.Format == fileTypePDF.Format {
+		if err := validatePDF(upload.ReaderAt(), upload.size, upload.openPDF, s.pdfLimits.MaxPages); err != nil {
+			s.logger.Warn("Rejected unreadable PDF", "doc_id", docID, "error", err)
+			return nil, err
+		}
+	}
+
+	// Comics are unpacked up front for the same reason: an archive without readable
+	// pages would otherwise become an empty document.
+	var comic *ComicResult
+	if quarantine == nil && fileType.Format == fileTypeCBZ.Format {
+		if comic, err = s.comicProcessor.ProcessComic(upload.ReaderAt(), upload.size); err != nil {
+			s.logger.Warn("Rejected unreadable comic archive", "doc_id", docID, "error", err)
+			return nil, err
+		}
+	}
+
+	if err := s.storage.Upload(ctx, path, upload.Reader(), fileType.ContentType, principal.Token); err != nil {
 		return nil, err
 	}
 
@@ -235,7 +739,7 @@ func (s *DocumentService) Upload(
 
 	// Use original filename or generate one
 	if originalName == "" {
-		originalName = docID + ".pdf"
+		originalName = docID + fileType.Extension
 	}
 
 	// Process PDF to extract text and metadata
@@ -247,13 +751,89 @@ func (s *DocumentService) Upload(
 	var metadata domain.DocumentMetadata
 	title := originalName
 
-	if totalSize < asyncThreshold {
+	if quarantine != nil {
+		contentJSON = json.RawMessage("[]")
+		metadata = domain.DocumentMetadata{}
+	} else if fileType.Format == fileTypeEPUB.Format {
+		book, err := s.processEPUB(ctx, principal, docID, upload)
+		if err != nil {
+			s.logger.Error("Failed to process EPUB", err, "doc_id", docID)
+			contentJSON = json.RawMessage("[]")
+			metadata = domain.DocumentMetadata{ProcessingError: err.Error(), ProcessingAttempts: 1}
+		} else {
+			contentJSON, err = s.pdfProcessor.ConvertToJSON(book.Blocks)
+			if err != nil {
+				s.logger.Error("Failed to convert blocks to JSON", err, "doc_id", docID)
+				contentJSON = json.RawMessage("[]")
+			}
+
+			if book.Metadata.Title != "" {
+				title = book.Metadata.Title
+			}
+
+			metadata = domain.DocumentMetadata{
+				OriginalTitle:  originalName,
+				OriginalAuthor: book.Metadata.Author,
+				Language:       book.Metadata.Language,
+				PageCount:      book.Metadata.ChapterCount,
+				FileSize:       totalSize,
+				Format:         fileTypeEPUB.Format,
+				CoverPath:      book.Cover,
+				Outline:        book.Outline,
+			}
+
+			s.logger.Info("EPUB processed",
+				"doc_id", docID,
+				"blocks_count", len(book.Blocks),
+				"images_count", len(book.Images),
+			)
+		}
+	} else if comic != nil {
+		manifest, err := s.uploadComicPages(ctx, principal, docID, comic)
+		if err != nil {
+			return nil, err
+		}
+		contentJSON, err = json.Marshal(manifest)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode page manifest: %w", err)
+		}
+
+		if comic.Title != "" {
+			title = comic.Title
+		}
+
+		metadata = domain.DocumentMetadata{
+			OriginalTitle:  originalName,
+			OriginalAuthor: comic.Author,
+			Language:       comic.Language,
+			PageCount:      len(manifest),
+			FileSize:       totalSize,
+			Format:         fileTypeCBZ.Format,
+			CoverPath:      manifest[0].Path,
+		}
+
+		s.logger.Info("Comic processed", "doc_id", docID, "page_count", len(manifest))
+	} else if fileType.Format != fileTypePDF.Format {
+		// Only PDFs have a text extractor; other formats are stored as-is.
+		contentJSON = json.RawMessage("[]")
+		metadata = domain.DocumentMetadata{}
+	} else if totalSize < asyncThreshold {
 		// Process synchronously for small files
-		blocks, pdfMetadata, err := s.pdfProcessor.ProcessPDF(fileBytes)
+		blocks, pdfMetadata, err := upload.processPDF(ctx, s.pdfProcessor)
+		var pdfErr *domain.PDFValidationError
+		if errors.As(err, &pdfErr) {
+			// Over the processing limits: reject the upload rather than keep a document
+			// that can never be read.
+			s.logger.Warn("Rejected PDF over processing limits", "doc_id", docID, "code", pdfErr.Code)
+			if delErr := s.storage.Delete(ctx, path, principal.Token); delErr != nil {
+				s.logger.Warn("Failed to delete rejected upload", "doc_id", docID, "error", delErr)
+			}
+			return nil, err
+		}
 		if err != nil {
 			s.logger.Error("Failed to process PDF", err, "doc_id", docID)
 			contentJSON = json.RawMessage("[]")
-			metadata = domain.DocumentMetadata{}
+			metadata = domain.DocumentMetadata{ProcessingError: err.Error(), ProcessingAttempts: 1}
 		} else {
 			contentJSON, err = s.pdfProcessor.ConvertToJSON(blocks)
 			if err != nil {
@@ -272,6 +852,8 @@ func (s *DocumentService) Upload(
 				HasPassword:    pdfMetadata.HasPassword,
 				FileSize:       totalSize,
 				Format:         "pdf",
+				Outline:        pdfMetadata.Outline,
+				References:     pdfMetadata.References,
 			}
 
 			s.logger.Info("DocumentData processed synchronously",
@@ -285,17 +867,23 @@ func (s *DocumentService) Upload(
 		contentJSON = json.RawMessage("[]")
 		metadata = domain.DocumentMetadata{}
 
-		// Process in background goroutine
+		// Process in background goroutine. It outlives the request, so detach it from
+		// the request's cancellation; repository calls still apply their own timeouts.
+		bgCtx := context.WithoutCancel(ctx)
+		keepSpool = true
 		go func() {
-			blocks, pdfMetadata, err := s.pdfProcessor.ProcessPDF(fileBytes)
+			defer upload.Close()
+			blocks, pdfMetadata, err := upload.processPDF(bgCtx, s.pdfProcessor)
 			if err != nil {
 				s.logger.Error("Failed to process PDF in background", err, "doc_id", docID)
+				s.recordProcessingFailure(bgCtx, principal, docID, err)
 				return
 			}
 
 			contentJSON, err := s.pdfProcessor.ConvertToJSON(blocks)
 			if err != nil {
 				s.logger.Error("Failed to convert blocks to JSON in background", err, "doc_id", docID)
+				s.recordProcessingFailure(bgCtx, principal, docID, err)
 				return
 			}
 
@@ -308,7 +896,7 @@ func (s *DocumentService) Upload(
 			// Update document with processed content
 			updatedDoc := &domain.DocumentData{
 				ID:      docID,
-				UserID:  userID,
+				UserID:  principal.UserID,
 				Title:   docTitle,
 				Content: contentJSON,
 				Metadata: domain.DocumentMetadata{
@@ -318,11 +906,17 @@ func (s *DocumentService) Upload(
 					HasPassword:    pdfMetadata.HasPassword,
 					FileSize:       totalSize,
 					Format:         "pdf",
+					SHA256:         upload.sha256,
+					Outline:        pdfMetadata.Outline,
+					References:     pdfMetadata.References,
 				},
 				UpdatedAt: time.Now().UTC(),
 			}
 
-			if err := s.repo.Update(updatedDoc, token); err != nil {
+			if apply != nil {
+				apply(updatedDoc)
+			}
+			if err := s.repo.Update(bgCtx, principal, updatedDoc); err != nil {
 				s.logger.Error("Failed to update document with processed content", err, "doc_id", docID)
 				return
 			}
@@ -332,6 +926,7 @@ func (s *DocumentService) Upload(
 				"blocks_count", len(blocks),
 				"page_count", pdfMetadata.PageCount,
 			)
+			s.publish(bgCtx, principal, domain.EventDocumentProcessed, domain.DocumentEvent{DocumentID: docID, Title: updatedDoc.Title})
 		}()
 
 		s.logger.Info("DocumentData created, processing in background", "doc_id", docID, "file_size", totalSize)
@@ -351,12 +946,14 @@ func (s *DocumentService) Upload(
 		metadata.OriginalTitle = originalName
 	}
 	if metadata.Format == "" {
-		metadata.Format = "pdf"
+		metadata.Format = fileType.Format
 	}
+	metadata.SHA256 = upload.sha256
+	metadata.Quarantine = quarantine
 
 	doc := &domain.DocumentData{
 		ID:        docID,
-		UserID:    userID,
+		UserID:    principal.UserID,
 		Title:     title,
 		Author:    author,
 		Content:   contentJSON,
@@ -365,9 +962,110 @@ func (s *DocumentService) Upload(
 		UpdatedAt: now,
 	}
 
-	if err := s.repo.Create(doc, token); err != nil {
+	if apply != nil {
+		apply(doc)
+	}
+	if err := s.repo.Create(ctx, principal, doc); err != nil {
+		return nil, err
+	}
+
+	s.publish(ctx, principal, domain.EventIngestionDone, domain.DocumentEvent{DocumentID: docID, Title: doc.Title})
+	warnAt := int64(float64(maxUserStorage) * domain.QuotaWarningRatio)
+	if used := currentUsage + totalSize; currentUsage < warnAt && used >= warnAt {
+		s.publish(ctx, principal, domain.EventQuotaWarning, domain.QuotaWarning{UsedBytes: used, LimitBytes: maxUserStorage})
+	}
+
+	return doc, nil
+}
+
+// uploadImported uploads a book from another reading app, keeping the library
+// metadata the user curated there. The book's cover replaces the extracted one and
+// its first tag is assigned; the tags must already exist.
+func (s *DocumentService) uploadImported(
+	ctx context.Context,
+	principal domain.Principal,
+	file io.Reader,
+	originalName string,
+	book *domain.ImportedBook,
+) (*domain.DocumentData, error) {
+	var tag *string
+	if len(book.Tags) > 0 {
+		tag = &book.Tags[0]
+	}
+	// apply also runs in the background for large PDFs, so the cover is uploaded once.
+	var (
+		coverOnce sync.Once
+		coverPath string
+	)
+
+	doc, err := s.upload(ctx, principal, file, originalName, func(doc *domain.DocumentData) {
+		coverOnce.Do(func() {
+			if len(book.Cover) == 0 {
+				return
+			}
+			path := fmt.Sprintf("%s/%s/images/cover%s", principal.UserID, doc.ID, coverExtension(book.CoverType))
+			if err := s.storage.Upload(ctx, path, bytes.NewReader(book.Cover), book.CoverType, principal.Token); err != nil {
+				s.logger.Warn("Failed to upload imported cover", "doc_id", doc.ID, "error", err)
+				return
+			}
+			coverPath = path
+		})
+
+		if book.Title != "" {
+			doc.Title = book.Title
+		}
+		if len(book.Authors) > 0 {
+			author := strings.Join(book.Authors, domain.AuthorSeparator)
+			doc.Author = &author
+		}
+		if book.Description != "" {
+			doc.Description = &book.Description
+		}
+		doc.Tag = tag
+		doc.Metadata.Source = book.Source
+		doc.Metadata.Series = book.Series
+		doc.Metadata.SeriesIndex = book.SeriesIndex
+		doc.Metadata.ISBN = book.ISBN
+		doc.Metadata.SourceURL = book.SourceURL
+		doc.Metadata.SourceTags = book.Tags
+		if coverPath != "" {
+			doc.Metadata.CoverPath = coverPath
+		}
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	// Create does not assign tags. Only the tag is written: background extraction
+	// of a large PDF or EPUB may be saving the content at the same time.
+	if tag != nil {
+		patch := domain.DocumentPatch{Tag: tag, UpdatedAt: time.Now().UTC()}
+		if err := s.repo.Patch(ctx, principal, doc.ID, patch); err != nil {
+			return nil, fmt.Errorf("failed to tag imported document: %w", err)
+		}
+		doc.UpdatedAt = patch.UpdatedAt
+	}
 	return doc, nil
 }
+
+// coverExtension maps a cover image content type to its file extension.
+func coverExtension(contentType string) string {
+	switch contentType {
+	case "image/png":
+		return ".png"
+	case "image/gif":
+		return ".gif"
+	case "image/webp":
+		return ".webp"
+	default:
+		return ".jpg"
+	}
+}
+
+// publish sends an event about the user's library when a publisher is configured.
+func (s *DocumentService) publish(ctx context.Context, principal domain.Principal, eventType string, data any) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(ctx, principal, domain.Event{Type: eventType, Data: data, At: time.Now().UTC()})
+}
@@ -1,11 +1,18 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"pdf-text-reader/internal/domain"
 )
@@ -23,7 +30,7 @@ func NewMockDocumentRepository() *MockDocumentRepository {
 	}
 }
 
-func (m *MockDocumentRepository) Create(document *domain.Document, token string) error {
+func (m *MockDocumentRepository) Create(ctx context.Context, principal domain.Principal, document *domain.Document) error {
 	if document.ID == "" {
 		return errors.New("document ID is required")
 	}
@@ -31,24 +38,24 @@ func (m *MockDocumentRepository) Create(document *domain.Document, token string)
 	return nil
 }
 
-func (m *MockDocumentRepository) GetByID(id string, token string) (*domain.Document, error) {
+func (m *MockDocumentRepository) GetByID(ctx context.Context, principal domain.Principal, id string) (*domain.Document, error) {
 	if doc, exists := m.documents[id]; exists {
 		return doc, nil
 	}
 	return nil, errors.New("document not found")
 }
 
-func (m *MockDocumentRepository) GetByUserID(userID string, token string) ([]*domain.Document, error) {
+func (m *MockDocumentRepository) GetByUserID(ctx context.Context, principal domain.Principal) ([]*domain.Document, error) {
 	var docs []*domain.Document
 	for _, doc := range m.documents {
-		if doc.UserID == userID {
+		if doc.UserID == principal.UserID {
 			docs = append(docs, doc)
 		}
 	}
 	return docs, nil
 }
 
-func (m *MockDocumentRepository) Update(document *domain.Document, token string) error {
+func (m *MockDocumentRepository) Update(ctx context.Context, principal domain.Principal, document *domain.Document) error {
 	if _, exists := m.documents[document.ID]; !exists {
 		return errors.New("document not found")
 	}
@@ -56,7 +63,31 @@ func (m *MockDocumentRepository) Update(document *domain.Document, token string)
 	return nil
 }
 
-func (m *MockDocumentRepository) Delete(id string, token string) error {
+// Patch stores a patched copy, so a document the caller read keeps its old values.
+func (m *MockDocumentRepository) Patch(ctx context.Context, principal domain.Principal, documentID string, patch domain.DocumentPatch) error {
+	doc, exists := m.documents[documentID]
+	if !exists {
+		return errors.New("document not found")
+	}
+	patched := *doc
+	if patch.Title != nil {
+		patched.Title = *patch.Title
+	}
+	if patch.Author != nil {
+		patched.Author = patch.Author
+	}
+	if patch.Tag != nil {
+		patched.Tag = patch.Tag
+		if *patch.Tag == "" {
+			patched.Tag = nil
+		}
+	}
+	patched.UpdatedAt = patch.UpdatedAt
+	m.documents[documentID] = &patched
+	return nil
+}
+
+func (m *MockDocumentRepository) Delete(ctx context.Context, principal domain.Principal, id string) error {
 	if _, exists := m.documents[id]; !exists {
 		return errors.New("document not found")
 	}
@@ -64,40 +95,40 @@ func (m *MockDocumentRepository) Delete(id string, token string) error {
 	return nil
 }
 
-func (m *MockDocumentRepository) Search(userID, query string, token string) ([]*domain.Document, error) {
+func (m *MockDocumentRepository) Search(ctx context.Context, principal domain.Principal, query *domain.DocumentQuery) ([]*domain.Document, error) {
 	var docs []*domain.Document
 	for _, doc := range m.documents {
-		if doc.UserID == userID && strings.Contains(strings.ToLower(doc.Title), strings.ToLower(query)) {
+		if doc.UserID == principal.UserID && query.Match(doc) {
 			docs = append(docs, doc)
 		}
 	}
 	return docs, nil
 }
 
-func (m *MockDocumentRepository) GetTagsByUserID(userID string, token string) ([]string, error) {
-	return m.tags[userID], nil
+func (m *MockDocumentRepository) GetTagsByUserID(ctx context.Context, principal domain.Principal) ([]string, error) {
+	return m.tags[principal.UserID], nil
 }
 
-func (m *MockDocumentRepository) CreateTag(userID string, tagName string, token string) error {
-	if m.tags[userID] == nil {
-		m.tags[userID] = []string{}
+func (m *MockDocumentRepository) CreateTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	if m.tags[principal.UserID] == nil {
+		m.tags[principal.UserID] = []string{}
 	}
-	m.tags[userID] = append(m.tags[userID], tagName)
+	m.tags[principal.UserID] = append(m.tags[principal.UserID], tagName)
 	return nil
 }
 
-func (m *MockDocumentRepository) DeleteTag(userID string, tagName string, token string) error {
-	tags := m.tags[userID]
+func (m *MockDocumentRepository) DeleteTag(ctx context.Context, principal domain.Principal, tagName string) error {
+	tags := m.tags[principal.UserID]
 	for i, tag := range tags {
 		if tag == tagName {
-			m.tags[userID] = append(tags[:i], tags[i+1:]...)
+			m.tags[principal.UserID] = append(tags[:i], tags[i+1:]...)
 			return nil
 		}
 	}
 	return errors.New("tag not found")
 }
 
-func (m *MockDocumentRepository) SetFavorite(userID string, documentID string, isFavorite bool, token string) error {
+func (m *MockDocumentRepository) SetFavorite(ctx context.Context, principal domain.Principal, documentID string, isFavorite bool) error {
 	if doc, exists := m.documents[documentID]; exists {
 		doc.IsFavorite = isFavorite
 		return nil
@@ -105,6 +136,73 @@ func (m *MockDocumentRepository) SetFavorite(userID string, documentID string, i
 	return errors.New("document not found")
 }
 
+func (m *MockDocumentRepository) SetArchived(ctx context.Context, principal domain.Principal, documentID string, archived bool) error {
+	if doc, exists := m.documents[documentID]; exists {
+		doc.IsArchived = archived
+		return nil
+	}
+	return errors.New("document not found")
+}
+
+func (m *MockDocumentRepository) SetLastOpened(ctx context.Context, principal domain.Principal, documentID string, at time.Time) error {
+	if doc, exists := m.documents[documentID]; exists {
+		doc.LastOpenedAt = &at
+		return nil
+	}
+	return errors.New("document not found")
+}
+
+type MockDocumentVersionRepository struct {
+	versions map[string][]*domain.DocumentVersion // Oldest first
+}
+
+func NewMockDocumentVersionRepository() *MockDocumentVersionRepository {
+	return &MockDocumentVersionRepository{
+		versions: make(map[string][]*domain.DocumentVersion),
+	}
+}
+
+func (m *MockDocumentVersionRepository) Create(ctx context.Context, principal domain.Principal, version *domain.DocumentVersion) (*domain.DocumentVersion, error) {
+	saved := *version
+	saved.Version = 1
+	if existing := m.versions[version.DocumentID]; len(existing) > 0 {
+		saved.Version = existing[len(existing)-1].Version + 1
+	}
+	m.versions[version.DocumentID] = append(m.versions[version.DocumentID], &saved)
+	return &saved, nil
+}
+
+func (m *MockDocumentVersionRepository) ListByDocument(ctx context.Context, principal domain.Principal, documentID string) ([]*domain.DocumentVersion, error) {
+	existing := m.versions[documentID]
+	out := make([]*domain.DocumentVersion, 0, len(existing))
+	for i := len(existing) - 1; i >= 0; i-- {
+		listed := *existing[i]
+		listed.Content = nil
+		out = append(out, &listed)
+	}
+	return out, nil
+}
+
+func (m *MockDocumentVersionRepository) Get(ctx context.Context, principal domain.Principal, documentID string, version int) (*domain.DocumentVersion, error) {
+	for _, v := range m.versions[documentID] {
+		if v.Version == version {
+			return v, nil
+		}
+	}
+	return nil, domain.ErrVersionNotFound
+}
+
+func (m *MockDocumentVersionRepository) DeleteBefore(ctx context.Context, principal domain.Principal, documentID string, version int) error {
+	var kept []*domain.DocumentVersion
+	for _, v := range m.versions[documentID] {
+		if v.Version >= version {
+			kept = append(kept, v)
+		}
+	}
+	m.versions[documentID] = kept
+	return nil
+}
+
 type MockStorageService struct {
 	files map[string][]byte
 }
@@ -115,11 +213,48 @@ func NewMockStorageService() *MockStorageService {
 	}
 }
 
-func (m *MockStorageService) Upload(ctx context.Context, path string, file io.Reader, token string) error {
-	// Simplified mock - just record that upload was called
+func (m *MockStorageService) Upload(ctx context.Context, path string, file io.Reader, contentType string, token string) error {
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return err
+	}
+	m.files[path] = data
 	return nil
 }
 
+func (m *MockStorageService) SignedURL(ctx context.Context, path string, expiresIn time.Duration, token string) (string, error) {
+	if _, ok := m.files[path]; !ok {
+		return "", errors.New("object not found")
+	}
+	return "https://storage.test/sign/" + path, nil
+}
+
+func (m *MockStorageService) Delete(ctx context.Context, path string, token string) error {
+	delete(m.files, path)
+	return nil
+}
+
+func (m *MockStorageService) Exists(ctx context.Context, path string, token string) (bool, error) {
+	_, ok := m.files[path]
+	return ok, nil
+}
+
+func (m *MockStorageService) Stat(ctx context.Context, path string, token string) (int64, error) {
+	data, ok := m.files[path]
+	if !ok {
+		return 0, domain.ErrBlobNotFound
+	}
+	return int64(len(data)), nil
+}
+
+func (m *MockStorageService) Download(ctx context.Context, path string, token string) (io.ReadCloser, error) {
+	data, ok := m.files[path]
+	if !ok {
+		return nil, domain.ErrBlobNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
 type MockLogger struct {
 	messages []string
 }
@@ -151,7 +286,7 @@ func TestDocumentService_GetDocumentsByUserID(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test documents
 	doc1 := &domain.Document{
@@ -165,11 +300,11 @@ func TestDocumentService_GetDocumentsByUserID(t *testing.T) {
 		Title:  "Document 2",
 	}
 
-	_ = repo.Create(doc1, "token")
-	_ = repo.Create(doc2, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc1.UserID), doc1)
+	_ = repo.Create(context.Background(), testPrincipal(doc2.UserID), doc2)
 
 	// Test getting documents for user1
-	docs, err := service.GetDocumentsByUserID("user1", "token")
+	docs, err := service.GetDocumentsByUserID(context.Background(), testPrincipal("user1"))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -188,7 +323,7 @@ func TestDocumentService_GetDocument(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -197,10 +332,10 @@ func TestDocumentService_GetDocument(t *testing.T) {
 		Title:  "Document 1",
 	}
 
-	_ = repo.Create(doc, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc.UserID), doc)
 
 	// Test getting existing document
-	retrievedDoc, err := service.GetDocument("doc1", "token")
+	retrievedDoc, err := service.GetDocument(context.Background(), testPrincipal("user1"), "doc1")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -210,7 +345,7 @@ func TestDocumentService_GetDocument(t *testing.T) {
 	}
 
 	// Test getting non-existent document
-	_, err = service.GetDocument("nonexistent", "token")
+	_, err = service.GetDocument(context.Background(), testPrincipal("user1"), "nonexistent")
 	if err == nil {
 		t.Error("Expected error for non-existent document")
 	}
@@ -221,7 +356,7 @@ func TestDocumentService_DeleteDocument(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -230,22 +365,28 @@ func TestDocumentService_DeleteDocument(t *testing.T) {
 		Title:  "Document 1",
 	}
 
-	_ = repo.Create(doc, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc.UserID), doc)
 
 	// Verify document exists
-	_, err := repo.GetByID("doc1", "token")
+	_, err := repo.GetByID(context.Background(), testPrincipal("user1"), "doc1")
 	if err != nil {
 		t.Error("Expected document to exist before deletion")
 	}
 
+	// Another user cannot delete it
+	err = service.DeleteDocument(context.Background(), testPrincipal("user2"), "doc1")
+	if !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("Expected ErrAccessDenied, got %v", err)
+	}
+
 	// Delete document
-	err = service.DeleteDocument("doc1", "token")
+	err = service.DeleteDocument(context.Background(), testPrincipal("user1"), "doc1")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	// Verify document is deleted
-	_, err = repo.GetByID("doc1", "token")
+	_, err = repo.GetByID(context.Background(), testPrincipal("user1"), "doc1")
 	if err == nil {
 		t.Error("Expected document to be deleted")
 	}
@@ -256,7 +397,7 @@ func TestDocumentService_SearchDocuments(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test documents
 	doc1 := &domain.Document{
@@ -275,12 +416,12 @@ func TestDocumentService_SearchDocuments(t *testing.T) {
 		Title:  "Go Web Development",
 	}
 
-	_ = repo.Create(doc1, "token")
-	_ = repo.Create(doc2, "token")
-	_ = repo.Create(doc3, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc1.UserID), doc1)
+	_ = repo.Create(context.Background(), testPrincipal(doc2.UserID), doc2)
+	_ = repo.Create(context.Background(), testPrincipal(doc3.UserID), doc3)
 
 	// Test searching for "Go"
-	docs, err := service.SearchDocuments("user1", "Go", "token")
+	docs, err := service.SearchDocuments(context.Background(), testPrincipal("user1"), "Go")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -294,7 +435,7 @@ func TestDocumentService_SearchDocuments(t *testing.T) {
 	}
 
 	// Test searching for "Python"
-	docs, err = service.SearchDocuments("user1", "Python", "token")
+	docs, err = service.SearchDocuments(context.Background(), testPrincipal("user1"), "Python")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -306,6 +447,24 @@ func TestDocumentService_SearchDocuments(t *testing.T) {
 	if docs[0].ID != "doc2" {
 		t.Errorf("Expected document ID 'doc2', got '%s'", docs[0].ID)
 	}
+
+	// Results say where they matched, in the title and in the extracted text.
+	doc2.Content = json.RawMessage(`[{"type":"paragraph","content":"A tutorial on Python and its tooling."}]`)
+	docs, err = service.SearchDocuments(context.Background(), testPrincipal("user1"), "python")
+	if err != nil || len(docs) != 1 {
+		t.Fatalf("Expected 1 document, got %d (%v)", len(docs), err)
+	}
+	snippets := docs[0].Snippets
+	if len(snippets) != 2 || snippets[0].Field != "title" || snippets[1].Field != "content" ||
+		snippets[1].Text != "A tutorial on Python and its tooling." || snippets[1].Marks[0] != (domain.SearchMark{Start: 14, End: 20}) {
+		t.Errorf("unexpected snippets: %+v", snippets)
+	}
+
+	// Query syntax errors are the caller's.
+	var validationErrs domain.ValidationErrors
+	if _, err := service.SearchDocuments(context.Background(), testPrincipal("user1"), `"Go`); !errors.As(err, &validationErrs) {
+		t.Errorf("Expected a validation error for an unterminated phrase, got %v", err)
+	}
 }
 
 func TestDocumentService_SetFavorite(t *testing.T) {
@@ -313,7 +472,7 @@ func TestDocumentService_SetFavorite(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -323,43 +482,107 @@ func TestDocumentService_SetFavorite(t *testing.T) {
 		IsFavorite: false,
 	}
 
-	_ = repo.Create(doc, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc.UserID), doc)
 
 	// Test setting favorite to true
-	err := service.SetFavorite("user1", "doc1", true, "token")
+	err := service.SetFavorite(context.Background(), testPrincipal("user1"), "doc1", true)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	updatedDoc, _ := repo.GetByID("doc1", "token")
+	updatedDoc, _ := repo.GetByID(context.Background(), testPrincipal("user1"), "doc1")
 	if !updatedDoc.IsFavorite {
 		t.Error("Expected document to be marked as favorite")
 	}
 
 	// Test setting favorite to false
-	err = service.SetFavorite("user1", "doc1", false, "token")
+	err = service.SetFavorite(context.Background(), testPrincipal("user1"), "doc1", false)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
-	updatedDoc, _ = repo.GetByID("doc1", "token")
+	updatedDoc, _ = repo.GetByID(context.Background(), testPrincipal("user1"), "doc1")
 	if updatedDoc.IsFavorite {
 		t.Error("Expected document to not be marked as favorite")
 	}
 
 	// Test setting favorite for different user (should fail)
-	err = service.SetFavorite("user2", "doc1", true, "token")
+	err = service.SetFavorite(context.Background(), testPrincipal("user2"), "doc1", true)
 	if err == nil {
 		t.Error("Expected error when setting favorite for different user")
 	}
 }
 
+func TestDocumentService_SetArchived(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	updatedAt := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	doc := &domain.Document{ID: "doc1", UserID: "user1", Title: "Document 1", UpdatedAt: updatedAt}
+	_ = repo.Create(context.Background(), testPrincipal(doc.UserID), doc)
+
+	if err := service.SetArchived(context.Background(), testPrincipal("user1"), "doc1", true); err != nil {
+		t.Fatalf("archive failed: %v", err)
+	}
+	archived, _ := repo.GetByID(context.Background(), testPrincipal("user1"), "doc1")
+	if !archived.IsArchived || !archived.UpdatedAt.Equal(updatedAt) {
+		t.Fatalf("expected the document to be archived and otherwise untouched, got %+v", archived)
+	}
+
+	if err := service.SetArchived(context.Background(), testPrincipal("user1"), "doc1", false); err != nil {
+		t.Fatalf("unarchive failed: %v", err)
+	}
+	if archived.IsArchived {
+		t.Fatal("expected the document to be unarchived")
+	}
+
+	if err := service.SetArchived(context.Background(), testPrincipal("user2"), "doc1", true); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("expected an access denied error for another user, got %v", err)
+	}
+}
+
+func TestDocumentService_OpenDocument(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{ID: "doc1", UserID: "user1", Title: "Document 1", UpdatedAt: updatedAt})
+
+	if _, err := service.GetDocument(context.Background(), testPrincipal("user1"), "doc1"); err != nil {
+		t.Fatalf("GetDocument() error = %v", err)
+	}
+	if repo.documents["doc1"].LastOpenedAt != nil {
+		t.Fatal("GetDocument should not count as opening the document")
+	}
+	// During maintenance the open is served but not recorded.
+	if _, err := service.OpenDocument(domain.ContextWithReadOnly(context.Background()), testPrincipal("user1"), "doc1"); err != nil || repo.documents["doc1"].LastOpenedAt != nil {
+		t.Fatalf("expected a read-only open not to be recorded (%v)", err)
+	}
+
+	doc, err := service.OpenDocument(context.Background(), testPrincipal("user1"), "doc1")
+	if err != nil {
+		t.Fatalf("OpenDocument() error = %v", err)
+	}
+	if doc.LastOpenedAt == nil || repo.documents["doc1"].LastOpenedAt == nil {
+		t.Fatal("expected last_opened_at to be recorded")
+	}
+	if !repo.documents["doc1"].UpdatedAt.Equal(updatedAt) {
+		t.Error("opening a document should not change updated_at")
+	}
+
+	if _, err := service.OpenDocument(context.Background(), testPrincipal("user2"), "doc1"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("expected ErrAccessDenied for another user, got %v", err)
+	}
+}
+
 func TestDocumentService_UpdateDocumentDetails(t *testing.T) {
 	repo := NewMockDocumentRepository()
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create test document
 	doc := &domain.Document{
@@ -368,11 +591,11 @@ func TestDocumentService_UpdateDocumentDetails(t *testing.T) {
 		Title:  "Document 1",
 	}
 
-	_ = repo.Create(doc, "token")
+	_ = repo.Create(context.Background(), testPrincipal(doc.UserID), doc)
 
 	// Test updating title
 	newTitle := "Updated Title"
-	updatedDoc, err := service.UpdateDocumentDetails("user1", "doc1", &newTitle, nil, nil, "token")
+	updatedDoc, err := service.UpdateDocumentDetails(context.Background(), testPrincipal("user1"), "doc1", &newTitle, nil, nil)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -383,7 +606,7 @@ func TestDocumentService_UpdateDocumentDetails(t *testing.T) {
 
 	// Test updating author
 	newAuthor := "Updated Author"
-	updatedDoc, err = service.UpdateDocumentDetails("user1", "doc1", nil, &newAuthor, nil, "token")
+	updatedDoc, err = service.UpdateDocumentDetails(context.Background(), testPrincipal("user1"), "doc1", nil, &newAuthor, nil)
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -393,26 +616,204 @@ func TestDocumentService_UpdateDocumentDetails(t *testing.T) {
 	}
 
 	// Test updating for different user (should fail)
-	_, err = service.UpdateDocumentDetails("user2", "doc1", &newTitle, nil, nil, "token")
+	_, err = service.UpdateDocumentDetails(context.Background(), testPrincipal("user2"), "doc1", &newTitle, nil, nil)
 	if err == nil {
 		t.Error("Expected error when updating for different user")
 	}
 }
 
+// concurrentEditRepository hands out a copy of the document and then lets another
+// device rewrite the stored content, as if it saved between our read and write.
+type concurrentEditRepository struct {
+	*MockDocumentRepository
+}
+
+func (r concurrentEditRepository) GetByID(ctx context.Context, principal domain.Principal, id string) (*domain.Document, error) {
+	doc, err := r.MockDocumentRepository.GetByID(ctx, principal, id)
+	if err != nil {
+		return nil, err
+	}
+	read := *doc
+	edited := *doc
+	edited.Content = json.RawMessage(`[{"content":"edited elsewhere"}]`)
+	r.documents[id] = &edited
+	return &read, nil
+}
+
+func TestDocumentService_UpdateDocumentDetails_KeepsConcurrentEdits(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(concurrentEditRepository{repo}, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID:      "doc1",
+		UserID:  "user1",
+		Title:   "Original",
+		Content: json.RawMessage(`[{"content":"first draft"}]`),
+	})
+
+	tag := "poetry"
+	if _, err := service.UpdateDocumentDetails(context.Background(), testPrincipal("user1"), "doc1", nil, nil, &tag); err != nil {
+		t.Fatalf("UpdateDocumentDetails() error = %v", err)
+	}
+	stored := repo.documents["doc1"]
+	if stored.Tag == nil || *stored.Tag != tag {
+		t.Fatalf("tag not written: %+v", stored)
+	}
+	if string(stored.Content) != `[{"content":"edited elsewhere"}]` {
+		t.Fatalf("tag update overwrote a concurrent edit: %s", stored.Content)
+	}
+}
+
+func TestDocumentService_VersionsOnUpdate(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	versions := NewMockDocumentVersionRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, versions, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID:      "doc1",
+		UserID:  "user1",
+		Title:   "Original",
+		Content: json.RawMessage(`[{"content":"first draft"}]`),
+	})
+
+	for _, title := range []string{"Second", "Third"} {
+		if _, err := service.UpdateDocumentDetails(context.Background(), testPrincipal("user1"), "doc1", &title, nil, nil); err != nil {
+			t.Fatalf("UpdateDocumentDetails(%q) error = %v", title, err)
+		}
+	}
+
+	listed, err := service.ListVersions(context.Background(), testPrincipal("user1"), "doc1")
+	if err != nil {
+		t.Fatalf("ListVersions() error = %v", err)
+	}
+	if len(listed) != 2 || listed[0].Version != 2 || listed[0].Title != "Second" || listed[1].Title != "Original" {
+		t.Fatalf("unexpected versions: %+v", listed)
+	}
+	if listed[0].Content != nil {
+		t.Fatalf("listed versions should not carry content")
+	}
+
+	if _, err := service.ListVersions(context.Background(), testPrincipal("user2"), "doc1"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("expected ErrAccessDenied for another user, got %v", err)
+	}
+}
+
+func TestDocumentService_UpdateDocumentDetails_Stale(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	versions := NewMockDocumentVersionRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, versions, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	updatedAt := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{ID: "doc1", UserID: "user1", Title: "Theirs", UpdatedAt: updatedAt})
+
+	title := "Mine"
+	ctx := domain.ContextWithUnmodifiedSince(context.Background(), updatedAt.Add(-time.Second))
+	if _, err := service.UpdateDocumentDetails(ctx, testPrincipal("user1"), "doc1", &title, nil, nil); !errors.Is(err, domain.ErrStaleUpdate) {
+		t.Fatalf("expected ErrStaleUpdate, got %v", err)
+	}
+	if repo.documents["doc1"].Title != "Theirs" || len(versions.versions["doc1"]) != 0 {
+		t.Fatalf("stale update should not write the document or a version")
+	}
+
+	ctx = domain.ContextWithUnmodifiedSince(context.Background(), updatedAt)
+	if _, err := service.UpdateDocumentDetails(ctx, testPrincipal("user1"), "doc1", &title, nil, nil); err != nil {
+		t.Fatalf("UpdateDocumentDetails() with current precondition error = %v", err)
+	}
+}
+
+func TestDocumentService_RestoreVersion(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	versions := NewMockDocumentVersionRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, versions, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID:      "doc1",
+		UserID:  "user1",
+		Title:   "Current",
+		Content: json.RawMessage(`[{"content":"current text"}]`),
+	})
+	author := "Old Author"
+	_, _ = versions.Create(context.Background(), testPrincipal("user1"), &domain.DocumentVersion{
+		DocumentID: "doc1",
+		Title:      "Old",
+		Author:     &author,
+		Content:    json.RawMessage(`[{"content":"old text"}]`),
+		Metadata:   domain.DocumentMetadata{PageCount: 3},
+		Reason:     domain.VersionReasonUpdate,
+	})
+
+	restored, err := service.RestoreVersion(context.Background(), testPrincipal("user1"), "doc1", 1)
+	if err != nil {
+		t.Fatalf("RestoreVersion() error = %v", err)
+	}
+	if restored.Title != "Old" || restored.Author == nil || *restored.Author != author || restored.Metadata.PageCount != 3 {
+		t.Fatalf("unexpected restored document: %+v", restored)
+	}
+	if string(restored.Content) != `[{"content":"old text"}]` {
+		t.Fatalf("unexpected restored content: %s", restored.Content)
+	}
+	if restored.Metadata.ContentVersion != 1 {
+		t.Fatalf("expected the content version to be bumped, got %d", restored.Metadata.ContentVersion)
+	}
+
+	// The overwritten state is kept so the restore can be undone.
+	current, err := versions.Get(context.Background(), testPrincipal("user1"), "doc1", 2)
+	if err != nil {
+		t.Fatalf("expected snapshot of the overwritten state: %v", err)
+	}
+	if current.Title != "Current" || current.Reason != domain.VersionReasonRestore {
+		t.Fatalf("unexpected snapshot: %+v", current)
+	}
+
+	if _, err := service.RestoreVersion(context.Background(), testPrincipal("user1"), "doc1", 9); !errors.Is(err, domain.ErrVersionNotFound) {
+		t.Fatalf("expected ErrVersionNotFound, got %v", err)
+	}
+	if _, err := service.RestoreVersion(context.Background(), testPrincipal("user2"), "doc1", 1); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("expected ErrAccessDenied for another user, got %v", err)
+	}
+}
+
+func TestDocumentService_VersionsPruned(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	versions := NewMockDocumentVersionRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, versions, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{ID: "doc1", UserID: "user1", Title: "Doc"})
+	for i := 0; i < maxDocumentVersions+5; i++ {
+		title := fmt.Sprintf("Title %d", i)
+		if _, err := service.UpdateDocumentDetails(context.Background(), testPrincipal("user1"), "doc1", &title, nil, nil); err != nil {
+			t.Fatalf("UpdateDocumentDetails() error = %v", err)
+		}
+	}
+
+	kept := versions.versions["doc1"]
+	if len(kept) != maxDocumentVersions {
+		t.Fatalf("expected %d versions kept, got %d", maxDocumentVersions, len(kept))
+	}
+	if kept[len(kept)-1].Version != maxDocumentVersions+5 {
+		t.Fatalf("expected newest version %d, got %d", maxDocumentVersions+5, kept[len(kept)-1].Version)
+	}
+}
+
 func TestDocumentService_GetDocumentTags(t *testing.T) {
 	repo := NewMockDocumentRepository()
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Add some tags for user1
-	_ = repo.CreateTag("user1", "programming", "token")
-	_ = repo.CreateTag("user1", "tutorial", "token")
-	_ = repo.CreateTag("user2", "design", "token")
+	_ = repo.CreateTag(context.Background(), testPrincipal("user1"), "programming")
+	_ = repo.CreateTag(context.Background(), testPrincipal("user1"), "tutorial")
+	_ = repo.CreateTag(context.Background(), testPrincipal("user2"), "design")
 
 	// Test getting tags for user1
-	tags, err := service.GetDocumentTags("user1", "token")
+	tags, err := service.GetDocumentTags(context.Background(), testPrincipal("user1"))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -422,7 +823,7 @@ func TestDocumentService_GetDocumentTags(t *testing.T) {
 	}
 
 	// Test getting tags for user2
-	tags, err = service.GetDocumentTags("user2", "token")
+	tags, err = service.GetDocumentTags(context.Background(), testPrincipal("user2"))
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
@@ -437,22 +838,22 @@ func TestDocumentService_CreateTag(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Test creating valid tag
-	err := service.CreateTag("user1", "programming", "token")
+	err := service.CreateTag(context.Background(), testPrincipal("user1"), "programming")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	// Test creating tag with empty name (should fail)
-	err = service.CreateTag("user1", "", "token")
+	err = service.CreateTag(context.Background(), testPrincipal("user1"), "")
 	if err == nil {
 		t.Error("Expected error for empty tag name")
 	}
 
 	// Test creating tag with only whitespace (should fail)
-	err = service.CreateTag("user1", "   ", "token")
+	err = service.CreateTag(context.Background(), testPrincipal("user1"), "   ")
 	if err == nil {
 		t.Error("Expected error for whitespace-only tag name")
 	}
@@ -463,26 +864,537 @@ func TestDocumentService_DeleteTag(t *testing.T) {
 	storage := NewMockStorageService()
 	logger := NewMockLogger()
 
-	service := NewDocumentService(repo, nil, storage, logger)
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
 
 	// Create a tag first
-	_ = repo.CreateTag("user1", "programming", "token")
+	_ = repo.CreateTag(context.Background(), testPrincipal("user1"), "programming")
 
 	// Test deleting existing tag
-	err := service.DeleteTag("user1", "programming", "token")
+	err := service.DeleteTag(context.Background(), testPrincipal("user1"), "programming")
 	if err != nil {
 		t.Errorf("Expected no error, got %v", err)
 	}
 
 	// Test deleting non-existent tag (should fail)
-	err = service.DeleteTag("user1", "nonexistent", "token")
+	err = service.DeleteTag(context.Background(), testPrincipal("user1"), "nonexistent")
 	if err == nil {
 		t.Error("Expected error for non-existent tag")
 	}
 
 	// Test deleting tag with empty name (should fail)
-	err = service.DeleteTag("user1", "", "token")
+	err = service.DeleteTag(context.Background(), testPrincipal("user1"), "")
 	if err == nil {
 		t.Error("Expected error for empty tag name")
 	}
 }
+
+func TestDocumentService_Upload_FileTooLarge(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+
+	limits := domain.UploadLimits{PerFormat: map[string]int64{"pdf": 8}}
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), limits, domain.PDFLimits{}, nil, nil, logger)
+
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader("%PDF-1.7 more than eight bytes"), "book.pdf")
+	if !errors.Is(err, domain.ErrFileTooLarge) {
+		t.Fatalf("Expected ErrFileTooLarge, got %v", err)
+	}
+	if len(repo.documents) != 0 {
+		t.Fatalf("Expected no document to be created")
+	}
+
+	if got := service.UploadLimit(context.Background(), testPrincipal("user1"), "pdf"); got.MaxBytes != 8 || got.Plan != "free" {
+		t.Fatalf("Unexpected upload limit: %+v", got)
+	}
+}
+
+func TestDocumentService_Upload_PDFLimits(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{MaxPages: 2}, nil, nil, logger)
+
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), bytes.NewReader(testPDF("1.7", 3, "", "")), "book.pdf")
+	var pdfErr *domain.PDFValidationError
+	if !errors.As(err, &pdfErr) || pdfErr.Code != domain.PDFErrorTooLarge {
+		t.Fatalf("Expected %s, got %v", domain.PDFErrorTooLarge, err)
+	}
+	if len(storage.files) != 0 || len(repo.documents) != 0 {
+		t.Fatalf("Expected the PDF to be rejected before it is stored")
+	}
+}
+
+// countingReader records how many bytes were read from it.
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func TestDocumentService_Upload_StorageLimit(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	quota := domain.StorageLimitBytesForPlan("free")
+	repo.documents["existing"] = &domain.Document{
+		ID:       "existing",
+		UserID:   "user1",
+		Metadata: domain.DocumentMetadata{FileSize: quota - 10},
+	}
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	body := &countingReader{r: strings.NewReader(strings.Repeat("text ", 1000))}
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), body, "notes.txt")
+	if err == nil || !strings.Contains(err.Error(), "storage limit exceeded") {
+		t.Fatalf("Expected storage limit error, got %v", err)
+	}
+	// Reading stops once the remaining quota is exceeded instead of buffering the body.
+	if body.n > 11 {
+		t.Fatalf("Expected at most 11 bytes to be read, read %d", body.n)
+	}
+	if len(storage.files) != 0 {
+		t.Fatalf("Expected nothing to be stored, got %v", storage.files)
+	}
+}
+
+func TestDocumentService_Upload_AccountDisabled(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	prefsRepo := newMockUserPreferencesRepo()
+	_ = prefsRepo.SetAccountDisabled(context.Background(), testPrincipal("user1"), true)
+	service := NewDocumentService(repo, prefsRepo, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	body := &countingReader{r: strings.NewReader("notes")}
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), body, "notes.txt")
+	if !errors.Is(err, domain.ErrAccountDisabled) {
+		t.Fatalf("Expected ErrAccountDisabled, got %v", err)
+	}
+	if body.n != 0 || len(storage.files) != 0 || len(repo.documents) != 0 {
+		t.Fatalf("Expected nothing to be read or stored, read %d bytes", body.n)
+	}
+
+	// Other accounts, with or without preferences, are unaffected.
+	if _, err := service.Upload(context.Background(), testPrincipal("user2"), strings.NewReader("notes"), "notes.txt"); err != nil {
+		t.Fatalf("Expected upload of an active account to succeed, got %v", err)
+	}
+}
+
+func TestDocumentService_Upload_PreferencesUnavailable(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	prefsRepo := newMockUserPreferencesRepo()
+	prefsRepo.err = errors.New("connection refused")
+	service := NewDocumentService(repo, prefsRepo, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	// An account that cannot be checked is not let through.
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader("notes"), "notes.txt")
+	if err == nil || !errors.Is(err, prefsRepo.err) {
+		t.Fatalf("Expected the preferences error, got %v", err)
+	}
+	if len(storage.files) != 0 || len(repo.documents) != 0 {
+		t.Fatalf("Expected nothing to be stored, got %v", storage.files)
+	}
+}
+
+type recordingPublisher struct {
+	events []domain.Event
+}
+
+func (p *recordingPublisher) Publish(ctx context.Context, principal domain.Principal, event domain.Event) {
+	p.events = append(p.events, event)
+}
+
+func TestDocumentService_Upload_Events(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	quota := domain.StorageLimitBytesForPlan("free")
+	repo.documents["existing"] = &domain.Document{
+		ID:       "existing",
+		UserID:   "user1",
+		Metadata: domain.DocumentMetadata{FileSize: int64(float64(quota)*domain.QuotaWarningRatio) - 10},
+	}
+	events := &recordingPublisher{}
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, events, logger)
+
+	doc, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader(strings.Repeat("text ", 10)), "notes.txt")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events.events) != 2 {
+		t.Fatalf("Expected ingestion and quota events, got %+v", events.events)
+	}
+	if got := events.events[0]; got.Type != domain.EventIngestionDone || got.Data.(domain.DocumentEvent).DocumentID != doc.ID {
+		t.Fatalf("Unexpected ingestion event %+v", got)
+	}
+	if got := events.events[1]; got.Type != domain.EventQuotaWarning || got.Data.(domain.QuotaWarning).LimitBytes != quota {
+		t.Fatalf("Unexpected quota event %+v", got)
+	}
+
+	// Only crossing the threshold warns; the next upload stays quiet about the quota.
+	events.events = nil
+	if _, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader("more"), "more.txt"); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(events.events) != 1 || events.events[0].Type != domain.EventIngestionDone {
+		t.Fatalf("Expected only an ingestion event, got %+v", events.events)
+	}
+}
+
+func TestDocumentService_Upload_FileType(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		filename   string
+		wantErr    error
+		wantFormat string
+	}{
+		{name: "pdf", content: string(testPDF("1.7", 1, "", "")), filename: "book.pdf", wantFormat: "pdf"},
+		{name: "corrupt pdf", content: "%PDF-1.7\n1 0 obj\n<<", filename: "book.pdf", wantErr: domain.ErrInvalidFile},
+		{name: "text without extension", content: "Chapter one\n", filename: "notes", wantFormat: "txt"},
+		{name: "pdf named epub", content: "%PDF-1.7\n", filename: "book.epub", wantErr: domain.ErrInvalidFile},
+		{name: "binary", content: "\x89PNG\r\n\x1a\n\x00\x00", filename: "book.pdf", wantErr: domain.ErrUnsupportedFileType},
+		{name: "unsupported extension", content: "hello", filename: "book.docx", wantErr: domain.ErrUnsupportedFileType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMockDocumentRepository()
+			storage := NewMockStorageService()
+			logger := NewMockLogger()
+			service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+			doc, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader(tt.content), tt.filename)
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("Expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if doc.Metadata.Format != tt.wantFormat {
+				t.Fatalf("Expected format %q, got %q", tt.wantFormat, doc.Metadata.Format)
+			}
+			if sum := sha256.Sum256([]byte(tt.content)); doc.Metadata.SHA256 != hex.EncodeToString(sum[:]) {
+				t.Fatalf("Expected the content hash in metadata, got %q", doc.Metadata.SHA256)
+			}
+			if _, ok := storage.files["user1/"+doc.ID+"."+tt.wantFormat]; !ok {
+				t.Fatalf("Expected storage path with .%s extension, got %v", tt.wantFormat, storage.files)
+			}
+		})
+	}
+}
+
+// extractingDocumentRepository stores extracted content on Create, as background
+// extraction of a large PDF or EPUB does after the upload returns.
+type extractingDocumentRepository struct {
+	*MockDocumentRepository
+}
+
+func (r extractingDocumentRepository) Create(ctx context.Context, principal domain.Principal, document *domain.Document) error {
+	extracted := *document
+	extracted.Content = json.RawMessage(`[{"type":"paragraph","content":"extracted"}]`)
+	return r.MockDocumentRepository.Create(ctx, principal, &extracted)
+}
+
+func TestDocumentService_UploadImported_TagKeepsContent(t *testing.T) {
+	repo := extractingDocumentRepository{NewMockDocumentRepository()}
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	doc, err := service.uploadImported(context.Background(), testPrincipal("user1"), strings.NewReader("Chapter one\n"), "notes.txt",
+		&domain.ImportedBook{Title: "Notes", Tags: []string{"imported"}})
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	stored := repo.documents[doc.ID]
+	if stored.Tag == nil || *stored.Tag != "imported" {
+		t.Fatalf("expected the imported tag, got %v", stored.Tag)
+	}
+	if !strings.Contains(string(stored.Content), "extracted") {
+		t.Fatalf("expected tagging to leave the extracted content alone, got %s", stored.Content)
+	}
+}
+
+func TestDocumentService_Upload_EPUB(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	doc, err := service.Upload(context.Background(), testPrincipal("user1"), bytes.NewReader(sampleEPUB(t)), "moby.epub")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.Title != "Moby Dick" || doc.Metadata.Format != "epub" || doc.Metadata.PageCount != 2 {
+		t.Fatalf("Unexpected document: title %q, metadata %+v", doc.Title, doc.Metadata)
+	}
+
+	imagePath := "user1/" + doc.ID + "/images/000.png"
+	coverPath := "user1/" + doc.ID + "/images/001.jpg"
+	if _, ok := storage.files[imagePath]; !ok {
+		t.Fatalf("Expected image at %s, got %v", imagePath, storage.files)
+	}
+	if doc.Metadata.CoverPath != coverPath {
+		t.Fatalf("Expected cover path %s, got %s", coverPath, doc.Metadata.CoverPath)
+	}
+
+	var blocks []TextBlock
+	if err := json.Unmarshal(doc.Content, &blocks); err != nil {
+		t.Fatalf("Failed to decode content: %v", err)
+	}
+	var found bool
+	for _, b := range blocks {
+		if b.Type == "image" {
+			found = b.Src == imagePath
+		}
+	}
+	if !found {
+		t.Fatalf("Expected image block pointing at %s, got %+v", imagePath, blocks)
+	}
+}
+
+func TestDocumentService_GetOutline(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	outline := []domain.OutlineEntry{{Title: "Chapter 1", Page: 1}}
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID: "doc1", UserID: "user1", Metadata: domain.DocumentMetadata{Outline: outline},
+	})
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{ID: "doc2", UserID: "user1"})
+
+	got, err := service.GetOutline(context.Background(), testPrincipal("user1"), "doc1")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !reflect.DeepEqual(got, outline) {
+		t.Fatalf("Expected outline %+v, got %+v", outline, got)
+	}
+
+	got, err = service.GetOutline(context.Background(), testPrincipal("user1"), "doc2")
+	if err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("Expected empty outline, got %+v (err %v)", got, err)
+	}
+
+	if _, err := service.GetOutline(context.Background(), testPrincipal("user2"), "doc1"); err == nil {
+		t.Fatalf("Expected error for another user's document")
+	}
+}
+
+func TestDocumentService_CompareDocuments(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	create := func(id, userID, format, content string) {
+		_ = repo.Create(context.Background(), testPrincipal(userID), &domain.Document{
+			ID: id, UserID: userID, Content: json.RawMessage(content), Metadata: domain.DocumentMetadata{Format: format},
+		})
+	}
+	create("v1", "user1", "pdf", `[{"type":"paragraph","content":"Term: 12 months","page_number":1,"position":0}]`)
+	create("v2", "user1", "pdf", `[{"type":"paragraph","content":"Term: 24 months","page_number":1,"position":0}]`)
+	create("pending", "user1", "pdf", `[]`)
+	create("comic", "user1", "cbz", `[{"page":1,"path":"user1/comic/pages/0001.png","content_type":"image/png"}]`)
+	create("other", "user2", "pdf", `[{"type":"paragraph","content":"Private","page_number":1,"position":0}]`)
+
+	comparison, err := service.CompareDocuments(context.Background(), testPrincipal("user1"), "v1", "v2")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if comparison.LeftDocumentID != "v1" || comparison.RightDocumentID != "v2" || comparison.Summary.ParagraphsReplaced != 1 {
+		t.Fatalf("Unexpected comparison: %+v", comparison)
+	}
+
+	for _, id := range []string{"pending", "comic"} {
+		if _, err := service.CompareDocuments(context.Background(), testPrincipal("user1"), "v1", id); !errors.Is(err, domain.ErrDocumentNotComparable) {
+			t.Fatalf("%s: expected ErrDocumentNotComparable, got %v", id, err)
+		}
+	}
+	if _, err := service.CompareDocuments(context.Background(), testPrincipal("user1"), "v1", "other"); err == nil {
+		t.Fatalf("Expected error comparing with another user's document")
+	}
+}
+
+func TestDocumentService_GetReferences(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	references := []domain.Reference{{Number: 1, Raw: "J. Ba. Layer normalization. 2016.", Year: 2016, Page: 9, Position: 1}}
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID: "paper", UserID: "user1", Metadata: domain.DocumentMetadata{References: references},
+	})
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{ID: "novel", UserID: "user1"})
+
+	got, err := service.GetReferences(context.Background(), testPrincipal("user1"), "paper")
+	if err != nil || !reflect.DeepEqual(got, references) {
+		t.Fatalf("Expected references %+v, got %+v (err %v)", references, got, err)
+	}
+
+	got, err = service.GetReferences(context.Background(), testPrincipal("user1"), "novel")
+	if err != nil || got == nil || len(got) != 0 {
+		t.Fatalf("Expected empty references, got %+v (err %v)", got, err)
+	}
+
+	if _, err := service.GetReferences(context.Background(), testPrincipal("user2"), "paper"); err == nil {
+		t.Fatalf("Expected error for another user's document")
+	}
+}
+
+func TestDocumentService_Upload_Comic(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	data := testCBZ(t,
+		[2]string{"p10.png", testPNG(t, 20, 30)},
+		[2]string{"p2.jpg", "jpeg-bytes"},
+		[2]string{"ComicInfo.xml", "<ComicInfo><Title>The Whale</Title></ComicInfo>"},
+	)
+	doc, err := service.Upload(context.Background(), testPrincipal("user1"), bytes.NewReader(data), "whale.cbr")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if doc.Title != "The Whale" || doc.Metadata.Format != "cbz" || doc.Metadata.PageCount != 2 {
+		t.Fatalf("Unexpected document: title %q, metadata %+v", doc.Title, doc.Metadata)
+	}
+	if _, ok := storage.files["user1/"+doc.ID+".cbz"]; !ok {
+		t.Fatalf("Expected archive stored as .cbz, got %v", storage.files)
+	}
+
+	var manifest []domain.ComicPage
+	if err := json.Unmarshal(doc.Content, &manifest); err != nil {
+		t.Fatalf("Failed to decode manifest: %v", err)
+	}
+	want := []domain.ComicPage{
+		{Page: 1, Path: "user1/" + doc.ID + "/pages/0001.jpg", ContentType: "image/jpeg"},
+		{Page: 2, Path: "user1/" + doc.ID + "/pages/0002.png", ContentType: "image/png", Width: 20, Height: 30},
+	}
+	if !reflect.DeepEqual(manifest, want) {
+		t.Fatalf("Expected manifest %+v, got %+v", want, manifest)
+	}
+	if string(storage.files[want[0].Path]) != "jpeg-bytes" {
+		t.Fatalf("Expected page 1 stored at %s", want[0].Path)
+	}
+	if doc.Metadata.CoverPath != want[0].Path {
+		t.Fatalf("Expected cover %s, got %s", want[0].Path, doc.Metadata.CoverPath)
+	}
+
+	img, err := service.GetPageImage(context.Background(), testPrincipal("user1"), doc.ID, 2)
+	if err != nil {
+		t.Fatalf("GetPageImage: %v", err)
+	}
+	if img.URL != "https://storage.test/sign/"+want[1].Path || img.Width != 20 || img.ContentType != "image/png" {
+		t.Fatalf("Unexpected page image: %+v", img)
+	}
+	if !img.ExpiresAt.After(time.Now()) {
+		t.Fatalf("Expected future expiry, got %v", img.ExpiresAt)
+	}
+
+	for _, page := range []int{0, 3} {
+		if _, err := service.GetPageImage(context.Background(), testPrincipal("user1"), doc.ID, page); !errors.Is(err, domain.ErrPageNotFound) {
+			t.Fatalf("page %d: expected ErrPageNotFound, got %v", page, err)
+		}
+	}
+	if _, err := service.GetPageImage(context.Background(), testPrincipal("user2"), doc.ID, 1); err == nil {
+		t.Fatalf("Expected error for another user's comic")
+	}
+}
+
+func TestDocumentService_Upload_ComicRejected(t *testing.T) {
+	logger := NewMockLogger()
+	storage := NewMockStorageService()
+	service := NewDocumentService(NewMockDocumentRepository(), nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_, err := service.Upload(context.Background(), testPrincipal("user1"), bytes.NewReader([]byte("Rar!\x1a\x07\x00rar-data")), "issue.cbr")
+	if !errors.Is(err, domain.ErrUnsupportedFileType) {
+		t.Fatalf("Expected ErrUnsupportedFileType for RAR comic, got %v", err)
+	}
+	if len(storage.files) != 0 {
+		t.Fatalf("Expected nothing stored, got %v", storage.files)
+	}
+}
+
+func TestDocumentService_GetPageImage_NotComic(t *testing.T) {
+	repo := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	service := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	_ = repo.Create(context.Background(), testPrincipal("user1"), &domain.Document{
+		ID: "doc1", UserID: "user1", Content: json.RawMessage("[]"), Metadata: domain.DocumentMetadata{Format: "pdf"},
+	})
+	if _, err := service.GetPageImage(context.Background(), testPrincipal("user1"), "doc1", 1); !errors.Is(err, domain.ErrPageNotFound) {
+		t.Fatalf("Expected ErrPageNotFound, got %v", err)
+	}
+}
+
+type stubScanner struct {
+	result *domain.ScanResult
+	err    error
+}
+
+func (s stubScanner) Scan(ctx context.Context, filename string, content io.Reader) (*domain.ScanResult, error) {
+	return s.result, s.err
+}
+
+func TestDocumentService_Upload_Quarantine(t *testing.T) {
+	tests := []struct {
+		name    string
+		scanner domain.UploadScanner
+		reason  string
+	}{
+		{name: "clean", scanner: stubScanner{result: &domain.ScanResult{Clean: true}}},
+		{name: "infected", scanner: stubScanner{result: &domain.ScanResult{Signature: "Eicar-Test-Signature", Engine: "clamav"}}, reason: domain.QuarantineMalwareDetected},
+		{name: "scanner down", scanner: stubScanner{err: errors.New("connection refused")}, reason: domain.QuarantineScanFailed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := NewMockDocumentRepository()
+			storage := NewMockStorageService()
+			logger := NewMockLogger()
+			service := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, tt.scanner, nil, logger)
+
+			doc, err := service.Upload(context.Background(), testPrincipal("user1"), strings.NewReader("plain text"), "notes.txt")
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			_, err = service.GetDocument(context.Background(), testPrincipal("user1"), doc.ID)
+			if tt.reason == "" {
+				if doc.IsQuarantined() || err != nil {
+					t.Fatalf("Expected clean document, got quarantine %+v, err %v", doc.Metadata.Quarantine, err)
+				}
+				return
+			}
+			if !doc.IsQuarantined() || doc.Metadata.Quarantine.Reason != tt.reason {
+				t.Fatalf("Expected quarantine reason %q, got %+v", tt.reason, doc.Metadata.Quarantine)
+			}
+			if !errors.Is(err, domain.ErrDocumentQuarantined) {
+				t.Fatalf("Expected ErrDocumentQuarantined, got %v", err)
+			}
+			if len(storage.files) != 1 {
+				t.Fatalf("Expected quarantined file to be stored for review")
+			}
+		})
+	}
+}
+
+func testPrincipal(userID string) domain.Principal {
+	return domain.Principal{UserID: userID, Token: "token"}
+}
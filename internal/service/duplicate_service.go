@@ -0,0 +1,317 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// minTitleSimilarity is how alike two normalized titles must be to match when they
+// are not equal.
+const minTitleSimilarity = 0.85
+
+// DuplicateService finds copies of the same book in a library and merges them.
+type DuplicateService struct {
+	documents  *DocumentService
+	highlights domain.HighlightRepository
+	positions  domain.UserPreferencesRepository
+	logger     domain.Logger
+}
+
+func NewDuplicateService(
+	documents *DocumentService,
+	highlights domain.HighlightRepository,
+	positions domain.UserPreferencesRepository,
+	logger domain.Logger,
+) domain.DuplicateService {
+	return &DuplicateService{
+		documents:  documents,
+		highlights: highlights,
+		positions:  positions,
+		logger:     logger,
+	}
+}
+
+// duplicateMatch is why two documents of a cluster matched.
+type duplicateMatch struct {
+	reasons    []string
+	confidence float64
+}
+
+// duplicateKeys are a document's normalized title and authors, computed once per
+// listing rather than once per compared pair.
+type duplicateKeys struct {
+	doc       *domain.DocumentData
+	title     []rune
+	mainTitle string // title without a subtitle after a colon
+	authors   []string
+}
+
+func newDuplicateKeys(doc *domain.DocumentData) duplicateKeys {
+	main, _, _ := strings.Cut(doc.Title, ":")
+	keys := duplicateKeys{doc: doc, title: []rune(domain.CatalogKey(doc.Title)), mainTitle: domain.CatalogKey(main)}
+	for _, author := range documentAuthors(doc) {
+		keys.authors = append(keys.authors, domain.CatalogKey(author))
+	}
+	return keys
+}
+
+// matchDocuments compares two documents. An identical file always matches; otherwise
+// the titles must match and the authors must not disagree.
+func matchDocuments(a, b duplicateKeys) (duplicateMatch, bool) {
+	if sha := a.doc.Metadata.SHA256; sha != "" && sha == b.doc.Metadata.SHA256 {
+		return duplicateMatch{reasons: []string{domain.DuplicateSameFile}, confidence: 1}, true
+	}
+	if !similarTitles(a, b) {
+		return duplicateMatch{}, false
+	}
+
+	match := duplicateMatch{reasons: []string{domain.DuplicateSimilarTitle}, confidence: 0.5}
+	if len(a.authors) > 0 && len(b.authors) > 0 {
+		if !slices.ContainsFunc(a.authors, func(author string) bool { return slices.Contains(b.authors, author) }) {
+			return duplicateMatch{}, false
+		}
+		match.reasons = append(match.reasons, domain.DuplicateSameAuthor)
+		match.confidence += 0.25
+	}
+	if pages := a.doc.Metadata.PageCount; pages > 0 && pages == b.doc.Metadata.PageCount {
+		match.reasons = append(match.reasons, domain.DuplicateSamePageCount)
+		match.confidence += 0.2
+	}
+	return match, true
+}
+
+// similarTitles compares normalized titles, ignoring subtitles, and tolerates a few
+// character differences such as typos or edition marks in longer titles.
+func similarTitles(a, b duplicateKeys) bool {
+	if len(a.title) == 0 || len(b.title) == 0 {
+		return false
+	}
+	if a.mainTitle != "" && a.mainTitle == b.mainTitle {
+		return true
+	}
+	longest := max(len(a.title), len(b.title))
+	maxDistance := int(float64(longest) * (1 - minTitleSimilarity))
+	// The length difference alone rules most pairs out before the edit distance.
+	if diff := len(a.title) - len(b.title); diff > maxDistance || diff < -maxDistance {
+		return false
+	}
+	return levenshtein(a.title, b.title) <= maxDistance
+}
+
+// levenshtein returns the edit distance between two rune strings.
+func levenshtein(ra, rb []rune) int {
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+func (s *DuplicateService) FindDuplicates(ctx context.Context, principal domain.Principal) ([]*domain.DuplicateCluster, error) {
+	docs, err := s.documents.repo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+
+	// Union the matching pairs; a cluster's confidence is that of its weakest link.
+	parent := make([]int, len(docs))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(i int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	keys := make([]duplicateKeys, len(docs))
+	for i, doc := range docs {
+		keys[i] = newDuplicateKeys(doc)
+	}
+	matches := make(map[int][]duplicateMatch)
+	for i := range docs {
+		for j := i + 1; j < len(docs); j++ {
+			match, ok := matchDocuments(keys[i], keys[j])
+			if !ok {
+				continue
+			}
+			ri, rj := find(i), find(j)
+			if ri != rj {
+				parent[rj] = ri
+				matches[ri] = append(matches[ri], matches[rj]...)
+				delete(matches, rj)
+			}
+			matches[ri] = append(matches[ri], match)
+		}
+	}
+
+	members := make(map[int][]*domain.DocumentData)
+	var ids []string
+	for i, doc := range docs {
+		if root := find(i); len(matches[root]) > 0 {
+			members[root] = append(members[root], doc)
+			ids = append(ids, doc.ID)
+		}
+	}
+	if len(members) == 0 {
+		return []*domain.DuplicateCluster{}, nil
+	}
+
+	counts, err := s.highlights.CountByDocuments(ctx, principal, ids)
+	if err != nil {
+		s.logger.Warn("Failed to count highlights for duplicates", "user_id", principal.UserID, "error", err)
+	}
+
+	clusters := make([]*domain.DuplicateCluster, 0, len(members))
+	for root, cluster := range members {
+		slices.SortFunc(cluster, func(a, b *domain.DocumentData) int {
+			return cmp.Or(cmp.Compare(counts[b.ID], counts[a.ID]), a.CreatedAt.Compare(b.CreatedAt), strings.Compare(a.ID, b.ID))
+		})
+		result := &domain.DuplicateCluster{Documents: cluster, Confidence: 1, CanonicalID: cluster[0].ID}
+		for _, match := range matches[root] {
+			result.Confidence = min(result.Confidence, match.confidence)
+			for _, reason := range match.reasons {
+				if !slices.Contains(result.Reasons, reason) {
+					result.Reasons = append(result.Reasons, reason)
+				}
+			}
+		}
+		clusters = append(clusters, result)
+	}
+	slices.SortFunc(clusters, func(a, b *domain.DuplicateCluster) int {
+		return cmp.Or(cmp.Compare(b.Confidence, a.Confidence), strings.Compare(a.CanonicalID, b.CanonicalID))
+	})
+	return clusters, nil
+}
+
+func (s *DuplicateService) MergeDuplicates(ctx context.Context, principal domain.Principal, canonicalID string, duplicateIDs []string) (*domain.DuplicateMerge, error) {
+	if err := validateDuplicateMerge(canonicalID, duplicateIDs); err != nil {
+		return nil, err
+	}
+	duplicateIDs = slices.Compact(slices.Sorted(slices.Values(duplicateIDs)))
+
+	canonical, err := s.documents.repo.GetByID(ctx, principal, canonicalID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.documents.authz.AuthorizeDocument(ctx, principal, canonical, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
+	duplicates := make([]*domain.DocumentData, 0, len(duplicateIDs))
+	for _, id := range duplicateIDs {
+		doc, err := s.documents.repo.GetByID(ctx, principal, id)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.documents.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionDelete); err != nil {
+			return nil, err
+		}
+		duplicates = append(duplicates, doc)
+	}
+
+	moved, err := s.highlights.MoveToDocument(ctx, principal, duplicateIDs, canonicalID)
+	if err != nil {
+		return nil, err
+	}
+	result := &domain.DuplicateMerge{MergedIDs: duplicateIDs, HighlightsMoved: moved}
+
+	if result.PositionAdopted, err = s.adoptPosition(ctx, principal, canonicalID, duplicateIDs); err != nil {
+		return nil, err
+	}
+
+	// Carry over the favorite flag and a tag the canonical copy lacks.
+	changed := false
+	for _, doc := range duplicates {
+		if doc.IsFavorite && !canonical.IsFavorite {
+			if err := s.documents.repo.SetFavorite(ctx, principal, canonicalID, true); err != nil {
+				return nil, err
+			}
+			canonical.IsFavorite = true
+		}
+		if canonical.Tag == nil && doc.Tag != nil {
+			canonical.Tag = doc.Tag
+			changed = true
+		}
+	}
+	if changed {
+		canonical.UpdatedAt = time.Now().UTC()
+		if err := s.documents.repo.Update(ctx, principal, canonical); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, id := range duplicateIDs {
+		if err := s.documents.repo.Delete(ctx, principal, id); err != nil {
+			return nil, fmt.Errorf("failed to delete duplicate %s: %w", id, err)
+		}
+	}
+
+	s.logger.Info("Duplicates merged", "user_id", principal.UserID, "canonical_id", canonicalID,
+		"merged", len(duplicateIDs), "highlights_moved", moved)
+	result.Document = canonical
+	return result, nil
+}
+
+// adoptPosition keeps the most recently updated reading position of the group on the
+// canonical document, so the reader continues where they last were.
+func (s *DuplicateService) adoptPosition(ctx context.Context, principal domain.Principal, canonicalID string, duplicateIDs []string) (bool, error) {
+	positions, err := s.positions.GetReadingPositions(ctx, principal, append([]string{canonicalID}, duplicateIDs...))
+	if err != nil {
+		return false, err
+	}
+	latest := positions[canonicalID]
+	for _, id := range duplicateIDs {
+		if position := positions[id]; position != nil && (latest == nil || position.UpdatedAt.After(latest.UpdatedAt)) {
+			latest = position
+		}
+	}
+	if latest == nil || latest.DocumentID == canonicalID {
+		return false, nil
+	}
+
+	adopted := *latest
+	adopted.DocumentID = canonicalID
+	adopted.UserID = principal.UserID
+	if err := s.positions.UpdateReadingPosition(ctx, principal, &adopted); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func validateDuplicateMerge(canonicalID string, duplicateIDs []string) error {
+	var errs domain.ValidationErrors
+	if canonicalID == "" {
+		errs = append(errs, &domain.ValidationError{Field: "canonical_id", Message: "the document to keep is required"})
+	}
+	switch {
+	case len(duplicateIDs) == 0:
+		errs = append(errs, &domain.ValidationError{Field: "duplicate_ids", Message: "at least one duplicate is required"})
+	case slices.Contains(duplicateIDs, canonicalID):
+		errs = append(errs, &domain.ValidationError{Field: "duplicate_ids", Message: "cannot merge a document into itself"})
+	case slices.Contains(duplicateIDs, ""):
+		errs = append(errs, &domain.ValidationError{Field: "duplicate_ids", Message: "document IDs cannot be empty"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockHighlightRepo struct {
+	highlights []*domain.Highlight
+}
+
+func (m *mockHighlightRepo) Create(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) (*domain.Highlight, error) {
+	m.highlights = append(m.highlights, highlight)
+	return highlight, nil
+}
+
+func (m *mockHighlightRepo) ListByUser(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
+	var found []*domain.Highlight
+	for _, h := range m.highlights {
+		if documentID == nil || h.DocumentID == *documentID {
+			found = append(found, h)
+		}
+	}
+	return found, nil
+}
+
+func (m *mockHighlightRepo) CountByDocuments(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	counts := make(map[string]int)
+	for _, h := range m.highlights {
+		if slices.Contains(documentIDs, h.DocumentID) {
+			counts[h.DocumentID]++
+		}
+	}
+	return counts, nil
+}
+
+func (m *mockHighlightRepo) Delete(ctx context.Context, principal domain.Principal, highlightID string) error {
+	return nil
+}
+
+func (m *mockHighlightRepo) MoveToDocument(ctx context.Context, principal domain.Principal, fromDocumentIDs []string, toDocumentID string) (int, error) {
+	moved := 0
+	for _, h := range m.highlights {
+		if slices.Contains(fromDocumentIDs, h.DocumentID) {
+			h.DocumentID = toDocumentID
+			moved++
+		}
+	}
+	return moved, nil
+}
+
+func (m *mockHighlightRepo) UpdateAnchor(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	for i, h := range m.highlights {
+		if h.ID == highlight.ID {
+			m.highlights[i] = highlight
+		}
+	}
+	return nil
+}
+
+func (m *mockHighlightRepo) Search(ctx context.Context, principal domain.Principal, search domain.HighlightSearch) ([]*domain.Highlight, error) {
+	var found []*domain.Highlight
+	for _, h := range m.highlights {
+		text := strings.ToLower(h.Quote + " " + h.Note)
+		if strings.Contains(text, strings.ToLower(search.Query)) && (search.DocumentID == "" || h.DocumentID == search.DocumentID) {
+			found = append(found, h)
+		}
+	}
+	return found[:min(len(found), search.Limit)], nil
+}
+
+func (m *mockHighlightRepo) Get(ctx context.Context, principal domain.Principal, highlightID string) (*domain.Highlight, error) {
+	for _, h := range m.highlights {
+		if h.ID == highlightID {
+			return h, nil
+		}
+	}
+	return nil, domain.ErrHighlightNotFound
+}
+
+func (m *mockHighlightRepo) UpdateReview(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) error {
+	return m.UpdateAnchor(ctx, principal, highlight)
+}
+
+func newTestDuplicateService() (domain.DuplicateService, *MockDocumentRepository, *mockHighlightRepo, *mockUserPreferencesRepo) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	highlights := &mockHighlightRepo{}
+	positions := newMockUserPreferencesRepo()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	author := func(s string) *string { return &s }
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	docs := []*domain.Document{
+		{ID: "d1", Title: "The Dispossessed", Author: author("Ursula K. Le Guin"), CreatedAt: created,
+			Metadata: domain.DocumentMetadata{PageCount: 387}},
+		{ID: "d2", Title: "The Dispossesed", Author: author("Ursula K Le Guin"), CreatedAt: created.Add(time.Hour),
+			Metadata: domain.DocumentMetadata{PageCount: 387}},
+		{ID: "d3", Title: "The Dispossessed: An Ambiguous Utopia", Author: author("Ursula K. Le Guin"), CreatedAt: created.Add(2 * time.Hour)},
+		{ID: "d4", Title: "The Dispossessed", Author: author("Someone Else"), CreatedAt: created},
+		{ID: "d5", Title: "Notes", CreatedAt: created, Metadata: domain.DocumentMetadata{SHA256: "abc"}},
+		{ID: "d6", Title: "Scan 2", CreatedAt: created, Metadata: domain.DocumentMetadata{SHA256: "abc"}},
+	}
+	for _, doc := range docs {
+		doc.UserID = "user1"
+		repo.documents[doc.ID] = doc
+	}
+	return NewDuplicateService(documents, highlights, positions, logger), repo, highlights, positions
+}
+
+func TestDuplicateService_FindDuplicates(t *testing.T) {
+	s, _, highlights, _ := newTestDuplicateService()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	highlights.highlights = []*domain.Highlight{{ID: "h1", DocumentID: "d2"}}
+
+	clusters, err := s.FindDuplicates(ctx, principal)
+	if err != nil {
+		t.Fatalf("find failed: %v", err)
+	}
+	if len(clusters) != 2 {
+		t.Fatalf("expected 2 clusters, got %+v", clusters)
+	}
+
+	same := clusters[0]
+	if same.Confidence != 1 || len(same.Documents) != 2 || !slices.Contains(same.Reasons, domain.DuplicateSameFile) {
+		t.Fatalf("expected the identical files first, got %+v", same)
+	}
+
+	similar := clusters[1]
+	var ids []string
+	for _, doc := range similar.Documents {
+		ids = append(ids, doc.ID)
+	}
+	// The copy with highlights is kept; the book by another author is not a duplicate.
+	if !slices.Equal(ids, []string{"d2", "d1", "d3"}) || similar.CanonicalID != "d2" {
+		t.Fatalf("unexpected cluster %v (canonical %s)", ids, similar.CanonicalID)
+	}
+	if similar.Confidence != 0.75 || !slices.Contains(similar.Reasons, domain.DuplicateSameAuthor) {
+		t.Fatalf("unexpected cluster match %v %v", similar.Confidence, similar.Reasons)
+	}
+}
+
+func TestDuplicateService_MergeDuplicates(t *testing.T) {
+	s, repo, highlights, positions := newTestDuplicateService()
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	tag := "Science Fiction"
+	repo.documents["d2"].Tag = &tag
+	repo.documents["d3"].IsFavorite = true
+	highlights.highlights = []*domain.Highlight{{ID: "h1", DocumentID: "d2"}, {ID: "h2", DocumentID: "d3"}}
+	now := time.Now().UTC()
+	positions.positions["user1"] = map[string]*domain.ReadingPosition{
+		"d1": {UserID: "user1", DocumentID: "d1", Progress: 0.1, UpdatedAt: now.Add(-time.Hour)},
+		"d3": {UserID: "user1", DocumentID: "d3", Progress: 0.6, PageNumber: 120, UpdatedAt: now},
+	}
+
+	merge, err := s.MergeDuplicates(ctx, principal, "d1", []string{"d3", "d2", "d3"})
+	if err != nil {
+		t.Fatalf("merge failed: %v", err)
+	}
+	if merge.HighlightsMoved != 2 || !merge.PositionAdopted || !slices.Equal(merge.MergedIDs, []string{"d2", "d3"}) {
+		t.Fatalf("unexpected merge %+v", merge)
+	}
+	for _, h := range highlights.highlights {
+		if h.DocumentID != "d1" {
+			t.Fatalf("expected highlight %s on the kept copy, got %s", h.ID, h.DocumentID)
+		}
+	}
+	if position := positions.positions["user1"]["d1"]; position.Progress != 0.6 || position.PageNumber != 120 {
+		t.Fatalf("expected the latest position to be adopted, got %+v", position)
+	}
+
+	kept := repo.documents["d1"]
+	if !kept.IsFavorite || kept.Tag == nil || *kept.Tag != tag {
+		t.Fatalf("expected the favorite and tag to carry over, got %+v", kept)
+	}
+	if _, ok := repo.documents["d2"]; ok {
+		t.Fatal("expected the duplicates to be deleted")
+	}
+	if _, ok := repo.documents["d3"]; ok {
+		t.Fatal("expected the duplicates to be deleted")
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.MergeDuplicates(ctx, principal, "d1", []string{"d1"}); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := s.MergeDuplicates(ctx, testPrincipal("user2"), "d4", []string{"d1"}); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("expected an access denied error, got %v", err)
+	}
+}
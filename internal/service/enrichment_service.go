@@ -0,0 +1,156 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// EnrichmentService fills in missing document details from external book catalogs.
+type EnrichmentService struct {
+	documents *DocumentService
+	catalogs  []domain.BookCatalog
+	logger    domain.Logger
+}
+
+func NewEnrichmentService(documents *DocumentService, catalogs []domain.BookCatalog, logger domain.Logger) domain.EnrichmentService {
+	return &EnrichmentService{documents: documents, catalogs: catalogs, logger: logger}
+}
+
+func (s *EnrichmentService) EnrichDocument(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentData, error) {
+	doc, err := s.documents.repo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.documents.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionWrite); err != nil {
+		return nil, err
+	}
+	if err := checkUnmodified(ctx, doc); err != nil {
+		return nil, err
+	}
+
+	catalog, info, err := s.lookup(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+
+	before := *doc
+	fields := s.fill(ctx, principal, doc, catalog, info)
+	if len(fields) > 0 {
+		if err := s.documents.snapshot(ctx, principal, &before, domain.VersionReasonEnrich); err != nil {
+			return nil, err
+		}
+	}
+	doc.Metadata.Enrichment = &domain.MetadataEnrichment{
+		Source:     info.Source,
+		SourceID:   info.SourceID,
+		Fields:     fields,
+		EnrichedAt: time.Now().UTC(),
+	}
+	doc.UpdatedAt = time.Now().UTC()
+	if err := s.documents.repo.Update(ctx, principal, doc); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Document enriched", "doc_id", doc.ID, "source", info.Source, "fields", strings.Join(fields, ","))
+	return doc, nil
+}
+
+// lookup asks each catalog in turn for the document's book. A title match must be
+// the same title, so a loose search result does not overwrite anything.
+func (s *EnrichmentService) lookup(ctx context.Context, doc *domain.DocumentData) (domain.BookCatalog, *domain.BookInfo, error) {
+	query := domain.BookQuery{ISBN: doc.Metadata.ISBN, Title: doc.Title}
+	if doc.Author != nil {
+		if authors := domain.SplitAuthors(*doc.Author); len(authors) > 0 {
+			query.Author = authors[0]
+		}
+	}
+	if query.ISBN == "" && domain.CatalogKey(query.Title) == "" {
+		return nil, nil, domain.ErrBookNotFound
+	}
+
+	var lastErr error
+	for _, catalog := range s.catalogs {
+		info, err := catalog.Lookup(ctx, query)
+		switch {
+		case errors.Is(err, domain.ErrBookNotFound):
+			continue
+		case err != nil:
+			s.logger.Warn("Book catalog lookup failed", "catalog", catalog.Name(), "doc_id", doc.ID, "error", err)
+			lastErr = err
+			continue
+		}
+		if query.ISBN == "" && !sameTitle(query.Title, info.Title) {
+			continue
+		}
+		return catalog, info, nil
+	}
+	if lastErr != nil {
+		return nil, nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, lastErr)
+	}
+	return nil, nil, domain.ErrBookNotFound
+}
+
+// sameTitle reports whether a catalog title is the document's title, ignoring case,
+// punctuation and a subtitle after a colon on either side.
+func sameTitle(title, found string) bool {
+	a, b := domain.CatalogKey(title), domain.CatalogKey(found)
+	if a == "" || b == "" {
+		return false
+	}
+	mainTitle := func(s string) string {
+		main, _, _ := strings.Cut(s, ":")
+		return domain.CatalogKey(main)
+	}
+	return a == b || mainTitle(title) == mainTitle(found)
+}
+
+// fill copies the catalog's details into the fields the document lacks and returns
+// their names. A cover that fails to download is left out.
+func (s *EnrichmentService) fill(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, catalog domain.BookCatalog, info *domain.BookInfo) []string {
+	fields := make([]string, 0)
+	if (doc.Author == nil || strings.TrimSpace(*doc.Author) == "") && len(info.Authors) > 0 {
+		author := strings.Join(info.Authors, domain.AuthorSeparator)
+		doc.Author = &author
+		fields = append(fields, "author")
+	}
+	if (doc.Description == nil || strings.TrimSpace(*doc.Description) == "") && info.Description != "" {
+		description := info.Description
+		doc.Description = &description
+		fields = append(fields, "description")
+	}
+	if doc.Metadata.PublicationYear == 0 && info.PublicationYear > 0 {
+		doc.Metadata.PublicationYear = info.PublicationYear
+		fields = append(fields, "publication_year")
+	}
+	if doc.Metadata.ISBN == "" && info.ISBN != "" {
+		doc.Metadata.ISBN = info.ISBN
+		fields = append(fields, "isbn")
+	}
+	if doc.Metadata.CoverPath == "" && info.CoverURL != "" {
+		if coverPath, err := s.storeCover(ctx, principal, doc, catalog, info); err != nil {
+			s.logger.Warn("Failed to store catalog cover", "doc_id", doc.ID, "source", info.Source, "error", err)
+		} else {
+			doc.Metadata.CoverPath = coverPath
+			fields = append(fields, "cover")
+		}
+	}
+	return fields
+}
+
+func (s *EnrichmentService) storeCover(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, catalog domain.BookCatalog, info *domain.BookInfo) (string, error) {
+	data, contentType, err := catalog.FetchCover(ctx, info)
+	if err != nil {
+		return "", err
+	}
+	path := fmt.Sprintf("%s/%s/images/cover%s", principal.UserID, doc.ID, coverExtension(contentType))
+	if err := s.documents.storage.Upload(ctx, path, bytes.NewReader(data), contentType, principal.Token); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockBookCatalog struct {
+	name string
+	info *domain.BookInfo
+	err  error
+}
+
+func (c *mockBookCatalog) Name() string { return c.name }
+
+func (c *mockBookCatalog) Lookup(ctx context.Context, query domain.BookQuery) (*domain.BookInfo, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+	if c.info == nil {
+		return nil, domain.ErrBookNotFound
+	}
+	return c.info, nil
+}
+
+func (c *mockBookCatalog) FetchCover(ctx context.Context, info *domain.BookInfo) ([]byte, string, error) {
+	return []byte("png"), "image/png", nil
+}
+
+func newTestEnrichmentService(catalogs ...domain.BookCatalog) (domain.EnrichmentService, *MockDocumentRepository, *MockStorageService) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	documents := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	return NewEnrichmentService(documents, catalogs, logger), repo, storage
+}
+
+func TestEnrichmentService_EnrichDocument(t *testing.T) {
+	description := "My own summary"
+	unavailable := &mockBookCatalog{name: "down", err: errors.New("status 503")}
+	wrongTitle := &mockBookCatalog{name: "loose", info: &domain.BookInfo{Source: "loose", Title: "Dune Messiah", Authors: []string{"Someone"}}}
+	match := &mockBookCatalog{name: "openlibrary", info: &domain.BookInfo{
+		Source:          "openlibrary",
+		SourceID:        "/works/OL1W",
+		Title:           "Dune: Deluxe Edition",
+		Authors:         []string{"Frank Herbert"},
+		Description:     "Desert planet.",
+		PublicationYear: 1965,
+		CoverURL:        "https://covers.example.com/1.png",
+	}}
+	s, repo, storage := newTestEnrichmentService(unavailable, wrongTitle, match)
+	repo.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Dune", Description: &description}
+
+	doc, err := s.EnrichDocument(context.Background(), testPrincipal("user1"), "doc1")
+	if err != nil {
+		t.Fatalf("enrich failed: %v", err)
+	}
+	if doc.Author == nil || *doc.Author != "Frank Herbert" || doc.Metadata.PublicationYear != 1965 {
+		t.Fatalf("expected the missing details to be filled in, got %+v", doc)
+	}
+	if *doc.Description != description {
+		t.Fatalf("expected the user's description to be kept, got %q", *doc.Description)
+	}
+	if got := string(storage.files[doc.Metadata.CoverPath]); got != "png" {
+		t.Fatalf("expected the cover at %q, got %q", doc.Metadata.CoverPath, got)
+	}
+	enrichment := doc.Metadata.Enrichment
+	if enrichment == nil || enrichment.Source != "openlibrary" || len(enrichment.Fields) != 3 {
+		t.Fatalf("unexpected enrichment record %+v", enrichment)
+	}
+}
+
+func TestEnrichmentService_NoMatch(t *testing.T) {
+	s, repo, _ := newTestEnrichmentService(&mockBookCatalog{name: "empty"})
+	repo.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes"}
+	if _, err := s.EnrichDocument(context.Background(), testPrincipal("user1"), "doc1"); !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+
+	s, repo, _ = newTestEnrichmentService(&mockBookCatalog{name: "down", err: errors.New("timeout")})
+	repo.documents["doc1"] = &domain.Document{ID: "doc1", UserID: "user1", Title: "Notes"}
+	if _, err := s.EnrichDocument(context.Background(), testPrincipal("user1"), "doc1"); !errors.Is(err, domain.ErrCatalogUnavailable) {
+		t.Fatalf("expected a catalog unavailable error, got %v", err)
+	}
+}
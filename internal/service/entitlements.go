@@ -0,0 +1,29 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// accountPreferences loads the caller's preferences, which carry their plan and
+// storage limit, for an operation that adds data to the account. It fails with
+// domain.ErrAccountDisabled when the account was disabled: the auth middleware
+// checks the flag too, but a session validated before an admin disabled the
+// account, or whose status is still cached, would otherwise keep going. When the
+// preferences cannot be read the operation fails too rather than going ahead
+// unchecked. Without a preferences repository there is nothing to check.
+func accountPreferences(ctx context.Context, repo domain.UserPreferencesRepository, principal domain.Principal) (*domain.UserPreferences, error) {
+	if repo == nil {
+		return nil, nil
+	}
+	prefs, err := repo.GetPreferences(ctx, principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check account: %w", err)
+	}
+	if prefs != nil && prefs.AccountDisabled {
+		return nil, domain.ErrAccountDisabled
+	}
+	return prefs, nil
+}
@@ -0,0 +1,725 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"path"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// maxEPUBEntrySize caps how much of a single archive entry is decompressed, guarding
+// against zip bombs. Comic pages share the same cap.
+const maxEPUBEntrySize = 32 << 20
+
+// EPUBProcessor handles EPUB text, image and link extraction
+type EPUBProcessor struct {
+	logger domain.Logger
+	text   *PDFProcessor // shared text sanitization
+}
+
+// NewEPUBProcessor creates a new EPUB processor
+func NewEPUBProcessor(logger domain.Logger) *EPUBProcessor {
+	return &EPUBProcessor{
+		logger: logger,
+		text:   NewPDFProcessor(logger),
+	}
+}
+
+// EPUBMetadata contains metadata from the package document
+type EPUBMetadata struct {
+	Title        string
+	Author       string
+	Language     string
+	ChapterCount int
+}
+
+// EPUBImage is an image referenced by the book, keyed by its path inside the archive.
+type EPUBImage struct {
+	Path        string
+	ContentType string
+	Data        []byte
+}
+
+// EPUBResult is the output of EPUB extraction. Image blocks and Cover refer to
+// archive paths in Images until the caller rewrites them to storage locations.
+type EPUBResult struct {
+	Blocks   []TextBlock
+	Metadata EPUBMetadata
+	Images   []EPUBImage
+	Cover    string
+	Outline  []domain.OutlineEntry
+}
+
+// ProcessEPUB extracts text blocks, images and links from an EPUB file.
+// Each spine document becomes a page; block positions count from 0 within it.
+func (p *EPUBProcessor) ProcessEPUB(r io.ReaderAt, size int64) (*EPUBResult, error) {
+	return extractEPUB(r, size, p.text.sanitizeText)
+}
+
+type epubContainer struct {
+	Rootfiles []struct {
+		FullPath string `xml:"full-path,attr"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+type epubPackage struct {
+	Metadata struct {
+		Titles    []string `xml:"title"`
+		Creators  []string `xml:"creator"`
+		Languages []string `xml:"language"`
+		Metas     []struct {
+			Name    string `xml:"name,attr"`
+			Content string `xml:"content,attr"`
+		} `xml:"meta"`
+	} `xml:"metadata"`
+	Manifest []epubItem `xml:"manifest>item"`
+	Spine    struct {
+		Toc      string `xml:"toc,attr"` // NCX manifest id (EPUB 2)
+		Itemrefs []struct {
+			IDRef string `xml:"idref,attr"`
+		} `xml:"itemref"`
+	} `xml:"spine"`
+}
+
+type epubItem struct {
+	ID         string `xml:"id,attr"`
+	Href       string `xml:"href,attr"`
+	MediaType  string `xml:"media-type,attr"`
+	Properties string `xml:"properties,attr"`
+}
+
+// epubArchive wraps the zip with path lookups and manifest media types.
+type epubArchive struct {
+	files      map[string]*zip.File
+	mediaTypes map[string]string
+}
+
+func (a *epubArchive) read(name string) ([]byte, error) {
+	f, ok := a.files[name]
+	if !ok {
+		return nil, fmt.Errorf("missing %s", name)
+	}
+	return readZipFile(f, maxEPUBEntrySize)
+}
+
+// readZipFile decompresses an archive entry, failing once it exceeds limit bytes.
+func readZipFile(f *zip.File, limit int64) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(io.LimitReader(rc, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, fmt.Errorf("%s exceeds %d bytes", f.Name, limit)
+	}
+	return data, nil
+}
+
+func (a *epubArchive) contentType(name string) string {
+	if mt := a.mediaTypes[name]; mt != "" {
+		return mt
+	}
+	return mime.TypeByExtension(path.Ext(name))
+}
+
+// extractEPUB parses the container, package document and spine, then walks each
+// spine document collecting blocks, images and links.
+func extractEPUB(r io.ReaderAt, size int64, sanitize func(string) string) (*EPUBResult, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open EPUB archive: %w", err)
+	}
+
+	archive := &epubArchive{
+		files:      make(map[string]*zip.File, len(zr.File)),
+		mediaTypes: make(map[string]string),
+	}
+	for _, f := range zr.File {
+		archive.files[f.Name] = f
+	}
+
+	containerXML, err := archive.read("META-INF/container.xml")
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPUB container: %w", err)
+	}
+	var container epubContainer
+	if err := xml.Unmarshal(containerXML, &container); err != nil || len(container.Rootfiles) == 0 {
+		return nil, errors.New("invalid EPUB container: no rootfile")
+	}
+	opfPath := container.Rootfiles[0].FullPath
+
+	opfXML, err := archive.read(opfPath)
+	if err != nil {
+		return nil, fmt.Errorf("invalid EPUB package: %w", err)
+	}
+	var pkg epubPackage
+	if err := xml.Unmarshal(opfXML, &pkg); err != nil {
+		return nil, fmt.Errorf("invalid EPUB package: %w", err)
+	}
+
+	items := make(map[string]epubItem, len(pkg.Manifest))
+	for _, item := range pkg.Manifest {
+		item.Href = resolveEPUBPath(opfPath, item.Href)
+		items[item.ID] = item
+		archive.mediaTypes[item.Href] = item.MediaType
+	}
+
+	result := &EPUBResult{Metadata: EPUBMetadata{
+		Title:    firstNonEmpty(pkg.Metadata.Titles),
+		Author:   firstNonEmpty(pkg.Metadata.Creators),
+		Language: firstNonEmpty(pkg.Metadata.Languages),
+	}}
+	result.Cover = epubCover(&pkg, items)
+
+	ex := &epubExtractor{
+		archive:  archive,
+		sanitize: sanitize,
+		anchors:  make(map[string]epubTarget),
+		images:   make(map[string]bool),
+	}
+	for _, ref := range pkg.Spine.Itemrefs {
+		item, ok := items[ref.IDRef]
+		if !ok || !isXHTML(item.MediaType) {
+			continue
+		}
+		doc, err := archive.read(item.Href)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chapter %s: %w", item.Href, err)
+		}
+		ex.chapter(item.Href, doc)
+	}
+	ex.resolveLinks()
+	result.Outline = ex.outline(&pkg, items)
+
+	result.Blocks = ex.blocks
+	result.Metadata.ChapterCount = ex.page
+
+	if result.Cover != "" && archive.files[result.Cover] != nil {
+		ex.addImage(result.Cover)
+	} else {
+		result.Cover = ""
+	}
+	for _, name := range ex.imageOrder {
+		img, err := archive.read(name)
+		if err != nil {
+			continue
+		}
+		result.Images = append(result.Images, EPUBImage{Path: name, ContentType: archive.contentType(name), Data: img})
+	}
+
+	return result, nil
+}
+
+// epubCover finds the cover image via the EPUB 3 "cover-image" property or the
+// EPUB 2 <meta name="cover"> convention.
+func epubCover(pkg *epubPackage, items map[string]epubItem) string {
+	for _, item := range pkg.Manifest {
+		if hasProperty(item.Properties, "cover-image") {
+			return items[item.ID].Href
+		}
+	}
+	for _, meta := range pkg.Metadata.Metas {
+		if meta.Name == "cover" {
+			if item, ok := items[meta.Content]; ok && strings.HasPrefix(item.MediaType, "image/") {
+				return item.Href
+			}
+		}
+	}
+	return ""
+}
+
+// epubTarget is a resolved position inside the extracted book.
+type epubTarget struct {
+	page     int
+	position int
+}
+
+// epubPendingLink is an internal link waiting for all anchors to be known.
+type epubPendingLink struct {
+	block  int
+	link   int
+	target string
+}
+
+type epubExtractor struct {
+	archive  *epubArchive
+	sanitize func(string) string
+
+	blocks  []TextBlock
+	page    int
+	anchors map[string]epubTarget
+	pending []epubPendingLink
+
+	images     map[string]bool
+	imageOrder []string
+}
+
+func (ex *epubExtractor) addImage(name string) {
+	if !ex.images[name] {
+		ex.images[name] = true
+		ex.imageOrder = append(ex.imageOrder, name)
+	}
+}
+
+// epubBlockElements end the current text block when they open or close.
+var epubBlockElements = map[string]bool{
+	"p": true, "div": true, "li": true, "blockquote": true, "pre": true,
+	"section": true, "article": true, "aside": true, "header": true, "footer": true,
+	"figure": true, "figcaption": true, "dt": true, "dd": true, "tr": true,
+	"table": true, "ul": true, "ol": true, "dl": true, "hr": true, "body": true, "nav": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+}
+
+// chapterState accumulates one spine document.
+type chapterState struct {
+	href     string
+	steps    []int  // CFI step of each open element, the root element first
+	children []int  // child elements seen so far by each open element
+	block    string // path of the element the pending text belongs to
+	position int
+	heading  int
+	text     strings.Builder
+	anchors  []string
+	links    []BlockLink
+	targets  []string
+	linkText *strings.Builder
+	linkHref string
+	skip     int
+}
+
+func (ex *epubExtractor) chapter(href string, doc []byte) {
+	ex.page++
+	ex.anchors[href] = epubTarget{page: ex.page}
+	c := &chapterState{href: href}
+
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			c.open()
+			name := strings.ToLower(t.Name.Local)
+			if name == "head" || name == "script" || name == "style" {
+				c.skip++
+				continue
+			}
+			if c.skip > 0 {
+				continue
+			}
+			if epubBlockElements[name] {
+				ex.flush(c)
+				c.block = c.path()
+				if len(name) == 2 && name[0] == 'h' && name[1] >= '1' && name[1] <= '6' {
+					c.heading = int(name[1] - '0')
+				}
+			}
+			if id := epubAttr(t, "id"); id != "" {
+				c.anchors = append(c.anchors, id)
+			}
+			switch name {
+			case "a":
+				if href := epubAttr(t, "href"); href != "" {
+					c.linkHref = href
+					c.linkText = &strings.Builder{}
+				}
+			case "img", "image":
+				src := epubAttr(t, "src")
+				if src == "" {
+					src = epubAttr(t, "href") // SVG <image xlink:href>
+				}
+				ex.image(c, src, epubAttr(t, "alt"))
+			case "br":
+				c.text.WriteString("\n")
+			case "td", "th":
+				c.text.WriteString(" ")
+			}
+		case xml.EndElement:
+			c.close()
+			name := strings.ToLower(t.Name.Local)
+			if name == "head" || name == "script" || name == "style" {
+				if c.skip > 0 {
+					c.skip--
+				}
+				continue
+			}
+			if c.skip > 0 {
+				continue
+			}
+			if name == "a" && c.linkText != nil {
+				ex.endLink(c)
+			}
+			if epubBlockElements[name] {
+				ex.flush(c)
+				c.block = c.path()
+				c.heading = 0
+			}
+		case xml.CharData:
+			if c.skip > 0 {
+				continue
+			}
+			s := strings.NewReplacer("\n", " ", "\r", " ", "\t", " ").Replace(string(t))
+			c.text.WriteString(s)
+			if c.linkText != nil {
+				c.linkText.WriteString(s)
+			}
+		}
+	}
+	ex.flush(c)
+
+	// Anchors after the last block point at the chapter's final block.
+	if len(c.anchors) > 0 {
+		target := epubTarget{page: ex.page, position: max(c.position-1, 0)}
+		for _, id := range c.anchors {
+			ex.anchors[c.href+"#"+id] = target
+		}
+	}
+}
+
+// open enters an element. As in an EPUB CFI, the nth child element is step 2n, so
+// the body of a document with a head is "/4".
+func (c *chapterState) open() {
+	step := 0
+	if n := len(c.children); n > 0 {
+		c.children[n-1]++
+		step = c.children[n-1] * 2
+	}
+	c.steps = append(c.steps, step)
+	c.children = append(c.children, 0)
+}
+
+// close leaves the innermost element. The decoder balances end tags outside strict
+// mode, so they always match the open elements.
+func (c *chapterState) close() {
+	if n := len(c.steps); n > 0 {
+		c.steps = c.steps[:n-1]
+		c.children = c.children[:n-1]
+	}
+}
+
+// path is the location of the innermost open element below the root element.
+func (c *chapterState) path() string {
+	var b strings.Builder
+	for i := 1; i < len(c.steps); i++ {
+		fmt.Fprintf(&b, "/%d", c.steps[i])
+	}
+	return b.String()
+}
+
+func (ex *epubExtractor) endLink(c *chapterState) {
+	text := strings.Join(strings.Fields(c.linkText.String()), " ")
+	href := c.linkHref
+	c.linkText, c.linkHref = nil, ""
+	if text == "" {
+		return
+	}
+
+	if u, err := url.Parse(href); err == nil && u.Scheme != "" {
+		c.links = append(c.links, BlockLink{Text: ex.sanitize(text), Href: href})
+		c.targets = append(c.targets, "")
+		return
+	}
+
+	c.links = append(c.links, BlockLink{Text: ex.sanitize(text)})
+	c.targets = append(c.targets, resolveEPUBTarget(c.href, href))
+}
+
+func (ex *epubExtractor) image(c *chapterState, src, alt string) {
+	if src == "" {
+		return
+	}
+	name := resolveEPUBPath(c.href, src)
+	if ex.archive.files[name] == nil {
+		return
+	}
+	ex.flush(c)
+	ex.addImage(name)
+	ex.emit(c, TextBlock{Type: "image", Content: ex.sanitize(strings.TrimSpace(alt)), Src: name, Path: c.path()})
+}
+
+// flush emits the accumulated text as a paragraph or heading block.
+func (ex *epubExtractor) flush(c *chapterState) {
+	var lines []string
+	for _, line := range strings.Split(c.text.String(), "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	c.text.Reset()
+
+	links, targets := c.links, c.targets
+	c.links, c.targets = nil, nil
+	if len(lines) == 0 {
+		return
+	}
+
+	block := TextBlock{Type: "paragraph", Content: ex.sanitize(strings.Join(lines, "\n")), Path: c.block}
+	if c.heading > 0 {
+		block.Type = "heading"
+		block.Level = c.heading
+	}
+	block.Links = links
+	for i, target := range targets {
+		if target != "" {
+			ex.pending = append(ex.pending, epubPendingLink{block: len(ex.blocks), link: i, target: target})
+		}
+	}
+	ex.emit(c, block)
+}
+
+func (ex *epubExtractor) emit(c *chapterState, block TextBlock) {
+	block.PageNumber = ex.page
+	block.Position = c.position
+	block.Spine = c.href
+	if len(c.anchors) > 0 {
+		block.Anchor = c.anchors[0]
+		for _, id := range c.anchors {
+			ex.anchors[c.href+"#"+id] = epubTarget{page: ex.page, position: c.position}
+		}
+		c.anchors = nil
+	}
+	ex.blocks = append(ex.blocks, block)
+	c.position++
+}
+
+// resolveLinks points internal links at the page/position of their anchor, falling
+// back to the start of the target chapter. Links outside the spine stay unresolved.
+func (ex *epubExtractor) resolveLinks() {
+	for _, p := range ex.pending {
+		target, ok := ex.anchors[p.target]
+		if !ok {
+			file, _, _ := strings.Cut(p.target, "#")
+			target, ok = ex.anchors[file]
+		}
+		if !ok {
+			continue
+		}
+		link := &ex.blocks[p.block].Links[p.link]
+		link.Page = target.page
+		link.Position = target.position
+	}
+}
+
+// epubTocEntry is a raw table-of-contents entry before target resolution.
+type epubTocEntry struct {
+	title  string
+	depth  int
+	target string // archive path with optional #fragment
+}
+
+// outline builds the table of contents from the EPUB 3 nav document, falling back
+// to the EPUB 2 NCX. Entries whose target is not in the spine are dropped.
+func (ex *epubExtractor) outline(pkg *epubPackage, items map[string]epubItem) []domain.OutlineEntry {
+	var entries []epubTocEntry
+	for _, item := range pkg.Manifest {
+		if hasProperty(item.Properties, "nav") {
+			if doc, err := ex.archive.read(items[item.ID].Href); err == nil {
+				entries = parseEPUBNav(items[item.ID].Href, doc)
+			}
+			break
+		}
+	}
+	if len(entries) == 0 {
+		if item, ok := items[pkg.Spine.Toc]; ok {
+			if doc, err := ex.archive.read(item.Href); err == nil {
+				entries = parseEPUBNCX(item.Href, doc)
+			}
+		}
+	}
+
+	var outline []domain.OutlineEntry
+	for _, entry := range entries {
+		target, ok := ex.anchors[entry.target]
+		if !ok {
+			file, _, _ := strings.Cut(entry.target, "#")
+			if target, ok = ex.anchors[file]; !ok {
+				continue
+			}
+		}
+		outline = append(outline, domain.OutlineEntry{
+			Title:    entry.title,
+			Depth:    entry.depth,
+			Page:     target.page,
+			Position: target.position,
+		})
+	}
+	return outline
+}
+
+// parseEPUBNav reads <nav epub:type="toc"> from an EPUB 3 navigation document.
+// Depth follows <ol> nesting.
+func parseEPUBNav(href string, doc []byte) []epubTocEntry {
+	dec := xml.NewDecoder(bytes.NewReader(doc))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+
+	var (
+		entries  []epubTocEntry
+		navDepth int // >0 while inside the toc nav
+		olDepth  int
+		link     *strings.Builder
+		linkHref string
+	)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if navDepth == 0 {
+				if name == "nav" && hasProperty(epubAttr(t, "type"), "toc") {
+					navDepth = 1
+				}
+				continue
+			}
+			switch name {
+			case "nav":
+				navDepth++
+			case "ol":
+				olDepth++
+			case "a":
+				linkHref = epubAttr(t, "href")
+				link = &strings.Builder{}
+			}
+		case xml.EndElement:
+			if navDepth == 0 {
+				continue
+			}
+			switch strings.ToLower(t.Name.Local) {
+			case "nav":
+				navDepth--
+			case "ol":
+				olDepth--
+			case "a":
+				if link != nil {
+					if title := strings.Join(strings.Fields(link.String()), " "); title != "" && linkHref != "" {
+						entries = append(entries, epubTocEntry{
+							title:  title,
+							depth:  max(olDepth-1, 0),
+							target: resolveEPUBTarget(href, linkHref),
+						})
+					}
+					link = nil
+				}
+			}
+		case xml.CharData:
+			if link != nil {
+				link.Write(t)
+			}
+		}
+	}
+	return entries
+}
+
+type ncxNavPoint struct {
+	Label   string `xml:"navLabel>text"`
+	Content struct {
+		Src string `xml:"src,attr"`
+	} `xml:"content"`
+	Children []ncxNavPoint `xml:"navPoint"`
+}
+
+// parseEPUBNCX reads the navMap of an EPUB 2 toc.ncx.
+func parseEPUBNCX(href string, doc []byte) []epubTocEntry {
+	var ncx struct {
+		NavPoints []ncxNavPoint `xml:"navMap>navPoint"`
+	}
+	if err := xml.Unmarshal(doc, &ncx); err != nil {
+		return nil
+	}
+
+	var entries []epubTocEntry
+	var walk func(points []ncxNavPoint, depth int)
+	walk = func(points []ncxNavPoint, depth int) {
+		for _, point := range points {
+			title := strings.Join(strings.Fields(point.Label), " ")
+			if title != "" && point.Content.Src != "" {
+				entries = append(entries, epubTocEntry{
+					title:  title,
+					depth:  depth,
+					target: resolveEPUBTarget(href, point.Content.Src),
+				})
+			}
+			walk(point.Children, depth+1)
+		}
+	}
+	walk(ncx.NavPoints, 0)
+	return entries
+}
+
+// resolveEPUBTarget resolves an href with an optional fragment relative to base.
+func resolveEPUBTarget(base, href string) string {
+	file, fragment, _ := strings.Cut(href, "#")
+	target := base
+	if file != "" {
+		target = resolveEPUBPath(base, file)
+	}
+	if fragment != "" {
+		target += "#" + fragment
+	}
+	return target
+}
+
+func hasProperty(properties, name string) bool {
+	for _, prop := range strings.Fields(properties) {
+		if prop == name {
+			return true
+		}
+	}
+	return false
+}
+
+func epubAttr(el xml.StartElement, name string) string {
+	for _, attr := range el.Attr {
+		if strings.EqualFold(attr.Name.Local, name) {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// resolveEPUBPath resolves href relative to the archive path of base.
+func resolveEPUBPath(base, href string) string {
+	if unescaped, err := url.PathUnescape(href); err == nil {
+		href = unescaped
+	}
+	if strings.HasPrefix(href, "/") {
+		return strings.TrimPrefix(path.Clean(href), "/")
+	}
+	return path.Join(path.Dir(base), href)
+}
+
+func isXHTML(mediaType string) bool {
+	return mediaType == "application/xhtml+xml" || mediaType == "text/html"
+}
+
+func firstNonEmpty(values []string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
+	}
+	return ""
+}
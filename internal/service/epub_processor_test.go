@@ -0,0 +1,245 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// testEPUB builds an EPUB archive from path -> content pairs, adding the mimetype
+// entry and container.xml pointing at OEBPS/content.opf.
+func testEPUB(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+
+	w, err := zw.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		t.Fatalf("create mimetype: %v", err)
+	}
+	_, _ = w.Write([]byte("application/epub+zip"))
+
+	files["META-INF/container.xml"] = `<?xml version="1.0"?>
+<container version="1.0" xmlns="urn:oasis:names:tc:opendocument:xmlns:container">
+  <rootfiles><rootfile full-path="OEBPS/content.opf" media-type="application/oebps-package+xml"/></rootfiles>
+</container>`
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		_, _ = w.Write([]byte(content))
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("close zip: %v", err)
+	}
+	return buf.Bytes()
+}
+
+// zipReader adapts archive bytes to the ReaderAt and size the processors take.
+func zipReader(data []byte) (*bytes.Reader, int64) {
+	return bytes.NewReader(data), int64(len(data))
+}
+
+func sampleEPUB(t *testing.T) []byte {
+	return testEPUB(t, map[string]string{
+		"OEBPS/content.opf": `<?xml version="1.0"?>
+<package xmlns="http://www.idpf.org/2007/opf" version="3.0">
+  <metadata xmlns:dc="http://purl.org/dc/elements/1.1/">
+    <dc:title>Moby Dick</dc:title>
+    <dc:creator>Herman Melville</dc:creator>
+    <dc:language>en</dc:language>
+  </metadata>
+  <manifest>
+    <item id="c1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>
+    <item id="cover" href="images/cover.jpg" media-type="image/jpeg" properties="cover-image"/>
+    <item id="whale" href="images/whale%20one.png" media-type="image/png"/>
+  </manifest>
+  <spine><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`,
+		"OEBPS/text/ch1.xhtml": `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml"><head><title>One</title><style>p{}</style></head>
+<body>
+  <h1 id="loomings">Loomings</h1>
+  <p>Call me <em>Ishmael</em>. See <a href="ch2.xhtml#whale">the whale</a> or
+     <a href="https://example.com/moby">the web</a>.</p>
+  <figure><img src="../images/whale%20one.png" alt="A whale"/><figcaption>Fig&nbsp;1</figcaption></figure>
+</body></html>`,
+		"OEBPS/text/ch2.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body>
+  <h2>The Carpet-Bag</h2>
+  <p>Filler paragraph.</p>
+  <p id="whale">Here is the whale.<br/>Second line.</p>
+  <p><a href="#missing">Back</a> to <a href="ch1.xhtml">start</a>.</p>
+</body></html>`,
+		"OEBPS/images/cover.jpg":     "jpeg-bytes",
+		"OEBPS/images/whale one.png": "png-bytes",
+	})
+}
+
+func TestEPUBProcessor_ProcessEPUB(t *testing.T) {
+	book, err := NewEPUBProcessor(NewMockLogger()).ProcessEPUB(zipReader(sampleEPUB(t)))
+	if err != nil {
+		t.Fatalf("ProcessEPUB: %v", err)
+	}
+
+	if book.Metadata.Title != "Moby Dick" || book.Metadata.Author != "Herman Melville" ||
+		book.Metadata.Language != "en" || book.Metadata.ChapterCount != 2 {
+		t.Fatalf("unexpected metadata: %+v", book.Metadata)
+	}
+	if book.Cover != "OEBPS/images/cover.jpg" {
+		t.Fatalf("unexpected cover: %q", book.Cover)
+	}
+	if len(book.Images) != 2 || book.Images[0].Path != "OEBPS/images/whale one.png" ||
+		book.Images[0].ContentType != "image/png" || string(book.Images[1].Data) != "jpeg-bytes" {
+		t.Fatalf("unexpected images: %+v", book.Images)
+	}
+
+	want := []TextBlock{
+		{Type: "heading", Content: "Loomings", Level: 1, PageNumber: 1, Position: 0, Anchor: "loomings", Spine: "OEBPS/text/ch1.xhtml", Path: "/4/2"},
+		{Type: "paragraph", Content: "Call me Ishmael. See the whale or the web.", PageNumber: 1, Position: 1, Spine: "OEBPS/text/ch1.xhtml", Path: "/4/4"},
+		{Type: "image", Content: "A whale", PageNumber: 1, Position: 2, Src: "OEBPS/images/whale one.png", Spine: "OEBPS/text/ch1.xhtml", Path: "/4/6/2"},
+		{Type: "paragraph", Content: "Fig 1", PageNumber: 1, Position: 3, Spine: "OEBPS/text/ch1.xhtml", Path: "/4/6/4"},
+		{Type: "heading", Content: "The Carpet-Bag", Level: 2, PageNumber: 2, Position: 0, Spine: "OEBPS/text/ch2.xhtml", Path: "/2/2"},
+		{Type: "paragraph", Content: "Filler paragraph.", PageNumber: 2, Position: 1, Spine: "OEBPS/text/ch2.xhtml", Path: "/2/4"},
+		{Type: "paragraph", Content: "Here is the whale.\nSecond line.", PageNumber: 2, Position: 2, Anchor: "whale", Spine: "OEBPS/text/ch2.xhtml", Path: "/2/6"},
+		{Type: "paragraph", Content: "Back to start.", PageNumber: 2, Position: 3, Spine: "OEBPS/text/ch2.xhtml", Path: "/2/8"},
+	}
+	if len(book.Blocks) != len(want) {
+		t.Fatalf("expected %d blocks, got %d: %+v", len(want), len(book.Blocks), book.Blocks)
+	}
+	for i, w := range want {
+		got := book.Blocks[i]
+		got.Links = nil
+		if !reflect.DeepEqual(got, w) {
+			t.Fatalf("block %d: expected %#v, got %#v", i, w, got)
+		}
+	}
+
+	links := book.Blocks[1].Links
+	if len(links) != 2 {
+		t.Fatalf("expected 2 links, got %+v", links)
+	}
+	if links[0] != (BlockLink{Text: "the whale", Page: 2, Position: 2}) {
+		t.Fatalf("unexpected internal link: %+v", links[0])
+	}
+	if links[1] != (BlockLink{Text: "the web", Href: "https://example.com/moby"}) {
+		t.Fatalf("unexpected external link: %+v", links[1])
+	}
+
+	// Unknown fragments fall back to the chapter start; bare chapter links resolve too.
+	back := book.Blocks[7].Links
+	if len(back) != 2 || back[0] != (BlockLink{Text: "Back", Page: 2}) || back[1] != (BlockLink{Text: "start", Page: 1}) {
+		t.Fatalf("unexpected fallback links: %+v", back)
+	}
+}
+
+func TestEPUBProcessor_InvalidArchive(t *testing.T) {
+	p := NewEPUBProcessor(NewMockLogger())
+	if _, err := p.ProcessEPUB(zipReader([]byte("not a zip"))); err == nil {
+		t.Fatalf("expected error for non-zip input")
+	}
+	if _, err := p.ProcessEPUB(zipReader(testEPUB(t, map[string]string{}))); err == nil {
+		t.Fatalf("expected error for missing package document")
+	}
+}
+
+func TestEPUBProcessor_Outline(t *testing.T) {
+	chapters := map[string]string{
+		"OEBPS/text/ch1.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body>
+  <h1>Part One</h1>
+  <p>Intro.</p>
+  <h2 id="s1">Section</h2>
+</body></html>`,
+		"OEBPS/text/ch2.xhtml": `<html xmlns="http://www.w3.org/1999/xhtml"><body><h1>Part Two</h1></body></html>`,
+	}
+	nav := `<html xmlns="http://www.w3.org/1999/xhtml" xmlns:epub="http://www.idpf.org/2007/ops"><body>
+  <nav epub:type="landmarks"><ol><li><a href="text/ch2.xhtml">Landmark</a></li></ol></nav>
+  <nav epub:type="toc"><ol>
+    <li><a href="text/ch1.xhtml">Part <em>One</em></a>
+      <ol><li><a href="text/ch1.xhtml#s1">Section</a></li></ol>
+    </li>
+    <li><a href="text/ch2.xhtml">Part Two</a></li>
+    <li><a href="text/missing.xhtml">Gone</a></li>
+  </ol></nav>
+</body></html>`
+	ncx := `<?xml version="1.0"?>
+<ncx xmlns="http://www.daisy.org/z3986/2005/ncx/" version="2005-1"><navMap>
+  <navPoint id="n1"><navLabel><text>NCX One</text></navLabel><content src="text/ch1.xhtml"/>
+    <navPoint id="n2"><navLabel><text>NCX Section</text></navLabel><content src="text/ch1.xhtml#s1"/></navPoint>
+  </navPoint>
+  <navPoint id="n3"><navLabel><text>NCX Two</text></navLabel><content src="text/ch2.xhtml#unknown"/></navPoint>
+</navMap></ncx>`
+
+	opf := func(extraItems, spineAttrs string) string {
+		return `<package xmlns="http://www.idpf.org/2007/opf" version="3.0"><metadata/>
+  <manifest>
+    <item id="c1" href="text/ch1.xhtml" media-type="application/xhtml+xml"/>
+    <item id="c2" href="text/ch2.xhtml" media-type="application/xhtml+xml"/>
+    ` + extraItems + `
+  </manifest>
+  <spine` + spineAttrs + `><itemref idref="c1"/><itemref idref="c2"/></spine>
+</package>`
+	}
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  []domain.OutlineEntry
+	}{
+		{
+			name: "nav document",
+			files: map[string]string{
+				"OEBPS/content.opf": opf(`<item id="nav" href="nav.xhtml" media-type="application/xhtml+xml" properties="nav"/>
+    <item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>`, ` toc="ncx"`),
+				"OEBPS/nav.xhtml": nav,
+				"OEBPS/toc.ncx":   ncx,
+			},
+			want: []domain.OutlineEntry{
+				{Title: "Part One", Depth: 0, Page: 1, Position: 0},
+				{Title: "Section", Depth: 1, Page: 1, Position: 2},
+				{Title: "Part Two", Depth: 0, Page: 2, Position: 0},
+			},
+		},
+		{
+			name: "ncx fallback",
+			files: map[string]string{
+				"OEBPS/content.opf": opf(`<item id="ncx" href="toc.ncx" media-type="application/x-dtbncx+xml"/>`, ` toc="ncx"`),
+				"OEBPS/toc.ncx":     ncx,
+			},
+			want: []domain.OutlineEntry{
+				{Title: "NCX One", Depth: 0, Page: 1, Position: 0},
+				{Title: "NCX Section", Depth: 1, Page: 1, Position: 2},
+				{Title: "NCX Two", Depth: 0, Page: 2, Position: 0},
+			},
+		},
+		{
+			name:  "no table of contents",
+			files: map[string]string{"OEBPS/content.opf": opf("", "")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			files := map[string]string{}
+			for name, content := range chapters {
+				files[name] = content
+			}
+			for name, content := range tt.files {
+				files[name] = content
+			}
+
+			book, err := NewEPUBProcessor(NewMockLogger()).ProcessEPUB(zipReader(testEPUB(t, files)))
+			if err != nil {
+				t.Fatalf("ProcessEPUB: %v", err)
+			}
+			if !reflect.DeepEqual(book.Outline, tt.want) {
+				t.Fatalf("expected outline %+v, got %+v", tt.want, book.Outline)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package service
+
+import (
+	"context"
+	"sync"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// eventBufferSize is how many events a slow subscriber may fall behind before new
+// ones are dropped for it.
+const eventBufferSize = 16
+
+// EventBroker delivers events to the event streams open on this server. Streams
+// connected to another replica do not see them.
+type EventBroker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan domain.Event]struct{} // by user ID
+	logger      domain.Logger
+}
+
+func NewEventBroker(logger domain.Logger) domain.EventBroker {
+	return &EventBroker{
+		subscribers: make(map[string]map[chan domain.Event]struct{}),
+		logger:      logger,
+	}
+}
+
+func (b *EventBroker) Subscribe(ctx context.Context, principal domain.Principal) <-chan domain.Event {
+	ch := make(chan domain.Event, eventBufferSize)
+
+	b.mu.Lock()
+	if b.subscribers[principal.UserID] == nil {
+		b.subscribers[principal.UserID] = make(map[chan domain.Event]struct{})
+	}
+	b.subscribers[principal.UserID][ch] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[principal.UserID], ch)
+		if len(b.subscribers[principal.UserID]) == 0 {
+			delete(b.subscribers, principal.UserID)
+		}
+		close(ch)
+	}()
+
+	return ch
+}
+
+func (b *EventBroker) Publish(ctx context.Context, principal domain.Principal, event domain.Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers[principal.UserID] {
+		select {
+		case ch <- event:
+		default:
+			b.logger.Warn("Dropped event for slow subscriber", "user_id", principal.UserID, "event", event.Type)
+		}
+	}
+}
+
+// EventPublishers publishes each event to all of its publishers in turn.
+type EventPublishers []domain.EventPublisher
+
+func (p EventPublishers) Publish(ctx context.Context, principal domain.Principal, event domain.Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, principal, event)
+	}
+}
@@ -0,0 +1,41 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestEventBroker_DeliversToTheUsersSubscribers(t *testing.T) {
+	broker := NewEventBroker(NewMockLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	owner := domain.Principal{UserID: "owner"}
+
+	events := broker.Subscribe(ctx, owner)
+	other := broker.Subscribe(ctx, domain.Principal{UserID: "other"})
+
+	broker.Publish(context.Background(), owner, domain.Event{Type: domain.EventIngestionDone})
+	if event := <-events; event.Type != domain.EventIngestionDone {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	select {
+	case event := <-other:
+		t.Fatalf("event leaked to another user: %+v", event)
+	default:
+	}
+
+	// Events beyond the buffer are dropped instead of blocking the publisher.
+	for range eventBufferSize + 1 {
+		broker.Publish(context.Background(), owner, domain.Event{Type: domain.EventQuotaWarning})
+	}
+
+	cancel()
+	received := 0
+	for range events {
+		received++
+	}
+	if received != eventBufferSize {
+		t.Errorf("expected %d buffered events, got %d", eventBufferSize, received)
+	}
+}
@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"unicode/utf8"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Supported upload formats, keyed by domain.UploadFormat extension.
+var (
+	fileTypePDF  = domain.FileType{Format: "pdf", Extension: ".pdf", ContentType: "application/pdf"}
+	fileTypeEPUB = domain.FileType{Format: "epub", Extension: ".epub", ContentType: "application/epub+zip"}
+	fileTypeTXT  = domain.FileType{Format: "txt", Extension: ".txt", ContentType: "text/plain; charset=utf-8"}
+	fileTypeCBZ  = domain.FileType{Format: "cbz", Extension: ".cbz", ContentType: "application/vnd.comicbook+zip"}
+
+	// Many .cbr files are zip archives under the wrong extension; those are read as CBZ.
+	supportedFileTypes = map[string]domain.FileType{
+		"pdf":  fileTypePDF,
+		"epub": fileTypeEPUB,
+		"txt":  fileTypeTXT,
+		"cbz":  fileTypeCBZ,
+		"cbr":  fileTypeCBZ,
+	}
+)
+
+const supportedFormatsHint = "supported formats are pdf, epub, txt and cbz"
+
+// pdfHeaderWindow is how far into the file the %PDF- marker may appear; readers
+// tolerate leading garbage before the header.
+const pdfHeaderWindow = 1024
+
+// DetectFileType identifies a supported format from the file's magic bytes.
+func DetectFileType(data []byte) (domain.FileType, bool) {
+	return detectFileType(bytes.NewReader(data), int64(len(data)))
+}
+
+// detectFileType is DetectFileType over a file that may be spooled to disk. Only the
+// zip directory and, for text, the whole content are read beyond the header.
+func detectFileType(r io.ReaderAt, size int64) (domain.FileType, bool) {
+	head := readHead(r, size, pdfHeaderWindow)
+	if bytes.Contains(head, []byte("%PDF-")) {
+		return fileTypePDF, true
+	}
+
+	// EPUB (OCF) requires an uncompressed first zip entry named "mimetype" holding
+	// "application/epub+zip", which places both at fixed offsets.
+	if len(head) >= 58 && bytes.HasPrefix(head, []byte("PK\x03\x04")) &&
+		string(head[30:38]) == "mimetype" && string(head[38:58]) == "application/epub+zip" {
+		return fileTypeEPUB, true
+	}
+
+	if isComicArchive(r, size) {
+		return fileTypeCBZ, true
+	}
+
+	if size > 0 && isPlainText(io.NewSectionReader(r, 0, size)) {
+		return fileTypeTXT, true
+	}
+
+	return domain.FileType{}, false
+}
+
+// ValidateUpload detects the file type and checks it against the filename's extension.
+// A missing extension is accepted; a known extension must match the detected type.
+func ValidateUpload(data []byte, filename string) (domain.FileType, error) {
+	return validateUpload(bytes.NewReader(data), int64(len(data)), filename)
+}
+
+func validateUpload(r io.ReaderAt, size int64, filename string) (domain.FileType, error) {
+	fileType, ok := detectFileType(r, size)
+	if !ok {
+		head := readHead(r, size, pdfHeaderWindow)
+		if bytes.HasPrefix(head, rarSignature) {
+			return domain.FileType{}, fmt.Errorf("%w: RAR-compressed comic archives are not supported; repack the file as CBZ",
+				domain.ErrUnsupportedFileType)
+		}
+		return domain.FileType{}, fmt.Errorf("%w: detected %s; %s",
+			domain.ErrUnsupportedFileType, http.DetectContentType(head), supportedFormatsHint)
+	}
+
+	ext := domain.UploadFormat(filename)
+	if ext == "" {
+		return fileType, nil
+	}
+	if expected, known := supportedFileTypes[ext]; known {
+		if expected.Format == fileType.Format {
+			return fileType, nil
+		}
+		return domain.FileType{}, fmt.Errorf("%w: file extension .%s does not match detected type %s",
+			domain.ErrInvalidFile, ext, fileType.Format)
+	}
+	return domain.FileType{}, fmt.Errorf("%w: .%s files are not supported; %s",
+		domain.ErrUnsupportedFileType, ext, supportedFormatsHint)
+}
+
+// readHead returns up to n bytes from the start of the file.
+func readHead(r io.ReaderAt, size int64, n int) []byte {
+	head := make([]byte, min(size, int64(n)))
+	read, _ := r.ReadAt(head, 0)
+	return head[:read]
+}
+
+// readTail returns up to n bytes from the end of the file.
+func readTail(r io.ReaderAt, size int64, n int) []byte {
+	tail := make([]byte, min(size, int64(n)))
+	read, _ := r.ReadAt(tail, size-int64(len(tail)))
+	return tail[:read]
+}
+
+// isPlainText reports whether the content looks like UTF-8 text: valid encoding and
+// no control characters other than common whitespace.
+func isPlainText(r io.Reader) bool {
+	br := bufio.NewReader(r)
+	for {
+		c, width, err := br.ReadRune()
+		if err == io.EOF {
+			return true
+		}
+		if err != nil || (c == utf8.RuneError && width == 1) {
+			return false
+		}
+		if c < 0x20 && c != '\t' && c != '\n' && c != '\r' && c != '\f' {
+			return false
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package service
+
+import (
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestDetectFileType(t *testing.T) {
+	epub := []byte("PK\x03\x04" + string(make([]byte, 26)) + "mimetypeapplication/epub+zip" + "PK\x03\x04")
+
+	tests := []struct {
+		name   string
+		data   []byte
+		format string
+		ok     bool
+	}{
+		{"pdf", []byte("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n"), "pdf", true},
+		{"pdf after leading junk", append(make([]byte, 100), []byte("%PDF-1.7")...), "pdf", true},
+		{"epub", epub, "epub", true},
+		{"plain zip", []byte("PK\x03\x04" + string(make([]byte, 60))), "", false},
+		{"cbz", testCBZ(t, [2]string{"001.jpg", "jpeg-bytes"}), "cbz", true},
+		{"zip without images", testCBZ(t, [2]string{"notes.txt", "hello"}), "", false},
+		{"rar", []byte("Rar!\x1a\x07\x01\x00"), "", false},
+		{"text", []byte("Call me Ishmael.\r\n\tSome years ago…\n"), "txt", true},
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), "", false},
+		{"invalid utf-8", []byte{0xff, 0xfe, 'a'}, "", false},
+		{"empty", nil, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := DetectFileType(tt.data)
+			if ok != tt.ok || got.Format != tt.format {
+				t.Fatalf("expected (%q, %v), got (%q, %v)", tt.format, tt.ok, got.Format, ok)
+			}
+		})
+	}
+}
+
+func TestValidateUpload_Comics(t *testing.T) {
+	cbz := testCBZ(t, [2]string{"001.jpg", "jpeg-bytes"})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		filename string
+		wantErr  error
+	}{
+		{"cbz", cbz, "issue.cbz", nil},
+		{"zip under cbr extension", cbz, "issue.cbr", nil},
+		{"rar cbr", []byte("Rar!\x1a\x07\x00"), "issue.cbr", domain.ErrUnsupportedFileType},
+		{"comic as pdf", cbz, "issue.pdf", domain.ErrInvalidFile},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fileType, err := ValidateUpload(tt.data, tt.filename)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("expected %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && fileType.Format != "cbz" {
+				t.Fatalf("expected cbz, got %q", fileType.Format)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// fontURLTTL bounds how long a font download URL stays valid.
+const fontURLTTL = time.Hour
+
+// defaultFontFamily replaces a custom font in preferences when that font is deleted.
+const defaultFontFamily = "system-ui"
+
+type fontService struct {
+	repo      domain.FontRepository
+	prefsRepo domain.UserPreferencesRepository
+	storage   domain.BlobStore
+	logger    domain.Logger
+}
+
+func NewFontService(
+	repo domain.FontRepository,
+	prefsRepo domain.UserPreferencesRepository,
+	storage domain.BlobStore,
+	logger domain.Logger,
+) domain.FontService {
+	return &fontService{
+		repo:      repo,
+		prefsRepo: prefsRepo,
+		storage:   storage,
+		logger:    logger,
+	}
+}
+
+// UploadFont validates and stores a font file for a Pro user.
+func (s *fontService) UploadFont(ctx context.Context, principal domain.Principal, file io.Reader, filename string) (*domain.Font, error) {
+	prefs, err := accountPreferences(ctx, s.prefsRepo, principal)
+	if err != nil {
+		return nil, err
+	}
+	if prefs == nil || !domain.IsProPlan(prefs.SubscriptionPlan) {
+		return nil, domain.ErrPlanRequired
+	}
+
+	// Read one byte past the limit so oversized files are detected without buffering them.
+	data, err := io.ReadAll(io.LimitReader(file, domain.MaxFontBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font: %w", err)
+	}
+	if len(data) > domain.MaxFontBytes {
+		return nil, fmt.Errorf("%w: fonts are limited to %d bytes", domain.ErrFileTooLarge, domain.MaxFontBytes)
+	}
+
+	format, err := detectFontFormat(data)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if len(existing) >= domain.MaxFontsPerUser {
+		return nil, fmt.Errorf("%w: at most %d fonts per user", domain.ErrFontLimitReached, domain.MaxFontsPerUser)
+	}
+
+	id := uuid.New().String()
+	font := &domain.Font{
+		ID:        id,
+		UserID:    principal.UserID,
+		Name:      fontName(filename),
+		Format:    format,
+		SizeBytes: int64(len(data)),
+		Path:      fmt.Sprintf("%s/fonts/%s.%s", principal.UserID, id, format),
+	}
+
+	if err := s.storage.Upload(ctx, font.Path, bytes.NewReader(data), "font/"+format, principal.Token); err != nil {
+		return nil, fmt.Errorf("failed to upload font: %w", err)
+	}
+
+	created, err := s.repo.Create(ctx, principal, font)
+	if err != nil {
+		if delErr := s.storage.Delete(ctx, font.Path, principal.Token); delErr != nil {
+			s.logger.Warn("Failed to remove orphaned font file", "path", font.Path, "error", delErr)
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Font uploaded", "font_id", created.ID, "user_id", principal.UserID)
+	return s.withURL(ctx, principal, created), nil
+}
+
+// GetFont returns one of the user's fonts
+func (s *fontService) GetFont(ctx context.Context, principal domain.Principal, fontID string) (*domain.Font, error) {
+	return s.repo.Get(ctx, principal, fontID)
+}
+
+// ListFonts returns the user's fonts with signed download URLs
+func (s *fontService) ListFonts(ctx context.Context, principal domain.Principal) ([]*domain.Font, error) {
+	fonts, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	for i, font := range fonts {
+		fonts[i] = s.withURL(ctx, principal, font)
+	}
+	return fonts, nil
+}
+
+// DeleteFont removes a font and resets preferences that still reference it
+func (s *fontService) DeleteFont(ctx context.Context, principal domain.Principal, fontID string) error {
+	font, err := s.repo.Get(ctx, principal, fontID)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Delete(ctx, principal, fontID); err != nil {
+		return err
+	}
+	if err := s.storage.Delete(ctx, font.Path, principal.Token); err != nil {
+		s.logger.Warn("Failed to remove font file", "path", font.Path, "error", err)
+	}
+
+	prefs, err := s.prefsRepo.GetPreferences(ctx, principal)
+	if err != nil {
+		s.logger.Warn("Failed to load preferences after font deletion", "font_id", fontID, "error", err)
+	} else if prefs != nil && prefs.FontFamily == font.FontFamily() {
+		prefs.FontFamily = defaultFontFamily
+		prefs.UpdatedAt = time.Now()
+		if err := s.prefsRepo.UpdatePreferences(ctx, principal, prefs); err != nil {
+			return err
+		}
+	}
+
+	s.logger.Info("Font deleted", "font_id", fontID, "user_id", principal.UserID)
+	return nil
+}
+
+// withURL attaches a signed download URL; signing failures leave the URL empty.
+func (s *fontService) withURL(ctx context.Context, principal domain.Principal, font *domain.Font) *domain.Font {
+	url, err := s.storage.SignedURL(ctx, font.Path, fontURLTTL, principal.Token)
+	if err != nil {
+		s.logger.Warn("Failed to sign font URL", "font_id", font.ID, "error", err)
+		return font
+	}
+	font.URL = url
+	return font
+}
+
+// detectFontFormat identifies woff2 and TrueType files by their signature.
+func detectFontFormat(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("wOF2")):
+		return domain.FontFormatWOFF2, nil
+	case bytes.HasPrefix(data, []byte{0x00, 0x01, 0x00, 0x00}), bytes.HasPrefix(data, []byte("true")):
+		return domain.FontFormatTTF, nil
+	default:
+		return "", fmt.Errorf("%w: fonts must be woff2 or ttf", domain.ErrUnsupportedFileType)
+	}
+}
+
+// fontName derives a display name from the uploaded filename.
+func fontName(filename string) string {
+	name := strings.TrimSpace(strings.TrimSuffix(filepath.Base(filename), filepath.Ext(filename)))
+	if name == "" || name == "." {
+		return "Custom font"
+	}
+	return name
+}
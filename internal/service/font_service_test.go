@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockFontRepo struct {
+	fonts map[string]*domain.Font
+}
+
+func newMockFontRepo() *mockFontRepo {
+	return &mockFontRepo{fonts: make(map[string]*domain.Font)}
+}
+
+func (m *mockFontRepo) Create(ctx context.Context, principal domain.Principal, font *domain.Font) (*domain.Font, error) {
+	stored := *font
+	m.fonts[font.ID] = &stored
+	return font, nil
+}
+
+func (m *mockFontRepo) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Font, error) {
+	var out []*domain.Font
+	for _, font := range m.fonts {
+		if font.UserID == principal.UserID {
+			copied := *font
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockFontRepo) Get(ctx context.Context, principal domain.Principal, fontID string) (*domain.Font, error) {
+	font, ok := m.fonts[fontID]
+	if !ok || font.UserID != principal.UserID {
+		return nil, domain.ErrFontNotFound
+	}
+	copied := *font
+	return &copied, nil
+}
+
+func (m *mockFontRepo) Delete(ctx context.Context, principal domain.Principal, fontID string) error {
+	delete(m.fonts, fontID)
+	return nil
+}
+
+const woff2Header = "wOF2\x00\x01\x00\x00"
+
+func newTestFontService(plan string) (domain.FontService, *mockFontRepo, *mockUserPreferencesRepo, *MockStorageService) {
+	fonts := newMockFontRepo()
+	prefs := newMockUserPreferencesRepo()
+	prefs.prefs["user-1"] = &domain.UserPreferences{UserID: "user-1", FontFamily: "serif", SubscriptionPlan: plan}
+	storage := NewMockStorageService()
+	return NewFontService(fonts, prefs, storage, NewMockLogger()), fonts, prefs, storage
+}
+
+func TestFontService_UploadFont_RequiresPro(t *testing.T) {
+	svc, _, _, storage := newTestFontService("free")
+
+	_, err := svc.UploadFont(context.Background(), testPrincipal("user-1"), strings.NewReader(woff2Header), "Inter.woff2")
+	if !errors.Is(err, domain.ErrPlanRequired) {
+		t.Fatalf("expected ErrPlanRequired, got %v", err)
+	}
+	if len(storage.files) != 0 {
+		t.Fatalf("expected nothing uploaded, got %d files", len(storage.files))
+	}
+}
+
+func TestFontService_UploadFont_Formats(t *testing.T) {
+	tests := []struct {
+		name       string
+		content    string
+		wantFormat string
+		wantErr    error
+	}{
+		{name: "woff2", content: woff2Header, wantFormat: domain.FontFormatWOFF2},
+		{name: "truetype", content: "\x00\x01\x00\x00\x00\x10", wantFormat: domain.FontFormatTTF},
+		{name: "apple truetype", content: "true\x00\x10", wantFormat: domain.FontFormatTTF},
+		{name: "woff1", content: "wOFF\x00\x01\x00\x00", wantErr: domain.ErrUnsupportedFileType},
+		{name: "pdf", content: "%PDF-1.7", wantErr: domain.ErrUnsupportedFileType},
+		{name: "too large", content: woff2Header + strings.Repeat("x", domain.MaxFontBytes), wantErr: domain.ErrFileTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, _, _, storage := newTestFontService("pro_monthly")
+
+			font, err := svc.UploadFont(context.Background(), testPrincipal("user-1"), strings.NewReader(tt.content), "My Font.bin")
+			if tt.wantErr != nil {
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if font.Format != tt.wantFormat {
+				t.Fatalf("expected format %s, got %s", tt.wantFormat, font.Format)
+			}
+			if font.Name != "My Font" {
+				t.Fatalf("expected name from filename, got %q", font.Name)
+			}
+			if !strings.HasPrefix(font.Path, "user-1/fonts/") || !strings.HasSuffix(font.Path, "."+tt.wantFormat) {
+				t.Fatalf("unexpected storage path %q", font.Path)
+			}
+			if _, ok := storage.files[font.Path]; !ok {
+				t.Fatalf("expected font file to be uploaded")
+			}
+			if font.URL == "" {
+				t.Fatalf("expected signed URL")
+			}
+		})
+	}
+}
+
+func TestFontService_UploadFont_PerUserLimit(t *testing.T) {
+	svc, fonts, _, _ := newTestFontService("pro_yearly")
+	for i := 0; i < domain.MaxFontsPerUser; i++ {
+		id := string(rune('a' + i))
+		fonts.fonts[id] = &domain.Font{ID: id, UserID: "user-1"}
+	}
+
+	_, err := svc.UploadFont(context.Background(), testPrincipal("user-1"), strings.NewReader(woff2Header), "Inter.woff2")
+	if !errors.Is(err, domain.ErrFontLimitReached) {
+		t.Fatalf("expected ErrFontLimitReached, got %v", err)
+	}
+}
+
+func TestFontService_DeleteFont_ResetsPreferences(t *testing.T) {
+	svc, fonts, prefs, storage := newTestFontService("pro_monthly")
+	principal := testPrincipal("user-1")
+
+	font, err := svc.UploadFont(context.Background(), principal, strings.NewReader(woff2Header), "Inter.woff2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	prefs.prefs["user-1"].FontFamily = font.FontFamily()
+
+	if err := svc.DeleteFont(context.Background(), principal, font.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, ok := fonts.fonts[font.ID]; ok {
+		t.Fatalf("expected font row to be deleted")
+	}
+	if _, ok := storage.files[font.Path]; ok {
+		t.Fatalf("expected font file to be deleted")
+	}
+	if got := prefs.prefs["user-1"].FontFamily; got != defaultFontFamily {
+		t.Fatalf("expected font family reset to %s, got %s", defaultFontFamily, got)
+	}
+}
+
+func TestFontService_DeleteFont_NotFound(t *testing.T) {
+	svc, _, prefs, _ := newTestFontService("pro_monthly")
+
+	err := svc.DeleteFont(context.Background(), testPrincipal("user-1"), "missing")
+	if !errors.Is(err, domain.ErrFontNotFound) {
+		t.Fatalf("expected ErrFontNotFound, got %v", err)
+	}
+	if prefs.lastUpdated != nil {
+		t.Fatalf("expected preferences untouched")
+	}
+}
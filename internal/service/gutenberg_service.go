@@ -0,0 +1,67 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// GutenbergService imports public-domain books from Project Gutenberg.
+type GutenbergService struct {
+	catalog   domain.GutenbergCatalog
+	documents *DocumentService
+	logger    domain.Logger
+}
+
+func NewGutenbergService(catalog domain.GutenbergCatalog, documents *DocumentService, logger domain.Logger) domain.GutenbergService {
+	return &GutenbergService{
+		catalog:   catalog,
+		documents: documents,
+		logger:    logger,
+	}
+}
+
+func (s *GutenbergService) SearchCatalog(ctx context.Context, query string, page int) (*domain.GutenbergSearchResult, error) {
+	if page < 1 {
+		return nil, domain.ValidationErrors{{Field: "page", Message: "page must be 1 or more"}}
+	}
+	result, err := s.catalog.Search(ctx, query, page)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, err)
+	}
+	return result, nil
+}
+
+// ImportBook downloads the book's EPUB and uploads it like any other, so it is
+// extracted, counted against the user's storage and scanned.
+func (s *GutenbergService) ImportBook(ctx context.Context, principal domain.Principal, id int) (*domain.DocumentData, error) {
+	book, err := s.catalog.Book(ctx, id)
+	switch {
+	case errors.Is(err, domain.ErrBookNotFound):
+		return nil, err
+	case err != nil:
+		return nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, err)
+	case !book.HasEPUB:
+		return nil, domain.ErrBookNotFound
+	}
+
+	body, err := s.catalog.DownloadEPUB(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrCatalogUnavailable, err)
+	}
+	defer body.Close()
+
+	doc, err := s.documents.uploadImported(ctx, principal, body, fmt.Sprintf("pg%d.epub", id), &domain.ImportedBook{
+		Title:   book.Title,
+		Authors: book.Authors,
+		Source:  domain.GutenbergSource,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Gutenberg book imported", "user_id", principal.UserID, "gutenberg_id", id, "doc_id", doc.ID)
+	return doc, nil
+}
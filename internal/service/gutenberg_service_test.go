@@ -0,0 +1,94 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockGutenbergCatalog struct {
+	books       map[int]domain.GutenbergBook
+	epub        []byte
+	downloadErr error
+}
+
+func (m *mockGutenbergCatalog) Search(ctx context.Context, query string, page int) (*domain.GutenbergSearchResult, error) {
+	result := &domain.GutenbergSearchResult{Page: page}
+	for _, book := range m.books {
+		result.Books = append(result.Books, book)
+	}
+	result.Total = len(result.Books)
+	return result, nil
+}
+
+func (m *mockGutenbergCatalog) Book(ctx context.Context, id int) (*domain.GutenbergBook, error) {
+	book, ok := m.books[id]
+	if !ok {
+		return nil, domain.ErrBookNotFound
+	}
+	return &book, nil
+}
+
+func (m *mockGutenbergCatalog) DownloadEPUB(ctx context.Context, id int) (io.ReadCloser, error) {
+	if m.downloadErr != nil {
+		return nil, m.downloadErr
+	}
+	return io.NopCloser(bytes.NewReader(m.epub)), nil
+}
+
+func TestGutenbergService_ImportBook(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	catalog := &mockGutenbergCatalog{
+		books: map[int]domain.GutenbergBook{
+			2701: {ID: 2701, Title: "Moby-Dick; or, The Whale", Authors: []string{"Herman Melville"}, HasEPUB: true},
+			9999: {ID: 9999, Title: "An Audio Book"},
+		},
+		epub: sampleEPUB(t),
+	}
+	s := NewGutenbergService(catalog, documents, logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	doc, err := s.ImportBook(ctx, principal, 2701)
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	// The catalog's title wins over the one in the EPUB.
+	stored := repo.documents[doc.ID]
+	if stored == nil || stored.Title != "Moby-Dick; or, The Whale" || stored.Author == nil || *stored.Author != "Herman Melville" {
+		t.Fatalf("unexpected document %+v", stored)
+	}
+	if stored.Metadata.Format != "epub" || stored.Metadata.Source != domain.GutenbergSource || len(stored.Content) <= 2 {
+		t.Fatalf("expected an extracted EPUB from gutenberg, got %+v", stored.Metadata)
+	}
+
+	if _, err := s.ImportBook(ctx, principal, 1); !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("expected ErrBookNotFound for an unknown book, got %v", err)
+	}
+	if _, err := s.ImportBook(ctx, principal, 9999); !errors.Is(err, domain.ErrBookNotFound) {
+		t.Fatalf("expected ErrBookNotFound for a book without an EPUB, got %v", err)
+	}
+	catalog.downloadErr = errors.New("all mirrors down")
+	if _, err := s.ImportBook(ctx, principal, 2701); !errors.Is(err, domain.ErrCatalogUnavailable) {
+		t.Fatalf("expected ErrCatalogUnavailable, got %v", err)
+	}
+}
+
+func TestGutenbergService_SearchCatalog(t *testing.T) {
+	logger := NewMockLogger()
+	s := NewGutenbergService(&mockGutenbergCatalog{}, nil, logger)
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.SearchCatalog(context.Background(), "austen", 0); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error for page 0, got %v", err)
+	}
+	if result, err := s.SearchCatalog(context.Background(), "austen", 2); err != nil || result.Page != 2 {
+		t.Fatalf("unexpected result %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,259 @@
+package service
+
+import (
+	"slices"
+	"sort"
+	"unicode"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// highlightContextLen is how many characters of text around a quote are kept with a
+// highlight.
+const highlightContextLen = 32
+
+// Fuzzy quote matching tolerates this share of differing characters, for quotes of
+// at least minFuzzyQuote characters. It first looks within fuzzyWindow characters of
+// where the quote used to be, and scans the whole text only when that costs at most
+// maxFuzzyCost comparisons.
+const (
+	maxQuoteErrors = 0.2
+	minFuzzyQuote  = 8
+	fuzzyWindow    = 20000
+	maxFuzzyCost   = 50_000_000
+)
+
+// foldedText is a document's text prepared for quote matching: lowercased, with
+// whitespace runs collapsed to one space and blocks separated by one. Each rune
+// keeps the text offset it came from.
+type foldedText struct {
+	runes   []rune
+	offsets []int
+}
+
+func (ix *textIndex) folded() *foldedText {
+	if ix.fold != nil {
+		return ix.fold
+	}
+	f := &foldedText{}
+	space := func(offset int) {
+		if n := len(f.runes); n > 0 && f.runes[n-1] != ' ' {
+			f.runes = append(f.runes, ' ')
+			f.offsets = append(f.offsets, offset)
+		}
+	}
+	for i, block := range ix.blocks {
+		offset := ix.starts[i]
+		for _, r := range block.Content {
+			if unicode.IsSpace(r) {
+				space(offset)
+			} else {
+				f.runes = append(f.runes, unicode.ToLower(r))
+				f.offsets = append(f.offsets, offset)
+			}
+			offset++
+		}
+		space(offset)
+	}
+	ix.fold = f
+	return f
+}
+
+// foldQuote folds a quote or context the way foldedText folds the document.
+func foldQuote(s string) []rune {
+	var out []rune
+	for _, r := range s {
+		switch {
+		case !unicode.IsSpace(r):
+			out = append(out, unicode.ToLower(r))
+		case len(out) > 0 && out[len(out)-1] != ' ':
+			out = append(out, ' ')
+		}
+	}
+	if n := len(out); n > 0 && out[n-1] == ' ' {
+		out = out[:n-1]
+	}
+	return out
+}
+
+// locateQuote finds a quote in the text and returns its start and end offsets. Of
+// several exact occurrences it picks the one whose surrounding text best matches
+// the context, then the nearest to expected. Without an exact occurrence, the
+// closest approximate match near expected, then anywhere, is used.
+func (ix *textIndex) locateQuote(quote, before, after string, expected int) (int, int, bool) {
+	q := foldQuote(quote)
+	if len(q) == 0 {
+		return 0, 0, false
+	}
+	f := ix.folded()
+	at := sort.SearchInts(f.offsets, expected)
+
+	best, bestScore, bestDistance := -1, -1, 0
+	contextBefore, contextAfter := foldQuote(before), foldQuote(after)
+	for i := 0; i+len(q) <= len(f.runes); i++ {
+		if f.runes[i] != q[0] || !slices.Equal(f.runes[i:i+len(q)], q) {
+			continue
+		}
+		score := commonSuffix(f.runes[:i], contextBefore) + commonPrefix(f.runes[i+len(q):], contextAfter)
+		distance := max(i-at, at-i)
+		if score > bestScore || score == bestScore && distance < bestDistance {
+			best, bestScore, bestDistance = i, score, distance
+		}
+	}
+	if best >= 0 {
+		return f.offsets[best], f.offsets[best+len(q)-1] + 1, true
+	}
+	if len(q) < minFuzzyQuote {
+		return 0, 0, false
+	}
+
+	maxErrors := max(1, int(float64(len(q))*maxQuoteErrors))
+	from, to := max(0, at-fuzzyWindow-4*len(q)), min(len(f.runes), at+fuzzyWindow+4*len(q))
+	start, end, ok := fuzzyFind(f.runes[from:to], q, maxErrors)
+	if !ok {
+		if from == 0 && to == len(f.runes) || len(q)*len(f.runes) > maxFuzzyCost {
+			return 0, 0, false
+		}
+		from = 0
+		if start, end, ok = fuzzyFind(f.runes, q, maxErrors); !ok {
+			return 0, 0, false
+		}
+	}
+	return f.offsets[from+start], f.offsets[from+end-1] + 1, true
+}
+
+// fuzzyFind returns the span of text closest to pattern by edit distance, if within
+// maxErrors. The end comes from a forward pass of Sellers' algorithm, the start from
+// a pass over the reversed text before that end.
+func fuzzyFind(text, pattern []rune, maxErrors int) (int, int, bool) {
+	end, distance := sellers(text, pattern, false)
+	if distance > maxErrors || end == 0 {
+		return 0, 0, false
+	}
+	from := max(0, end-len(pattern)-maxErrors)
+	rtext, rpattern := slices.Clone(text[from:end]), slices.Clone(pattern)
+	slices.Reverse(rtext)
+	slices.Reverse(rpattern)
+	length, _ := sellers(rtext, rpattern, true)
+	return end - length, end, true
+}
+
+// sellers finds where pattern ends in text with the fewest edits and returns that
+// end (exclusive) and the edit distance. The match may start anywhere in text unless
+// anchored, when it starts at the beginning.
+func sellers(text, pattern []rune, anchored bool) (int, int) {
+	column := make([]int, len(pattern)+1)
+	for i := range column {
+		column[i] = i
+	}
+	bestEnd, bestDistance := 0, len(pattern)
+	for j, r := range text {
+		diagonal := column[0]
+		if anchored {
+			column[0] = j + 1
+		}
+		for i := 1; i <= len(pattern); i++ {
+			cost := 1
+			if pattern[i-1] == r {
+				cost = 0
+			}
+			next := min(column[i]+1, column[i-1]+1, diagonal+cost)
+			diagonal, column[i] = column[i], next
+		}
+		if column[len(pattern)] < bestDistance {
+			bestEnd, bestDistance = j+1, column[len(pattern)]
+		}
+	}
+	return bestEnd, bestDistance
+}
+
+// commonSuffix and commonPrefix count the characters text and context share at their
+// end and start. Spaces are skipped: a quote is matched without the spaces around it,
+// and a context taken across blocks has no space where the folded text has one.
+func commonSuffix(text, context []rune) int {
+	n := 0
+	i, j := len(text)-1, len(context)-1
+	for i >= 0 && j >= 0 {
+		switch {
+		case text[i] == ' ':
+			i--
+		case context[j] == ' ':
+			j--
+		case text[i] == context[j]:
+			n++
+			i--
+			j--
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+func commonPrefix(text, context []rune) int {
+	n := 0
+	i, j := 0, 0
+	for i < len(text) && j < len(context) {
+		switch {
+		case text[i] == ' ':
+			i++
+		case context[j] == ' ':
+			j++
+		case text[i] == context[j]:
+			n++
+			i++
+			j++
+		default:
+			return n
+		}
+	}
+	return n
+}
+
+// context returns up to highlightContextLen characters of text on each side of a span.
+func (ix *textIndex) context(start, end int) (string, string) {
+	text := ix.runes()
+	return string(text[max(0, start-highlightContextLen):start]), string(text[end:min(len(text), end+highlightContextLen)])
+}
+
+// runes returns the document's text, built on first use.
+func (ix *textIndex) runes() []rune {
+	if ix.text == nil {
+		for _, block := range ix.blocks {
+			ix.text = append(ix.text, []rune(block.Content)...)
+		}
+	}
+	return ix.text
+}
+
+// expectedOffset is where a highlight was last known to be. Only knowing its page
+// puts it in the middle of the page.
+func expectedOffset(index *textIndex, highlight *domain.Highlight) int {
+	switch {
+	case highlight.Progress != nil:
+		return int(*highlight.Progress * float32(index.total))
+	case highlight.PageNumber != nil:
+		page := *highlight.PageNumber
+		return (index.pageStart(page) + index.pageStart(page+1)) / 2
+	}
+	return 0
+}
+
+// relocateHighlight finds a highlight's quote in a document's current text, using
+// its context to pick between occurrences, and moves its page, progress, locator and
+// context there. A quote that cannot be found leaves the highlight orphaned where it
+// was.
+func relocateHighlight(index *textIndex, highlight *domain.Highlight) bool {
+	start, end, ok := index.locateQuote(highlight.Quote, highlight.ContextBefore, highlight.ContextAfter, expectedOffset(index, highlight))
+	if !ok {
+		highlight.Orphaned = true
+		return false
+	}
+	anchor := index.fromOffset(start)
+	highlight.PageNumber = &anchor.PageNumber
+	highlight.Progress = &anchor.Progress
+	highlight.Locator = anchor.Locator
+	highlight.ContextBefore, highlight.ContextAfter = index.context(start, end)
+	highlight.Orphaned = false
+	return true
+}
@@ -0,0 +1,118 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func quoteIndex(t *testing.T, texts ...string) *textIndex {
+	t.Helper()
+	var blocks []TextBlock
+	for i, text := range texts {
+		blocks = append(blocks, TextBlock{Type: "paragraph", Content: text, PageNumber: i + 1})
+	}
+	content, err := json.Marshal(blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	index, err := newTextIndex(&domain.Document{Content: content})
+	if err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+	return index
+}
+
+func TestTextIndex_LocateQuote(t *testing.T) {
+	index := quoteIndex(t,
+		"It was the best of times, it was the worst of times.",
+		"It was the age of wisdom,   it was the age of foolishness.",
+	)
+
+	tests := []struct {
+		name          string
+		quote         string
+		before, after string
+		expected      int
+		start, end    int
+		ok            bool
+	}{
+		{"nearest occurrence", "it was", "", "", 75, 80, 86, true},
+		{"context picks the occurrence", "it was", "best of times, ", "", 100, 26, 32, true},
+		{"context across blocks", "it was", "worst of times.", "the age", 0, 52, 58, true},
+		{"case and spacing", "WISDOM, IT  WAS", "", "", 0, 70, 86, true},
+		{"across blocks", "times. It was", "", "", 0, 46, 58, true},
+		{"typo", "the age of foolishnes", "", "", 0, 87, 108, true},
+		{"reworded", "the age of fooolishmess", "", "", 0, 87, 109, true},
+		{"short quotes must match exactly", "wisdim", "", "", 0, 0, 0, false},
+		{"missing", "a tale of two cities", "", "", 0, 0, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, ok := index.locateQuote(tt.quote, tt.before, tt.after, tt.expected)
+			if ok != tt.ok || ok && (start != tt.start || end != tt.end) {
+				t.Fatalf("expected %d-%d %v, got %d-%d %v", tt.start, tt.end, tt.ok, start, end, ok)
+			}
+		})
+	}
+}
+
+func TestHighlightService_ReanchorsAfterContentChange(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	highlights := &mockHighlightRepo{}
+	s := NewHighlightService(highlights, repo, nil, NewAuthorizationService(logger), logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	content := func(texts ...string) json.RawMessage {
+		var blocks []TextBlock
+		for i, text := range texts {
+			blocks = append(blocks, TextBlock{Type: "paragraph", Content: text, PageNumber: i + 1})
+		}
+		data, err := json.Marshal(blocks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+	repo.documents["doc"] = &domain.Document{ID: "doc", UserID: "user1",
+		Content: content("Call me Ishmael. Some years ago, never mind how long.", "Call me Ishmael, he said again.")}
+
+	page := 2
+	created, err := s.CreateHighlight(ctx, principal, &domain.Highlight{ID: "h1", DocumentID: "doc", Quote: "Call me Ishmael", PageNumber: &page})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if created.ContextBefore != " years ago, never mind how long." || created.ContextAfter != ", he said again." {
+		t.Fatalf("expected the context of the second occurrence, got %q %q", created.ContextBefore, created.ContextAfter)
+	}
+	_, _ = s.CreateHighlight(ctx, principal, &domain.Highlight{ID: "h2", DocumentID: "doc", Quote: "never mind how long"})
+
+	// Unchanged content leaves the highlights alone.
+	listed, err := s.ListHighlights(ctx, principal, &created.DocumentID)
+	if err != nil || len(listed) != 2 || *listed[0].PageNumber != 2 {
+		t.Fatalf("unexpected highlights %+v (%v)", listed, err)
+	}
+
+	// Reprocessing adds a page in front and rewords the first one.
+	doc := repo.documents["doc"]
+	doc.Content = content("Foreword.", "Call me Ishmael. Some years ago, whatever.", "Call me Ishmael, he said again.")
+	doc.Metadata.ContentVersion = 1
+
+	listed, err = s.ListHighlights(ctx, principal, &created.DocumentID)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	h1, h2 := listed[0], listed[1]
+	if *h1.PageNumber != 3 || h1.Orphaned || h1.ContentVersion != 1 || h1.ContextBefore != "hmael. Some years ago, whatever." {
+		t.Fatalf("expected the highlight on page 3, got %+v", h1)
+	}
+	if !h2.Orphaned || h2.ContentVersion != 1 {
+		t.Fatalf("expected the reworded highlight to be orphaned, got %+v", h2)
+	}
+	if highlights.highlights[0].ContentVersion != 1 {
+		t.Fatal("expected the re-anchored highlight to be stored")
+	}
+}
@@ -0,0 +1,441 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// kindleLocationChars is roughly how much text one Kindle location spans; it turns a
+// location into an estimate of where a highlight is.
+const kindleLocationChars = 128
+
+// kindleClippingSeparator ends each entry of "My Clippings.txt".
+const kindleClippingSeparator = "=========="
+
+var (
+	kindlePage     = regexp.MustCompile(`(?i)\bpage\s+([0-9]+)`)
+	kindleLocation = regexp.MustCompile(`(?i)\b(?:location|loc\.)\s+([0-9]+)(?:-([0-9]+))?`)
+	// kindleAuthor is the author in parentheses at the end of an entry's title line.
+	kindleAuthor = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	// titleExtras are series and edition marks in brackets, which library titles
+	// usually lack.
+	titleExtras = regexp.MustCompile(`\s*[(\[][^()\[\]]*[)\]]`)
+)
+
+// HighlightImportService imports the highlights of other reading apps into the
+// documents of the library.
+type HighlightImportService struct {
+	repo      domain.HighlightRepository
+	docRepo   domain.DocumentRepository
+	prefsRepo domain.UserPreferencesRepository
+	authz     domain.AuthorizationService
+	logger    domain.Logger
+}
+
+func NewHighlightImportService(
+	repo domain.HighlightRepository,
+	docRepo domain.DocumentRepository,
+	prefsRepo domain.UserPreferencesRepository,
+	authz domain.AuthorizationService,
+	logger domain.Logger,
+) domain.HighlightImportService {
+	return &HighlightImportService{
+		repo:      repo,
+		docRepo:   docRepo,
+		prefsRepo: prefsRepo,
+		authz:     authz,
+		logger:    logger,
+	}
+}
+
+// importedBook is a book of a highlights export with its highlights in order.
+type importedBook struct {
+	title      string
+	author     string
+	highlights []*importedHighlight
+}
+
+type importedHighlight struct {
+	quote string
+	note  string
+	page  int // as printed in the reading app, 0 if unknown
+	// Kindle location range, 0 if unknown.
+	location, locationEnd int
+}
+
+func (s *HighlightImportService) ImportHighlights(ctx context.Context, principal domain.Principal, export io.Reader) (*domain.HighlightImportResult, error) {
+	if _, err := accountPreferences(ctx, s.prefsRepo, principal); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(io.LimitReader(export, domain.MaxHighlightExportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > domain.MaxHighlightExportSize {
+		return nil, domain.ErrFileTooLarge
+	}
+	format, books, err := parseHighlightExport(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(books) == 0 {
+		return nil, domain.ValidationErrors{{Field: "file", Message: "the export contains no highlights"}}
+	}
+
+	docs, err := s.docRepo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	library := make([]duplicateKeys, len(docs))
+	for i, doc := range docs {
+		library[i] = newDuplicateKeys(doc)
+	}
+
+	result := &domain.HighlightImportResult{Format: format, Books: make([]*domain.HighlightImportBook, 0, len(books))}
+	for _, book := range books {
+		summary := &domain.HighlightImportBook{Title: book.title, Author: book.author, Highlights: len(book.highlights)}
+		result.Books = append(result.Books, summary)
+		match := matchImportedBook(book, library)
+		if match == nil {
+			continue
+		}
+		summary.DocumentID, summary.DocumentTitle = match.ID, match.Title
+		duplicates, err := s.importBook(ctx, principal, match.ID, book, summary)
+		if err != nil {
+			return nil, err
+		}
+		result.Imported += summary.Imported
+		result.Duplicates += duplicates
+	}
+
+	s.logger.Info("Highlights imported", "user_id", principal.UserID, "format", format,
+		"books", len(books), "imported", result.Imported, "duplicates", result.Duplicates)
+	return result, nil
+}
+
+// matchImportedBook returns the library document that is the book, by the same
+// title and author rules as duplicate detection, or nil.
+func matchImportedBook(book *importedBook, library []duplicateKeys) *domain.DocumentData {
+	title := strings.TrimSpace(titleExtras.ReplaceAllString(book.title, ""))
+	if title == "" {
+		title = book.title
+	}
+	author := book.author
+	keys := newDuplicateKeys(&domain.DocumentData{Title: title, Author: &author})
+
+	var best *domain.DocumentData
+	bestConfidence := 0.0
+	for _, candidate := range library {
+		if match, ok := matchDocuments(keys, candidate); ok && match.confidence > bestConfidence {
+			best, bestConfidence = candidate.doc, match.confidence
+		}
+	}
+	return best
+}
+
+// importBook creates a book's highlights in its document, skipping those already
+// there, and returns how many were skipped.
+func (s *HighlightImportService) importBook(ctx context.Context, principal domain.Principal, documentID string, book *importedBook, summary *domain.HighlightImportBook) (int, error) {
+	doc, err := s.docRepo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		return 0, err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionAnnotate); err != nil {
+		return 0, err
+	}
+	existing, err := s.repo.ListByUser(ctx, principal, &documentID)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[string]bool, len(existing)+len(book.highlights))
+	for _, h := range existing {
+		seen[string(foldQuote(h.Quote))] = true
+	}
+	// Comics and documents without text keep the reading app's page only.
+	index, _ := newTextIndex(doc)
+
+	duplicates := 0
+	for _, imported := range book.highlights {
+		key := string(foldQuote(imported.quote))
+		if seen[key] {
+			duplicates++
+			continue
+		}
+		seen[key] = true
+
+		highlight := &domain.Highlight{
+			UserID:         principal.UserID,
+			DocumentID:     documentID,
+			Quote:          imported.quote,
+			Note:           imported.note,
+			ContentVersion: doc.Metadata.ContentVersion,
+		}
+		if imported.page > 0 {
+			page := imported.page
+			highlight.PageNumber = &page
+		}
+		if index != nil && imported.location > 0 {
+			progress := min(float32(imported.location*kindleLocationChars)/float32(index.total), 1)
+			highlight.Progress = &progress
+		}
+		if index == nil || !placeImportedHighlight(index, highlight) {
+			summary.Estimated++
+		}
+		if _, err := s.repo.Create(ctx, principal, highlight); err != nil {
+			return duplicates, err
+		}
+		summary.Imported++
+	}
+	return duplicates, nil
+}
+
+// placeImportedHighlight finds an imported highlight's quote in the document and
+// anchors it there. A quote that is not found, often because the editions differ, is
+// placed by its location, then its page. Reports whether the quote was found.
+func placeImportedHighlight(index *textIndex, highlight *domain.Highlight) bool {
+	var anchor *domain.TextAnchor
+	start, end, found := index.locateQuote(highlight.Quote, "", "", expectedOffset(index, highlight))
+	switch {
+	case found:
+		anchor = index.fromOffset(start)
+		highlight.ContextBefore, highlight.ContextAfter = index.context(start, end)
+	case highlight.Progress != nil:
+		anchor = index.fromProgress(*highlight.Progress)
+	case highlight.PageNumber != nil:
+		anchor = index.fromOffset(index.pageStart(*highlight.PageNumber))
+	default:
+		return false
+	}
+	highlight.PageNumber = &anchor.PageNumber
+	highlight.Progress = &anchor.Progress
+	highlight.Locator = anchor.Locator
+	return found
+}
+
+// parseHighlightExport reads a Kindle "My Clippings.txt" or a Kobo annotation CSV
+// and returns the export's format and its books.
+func parseHighlightExport(data []byte) (string, []*importedBook, error) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	if bytes.Contains(data, []byte(kindleClippingSeparator)) {
+		return domain.HighlightExportKindle, parseKindleClippings(data), nil
+	}
+	books, err := parseKoboAnnotations(data)
+	if err != nil {
+		return "", nil, err
+	}
+	return domain.HighlightExportKobo, books, nil
+}
+
+// parseKindleClippings reads "My Clippings.txt". Each entry is a title line with the
+// author in parentheses, a line such as "- Your Highlight on page 12 | Location
+// 170-172 | Added on ...", a blank line and the text. Kindle adds a new entry when a
+// highlight is extended, so a later highlight starting at the same location replaces
+// the earlier one. Notes are attached to the highlight whose location they are at;
+// bookmarks and notes without a highlight are left out.
+func parseKindleClippings(data []byte) []*importedBook {
+	var (
+		books  []*importedBook
+		byName = map[string]*importedBook{}
+		notes  = map[*importedBook][]*importedHighlight{}
+	)
+	for _, entry := range strings.Split(string(data), kindleClippingSeparator) {
+		lines := strings.Split(strings.TrimSpace(strings.ReplaceAll(entry, "\r", "")), "\n")
+		if len(lines) < 3 {
+			continue
+		}
+		text := strings.TrimSpace(strings.Join(lines[2:], "\n"))
+		meta := strings.ToLower(lines[1])
+		isNote := strings.Contains(meta, "note")
+		if text == "" || !isNote && !strings.Contains(meta, "highlight") {
+			continue
+		}
+
+		titleLine := strings.TrimSpace(strings.TrimPrefix(lines[0], "\uFEFF"))
+		book := byName[titleLine]
+		if book == nil {
+			book = &importedBook{title: titleLine}
+			if m := kindleAuthor.FindStringSubmatch(titleLine); m != nil && m[1] != "" {
+				book.title, book.author = m[1], kindleAuthorNames(m[2])
+			}
+			byName[titleLine] = book
+			books = append(books, book)
+		}
+
+		clip := &importedHighlight{}
+		if m := kindlePage.FindStringSubmatch(lines[1]); m != nil {
+			clip.page, _ = strconv.Atoi(m[1])
+		}
+		if m := kindleLocation.FindStringSubmatch(lines[1]); m != nil {
+			clip.location, _ = strconv.Atoi(m[1])
+			clip.locationEnd = kindleRangeEnd(m[1], m[2])
+		}
+		if isNote {
+			clip.note = text
+			notes[book] = append(notes[book], clip)
+			continue
+		}
+		clip.quote = text
+		if i := indexByLocation(book.highlights, clip.location); i >= 0 {
+			book.highlights[i] = clip
+		} else {
+			book.highlights = append(book.highlights, clip)
+		}
+	}
+
+	for book, bookNotes := range notes {
+		for _, note := range bookNotes {
+			var target *importedHighlight
+			for _, h := range book.highlights {
+				if h.location > 0 && h.location <= note.location && note.location <= h.locationEnd {
+					// A note is usually made at the end of its highlight.
+					if target == nil || h.locationEnd == note.location {
+						target = h
+					}
+				}
+			}
+			if target != nil && target.note == "" {
+				target.note = note.note
+			}
+		}
+	}
+	return withHighlights(books)
+}
+
+// indexByLocation returns the highlight starting at location, or -1. Unknown
+// locations never match.
+func indexByLocation(highlights []*importedHighlight, location int) int {
+	if location == 0 {
+		return -1
+	}
+	for i, h := range highlights {
+		if h.location == location {
+			return i
+		}
+	}
+	return -1
+}
+
+// withHighlights drops the books left with notes or bookmarks only.
+func withHighlights(books []*importedBook) []*importedBook {
+	kept := books[:0]
+	for _, book := range books {
+		if len(book.highlights) > 0 {
+			kept = append(kept, book)
+		}
+	}
+	return kept
+}
+
+// kindleRangeEnd reads the end of a location range. Older Kindles abbreviate it to
+// the digits that change, as in "1234-56".
+func kindleRangeEnd(start, end string) int {
+	if end == "" {
+		n, _ := strconv.Atoi(start)
+		return n
+	}
+	if len(end) < len(start) {
+		end = start[:len(start)-len(end)] + end
+	}
+	n, _ := strconv.Atoi(end)
+	return n
+}
+
+// kindleAuthorNames turns Kindle's "Le Guin, Ursula K.;Other, Name" into
+// "Ursula K. Le Guin & Name Other".
+func kindleAuthorNames(authors string) string {
+	names := domain.SplitAuthors(authors)
+	for i, name := range names {
+		if last, first, ok := strings.Cut(name, ", "); ok && !strings.Contains(first, ",") {
+			names[i] = strings.TrimSpace(first) + " " + strings.TrimSpace(last)
+		}
+	}
+	return strings.Join(names, domain.AuthorSeparator)
+}
+
+// parseKoboAnnotations reads Kobo annotations as CSV: the rows of the e-reader's
+// Bookmark table joined with its book titles, as annotation export tools write them.
+// Columns are found by name: the book's title (or its VolumeID file path), author,
+// the highlighted text, the annotation and, when known, a page.
+func parseKoboAnnotations(data []byte) ([]*importedBook, error) {
+	r := csv.NewReader(bytes.NewReader(data))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a Kindle or Kobo highlights export", domain.ErrInvalidFile)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.NewReplacer(" ", "", "_", "").Replace(strings.ToLower(strings.TrimSpace(name)))
+		if _, ok := columns[name]; !ok {
+			columns[name] = i
+		}
+	}
+	column := func(names ...string) int {
+		for _, name := range names {
+			if i, ok := columns[name]; ok {
+				return i
+			}
+		}
+		return -1
+	}
+	titleCol, volumeCol := column("booktitle", "title", "book"), column("volumeid")
+	authorCol := column("author", "attribution")
+	textCol, noteCol, pageCol := column("text", "highlight", "quote"), column("annotation", "note"), column("page")
+	if textCol < 0 || titleCol < 0 && volumeCol < 0 {
+		return nil, fmt.Errorf("%w: not a Kindle or Kobo highlights export", domain.ErrInvalidFile)
+	}
+	field := func(record []string, i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var (
+		books  []*importedBook
+		byName = map[string]*importedBook{}
+	)
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+		}
+		quote := field(record, textCol)
+		if quote == "" {
+			continue // bookmarks ("dog-ears") have no text
+		}
+		title := field(record, titleCol)
+		if title == "" {
+			volume := field(record, volumeCol)
+			title = strings.TrimSuffix(path.Base(volume), path.Ext(volume))
+		}
+		if title == "" || title == "." {
+			continue
+		}
+		author := field(record, authorCol)
+		book := byName[title+"\x00"+author]
+		if book == nil {
+			book = &importedBook{title: title, author: author}
+			byName[title+"\x00"+author] = book
+			books = append(books, book)
+		}
+		page, _ := strconv.Atoi(field(record, pageCol))
+		book.highlights = append(book.highlights, &importedHighlight{quote: quote, note: field(record, noteCol), page: max(page, 0)})
+	}
+	return books, nil
+}
@@ -0,0 +1,128 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const testKindleClippings = "\uFEFFMoby-Dick; or, The Whale (Melville, Herman)\r\n" +
+	"- Your Highlight on page 2 | Location 30-31 | Added on Monday, March 4, 2019 9:31:12 PM\r\n\r\n" +
+	"It is a way I have of driving off\r\n==========\r\n" +
+	"Moby-Dick; or, The Whale (Melville, Herman)\r\n" +
+	"- Your Highlight on page 2 | Location 30-32 | Added on Monday, March 4, 2019 9:31:40 PM\r\n\r\n" +
+	"It is a way I have of driving off the spleen\r\n==========\r\n" +
+	"Moby-Dick; or, The Whale (Melville, Herman)\r\n" +
+	"- Your Note on page 2 | Location 32 | Added on Monday, March 4, 2019 9:32:00 PM\r\n\r\n" +
+	"Sailing as therapy\r\n==========\r\n" +
+	"Moby-Dick; or, The Whale (Melville, Herman)\r\n" +
+	"- Your Highlight on page 9 | Location 1200-1201 | Added on Monday, March 4, 2019 9:40:00 PM\r\n\r\n" +
+	"A sentence from another edition\r\n==========\r\n" +
+	"Moby-Dick; or, The Whale (Melville, Herman)\r\n" +
+	"- Your Bookmark on page 3 | Location 40 | Added on Monday, March 4, 2019 9:41:00 PM\r\n\r\n\r\n==========\r\n" +
+	"The Left Hand of Darkness (Le Guin, Ursula K.)\r\n" +
+	"- Highlight Loc. 1234-56 | Added on Tuesday, March 5, 2019 8:00:00 AM\r\n\r\n" +
+	"Light is the left hand of darkness\r\n==========\r\n"
+
+func TestParseKindleClippings(t *testing.T) {
+	format, books, err := parseHighlightExport([]byte(testKindleClippings))
+	if err != nil || format != domain.HighlightExportKindle {
+		t.Fatalf("unexpected format %q (%v)", format, err)
+	}
+	if len(books) != 2 {
+		t.Fatalf("expected two books, got %d", len(books))
+	}
+	moby := books[0]
+	if moby.title != "Moby-Dick; or, The Whale" || moby.author != "Herman Melville" {
+		t.Fatalf("unexpected book %q by %q", moby.title, moby.author)
+	}
+	// The extended highlight replaces the first one and gets the note at its end.
+	if len(moby.highlights) != 2 {
+		t.Fatalf("expected two highlights, got %+v", moby.highlights)
+	}
+	first := moby.highlights[0]
+	if first.quote != "It is a way I have of driving off the spleen" || first.note != "Sailing as therapy" || first.page != 2 || first.location != 30 {
+		t.Fatalf("unexpected highlight %+v", first)
+	}
+	if h := books[1].highlights[0]; h.location != 1234 || h.locationEnd != 1256 || books[1].author != "Ursula K. Le Guin" {
+		t.Fatalf("unexpected abbreviated location %+v by %q", h, books[1].author)
+	}
+}
+
+func TestParseKoboAnnotations(t *testing.T) {
+	export := "VolumeID,Title,Author,Text,Annotation,Type\n" +
+		"file:///mnt/onboard/Melville/Moby Dick.epub,,Herman Melville,\"Call me Ishmael.\",,highlight\n" +
+		"file:///mnt/onboard/Melville/Moby Dick.epub,,Herman Melville,,,dogear\n" +
+		"abc-123,Persuasion,Jane Austen,\"You pierce my soul.\",Swoon,highlight\n"
+	format, books, err := parseHighlightExport([]byte(export))
+	if err != nil || format != domain.HighlightExportKobo {
+		t.Fatalf("unexpected format %q (%v)", format, err)
+	}
+	if len(books) != 2 || books[0].title != "Moby Dick" || len(books[0].highlights) != 1 {
+		t.Fatalf("unexpected books %+v", books)
+	}
+	if h := books[1].highlights[0]; books[1].title != "Persuasion" || h.quote != "You pierce my soul." || h.note != "Swoon" {
+		t.Fatalf("unexpected highlight %+v", h)
+	}
+
+	if _, _, err := parseHighlightExport([]byte("just some notes\n")); !errors.Is(err, domain.ErrInvalidFile) {
+		t.Fatalf("expected an invalid file error, got %v", err)
+	}
+}
+
+func TestHighlightImportService_ImportHighlights(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	highlights := &mockHighlightRepo{}
+	s := NewHighlightImportService(highlights, repo, nil, NewAuthorizationService(logger), logger)
+	content, err := json.Marshal([]TextBlock{
+		{Type: "paragraph", Content: "Call me Ishmael. Some years ago, never mind how long precisely, having little or no money in my purse.", PageNumber: 1},
+		{Type: "paragraph", Content: "It is a way I have of driving off the spleen, and regulating the circulation.", PageNumber: 5},
+		{Type: "paragraph", Content: strings.Repeat("More sea. ", 20), PageNumber: 6},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	author := "Herman Melville"
+	repo.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user1", Title: "Moby Dick, or The Whale", Author: &author, Content: content}
+	other := "Someone Else"
+	repo.documents["doc-2"] = &domain.Document{ID: "doc-2", UserID: "user1", Title: "The Left Hand of Darkness", Author: &other}
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	result, err := s.ImportHighlights(ctx, principal, strings.NewReader(testKindleClippings))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if result.Imported != 2 || len(result.Books) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	moby, darkness := result.Books[0], result.Books[1]
+	if moby.DocumentID != "doc-1" || moby.Imported != 2 || moby.Estimated != 1 {
+		t.Fatalf("unexpected book result %+v", moby)
+	}
+	// The authors disagree, so the same title is not a match.
+	if darkness.DocumentID != "" || darkness.Imported != 0 {
+		t.Fatalf("expected no match for a different author, got %+v", darkness)
+	}
+
+	found := highlights.highlights[0]
+	if found.PageNumber == nil || *found.PageNumber != 5 || found.Note != "Sailing as therapy" || found.ContextAfter == "" {
+		t.Fatalf("expected the quote to be found on the document's page 5, got %+v", found)
+	}
+	// Not in the text: placed by its location, which is past the end of this short text.
+	estimated := highlights.highlights[1]
+	if estimated.Progress == nil || *estimated.Progress != 1 || estimated.PageNumber == nil || *estimated.PageNumber != 6 {
+		t.Fatalf("expected an estimate from the location, got %+v", estimated)
+	}
+
+	// Importing the same clippings again creates nothing new.
+	result, err = s.ImportHighlights(ctx, principal, strings.NewReader(testKindleClippings))
+	if err != nil || result.Imported != 0 || result.Duplicates != 2 {
+		t.Fatalf("expected only duplicates, got %+v (%v)", result, err)
+	}
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"slices"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+const reviewDay = 24 * time.Hour
+
+// GetReview selects today's highlights to review from all of the user's highlights.
+func (s *HighlightService) GetReview(ctx context.Context, principal domain.Principal, size int) (*domain.HighlightReview, error) {
+	if size < 1 || size > domain.MaxHighlightReviewSize {
+		return nil, domain.ValidationErrors{{Field: "limit", Message: fmt.Sprintf("limit must be between 1 and %d", domain.MaxHighlightReviewSize)}}
+	}
+	highlights, err := s.repo.ListByUser(ctx, principal, nil)
+	if err != nil {
+		return nil, err
+	}
+	return selectReview(highlights, time.Now().UTC(), size), nil
+}
+
+// selectReview picks the day's highlights: overdue ones first, then those never
+// reviewed, shuffled by a hash of the date so a different set comes up each day while
+// the selection stays put within one.
+func selectReview(highlights []*domain.Highlight, now time.Time, size int) *domain.HighlightReview {
+	today := now.UTC().Truncate(reviewDay)
+	review := &domain.HighlightReview{Date: today.Format(time.DateOnly), Highlights: []*domain.Highlight{}}
+
+	var due, fresh []*domain.Highlight
+	for _, h := range highlights {
+		switch {
+		case h.ReviewedAt != nil && !h.ReviewedAt.Before(today):
+			review.Reviewed++
+		case h.ReviewDueAt == nil:
+			fresh = append(fresh, h)
+		case h.ReviewDueAt.Before(today.Add(reviewDay)):
+			due = append(due, h)
+		}
+	}
+	slices.SortFunc(due, func(a, b *domain.Highlight) int {
+		return cmp.Or(a.ReviewDueAt.Compare(*b.ReviewDueAt), strings.Compare(a.ID, b.ID))
+	})
+	slices.SortFunc(fresh, func(a, b *domain.Highlight) int {
+		return cmp.Or(cmp.Compare(dailyRank(review.Date, a.ID), dailyRank(review.Date, b.ID)), strings.Compare(a.ID, b.ID))
+	})
+
+	candidates := append(due, fresh...)
+	n := min(len(candidates), max(size-review.Reviewed, 0))
+	review.Highlights = append(review.Highlights, candidates[:n]...)
+	review.Remaining = len(candidates) - n
+	return review
+}
+
+func dailyRank(date, id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(date))
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+// scheduleReview records a review at now and sets when the highlight is next due.
+// Remembered highlights come back after two (good) or four (easy) times the last
+// interval; forgotten ones come back tomorrow.
+func scheduleReview(highlight *domain.Highlight, rating string, now time.Time) {
+	interval := time.Duration(highlight.ReviewInterval) * reviewDay
+	switch {
+	case rating == domain.ReviewAgain:
+		interval = reviewDay
+	case interval == 0 && rating == domain.ReviewEasy:
+		interval = domain.FirstEasyReviewInterval
+	case interval == 0:
+		interval = domain.FirstReviewInterval
+	case rating == domain.ReviewEasy:
+		interval *= 4
+	default:
+		interval *= 2
+	}
+	interval = min(interval, maxReviewInterval(highlight))
+
+	due := now.UTC().Truncate(reviewDay).Add(interval)
+	reviewed := now.UTC()
+	highlight.ReviewCount++
+	highlight.ReviewInterval = int(interval / reviewDay)
+	highlight.ReviewDueAt = &due
+	highlight.ReviewedAt = &reviewed
+}
+
+func maxReviewInterval(highlight *domain.Highlight) time.Duration {
+	if highlight.IsFavorite {
+		return domain.MaxFavoriteReviewInterval
+	}
+	return domain.MaxReviewInterval
+}
+
+func (s *HighlightService) ReviewHighlight(ctx context.Context, principal domain.Principal, highlightID, rating string) (*domain.Highlight, error) {
+	if err := domain.ValidateReviewRating(rating); err != nil {
+		return nil, err
+	}
+	highlight, err := s.repo.Get(ctx, principal, highlightID)
+	if err != nil {
+		return nil, err
+	}
+	scheduleReview(highlight, rating, time.Now())
+	if err := s.repo.UpdateReview(ctx, principal, highlight); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
+
+// SetFavorite marks or unmarks a highlight as a favorite. A new favorite scheduled
+// further out than favorites may be is brought forward.
+func (s *HighlightService) SetFavorite(ctx context.Context, principal domain.Principal, highlightID string, isFavorite bool) (*domain.Highlight, error) {
+	highlight, err := s.repo.Get(ctx, principal, highlightID)
+	if err != nil {
+		return nil, err
+	}
+	highlight.IsFavorite = isFavorite
+	if isFavorite && highlight.ReviewDueAt != nil && highlight.ReviewedAt != nil {
+		latest := highlight.ReviewedAt.UTC().Truncate(reviewDay).Add(domain.MaxFavoriteReviewInterval)
+		if highlight.ReviewDueAt.After(latest) {
+			highlight.ReviewDueAt = &latest
+			highlight.ReviewInterval = int(domain.MaxFavoriteReviewInterval / reviewDay)
+		}
+	}
+	if err := s.repo.UpdateReview(ctx, principal, highlight); err != nil {
+		return nil, err
+	}
+	return highlight, nil
+}
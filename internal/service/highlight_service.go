@@ -1,55 +1,194 @@
 package service
 
 import (
+	"context"
 	"fmt"
 	"pdf-text-reader/internal/domain"
+	"slices"
 	"time"
 )
 
 type HighlightService struct {
-	repo   domain.HighlightRepository
-	logger domain.Logger
+	repo      domain.HighlightRepository
+	docRepo   domain.DocumentRepository
+	prefsRepo domain.UserPreferencesRepository
+	authz     domain.AuthorizationService
+	logger    domain.Logger
 }
 
-func NewHighlightService(repo domain.HighlightRepository, logger domain.Logger) domain.HighlightService {
+func NewHighlightService(
+	repo domain.HighlightRepository,
+	docRepo domain.DocumentRepository,
+	prefsRepo domain.UserPreferencesRepository,
+	authz domain.AuthorizationService,
+	logger domain.Logger,
+) domain.HighlightService {
 	return &HighlightService{
-		repo:   repo,
-		logger: logger,
+		repo:      repo,
+		docRepo:   docRepo,
+		prefsRepo: prefsRepo,
+		authz:     authz,
+		logger:    logger,
 	}
 }
 
-func (s *HighlightService) CreateHighlight(userID string, highlight *domain.Highlight, token string) (*domain.Highlight, error) {
+func (s *HighlightService) CreateHighlight(ctx context.Context, principal domain.Principal, highlight *domain.Highlight) (*domain.Highlight, error) {
 	if highlight == nil {
 		return nil, fmt.Errorf("highlight is required")
 	}
-	highlight.UserID = userID
+	highlight.UserID = principal.UserID
 	if highlight.DocumentID == "" {
 		return nil, fmt.Errorf("document_id is required")
 	}
 	if highlight.Quote == "" {
 		return nil, fmt.Errorf("quote is required")
 	}
+	if _, err := accountPreferences(ctx, s.prefsRepo, principal); err != nil {
+		return nil, err
+	}
+
+	doc, err := s.docRepo.GetByID(ctx, principal, highlight.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionAnnotate); err != nil {
+		return nil, err
+	}
+	if highlight.Locator != nil {
+		if err := highlight.Locator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	if index, err := newTextIndex(doc); err == nil {
+		anchorHighlight(index, highlight)
+		if start, end, ok := index.locateQuote(highlight.Quote, highlight.ContextBefore, highlight.ContextAfter, expectedOffset(index, highlight)); ok {
+			highlight.ContextBefore, highlight.ContextAfter = index.context(start, end)
+		}
+	} else {
+		highlight.Locator = nil
+	}
+	highlight.ContentVersion = doc.Metadata.ContentVersion
 	// created_at is assigned by DB; keep a local value for logging if missing.
 	if highlight.CreatedAt.IsZero() {
 		highlight.CreatedAt = time.Now()
 	}
 
-	created, err := s.repo.Create(highlight, token)
+	created, err := s.repo.Create(ctx, principal, highlight)
 	if err != nil {
 		return nil, err
 	}
-	s.logger.Info("Highlight created", "user_id", userID, "document_id", highlight.DocumentID, "highlight_id", created.ID)
+	s.logger.Info("Highlight created", "user_id", principal.UserID, "document_id", highlight.DocumentID, "highlight_id", created.ID)
 	return created, nil
 }
 
-func (s *HighlightService) ListHighlights(userID string, documentID *string, token string) ([]*domain.Highlight, error) {
-	return s.repo.ListByUser(userID, documentID, token)
+func (s *HighlightService) ListHighlights(ctx context.Context, principal domain.Principal, documentID *string) ([]*domain.Highlight, error) {
+	highlights, err := s.repo.ListByUser(ctx, principal, documentID)
+	if err != nil || documentID == nil {
+		return highlights, err
+	}
+	s.reanchor(ctx, principal, *documentID, highlights)
+	return highlights, nil
 }
 
-func (s *HighlightService) DeleteHighlight(userID string, highlightID string, token string) error {
+// reanchor moves highlights made against an earlier version of a document's content
+// to where their quotes are now. Highlights whose quote is gone are marked orphaned.
+// Failures are logged; the highlights are still listed as stored.
+func (s *HighlightService) reanchor(ctx context.Context, principal domain.Principal, documentID string, highlights []*domain.Highlight) {
+	var stale []*domain.Highlight
+	for _, h := range highlights {
+		if h.DocumentID == documentID {
+			stale = append(stale, h)
+		}
+	}
+	if len(stale) == 0 {
+		return
+	}
+	doc, err := s.docRepo.GetByID(ctx, principal, documentID)
+	if err != nil {
+		s.logger.Warn("Failed to load document to re-anchor highlights", "document_id", documentID, "error", err)
+		return
+	}
+	version := doc.Metadata.ContentVersion
+	stale = slices.DeleteFunc(stale, func(h *domain.Highlight) bool { return h.ContentVersion == version })
+	if len(stale) == 0 {
+		return
+	}
+
+	index, err := newTextIndex(doc)
+	relocated, orphaned := 0, 0
+	for _, h := range stale {
+		moved := *h
+		moved.ContentVersion = version
+		switch {
+		case err != nil:
+			// Nothing to anchor to; keep the highlight where it was.
+		case relocateHighlight(index, &moved):
+			relocated++
+		default:
+			orphaned++
+		}
+		if err := s.repo.UpdateAnchor(ctx, principal, &moved); err != nil {
+			s.logger.Warn("Failed to re-anchor highlight", "highlight_id", h.ID, "error", err)
+			continue
+		}
+		*h = moved
+	}
+	s.logger.Info("Highlights re-anchored", "user_id", principal.UserID, "document_id", documentID,
+		"content_version", version, "relocated", relocated, "orphaned", orphaned)
+}
+
+// anchorHighlight gives an EPUB highlight a locator, resolving the one it has or
+// placing it by its quote, then by its progress or page, and derives its page and
+// progress from it. It reports whether the highlight was anchored; highlights of
+// documents extracted without structure lose any locator.
+func anchorHighlight(index *textIndex, highlight *domain.Highlight) bool {
+	if !index.hasLocators() {
+		highlight.Locator = nil
+		return false
+	}
+	var anchor *domain.TextAnchor
+	if highlight.Locator != nil {
+		anchor, _ = index.fromLocator(highlight.Locator)
+	}
+	if anchor == nil {
+		start, _, found := index.locateQuote(highlight.Quote, highlight.ContextBefore, highlight.ContextAfter, expectedOffset(index, highlight))
+		switch {
+		case found:
+			anchor = index.fromOffset(start)
+		case highlight.Progress != nil:
+			anchor = index.fromProgress(*highlight.Progress)
+		case highlight.PageNumber != nil:
+			anchor = index.fromOffset(index.pageStart(*highlight.PageNumber))
+		default:
+			highlight.Locator = nil
+			return false
+		}
+	}
+	highlight.Locator = anchor.Locator
+	highlight.PageNumber = &anchor.PageNumber
+	highlight.Progress = &anchor.Progress
+	return true
+}
+
+// CountHighlights returns the number of highlights of each given document.
+func (s *HighlightService) CountHighlights(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]int, error) {
+	if len(documentIDs) == 0 {
+		return map[string]int{}, nil
+	}
+	return s.repo.CountByDocuments(ctx, principal, documentIDs)
+}
+
+// SearchHighlights finds highlights by the words of their quote or note.
+func (s *HighlightService) SearchHighlights(ctx context.Context, principal domain.Principal, search domain.HighlightSearch) ([]*domain.Highlight, error) {
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+	return s.repo.Search(ctx, principal, search)
+}
+
+func (s *HighlightService) DeleteHighlight(ctx context.Context, principal domain.Principal, highlightID string) error {
 	if highlightID == "" {
 		return fmt.Errorf("highlight_id is required")
 	}
-	return s.repo.Delete(userID, highlightID, token)
+	return s.repo.Delete(ctx, principal, highlightID)
 }
-
@@ -0,0 +1,139 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestHighlightService_SearchHighlights(t *testing.T) {
+	logger := NewMockLogger()
+	highlights := &mockHighlightRepo{highlights: []*domain.Highlight{
+		{ID: "h1", DocumentID: "d1", Quote: "Entropy always increases."},
+		{ID: "h2", DocumentID: "d2", Quote: "A closed system.", Note: "entropy again"},
+		{ID: "h3", DocumentID: "d2", Quote: "Unrelated."},
+	}}
+	s := NewHighlightService(highlights, NewMockDocumentRepository(), nil, NewAuthorizationService(logger), logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	found, err := s.SearchHighlights(ctx, principal, domain.HighlightSearch{Query: "  entropy "})
+	if err != nil || len(found) != 2 {
+		t.Fatalf("expected the quote and the note to match, got %+v (%v)", found, err)
+	}
+	found, err = s.SearchHighlights(ctx, principal, domain.HighlightSearch{Query: "entropy", DocumentID: "d2"})
+	if err != nil || len(found) != 1 || found[0].ID != "h2" {
+		t.Fatalf("expected the document filter to apply, got %+v (%v)", found, err)
+	}
+
+	var validationErrs domain.ValidationErrors
+	_, err = s.SearchHighlights(ctx, principal, domain.HighlightSearch{Query: " ", Limit: domain.MaxHighlightSearchLimit + 1})
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Fatalf("expected errors for the query and the limit, got %v", err)
+	}
+}
+
+func TestHighlightService_CreateHighlight_AccountDisabled(t *testing.T) {
+	logger := NewMockLogger()
+	highlights := &mockHighlightRepo{}
+	docs := NewMockDocumentRepository()
+	docs.documents["d1"] = &domain.Document{ID: "d1", UserID: "user1", Title: "Notes"}
+	prefs := newMockUserPreferencesRepo()
+	ctx := context.Background()
+	_ = prefs.SetAccountDisabled(ctx, testPrincipal("user1"), true)
+	s := NewHighlightService(highlights, docs, prefs, NewAuthorizationService(logger), logger)
+
+	_, err := s.CreateHighlight(ctx, testPrincipal("user1"), &domain.Highlight{DocumentID: "d1", Quote: "A quote."})
+	if !errors.Is(err, domain.ErrAccountDisabled) || len(highlights.highlights) != 0 {
+		t.Fatalf("expected ErrAccountDisabled and nothing created, got %v", err)
+	}
+}
+
+func TestSelectReview(t *testing.T) {
+	now := time.Date(2026, 3, 10, 15, 0, 0, 0, time.UTC)
+	at := func(days int) *time.Time {
+		t := now.Truncate(24*time.Hour).AddDate(0, 0, days)
+		return &t
+	}
+	highlights := []*domain.Highlight{
+		{ID: "later", ReviewDueAt: at(2), ReviewedAt: at(-2)},
+		{ID: "due", ReviewDueAt: at(0), ReviewedAt: at(-3)},
+		{ID: "overdue", ReviewDueAt: at(-4), ReviewedAt: at(-10)},
+		{ID: "done", ReviewDueAt: at(1), ReviewedAt: &now},
+		{ID: "new1"}, {ID: "new2"}, {ID: "new3"},
+	}
+
+	review := selectReview(highlights, now, 3)
+	if review.Date != "2026-03-10" || review.Reviewed != 1 || review.Remaining != 3 {
+		t.Fatalf("unexpected review %+v", review)
+	}
+	// Overdue first; one review is already done, so two slots are left.
+	if len(review.Highlights) != 2 || review.Highlights[0].ID != "overdue" || review.Highlights[1].ID != "due" {
+		t.Fatalf("expected the due highlights, got %+v", review.Highlights)
+	}
+
+	// New highlights are shuffled differently from one day to the next, and the
+	// selection is the same all day.
+	orders := make(map[string]bool)
+	for day := range 10 {
+		day := now.AddDate(0, 0, day)
+		var ids []string
+		for _, h := range selectReview(highlights[4:], day, 3).Highlights {
+			ids = append(ids, h.ID)
+		}
+		if again := selectReview(highlights[4:], day.Add(time.Hour), 3).Highlights; again[0].ID != ids[0] {
+			t.Fatalf("expected a stable selection within %s", day.Format(time.DateOnly))
+		}
+		orders[strings.Join(ids, ",")] = true
+	}
+	if len(orders) < 2 {
+		t.Fatalf("expected new highlights to rotate, got %v", orders)
+	}
+}
+
+func TestHighlightService_ReviewHighlight(t *testing.T) {
+	logger := NewMockLogger()
+	highlights := &mockHighlightRepo{highlights: []*domain.Highlight{{ID: "h1", Quote: "Entropy always increases."}}}
+	s := NewHighlightService(highlights, NewMockDocumentRepository(), nil, NewAuthorizationService(logger), logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+
+	steps := []struct {
+		rating   string
+		interval int
+	}{
+		{"", 3}, {domain.ReviewGood, 6}, {domain.ReviewEasy, 24}, {domain.ReviewAgain, 1}, {domain.ReviewGood, 2},
+	}
+	for i, step := range steps {
+		h, err := s.ReviewHighlight(ctx, principal, "h1", step.rating)
+		if err != nil {
+			t.Fatalf("review failed: %v", err)
+		}
+		if h.ReviewCount != i+1 || h.ReviewInterval != step.interval || !h.ReviewDueAt.Equal(today.AddDate(0, 0, step.interval)) {
+			t.Fatalf("review %d (%q): unexpected schedule %d days, due %v", i+1, step.rating, h.ReviewInterval, h.ReviewDueAt)
+		}
+	}
+
+	// Favorites come back at least every 60 days.
+	highlights.highlights[0].ReviewInterval = 200
+	h, err := s.SetFavorite(ctx, principal, "h1", true)
+	if err != nil || !h.IsFavorite {
+		t.Fatalf("favorite failed: %+v (%v)", h, err)
+	}
+	if h, _ = s.ReviewHighlight(ctx, principal, "h1", domain.ReviewEasy); h.ReviewInterval != 60 {
+		t.Fatalf("expected a favorite's interval to be capped, got %d", h.ReviewInterval)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.ReviewHighlight(ctx, principal, "h1", "meh"); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	if _, err := s.ReviewHighlight(ctx, principal, "missing", ""); !errors.Is(err, domain.ErrHighlightNotFound) {
+		t.Fatalf("expected ErrHighlightNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/google/uuid"
+)
+
+// importJobRetention is how long finished import jobs can still be looked up.
+const importJobRetention = 24 * time.Hour
+
+// ImportJobTracker keeps the status of the import jobs running on this server, so
+// every importer's jobs are served by GET /import/jobs/{id}. Jobs live in memory:
+// their status is lost on restart, the imported documents are not.
+type ImportJobTracker struct {
+	mu   sync.Mutex
+	jobs map[string]*domain.ImportJob
+}
+
+func NewImportJobTracker() *ImportJobTracker {
+	return &ImportJobTracker{jobs: make(map[string]*domain.ImportJob)}
+}
+
+// start registers a pending job for the given files and returns it together with a
+// snapshot for the caller. Finished jobs past their retention are dropped.
+func (t *ImportJobTracker) start(principal domain.Principal, provider string, files []domain.ImportFile) (*domain.ImportJob, *domain.ImportJob) {
+	now := time.Now().UTC()
+	job := &domain.ImportJob{
+		ID:        uuid.New().String(),
+		UserID:    principal.UserID,
+		Provider:  provider,
+		Status:    domain.ImportStatusPending,
+		Files:     files,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for id, old := range t.jobs {
+		if old.Status == domain.ImportStatusDone && now.Sub(old.UpdatedAt) > importJobRetention {
+			delete(t.jobs, id)
+		}
+	}
+	t.jobs[job.ID] = job
+	return job, copyImportJob(job)
+}
+
+// update changes a job under the tracker's lock.
+func (t *ImportJobTracker) update(job *domain.ImportJob, update func()) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	update()
+	job.UpdatedAt = time.Now().UTC()
+}
+
+// finishFile records the outcome of one file of the job.
+func (t *ImportJobTracker) finishFile(job *domain.ImportJob, i int, name string, doc *domain.DocumentData, err error) {
+	t.update(job, func() {
+		file := &job.Files[i]
+		if name != "" {
+			file.Name = name
+		}
+		if err != nil {
+			file.Status = domain.ImportStatusFailed
+			file.Error = importErrorMessage(err)
+			job.Failed++
+			return
+		}
+		file.Status = domain.ImportStatusDone
+		file.DocumentID = doc.ID
+		job.Completed++
+	})
+}
+
+// importErrorMessage is the reason shown to the user for a file that failed.
+func importErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, domain.ErrUnsupportedFileType), errors.Is(err, domain.ErrInvalidFile):
+		return "Unsupported or unreadable file"
+	case errors.Is(err, domain.ErrFileTooLarge):
+		return "File is too large"
+	case errors.Is(err, domain.ErrStorageLimitExceeded):
+		return "Storage limit exceeded"
+	case errors.Is(err, domain.ErrCloudNotConnected):
+		return "Cloud drive was disconnected"
+	default:
+		return "Transfer failed"
+	}
+}
+
+// get returns a snapshot of the job, or ErrImportJobNotFound for jobs of other users.
+func (t *ImportJobTracker) get(principal domain.Principal, jobID string) (*domain.ImportJob, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	job, ok := t.jobs[jobID]
+	if !ok || job.UserID != principal.UserID {
+		return nil, domain.ErrImportJobNotFound
+	}
+	return copyImportJob(job), nil
+}
+
+// copyImportJob returns a snapshot of a job the background transfer keeps updating.
+func copyImportJob(job *domain.ImportJob) *domain.ImportJob {
+	snapshot := *job
+	snapshot.Files = slices.Clone(job.Files)
+	return &snapshot
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// localTokenTTL is how long access tokens of the local auth backend stay valid. There
+// are no refresh tokens, so readers sign in again once it expires.
+const localTokenTTL = 7 * 24 * time.Hour
+
+// localTokenAudience matches the audience of Supabase access tokens.
+const localTokenAudience = "authenticated"
+
+// dummyPasswordHash is compared against when a sign-in names no account, so that
+// costs the same bcrypt work as a wrong password and the timing does not tell
+// which emails have accounts. It is a DefaultCost hash of a password nobody uses.
+const dummyPasswordHash = "$2a$10$E0eam/m8nOoJcRr2Lylzb.ZJUFQavbg1kPbgdbArb1gNQvwcf6jti"
+
+var localTokenHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// localTokenClaims are the JWT claims issued by the local auth backend. sub and role
+// carry the same meaning as in Supabase tokens, which postgres.WithUserTx relies on.
+type localTokenClaims struct {
+	Subject   string `json:"sub"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Audience  string `json:"aud"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type localAuthService struct {
+	users       domain.UserRepository
+	preferences domain.UserPreferencesRepository
+	secret      []byte
+	logger      domain.Logger
+
+	now func() time.Time
+}
+
+// NewLocalAuthService creates the auth backend of self-hosted mode. Accounts live in
+// the users table and access tokens are HS256 JWTs signed with secret.
+func NewLocalAuthService(
+	users domain.UserRepository,
+	preferences domain.UserPreferencesRepository,
+	secret string,
+	logger domain.Logger,
+) domain.LocalAuthService {
+	return &localAuthService{
+		users:       users,
+		preferences: preferences,
+		secret:      []byte(secret),
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// Register creates an account and signs it in
+func (s *localAuthService) Register(ctx context.Context, email, password, name string) (*domain.AuthSession, error) {
+	email = strings.TrimSpace(email)
+	var errs domain.ValidationErrors
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		errs = append(errs, &domain.ValidationError{Field: "email", Message: "must be a valid email address"})
+	}
+	if len(password) < domain.MinPasswordLength {
+		errs = append(errs, &domain.ValidationError{Field: "password", Message: fmt.Sprintf("must be at least %d characters", domain.MinPasswordLength)})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &domain.User{
+		Email:    email,
+		Password: string(hash),
+		Name:     strings.TrimSpace(name),
+	}
+	if err := s.users.Create(ctx, user); err != nil {
+		return nil, err
+	}
+
+	return s.newSession(user)
+}
+
+// Login checks the password and issues a new access token
+func (s *localAuthService) Login(ctx context.Context, email, password string) (*domain.AuthSession, error) {
+	user, err := s.users.GetByEmail(ctx, strings.TrimSpace(email))
+	if errors.Is(err, domain.ErrUserNotFound) {
+		_ = bcrypt.CompareHashAndPassword([]byte(dummyPasswordHash), []byte(password))
+		return nil, domain.ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
+		return nil, domain.ErrInvalidCredentials
+	}
+
+	return s.newSession(user)
+}
+
+// ValidateToken verifies the token's signature and expiry and loads its user
+func (s *localAuthService) ValidateToken(ctx context.Context, token string) (*domain.SupabaseUser, error) {
+	claims, err := s.parseToken(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidToken, err)
+	}
+
+	user, err := s.users.GetByID(ctx, claims.Subject)
+	if errors.Is(err, domain.ErrUserNotFound) {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidToken, err)
+	}
+	if err != nil {
+		// The token is fine; signing the reader out because the database is down
+		// would be wrong, so this is answered with 503 and a Retry-After.
+		s.logger.Error("Failed to load user of local token", err, "user_id", claims.Subject)
+		return nil, &domain.UpstreamError{RetryAfter: domain.UpstreamRetryAfter, Err: err}
+	}
+
+	return toAuthUser(user), nil
+}
+
+// IsAccountDisabled checks the persisted flag in `user_preferences.account_disabled`.
+// If the user has no preferences row yet, it defaults to false.
+func (s *localAuthService) IsAccountDisabled(ctx context.Context, userID string, token string) (bool, error) {
+	prefs, err := s.preferences.GetPreferences(ctx, domain.Principal{UserID: userID, Token: token})
+	if err != nil {
+		return false, fmt.Errorf("failed to get account status: %w", err)
+	}
+	return prefs.AccountDisabled, nil
+}
+
+func (s *localAuthService) newSession(user *domain.User) (*domain.AuthSession, error) {
+	now := s.now()
+	expiresAt := now.Add(localTokenTTL)
+
+	token, err := s.signToken(localTokenClaims{
+		Subject:   user.ID,
+		Email:     user.Email,
+		Role:      "authenticated",
+		Audience:  localTokenAudience,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.AuthSession{
+		AccessToken: token,
+		TokenType:   "bearer",
+		ExpiresIn:   int64(localTokenTTL / time.Second),
+		ExpiresAt:   expiresAt.Unix(),
+		User:        toAuthUser(user),
+	}, nil
+}
+
+func (s *localAuthService) signToken(claims localTokenClaims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal token claims: %w", err)
+	}
+	signed := localTokenHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + s.sign(signed), nil
+}
+
+func (s *localAuthService) parseToken(token string) (*localTokenClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("malformed token")
+	}
+	// Only the header this service issues is accepted, which pins the algorithm.
+	if parts[0] != localTokenHeader {
+		return nil, fmt.Errorf("unsupported token header")
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(s.sign(parts[0]+"."+parts[1]))) {
+		return nil, fmt.Errorf("bad signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("malformed payload: %w", err)
+	}
+	var claims localTokenClaims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, fmt.Errorf("malformed claims: %w", err)
+	}
+	if claims.Subject == "" || claims.Audience != localTokenAudience {
+		return nil, fmt.Errorf("unexpected claims")
+	}
+	if s.now().Unix() >= claims.ExpiresAt {
+		return nil, fmt.Errorf("token expired")
+	}
+
+	return &claims, nil
+}
+
+func (s *localAuthService) sign(signed string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(signed))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// toAuthUser presents a local account the way Supabase users are returned.
+func toAuthUser(user *domain.User) *domain.SupabaseUser {
+	return &domain.SupabaseUser{
+		ID:           user.ID,
+		Email:        user.Email,
+		UserMetadata: map[string]interface{}{"name": user.Name},
+		AppMetadata:  map[string]interface{}{"provider": "email"},
+		CreatedAt:    user.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:    user.UpdatedAt.Format(time.RFC3339),
+	}
+}
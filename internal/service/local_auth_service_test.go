@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type mockUserRepo struct {
+	users map[string]*domain.User
+	err   error
+}
+
+func newMockUserRepo() *mockUserRepo {
+	return &mockUserRepo{users: make(map[string]*domain.User)}
+}
+
+func (m *mockUserRepo) Create(ctx context.Context, user *domain.User) error {
+	for _, existing := range m.users {
+		if existing.Email == user.Email {
+			return domain.ErrEmailTaken
+		}
+	}
+	user.ID = fmt.Sprintf("user-%d", len(m.users)+1)
+	user.CreatedAt = time.Now()
+	user.UpdatedAt = user.CreatedAt
+	m.users[user.ID] = user
+	return nil
+}
+
+func (m *mockUserRepo) GetByEmail(ctx context.Context, email string) (*domain.User, error) {
+	for _, user := range m.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (m *mockUserRepo) GetByID(ctx context.Context, id string) (*domain.User, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if user, ok := m.users[id]; ok {
+		return user, nil
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func newTestLocalAuthService() (*localAuthService, *mockUserPreferencesRepo) {
+	prefs := newMockUserPreferencesRepo()
+	svc := NewLocalAuthService(newMockUserRepo(), prefs, strings.Repeat("k", 32), NewMockLogger())
+	return svc.(*localAuthService), prefs
+}
+
+func TestLocalAuthService_RegisterValidation(t *testing.T) {
+	svc, _ := newTestLocalAuthService()
+
+	_, err := svc.Register(context.Background(), "not-an-email", "short", "")
+	var verrs domain.ValidationErrors
+	if !errors.As(err, &verrs) || len(verrs) != 2 {
+		t.Fatalf("expected email and password validation errors, got %v", err)
+	}
+}
+
+func TestLocalAuthService_RegisterAndLogin(t *testing.T) {
+	svc, _ := newTestLocalAuthService()
+	ctx := context.Background()
+
+	session, err := svc.Register(ctx, "reader@example.com", "correct horse", "Reader")
+	if err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+	if session.AccessToken == "" || session.User.Email != "reader@example.com" {
+		t.Fatalf("unexpected session: %+v", session)
+	}
+	if _, err := svc.Register(ctx, "reader@example.com", "another password", ""); !errors.Is(err, domain.ErrEmailTaken) {
+		t.Fatalf("expected ErrEmailTaken, got %v", err)
+	}
+
+	if _, err := svc.Login(ctx, "reader@example.com", "wrong password"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for a wrong password, got %v", err)
+	}
+	if _, err := svc.Login(ctx, "nobody@example.com", "correct horse"); !errors.Is(err, domain.ErrInvalidCredentials) {
+		t.Fatalf("expected ErrInvalidCredentials for an unknown email, got %v", err)
+	}
+	// An unknown email costs as much bcrypt work as a wrong password.
+	if cost, err := bcrypt.Cost([]byte(dummyPasswordHash)); err != nil || cost != bcrypt.DefaultCost {
+		t.Fatalf("expected the dummy hash to be a DefaultCost bcrypt hash, got cost %d (%v)", cost, err)
+	}
+
+	session, err = svc.Login(ctx, "reader@example.com", "correct horse")
+	if err != nil {
+		t.Fatalf("unexpected login error: %v", err)
+	}
+	user, err := svc.ValidateToken(context.Background(), session.AccessToken)
+	if err != nil {
+		t.Fatalf("expected issued token to validate, got %v", err)
+	}
+	if user.ID != session.User.ID || user.UserMetadata["name"] != "Reader" {
+		t.Fatalf("unexpected user: %+v", user)
+	}
+}
+
+func TestLocalAuthService_ValidateTokenRejects(t *testing.T) {
+	svc, _ := newTestLocalAuthService()
+	session, err := svc.Register(context.Background(), "reader@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	parts := strings.Split(session.AccessToken, ".")
+	tampered := parts[0] + "." + parts[1] + "x." + parts[2]
+	if _, err := svc.ValidateToken(context.Background(), tampered); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected tampered token to be rejected, got %v", err)
+	}
+
+	other := NewLocalAuthService(svc.users, svc.preferences, strings.Repeat("o", 32), NewMockLogger())
+	if _, err := other.ValidateToken(context.Background(), session.AccessToken); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected token signed with another secret to be rejected, got %v", err)
+	}
+
+	svc.now = func() time.Time { return time.Now().Add(localTokenTTL + time.Minute) }
+	if _, err := svc.ValidateToken(context.Background(), session.AccessToken); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected expired token to be rejected, got %v", err)
+	}
+}
+
+func TestLocalAuthService_ValidateTokenDatabaseDown(t *testing.T) {
+	svc, _ := newTestLocalAuthService()
+	session, err := svc.Register(context.Background(), "reader@example.com", "correct horse", "")
+	if err != nil {
+		t.Fatalf("unexpected register error: %v", err)
+	}
+
+	svc.users.(*mockUserRepo).err = errors.New("connection refused")
+	_, err = svc.ValidateToken(context.Background(), session.AccessToken)
+	if !errors.Is(err, domain.ErrUpstreamUnavailable) || errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected the outage to be reported as unavailable, got %v", err)
+	}
+
+	svc.users.(*mockUserRepo).err = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := svc.ValidateToken(ctx, session.AccessToken); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the request's context to reach the repository, got %v", err)
+	}
+
+	delete(svc.users.(*mockUserRepo).users, session.User.ID)
+	if _, err := svc.ValidateToken(context.Background(), session.AccessToken); !errors.Is(err, domain.ErrInvalidToken) {
+		t.Fatalf("expected a deleted account's token to be rejected, got %v", err)
+	}
+}
+
+func TestLocalAuthService_IsAccountDisabled(t *testing.T) {
+	svc, prefs := newTestLocalAuthService()
+	prefs.prefs["user-1"] = &domain.UserPreferences{UserID: "user-1", AccountDisabled: true}
+
+	disabled, err := svc.IsAccountDisabled(context.Background(), "user-1", "token")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !disabled {
+		t.Fatalf("expected account to be disabled")
+	}
+}
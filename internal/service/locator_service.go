@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// LocatorService backfills EPUB locators onto reading positions and highlights saved
+// before they existed.
+type LocatorService struct {
+	documents  *DocumentService
+	highlights domain.HighlightRepository
+	positions  domain.UserPreferencesRepository
+	logger     domain.Logger
+}
+
+func NewLocatorService(
+	documents *DocumentService,
+	highlights domain.HighlightRepository,
+	positions domain.UserPreferencesRepository,
+	logger domain.Logger,
+) domain.LocatorService {
+	return &LocatorService{
+		documents:  documents,
+		highlights: highlights,
+		positions:  positions,
+		logger:     logger,
+	}
+}
+
+func (s *LocatorService) MigrateLocators(ctx context.Context, principal domain.Principal) (*domain.LocatorMigration, error) {
+	docs, err := s.documents.repo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, doc := range docs {
+		if doc.Metadata.Format == fileTypeEPUB.Format {
+			ids = append(ids, doc.ID)
+		}
+	}
+	result := &domain.LocatorMigration{Documents: len(ids)}
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	positions, err := s.positions.GetReadingPositions(ctx, principal, ids)
+	if err != nil {
+		return nil, err
+	}
+	all, err := s.highlights.ListByUser(ctx, principal, nil)
+	if err != nil {
+		return nil, err
+	}
+	highlights := make(map[string][]*domain.Highlight)
+	for _, h := range all {
+		if h.Locator == nil {
+			highlights[h.DocumentID] = append(highlights[h.DocumentID], h)
+		}
+	}
+
+	for _, id := range ids {
+		position := positions[id]
+		if position != nil && position.Locator != nil {
+			position = nil
+		}
+		if position == nil && len(highlights[id]) == 0 {
+			continue
+		}
+
+		// Listings leave the content out, so the book is loaded only when needed.
+		doc, err := s.documents.repo.GetByID(ctx, principal, id)
+		if err != nil {
+			return nil, err
+		}
+		index, err := newTextIndex(doc)
+		if err != nil || !index.hasLocators() {
+			result.Skipped++
+			continue
+		}
+
+		if position != nil {
+			applyAnchor(position, positionStart(index, position))
+			if err := s.positions.UpdateReadingPosition(ctx, principal, position); err != nil {
+				return nil, err
+			}
+			result.Positions++
+		}
+		for _, h := range highlights[id] {
+			if !anchorHighlight(index, h) {
+				continue
+			}
+			if err := s.highlights.UpdateAnchor(ctx, principal, h); err != nil {
+				return nil, err
+			}
+			result.Highlights++
+		}
+	}
+
+	s.logger.Info("Locators migrated", "user_id", principal.UserID, "documents", result.Documents,
+		"positions", result.Positions, "highlights", result.Highlights, "skipped", result.Skipped)
+	return result, nil
+}
+
+// positionStart places a position saved without a locator: by its character offset,
+// then its progress, then the start of its page.
+func positionStart(index *textIndex, position *domain.ReadingPosition) *domain.TextAnchor {
+	switch {
+	case position.CharOffset != nil:
+		return index.fromOffset(*position.CharOffset)
+	case position.Progress > 0:
+		return index.fromProgress(position.Progress)
+	default:
+		return index.fromOffset(index.pageStart(position.PageNumber))
+	}
+}
@@ -0,0 +1,193 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// sampleEPUBDocument is the sample EPUB as stored after processing. Its text:
+//
+//	ch1: "Loomings" /4/2 at 0, "Call me Ishmael…" /4/4 at 8, "A whale" /4/6/2 at 50, "Fig 1" /4/6/4 at 57
+//	ch2: "The Carpet-Bag" /2/2 at 62, "Filler paragraph." /2/4 at 76, "Here is the whale…" /2/6 at 93, "Back to start." /2/8 at 124
+func sampleEPUBDocument(t *testing.T, id string) *domain.Document {
+	t.Helper()
+	book, err := NewEPUBProcessor(NewMockLogger()).ProcessEPUB(zipReader(sampleEPUB(t)))
+	if err != nil {
+		t.Fatalf("ProcessEPUB: %v", err)
+	}
+	content, err := json.Marshal(book.Blocks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &domain.Document{ID: id, UserID: "user1", Content: content, Metadata: domain.DocumentMetadata{Format: fileTypeEPUB.Format}}
+}
+
+const (
+	sampleChapter1 = "OEBPS/text/ch1.xhtml"
+	sampleChapter2 = "OEBPS/text/ch2.xhtml"
+)
+
+func TestTextIndex_Locators(t *testing.T) {
+	index, err := newTextIndex(sampleEPUBDocument(t, "book"))
+	if err != nil {
+		t.Fatalf("index failed: %v", err)
+	}
+	if index.total != 138 {
+		t.Fatalf("expected 138 characters, got %d", index.total)
+	}
+
+	anchor := index.fromOffset(10)
+	if anchor.Locator == nil || *anchor.Locator != (domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/4", Offset: 2}) {
+		t.Fatalf("unexpected locator %+v", anchor.Locator)
+	}
+
+	tests := []struct {
+		name    string
+		locator domain.EPUBLocator
+		offset  int
+		page    int
+	}{
+		{"element", domain.EPUBLocator{Spine: sampleChapter2, Path: "/2/6", Offset: 8}, 101, 2},
+		{"missing element", domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/5"}, 50, 1},
+		{"past the chapter", domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/8"}, 62, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anchor, err := index.fromLocator(&tt.locator)
+			if err != nil {
+				t.Fatalf("resolve failed: %v", err)
+			}
+			if anchor.CharOffset != tt.offset || anchor.PageNumber != tt.page {
+				t.Fatalf("expected offset %d on page %d, got %+v", tt.offset, tt.page, anchor)
+			}
+		})
+	}
+	if _, err := index.fromLocator(&domain.EPUBLocator{Spine: "OEBPS/text/gone.xhtml"}); !errors.Is(err, domain.ErrLocatorNotFound) {
+		t.Fatalf("expected an unknown spine document to fail, got %v", err)
+	}
+
+	for page, want := range map[int]int{1: 29, 2: 101} {
+		h := &domain.Highlight{Quote: "the whale", PageNumber: &page}
+		if offset, _, ok := index.locateQuote(h.Quote, "", "", expectedOffset(index, h)); !ok || offset != want {
+			t.Fatalf("expected the quote on page %d at %d, got %d %v", page, want, offset, ok)
+		}
+	}
+}
+
+func TestLocatorService_MigrateLocators(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	positions := newMockUserPreferencesRepo()
+	highlights := &mockHighlightRepo{}
+	s := NewLocatorService(documents, highlights, positions, logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	repo.documents["book"] = sampleEPUBDocument(t, "book")
+	// Extracted before locators: the blocks have no structure.
+	repo.documents["old"] = &domain.Document{ID: "old", UserID: "user1", Content: json.RawMessage(`[{"content":"Text","page_number":1}]`),
+		Metadata: domain.DocumentMetadata{Format: fileTypeEPUB.Format}}
+	repo.documents["pdf"] = &domain.Document{ID: "pdf", UserID: "user1", Content: json.RawMessage(`[{"content":"Text","page_number":1}]`)}
+
+	positions.positions["user1"] = map[string]*domain.ReadingPosition{
+		"book": {UserID: "user1", DocumentID: "book", PageNumber: 2},
+		"old":  {UserID: "user1", DocumentID: "old", PageNumber: 1},
+		"pdf":  {UserID: "user1", DocumentID: "pdf", PageNumber: 1},
+	}
+	page := 2
+	kept := &domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/2"}
+	highlights.highlights = []*domain.Highlight{
+		{ID: "h1", DocumentID: "book", Quote: "the whale", PageNumber: &page},
+		{ID: "h2", DocumentID: "book", Quote: "Loomings", Locator: kept},
+	}
+
+	migration, err := s.MigrateLocators(ctx, principal)
+	if err != nil {
+		t.Fatalf("migrate failed: %v", err)
+	}
+	if *migration != (domain.LocatorMigration{Documents: 2, Positions: 1, Highlights: 1, Skipped: 1}) {
+		t.Fatalf("unexpected migration %+v", migration)
+	}
+
+	// A page-only position starts at its page.
+	position := positions.positions["user1"]["book"]
+	if position.Locator == nil || *position.Locator != (domain.EPUBLocator{Spine: sampleChapter2, Path: "/2/2"}) || *position.CharOffset != 62 {
+		t.Fatalf("unexpected migrated position %+v", position)
+	}
+	// The highlight is placed by its quote on its page.
+	h1 := highlights.highlights[0]
+	if h1.Locator == nil || *h1.Locator != (domain.EPUBLocator{Spine: sampleChapter2, Path: "/2/6", Offset: 8}) || *h1.Progress != float32(101)/138 {
+		t.Fatalf("unexpected migrated highlight %+v", h1)
+	}
+	if highlights.highlights[1].Locator != kept {
+		t.Fatal("expected a highlight with a locator to be left alone")
+	}
+
+	// Nothing is left to migrate.
+	again, err := s.MigrateLocators(ctx, principal)
+	if err != nil || again.Positions != 0 || again.Highlights != 0 {
+		t.Fatalf("expected nothing left to migrate, got %+v (%v)", again, err)
+	}
+}
+
+func TestHighlightService_CreateHighlightLocator(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	highlights := &mockHighlightRepo{}
+	s := NewHighlightService(highlights, repo, nil, NewAuthorizationService(logger), logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	repo.documents["book"] = sampleEPUBDocument(t, "book")
+
+	created, err := s.CreateHighlight(ctx, principal, &domain.Highlight{
+		DocumentID: "book",
+		Quote:      "Ishmael",
+		Locator:    &domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/4", Offset: 8},
+	})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if *created.PageNumber != 1 || *created.Progress != float32(16)/138 {
+		t.Fatalf("expected page and progress from the locator, got %+v", created)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.CreateHighlight(ctx, principal, &domain.Highlight{DocumentID: "book", Quote: "x", Locator: &domain.EPUBLocator{Path: "4"}}); !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Fatalf("expected a validation error for the locator, got %v", err)
+	}
+}
+
+func TestUserPreferencesService_LocatorPosition(t *testing.T) {
+	repo := newMockUserPreferencesRepo()
+	documents := newTestAnchorService(t)
+	documents.repo.(*MockDocumentRepository).documents["book"] = sampleEPUBDocument(t, "book")
+	svc := NewUserPreferencesService(repo, documents, NewMockLogger())
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	// The locator places the position; the client's page and progress are replaced.
+	position := &domain.ReadingPosition{Progress: 0.1, PageNumber: 7, Locator: &domain.EPUBLocator{Spine: sampleChapter2, Path: "/2/6", Offset: 8}}
+	if err := svc.UpdateReadingPosition(ctx, principal, "book", position); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	stored := repo.positions["user1"]["book"]
+	if stored.PageNumber != 2 || *stored.CharOffset != 101 || stored.Progress != float32(101)/138 {
+		t.Fatalf("unexpected stored position %+v", stored)
+	}
+
+	// An unknown spine document falls back to the character offset.
+	offset := 10
+	position = &domain.ReadingPosition{Progress: 0.5, PageNumber: 1, CharOffset: &offset, Locator: &domain.EPUBLocator{Spine: "gone.xhtml"}}
+	if err := svc.UpdateReadingPosition(ctx, principal, "book", position); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	stored = repo.positions["user1"]["book"]
+	if stored.Locator == nil || *stored.Locator != (domain.EPUBLocator{Spine: sampleChapter1, Path: "/4/4", Offset: 2}) {
+		t.Fatalf("expected the locator rebuilt from the offset, got %+v", stored.Locator)
+	}
+}
@@ -0,0 +1,50 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// defaultMaintenanceMessage is shown to clients when maintenance is switched on
+// without a message of its own.
+const defaultMaintenanceMessage = "Lector is being upgraded. Your library is still readable, but changes are paused for a few minutes."
+
+// MaintenanceMode is the in-memory switch behind maintenance mode, shared by the
+// HTTP and gRPC APIs. The zero value is off.
+type MaintenanceMode struct {
+	mu     sync.RWMutex
+	status domain.MaintenanceStatus
+}
+
+// NewMaintenanceMode creates the switch, off.
+func NewMaintenanceMode() domain.MaintenanceMode {
+	return &MaintenanceMode{}
+}
+
+func (m *MaintenanceMode) Set(enabled bool, message string) domain.MaintenanceStatus {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !enabled {
+		m.status = domain.MaintenanceStatus{}
+		return m.status
+	}
+	if message == "" {
+		message = defaultMaintenanceMessage
+	}
+	if m.status.Since == nil {
+		since := time.Now().UTC()
+		m.status.Since = &since
+	}
+	m.status.Enabled = true
+	m.status.Message = message
+	return m.status
+}
+
+func (m *MaintenanceMode) Status() domain.MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
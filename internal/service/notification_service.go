@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type NotificationService struct {
+	devices domain.DeviceRepository
+	sender  domain.PushSender
+	logger  domain.Logger
+}
+
+// NewNotificationService creates the notification service. sender may be nil when no
+// push provider is configured; devices can still be registered but nothing is sent.
+func NewNotificationService(
+	devices domain.DeviceRepository,
+	sender domain.PushSender,
+	logger domain.Logger,
+) domain.NotificationService {
+	return &NotificationService{
+		devices: devices,
+		sender:  sender,
+		logger:  logger,
+	}
+}
+
+func (s *NotificationService) RegisterDevice(ctx context.Context, principal domain.Principal, device *domain.Device) (*domain.Device, error) {
+	device.Token = strings.TrimSpace(device.Token)
+	if err := device.Validate(); err != nil {
+		return nil, err
+	}
+	device.UserID = principal.UserID
+	if err := s.devices.Upsert(ctx, principal, device); err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (s *NotificationService) UnregisterDevice(ctx context.Context, principal domain.Principal, token string) error {
+	return s.devices.Delete(ctx, principal, token)
+}
+
+func (s *NotificationService) ListDevices(ctx context.Context, principal domain.Principal) ([]*domain.Device, error) {
+	return s.devices.ListByUser(ctx, principal)
+}
+
+// Notify pushes to every registered device. A device that fails is logged and
+// skipped; one whose token the provider no longer knows is removed.
+func (s *NotificationService) Notify(ctx context.Context, principal domain.Principal, notification *domain.PushNotification) (int, error) {
+	if s.sender == nil {
+		return 0, nil
+	}
+	devices, err := s.devices.ListByUser(ctx, principal)
+	if err != nil {
+		return 0, err
+	}
+
+	delivered := 0
+	for _, device := range devices {
+		err := s.sender.Send(ctx, device, notification)
+		switch {
+		case err == nil:
+			delivered++
+		case errors.Is(err, domain.ErrDeviceUnregistered):
+			if err := s.devices.Delete(ctx, principal, device.Token); err != nil {
+				s.logger.Warn("Failed to remove unregistered device", "user_id", principal.UserID, "error", err)
+			}
+		default:
+			s.logger.Error("Failed to send push notification", err, "user_id", principal.UserID, "platform", device.Platform)
+		}
+	}
+	return delivered, nil
+}
+
+// Publish implements domain.EventPublisher by pushing the events users want on their
+// devices; other events are ignored.
+func (s *NotificationService) Publish(ctx context.Context, principal domain.Principal, event domain.Event) {
+	var notification *domain.PushNotification
+	switch event.Type {
+	case domain.EventDocumentProcessed:
+		doc, ok := event.Data.(domain.DocumentEvent)
+		if !ok {
+			return
+		}
+		notification = &domain.PushNotification{
+			Title: "Your document is ready",
+			Body:  doc.Title + " has finished processing.",
+			Data:  map[string]string{"type": event.Type, "document_id": doc.DocumentID},
+		}
+	default:
+		return
+	}
+
+	if _, err := s.Notify(ctx, principal, notification); err != nil {
+		s.logger.Error("Failed to notify devices", err, "user_id", principal.UserID, "event", event.Type)
+	}
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockDeviceRepo struct {
+	devices []*domain.Device
+	deleted []string
+}
+
+func (m *mockDeviceRepo) Upsert(ctx context.Context, principal domain.Principal, device *domain.Device) error {
+	m.devices = append(m.devices, device)
+	return nil
+}
+
+func (m *mockDeviceRepo) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Device, error) {
+	return m.devices, nil
+}
+
+func (m *mockDeviceRepo) Delete(ctx context.Context, principal domain.Principal, token string) error {
+	m.deleted = append(m.deleted, token)
+	return nil
+}
+
+type mockPushSender struct {
+	sent []*domain.PushNotification
+	errs map[string]error
+}
+
+func (m *mockPushSender) Send(ctx context.Context, device *domain.Device, notification *domain.PushNotification) error {
+	if err := m.errs[device.Token]; err != nil {
+		return err
+	}
+	m.sent = append(m.sent, notification)
+	return nil
+}
+
+func TestNotificationService_RegisterDevice(t *testing.T) {
+	repo := &mockDeviceRepo{}
+	s := NewNotificationService(repo, nil, NewMockLogger())
+	principal := domain.Principal{UserID: "user-1"}
+
+	_, err := s.RegisterDevice(context.Background(), principal, &domain.Device{Token: " ", Platform: "symbian"})
+	var validationErrs domain.ValidationErrors
+	if !errors.As(err, &validationErrs) || len(validationErrs) != 2 {
+		t.Fatalf("expected token and platform errors, got %v", err)
+	}
+
+	device, err := s.RegisterDevice(context.Background(), principal, &domain.Device{Token: " abc ", Platform: domain.DevicePlatformIOS})
+	if err != nil {
+		t.Fatalf("RegisterDevice: %v", err)
+	}
+	if device.Token != "abc" || device.UserID != "user-1" || len(repo.devices) != 1 {
+		t.Fatalf("unexpected device %+v", device)
+	}
+}
+
+func TestNotificationService_PublishDocumentProcessed(t *testing.T) {
+	repo := &mockDeviceRepo{devices: []*domain.Device{
+		{Token: "phone", Platform: domain.DevicePlatformAndroid},
+		{Token: "stale", Platform: domain.DevicePlatformIOS},
+		{Token: "flaky", Platform: domain.DevicePlatformWeb},
+	}}
+	sender := &mockPushSender{errs: map[string]error{
+		"stale": domain.ErrDeviceUnregistered,
+		"flaky": errors.New("provider unavailable"),
+	}}
+	s := NewNotificationService(repo, sender, NewMockLogger())
+
+	s.Publish(context.Background(), domain.Principal{UserID: "user-1"}, domain.Event{
+		Type: domain.EventDocumentProcessed,
+		Data: domain.DocumentEvent{DocumentID: "doc-1", Title: "Middlemarch"},
+	})
+
+	if len(sender.sent) != 1 {
+		t.Fatalf("expected 1 delivered notification, got %d", len(sender.sent))
+	}
+	if got := sender.sent[0]; got.Data["document_id"] != "doc-1" || got.Data["type"] != domain.EventDocumentProcessed {
+		t.Errorf("unexpected notification data %v", got.Data)
+	}
+	if len(repo.deleted) != 1 || repo.deleted[0] != "stale" {
+		t.Errorf("expected only the unregistered device to be removed, got %v", repo.deleted)
+	}
+
+	s.Publish(context.Background(), domain.Principal{UserID: "user-1"}, domain.Event{Type: "document.deleted"})
+	if len(sender.sent) != 1 {
+		t.Errorf("unexpected notification for an ignored event")
+	}
+}
@@ -0,0 +1,138 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+//go:embed starter/*.md
+var starterFiles embed.FS
+
+// starterBook is a public-domain book added to new accounts, with highlights that
+// show what the reader can do with a passage. Its text is kept as paragraphs and
+// "## " headings and uploaded as an EPUB, so it reads and anchors like any book.
+type starterBook struct {
+	id         string // EPUB identifier
+	file       string
+	book       domain.ImportedBook
+	highlights []domain.Highlight
+}
+
+var starterLibrary = []starterBook{
+	{
+		id:   "0b6f7f52-3c1e-4d8a-9a57-5f2c1e8d4b10",
+		file: "aesops-fables.md",
+		book: domain.ImportedBook{
+			Title:       "Aesop's Fables",
+			Authors:     []string{"Aesop"},
+			Description: "Five fables in the 1867 translation by George Fyler Townsend.",
+		},
+		highlights: []domain.Highlight{
+			{Quote: "The Grapes are sour, and not ripe as I thought.", Note: "Highlights can carry a note of your own, like this one."},
+			{Quote: "Slow but steady wins the race."},
+			{Quote: "it is possible for even a Mouse to confer benefits on a Lion."},
+		},
+	},
+}
+
+// OnboardingService adds the starter library to new accounts after they sign up.
+// The queued onboarding is claimed before the content is added, so a failure part
+// way leaves an account with some of it rather than adding it twice.
+type OnboardingService struct {
+	repo       domain.OnboardingRepository
+	documents  *DocumentService
+	highlights domain.HighlightService
+	logger     domain.Logger
+}
+
+func NewOnboardingService(
+	repo domain.OnboardingRepository,
+	documents *DocumentService,
+	highlights domain.HighlightService,
+	logger domain.Logger,
+) domain.OnboardingService {
+	return &OnboardingService{
+		repo:       repo,
+		documents:  documents,
+		highlights: highlights,
+		logger:     logger,
+	}
+}
+
+// OnboardUser claims the account's queued onboarding and adds the starter library
+// to it unless it already has documents.
+func (s *OnboardingService) OnboardUser(ctx context.Context, principal domain.Principal) error {
+	claimed, err := s.repo.ClaimOnboarding(ctx, principal)
+	if err != nil || !claimed {
+		return err
+	}
+
+	existing, err := s.documents.GetDocumentsByUserID(ctx, principal)
+	if err != nil {
+		return err
+	}
+	if len(existing) > 0 {
+		return nil
+	}
+	for _, starter := range starterLibrary {
+		if err := s.addStarterBook(ctx, principal, starter); err != nil {
+			return fmt.Errorf("failed to add %s: %w", starter.file, err)
+		}
+	}
+
+	s.logger.Info("Starter content added", "user_id", principal.UserID, "documents", len(starterLibrary))
+	return nil
+}
+
+func (s *OnboardingService) addStarterBook(ctx context.Context, principal domain.Principal, starter starterBook) error {
+	content, err := starter.epub()
+	if err != nil {
+		return err
+	}
+
+	book := starter.book
+	book.Source = domain.StarterContentSource
+	name := strings.TrimSuffix(starter.file, ".md") + ".epub"
+	doc, err := s.documents.uploadImported(ctx, principal, bytes.NewReader(content), name, &book)
+	if err != nil {
+		return err
+	}
+
+	for _, preset := range starter.highlights {
+		highlight := preset
+		highlight.DocumentID = doc.ID
+		if _, err := s.highlights.CreateHighlight(ctx, principal, &highlight); err != nil {
+			return fmt.Errorf("failed to create highlight: %w", err)
+		}
+	}
+	return nil
+}
+
+// epub builds the book with the export writer, one chapter per heading.
+func (b starterBook) epub() ([]byte, error) {
+	text, err := starterFiles.ReadFile("starter/" + b.file)
+	if err != nil {
+		return nil, err
+	}
+
+	var blocks []TextBlock
+	for _, line := range strings.Split(string(text), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		block := TextBlock{Type: "paragraph", Content: line, PageNumber: 1, Position: len(blocks)}
+		if heading, ok := strings.CutPrefix(line, "## "); ok {
+			block.Type, block.Content, block.Level = "heading", heading, 2
+		}
+		blocks = append(blocks, block)
+	}
+
+	author := strings.Join(b.book.Authors, domain.AuthorSeparator)
+	return exportEPUB(&domain.DocumentData{ID: b.id, Title: b.book.Title, Author: &author}, blocks)
+}
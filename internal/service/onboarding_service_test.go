@@ -0,0 +1,89 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockOnboardingRepo struct {
+	queued map[string]bool
+}
+
+func (m *mockOnboardingRepo) ClaimOnboarding(ctx context.Context, principal domain.Principal) (bool, error) {
+	if !m.queued[principal.UserID] {
+		return false, nil
+	}
+	delete(m.queued, principal.UserID)
+	return true, nil
+}
+
+func newTestOnboardingService() (*OnboardingService, *mockOnboardingRepo, *MockDocumentRepository, *mockHighlightRepo) {
+	logger := NewMockLogger()
+	repo := &mockOnboardingRepo{queued: make(map[string]bool)}
+	docs := NewMockDocumentRepository()
+	highlights := &mockHighlightRepo{}
+	authz := NewAuthorizationService(logger)
+	documents := NewDocumentService(docs, nil, nil, NewMockStorageService(), authz, domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	svc := NewOnboardingService(repo, documents, NewHighlightService(highlights, docs, nil, authz, logger), logger)
+	return svc.(*OnboardingService), repo, docs, highlights
+}
+
+func TestOnboardingService_OnboardUser(t *testing.T) {
+	svc, repo, docs, highlights := newTestOnboardingService()
+	principal := testPrincipal("user-1")
+	repo.queued["user-1"] = true
+
+	if err := svc.OnboardUser(context.Background(), principal); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(docs.documents) != 1 {
+		t.Fatalf("expected the starter book, got %d documents", len(docs.documents))
+	}
+	var doc *domain.Document
+	for _, d := range docs.documents {
+		doc = d
+	}
+	if doc.UserID != "user-1" || doc.Title != "Aesop's Fables" || doc.Metadata.Format != "epub" || doc.Metadata.Source != domain.StarterContentSource {
+		t.Fatalf("unexpected starter document %+v", doc)
+	}
+
+	if len(highlights.highlights) != len(starterLibrary[0].highlights) {
+		t.Fatalf("expected the preset highlights, got %d", len(highlights.highlights))
+	}
+	for _, h := range highlights.highlights {
+		if h.DocumentID != doc.ID || h.ContextBefore == "" {
+			t.Fatalf("expected highlight %q anchored in the starter book, got %+v", h.Quote, h)
+		}
+	}
+
+	// Later calls do not add the book again.
+	if err := svc.OnboardUser(context.Background(), principal); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(docs.documents) != 1 {
+		t.Fatalf("expected a single onboarding, got %d documents", len(docs.documents))
+	}
+}
+
+func TestOnboardingService_OnboardUser_Skips(t *testing.T) {
+	svc, repo, docs, _ := newTestOnboardingService()
+
+	// An account that was not queued at sign-up, like one created before starter
+	// content, is not given the book even with an empty library.
+	if err := svc.OnboardUser(context.Background(), testPrincipal("user-1")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	// Nor is a queued account that already has a library.
+	repo.queued["user-2"] = true
+	docs.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user-2", Title: "Existing"}
+	if err := svc.OnboardUser(context.Background(), testPrincipal("user-2")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if len(docs.documents) != 1 || repo.queued["user-2"] {
+		t.Fatalf("expected no starter content, got %d documents", len(docs.documents))
+	}
+}
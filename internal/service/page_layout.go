@@ -0,0 +1,265 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"html"
+	"image"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// go-fitz exposes MuPDF's structured text only as HTML, with one positioned
+// paragraph per line and a span per font run. Lines are placed exactly; words
+// within a line are placed by font metrics, scaled to the ink of the line.
+var (
+	layoutLinePattern = regexp.MustCompile(`<p style="top:([\d.]+)pt;left:([\d.]+)pt;line-height:([\d.]+)pt">(.*?)</p>`)
+	layoutSpanPattern = regexp.MustCompile(`(<b>)?(?:<i>)?<span style="font-family:([^;"]*);font-size:([\d.]+)pt">(.*?)</span>`)
+	layoutTagPattern  = regexp.MustCompile(`<[^>]*>`)
+)
+
+// pdfCourier is the fixed advance of monospaced text, per 1000 units of font size.
+const pdfCourier = 600
+
+func renderLayoutPath(doc *domain.DocumentData, page int) string {
+	return fmt.Sprintf("%s/%d/layout.json", renderDir(doc), page)
+}
+
+// GetPageLayout returns the words of a page with their boxes. Layouts are stored
+// next to the tiles the first time a page is asked for.
+func (s *PageRenderService) GetPageLayout(ctx context.Context, principal domain.Principal, documentID string, page int) (*domain.PageLayout, error) {
+	if !s.config.Enabled {
+		return nil, domain.ErrPageRenderDisabled
+	}
+	doc, err := s.documents.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case doc.Metadata.Format != fileTypePDF.Format:
+		return nil, fmt.Errorf("%w: only PDFs have fixed pages", domain.ErrNotRenderable)
+	case doc.Metadata.HasPassword:
+		return nil, fmt.Errorf("%w: the PDF is password protected", domain.ErrNotRenderable)
+	case page < 1 || (doc.Metadata.PageCount > 0 && page > doc.Metadata.PageCount):
+		return nil, fmt.Errorf("%w: no page %d", domain.ErrPageNotFound, page)
+	}
+
+	if layout, err := s.loadLayout(ctx, principal, doc, page); err == nil && layout.SourceSHA256 == doc.Metadata.SHA256 {
+		return layout, nil
+	}
+
+	layout, err := s.readLayout(ctx, principal, doc, page)
+	if err != nil {
+		return nil, err
+	}
+	data, _ := json.Marshal(layout)
+	if err := s.documents.storage.Upload(ctx, renderLayoutPath(doc, page), bytes.NewReader(data), "application/json", principal.Token); err != nil {
+		s.logger.Error("Failed to store page layout", err, "doc_id", doc.ID, "page", page)
+	}
+	return layout, nil
+}
+
+func (s *PageRenderService) loadLayout(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, page int) (*domain.PageLayout, error) {
+	file, err := s.documents.storage.Download(ctx, renderLayoutPath(doc, page), principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var layout domain.PageLayout
+	if err := json.NewDecoder(file).Decode(&layout); err != nil {
+		return nil, fmt.Errorf("failed to decode page layout: %w", err)
+	}
+	return &layout, nil
+}
+
+// readLayout reads the layout of a page from the original.
+func (s *PageRenderService) readLayout(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, page int) (*domain.PageLayout, error) {
+	path, _ := originalPath(doc)
+	body, err := s.documents.storage.Download(ctx, path, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	upload, err := spoolUpload(body, math.MaxInt64-1)
+	body.Close()
+	if err != nil {
+		return nil, err
+	}
+	defer upload.Close()
+
+	pdf, err := upload.openPDF()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+	}
+	defer pdf.Close()
+	if page > pdf.NumPage() {
+		return nil, fmt.Errorf("%w: no page %d", domain.ErrPageNotFound, page)
+	}
+
+	layout, err := pageLayout(pdf, page-1)
+	if err != nil {
+		return nil, fmt.Errorf("page %d: %w", page, err)
+	}
+	layout.DocumentID = doc.ID
+	layout.SourceSHA256 = doc.Metadata.SHA256
+	return layout, nil
+}
+
+// layoutLine is a line of a page with the advance width of each of its characters.
+type layoutLine struct {
+	x, y, height float64
+	text         []rune
+	advances     []float64
+}
+
+// pageLayout lays out page i (0-indexed) of an open PDF.
+func pageLayout(pdf *fitz.Document, i int) (*domain.PageLayout, error) {
+	bounds, err := pdf.Bound(i)
+	if err != nil {
+		return nil, err
+	}
+	markup, err := pdf.HTML(i, false)
+	if err != nil {
+		return nil, err
+	}
+	lines := parseLayoutLines(markup)
+
+	// A render at 72 dpi has one pixel per point, which is enough to find where
+	// each line's ink ends.
+	var img *image.RGBA
+	if len(lines) > 0 {
+		if img, err = pdf.ImageDPI(i, 72); err != nil {
+			return nil, err
+		}
+	}
+
+	layout := &domain.PageLayout{Page: i + 1, Width: bounds.Dx(), Height: bounds.Dy(), Words: []domain.LayoutWord{}}
+	var text strings.Builder
+	offset := 0
+	for n, line := range lines {
+		if n > 0 {
+			text.WriteByte('\n')
+			offset++
+		}
+		scale := inkScale(img, line)
+		x := line.x
+		for start := 0; start < len(line.text); {
+			if unicode.IsSpace(line.text[start]) {
+				x += line.advances[start] * scale
+				start++
+				continue
+			}
+			end, width := start, 0.0
+			for end < len(line.text) && !unicode.IsSpace(line.text[end]) {
+				width += line.advances[end] * scale
+				end++
+			}
+			layout.Words = append(layout.Words, domain.LayoutWord{
+				Text:   string(line.text[start:end]),
+				Offset: offset + start,
+				Line:   n,
+				X:      round2(x),
+				Y:      round2(line.y),
+				Width:  round2(width),
+				Height: round2(line.height),
+			})
+			x += width
+			start = end
+		}
+		text.WriteString(string(line.text))
+		offset += len(line.text)
+	}
+	layout.Text = text.String()
+	return layout, nil
+}
+
+// parseLayoutLines reads the lines of MuPDF's HTML output, with each character's
+// advance taken from the standard font closest to the span's.
+func parseLayoutLines(markup string) []layoutLine {
+	var lines []layoutLine
+	for _, m := range layoutLinePattern.FindAllStringSubmatch(markup, -1) {
+		line := layoutLine{x: parseLayoutFloat(m[2]), y: parseLayoutFloat(m[1]), height: parseLayoutFloat(m[3])}
+		for _, span := range layoutSpanPattern.FindAllStringSubmatch(m[4], -1) {
+			size := parseLayoutFloat(span[3])
+			font := &pdfTimesRoman
+			if span[1] != "" {
+				font = &pdfTimesBold
+			}
+			monospace := strings.Contains(strings.ToLower(span[2]), "mono") || strings.Contains(strings.ToLower(span[2]), "courier")
+			for _, r := range html.UnescapeString(layoutTagPattern.ReplaceAllString(span[4], "")) {
+				advance := float64(pdfCourier) * size / 1000
+				if !monospace {
+					advance = font.width(winAnsi(string(r)), size)
+				}
+				line.text = append(line.text, r)
+				line.advances = append(line.advances, advance)
+			}
+		}
+		if strings.TrimSpace(string(line.text)) != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// inkScale compares the width of a line by font metrics with the ink drawn for
+// it, so words in fonts wider or narrower than Times are placed where they are
+// drawn. It returns 1 when the ink cannot be told apart from its surroundings.
+func inkScale(img *image.RGBA, line layoutLine) float64 {
+	estimated := 0.0
+	for i, r := range line.text {
+		if i < len(line.text)-1 || !unicode.IsSpace(r) {
+			estimated += line.advances[i]
+		}
+	}
+	if img == nil || estimated <= 0 {
+		return 1
+	}
+
+	b := img.Bounds()
+	top := max(int(line.y+line.height*0.2), b.Min.Y)
+	bottom := min(int(line.y+line.height*0.8), b.Max.Y)
+	left := max(int(line.x), b.Min.X)
+	right := min(int(line.x+estimated*1.5+line.height), b.Max.X)
+	gap := int(line.height * 1.5) // wider than a word space, so a next column ends the line
+
+	last := -1
+	for x := left; x < right; x++ {
+		if last >= 0 && x-last > gap {
+			break
+		}
+		for y := top; y < bottom; y++ {
+			c := img.RGBAAt(x, y)
+			if int(c.R)+int(c.G)+int(c.B) < 3*160 {
+				last = x
+				break
+			}
+		}
+	}
+	if last < 0 {
+		return 1
+	}
+	scale := float64(last-left+1) / estimated
+	if scale < 0.5 || scale > 1.5 {
+		return 1
+	}
+	return scale
+}
+
+func parseLayoutFloat(s string) float64 {
+	f, _ := strconv.ParseFloat(s, 64)
+	return f
+}
+
+func round2(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,59 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestPageRenderService_GetPageLayout(t *testing.T) {
+	s, _, storage := newTestPageRenderService(t, domain.PageRenderConfig{Enabled: true, Scales: []float64{1}, TileSize: 256})
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	// Page 1 is the title page; the paragraph starts on page 2.
+	layout, err := s.GetPageLayout(ctx, principal, "paper", 2)
+	if err != nil {
+		t.Fatalf("layout: %v", err)
+	}
+	if layout.Width != 595 || layout.Height != 842 || layout.SourceSHA256 != "abc" {
+		t.Errorf("unexpected page: %+v", layout)
+	}
+
+	var words []string
+	for _, w := range layout.Words {
+		words = append(words, w.Text)
+		if got := string([]rune(layout.Text)[w.Offset : w.Offset+len([]rune(w.Text))]); got != w.Text {
+			t.Errorf("offset %d of %q points at %q", w.Offset, w.Text, got)
+		}
+		if w.X < 0 || w.Y < 0 || w.X+w.Width > float64(layout.Width) || w.Y+w.Height > float64(layout.Height) || w.Width <= 0 {
+			t.Errorf("%q is off the page: %+v", w.Text, w)
+		}
+	}
+	if got := strings.Join(words, " "); !strings.HasPrefix(got, "The river was high that spring.") {
+		t.Fatalf("words = %q", got)
+	}
+	first, second := layout.Words[0], layout.Words[1]
+	if first.Y != second.Y || second.X <= first.X+first.Width {
+		t.Errorf("words on a line should follow each other: %+v, %+v", first, second)
+	}
+
+	// The layout is stored and served from storage until the file changes.
+	if _, ok := storage.files["user1/paper/render/2/layout.json"]; !ok {
+		t.Error("layout was not stored")
+	}
+	delete(storage.files, "user1/paper.pdf")
+	if _, err := s.GetPageLayout(ctx, principal, "paper", 2); err != nil {
+		t.Errorf("stored layout: %v", err)
+	}
+
+	if _, err := s.GetPageLayout(ctx, principal, "paper", 0); !errors.Is(err, domain.ErrPageNotFound) {
+		t.Errorf("page 0: got %v, want ErrPageNotFound", err)
+	}
+	if _, err := s.GetPageLayout(ctx, principal, "book", 1); !errors.Is(err, domain.ErrNotRenderable) {
+		t.Errorf("epub: got %v, want ErrNotRenderable", err)
+	}
+}
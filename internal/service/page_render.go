@@ -0,0 +1,296 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"slices"
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// PageRenderService rasterizes PDF pages into PNG tiles at several zoom levels for
+// the fixed-layout viewer. Tiles and a JSON manifest are stored under
+// "<user id>/<document id>/render/"; renders in progress are tracked in memory, so
+// one cut short by a restart is started again by the next request.
+type PageRenderService struct {
+	documents *DocumentService
+	config    domain.PageRenderConfig
+	logger    domain.Logger
+
+	// slots runs one render at a time, so renders do not take the CPU from uploads.
+	slots chan struct{}
+
+	mu      sync.Mutex
+	running map[string]*domain.PageRenderManifest // by document ID
+}
+
+func NewPageRenderService(documents *DocumentService, config domain.PageRenderConfig, logger domain.Logger) domain.PageRenderService {
+	return &PageRenderService{
+		documents: documents,
+		config:    config,
+		logger:    logger,
+		slots:     make(chan struct{}, 1),
+		running:   make(map[string]*domain.PageRenderManifest),
+	}
+}
+
+func renderDir(doc *domain.DocumentData) string {
+	return doc.UserID + "/" + doc.ID + "/render"
+}
+
+func renderManifestPath(doc *domain.DocumentData) string {
+	return renderDir(doc) + "/manifest.json"
+}
+
+func renderTilePath(doc *domain.DocumentData, page, level, column, row int) string {
+	return fmt.Sprintf("%s/%d/%d/%d-%d.png", renderDir(doc), page, level, column, row)
+}
+
+// RenderPages starts rendering the document unless it is being rendered, or was
+// rendered from the same file with the current scales and tile size.
+func (s *PageRenderService) RenderPages(ctx context.Context, principal domain.Principal, documentID string) (*domain.PageRenderManifest, error) {
+	if !s.config.Enabled {
+		return nil, domain.ErrPageRenderDisabled
+	}
+	doc, err := s.documents.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case doc.Metadata.Format != fileTypePDF.Format:
+		return nil, fmt.Errorf("%w: only PDFs have fixed pages", domain.ErrNotRenderable)
+	case doc.Metadata.HasPassword:
+		return nil, fmt.Errorf("%w: the PDF is password protected", domain.ErrNotRenderable)
+	case s.config.MaxPages > 0 && doc.Metadata.PageCount > s.config.MaxPages:
+		return nil, fmt.Errorf("%w: %d pages, the limit is %d", domain.ErrNotRenderable, doc.Metadata.PageCount, s.config.MaxPages)
+	}
+
+	stored, err := s.loadManifest(ctx, principal, doc)
+	if err != nil && !errors.Is(err, domain.ErrRenderNotFound) {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if manifest, ok := s.running[doc.ID]; ok {
+		return copyRenderManifest(manifest), nil
+	}
+	if stored != nil && stored.Status == domain.PageRenderReady && s.current(stored, doc) {
+		return stored, nil
+	}
+
+	manifest := &domain.PageRenderManifest{
+		DocumentID:   doc.ID,
+		Status:       domain.PageRenderRendering,
+		PageCount:    doc.Metadata.PageCount,
+		TileSize:     s.config.TileSize,
+		Scales:       s.config.Scales,
+		Pages:        []domain.RenderedPage{},
+		SourceSHA256: doc.Metadata.SHA256,
+		UpdatedAt:    time.Now().UTC(),
+	}
+	s.running[doc.ID] = manifest
+	go s.render(context.WithoutCancel(ctx), principal, doc, manifest)
+	return copyRenderManifest(manifest), nil
+}
+
+// GetRenderManifest returns the render in progress, or else the stored manifest.
+func (s *PageRenderService) GetRenderManifest(ctx context.Context, principal domain.Principal, documentID string) (*domain.PageRenderManifest, error) {
+	doc, err := s.documents.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	manifest, ok := s.running[doc.ID]
+	if ok {
+		manifest = copyRenderManifest(manifest)
+	}
+	s.mu.Unlock()
+	if ok {
+		return manifest, nil
+	}
+	return s.loadManifest(ctx, principal, doc)
+}
+
+// OpenTile opens a tile listed in the document's manifest.
+func (s *PageRenderService) OpenTile(ctx context.Context, principal domain.Principal, documentID string, page, level, column, row int) (io.ReadCloser, error) {
+	doc, err := s.documents.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if page < 1 || level < 0 || level >= len(s.config.Scales) || column < 0 || row < 0 {
+		return nil, fmt.Errorf("%w: no tile %d/%d/%d-%d", domain.ErrPageNotFound, page, level, column, row)
+	}
+
+	tile, err := s.documents.storage.Download(ctx, renderTilePath(doc, page, level, column, row), principal.Token)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return nil, fmt.Errorf("%w: no tile %d/%d/%d-%d", domain.ErrPageNotFound, page, level, column, row)
+	}
+	return tile, err
+}
+
+// current reports whether a stored manifest was rendered from the document's file
+// with the configured scales and tile size.
+func (s *PageRenderService) current(manifest *domain.PageRenderManifest, doc *domain.DocumentData) bool {
+	return manifest.SourceSHA256 == doc.Metadata.SHA256 &&
+		manifest.TileSize == s.config.TileSize &&
+		slices.Equal(manifest.Scales, s.config.Scales)
+}
+
+func (s *PageRenderService) loadManifest(ctx context.Context, principal domain.Principal, doc *domain.DocumentData) (*domain.PageRenderManifest, error) {
+	file, err := s.documents.storage.Download(ctx, renderManifestPath(doc), principal.Token)
+	if errors.Is(err, domain.ErrBlobNotFound) {
+		return nil, domain.ErrRenderNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var manifest domain.PageRenderManifest
+	if err := json.NewDecoder(file).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode render manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// render draws every page of the document and stores the manifest once it is done
+// or has failed.
+func (s *PageRenderService) render(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, manifest *domain.PageRenderManifest) {
+	s.slots <- struct{}{}
+	defer func() { <-s.slots }()
+
+	started := time.Now()
+	tiles, err := s.renderPages(ctx, principal, doc, manifest)
+
+	s.mu.Lock()
+	if err != nil {
+		manifest.Status = domain.PageRenderFailed
+		manifest.Error = err.Error()
+	} else {
+		manifest.Status = domain.PageRenderReady
+	}
+	manifest.UpdatedAt = time.Now().UTC()
+	data, _ := json.Marshal(manifest)
+	s.mu.Unlock()
+
+	if uploadErr := s.documents.storage.Upload(ctx, renderManifestPath(doc), bytes.NewReader(data), "application/json", principal.Token); uploadErr != nil {
+		s.logger.Error("Failed to store render manifest", uploadErr, "doc_id", doc.ID)
+	}
+
+	s.mu.Lock()
+	delete(s.running, doc.ID)
+	s.mu.Unlock()
+
+	if err != nil {
+		s.logger.Error("Page rendering failed", err, "doc_id", doc.ID, "user_id", doc.UserID)
+		return
+	}
+	s.logger.Info("Pages rendered", "doc_id", doc.ID, "pages", len(manifest.Pages), "tiles", tiles, "duration", time.Since(started).String())
+}
+
+// renderPages renders the original page by page, adding each page to the manifest
+// as it is stored. It returns the number of tiles stored.
+func (s *PageRenderService) renderPages(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, manifest *domain.PageRenderManifest) (int, error) {
+	path, _ := originalPath(doc)
+	body, err := s.documents.storage.Download(ctx, path, principal.Token)
+	if err != nil {
+		return 0, err
+	}
+	upload, err := spoolUpload(body, math.MaxInt64-1)
+	body.Close()
+	if err != nil {
+		return 0, err
+	}
+	defer upload.Close()
+
+	pdf, err := upload.openPDF()
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+	}
+	defer pdf.Close()
+
+	pageCount := pdf.NumPage()
+	if s.config.MaxPages > 0 && pageCount > s.config.MaxPages {
+		return 0, fmt.Errorf("%w: %d pages, the limit is %d", domain.ErrNotRenderable, pageCount, s.config.MaxPages)
+	}
+	s.mu.Lock()
+	manifest.PageCount = pageCount
+	s.mu.Unlock()
+
+	tiles := 0
+	for i := 0; i < pageCount; i++ {
+		page, n, err := s.renderPage(ctx, principal, doc, pdf, i)
+		if err != nil {
+			return tiles, fmt.Errorf("page %d: %w", i+1, err)
+		}
+		tiles += n
+
+		s.mu.Lock()
+		manifest.Pages = append(manifest.Pages, page)
+		manifest.UpdatedAt = time.Now().UTC()
+		s.mu.Unlock()
+	}
+	return tiles, nil
+}
+
+// renderPage rasterizes page i (0-indexed) at every scale and stores its tiles.
+func (s *PageRenderService) renderPage(ctx context.Context, principal domain.Principal, doc *domain.DocumentData, pdf *fitz.Document, i int) (domain.RenderedPage, int, error) {
+	bounds, err := pdf.Bound(i)
+	if err != nil {
+		return domain.RenderedPage{}, 0, err
+	}
+	page := domain.RenderedPage{Page: i + 1, Width: bounds.Dx(), Height: bounds.Dy()}
+
+	tiles := 0
+	size := s.config.TileSize
+	for level, scale := range s.config.Scales {
+		img, err := pdf.ImageDPI(i, 72*scale)
+		if err != nil {
+			return page, tiles, err
+		}
+		b := img.Bounds()
+		rendered := domain.PageRenderLevel{
+			Scale:   scale,
+			Width:   b.Dx(),
+			Height:  b.Dy(),
+			Columns: (b.Dx() + size - 1) / size,
+			Rows:    (b.Dy() + size - 1) / size,
+		}
+
+		for row := 0; row < rendered.Rows; row++ {
+			for column := 0; column < rendered.Columns; column++ {
+				rect := image.Rect(column*size, row*size, (column+1)*size, (row+1)*size).Add(b.Min).Intersect(b)
+				var buf bytes.Buffer
+				if err := png.Encode(&buf, img.SubImage(rect)); err != nil {
+					return page, tiles, err
+				}
+				path := renderTilePath(doc, page.Page, level, column, row)
+				if err := s.documents.storage.Upload(ctx, path, &buf, "image/png", principal.Token); err != nil {
+					return page, tiles, err
+				}
+				tiles++
+			}
+		}
+		page.Levels = append(page.Levels, rendered)
+	}
+	return page, tiles, nil
+}
+
+func copyRenderManifest(manifest *domain.PageRenderManifest) *domain.PageRenderManifest {
+	c := *manifest
+	c.Pages = slices.Clone(manifest.Pages)
+	return &c
+}
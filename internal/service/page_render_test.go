@@ -0,0 +1,134 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"image/png"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func newTestPageRenderService(t *testing.T, config domain.PageRenderConfig) (domain.PageRenderService, *MockDocumentRepository, *MockStorageService) {
+	t.Helper()
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	documents := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	original, err := exportPDF(&domain.DocumentData{Title: "Field Notes"}, []TextBlock{
+		{Type: "paragraph", Content: "The river was high that spring."},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	storage.files["user1/paper.pdf"] = original
+	repo.documents["paper"] = &domain.Document{ID: "paper", UserID: "user1", Title: "Field Notes",
+		Metadata: domain.DocumentMetadata{Format: "pdf", SHA256: "abc"}}
+	repo.documents["book"] = &domain.Document{ID: "book", UserID: "user1", Title: "Book",
+		Metadata: domain.DocumentMetadata{Format: "epub"}}
+
+	return NewPageRenderService(documents, config, logger), repo, storage
+}
+
+// waitForRender polls the manifest until rendering has finished.
+func waitForRender(t *testing.T, s domain.PageRenderService, principal domain.Principal, documentID string) *domain.PageRenderManifest {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for time.Now().Before(deadline) {
+		manifest, err := s.GetRenderManifest(context.Background(), principal, documentID)
+		if err != nil {
+			t.Fatalf("manifest: %v", err)
+		}
+		if manifest.Status != domain.PageRenderRendering {
+			return manifest
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("rendering did not finish")
+	return nil
+}
+
+func TestPageRenderService_RendersTiles(t *testing.T) {
+	s, _, storage := newTestPageRenderService(t, domain.PageRenderConfig{Enabled: true, Scales: []float64{1, 2}, TileSize: 256})
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	if _, err := s.GetRenderManifest(ctx, principal, "paper"); !errors.Is(err, domain.ErrRenderNotFound) {
+		t.Fatalf("before rendering: got %v, want ErrRenderNotFound", err)
+	}
+
+	started, err := s.RenderPages(ctx, principal, "paper")
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if started.Status != domain.PageRenderRendering {
+		t.Fatalf("status = %q, want rendering", started.Status)
+	}
+
+	manifest := waitForRender(t, s, principal, "paper")
+	if manifest.Status != domain.PageRenderReady || manifest.Error != "" {
+		t.Fatalf("status = %q (%s)", manifest.Status, manifest.Error)
+	}
+	if manifest.PageCount == 0 || len(manifest.Pages) != manifest.PageCount {
+		t.Fatalf("rendered %d of %d pages", len(manifest.Pages), manifest.PageCount)
+	}
+	page := manifest.Pages[0]
+	if len(page.Levels) != 2 || page.Levels[1].Width < 2*page.Width-1 {
+		t.Fatalf("unexpected levels for a %dx%d page: %+v", page.Width, page.Height, page.Levels)
+	}
+	level := page.Levels[1]
+	if level.Columns != (level.Width+255)/256 || level.Rows != (level.Height+255)/256 {
+		t.Fatalf("unexpected tiling: %+v", level)
+	}
+
+	// The last tile of a level holds what is left of the page.
+	tile, err := s.OpenTile(ctx, principal, "paper", 1, 1, level.Columns-1, level.Rows-1)
+	if err != nil {
+		t.Fatalf("open tile: %v", err)
+	}
+	img, err := png.Decode(tile)
+	tile.Close()
+	if err != nil {
+		t.Fatalf("tile is not a PNG: %v", err)
+	}
+	if got, want := img.Bounds().Dx(), level.Width-(level.Columns-1)*256; got != want {
+		t.Errorf("edge tile width = %d, want %d", got, want)
+	}
+	if _, err := s.OpenTile(ctx, principal, "paper", 1, 1, level.Columns, 0); !errors.Is(err, domain.ErrPageNotFound) {
+		t.Errorf("tile past the edge: got %v, want ErrPageNotFound", err)
+	}
+
+	// A document rendered from the same file is not rendered again.
+	uploads := len(storage.files)
+	again, err := s.RenderPages(ctx, principal, "paper")
+	if err != nil || again.Status != domain.PageRenderReady {
+		t.Fatalf("render again: %v, %+v", err, again)
+	}
+	if len(storage.files) != uploads {
+		t.Errorf("expected no new files, got %d more", len(storage.files)-uploads)
+	}
+}
+
+func TestPageRenderService_Refuses(t *testing.T) {
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	disabled, _, _ := newTestPageRenderService(t, domain.PageRenderConfig{})
+	if _, err := disabled.RenderPages(ctx, principal, "paper"); !errors.Is(err, domain.ErrPageRenderDisabled) {
+		t.Errorf("disabled: got %v, want ErrPageRenderDisabled", err)
+	}
+
+	s, repo, _ := newTestPageRenderService(t, domain.PageRenderConfig{Enabled: true, Scales: []float64{1}, TileSize: 256, MaxPages: 10})
+	if _, err := s.RenderPages(ctx, principal, "book"); !errors.Is(err, domain.ErrNotRenderable) {
+		t.Errorf("epub: got %v, want ErrNotRenderable", err)
+	}
+	repo.documents["paper"].Metadata.PageCount = 11
+	if _, err := s.RenderPages(ctx, principal, "paper"); !errors.Is(err, domain.ErrNotRenderable) {
+		t.Errorf("too many pages: got %v, want ErrNotRenderable", err)
+	}
+	if _, err := s.RenderPages(ctx, testPrincipal("user2"), "paper"); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Errorf("other user: got %v, want ErrAccessDenied", err)
+	}
+}
@@ -0,0 +1,112 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// errPDFTimeout is the cancellation cause set when an extraction exceeds
+// PDFLimits.Timeout, telling it apart from the caller giving up.
+var errPDFTimeout = errors.New("pdf extraction timed out")
+
+// errPDFTooMuchText is the cancellation cause set when extraction passes
+// PDFLimits.MaxChars, stopping the other page workers.
+var errPDFTooMuchText = errors.New("pdf text limit exceeded")
+
+// pdfStats counts extraction outcomes for PDFProcessingStats.
+type pdfStats struct {
+	processed     atomic.Int64
+	failed        atomic.Int64
+	rejectedLarge atomic.Int64
+	timedOut      atomic.Int64
+	inFlight      atomic.Int64
+	waiting       atomic.Int64
+}
+
+// Stats returns a snapshot of the processor's extraction metrics.
+func (p *PDFProcessor) Stats() domain.PDFProcessingStats {
+	return domain.PDFProcessingStats{
+		Processed:     p.stats.processed.Load(),
+		Failed:        p.stats.failed.Load(),
+		RejectedLarge: p.stats.rejectedLarge.Load(),
+		TimedOut:      p.stats.timedOut.Load(),
+		InFlight:      p.stats.inFlight.Load(),
+		Waiting:       p.stats.waiting.Load(),
+		MaxConcurrent: p.limits.MaxConcurrent,
+	}
+}
+
+// run applies the job limits around one extraction: it waits for a free slot,
+// bounds the job by the timeout and records the outcome.
+func (p *PDFProcessor) run(ctx context.Context, extract func(ctx context.Context) ([]TextBlock, PDFMetadata, error)) ([]TextBlock, PDFMetadata, error) {
+	if p.slots != nil {
+		p.stats.waiting.Add(1)
+		select {
+		case p.slots <- struct{}{}:
+			p.stats.waiting.Add(-1)
+			defer func() { <-p.slots }()
+		case <-ctx.Done():
+			p.stats.waiting.Add(-1)
+			return nil, PDFMetadata{}, fmt.Errorf("waiting for a PDF extraction slot: %w", ctx.Err())
+		}
+	}
+
+	if p.limits.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeoutCause(ctx, p.limits.Timeout, errPDFTimeout)
+		defer cancel()
+	}
+
+	p.stats.inFlight.Add(1)
+	blocks, metadata, err := extract(ctx)
+	p.stats.inFlight.Add(-1)
+
+	var pdfErr *domain.PDFValidationError
+	switch {
+	case err == nil:
+		p.stats.processed.Add(1)
+	case errors.As(err, &pdfErr) && pdfErr.Code == domain.PDFErrorTooLarge:
+		p.stats.rejectedLarge.Add(1)
+	case errors.As(err, &pdfErr) && pdfErr.Code == domain.PDFErrorTooComplex:
+		p.stats.timedOut.Add(1)
+	default:
+		p.stats.failed.Add(1)
+	}
+	return blocks, metadata, err
+}
+
+// checkPageLimit rejects documents with more pages than PDFLimits.MaxPages.
+func checkPageLimit(pages, maxPages int) error {
+	if maxPages > 0 && pages > maxPages {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorTooLarge,
+			Message: fmt.Sprintf("This PDF has %d pages; documents may have at most %d", pages, maxPages),
+		}
+	}
+	return nil
+}
+
+// errTooMuchText is returned once extraction passes PDFLimits.MaxChars.
+func errTooMuchText(maxChars int) error {
+	return &domain.PDFValidationError{
+		Code:    domain.PDFErrorTooLarge,
+		Message: fmt.Sprintf("This PDF contains more than %d characters of text, which is too large to process", maxChars),
+	}
+}
+
+// pdfContextError reports why an extraction was stopped: the job timeout becomes a
+// "too complex" validation error, anything else is the caller's cancellation.
+func pdfContextError(ctx context.Context) error {
+	if errors.Is(context.Cause(ctx), errPDFTimeout) {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorTooComplex,
+			Message: "This PDF is too complex to process in time",
+			Err:     errPDFTimeout,
+		}
+	}
+	return ctx.Err()
+}
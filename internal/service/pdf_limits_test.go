@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// testTextPDF builds a PDF with one page per entry, each showing that text.
+func testTextPDF(pages ...string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.7\n")
+	kids := ""
+	for i := range pages {
+		kids += fmt.Sprintf("%d 0 R ", 4+2*i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, len(pages)))
+	obj("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i, text := range pages {
+		stream := fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		obj(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+		obj(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(stream), stream))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+func TestPDFProcessor_Limits(t *testing.T) {
+	tests := []struct {
+		name   string
+		limits domain.PDFLimits
+		pdf    []byte
+		code   string
+		stats  func(domain.PDFProcessingStats) int64
+	}{
+		{
+			name:   "within limits",
+			limits: domain.PDFLimits{MaxPages: 2, MaxChars: 100, Timeout: time.Minute, MaxConcurrent: 1},
+			pdf:    testTextPDF("First page", "Second page"),
+			stats:  func(s domain.PDFProcessingStats) int64 { return s.Processed },
+		},
+		{
+			name:   "too many pages",
+			limits: domain.PDFLimits{MaxPages: 2},
+			pdf:    testPDF("1.7", 3, "", ""),
+			code:   domain.PDFErrorTooLarge,
+			stats:  func(s domain.PDFProcessingStats) int64 { return s.RejectedLarge },
+		},
+		{
+			name:   "too much text",
+			limits: domain.PDFLimits{MaxChars: 15},
+			pdf:    testTextPDF("First page", "Second page"),
+			code:   domain.PDFErrorTooLarge,
+			stats:  func(s domain.PDFProcessingStats) int64 { return s.RejectedLarge },
+		},
+		{
+			name:   "timeout",
+			limits: domain.PDFLimits{Timeout: time.Nanosecond},
+			pdf:    testTextPDF("First page"),
+			code:   domain.PDFErrorTooComplex,
+			stats:  func(s domain.PDFProcessingStats) int64 { return s.TimedOut },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewLimitedPDFProcessor(NewMockLogger(), tt.limits)
+			blocks, _, err := p.ProcessPDF(context.Background(), tt.pdf)
+
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("ProcessPDF: %v", err)
+				}
+				if len(blocks) != 2 || !strings.Contains(blocks[1].Content, "Second page") {
+					t.Fatalf("unexpected blocks: %+v", blocks)
+				}
+			} else {
+				var pdfErr *domain.PDFValidationError
+				if !errors.As(err, &pdfErr) || pdfErr.Code != tt.code {
+					t.Fatalf("expected %s, got %v", tt.code, err)
+				}
+			}
+			if got := tt.stats(p.Stats()); got != 1 {
+				t.Fatalf("expected the outcome to be counted once, stats %+v", p.Stats())
+			}
+		})
+	}
+}
+
+func TestPDFProcessor_ConcurrencyLimit(t *testing.T) {
+	p := NewLimitedPDFProcessor(NewMockLogger(), domain.PDFLimits{MaxConcurrent: 1})
+	p.slots <- struct{}{} // Another job holds the only slot
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, _, err := p.ProcessPDF(ctx, testTextPDF("Waiting")); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the job to give up waiting for a slot, got %v", err)
+	}
+	if stats := p.Stats(); stats.Waiting != 0 || stats.InFlight != 0 || stats.MaxConcurrent != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	<-p.slots
+	if _, _, err := p.ProcessPDF(context.Background(), testTextPDF("Running")); err != nil {
+		t.Fatalf("expected the job to run once the slot is free, got %v", err)
+	}
+}
@@ -1,11 +1,16 @@
 package service
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"regexp"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode/utf8"
 
 	"pdf-text-reader/internal/domain"
 
@@ -15,22 +20,54 @@ import (
 // PDFProcessor handles PDF text extraction
 type PDFProcessor struct {
 	logger domain.Logger
+	limits domain.PDFLimits
+	slots  chan struct{} // Nil when extraction concurrency is unlimited
+	stats  pdfStats
 }
 
-// NewPDFProcessor creates a new PDF processor
+// NewPDFProcessor creates a new PDF processor without job limits
 func NewPDFProcessor(logger domain.Logger) *PDFProcessor {
-	return &PDFProcessor{
+	return NewLimitedPDFProcessor(logger, domain.PDFLimits{})
+}
+
+// NewLimitedPDFProcessor creates a PDF processor that enforces limits on every job.
+func NewLimitedPDFProcessor(logger domain.Logger, limits domain.PDFLimits) *PDFProcessor {
+	p := &PDFProcessor{
 		logger: logger,
+		limits: limits,
 	}
+	if limits.MaxConcurrent > 0 {
+		p.slots = make(chan struct{}, limits.MaxConcurrent)
+	}
+	return p
 }
 
-// TextBlock represents a block of text from a PDF
+// TextBlock represents a block of text from a PDF or EPUB
 type TextBlock struct {
-	Type       string `json:"type"`        // "paragraph" or "heading"
+	Type       string `json:"type"`        // "paragraph", "heading" or "image" (EPUB)
 	Content    string `json:"content"`     // The text content
 	Level      int    `json:"level"`       // Heading level (0 for paragraphs)
 	PageNumber int    `json:"page_number"` // Page number (1-indexed)
 	Position   int    `json:"position"`    // Position within the page
+
+	// EPUB only
+	Anchor string `json:"anchor,omitempty"` // Element id at the start of the block
+	Src    string `json:"src,omitempty"`    // Storage path for "image" blocks
+	Spine  string `json:"spine,omitempty"`  // Archive path of the spine document
+	Path   string `json:"path,omitempty"`   // CFI-style steps to the enclosing element
+
+	Links []BlockLink `json:"links,omitempty"` // EPUB hyperlinks and PDF citation markers
+}
+
+// BlockLink is a hyperlink inside a text block. Internal links carry the target
+// page/position; external links carry Href. Citation markers also carry the number
+// of the reference they point at.
+type BlockLink struct {
+	Text      string `json:"text"`
+	Href      string `json:"href,omitempty"`
+	Page      int    `json:"page,omitempty"`
+	Position  int    `json:"position,omitempty"`
+	Reference int    `json:"reference,omitempty"`
 }
 
 // PDFMetadata contains extracted PDF metadata
@@ -39,17 +76,68 @@ type PDFMetadata struct {
 	PageCount   int    `json:"page_count"`
 	HasPassword bool   `json:"has_password"`
 	Title       string `json:"title"`
+
+	Outline    []domain.OutlineEntry `json:"outline,omitempty"`
+	References []domain.Reference    `json:"references,omitempty"` // Set in scientific paper mode
+}
+
+// ProcessPDF extracts text and metadata from a PDF file. The processor's limits
+// apply; exceeding them fails with a domain.PDFValidationError.
+func (p *PDFProcessor) ProcessPDF(ctx context.Context, pdfBytes []byte) ([]TextBlock, PDFMetadata, error) {
+	return p.run(ctx, func(ctx context.Context) ([]TextBlock, PDFMetadata, error) {
+		// Open PDF document from bytes
+		doc, err := fitz.NewFromMemory(pdfBytes)
+		if err != nil {
+			if doc != nil {
+				doc.Close()
+			}
+			return nil, PDFMetadata{}, fmt.Errorf("failed to open PDF: %w", classifyPDFOpenError(pdfBytes, err))
+		}
+		defer doc.Close()
+
+		return p.extract(ctx, doc, func() (*fitz.Document, error) {
+			return fitz.NewFromMemory(pdfBytes)
+		})
+	})
 }
 
-// ProcessPDF extracts text and metadata from a PDF file
-func (p *PDFProcessor) ProcessPDF(pdfBytes []byte) ([]TextBlock, PDFMetadata, error) {
-	// Open PDF document from bytes
-	doc, err := fitz.NewFromMemory(pdfBytes)
+// ProcessPDFFile is ProcessPDF for a file on disk; fitz reads it from there instead
+// of holding the whole file in memory.
+func (p *PDFProcessor) ProcessPDFFile(ctx context.Context, path string) ([]TextBlock, PDFMetadata, error) {
+	return p.run(ctx, func(ctx context.Context) ([]TextBlock, PDFMetadata, error) {
+		return p.processFile(ctx, path)
+	})
+}
+
+func (p *PDFProcessor) processFile(ctx context.Context, path string) ([]TextBlock, PDFMetadata, error) {
+	doc, err := fitz.New(path)
 	if err != nil {
-		return nil, PDFMetadata{}, fmt.Errorf("failed to open PDF: %w", err)
+		if doc != nil {
+			doc.Close()
+		}
+		var tail []byte
+		if f, openErr := os.Open(path); openErr == nil {
+			if info, statErr := f.Stat(); statErr == nil {
+				tail = readTail(f, info.Size(), pdfHeaderWindow)
+			}
+			f.Close()
+		}
+		return nil, PDFMetadata{}, fmt.Errorf("failed to open PDF: %w", classifyPDFOpenError(tail, err))
 	}
 	defer doc.Close()
 
+	return p.extract(ctx, doc, func() (*fitz.Document, error) {
+		return fitz.New(path)
+	})
+}
+
+// extract reads the metadata, outline and text blocks of an open document. open
+// reopens the same file for the parallel page workers.
+func (p *PDFProcessor) extract(ctx context.Context, doc *fitz.Document, open func() (*fitz.Document, error)) ([]TextBlock, PDFMetadata, error) {
+	if err := checkPageLimit(doc.NumPage(), p.limits.MaxPages); err != nil {
+		return nil, PDFMetadata{}, err
+	}
+
 	// Get metadata
 	docMetadata := doc.Metadata()
 	metadata := PDFMetadata{
@@ -57,20 +145,26 @@ func (p *PDFProcessor) ProcessPDF(pdfBytes []byte) ([]TextBlock, PDFMetadata, er
 		HasPassword: false, // go-fitz doesn't expose this directly
 	}
 
-	// Extract title and author from metadata
-	if title, ok := docMetadata["title"]; ok && title != "" {
-		metadata.Title = title
+	// Extract title and author from metadata. MuPDF pads the values with NULs,
+	// which PostgreSQL text columns reject.
+	metadata.Title = strings.TrimRight(docMetadata["title"], "\x00")
+	metadata.Author = strings.TrimRight(docMetadata["author"], "\x00")
+
+	// Documents without an outline make fitz return ErrLoadOutline; that is not an error.
+	if toc, err := doc.ToC(); err == nil {
+		metadata.Outline = pdfOutline(toc)
 	}
-	if author, ok := docMetadata["author"]; ok && author != "" {
-		metadata.Author = author
+
+	pages, err := p.pageTexts(ctx, doc, open)
+	if err != nil {
+		return nil, PDFMetadata{}, err
 	}
 
 	var blocks []TextBlock
 
 	// Process each page
-	for pageNum := 0; pageNum < doc.NumPage(); pageNum++ {
-		// Extract text from page
-		text, err := doc.Text(pageNum)
+	for pageNum, page := range pages {
+		text, err := page.text, page.err
 		if err != nil {
 			p.logger.Warn("Failed to extract text from page", "page_num", pageNum, "error", err)
 			continue
@@ -123,9 +217,92 @@ func (p *PDFProcessor) ProcessPDF(pdfBytes []byte) ([]TextBlock, PDFMetadata, er
 		}
 	}
 
+	metadata.References = extractCitations(blocks)
+
 	return blocks, metadata, nil
 }
 
+// pageText is the extracted text of one page, or why it could not be read.
+type pageText struct {
+	text string
+	err  error
+}
+
+// pageTexts extracts the text of every page in page order. fitz documents are not
+// safe for concurrent use, so up to PDFLimits.PageWorkers-1 extra workers each open
+// their own copy and take pages from a shared counter. Limits are checked between
+// pages; fitz cannot interrupt a page that is being read.
+func (p *PDFProcessor) pageTexts(ctx context.Context, doc *fitz.Document, open func() (*fitz.Document, error)) ([]pageText, error) {
+	n := doc.NumPage()
+	pages := make([]pageText, n)
+	workers := min(max(p.limits.PageWorkers, 1), n)
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
+	var next, chars atomic.Int64
+	work := func(d *fitz.Document) {
+		for ctx.Err() == nil {
+			i := int(next.Add(1) - 1)
+			if i >= n {
+				return
+			}
+			text, err := d.Text(i)
+			pages[i] = pageText{text: text, err: err}
+			if p.limits.MaxChars > 0 && chars.Add(int64(utf8.RuneCountInString(text))) > int64(p.limits.MaxChars) {
+				cancel(errPDFTooMuchText)
+			}
+		}
+	}
+
+	var wg sync.WaitGroup
+	for w := 1; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			d, err := open()
+			if err != nil {
+				// The remaining workers pick up this worker's share of the pages.
+				if d != nil {
+					d.Close()
+				}
+				p.logger.Warn("Failed to open PDF for a page worker", "error", err)
+				return
+			}
+			defer d.Close()
+			work(d)
+		}()
+	}
+	work(doc)
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		if errors.Is(context.Cause(ctx), errPDFTooMuchText) {
+			return nil, errTooMuchText(p.limits.MaxChars)
+		}
+		return nil, pdfContextError(ctx)
+	}
+	return pages, nil
+}
+
+// pdfOutline converts fitz outline entries (1-based levels, 0-based pages) into
+// outline entries, dropping entries that do not point at a page in the document.
+func pdfOutline(toc []fitz.Outline) []domain.OutlineEntry {
+	var outline []domain.OutlineEntry
+	for _, item := range toc {
+		title := strings.TrimSpace(item.Title)
+		if title == "" || item.Page < 0 {
+			continue
+		}
+		outline = append(outline, domain.OutlineEntry{
+			Title: title,
+			Depth: max(item.Level-1, 0),
+			Page:  item.Page + 1,
+		})
+	}
+	return outline
+}
+
 // splitIntoParagraphs splits text into paragraphs based on double newlines
 func (p *PDFProcessor) splitIntoParagraphs(text string) []string {
 	// Normalize line breaks
@@ -182,12 +359,12 @@ func (p *PDFProcessor) isHeading(text string) bool {
 }
 
 // ProcessPDFFromReader processes a PDF from an io.Reader
-func (p *PDFProcessor) ProcessPDFFromReader(reader io.Reader) ([]TextBlock, PDFMetadata, error) {
+func (p *PDFProcessor) ProcessPDFFromReader(ctx context.Context, reader io.Reader) ([]TextBlock, PDFMetadata, error) {
 	pdfBytes, err := io.ReadAll(reader)
 	if err != nil {
 		return nil, PDFMetadata{}, fmt.Errorf("failed to read PDF: %w", err)
 	}
-	return p.ProcessPDF(pdfBytes)
+	return p.ProcessPDF(ctx, pdfBytes)
 }
 
 // sanitizeText removes problematic Unicode characters and control sequences
@@ -231,17 +408,8 @@ func (p *PDFProcessor) sanitizeText(text string) string {
 		return ""
 	}
 
-	// Check if the JSON contains problematic Unicode escape sequences
-	jsonStr := string(testJSON)
-
-	// Remove all control character Unicode escapes (0000-001F) that PostgreSQL rejects
-	// Use regex to match \u followed by 0000-001F
-	re := regexp.MustCompile(`\\u00[0-1][0-9a-fA-F]`)
-	jsonStr = re.ReplaceAllString(jsonStr, "")
-
-	// Remove surrogate pairs (D800-DFFF) which are invalid in JSON
-	reSurrogates := regexp.MustCompile(`\\u[dD][89aAbBcCdDeEfF][0-9a-fA-F]{2}`)
-	jsonStr = reSurrogates.ReplaceAllString(jsonStr, "")
+	// Remove control character (0000-001F) and surrogate (D800-DFFF) escapes that PostgreSQL rejects
+	jsonStr := domain.StripUnsafeUnicodeEscapes(string(testJSON))
 
 	// Remove any literal NULL bytes
 	jsonStr = strings.ReplaceAll(jsonStr, "\x00", "")
@@ -249,21 +417,8 @@ func (p *PDFProcessor) sanitizeText(text string) string {
 	// Unmarshal to verify it's valid and get the cleaned string
 	var testStr string
 	if err := json.Unmarshal([]byte(jsonStr), &testStr); err != nil {
-		// If unmarshaling fails, try to clean more aggressively
-		// Remove any remaining problematic sequences
-		cleaned := strings.ReplaceAll(sanitized, "\x00", "")
-		cleaned = strings.ReplaceAll(cleaned, "\u0000", "")
-		// Try marshaling again
-		if testJSON2, err := json.Marshal(cleaned); err == nil {
-			testStr2 := string(testJSON2)
-			testStr2 = re.ReplaceAllString(testStr2, "")
-			testStr2 = reSurrogates.ReplaceAllString(testStr2, "")
-			if err := json.Unmarshal([]byte(testStr2), &testStr); err != nil {
-				return cleaned // Return the cleaned string even if JSON unmarshal fails
-			}
-		} else {
-			return cleaned
-		}
+		// The first pass already removed every problematic rune, so fall back to it
+		return sanitized
 	}
 
 	return testStr
@@ -278,46 +433,18 @@ func (p *PDFProcessor) ConvertToJSON(blocks []TextBlock) (json.RawMessage, error
 		return nil, fmt.Errorf("failed to marshal blocks: %w", err)
 	}
 
-	// Remove problematic Unicode escape sequences that PostgreSQL rejects
-	jsonStr := string(jsonBytes)
-
-	// Remove all control character Unicode escapes (0000-001F)
-	reControlChars := regexp.MustCompile(`\\u00[0-1][0-9a-fA-F]`)
-	jsonStr = reControlChars.ReplaceAllString(jsonStr, "")
-
-	// Remove surrogate pairs (D800-DFFF) which are invalid in JSON
-	reSurrogates := regexp.MustCompile(`\\u[dD][89aAbBcCdDeEfF][0-9a-fA-F]{2}`)
-	jsonStr = reSurrogates.ReplaceAllString(jsonStr, "")
+	// Remove control character (0000-001F) and surrogate (D800-DFFF) escapes that PostgreSQL rejects
+	jsonStr := domain.StripUnsafeUnicodeEscapes(string(jsonBytes))
 
 	// Remove any literal NULL bytes
 	jsonStr = strings.ReplaceAll(jsonStr, "\x00", "")
-	jsonStr = strings.ReplaceAll(jsonStr, "\\u0000", "")
-	jsonStr = strings.ReplaceAll(jsonStr, "\\u000", "")
 
 	// Verify the cleaned JSON is valid by unmarshaling and re-marshaling
 	var verify []TextBlock
 	if err := json.Unmarshal([]byte(jsonStr), &verify); err != nil {
-		// If verification fails, try to recover by cleaning more aggressively
-		// Remove ALL Unicode escapes and re-encode
-		reAllUnicode := regexp.MustCompile(`\\u[0-9a-fA-F]{4}`)
-		jsonStr = reAllUnicode.ReplaceAllStringFunc(jsonStr, func(match string) string {
-			// Check if it's a control character or surrogate
-			hexStr := match[2:]
-			if len(hexStr) == 4 {
-				if (hexStr[0] == '0' && hexStr[1] == '0' && hexStr[2] <= '1') ||
-					((hexStr[0] == 'd' || hexStr[0] == 'D') && (hexStr[1] >= '8' && hexStr[1] <= 'f' || hexStr[1] >= '8' && hexStr[1] <= 'F')) {
-					return "" // Remove problematic sequences
-				}
-			}
-			return match // Keep other Unicode escapes
-		})
-
-		// Try unmarshaling again
-		if err := json.Unmarshal([]byte(jsonStr), &verify); err != nil {
-			p.logger.Warn("Failed to verify cleaned JSON after aggressive cleaning", "error", err)
-			// Return empty array as fallback
-			return json.RawMessage("[]"), nil
-		}
+		p.logger.Warn("Failed to verify cleaned JSON", "error", err)
+		// Return empty array as fallback
+		return json.RawMessage("[]"), nil
 	}
 
 	// Re-marshal to ensure clean JSON
@@ -328,9 +455,7 @@ func (p *PDFProcessor) ConvertToJSON(blocks []TextBlock) (json.RawMessage, error
 	}
 
 	// Final pass: clean the re-marshaled JSON one more time
-	finalJSONStr := string(cleanedJSON)
-	finalJSONStr = reControlChars.ReplaceAllString(finalJSONStr, "")
-	finalJSONStr = reSurrogates.ReplaceAllString(finalJSONStr, "")
+	finalJSONStr := domain.StripUnsafeUnicodeEscapes(string(cleanedJSON))
 	finalJSONStr = strings.ReplaceAll(finalJSONStr, "\x00", "")
 
 	return json.RawMessage(finalJSONStr), nil
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/gen2brain/go-fitz"
+	"pdf-text-reader/internal/domain"
+)
+
+// sanitizeSeeds are inputs that have historically produced PostgreSQL 22P05 errors
+// (NUL bytes, control characters, lone surrogates) or broke the JSON cleaning passes.
+var sanitizeSeeds = []string{
+	"",
+	"plain text",
+	"null\x00byte",
+	"tab\tnewline\ncarriage\r",
+	"bell\x07escape\x1b",
+	"\xed\xa0\x80lone surrogate bytes",
+	"invalid \xff\xfe utf8",
+	`literal \u0000 escape`,
+	`\u0009u0001`,
+	`\\u0001`,
+	`"quoted" and \backslash`,
+	"emoji 📚 and accents áéíóú",
+}
+
+// assertCleanText fails the test if s is not valid UTF-8 or contains characters
+// that PostgreSQL rejects in JSONB (control characters other than \t, \n, \r, and surrogates).
+func assertCleanText(t *testing.T, s string) {
+	t.Helper()
+	if !utf8.ValidString(s) {
+		t.Fatalf("output is not valid UTF-8: %q", s)
+	}
+	for _, r := range s {
+		if r < 0x20 && r != '\t' && r != '\n' && r != '\r' {
+			t.Fatalf("output contains control character %U: %q", r, s)
+		}
+		if r >= 0xD800 && r <= 0xDFFF {
+			t.Fatalf("output contains surrogate %U: %q", r, s)
+		}
+	}
+}
+
+func FuzzPDFProcessor_SanitizeText(f *testing.F) {
+	for _, seed := range sanitizeSeeds {
+		f.Add(seed)
+	}
+	p := NewPDFProcessor(NewMockLogger())
+
+	f.Fuzz(func(t *testing.T, input string) {
+		out := p.sanitizeText(input)
+		assertCleanText(t, out)
+
+		encoded, err := json.Marshal(out)
+		if err != nil {
+			t.Fatalf("sanitized text cannot be JSON-encoded: %v", err)
+		}
+		if !json.Valid(encoded) {
+			t.Fatalf("sanitized text produced invalid JSON: %s", encoded)
+		}
+	})
+}
+
+func FuzzPDFProcessor_ConvertToJSON(f *testing.F) {
+	for _, seed := range sanitizeSeeds {
+		f.Add(seed, 1)
+	}
+	p := NewPDFProcessor(NewMockLogger())
+
+	f.Fuzz(func(t *testing.T, content string, pageNumber int) {
+		blocks := []TextBlock{
+			{Type: "heading", Content: content, Level: 1, PageNumber: pageNumber},
+			{Type: "paragraph", Content: content + content, PageNumber: pageNumber, Position: 1},
+		}
+
+		out, err := p.ConvertToJSON(blocks)
+		if err != nil {
+			t.Fatalf("ConvertToJSON returned error: %v", err)
+		}
+		if !utf8.Valid(out) {
+			t.Fatalf("output is not valid UTF-8: %q", out)
+		}
+		if !json.Valid(out) {
+			t.Fatalf("output is not valid JSON: %s", out)
+		}
+
+		var decoded []TextBlock
+		if err := json.Unmarshal(out, &decoded); err != nil {
+			t.Fatalf("output does not decode into text blocks: %v", err)
+		}
+		for _, block := range decoded {
+			assertCleanText(t, block.Content)
+		}
+	})
+}
+
+func TestPDFOutline(t *testing.T) {
+	toc := []fitz.Outline{
+		{Level: 1, Title: " Chapter 1 ", Page: 0},
+		{Level: 2, Title: "Section 1.1", Page: 2},
+		{Level: 1, Title: "", Page: 3},
+		{Level: 1, Title: "External link", Page: -1},
+	}
+	want := []domain.OutlineEntry{
+		{Title: "Chapter 1", Depth: 0, Page: 1},
+		{Title: "Section 1.1", Depth: 1, Page: 3},
+	}
+	if got := pdfOutline(toc); !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %+v, got %+v", want, got)
+	}
+}
+
+// testBook builds a PDF of pages numbered pages, each with a line of body text.
+func testBook(pages int) []byte {
+	texts := make([]string, pages)
+	for i := range texts {
+		texts[i] = fmt.Sprintf("Page %d. %s", i+1, strings.Repeat("It was the best of times, it was the worst of times. ", 8))
+	}
+	return testTextPDF(texts...)
+}
+
+func TestPDFProcessor_ParallelPagesKeepOrder(t *testing.T) {
+	book := testBook(40)
+
+	sequential, _, err := NewLimitedPDFProcessor(NewMockLogger(), domain.PDFLimits{PageWorkers: 1}).ProcessPDF(context.Background(), book)
+	if err != nil {
+		t.Fatalf("sequential ProcessPDF: %v", err)
+	}
+	parallel, _, err := NewLimitedPDFProcessor(NewMockLogger(), domain.PDFLimits{PageWorkers: 4}).ProcessPDF(context.Background(), book)
+	if err != nil {
+		t.Fatalf("parallel ProcessPDF: %v", err)
+	}
+
+	if !reflect.DeepEqual(sequential, parallel) {
+		t.Fatalf("parallel extraction differs from sequential extraction")
+	}
+	for i, block := range parallel {
+		if block.PageNumber != i+1 || !strings.HasPrefix(block.Content, fmt.Sprintf("Page %d.", i+1)) {
+			t.Fatalf("block %d is out of order: page %d %q", i, block.PageNumber, block.Content)
+		}
+	}
+}
+
+// BenchmarkPDFProcessor_ProcessPDF compares sequential and parallel page extraction
+// on a generated book and on any PDFs dropped into testdata/.
+func BenchmarkPDFProcessor_ProcessPDF(b *testing.B) {
+	inputs := map[string][]byte{"generated-300-pages": testBook(300)}
+	paths, _ := filepath.Glob(filepath.Join("testdata", "*.pdf"))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			b.Fatalf("read %s: %v", path, err)
+		}
+		inputs[filepath.Base(path)] = data
+	}
+
+	for name, data := range inputs {
+		for _, workers := range []int{1, 2, 4, 8} {
+			b.Run(fmt.Sprintf("%s/workers=%d", name, workers), func(b *testing.B) {
+				p := NewLimitedPDFProcessor(NewMockLogger(), domain.PDFLimits{PageWorkers: workers})
+				b.SetBytes(int64(len(data)))
+				for b.Loop() {
+					if _, _, err := p.ProcessPDF(context.Background(), data); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
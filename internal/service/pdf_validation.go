@@ -0,0 +1,86 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// maxPDFVersion is the newest PDF specification we accept (PDF 2.0).
+const maxPDFVersion = 2.0
+
+var pdfVersionPattern = regexp.MustCompile(`%PDF-(\d+\.\d+)`)
+
+// ValidatePDF opens the file the same way extraction does and classifies failures
+// into domain.PDFValidationError codes. It returns nil for readable PDFs.
+func ValidatePDF(data []byte) error {
+	return validatePDF(bytes.NewReader(data), int64(len(data)), func() (*fitz.Document, error) {
+		return fitz.NewFromMemory(data)
+	}, 0)
+}
+
+// validatePDF is ValidatePDF for a file that may be spooled to disk; open must open
+// the same file with fitz. maxPages > 0 also rejects longer documents up front.
+func validatePDF(r io.ReaderAt, size int64, open func() (*fitz.Document, error), maxPages int) error {
+	match := pdfVersionPattern.FindSubmatch(readHead(r, size, pdfHeaderWindow))
+	if match == nil {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorCorrupt,
+			Message: "This PDF appears damaged: the file header is missing",
+		}
+	}
+	if version, err := strconv.ParseFloat(string(match[1]), 64); err == nil && version > maxPDFVersion {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorUnsupportedVersion,
+			Message: fmt.Sprintf("PDF version %s is not supported", match[1]),
+		}
+	}
+
+	doc, err := open()
+	if doc != nil {
+		defer doc.Close()
+	}
+	if err != nil {
+		return classifyPDFOpenError(readTail(r, size, pdfHeaderWindow), err)
+	}
+
+	if doc.NumPage() <= 0 {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorNoPages,
+			Message: "This PDF has no pages",
+		}
+	}
+	return checkPageLimit(doc.NumPage(), maxPages)
+}
+
+// classifyPDFOpenError maps a fitz open failure to a PDF validation error.
+func classifyPDFOpenError(data []byte, err error) error {
+	if errors.Is(err, fitz.ErrNeedsPassword) {
+		return &domain.PDFValidationError{
+			Code:    domain.PDFErrorEncrypted,
+			Message: "This PDF is password protected; remove the password and upload it again",
+			Err:     err,
+		}
+	}
+
+	message := "This PDF appears damaged and cannot be opened"
+	tail := data
+	if len(tail) > pdfHeaderWindow {
+		tail = tail[len(tail)-pdfHeaderWindow:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		message = "This PDF appears damaged or incomplete; try downloading it again"
+	}
+	return &domain.PDFValidationError{
+		Code:    domain.PDFErrorCorrupt,
+		Message: message,
+		Err:     err,
+	}
+}
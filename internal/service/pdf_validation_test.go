@@ -0,0 +1,82 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// testPDF builds a small PDF with the given number of blank pages. extraTrailer is
+// appended to the trailer dictionary (e.g. an /Encrypt reference).
+func testPDF(version string, pages int, extraObjects, extraTrailer string) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	obj := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	fmt.Fprintf(&buf, "%%PDF-%s\n", version)
+	kids := ""
+	for i := 0; i < pages; i++ {
+		kids += fmt.Sprintf("%d 0 R ", 3+i)
+	}
+	obj("<< /Type /Catalog /Pages 2 0 R >>")
+	obj(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pages))
+	for i := 0; i < pages; i++ {
+		obj("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >>")
+	}
+	if extraObjects != "" {
+		obj(extraObjects)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, off := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", off)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R %s >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, extraTrailer, xref)
+	return buf.Bytes()
+}
+
+func TestValidatePDF(t *testing.T) {
+	valid := testPDF("1.7", 1, "", "")
+	encrypted := testPDF("1.4", 1,
+		"<< /Filter /Standard /V 1 /R 2 /P -4 /O <"+string(bytes.Repeat([]byte("ab"), 32))+"> /U <"+string(bytes.Repeat([]byte("cd"), 32))+"> >>",
+		"/Encrypt 4 0 R /ID [<0123456789abcdef0123456789abcdef> <0123456789abcdef0123456789abcdef>]")
+
+	tests := []struct {
+		name string
+		data []byte
+		code string
+	}{
+		{name: "valid", data: valid},
+		{name: "no pages", data: testPDF("1.7", 0, "", ""), code: domain.PDFErrorNoPages},
+		{name: "future version", data: testPDF("3.0", 1, "", ""), code: domain.PDFErrorUnsupportedVersion},
+		{name: "encrypted", data: encrypted, code: domain.PDFErrorEncrypted},
+		{name: "truncated", data: []byte("%PDF-1.7\n1 0 obj\n<< /Type /Cat"), code: domain.PDFErrorCorrupt},
+		{name: "no header", data: []byte("not a pdf"), code: domain.PDFErrorCorrupt},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidatePDF(tt.data)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("expected valid PDF, got %v", err)
+				}
+				return
+			}
+			var pdfErr *domain.PDFValidationError
+			if !errors.As(err, &pdfErr) {
+				t.Fatalf("expected PDFValidationError, got %v", err)
+			}
+			if pdfErr.Code != tt.code {
+				t.Fatalf("expected code %s, got %s (%v)", tt.code, pdfErr.Code, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"unicode/utf16"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Exported PDF layout, in points on an A4 page. Text is set in the standard Times
+// fonts, which every PDF reader carries, so nothing has to be embedded; characters
+// outside their WinAnsi encoding print as '?'.
+const (
+	pdfPageWidth    = 595
+	pdfPageHeight   = 842
+	pdfMargin       = 72
+	pdfBodySize     = 11
+	pdfLeading      = 1.4 // of the font size
+	pdfParagraphGap = 6
+	pdfFooterSize   = 9
+)
+
+var pdfHeadingSizes = map[int]float64{1: 20, 2: 16, 3: 13}
+
+// pdfFont is a standard Type 1 font with its advance widths (per 1000 units of
+// font size) for the printable ASCII range; other characters use an average.
+type pdfFont struct {
+	resource, baseFont string
+	widths             [95]int
+	average            int
+}
+
+var pdfTimesRoman = pdfFont{
+	resource: "F1",
+	baseFont: "Times-Roman",
+	widths: [95]int{
+		250, 333, 408, 500, 500, 833, 778, 333, 333, 333, 500, 564, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 278, 278, 564, 564, 564, 444,
+		921, 722, 667, 667, 722, 611, 556, 722, 722, 333, 389, 722, 611, 889, 722, 722,
+		556, 722, 667, 556, 611, 722, 722, 944, 722, 722, 611, 333, 278, 333, 469, 500,
+		333, 444, 500, 444, 500, 444, 333, 500, 500, 278, 278, 500, 278, 778, 500, 500,
+		500, 500, 333, 389, 278, 500, 500, 722, 500, 500, 444, 480, 200, 480, 541,
+	},
+	average: 500,
+}
+
+var pdfTimesBold = pdfFont{
+	resource: "F2",
+	baseFont: "Times-Bold",
+	widths: [95]int{
+		250, 333, 555, 500, 500, 1000, 833, 333, 333, 333, 500, 570, 250, 333, 250, 278,
+		500, 500, 500, 500, 500, 500, 500, 500, 500, 500, 333, 333, 570, 570, 570, 500,
+		930, 722, 667, 722, 722, 667, 611, 778, 778, 389, 500, 778, 667, 944, 722, 778,
+		611, 778, 722, 556, 667, 722, 722, 1000, 722, 722, 667, 333, 278, 333, 581, 500,
+		333, 500, 556, 444, 556, 444, 333, 500, 556, 278, 333, 556, 278, 833, 556, 500,
+		556, 556, 444, 389, 333, 556, 500, 722, 500, 500, 444, 394, 220, 394, 520,
+	},
+	average: 556,
+}
+
+// winAnsiSpecials maps the characters WinAnsiEncoding places in 0x80-0x9F.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87,
+	'ˆ': 0x88, '‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91,
+	'’': 0x92, '“': 0x93, '”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98,
+	'™': 0x99, 'š': 0x9A, '›': 0x9B, 'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// winAnsi encodes s for the standard fonts.
+func winAnsi(s string) []byte {
+	out := make([]byte, 0, len(s))
+	for _, r := range s {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r':
+			out = append(out, ' ')
+		case r >= 0x20 && r < 0x7F, r >= 0xA0 && r <= 0xFF:
+			out = append(out, byte(r))
+		default:
+			if b, ok := winAnsiSpecials[r]; ok {
+				out = append(out, b)
+			} else {
+				out = append(out, '?')
+			}
+		}
+	}
+	return out
+}
+
+// width returns the advance width of encoded text at the given size.
+func (f *pdfFont) width(text []byte, size float64) float64 {
+	units := 0
+	for _, b := range text {
+		if b >= 0x20 && b < 0x7F {
+			units += f.widths[b-0x20]
+		} else {
+			units += f.average
+		}
+	}
+	return float64(units) * size / 1000
+}
+
+// wrap breaks encoded text into lines no wider than width.
+func (f *pdfFont) wrap(text []byte, size, width float64) [][]byte {
+	var lines [][]byte
+	var line []byte
+	for _, word := range bytes.Fields(text) {
+		candidate := word
+		if len(line) > 0 {
+			candidate = append(append(append([]byte{}, line...), ' '), word...)
+		}
+		if len(line) > 0 && f.width(candidate, size) > width {
+			lines = append(lines, line)
+			candidate = word
+		}
+		// A single word wider than the line is cut wherever it overflows.
+		for f.width(candidate, size) > width && len(candidate) > 1 {
+			cut := len(candidate) - 1
+			for cut > 1 && f.width(candidate[:cut], size) > width {
+				cut--
+			}
+			lines = append(lines, candidate[:cut])
+			candidate = candidate[cut:]
+		}
+		line = candidate
+	}
+	if len(line) > 0 {
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// pdfString writes encoded text as a PDF literal string.
+func pdfString(text []byte) string {
+	var b strings.Builder
+	b.WriteByte('(')
+	for _, c := range text {
+		if c == '(' || c == ')' || c == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(c)
+	}
+	b.WriteByte(')')
+	return b.String()
+}
+
+// pdfTextString writes a document-information string as UTF-16 so titles keep
+// every character.
+func pdfTextString(s string) string {
+	var b strings.Builder
+	b.WriteString("<FEFF")
+	for _, u := range utf16.Encode([]rune(s)) {
+		fmt.Fprintf(&b, "%04X", u)
+	}
+	b.WriteByte('>')
+	return b.String()
+}
+
+// pdfLayout sets text into page content streams, top to bottom.
+type pdfLayout struct {
+	pages [][]byte
+	page  *bytes.Buffer
+	y     float64
+}
+
+func (l *pdfLayout) newPage() {
+	l.flush()
+	l.page = &bytes.Buffer{}
+	l.y = pdfPageHeight - pdfMargin
+}
+
+func (l *pdfLayout) flush() {
+	if l.page == nil {
+		return
+	}
+	number := []byte(fmt.Sprint(len(l.pages) + 1))
+	x := (pdfPageWidth - pdfTimesRoman.width(number, pdfFooterSize)) / 2
+	fmt.Fprintf(l.page, "BT /%s %d Tf %.2f %d Td %s Tj ET\n", pdfTimesRoman.resource, pdfFooterSize, x, pdfMargin/2, pdfString(number))
+	l.pages = append(l.pages, l.page.Bytes())
+	l.page = nil
+}
+
+// empty reports whether nothing has been set on the current page.
+func (l *pdfLayout) empty() bool {
+	return l.page == nil || l.page.Len() == 0
+}
+
+// text sets a wrapped block, starting a new page whenever the next line would
+// run into the bottom margin.
+func (l *pdfLayout) text(font *pdfFont, size float64, content string, centered bool) {
+	leading := size * pdfLeading
+	for _, line := range font.wrap(winAnsi(content), size, pdfPageWidth-2*pdfMargin) {
+		if l.y-leading < pdfMargin {
+			l.newPage()
+		}
+		l.y -= leading
+		x := float64(pdfMargin)
+		if centered {
+			x = (pdfPageWidth - font.width(line, size)) / 2
+		}
+		fmt.Fprintf(l.page, "BT /%s %g Tf %.2f %.2f Td %s Tj ET\n", font.resource, size, x, l.y, pdfString(line))
+	}
+}
+
+// exportPDF sets the document as a title page followed by its text, starting each
+// top-level heading on a new page.
+func exportPDF(doc *domain.DocumentData, blocks []TextBlock) ([]byte, error) {
+	layout := &pdfLayout{}
+	layout.newPage()
+	layout.y = pdfPageHeight/2 + 60
+	layout.text(&pdfTimesBold, 26, doc.Title, true)
+	if author := exportAuthor(doc); author != "" {
+		layout.y -= 12
+		layout.text(&pdfTimesRoman, 16, author, true)
+	}
+	layout.newPage()
+
+	for _, chapter := range splitChapters(doc.Title, blocks) {
+		if !layout.empty() {
+			layout.newPage()
+		}
+		for _, block := range chapter.Blocks {
+			if block.Type != "heading" {
+				layout.text(&pdfTimesRoman, pdfBodySize, block.Content, false)
+				layout.y -= pdfParagraphGap
+				continue
+			}
+			size, ok := pdfHeadingSizes[headingLevel(block.Level)]
+			if !ok {
+				size = pdfBodySize + 1
+			}
+			if !layout.empty() {
+				layout.y -= size
+			}
+			// Keep a heading with the first lines that follow it.
+			if layout.y-4*size < pdfMargin {
+				layout.newPage()
+			}
+			layout.text(&pdfTimesBold, size, block.Content, false)
+			layout.y -= pdfParagraphGap
+		}
+	}
+	layout.flush()
+	return writePDF(layout.pages, doc.Title, exportAuthor(doc))
+}
+
+// writePDF serializes pages of content into a PDF file. Objects 1-5 are the
+// catalog, page tree, fonts and document information; each page then takes two
+// objects, the page and its compressed content stream.
+func writePDF(pages [][]byte, title, author string) ([]byte, error) {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.4\n%\xE2\xE3\xCF\xD3\n")
+	kids := make([]string, len(pages))
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", 6+2*i)
+	}
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	for _, font := range []*pdfFont{&pdfTimesRoman, &pdfTimesBold} {
+		object(fmt.Sprintf("<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", font.baseFont))
+	}
+	info := "<< /Producer (Lector) /Title " + pdfTextString(title)
+	if author != "" {
+		info += " /Author " + pdfTextString(author)
+	}
+	object(info + " >>")
+
+	for i, content := range pages {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %d %d] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+			pdfPageWidth, pdfPageHeight, pdfTimesRoman.resource, pdfTimesBold.resource, 7+2*i))
+		var stream bytes.Buffer
+		zw := zlib.NewWriter(&stream)
+		if _, err := zw.Write(content); err != nil {
+			return nil, err
+		}
+		if err := zw.Close(); err != nil {
+			return nil, err
+		}
+		object(fmt.Sprintf("<< /Length %d /Filter /FlateDecode >>\nstream\n%s\nendstream", stream.Len(), stream.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes(), nil
+}
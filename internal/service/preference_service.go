@@ -1,6 +1,8 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"pdf-text-reader/internal/domain"
@@ -8,45 +10,220 @@ import (
 
 type userPreferencesService struct {
 	userPreferencesRepo domain.UserPreferencesRepository
+	anchors             domain.TextAnchorResolver
 	logger              domain.Logger
 }
 
+// NewUserPreferencesService creates the preferences service. anchors recomputes
+// progress from the character offset of reading positions; without it the progress
+// sent by clients is stored as is.
 func NewUserPreferencesService(
 	userPreferencesRepo domain.UserPreferencesRepository,
+	anchors domain.TextAnchorResolver,
 	logger domain.Logger,
 ) domain.UserPreferencesService {
 	return &userPreferencesService{
 		userPreferencesRepo: userPreferencesRepo,
+		anchors:             anchors,
 		logger:              logger,
 	}
 }
 
 // GetPreferences retrieves user preferences
-func (s *userPreferencesService) GetPreferences(userID string, token string) (*domain.UserPreferences, error) {
-	return s.userPreferencesRepo.GetPreferences(userID, token)
+func (s *userPreferencesService) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
+	return s.userPreferencesRepo.GetPreferences(ctx, principal)
 }
 
 // UpdatePreferences updates user preferences
-func (s *userPreferencesService) UpdatePreferences(userID string, prefs *domain.UserPreferences, token string) error {
-	prefs.UserID = userID
+func (s *userPreferencesService) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
+	prefs.UserID = principal.UserID
 	prefs.UpdatedAt = time.Now()
-	return s.userPreferencesRepo.UpdatePreferences(prefs, token)
+	return s.userPreferencesRepo.UpdatePreferences(ctx, principal, prefs)
 }
 
-// GetReadingPosition retrieves reading position for a document
-func (s *userPreferencesService) GetReadingPosition(userID, documentID string, token string) (*domain.ReadingPosition, error) {
-	return s.userPreferencesRepo.GetReadingPosition(userID, documentID, token)
+// GetReadingPosition retrieves reading position for a document. A position anchored
+// to a character offset gets its progress recomputed, so it stays right after the
+// document is reprocessed.
+func (s *userPreferencesService) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
+	position, err := s.userPreferencesRepo.GetReadingPosition(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	if query, ok := positionAnchor(position); ok && s.anchors != nil {
+		anchor, err := s.anchors.ResolveAnchor(ctx, principal, documentID, query)
+		if err != nil {
+			s.logger.Warn("Failed to resolve reading position anchor", "user_id", principal.UserID, "document_id", documentID, "error", err)
+			return position, nil
+		}
+		applyAnchor(position, anchor)
+	}
+	return position, nil
 }
 
 // GetAllReadingPositions retrieves all reading positions for a user
-func (s *userPreferencesService) GetAllReadingPositions(userID string, token string) (map[string]*domain.ReadingPosition, error) {
-	return s.userPreferencesRepo.GetAllReadingPositions(userID, token)
+func (s *userPreferencesService) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
+	return s.userPreferencesRepo.GetAllReadingPositions(ctx, principal)
+}
+
+// GetReadingPositions retrieves the saved positions of the given documents
+func (s *userPreferencesService) GetReadingPositions(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]*domain.ReadingPosition, error) {
+	if len(documentIDs) == 0 {
+		return map[string]*domain.ReadingPosition{}, nil
+	}
+	return s.userPreferencesRepo.GetReadingPositions(ctx, principal, documentIDs)
 }
 
 // UpdateReadingPosition updates reading position for a document
-func (s *userPreferencesService) UpdateReadingPosition(userID, documentID string, position *domain.ReadingPosition, token string) error {
-	position.UserID = userID
+func (s *userPreferencesService) UpdateReadingPosition(ctx context.Context, principal domain.Principal, documentID string, position *domain.ReadingPosition) error {
+	position.UserID = principal.UserID
 	position.DocumentID = documentID
 	position.UpdatedAt = time.Now()
-	return s.userPreferencesRepo.UpdateReadingPosition(position, token)
+	s.anchorPosition(ctx, principal, position)
+	if err := s.userPreferencesRepo.UpdateReadingPosition(ctx, principal, position); err != nil {
+		return err
+	}
+	s.samplePosition(ctx, principal, position)
+	return nil
+}
+
+// anchorPosition derives the progress from the position's EPUB locator or character
+// offset, which do not change when a client re-paginates. An unknown locator falls
+// back to the offset; positions in documents without text drop both. Any other
+// failure keeps the progress the client sent.
+func (s *userPreferencesService) anchorPosition(ctx context.Context, principal domain.Principal, position *domain.ReadingPosition) {
+	query, ok := positionAnchor(position)
+	if !ok || s.anchors == nil {
+		return
+	}
+	anchor, err := s.anchors.ResolveAnchor(ctx, principal, position.DocumentID, query)
+	switch {
+	case errors.Is(err, domain.ErrLocatorNotFound):
+		position.Locator = nil
+		s.anchorPosition(ctx, principal, position)
+	case errors.Is(err, domain.ErrDocumentHasNoText):
+		position.CharOffset, position.Locator = nil, nil
+	case err != nil:
+		s.logger.Warn("Failed to resolve reading position anchor", "user_id", principal.UserID, "document_id", position.DocumentID, "error", err)
+	default:
+		applyAnchor(position, anchor)
+	}
+}
+
+// positionAnchor is what a position is anchored to, the locator first.
+func positionAnchor(position *domain.ReadingPosition) (domain.AnchorQuery, bool) {
+	switch {
+	case position.Locator != nil:
+		return domain.AnchorQuery{Locator: position.Locator}, true
+	case position.CharOffset != nil:
+		return domain.AnchorQuery{CharOffset: position.CharOffset}, true
+	}
+	return domain.AnchorQuery{}, false
+}
+
+// applyAnchor updates a position from its resolved anchor. The page only follows a
+// locator: for EPUBs it is the spine document, while elsewhere clients paginate.
+func applyAnchor(position *domain.ReadingPosition, anchor *domain.TextAnchor) {
+	if position.Locator != nil {
+		position.PageNumber = anchor.PageNumber
+	}
+	position.CharOffset = &anchor.CharOffset
+	position.Locator = anchor.Locator
+	position.Progress = anchor.Progress
+}
+
+// samplePosition keeps the saved position in the document's history when it is far
+// enough, in time or in the document, from the last sample. The history is a
+// convenience, so failures are only logged.
+func (s *userPreferencesService) samplePosition(ctx context.Context, principal domain.Principal, position *domain.ReadingPosition) {
+	latest, err := s.userPreferencesRepo.LatestPositionSample(ctx, principal, position.DocumentID)
+	if err != nil {
+		s.logger.Warn("Failed to read position history", "user_id", principal.UserID, "document_id", position.DocumentID, "error", err)
+		return
+	}
+	if !domain.ShouldSample(latest, position) {
+		return
+	}
+	sample := &domain.PositionSample{
+		DocumentID: position.DocumentID,
+		Progress:   position.Progress,
+		PageNumber: position.PageNumber,
+		CharOffset: position.CharOffset,
+		RecordedAt: position.UpdatedAt,
+	}
+	if err := s.userPreferencesRepo.AddPositionSample(ctx, principal, sample); err != nil {
+		s.logger.Warn("Failed to record position history", "user_id", principal.UserID, "document_id", position.DocumentID, "error", err)
+	}
+}
+
+// GetPositionHistory returns the document's position samples of the last days,
+// with the progress reached and the pages read on each day
+func (s *userPreferencesService) GetPositionHistory(ctx context.Context, principal domain.Principal, documentID string, days int) (*domain.PositionHistory, error) {
+	since := time.Now().UTC().AddDate(0, 0, -days)
+	samples, err := s.userPreferencesRepo.GetPositionHistory(ctx, principal, documentID, since)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.PositionHistory{
+		DocumentID: documentID,
+		Samples:    samples,
+		Days:       summarizePositionDays(samples),
+	}, nil
+}
+
+// summarizePositionDays groups samples by UTC day. Pages read are the forward page
+// moves between consecutive samples, counted on the day of the later one; jumping
+// back is not reading, so it does not subtract.
+func summarizePositionDays(samples []*domain.PositionSample) []domain.PositionDay {
+	days := make([]domain.PositionDay, 0)
+	for i, sample := range samples {
+		date := sample.RecordedAt.UTC().Format(time.DateOnly)
+		if len(days) == 0 || days[len(days)-1].Date != date {
+			days = append(days, domain.PositionDay{Date: date})
+		}
+		day := &days[len(days)-1]
+		day.Progress = sample.Progress
+		if i > 0 {
+			if moved := sample.PageNumber - samples[i-1].PageNumber; moved > 0 {
+				day.PagesRead += moved
+			}
+		}
+	}
+	return days
+}
+
+// GetDocumentPreferences resolves the preferences to use when reading a document
+func (s *userPreferencesService) GetDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) (*domain.UserPreferences, error) {
+	prefs, err := s.userPreferencesRepo.GetPreferences(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	override, err := s.userPreferencesRepo.GetDocumentPreferences(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	return prefs.WithOverride(override), nil
+}
+
+// UpdateDocumentPreferences replaces a document's overrides and returns the resolved preferences
+func (s *userPreferencesService) UpdateDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string, override *domain.DocumentPreferences) (*domain.UserPreferences, error) {
+	override.UserID = principal.UserID
+	override.DocumentID = documentID
+	if err := override.Validate(); err != nil {
+		return nil, err
+	}
+
+	if override.IsEmpty() {
+		if err := s.userPreferencesRepo.DeleteDocumentPreferences(ctx, principal, documentID); err != nil {
+			return nil, err
+		}
+	} else if err := s.userPreferencesRepo.UpsertDocumentPreferences(ctx, principal, override); err != nil {
+		return nil, err
+	}
+
+	return s.GetDocumentPreferences(ctx, principal, documentID)
+}
+
+// DisableAccount blocks the account on every device
+func (s *userPreferencesService) DisableAccount(ctx context.Context, principal domain.Principal) error {
+	return s.userPreferencesRepo.SetAccountDisabled(ctx, principal, true)
 }
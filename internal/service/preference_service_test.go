@@ -1,8 +1,10 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"testing"
+	"time"
 
 	"pdf-text-reader/internal/domain"
 )
@@ -10,33 +12,66 @@ import (
 type mockUserPreferencesRepo struct {
 	prefs        map[string]*domain.UserPreferences
 	positions    map[string]map[string]*domain.ReadingPosition
+	overrides    map[string]*domain.DocumentPreferences // Keyed by document ID
 	lastUpdated  *domain.UserPreferences
 	lastPosition *domain.ReadingPosition
+	batchCalls   int
+	samples      []*domain.PositionSample
+	err          error // returned by GetPreferences when set
 }
 
 func newMockUserPreferencesRepo() *mockUserPreferencesRepo {
 	return &mockUserPreferencesRepo{
 		prefs:     make(map[string]*domain.UserPreferences),
 		positions: make(map[string]map[string]*domain.ReadingPosition),
+		overrides: make(map[string]*domain.DocumentPreferences),
 	}
 }
 
-func (m *mockUserPreferencesRepo) GetPreferences(userID string, token string) (*domain.UserPreferences, error) {
-	prefs, ok := m.prefs[userID]
+func (m *mockUserPreferencesRepo) GetDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) (*domain.DocumentPreferences, error) {
+	return m.overrides[documentID], nil
+}
+
+func (m *mockUserPreferencesRepo) UpsertDocumentPreferences(ctx context.Context, principal domain.Principal, override *domain.DocumentPreferences) error {
+	m.overrides[override.DocumentID] = override
+	return nil
+}
+
+func (m *mockUserPreferencesRepo) DeleteDocumentPreferences(ctx context.Context, principal domain.Principal, documentID string) error {
+	delete(m.overrides, documentID)
+	return nil
+}
+
+func (m *mockUserPreferencesRepo) SetAccountDisabled(ctx context.Context, principal domain.Principal, disabled bool) error {
+	prefs, ok := m.prefs[principal.UserID]
 	if !ok {
-		return nil, errors.New("preferences not found")
+		prefs = domain.DefaultUserPreferences(principal.UserID)
+		m.prefs[principal.UserID] = prefs
+	}
+	prefs.AccountDisabled = disabled
+	return nil
+}
+
+func (m *mockUserPreferencesRepo) GetPreferences(ctx context.Context, principal domain.Principal) (*domain.UserPreferences, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	prefs, ok := m.prefs[principal.UserID]
+	if !ok {
+		// Like the repositories, a user without a row gets the defaults.
+		return domain.DefaultUserPreferences(principal.UserID), nil
 	}
 	return prefs, nil
 }
 
-func (m *mockUserPreferencesRepo) UpdatePreferences(prefs *domain.UserPreferences, token string) error {
+func (m *mockUserPreferencesRepo) UpdatePreferences(ctx context.Context, principal domain.Principal, prefs *domain.UserPreferences) error {
 	m.lastUpdated = prefs
 	m.prefs[prefs.UserID] = prefs
 	return nil
 }
 
-func (m *mockUserPreferencesRepo) GetReadingPosition(userID, documentID string, token string) (*domain.ReadingPosition, error) {
-	userPositions, ok := m.positions[userID]
+func (m *mockUserPreferencesRepo) GetReadingPosition(ctx context.Context, principal domain.Principal, documentID string) (*domain.ReadingPosition, error) {
+	userPositions, ok := m.positions[principal.UserID]
 	if !ok {
 		return nil, errors.New("position not found")
 	}
@@ -47,15 +82,26 @@ func (m *mockUserPreferencesRepo) GetReadingPosition(userID, documentID string,
 	return position, nil
 }
 
-func (m *mockUserPreferencesRepo) GetAllReadingPositions(userID string, token string) (map[string]*domain.ReadingPosition, error) {
-	userPositions, ok := m.positions[userID]
+func (m *mockUserPreferencesRepo) GetAllReadingPositions(ctx context.Context, principal domain.Principal) (map[string]*domain.ReadingPosition, error) {
+	userPositions, ok := m.positions[principal.UserID]
 	if !ok {
 		return map[string]*domain.ReadingPosition{}, nil
 	}
 	return userPositions, nil
 }
 
-func (m *mockUserPreferencesRepo) UpdateReadingPosition(position *domain.ReadingPosition, token string) error {
+func (m *mockUserPreferencesRepo) GetReadingPositions(ctx context.Context, principal domain.Principal, documentIDs []string) (map[string]*domain.ReadingPosition, error) {
+	m.batchCalls++
+	positions := make(map[string]*domain.ReadingPosition)
+	for _, id := range documentIDs {
+		if position, ok := m.positions[principal.UserID][id]; ok {
+			positions[id] = position
+		}
+	}
+	return positions, nil
+}
+
+func (m *mockUserPreferencesRepo) UpdateReadingPosition(ctx context.Context, principal domain.Principal, position *domain.ReadingPosition) error {
 	m.lastPosition = position
 	if m.positions[position.UserID] == nil {
 		m.positions[position.UserID] = make(map[string]*domain.ReadingPosition)
@@ -64,6 +110,30 @@ func (m *mockUserPreferencesRepo) UpdateReadingPosition(position *domain.Reading
 	return nil
 }
 
+func (m *mockUserPreferencesRepo) LatestPositionSample(ctx context.Context, principal domain.Principal, documentID string) (*domain.PositionSample, error) {
+	for i := len(m.samples) - 1; i >= 0; i-- {
+		if m.samples[i].DocumentID == documentID {
+			return m.samples[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (m *mockUserPreferencesRepo) AddPositionSample(ctx context.Context, principal domain.Principal, sample *domain.PositionSample) error {
+	m.samples = append(m.samples, sample)
+	return nil
+}
+
+func (m *mockUserPreferencesRepo) GetPositionHistory(ctx context.Context, principal domain.Principal, documentID string, since time.Time) ([]*domain.PositionSample, error) {
+	var samples []*domain.PositionSample
+	for _, sample := range m.samples {
+		if sample.DocumentID == documentID && !sample.RecordedAt.Before(since) {
+			samples = append(samples, sample)
+		}
+	}
+	return samples, nil
+}
+
 func TestUserPreferencesService_GetPreferences(t *testing.T) {
 	repo := newMockUserPreferencesRepo()
 	logger := NewMockLogger()
@@ -71,8 +141,8 @@ func TestUserPreferencesService_GetPreferences(t *testing.T) {
 	prefs := &domain.UserPreferences{UserID: "user-1", FontSize: 18}
 	repo.prefs["user-1"] = prefs
 
-	svc := NewUserPreferencesService(repo, logger)
-	got, err := svc.GetPreferences("user-1", "token")
+	svc := NewUserPreferencesService(repo, nil, logger)
+	got, err := svc.GetPreferences(context.Background(), testPrincipal("user-1"))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -85,10 +155,10 @@ func TestUserPreferencesService_UpdatePreferences(t *testing.T) {
 	repo := newMockUserPreferencesRepo()
 	logger := NewMockLogger()
 
-	svc := NewUserPreferencesService(repo, logger)
+	svc := NewUserPreferencesService(repo, nil, logger)
 	prefs := &domain.UserPreferences{FontSize: 20}
 
-	if err := svc.UpdatePreferences("user-2", prefs, "token"); err != nil {
+	if err := svc.UpdatePreferences(context.Background(), testPrincipal("user-2"), prefs); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	if repo.lastUpdated == nil {
@@ -109,8 +179,8 @@ func TestUserPreferencesService_GetAllReadingPositions(t *testing.T) {
 	position := &domain.ReadingPosition{UserID: "user-3", DocumentID: "doc-1", Progress: 0.5, PageNumber: 2}
 	repo.positions["user-3"] = map[string]*domain.ReadingPosition{"doc-1": position}
 
-	svc := NewUserPreferencesService(repo, logger)
-	got, err := svc.GetAllReadingPositions("user-3", "token")
+	svc := NewUserPreferencesService(repo, nil, logger)
+	got, err := svc.GetAllReadingPositions(context.Background(), testPrincipal("user-3"))
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
@@ -122,14 +192,40 @@ func TestUserPreferencesService_GetAllReadingPositions(t *testing.T) {
 	}
 }
 
+func TestUserPreferencesService_GetReadingPositions(t *testing.T) {
+	repo := newMockUserPreferencesRepo()
+	repo.positions["user-3"] = map[string]*domain.ReadingPosition{
+		"doc-1": {UserID: "user-3", DocumentID: "doc-1", Progress: 0.5},
+		"doc-2": {UserID: "user-3", DocumentID: "doc-2", Progress: 0.1},
+	}
+	svc := NewUserPreferencesService(repo, nil, NewMockLogger())
+
+	got, err := svc.GetReadingPositions(context.Background(), testPrincipal("user-3"), []string{"doc-1", "doc-3"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(got) != 1 || got["doc-1"] == nil {
+		t.Fatalf("expected only the position of doc-1, got %v", got)
+	}
+
+	// An empty library needs no query at all.
+	got, err = svc.GetReadingPositions(context.Background(), testPrincipal("user-3"), nil)
+	if err != nil || len(got) != 0 {
+		t.Fatalf("expected no positions, got %v (%v)", got, err)
+	}
+	if repo.batchCalls != 1 {
+		t.Fatalf("expected one batched repository call, got %d", repo.batchCalls)
+	}
+}
+
 func TestUserPreferencesService_UpdateReadingPosition(t *testing.T) {
 	repo := newMockUserPreferencesRepo()
 	logger := NewMockLogger()
 
-	svc := NewUserPreferencesService(repo, logger)
+	svc := NewUserPreferencesService(repo, nil, logger)
 	position := &domain.ReadingPosition{Progress: 0.25, PageNumber: 4}
 
-	if err := svc.UpdateReadingPosition("user-4", "doc-2", position, "token"); err != nil {
+	if err := svc.UpdateReadingPosition(context.Background(), testPrincipal("user-4"), "doc-2", position); err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
 	if repo.lastPosition == nil {
@@ -145,3 +241,96 @@ func TestUserPreferencesService_UpdateReadingPosition(t *testing.T) {
 		t.Fatalf("expected updated at to be set")
 	}
 }
+
+func TestUserPreferencesService_PositionHistory(t *testing.T) {
+	repo := newMockUserPreferencesRepo()
+	svc := NewUserPreferencesService(repo, nil, NewMockLogger())
+	ctx := context.Background()
+	principal := testPrincipal("user-4")
+
+	// Small moves within the sample interval are not kept; a jump is.
+	for _, position := range []*domain.ReadingPosition{
+		{Progress: 0.25, PageNumber: 25},
+		{Progress: 0.27, PageNumber: 27},
+		{Progress: 0.6, PageNumber: 60},
+	} {
+		if err := svc.UpdateReadingPosition(ctx, principal, "doc-2", position); err != nil {
+			t.Fatalf("update failed: %v", err)
+		}
+	}
+	if len(repo.samples) != 2 || repo.samples[0].PageNumber != 25 || repo.samples[1].PageNumber != 60 {
+		t.Fatalf("unexpected samples %+v", repo.samples)
+	}
+
+	history, err := svc.GetPositionHistory(ctx, principal, "doc-2", domain.DefaultPositionHistoryDays)
+	if err != nil {
+		t.Fatalf("history failed: %v", err)
+	}
+	if len(history.Samples) != 2 || len(history.Days) != 1 || history.Days[0].PagesRead != 35 || history.Days[0].Progress != 0.6 {
+		t.Fatalf("unexpected history %+v %+v", history.Samples, history.Days)
+	}
+}
+
+func TestSummarizePositionDays(t *testing.T) {
+	day := time.Date(2026, 3, 3, 20, 0, 0, 0, time.UTC)
+	samples := []*domain.PositionSample{
+		{Progress: 0.1, PageNumber: 10, RecordedAt: day},
+		{Progress: 0.3, PageNumber: 30, RecordedAt: day.Add(time.Hour)},
+		{Progress: 0.2, PageNumber: 20, RecordedAt: day.Add(5 * time.Hour)},
+		{Progress: 0.25, PageNumber: 25, RecordedAt: day.Add(6 * time.Hour)},
+	}
+
+	days := summarizePositionDays(samples)
+	if len(days) != 2 {
+		t.Fatalf("expected 2 days, got %+v", days)
+	}
+	if days[0].Date != "2026-03-03" || days[0].PagesRead != 20 || days[0].Progress != 0.3 {
+		t.Fatalf("unexpected first day %+v", days[0])
+	}
+	// Jumping back does not count as reading.
+	if days[1].Date != "2026-03-04" || days[1].PagesRead != 5 || days[1].Progress != 0.25 {
+		t.Fatalf("unexpected second day %+v", days[1])
+	}
+}
+
+func TestUserPreferencesService_DocumentPreferences(t *testing.T) {
+	repo := newMockUserPreferencesRepo()
+	repo.prefs["user-1"] = &domain.UserPreferences{UserID: "user-1", FontSize: 16, FontFamily: "system-ui", Theme: "light"}
+	svc := NewUserPreferencesService(repo, nil, NewMockLogger())
+	ctx := context.Background()
+
+	size := 20
+	got, err := svc.UpdateDocumentPreferences(ctx, testPrincipal("user-1"), "doc-1", &domain.DocumentPreferences{FontSize: &size})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got.FontSize != 20 || got.Theme != "light" || got.DocumentOverride == nil {
+		t.Fatalf("unexpected resolved preferences: %+v", got)
+	}
+	if stored := repo.overrides["doc-1"]; stored == nil || stored.UserID != "user-1" || stored.DocumentID != "doc-1" {
+		t.Fatalf("expected override stored for user-1/doc-1, got %+v", stored)
+	}
+
+	other, err := svc.GetDocumentPreferences(ctx, testPrincipal("user-1"), "doc-2")
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if other.FontSize != 16 || other.DocumentOverride != nil {
+		t.Fatalf("documents without overrides should use global preferences, got %+v", other)
+	}
+
+	cleared, err := svc.UpdateDocumentPreferences(ctx, testPrincipal("user-1"), "doc-1", &domain.DocumentPreferences{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if cleared.FontSize != 16 || repo.overrides["doc-1"] != nil {
+		t.Fatalf("empty override should clear the document's overrides, got %+v", cleared)
+	}
+
+	zero := 0
+	_, err = svc.UpdateDocumentPreferences(ctx, testPrincipal("user-1"), "doc-1", &domain.DocumentPreferences{FontSize: &zero})
+	var validationErrs domain.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		t.Fatalf("expected validation error, got %v", err)
+	}
+}
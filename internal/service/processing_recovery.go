@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// recoveryBatchSize caps how many documents one run reprocesses; the rest wait for
+// the next run so a backlog after an outage does not monopolize extraction.
+const recoveryBatchSize = 20
+
+type ProcessingRecoveryService struct {
+	repo      domain.ProcessingRecoveryRepository
+	documents *DocumentService
+	interval  time.Duration
+	grace     time.Duration
+	logger    domain.Logger
+	now       func() time.Time
+}
+
+func NewProcessingRecoveryService(
+	repo domain.ProcessingRecoveryRepository,
+	documents *DocumentService,
+	config domain.ProcessingRecoveryConfig,
+	logger domain.Logger,
+) domain.ProcessingRecoveryService {
+	return &ProcessingRecoveryService{
+		repo:      repo,
+		documents: documents,
+		interval:  config.CheckInterval,
+		grace:     config.GracePeriod,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Run recovers lost documents right away, since a restart is what loses them, and
+// then on every check interval.
+func (s *ProcessingRecoveryService) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	for {
+		recovered, err := s.RecoverDocuments(ctx)
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("Processing recovery run failed", err)
+		} else if recovered > 0 {
+			s.logger.Info("Recovered unprocessed documents", "count", recovered)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RecoverDocuments reprocesses documents whose extraction never stored content,
+// acting as their owner. A failure is recorded on the document and retried on later
+// runs until it reaches domain.MaxProcessingAttempts; it does not stop the others.
+func (s *ProcessingRecoveryService) RecoverDocuments(ctx context.Context) (int, error) {
+	docs, err := s.repo.ListUnprocessed(ctx, s.now().Add(-s.grace), recoveryBatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	recovered := 0
+	for _, doc := range docs {
+		if err := ctx.Err(); err != nil {
+			return recovered, err
+		}
+
+		principal := domain.Principal{UserID: doc.UserID}
+		if _, err := s.documents.ReprocessDocument(ctx, principal, doc.DocumentID); err != nil {
+			if ctx.Err() != nil {
+				return recovered, ctx.Err()
+			}
+			s.logger.Error("Failed to recover unprocessed document", err, "doc_id", doc.DocumentID)
+			s.documents.recordProcessingFailure(ctx, principal, doc.DocumentID, err)
+			continue
+		}
+		recovered++
+	}
+	return recovered, nil
+}
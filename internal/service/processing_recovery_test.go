@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockProcessingRecoveryRepo struct {
+	docs          []domain.UnprocessedDocument
+	createdBefore time.Time
+}
+
+func (m *mockProcessingRecoveryRepo) ListUnprocessed(ctx context.Context, createdBefore time.Time, limit int) ([]domain.UnprocessedDocument, error) {
+	m.createdBefore = createdBefore
+	return m.docs, nil
+}
+
+func TestProcessingRecoveryService_RecoverDocuments(t *testing.T) {
+	now := time.Date(2026, 3, 1, 9, 0, 0, 0, time.UTC)
+	documents, repo, _ := newTestIntegrityService(t)
+	// "book" has lost its original, so it cannot be recovered.
+	recoveryRepo := &mockProcessingRecoveryRepo{docs: []domain.UnprocessedDocument{
+		{DocumentID: "unprocessed", UserID: "user1"},
+		{DocumentID: "book", UserID: "user1"},
+	}}
+
+	s := NewProcessingRecoveryService(recoveryRepo, documents,
+		domain.ProcessingRecoveryConfig{CheckInterval: time.Hour, GracePeriod: 30 * time.Minute}, NewMockLogger()).(*ProcessingRecoveryService)
+	s.now = func() time.Time { return now }
+
+	recovered, err := s.RecoverDocuments(context.Background())
+	if err != nil {
+		t.Fatalf("recover failed: %v", err)
+	}
+	if recovered != 1 {
+		t.Errorf("recovered = %d, want 1", recovered)
+	}
+	if want := now.Add(-30 * time.Minute); !recoveryRepo.createdBefore.Equal(want) {
+		t.Errorf("created before = %v, want %v", recoveryRepo.createdBefore, want)
+	}
+
+	if doc := repo.documents["unprocessed"]; doc.Metadata.PageCount != 2 || doc.Metadata.ProcessingAttempts != 0 {
+		t.Errorf("recovered document: page count = %d, attempts = %d", doc.Metadata.PageCount, doc.Metadata.ProcessingAttempts)
+	}
+	failed := repo.documents["book"]
+	if failed.Metadata.ProcessingAttempts != 1 || failed.Metadata.ProcessingError == "" {
+		t.Errorf("failed document: attempts = %d, error = %q", failed.Metadata.ProcessingAttempts, failed.Metadata.ProcessingError)
+	}
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// ReadLaterImportService imports the saved articles of Pocket and Instapaper from
+// their export files. Each article is fetched from its original address and stored
+// as a one-chapter EPUB.
+type ReadLaterImportService struct {
+	documents *DocumentService
+	repo      domain.DocumentRepository
+	fetcher   domain.WebPageFetcher
+	jobs      *ImportJobTracker
+	logger    domain.Logger
+}
+
+func NewReadLaterImportService(
+	documents *DocumentService,
+	repo domain.DocumentRepository,
+	fetcher domain.WebPageFetcher,
+	jobs *ImportJobTracker,
+	logger domain.Logger,
+) domain.ReadLaterImportService {
+	return &ReadLaterImportService{
+		documents: documents,
+		repo:      repo,
+		fetcher:   fetcher,
+		jobs:      jobs,
+		logger:    logger,
+	}
+}
+
+func (s *ReadLaterImportService) Import(ctx context.Context, principal domain.Principal, service string, export io.Reader) (*domain.ImportJob, error) {
+	if service != domain.ReadLaterPocket && service != domain.ReadLaterInstapaper {
+		return nil, domain.ValidationErrors{{Field: "service", Message: "service must be pocket or instapaper"}}
+	}
+	data, err := io.ReadAll(io.LimitReader(export, domain.MaxReadLaterExportSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > domain.MaxReadLaterExportSize {
+		return nil, domain.ErrFileTooLarge
+	}
+
+	articles, err := parseReadLaterExport(data)
+	if err != nil {
+		return nil, err
+	}
+	articles, err = s.newArticles(ctx, principal, articles)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case len(articles) == 0:
+		return nil, domain.ValidationErrors{{Field: "file", Message: "the export contains no new articles"}}
+	case len(articles) > domain.MaxReadLaterArticles:
+		return nil, domain.ValidationErrors{{Field: "file", Message: fmt.Sprintf("at most %d articles can be imported at once", domain.MaxReadLaterArticles)}}
+	}
+
+	files := make([]domain.ImportFile, len(articles))
+	for i, article := range articles {
+		files[i] = domain.ImportFile{FileID: article.URL, Name: article.Title, Status: domain.ImportStatusPending}
+	}
+	job, snapshot := s.jobs.start(principal, service, files)
+
+	// The import outlives the request, so detach it from the request's cancellation.
+	go s.runImport(context.WithoutCancel(ctx), principal, job, articles)
+
+	return snapshot, nil
+}
+
+// newArticles drops the articles whose address is already in the library or listed
+// earlier in the export, so an export can be imported again after a failure.
+func (s *ReadLaterImportService) newArticles(ctx context.Context, principal domain.Principal, articles []domain.SavedArticle) ([]domain.SavedArticle, error) {
+	docs, err := s.repo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool, len(docs)+len(articles))
+	for _, doc := range docs {
+		if doc.Metadata.SourceURL != "" {
+			seen[doc.Metadata.SourceURL] = true
+		}
+	}
+	return slices.DeleteFunc(articles, func(article domain.SavedArticle) bool {
+		if seen[article.URL] {
+			return true
+		}
+		seen[article.URL] = true
+		return false
+	}), nil
+}
+
+// runImport creates the articles' tags, then imports the articles one at a time. An
+// article that fails, often because its page is gone, is recorded and the others
+// still go ahead.
+func (s *ReadLaterImportService) runImport(ctx context.Context, principal domain.Principal, job *domain.ImportJob, articles []domain.SavedArticle) {
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusRunning })
+
+	existing, err := s.repo.GetTagsByUserID(ctx, principal)
+	if err != nil {
+		s.logger.Warn("Failed to list tags for read-later import", "user_id", principal.UserID, "error", err)
+	}
+	for _, article := range articles {
+		for _, tag := range article.Tags {
+			if slices.Contains(existing, tag) {
+				continue
+			}
+			existing = append(existing, tag)
+			if err := s.repo.CreateTag(ctx, principal, tag); err != nil {
+				s.logger.Warn("Failed to create read-later tag", "user_id", principal.UserID, "tag", tag, "error", err)
+			}
+		}
+	}
+
+	for i, article := range articles {
+		s.jobs.update(job, func() { job.Files[i].Status = domain.ImportStatusRunning })
+
+		doc, err := s.importArticle(ctx, principal, job.Provider, article)
+		name := ""
+		if doc != nil {
+			name = doc.Title
+		}
+		s.jobs.finishFile(job, i, name, doc, err)
+		if err != nil {
+			s.logger.Warn("Failed to import saved article", "user_id", principal.UserID, "provider", job.Provider, "error", err)
+		}
+	}
+
+	s.jobs.update(job, func() { job.Status = domain.ImportStatusDone })
+	s.logger.Info("Read-later import finished", "user_id", principal.UserID, "provider", job.Provider,
+		"completed", job.Completed, "failed", job.Failed)
+}
+
+func (s *ReadLaterImportService) importArticle(ctx context.Context, principal domain.Principal, service string, article domain.SavedArticle) (*domain.DocumentData, error) {
+	page, err := s.fetcher.Fetch(ctx, article.URL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch article: %w", err)
+	}
+	content, ok := extractWebArticle(page.HTML, page.URL)
+	if !ok {
+		return nil, fmt.Errorf("%w: no article text found", domain.ErrDocumentHasNoText)
+	}
+
+	title := article.Title
+	if title == "" {
+		title = content.Title
+	}
+	if title == "" {
+		title = article.URL
+	}
+	epub, err := webArticleEPUB(content, title)
+	if err != nil {
+		return nil, err
+	}
+
+	doc, err := s.documents.uploadImported(ctx, principal, bytes.NewReader(epub), exportFilename(title)+".epub", &domain.ImportedBook{
+		Title:     title,
+		Tags:      article.Tags,
+		SourceURL: article.URL,
+		Source:    service,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if article.Archived {
+		if err := s.repo.SetArchived(ctx, principal, doc.ID, true); err != nil {
+			s.logger.Warn("Failed to archive imported article", "doc_id", doc.ID, "error", err)
+		} else {
+			doc.IsArchived = true
+		}
+	}
+	return doc, nil
+}
+
+// parseReadLaterExport reads a Pocket export (the HTML file, or the CSV files
+// zipped or on their own) or an Instapaper CSV export.
+func parseReadLaterExport(data []byte) ([]domain.SavedArticle, error) {
+	if bytes.HasPrefix(data, []byte("PK\x03\x04")) {
+		archive, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+		if err != nil {
+			return nil, fmt.Errorf("%w: not a zip archive", domain.ErrInvalidFile)
+		}
+		var articles []domain.SavedArticle
+		for _, f := range archive.File {
+			if !strings.EqualFold(path.Ext(f.Name), ".csv") {
+				continue
+			}
+			content, err := readZipFile(f, domain.MaxReadLaterExportSize)
+			if err != nil {
+				return nil, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+			}
+			found, err := parseReadLaterCSV(content)
+			if err != nil {
+				return nil, err
+			}
+			articles = append(articles, found...)
+		}
+		if articles == nil {
+			return nil, fmt.Errorf("%w: the archive holds no CSV export", domain.ErrInvalidFile)
+		}
+		return articles, nil
+	}
+	if bytes.HasPrefix(bytes.TrimSpace(data), []byte("<")) {
+		return parsePocketHTML(data), nil
+	}
+	return parseReadLaterCSV(data)
+}
+
+// parsePocketHTML reads Pocket's HTML export: a list of links under an "Unread" and
+// a "Read Archive" heading, with the tags and time added as attributes.
+func parsePocketHTML(data []byte) []domain.SavedArticle {
+	var (
+		articles []domain.SavedArticle
+		heading  *strings.Builder
+		link     *domain.SavedArticle
+		title    strings.Builder
+		archived bool
+	)
+	dec := newHTMLDecoder(data)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "h1", "h2":
+				heading = &strings.Builder{}
+			case "a":
+				link = &domain.SavedArticle{
+					URL:      epubAttr(t, "href"),
+					Tags:     splitTags(epubAttr(t, "tags")),
+					AddedAt:  unixTime(epubAttr(t, "time_added")),
+					Archived: archived,
+				}
+				title.Reset()
+			}
+		case xml.EndElement:
+			switch strings.ToLower(t.Name.Local) {
+			case "h1", "h2":
+				if heading != nil {
+					archived = strings.Contains(strings.ToLower(heading.String()), "archive")
+					heading = nil
+				}
+			case "a":
+				if link != nil {
+					link.Title = strings.Join(strings.Fields(title.String()), " ")
+					if validArticleURL(link.URL) {
+						articles = append(articles, *link)
+					}
+					link = nil
+				}
+			}
+		case xml.CharData:
+			if heading != nil {
+				heading.Write(t)
+			}
+			if link != nil {
+				title.Write(t)
+			}
+		}
+	}
+	return articles
+}
+
+// parseReadLaterCSV reads a CSV export by its header. Pocket writes title, url,
+// time_added, tags ("|"-separated) and status ("unread" or "archive"); Instapaper
+// writes URL, Title, Selection, Folder, Timestamp and, in newer exports, Tags as a
+// JSON list. Instapaper folders other than Unread, Starred and Archive become tags.
+func parseReadLaterCSV(data []byte) ([]domain.SavedArticle, error) {
+	r := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, []byte("\uFEFF"))))
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("%w: not a CSV export", domain.ErrInvalidFile)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	if _, ok := columns["url"]; !ok {
+		return nil, fmt.Errorf("%w: the export has no url column", domain.ErrInvalidFile)
+	}
+	field := func(record []string, names ...string) string {
+		for _, name := range names {
+			if i, ok := columns[name]; ok && i < len(record) {
+				return strings.TrimSpace(record[i])
+			}
+		}
+		return ""
+	}
+
+	var articles []domain.SavedArticle
+	for {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", domain.ErrInvalidFile, err)
+		}
+		article := domain.SavedArticle{
+			URL:      field(record, "url"),
+			Title:    field(record, "title"),
+			Tags:     splitTags(field(record, "tags")),
+			AddedAt:  unixTime(field(record, "time_added", "timestamp")),
+			Archived: strings.EqualFold(field(record, "status"), "archive"),
+		}
+		switch folder := field(record, "folder"); {
+		case strings.EqualFold(folder, "archive"):
+			article.Archived = true
+		case folder != "" && !strings.EqualFold(folder, "unread") && !strings.EqualFold(folder, "starred"):
+			if !slices.Contains(article.Tags, folder) {
+				article.Tags = append(article.Tags, folder)
+			}
+		}
+		if validArticleURL(article.URL) {
+			articles = append(articles, article)
+		}
+	}
+	return articles, nil
+}
+
+// splitTags reads a JSON list of tags or tags separated by "|" or ",".
+func splitTags(value string) []string {
+	var tags []string
+	if strings.HasPrefix(value, "[") && json.Unmarshal([]byte(value), &tags) == nil {
+		return compactTags(tags)
+	}
+	return compactTags(strings.FieldsFunc(value, func(r rune) bool { return r == '|' || r == ',' }))
+}
+
+func compactTags(tags []string) []string {
+	var out []string
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" && !slices.Contains(out, tag) {
+			out = append(out, tag)
+		}
+	}
+	return out
+}
+
+func unixTime(value string) time.Time {
+	seconds, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seconds <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(seconds, 0).UTC()
+}
+
+func validArticleURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
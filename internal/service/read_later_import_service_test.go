@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockWebPageFetcher struct {
+	pages map[string]string
+}
+
+func (m *mockWebPageFetcher) Fetch(ctx context.Context, url string) (*domain.WebPage, error) {
+	page, ok := m.pages[url]
+	if !ok {
+		return nil, fmt.Errorf("status 404")
+	}
+	return &domain.WebPage{URL: url, HTML: []byte(page)}, nil
+}
+
+const testPocketHTML = `<!DOCTYPE html>
+<html><head><title>Pocket Export</title></head><body>
+<h1>Unread</h1>
+<ul>
+<li><a href="https://example.com/slow-reading" time_added="1700000000" tags="essays,reading">Slow Reading</a></li>
+<li><a href="javascript:alert(1)" time_added="1700000001" tags="">Not an article</a></li>
+</ul>
+<h1>Read Archive</h1>
+<ul>
+<li><a href="https://example.com/gone" time_added="1600000000" tags="">Gone</a></li>
+</ul>
+</body></html>`
+
+func TestParseReadLaterExport(t *testing.T) {
+	articles, err := parseReadLaterExport([]byte(testPocketHTML))
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(articles) != 2 {
+		t.Fatalf("expected two articles, got %+v", articles)
+	}
+	first := articles[0]
+	if first.Title != "Slow Reading" || first.Archived || strings.Join(first.Tags, ",") != "essays,reading" || first.AddedAt.Unix() != 1700000000 {
+		t.Fatalf("unexpected article %+v", first)
+	}
+	if !articles[1].Archived {
+		t.Fatalf("expected the second article to be archived, got %+v", articles[1])
+	}
+
+	pocketCSV := "title,url,time_added,tags,status\nSlow Reading,https://example.com/slow-reading,1700000000,essays|reading,archive\n"
+	articles, err = parseReadLaterCSV([]byte(pocketCSV))
+	if err != nil || len(articles) != 1 || !articles[0].Archived || len(articles[0].Tags) != 2 {
+		t.Fatalf("unexpected Pocket CSV articles %+v (%v)", articles, err)
+	}
+
+	instapaperCSV := "URL,Title,Selection,Folder,Timestamp,Tags\n" +
+		"https://example.com/a,A,,Unread,1700000000,\"[\"\"essays\"\"]\"\n" +
+		"https://example.com/b,B,,Archive,1700000000,[]\n" +
+		"https://example.com/c,C,,Longreads,1700000000,\n"
+	articles, err = parseReadLaterCSV([]byte(instapaperCSV))
+	if err != nil || len(articles) != 3 {
+		t.Fatalf("unexpected Instapaper CSV articles %+v (%v)", articles, err)
+	}
+	if strings.Join(articles[0].Tags, ",") != "essays" || articles[0].Archived {
+		t.Fatalf("unexpected first article %+v", articles[0])
+	}
+	if !articles[1].Archived || strings.Join(articles[2].Tags, ",") != "Longreads" {
+		t.Fatalf("expected the Archive folder to archive and other folders to tag, got %+v", articles[1:])
+	}
+
+	if _, err := parseReadLaterCSV([]byte("title,added\nA,1\n")); !errors.Is(err, domain.ErrInvalidFile) {
+		t.Fatalf("expected an invalid file error without a url column, got %v", err)
+	}
+}
+
+func TestReadLaterImportService_Import(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	fetcher := &mockWebPageFetcher{pages: map[string]string{
+		"https://example.com/slow-reading": `<html><head><title>Slow Reading | Example</title></head><body>
+<nav><a href="/">Home</a></nav>
+<article><h1>Slow Reading</h1><p>Read less, <a href="/more">but better</a>.</p><script>track()</script></article>
+</body></html>`,
+	}}
+	s := NewReadLaterImportService(documents, repo, fetcher, NewImportJobTracker(), logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.Import(ctx, principal, "readability", strings.NewReader(testPocketHTML)); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error for an unknown service, got %v", err)
+	}
+
+	job, err := s.Import(ctx, principal, domain.ReadLaterPocket, strings.NewReader(testPocketHTML))
+	if err != nil {
+		t.Fatalf("import failed: %v", err)
+	}
+	if job.Provider != domain.ReadLaterPocket || len(job.Files) != 2 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	tracker := s.(*ReadLaterImportService).jobs
+	deadline := time.Now().Add(5 * time.Second)
+	for job.Status != domain.ImportStatusDone {
+		if time.Now().After(deadline) {
+			t.Fatalf("import did not finish: %+v", job)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if job, err = tracker.get(principal, job.ID); err != nil {
+			t.Fatalf("get job failed: %v", err)
+		}
+	}
+	// The archived article's page is gone; the other still imports.
+	if job.Completed != 1 || job.Failed != 1 {
+		t.Fatalf("expected one imported and one failed article, got %+v", job)
+	}
+
+	doc := repo.documents[job.Files[0].DocumentID]
+	if doc == nil || doc.Title != "Slow Reading" || doc.Metadata.Format != "epub" {
+		t.Fatalf("unexpected document %+v", doc)
+	}
+	if doc.Metadata.SourceURL != "https://example.com/slow-reading" || doc.Metadata.Source != domain.ReadLaterPocket {
+		t.Fatalf("expected the original URL and source to be kept, got %+v", doc.Metadata)
+	}
+	if doc.Tag == nil || *doc.Tag != "essays" || strings.Join(doc.Metadata.SourceTags, ",") != "essays,reading" || len(repo.tags["user1"]) != 2 {
+		t.Fatalf("expected the tags to be created and kept, got %v, %v and %v", doc.Tag, doc.Metadata.SourceTags, repo.tags["user1"])
+	}
+
+	// Importing the same export again only retries the article that failed.
+	retry := NewReadLaterImportService(documents, repo, &mockWebPageFetcher{}, NewImportJobTracker(), NewMockLogger())
+	job, err = retry.Import(ctx, principal, domain.ReadLaterPocket, strings.NewReader(testPocketHTML))
+	if err != nil || len(job.Files) != 1 || job.Files[0].FileID != "https://example.com/gone" {
+		t.Fatalf("expected only the failed article to be retried, got %+v (%v)", job, err)
+	}
+}
+
+func TestExtractWebArticle(t *testing.T) {
+	page := `<html><head><title> A  Title </title><style>p{}</style></head><body>
+<header>Site</header>
+<main><p>First <em>paragraph<p>Second, <a href="/x" onclick="bad()">linked</a> <img src="a.png"></main>
+<footer>Copyright</footer></body></html>`
+
+	article, ok := extractWebArticle([]byte(page), "https://example.com/post")
+	if !ok {
+		t.Fatal("expected an article")
+	}
+	body := string(article.Body)
+	if article.Title != "A Title" {
+		t.Fatalf("unexpected title %q", article.Title)
+	}
+	if strings.Contains(body, "Site") || strings.Contains(body, "Copyright") || strings.Contains(body, "img") || strings.Contains(body, "onclick") {
+		t.Fatalf("expected page furniture to be dropped, got %s", body)
+	}
+	if !strings.Contains(body, `<a href="https://example.com/x">linked</a>`) {
+		t.Fatalf("expected an absolute link, got %s", body)
+	}
+	if strings.Count(body, "<p>") != strings.Count(body, "</p>") {
+		t.Fatalf("expected balanced tags, got %s", body)
+	}
+
+	if _, ok := extractWebArticle([]byte("<html><body><script>x()</script></body></html>"), ""); ok {
+		t.Fatal("expected no article from a page without text")
+	}
+}
@@ -0,0 +1,141 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// Recommendation weights. A finished document that shares an author counts more
+// than one that shares a tag, and books finished recently count more than old ones.
+const (
+	sameAuthorWeight    = 2.0
+	sameTagWeight       = 1.0
+	inProgressWeight    = 1.5
+	recentFinishWindow  = 30 * 24 * time.Hour
+	staleFinishedFactor = 0.5
+)
+
+type RecommendationService struct {
+	docRepo   domain.DocumentRepository
+	prefsRepo domain.UserPreferencesRepository
+	logger    domain.Logger
+	now       func() time.Time
+}
+
+func NewRecommendationService(
+	docRepo domain.DocumentRepository,
+	prefsRepo domain.UserPreferencesRepository,
+	logger domain.Logger,
+) domain.RecommendationService {
+	return &RecommendationService{
+		docRepo:   docRepo,
+		prefsRepo: prefsRepo,
+		logger:    logger,
+		now:       time.Now,
+	}
+}
+
+// Recommend scores every unfinished document against the documents the user has
+// finished (shared author or tag, weighted by how recently they were finished) and
+// favours books already in progress. Quarantined documents are never suggested, and
+// archived ones are not either, though finishing them still counts.
+func (s *RecommendationService) Recommend(ctx context.Context, principal domain.Principal, limit int) ([]*domain.Recommendation, error) {
+	documents, err := s.docRepo.GetByUserID(ctx, principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list documents: %w", err)
+	}
+	positions, err := s.prefsRepo.GetAllReadingPositions(ctx, principal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reading positions: %w", err)
+	}
+
+	now := s.now()
+	var finished, candidates []*domain.Document
+	for _, doc := range documents {
+		if doc.IsQuarantined() {
+			continue
+		}
+		if pos := positions[doc.ID]; pos != nil && pos.Progress >= domain.FinishedProgress {
+			finished = append(finished, doc)
+		} else if !doc.IsArchived {
+			candidates = append(candidates, doc)
+		}
+	}
+
+	recommendations := make([]*domain.Recommendation, 0, len(candidates))
+	for _, doc := range candidates {
+		rec := &domain.Recommendation{Document: doc}
+
+		if pos := positions[doc.ID]; pos != nil && pos.Progress > 0 {
+			rec.Score += inProgressWeight
+			rec.Reasons = append(rec.Reasons, fmt.Sprintf("You are %d%% through it", int(pos.Progress*100)))
+		}
+
+		for _, read := range finished {
+			weight := 1.0
+			finishedAt := positions[read.ID].UpdatedAt
+			if now.Sub(finishedAt) > recentFinishWindow {
+				weight = staleFinishedFactor
+			}
+			when := finishedAgo(now, finishedAt)
+
+			if author := documentAuthor(doc); author != "" && strings.EqualFold(author, documentAuthor(read)) {
+				rec.Score += sameAuthorWeight * weight
+				rec.Reasons = append(rec.Reasons, fmt.Sprintf("Same author as %q, which you finished %s", read.Title, when))
+			}
+			if doc.Tag != nil && read.Tag != nil && *doc.Tag != "" && strings.EqualFold(*doc.Tag, *read.Tag) {
+				rec.Score += sameTagWeight * weight
+				rec.Reasons = append(rec.Reasons, fmt.Sprintf("Tagged %q like %q, which you finished %s", *doc.Tag, read.Title, when))
+			}
+		}
+
+		if len(rec.Reasons) == 0 {
+			rec.Reasons = []string{"Waiting in your library"}
+		}
+		recommendations = append(recommendations, rec)
+	}
+
+	// Best score first; ties go to the most recently added document.
+	sort.SliceStable(recommendations, func(i, j int) bool {
+		if recommendations[i].Score != recommendations[j].Score {
+			return recommendations[i].Score > recommendations[j].Score
+		}
+		return recommendations[i].Document.CreatedAt.After(recommendations[j].Document.CreatedAt)
+	})
+	if limit > 0 && len(recommendations) > limit {
+		recommendations = recommendations[:limit]
+	}
+	return recommendations, nil
+}
+
+// documentAuthor prefers the author set on the document over the one read from the file.
+func documentAuthor(doc *domain.Document) string {
+	if doc.Author != nil && strings.TrimSpace(*doc.Author) != "" {
+		return strings.TrimSpace(*doc.Author)
+	}
+	return strings.TrimSpace(doc.Metadata.OriginalAuthor)
+}
+
+// finishedAgo describes how long ago t was, e.g. "yesterday" or "3 weeks ago".
+func finishedAgo(now, t time.Time) string {
+	days := int(now.Sub(t).Hours() / 24)
+	switch {
+	case days <= 0:
+		return "today"
+	case days == 1:
+		return "yesterday"
+	case days < 7:
+		return fmt.Sprintf("%d days ago", days)
+	case days < 14:
+		return "last week"
+	case days < 60:
+		return fmt.Sprintf("%d weeks ago", days/7)
+	default:
+		return fmt.Sprintf("%d months ago", days/30)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestRecommendationService_Recommend(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	principal := domain.Principal{UserID: "user-1"}
+	author := "Ursula K. Le Guin"
+	otherAuthor := "Iain M. Banks"
+	scifi := "sci-fi"
+
+	docRepo := NewMockDocumentRepository()
+	add := func(id, title string, author *string, tag *string, createdAt time.Time) *domain.Document {
+		doc := &domain.Document{ID: id, UserID: principal.UserID, Title: title, Author: author, Tag: tag, CreatedAt: createdAt}
+		docRepo.documents[id] = doc
+		return doc
+	}
+	add("read", "The Dispossessed", &author, &scifi, now.AddDate(0, -6, 0))
+	add("same-author", "The Lathe of Heaven", &author, nil, now.AddDate(0, -1, 0))
+	add("same-tag", "Excession", &otherAuthor, &scifi, now.AddDate(0, -1, 0))
+	add("in-progress", "Middlemarch", nil, nil, now.AddDate(0, -2, 0))
+	add("untouched", "Moby-Dick", nil, nil, now.AddDate(0, -3, 0))
+	add("quarantined", "Suspicious", &author, &scifi, now).Metadata.Quarantine = &domain.Quarantine{Reason: "malware"}
+	add("archived", "The Word for World Is Forest", &author, &scifi, now).IsArchived = true
+
+	prefsRepo := newMockUserPreferencesRepo()
+	prefsRepo.positions[principal.UserID] = map[string]*domain.ReadingPosition{
+		"read":        {DocumentID: "read", Progress: 1, UpdatedAt: now.AddDate(0, 0, -3)},
+		"in-progress": {DocumentID: "in-progress", Progress: 0.4, UpdatedAt: now},
+	}
+
+	s := NewRecommendationService(docRepo, prefsRepo, NewMockLogger()).(*RecommendationService)
+	s.now = func() time.Time { return now }
+
+	recs, err := s.Recommend(context.Background(), principal, 0)
+	if err != nil {
+		t.Fatalf("Recommend: %v", err)
+	}
+
+	var ids []string
+	for _, rec := range recs {
+		ids = append(ids, rec.Document.ID)
+	}
+	if got, want := strings.Join(ids, ","), "same-author,in-progress,same-tag,untouched"; got != want {
+		t.Fatalf("expected order %s, got %s", want, got)
+	}
+	if reason := recs[0].Reasons[0]; reason != `Same author as "The Dispossessed", which you finished 3 days ago` {
+		t.Fatalf("unexpected author reason: %q", reason)
+	}
+	if reason := recs[1].Reasons[0]; reason != "You are 40% through it" {
+		t.Fatalf("unexpected progress reason: %q", reason)
+	}
+	if reason := recs[3].Reasons[0]; reason != "Waiting in your library" {
+		t.Fatalf("unexpected fallback reason: %q", reason)
+	}
+
+	recs, err = s.Recommend(context.Background(), principal, 2)
+	if err != nil {
+		t.Fatalf("Recommend: %v", err)
+	}
+	if len(recs) != 2 {
+		t.Fatalf("expected the limit to apply, got %d recommendations", len(recs))
+	}
+}
+
+func TestRecommendationService_StaleFinishWeighsLess(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	principal := domain.Principal{UserID: "user-1"}
+	leGuin := "Ursula K. Le Guin"
+	kay := "Guy Gavriel Kay"
+
+	docRepo := NewMockDocumentRepository()
+	docRepo.documents["old"] = &domain.Document{ID: "old", UserID: principal.UserID, Title: "A Wizard of Earthsea", Author: &leGuin}
+	docRepo.documents["recent"] = &domain.Document{ID: "recent", UserID: principal.UserID, Title: "Tigana", Author: &kay}
+	docRepo.documents["by-le-guin"] = &domain.Document{ID: "by-le-guin", UserID: principal.UserID, Title: "Tehanu", Author: &leGuin}
+	docRepo.documents["by-kay"] = &domain.Document{ID: "by-kay", UserID: principal.UserID, Title: "The Lions of Al-Rassan", Author: &kay}
+
+	prefsRepo := newMockUserPreferencesRepo()
+	prefsRepo.positions[principal.UserID] = map[string]*domain.ReadingPosition{
+		"old":    {Progress: 0.97, UpdatedAt: now.AddDate(-1, 0, 0)},
+		"recent": {Progress: 1, UpdatedAt: now.AddDate(0, 0, -1)},
+	}
+
+	s := NewRecommendationService(docRepo, prefsRepo, NewMockLogger()).(*RecommendationService)
+	s.now = func() time.Time { return now }
+
+	recs, err := s.Recommend(context.Background(), principal, 0)
+	if err != nil {
+		t.Fatalf("Recommend: %v", err)
+	}
+	if len(recs) != 2 || recs[0].Document.ID != "by-kay" {
+		t.Fatalf("expected the match on the recently finished book first, got %+v", recs)
+	}
+	if recs[0].Score != sameAuthorWeight || recs[1].Score != sameAuthorWeight*staleFinishedFactor {
+		t.Fatalf("unexpected scores %v and %v", recs[0].Score, recs[1].Score)
+	}
+	if reason := recs[1].Reasons[0]; !strings.HasSuffix(reason, "which you finished 12 months ago") {
+		t.Fatalf("unexpected reason: %q", reason)
+	}
+}
@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"net/url"
+	"strings"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type savedSearchService struct {
+	repo      domain.SavedSearchRepository
+	documents domain.DocumentService
+	logger    domain.Logger
+}
+
+func NewSavedSearchService(
+	repo domain.SavedSearchRepository,
+	documents domain.DocumentService,
+	logger domain.Logger,
+) domain.SavedSearchService {
+	return &savedSearchService{
+		repo:      repo,
+		documents: documents,
+		logger:    logger,
+	}
+}
+
+// ListSavedSearches returns the user's saved searches by name
+func (s *savedSearchService) ListSavedSearches(ctx context.Context, principal domain.Principal) ([]*domain.SavedSearch, error) {
+	return s.repo.ListByUser(ctx, principal)
+}
+
+// GetSavedSearch returns one of the user's saved searches
+func (s *savedSearchService) GetSavedSearch(ctx context.Context, principal domain.Principal, searchID string) (*domain.SavedSearch, error) {
+	return s.repo.Get(ctx, principal, searchID)
+}
+
+// CreateSavedSearch saves a new search
+func (s *savedSearchService) CreateSavedSearch(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+
+	saved, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if len(saved) >= domain.MaxSavedSearchesPerUser {
+		return nil, domain.ErrSavedSearchLimitReached
+	}
+	if savedSearchNameTaken(saved, search.Name, "") {
+		return nil, domain.ErrSavedSearchExists
+	}
+
+	search.UserID = principal.UserID
+	created, err := s.repo.Create(ctx, principal, search)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Saved search created", "search_id", created.ID, "user_id", principal.UserID)
+	return created, nil
+}
+
+// UpdateSavedSearch replaces a saved search
+func (s *savedSearchService) UpdateSavedSearch(ctx context.Context, principal domain.Principal, searchID string, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	if err := search.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.Get(ctx, principal, searchID)
+	if err != nil {
+		return nil, err
+	}
+	if search.Name != existing.Name {
+		saved, err := s.repo.ListByUser(ctx, principal)
+		if err != nil {
+			return nil, err
+		}
+		if savedSearchNameTaken(saved, search.Name, searchID) {
+			return nil, domain.ErrSavedSearchExists
+		}
+	}
+
+	search.ID = searchID
+	search.UserID = principal.UserID
+	return s.repo.Update(ctx, principal, search)
+}
+
+// DeleteSavedSearch removes a saved search
+func (s *savedSearchService) DeleteSavedSearch(ctx context.Context, principal domain.Principal, searchID string) error {
+	if _, err := s.repo.Get(ctx, principal, searchID); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, principal, searchID); err != nil {
+		return err
+	}
+
+	s.logger.Info("Saved search deleted", "search_id", searchID, "user_id", principal.UserID)
+	return nil
+}
+
+// CollectionDocuments runs a saved search against the library as it is now. Matches
+// are taken from the library listing rather than the search results, so they carry
+// the listing's fields and leave out the extracted text; search snippets are kept.
+func (s *savedSearchService) CollectionDocuments(ctx context.Context, principal domain.Principal, searchID string, params url.Values) ([]*domain.Document, error) {
+	search, err := s.repo.Get(ctx, principal, searchID)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := search.LibraryQuery(params)
+	if err != nil {
+		return nil, err
+	}
+
+	documents, err := s.documents.GetDocumentsByUserID(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(search.Query) != "" {
+		results, err := s.documents.SearchDocuments(ctx, principal, search.Query)
+		if err != nil {
+			return nil, err
+		}
+		matches := make(map[string]*domain.Document, len(results))
+		for _, result := range results {
+			matches[result.ID] = result
+		}
+		selected := make([]*domain.Document, 0, len(results))
+		for _, doc := range documents {
+			if match, ok := matches[doc.ID]; ok {
+				doc.Snippets = match.Snippets
+				selected = append(selected, doc)
+			}
+		}
+		documents = selected
+	}
+	return filter.Apply(documents), nil
+}
+
+func savedSearchNameTaken(searches []*domain.SavedSearch, name, exceptID string) bool {
+	for _, search := range searches {
+		if search.Name == name && search.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockSavedSearchRepo struct {
+	searches map[string]*domain.SavedSearch
+	nextID   int
+}
+
+func newMockSavedSearchRepo() *mockSavedSearchRepo {
+	return &mockSavedSearchRepo{searches: make(map[string]*domain.SavedSearch)}
+}
+
+func (m *mockSavedSearchRepo) Create(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	m.nextID++
+	stored := *search
+	stored.ID = fmt.Sprintf("search-%d", m.nextID)
+	stored.UserID = principal.UserID
+	m.searches[stored.ID] = &stored
+	created := stored
+	return &created, nil
+}
+
+func (m *mockSavedSearchRepo) Update(ctx context.Context, principal domain.Principal, search *domain.SavedSearch) (*domain.SavedSearch, error) {
+	if _, ok := m.searches[search.ID]; !ok {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+	stored := *search
+	m.searches[search.ID] = &stored
+	updated := stored
+	return &updated, nil
+}
+
+func (m *mockSavedSearchRepo) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.SavedSearch, error) {
+	var out []*domain.SavedSearch
+	for _, search := range m.searches {
+		if search.UserID == principal.UserID {
+			copied := *search
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockSavedSearchRepo) Get(ctx context.Context, principal domain.Principal, searchID string) (*domain.SavedSearch, error) {
+	search, ok := m.searches[searchID]
+	if !ok || search.UserID != principal.UserID {
+		return nil, domain.ErrSavedSearchNotFound
+	}
+	copied := *search
+	return &copied, nil
+}
+
+func (m *mockSavedSearchRepo) Delete(ctx context.Context, principal domain.Principal, searchID string) error {
+	delete(m.searches, searchID)
+	return nil
+}
+
+func newTestSavedSearchService() (domain.SavedSearchService, *MockDocumentRepository) {
+	docs := NewMockDocumentRepository()
+	logger := NewMockLogger()
+	documents := NewDocumentService(docs, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	return NewSavedSearchService(newMockSavedSearchRepo(), documents, logger), docs
+}
+
+func TestSavedSearchService_CreateSavedSearch_Rejects(t *testing.T) {
+	svc, _ := newTestSavedSearchService()
+	principal := testPrincipal("user-1")
+
+	if _, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{Name: "Go", Query: "go"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{Name: "Go", Query: "golang"}); !errors.Is(err, domain.ErrSavedSearchExists) {
+		t.Fatalf("expected ErrSavedSearchExists, got %v", err)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{Name: "Broken", Query: `"go`}); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected validation error for an unclosed phrase, got %v", err)
+	}
+	if _, err := svc.GetSavedSearch(context.Background(), testPrincipal("user-2"), "search-1"); !errors.Is(err, domain.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound for another user, got %v", err)
+	}
+}
+
+func TestSavedSearchService_UpdateSavedSearch_NameConflict(t *testing.T) {
+	svc, _ := newTestSavedSearchService()
+	principal := testPrincipal("user-1")
+
+	if _, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{Name: "Go", Query: "go"}); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{Name: "Rust", Query: "rust"})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := svc.UpdateSavedSearch(context.Background(), principal, second.ID, &domain.SavedSearch{Name: "Go"}); !errors.Is(err, domain.ErrSavedSearchExists) {
+		t.Fatalf("expected ErrSavedSearchExists, got %v", err)
+	}
+	updated, err := svc.UpdateSavedSearch(context.Background(), principal, second.ID, &domain.SavedSearch{Name: "Rust", Query: "rust OR cargo"})
+	if err != nil || updated.Query != "rust OR cargo" {
+		t.Fatalf("expected the query replaced, got %+v, %v", updated, err)
+	}
+	if err := svc.DeleteSavedSearch(context.Background(), principal, "missing"); !errors.Is(err, domain.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
+
+func TestSavedSearchService_CollectionDocuments(t *testing.T) {
+	svc, docs := newTestSavedSearchService()
+	principal := testPrincipal("user-1")
+	for _, doc := range []*domain.Document{
+		{ID: "doc-1", UserID: "user-1", Title: "The Go Programming Language"},
+		{ID: "doc-2", UserID: "user-1", Title: "Concurrency in Go", IsArchived: true},
+		{ID: "doc-3", UserID: "user-1", Title: "The Rust Book"},
+		{ID: "doc-4", UserID: "user-2", Title: "Go for Beginners"},
+	} {
+		docs.documents[doc.ID] = doc
+	}
+
+	search, err := svc.CreateSavedSearch(context.Background(), principal, &domain.SavedSearch{
+		Name:    "Go books",
+		Query:   "title:go",
+		Filters: domain.SavedSearchFilters{Archived: "all", Sort: "title"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	got, err := svc.CollectionDocuments(context.Background(), principal, search.ID, url.Values{})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "doc-2" || got[1].ID != "doc-1" {
+		t.Fatalf("expected both Go books by title, got %v", documentIDs(got))
+	}
+
+	// The collection follows the library: a new match joins it on the next request,
+	// and the request's own filters apply over the saved ones.
+	docs.documents["doc-5"] = &domain.Document{ID: "doc-5", UserID: "user-1", Title: "Go in Practice"}
+	got, err = svc.CollectionDocuments(context.Background(), principal, search.ID, url.Values{"archived": {"false"}})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "doc-5" || got[1].ID != "doc-1" {
+		t.Fatalf("expected the active Go books, got %v", documentIDs(got))
+	}
+	if len(got[0].Snippets) == 0 {
+		t.Fatalf("expected search snippets on collection documents")
+	}
+
+	if _, err := svc.CollectionDocuments(context.Background(), principal, "missing", url.Values{}); !errors.Is(err, domain.ErrSavedSearchNotFound) {
+		t.Fatalf("expected ErrSavedSearchNotFound, got %v", err)
+	}
+}
+
+func documentIDs(docs []*domain.Document) []string {
+	ids := make([]string, 0, len(docs))
+	for _, doc := range docs {
+		ids = append(ids, doc.ID)
+	}
+	return ids
+}
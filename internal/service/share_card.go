@@ -0,0 +1,212 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// shareCardURLTTL is how long a share card URL stays valid: the longest every blob
+// store can sign.
+const shareCardURLTTL = 7 * 24 * time.Hour
+
+// Share card layout, in pixels. The quote is set in the largest size that fits its
+// box, judging line length by an average character width.
+const (
+	shareCardSize       = 1080
+	shareCardMargin     = 96
+	shareCardQuoteTop   = 220
+	shareCardQuoteBox   = 600
+	shareCardCharWidth  = 0.5 // of the font size
+	shareCardLineHeight = 1.35
+)
+
+var shareCardFontSizes = []int{56, 48, 40, 34, 28}
+
+// ShareCardService renders a highlight's quote with its book's title into a PNG and
+// stores it next to the document's other assets.
+type ShareCardService struct {
+	documents  *DocumentService
+	highlights domain.HighlightRepository
+	logger     domain.Logger
+}
+
+func NewShareCardService(documents *DocumentService, highlights domain.HighlightRepository, logger domain.Logger) domain.ShareCardService {
+	return &ShareCardService{
+		documents:  documents,
+		highlights: highlights,
+		logger:     logger,
+	}
+}
+
+var shareCardSVG = template.Must(template.New("share-card").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Size}}" height="{{.Size}}" viewBox="0 0 {{.Size}} {{.Size}}">
+<rect width="{{.Size}}" height="{{.Size}}" fill="#F6F1E7"/>
+<text x="{{.Margin}}" y="{{.MarkY}}" font-family="serif" font-size="160" fill="#C9B99A">“</text>
+<text font-family="serif" font-size="{{.FontSize}}" fill="#2B2B2B">{{range $i, $line := .Lines}}<tspan x="{{$.Margin}}" y="{{index $.LineY $i}}">{{$line}}</tspan>{{end}}</text>
+<rect x="{{.Margin}}" y="{{.RuleY}}" width="64" height="4" fill="#C9B99A"/>
+<text x="{{.Margin}}" y="{{.TitleY}}" font-family="sans-serif" font-size="34" font-weight="bold" fill="#2B2B2B">{{.Title}}</text>
+{{if .Author}}<text x="{{.Margin}}" y="{{.AuthorY}}" font-family="sans-serif" font-size="30" fill="#7A6F5F">{{.Author}}</text>{{end}}
+</svg>`))
+
+type shareCardLayout struct {
+	Size, Margin, MarkY    int
+	FontSize               int
+	Lines                  []string
+	LineY                  []int
+	RuleY, TitleY, AuthorY int
+	Title, Author          string
+}
+
+// layoutShareCard sets the quote in the largest font size that fits the quote box,
+// cutting it short with an ellipsis at the smallest size if it is too long.
+func layoutShareCard(quote, title, author string) shareCardLayout {
+	width := shareCardSize - 2*shareCardMargin
+	layout := shareCardLayout{
+		Size:    shareCardSize,
+		Margin:  shareCardMargin,
+		MarkY:   shareCardQuoteTop - 20,
+		RuleY:   shareCardSize - 250,
+		TitleY:  shareCardSize - 180,
+		AuthorY: shareCardSize - 132,
+		Title:   truncateRunes(title, int(float64(width)/(34*0.55))),
+		Author:  truncateRunes(author, int(float64(width)/(30*0.5))),
+	}
+	quote = strings.Join(strings.Fields(quote), " ")
+	for i, size := range shareCardFontSizes {
+		perLine := int(float64(width) / (float64(size) * shareCardCharWidth))
+		lineHeight := int(float64(size) * shareCardLineHeight)
+		maxLines := shareCardQuoteBox / lineHeight
+		lines := wrapWords(quote, perLine)
+		last := i == len(shareCardFontSizes)-1
+		if len(lines) > maxLines && !last {
+			continue
+		}
+		if len(lines) > maxLines {
+			lines = lines[:maxLines]
+			lines[maxLines-1] = truncateRunes(lines[maxLines-1]+" …", perLine)
+		}
+		layout.FontSize = size
+		layout.Lines = lines
+		for j := range lines {
+			layout.LineY = append(layout.LineY, shareCardQuoteTop+size+j*lineHeight)
+		}
+		break
+	}
+	return layout
+}
+
+// wrapWords breaks text into lines of at most width characters, splitting words
+// longer than a line.
+func wrapWords(text string, width int) []string {
+	var lines []string
+	var line []rune
+	for _, word := range strings.Fields(text) {
+		w := []rune(word)
+		for len(w) > width {
+			if len(line) > 0 {
+				lines = append(lines, string(line))
+				line = nil
+			}
+			lines = append(lines, string(w[:width]))
+			w = w[width:]
+		}
+		if len(line) > 0 && len(line)+1+len(w) > width {
+			lines = append(lines, string(line))
+			line = nil
+		}
+		if len(line) > 0 {
+			line = append(line, ' ')
+		}
+		line = append(line, w...)
+	}
+	if len(line) > 0 {
+		lines = append(lines, string(line))
+	}
+	return lines
+}
+
+// truncateRunes shortens s to at most n characters, ending in an ellipsis when cut.
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	r := []rune(s)
+	return strings.TrimSpace(string(r[:n-1])) + "…"
+}
+
+// renderShareCard draws the card as SVG and rasterizes it with MuPDF.
+func renderShareCard(layout shareCardLayout) ([]byte, error) {
+	var svg bytes.Buffer
+	if err := shareCardSVG.Execute(&svg, layout); err != nil {
+		return nil, err
+	}
+	doc, err := fitz.NewFromMemory(svg.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open share card: %w", err)
+	}
+	defer doc.Close()
+	return doc.ImagePNG(0, 72)
+}
+
+// CreateShareCard renders the card and returns a signed URL for it. Cards are stored
+// under a hash of their content, so an unchanged card is not rendered or uploaded
+// again.
+func (s *ShareCardService) CreateShareCard(ctx context.Context, principal domain.Principal, highlightID string) (*domain.ShareCard, error) {
+	highlight, err := s.highlights.Get(ctx, principal, highlightID)
+	if err != nil {
+		return nil, err
+	}
+	doc, err := s.documents.repo.GetByID(ctx, principal, highlight.DocumentID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.documents.authz.AuthorizeDocument(ctx, principal, doc, domain.PermissionRead); err != nil {
+		return nil, err
+	}
+
+	author := ""
+	if doc.Author != nil {
+		author = *doc.Author
+	}
+	layout := layoutShareCard(highlight.Quote, doc.Title, author)
+	key := sha256.Sum256(fmt.Appendf(nil, "%d\x00%s\x00%s\x00%s", layout.FontSize, strings.Join(layout.Lines, "\n"), layout.Title, layout.Author))
+	path := fmt.Sprintf("%s/%s/share/%s-%s.png", principal.UserID, doc.ID, highlight.ID, hex.EncodeToString(key[:6]))
+
+	exists, err := s.documents.storage.Exists(ctx, path, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		png, err := renderShareCard(layout)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.documents.storage.Upload(ctx, path, bytes.NewReader(png), "image/png", principal.Token); err != nil {
+			return nil, err
+		}
+		s.logger.Info("Share card rendered", "user_id", principal.UserID, "highlight_id", highlight.ID, "bytes", len(png))
+	}
+
+	url, err := s.documents.storage.SignedURL(ctx, path, shareCardURLTTL, principal.Token)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.ShareCard{
+		HighlightID: highlight.ID,
+		URL:         url,
+		ContentType: "image/png",
+		Width:       shareCardSize,
+		Height:      shareCardSize,
+		ExpiresAt:   time.Now().UTC().Add(shareCardURLTTL),
+	}, nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"image/png"
+	"strings"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestLayoutShareCard(t *testing.T) {
+	short := layoutShareCard("Call me Ishmael.", "Moby-Dick", "Herman Melville")
+	if short.FontSize != shareCardFontSizes[0] || len(short.Lines) != 1 {
+		t.Fatalf("expected a short quote in the largest size, got %d %q", short.FontSize, short.Lines)
+	}
+
+	long := layoutShareCard(strings.Repeat("It is a way I have of driving off the spleen. ", 8), "Moby-Dick", "")
+	if long.FontSize >= short.FontSize || len(long.Lines) != len(long.LineY) {
+		t.Fatalf("expected a long quote in a smaller size, got %d", long.FontSize)
+	}
+
+	huge := layoutShareCard(strings.Repeat("whale ", 2000), strings.Repeat("Title ", 50), "")
+	last := huge.Lines[len(huge.Lines)-1]
+	if huge.FontSize != shareCardFontSizes[len(shareCardFontSizes)-1] || !strings.HasSuffix(last, "…") || !strings.HasSuffix(huge.Title, "…") {
+		t.Fatalf("expected a cut quote and title, got %d %q %q", huge.FontSize, last, huge.Title)
+	}
+
+	if lines := wrapWords("a "+strings.Repeat("x", 25)+" b", 10); len(lines) != 4 || lines[1] != strings.Repeat("x", 10) || lines[3] != "xxxxx b" {
+		t.Fatalf("unexpected wrapping %q", lines)
+	}
+}
+
+func TestShareCardService_CreateShareCard(t *testing.T) {
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	storage := NewMockStorageService()
+	documents := NewDocumentService(repo, nil, nil, storage, NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	highlights := &mockHighlightRepo{highlights: []*domain.Highlight{{ID: "h1", DocumentID: "book", Quote: "Call me <Ishmael> & friends."}}}
+	s := NewShareCardService(documents, highlights, logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	author := "Herman Melville"
+	repo.documents["book"] = &domain.Document{ID: "book", UserID: "user1", Title: "Moby-Dick", Author: &author}
+
+	card, err := s.CreateShareCard(ctx, principal, "h1")
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if len(storage.files) != 1 || !strings.HasPrefix(card.URL, "https://storage.test/sign/user1/book/share/h1-") {
+		t.Fatalf("unexpected card %+v", card)
+	}
+	for _, data := range storage.files {
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("expected a PNG: %v", err)
+		}
+		if b := img.Bounds(); b.Dx() != shareCardSize || b.Dy() != shareCardSize {
+			t.Fatalf("unexpected card size %v", b)
+		}
+	}
+
+	// The same card is not rendered twice; a new title makes a new one.
+	if again, err := s.CreateShareCard(ctx, principal, "h1"); err != nil || again.URL != card.URL {
+		t.Fatalf("expected the stored card, got %+v (%v)", again, err)
+	}
+	repo.documents["book"].Title = "Moby-Dick; or, The Whale"
+	if renamed, err := s.CreateShareCard(ctx, principal, "h1"); err != nil || renamed.URL == card.URL || len(storage.files) != 2 {
+		t.Fatalf("expected a new card, got %+v (%v)", renamed, err)
+	}
+
+	if _, err := s.CreateShareCard(ctx, principal, "missing"); !errors.Is(err, domain.ErrHighlightNotFound) {
+		t.Fatalf("expected ErrHighlightNotFound, got %v", err)
+	}
+}
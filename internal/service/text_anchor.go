@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// textIndex maps character offsets in a document's text to its blocks. The text is
+// the blocks' content concatenated in order, counted in characters (runes), so an
+// offset stays valid however a client paginates it.
+type textIndex struct {
+	blocks []TextBlock // blocks with text, in reading order
+	starts []int       // offset of each block
+	total  int
+
+	// Built on first use by quote matching.
+	fold *foldedText
+	text []rune
+}
+
+// newTextIndex indexes the text blocks of a document's content. Comics and
+// documents without extracted text have nothing to anchor to.
+func newTextIndex(doc *domain.DocumentData) (*textIndex, error) {
+	if doc.Metadata.Format == fileTypeCBZ.Format {
+		return nil, domain.ErrDocumentHasNoText
+	}
+	var blocks []TextBlock
+	if err := json.Unmarshal(doc.Content, &blocks); err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrDocumentHasNoText, err)
+	}
+
+	index := &textIndex{}
+	for _, block := range blocks {
+		n := utf8.RuneCountInString(block.Content)
+		if n == 0 {
+			continue
+		}
+		index.blocks = append(index.blocks, block)
+		index.starts = append(index.starts, index.total)
+		index.total += n
+	}
+	if index.total == 0 {
+		return nil, domain.ErrDocumentHasNoText
+	}
+	return index, nil
+}
+
+// resolveAnchor resolves a query against a loaded document.
+func resolveAnchor(doc *domain.DocumentData, query domain.AnchorQuery) (*domain.TextAnchor, error) {
+	index, err := newTextIndex(doc)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case query.Locator != nil:
+		return index.fromLocator(query.Locator)
+	case query.CharOffset != nil:
+		return index.fromOffset(*query.CharOffset), nil
+	default:
+		return index.fromProgress(*query.Progress), nil
+	}
+}
+
+// fromOffset resolves an offset, clamped to the text.
+func (ix *textIndex) fromOffset(offset int) *domain.TextAnchor {
+	offset = min(max(offset, 0), ix.total)
+	// The block containing the offset; the end of the text belongs to the last one.
+	i := max(sort.SearchInts(ix.starts, offset+1)-1, 0)
+	return &domain.TextAnchor{
+		CharOffset: offset,
+		CharCount:  ix.total,
+		Progress:   float32(offset) / float32(ix.total),
+		PageNumber: ix.blocks[i].PageNumber,
+		Locator:    ix.locatorAt(i, offset),
+	}
+}
+
+// fromProgress resolves a fraction of the text, clamped to [0, 1].
+func (ix *textIndex) fromProgress(progress float32) *domain.TextAnchor {
+	offset := int(math.Round(float64(min(max(progress, 0), 1)) * float64(ix.total)))
+	return ix.fromOffset(offset)
+}
+
+// locatorAt builds the locator of an offset inside block i. An element's text may
+// be split into several blocks, so the blocks of the same element before it count
+// towards the locator's offset. Blocks extracted without structure have none.
+func (ix *textIndex) locatorAt(i, offset int) *domain.EPUBLocator {
+	block := ix.blocks[i]
+	if block.Spine == "" {
+		return nil
+	}
+	within := offset - ix.starts[i]
+	for j := i - 1; j >= 0 && ix.blocks[j].Spine == block.Spine; j-- {
+		if ix.blocks[j].Path == block.Path {
+			within += ix.blockLen(j)
+		}
+	}
+	return &domain.EPUBLocator{Spine: block.Spine, Path: block.Path, Offset: within}
+}
+
+// fromLocator finds a locator's element in the text. When the element no longer has
+// text of its own, the position moves to the start of the next block in document
+// order, or to the end of the spine document.
+func (ix *textIndex) fromLocator(locator *domain.EPUBLocator) (*domain.TextAnchor, error) {
+	remaining := locator.Offset
+	last := -1
+	next := -1
+	for i, block := range ix.blocks {
+		if block.Spine != locator.Spine {
+			continue
+		}
+		last = i
+		if block.Path == locator.Path {
+			if n := ix.blockLen(i); remaining > n {
+				remaining -= n
+				continue
+			}
+			return ix.fromOffset(ix.starts[i] + remaining), nil
+		}
+		if next < 0 && compareLocatorPaths(block.Path, locator.Path) > 0 {
+			next = i
+		}
+	}
+	switch {
+	case last < 0:
+		return nil, domain.ErrLocatorNotFound
+	case next >= 0:
+		return ix.fromOffset(ix.starts[next]), nil
+	default:
+		return ix.fromOffset(ix.starts[last] + ix.blockLen(last)), nil
+	}
+}
+
+// pageStart returns the offset of a page's first block, or of the next page with
+// text when it has none.
+func (ix *textIndex) pageStart(page int) int {
+	for i, block := range ix.blocks {
+		if block.PageNumber >= page {
+			return ix.starts[i]
+		}
+	}
+	return ix.total
+}
+
+func (ix *textIndex) blockLen(i int) int {
+	if i+1 < len(ix.starts) {
+		return ix.starts[i+1] - ix.starts[i]
+	}
+	return ix.total - ix.starts[i]
+}
+
+// hasLocators reports whether the document was extracted with EPUB structure.
+func (ix *textIndex) hasLocators() bool {
+	return len(ix.blocks) > 0 && ix.blocks[0].Spine != ""
+}
+
+// compareLocatorPaths orders element paths in document order: by step, with an
+// ancestor before its descendants.
+func compareLocatorPaths(a, b string) int {
+	as, bs := strings.Split(a, "/")[1:], strings.Split(b, "/")[1:]
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		x, _ := strconv.Atoi(as[i])
+		y, _ := strconv.Atoi(bs[i])
+		if x != y {
+			return x - y
+		}
+	}
+	return len(as) - len(bs)
+}
+
+// ResolveAnchor converts a character offset, a progress or an EPUB locator into
+// every position unit against the document's current text.
+func (s *DocumentService) ResolveAnchor(ctx context.Context, principal domain.Principal, documentID string, query domain.AnchorQuery) (*domain.TextAnchor, error) {
+	set := 0
+	for _, given := range []bool{query.CharOffset != nil, query.Progress != nil, query.Locator != nil} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		return nil, domain.ValidationErrors{{Field: "char_offset", Message: "exactly one of char_offset, progress and locator is required"}}
+	}
+	if query.Locator != nil {
+		if err := query.Locator.Validate(); err != nil {
+			return nil, err
+		}
+	}
+	doc, err := s.GetDocument(ctx, principal, documentID)
+	if err != nil {
+		return nil, err
+	}
+	return resolveAnchor(doc, query)
+}
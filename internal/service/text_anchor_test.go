@@ -0,0 +1,120 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func newTestAnchorService(t *testing.T) *DocumentService {
+	t.Helper()
+	logger := NewMockLogger()
+	repo := NewMockDocumentRepository()
+	documents := NewDocumentService(repo, nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+
+	// 100 characters over three pages; the image block has no text.
+	content, err := json.Marshal([]TextBlock{
+		{Type: "heading", Content: "Chapter One", PageNumber: 1},
+		{Type: "paragraph", Content: string(make([]rune, 39)), PageNumber: 1},
+		{Type: "image", Src: "cover.png", PageNumber: 2},
+		{Type: "paragraph", Content: "ñ" + string(make([]rune, 29)), PageNumber: 2},
+		{Type: "paragraph", Content: string(make([]rune, 20)), PageNumber: 3},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.documents["doc-1"] = &domain.Document{ID: "doc-1", UserID: "user1", Content: content}
+	repo.documents["comic"] = &domain.Document{ID: "comic", UserID: "user1", Content: json.RawMessage(`[]`),
+		Metadata: domain.DocumentMetadata{Format: fileTypeCBZ.Format}}
+	return documents
+}
+
+func TestDocumentService_ResolveAnchor(t *testing.T) {
+	s := newTestAnchorService(t)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+	offset := func(n int) *int { return &n }
+	progress := func(f float32) *float32 { return &f }
+
+	tests := []struct {
+		name  string
+		query domain.AnchorQuery
+		want  domain.TextAnchor
+	}{
+		{"start", domain.AnchorQuery{CharOffset: offset(0)}, domain.TextAnchor{CharOffset: 0, CharCount: 100, Progress: 0, PageNumber: 1}},
+		{"page boundary", domain.AnchorQuery{CharOffset: offset(50)}, domain.TextAnchor{CharOffset: 50, CharCount: 100, Progress: 0.5, PageNumber: 2}},
+		{"last page", domain.AnchorQuery{CharOffset: offset(85)}, domain.TextAnchor{CharOffset: 85, CharCount: 100, Progress: 0.85, PageNumber: 3}},
+		{"past the end", domain.AnchorQuery{CharOffset: offset(500)}, domain.TextAnchor{CharOffset: 100, CharCount: 100, Progress: 1, PageNumber: 3}},
+		{"negative", domain.AnchorQuery{CharOffset: offset(-3)}, domain.TextAnchor{CharOffset: 0, CharCount: 100, Progress: 0, PageNumber: 1}},
+		{"progress", domain.AnchorQuery{Progress: progress(0.499)}, domain.TextAnchor{CharOffset: 50, CharCount: 100, Progress: 0.5, PageNumber: 2}},
+		{"progress clamped", domain.AnchorQuery{Progress: progress(1.5)}, domain.TextAnchor{CharOffset: 100, CharCount: 100, Progress: 1, PageNumber: 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			anchor, err := s.ResolveAnchor(ctx, principal, "doc-1", tt.query)
+			if err != nil {
+				t.Fatalf("resolve failed: %v", err)
+			}
+			if *anchor != tt.want {
+				t.Fatalf("expected %+v, got %+v", tt.want, *anchor)
+			}
+		})
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := s.ResolveAnchor(ctx, principal, "doc-1", domain.AnchorQuery{}); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error without input, got %v", err)
+	}
+	if _, err := s.ResolveAnchor(ctx, principal, "doc-1", domain.AnchorQuery{CharOffset: offset(1), Progress: progress(0.1)}); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected a validation error with both inputs, got %v", err)
+	}
+	if _, err := s.ResolveAnchor(ctx, principal, "comic", domain.AnchorQuery{CharOffset: offset(1)}); !errors.Is(err, domain.ErrDocumentHasNoText) {
+		t.Fatalf("expected a comic to have no text, got %v", err)
+	}
+	if _, err := s.ResolveAnchor(ctx, testPrincipal("user2"), "doc-1", domain.AnchorQuery{CharOffset: offset(1)}); !errors.Is(err, domain.ErrAccessDenied) {
+		t.Fatalf("expected an access denied error, got %v", err)
+	}
+}
+
+func TestUserPreferencesService_AnchoredPosition(t *testing.T) {
+	repo := newMockUserPreferencesRepo()
+	documents := newTestAnchorService(t)
+	svc := NewUserPreferencesService(repo, documents, NewMockLogger())
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	// The offset wins over the progress computed from the client's pagination.
+	offset := 75
+	if err := svc.UpdateReadingPosition(ctx, principal, "doc-1", &domain.ReadingPosition{Progress: 0.9, PageNumber: 12, CharOffset: &offset}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	stored := repo.positions["user1"]["doc-1"]
+	if stored.Progress != 0.75 || stored.PageNumber != 12 || *stored.CharOffset != 75 {
+		t.Fatalf("unexpected stored position %+v", stored)
+	}
+	if len(repo.samples) != 1 || repo.samples[0].CharOffset == nil || *repo.samples[0].CharOffset != 75 {
+		t.Fatalf("expected the offset in the history, got %+v", repo.samples)
+	}
+
+	// Reprocessing shortens the text; the stored offset is re-resolved on read.
+	content, _ := json.Marshal([]TextBlock{{Type: "paragraph", Content: string(make([]rune, 150)), PageNumber: 1}})
+	documents.repo.(*MockDocumentRepository).documents["doc-1"].Content = content
+	position, err := svc.GetReadingPosition(ctx, principal, "doc-1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if position.Progress != 0.5 {
+		t.Fatalf("expected the progress recomputed against the new text, got %v", position.Progress)
+	}
+
+	// A comic has no text to anchor to, so the offset is dropped.
+	if err := svc.UpdateReadingPosition(ctx, principal, "comic", &domain.ReadingPosition{Progress: 0.4, PageNumber: 4, CharOffset: &offset}); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if stored := repo.positions["user1"]["comic"]; stored.CharOffset != nil || stored.Progress != 0.4 {
+		t.Fatalf("unexpected comic position %+v", stored)
+	}
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type themeService struct {
+	repo      domain.ThemeRepository
+	prefsRepo domain.UserPreferencesRepository
+	logger    domain.Logger
+}
+
+func NewThemeService(
+	repo domain.ThemeRepository,
+	prefsRepo domain.UserPreferencesRepository,
+	logger domain.Logger,
+) domain.ThemeService {
+	return &themeService{
+		repo:      repo,
+		prefsRepo: prefsRepo,
+		logger:    logger,
+	}
+}
+
+// ListThemes returns the presets followed by the user's custom themes
+func (s *themeService) ListThemes(ctx context.Context, principal domain.Principal) ([]*domain.Theme, error) {
+	custom, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	return append(domain.ThemePresets(), custom...), nil
+}
+
+// GetTheme resolves a preset or custom theme by name
+func (s *themeService) GetTheme(ctx context.Context, principal domain.Principal, name string) (*domain.Theme, error) {
+	if preset, ok := domain.ThemePreset(name); ok {
+		return preset, nil
+	}
+	custom, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	for _, theme := range custom {
+		if theme.Name == name {
+			return theme, nil
+		}
+	}
+	return nil, domain.ErrThemeNotFound
+}
+
+// CreateTheme saves a new custom theme
+func (s *themeService) CreateTheme(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+	if _, err := accountPreferences(ctx, s.prefsRepo, principal); err != nil {
+		return nil, err
+	}
+
+	custom, err := s.repo.ListByUser(ctx, principal)
+	if err != nil {
+		return nil, err
+	}
+	if len(custom) >= domain.MaxThemesPerUser {
+		return nil, domain.ErrThemeLimitReached
+	}
+	if nameTaken(custom, theme.Name, "") {
+		return nil, domain.ErrThemeExists
+	}
+
+	theme.UserID = principal.UserID
+	created, err := s.repo.Create(ctx, principal, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Theme created", "theme_id", created.ID, "user_id", principal.UserID)
+	return created, nil
+}
+
+// UpdateTheme replaces a custom theme. Renaming a theme that is in use updates the
+// preference so it keeps pointing at the theme.
+func (s *themeService) UpdateTheme(ctx context.Context, principal domain.Principal, themeID string, theme *domain.Theme) (*domain.Theme, error) {
+	if err := theme.Validate(); err != nil {
+		return nil, err
+	}
+
+	existing, err := s.repo.Get(ctx, principal, themeID)
+	if err != nil {
+		return nil, err
+	}
+	if theme.Name != existing.Name {
+		custom, err := s.repo.ListByUser(ctx, principal)
+		if err != nil {
+			return nil, err
+		}
+		if nameTaken(custom, theme.Name, themeID) {
+			return nil, domain.ErrThemeExists
+		}
+	}
+
+	theme.ID = themeID
+	theme.UserID = principal.UserID
+	updated, err := s.repo.Update(ctx, principal, theme)
+	if err != nil {
+		return nil, err
+	}
+
+	if updated.Name != existing.Name {
+		if err := s.replaceThemePreference(ctx, principal, existing.Name, updated.Name); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
+}
+
+// DeleteTheme removes a custom theme and resets preferences that still reference it
+func (s *themeService) DeleteTheme(ctx context.Context, principal domain.Principal, themeID string) error {
+	theme, err := s.repo.Get(ctx, principal, themeID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, principal, themeID); err != nil {
+		return err
+	}
+	if err := s.replaceThemePreference(ctx, principal, theme.Name, domain.ThemeLight); err != nil {
+		return err
+	}
+
+	s.logger.Info("Theme deleted", "theme_id", themeID, "user_id", principal.UserID)
+	return nil
+}
+
+// replaceThemePreference points the user's theme preference at newName if it was
+// set to oldName. Missing preferences are left alone.
+func (s *themeService) replaceThemePreference(ctx context.Context, principal domain.Principal, oldName, newName string) error {
+	prefs, err := s.prefsRepo.GetPreferences(ctx, principal)
+	if err != nil {
+		s.logger.Warn("Failed to load preferences for theme change", "theme", oldName, "error", err)
+		return nil
+	}
+	if prefs == nil || prefs.Theme != oldName {
+		return nil
+	}
+	prefs.Theme = newName
+	prefs.UpdatedAt = time.Now()
+	return s.prefsRepo.UpdatePreferences(ctx, principal, prefs)
+}
+
+func nameTaken(themes []*domain.Theme, name, exceptID string) bool {
+	for _, theme := range themes {
+		if theme.Name == name && theme.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+type mockThemeRepo struct {
+	themes map[string]*domain.Theme
+	nextID int
+}
+
+func newMockThemeRepo() *mockThemeRepo {
+	return &mockThemeRepo{themes: make(map[string]*domain.Theme)}
+}
+
+func (m *mockThemeRepo) Create(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	m.nextID++
+	stored := *theme
+	stored.ID = "theme-" + string(rune('0'+m.nextID))
+	stored.UserID = principal.UserID
+	m.themes[stored.ID] = &stored
+	created := stored
+	return &created, nil
+}
+
+func (m *mockThemeRepo) Update(ctx context.Context, principal domain.Principal, theme *domain.Theme) (*domain.Theme, error) {
+	if _, ok := m.themes[theme.ID]; !ok {
+		return nil, domain.ErrThemeNotFound
+	}
+	stored := *theme
+	m.themes[theme.ID] = &stored
+	updated := stored
+	return &updated, nil
+}
+
+func (m *mockThemeRepo) ListByUser(ctx context.Context, principal domain.Principal) ([]*domain.Theme, error) {
+	var out []*domain.Theme
+	for _, theme := range m.themes {
+		if theme.UserID == principal.UserID {
+			copied := *theme
+			out = append(out, &copied)
+		}
+	}
+	return out, nil
+}
+
+func (m *mockThemeRepo) Get(ctx context.Context, principal domain.Principal, themeID string) (*domain.Theme, error) {
+	theme, ok := m.themes[themeID]
+	if !ok || theme.UserID != principal.UserID {
+		return nil, domain.ErrThemeNotFound
+	}
+	copied := *theme
+	return &copied, nil
+}
+
+func (m *mockThemeRepo) Delete(ctx context.Context, principal domain.Principal, themeID string) error {
+	delete(m.themes, themeID)
+	return nil
+}
+
+func testTheme(name string) *domain.Theme {
+	return &domain.Theme{Name: name, BackgroundColor: "#011627", TextColor: "#D6DEEB", AccentColor: "#82AAFF", LineHeight: 1.6}
+}
+
+func newTestThemeService() (domain.ThemeService, *mockThemeRepo, *mockUserPreferencesRepo) {
+	themes := newMockThemeRepo()
+	prefs := newMockUserPreferencesRepo()
+	prefs.prefs["user-1"] = &domain.UserPreferences{UserID: "user-1", Theme: domain.ThemeLight}
+	return NewThemeService(themes, prefs, NewMockLogger()), themes, prefs
+}
+
+func TestThemeService_ListAndGet(t *testing.T) {
+	svc, _, _ := newTestThemeService()
+	principal := testPrincipal("user-1")
+
+	if _, err := svc.CreateTheme(context.Background(), principal, testTheme("Night Owl")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	themes, err := svc.ListThemes(context.Background(), principal)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	presets := len(domain.ThemePresets())
+	if len(themes) != presets+1 || themes[presets].Name != "Night Owl" {
+		t.Fatalf("expected presets followed by the custom theme, got %d themes", len(themes))
+	}
+
+	if theme, err := svc.GetTheme(context.Background(), principal, domain.ThemeOLEDBlack); err != nil || !theme.Preset {
+		t.Fatalf("expected OLED preset, got %+v, %v", theme, err)
+	}
+	if theme, err := svc.GetTheme(context.Background(), principal, "Night Owl"); err != nil || theme.Preset {
+		t.Fatalf("expected custom theme, got %+v, %v", theme, err)
+	}
+	if _, err := svc.GetTheme(context.Background(), testPrincipal("user-2"), "Night Owl"); !errors.Is(err, domain.ErrThemeNotFound) {
+		t.Fatalf("expected ErrThemeNotFound for another user, got %v", err)
+	}
+}
+
+func TestThemeService_CreateTheme_Rejects(t *testing.T) {
+	svc, _, _ := newTestThemeService()
+	principal := testPrincipal("user-1")
+
+	if _, err := svc.CreateTheme(context.Background(), principal, testTheme("Night Owl")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if _, err := svc.CreateTheme(context.Background(), principal, testTheme("Night Owl")); !errors.Is(err, domain.ErrThemeExists) {
+		t.Fatalf("expected ErrThemeExists, got %v", err)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if _, err := svc.CreateTheme(context.Background(), principal, testTheme(domain.ThemeSepia)); !errors.As(err, &validationErrs) {
+		t.Fatalf("expected validation error for a preset name, got %v", err)
+	}
+}
+
+func TestThemeService_RenameFollowsPreference(t *testing.T) {
+	svc, _, prefs := newTestThemeService()
+	principal := testPrincipal("user-1")
+
+	created, err := svc.CreateTheme(context.Background(), principal, testTheme("Night Owl"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	prefs.prefs["user-1"].Theme = "Night Owl"
+
+	if _, err := svc.UpdateTheme(context.Background(), principal, created.ID, testTheme("Day Owl")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := prefs.prefs["user-1"].Theme; got != "Day Owl" {
+		t.Fatalf("expected preference to follow rename, got %s", got)
+	}
+
+	if err := svc.DeleteTheme(context.Background(), principal, created.ID); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if got := prefs.prefs["user-1"].Theme; got != domain.ThemeLight {
+		t.Fatalf("expected preference reset to light, got %s", got)
+	}
+}
+
+func TestThemeService_UpdateTheme_NameConflict(t *testing.T) {
+	svc, _, _ := newTestThemeService()
+	principal := testPrincipal("user-1")
+
+	if _, err := svc.CreateTheme(context.Background(), principal, testTheme("Night Owl")); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	second, err := svc.CreateTheme(context.Background(), principal, testTheme("Solarized"))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+
+	if _, err := svc.UpdateTheme(context.Background(), principal, second.ID, testTheme("Night Owl")); !errors.Is(err, domain.ErrThemeExists) {
+		t.Fatalf("expected ErrThemeExists, got %v", err)
+	}
+	if _, err := svc.UpdateTheme(context.Background(), principal, "missing", testTheme("Other")); !errors.Is(err, domain.ErrThemeNotFound) {
+		t.Fatalf("expected ErrThemeNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"pdf-text-reader/internal/domain"
+)
+
+// uploadRetention is how long a session can be looked up after its last update,
+// which also drops sessions whose client went away mid-upload.
+const uploadRetention = time.Hour
+
+// maxUploadSessionsPerUser bounds the sessions kept for one user. Finished sessions
+// make way for new ones, oldest first; uploads still running do not.
+const maxUploadSessionsPerUser = 20
+
+// uploadTracker keeps the progress of the direct uploads running on this server. Like
+// import jobs, sessions live in memory: a client polling another instance, or after
+// a restart, gets a 404.
+type uploadTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*domain.UploadProgress
+}
+
+func newUploadTracker() *uploadTracker {
+	return &uploadTracker{sessions: make(map[string]*domain.UploadProgress)}
+}
+
+// uploadKey scopes client-chosen IDs to their user.
+func uploadKey(principal domain.Principal, uploadID string) string {
+	return principal.UserID + "/" + uploadID
+}
+
+func (t *uploadTracker) start(principal domain.Principal, uploadID string, totalBytes int64) error {
+	now := time.Now().UTC()
+	key := uploadKey(principal, uploadID)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for k, old := range t.sessions {
+		if now.Sub(old.UpdatedAt) > uploadRetention {
+			delete(t.sessions, k)
+		}
+	}
+	if old, ok := t.sessions[key]; ok && !uploadFinished(old) {
+		return domain.ErrUploadInProgress
+	}
+	delete(t.sessions, key)
+	if err := t.makeRoom(principal.UserID); err != nil {
+		return err
+	}
+	t.sessions[key] = &domain.UploadProgress{
+		ID:         uploadID,
+		UserID:     principal.UserID,
+		Status:     domain.UploadStatusReceiving,
+		TotalBytes: max(totalBytes, 0),
+		StartedAt:  now,
+		UpdatedAt:  now,
+	}
+	return nil
+}
+
+// makeRoom drops the user's oldest finished sessions until a new one fits under
+// maxUploadSessionsPerUser. The caller holds the lock.
+func (t *uploadTracker) makeRoom(userID string) error {
+	for {
+		count := 0
+		var oldest string
+		for k, session := range t.sessions {
+			if session.UserID != userID {
+				continue
+			}
+			count++
+			if uploadFinished(session) && (oldest == "" || session.UpdatedAt.Before(t.sessions[oldest].UpdatedAt)) {
+				oldest = k
+			}
+		}
+		if count < maxUploadSessionsPerUser {
+			return nil
+		}
+		if oldest == "" {
+			return domain.ErrTooManyUploads
+		}
+		delete(t.sessions, oldest)
+	}
+}
+
+func uploadFinished(session *domain.UploadProgress) bool {
+	return session.Status == domain.UploadStatusDone || session.Status == domain.UploadStatusFailed
+}
+
+// update changes a session under the tracker's lock; unknown sessions are ignored.
+func (t *uploadTracker) update(principal domain.Principal, uploadID string, update func(*domain.UploadProgress)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if session, ok := t.sessions[uploadKey(principal, uploadID)]; ok {
+		update(session)
+		session.UpdatedAt = time.Now().UTC()
+	}
+}
+
+func (t *uploadTracker) get(principal domain.Principal, uploadID string) (*domain.UploadProgress, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	session, ok := t.sessions[uploadKey(principal, uploadID)]
+	if !ok {
+		return nil, domain.ErrUploadNotFound
+	}
+	snapshot := *session
+	if snapshot.TotalBytes > 0 {
+		snapshot.Percent = min(100, float64(snapshot.BytesReceived)*100/float64(snapshot.TotalBytes))
+	}
+	return &snapshot, nil
+}
+
+func (s *DocumentService) StartUpload(principal domain.Principal, uploadID string, totalBytes int64) error {
+	if err := domain.ValidateUploadID(uploadID); err != nil {
+		return err
+	}
+	return s.uploads.start(principal, uploadID, totalBytes)
+}
+
+func (s *DocumentService) UploadReceived(principal domain.Principal, uploadID string, n int64) {
+	s.uploads.update(principal, uploadID, func(session *domain.UploadProgress) {
+		session.BytesReceived += n
+	})
+}
+
+func (s *DocumentService) UploadProcessing(principal domain.Principal, uploadID string) {
+	s.uploads.update(principal, uploadID, func(session *domain.UploadProgress) {
+		session.Status = domain.UploadStatusProcessing
+		// Without a Content-Length the total is only known once the body has ended.
+		if session.TotalBytes == 0 {
+			session.TotalBytes = session.BytesReceived
+		}
+	})
+}
+
+func (s *DocumentService) FinishUpload(principal domain.Principal, uploadID, documentID string, err error) {
+	s.uploads.update(principal, uploadID, func(session *domain.UploadProgress) {
+		if err != nil {
+			session.Status = domain.UploadStatusFailed
+			session.Error = uploadErrorMessage(err)
+			return
+		}
+		session.Status = domain.UploadStatusDone
+		session.DocumentID = documentID
+	})
+}
+
+func (s *DocumentService) GetUploadProgress(ctx context.Context, principal domain.Principal, uploadID string) (*domain.UploadProgress, error) {
+	return s.uploads.get(principal, uploadID)
+}
+
+// uploadErrorMessage is the reason shown for a failed upload.
+func uploadErrorMessage(err error) string {
+	var pdfErr *domain.PDFValidationError
+	switch {
+	case errors.As(err, &pdfErr):
+		return pdfErr.Message
+	case errors.Is(err, domain.ErrUnsupportedFileType), errors.Is(err, domain.ErrInvalidFile):
+		return "Unsupported or unreadable file"
+	case errors.Is(err, domain.ErrFileTooLarge):
+		return "File is too large"
+	case errors.Is(err, domain.ErrStorageLimitExceeded):
+		return "Storage limit exceeded"
+	default:
+		return "Upload failed"
+	}
+}
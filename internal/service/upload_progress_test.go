@@ -0,0 +1,94 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestDocumentService_UploadProgress(t *testing.T) {
+	logger := NewMockLogger()
+	s := NewDocumentService(NewMockDocumentRepository(), nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	if err := s.StartUpload(principal, "upload-1", 400); err != nil {
+		t.Fatalf("start failed: %v", err)
+	}
+	s.UploadReceived(principal, "upload-1", 100)
+	progress, err := s.GetUploadProgress(ctx, principal, "upload-1")
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if progress.Status != domain.UploadStatusReceiving || progress.BytesReceived != 100 || progress.Percent != 25 {
+		t.Errorf("progress = %+v", progress)
+	}
+
+	if err := s.StartUpload(principal, "upload-1", 400); !errors.Is(err, domain.ErrUploadInProgress) {
+		t.Errorf("restart while receiving: got %v, want ErrUploadInProgress", err)
+	}
+	if _, err := s.GetUploadProgress(ctx, testPrincipal("user2"), "upload-1"); !errors.Is(err, domain.ErrUploadNotFound) {
+		t.Errorf("other user: got %v, want ErrUploadNotFound", err)
+	}
+	// IDs are scoped to their user.
+	if err := s.StartUpload(testPrincipal("user2"), "upload-1", 0); err != nil {
+		t.Errorf("same ID for another user: %v", err)
+	}
+
+	s.UploadReceived(principal, "upload-1", 300)
+	s.UploadProcessing(principal, "upload-1")
+	s.FinishUpload(principal, "upload-1", "", domain.ErrStorageLimitExceeded)
+	progress, _ = s.GetUploadProgress(ctx, principal, "upload-1")
+	if progress.Status != domain.UploadStatusFailed || progress.Error != "Storage limit exceeded" || progress.Percent != 100 {
+		t.Errorf("failed progress = %+v", progress)
+	}
+
+	// A finished session can be retried under the same ID; without a Content-Length
+	// the total is what was received.
+	if err := s.StartUpload(principal, "upload-1", -1); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	s.UploadReceived(principal, "upload-1", 50)
+	s.UploadProcessing(principal, "upload-1")
+	s.FinishUpload(principal, "upload-1", "doc-1", nil)
+	progress, _ = s.GetUploadProgress(ctx, principal, "upload-1")
+	if progress.Status != domain.UploadStatusDone || progress.DocumentID != "doc-1" || progress.TotalBytes != 50 {
+		t.Errorf("retried progress = %+v", progress)
+	}
+
+	var validationErrs domain.ValidationErrors
+	if err := s.StartUpload(principal, "../etc", 0); !errors.As(err, &validationErrs) {
+		t.Errorf("invalid ID: got %v, want validation error", err)
+	}
+}
+
+func TestDocumentService_UploadSessionsPerUser(t *testing.T) {
+	logger := NewMockLogger()
+	s := NewDocumentService(NewMockDocumentRepository(), nil, nil, NewMockStorageService(), NewAuthorizationService(logger), domain.UploadLimits{}, domain.PDFLimits{}, nil, nil, logger)
+	ctx := context.Background()
+	principal := testPrincipal("user1")
+
+	for i := 0; i < maxUploadSessionsPerUser; i++ {
+		if err := s.StartUpload(principal, fmt.Sprintf("upload-%d", i), 0); err != nil {
+			t.Fatalf("start %d failed: %v", i, err)
+		}
+	}
+	if err := s.StartUpload(principal, "one-more", 0); !errors.Is(err, domain.ErrTooManyUploads) {
+		t.Fatalf("expected ErrTooManyUploads while every session runs, got %v", err)
+	}
+	if err := s.StartUpload(testPrincipal("user2"), "one-more", 0); err != nil {
+		t.Fatalf("expected other users to be unaffected, got %v", err)
+	}
+
+	// A finished session makes way for a new one.
+	s.FinishUpload(principal, "upload-3", "doc-3", nil)
+	if err := s.StartUpload(principal, "one-more", 0); err != nil {
+		t.Fatalf("start after a finished upload failed: %v", err)
+	}
+	if _, err := s.GetUploadProgress(ctx, principal, "upload-3"); !errors.Is(err, domain.ErrUploadNotFound) {
+		t.Fatalf("expected the finished session to be dropped, got %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"pdf-text-reader/internal/domain"
+
+	"github.com/gen2brain/go-fitz"
+)
+
+// uploadMemoryThreshold is how much of an upload is kept in memory; larger files are
+// spooled to a temp file, so one request holds at most this much of the file.
+const uploadMemoryThreshold = 4 << 20 // 4MB
+
+// spooledUpload is an upload read once from the request body, sized and hashed on
+// the way in. Small files stay in memory; larger ones live in a temp file that is
+// removed by Close.
+type spooledUpload struct {
+	size   int64
+	sha256 string
+	data   []byte   // whole file while it fits in memory
+	file   *os.File // spool file otherwise
+}
+
+// spoolUpload reads r to the end, failing with domain.ErrFileTooLarge as soon as it
+// yields more than maxBytes.
+func spoolUpload(r io.Reader, maxBytes int64) (*spooledUpload, error) {
+	hash := sha256.New()
+	src := io.TeeReader(io.LimitReader(r, maxBytes+1), hash)
+
+	head, err := io.ReadAll(io.LimitReader(src, uploadMemoryThreshold+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	u := &spooledUpload{size: int64(len(head))}
+	if len(head) > uploadMemoryThreshold {
+		if u.file, err = os.CreateTemp("", "lector-upload-*"); err != nil {
+			return nil, fmt.Errorf("failed to create upload spool file: %w", err)
+		}
+		if _, err := u.file.Write(head); err != nil {
+			u.Close()
+			return nil, fmt.Errorf("failed to spool upload: %w", err)
+		}
+		n, err := io.Copy(u.file, src)
+		if err != nil {
+			u.Close()
+			return nil, fmt.Errorf("failed to spool upload: %w", err)
+		}
+		u.size += n
+	} else {
+		u.data = head
+	}
+
+	if u.size > maxBytes {
+		u.Close()
+		return nil, fmt.Errorf("%w: upload exceeds the %d byte limit", domain.ErrFileTooLarge, maxBytes)
+	}
+	u.sha256 = hex.EncodeToString(hash.Sum(nil))
+	return u, nil
+}
+
+// ReaderAt gives random access to the upload, as zip archives need.
+func (u *spooledUpload) ReaderAt() io.ReaderAt {
+	if u.file != nil {
+		return u.file
+	}
+	return bytes.NewReader(u.data)
+}
+
+// Reader returns a fresh reader over the whole upload; each call starts at offset 0.
+func (u *spooledUpload) Reader() io.Reader {
+	return io.NewSectionReader(u.ReaderAt(), 0, u.size)
+}
+
+// openPDF opens the upload with fitz, from disk when it was spooled.
+func (u *spooledUpload) openPDF() (*fitz.Document, error) {
+	if u.file != nil {
+		return fitz.New(u.file.Name())
+	}
+	return fitz.NewFromMemory(u.data)
+}
+
+// processPDF runs the extractor on the upload without loading a spooled file.
+func (u *spooledUpload) processPDF(ctx context.Context, p *PDFProcessor) ([]TextBlock, PDFMetadata, error) {
+	if u.file != nil {
+		return p.ProcessPDFFile(ctx, u.file.Name())
+	}
+	return p.ProcessPDF(ctx, u.data)
+}
+
+// Close removes the spool file, if any.
+func (u *spooledUpload) Close() error {
+	if u.file == nil {
+		return nil
+	}
+	name := u.file.Name()
+	u.file.Close()
+	u.file = nil
+	return os.Remove(name)
+}
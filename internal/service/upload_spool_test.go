@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"testing"
+
+	"pdf-text-reader/internal/domain"
+)
+
+func TestSpoolUpload(t *testing.T) {
+	tests := []struct {
+		name    string
+		size    int
+		spooled bool
+	}{
+		{"small file stays in memory", 1024, false},
+		{"large file is spooled to disk", uploadMemoryThreshold + 1, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data := bytes.Repeat([]byte("a"), tt.size)
+			upload, err := spoolUpload(bytes.NewReader(data), int64(tt.size))
+			if err != nil {
+				t.Fatalf("spoolUpload: %v", err)
+			}
+			defer upload.Close()
+
+			if (upload.file != nil) != tt.spooled {
+				t.Fatalf("expected spooled=%v", tt.spooled)
+			}
+			sum := sha256.Sum256(data)
+			if upload.size != int64(tt.size) || upload.sha256 != hex.EncodeToString(sum[:]) {
+				t.Fatalf("unexpected size %d or hash %s", upload.size, upload.sha256)
+			}
+			got, err := io.ReadAll(upload.Reader())
+			if err != nil || !bytes.Equal(got, data) {
+				t.Fatalf("reader returned %d bytes, err %v", len(got), err)
+			}
+		})
+	}
+}
+
+func TestSpoolUpload_TooLarge(t *testing.T) {
+	for _, size := range []int{101, uploadMemoryThreshold + 1} {
+		max := int64(size - 1)
+		if _, err := spoolUpload(bytes.NewReader(make([]byte, size)), max); !errors.Is(err, domain.ErrFileTooLarge) {
+			t.Fatalf("expected ErrFileTooLarge for %d bytes over a %d byte limit, got %v", size, max, err)
+		}
+	}
+}
+
+func TestSpooledUpload_CloseRemovesFile(t *testing.T) {
+	upload, err := spoolUpload(bytes.NewReader(make([]byte, uploadMemoryThreshold+1)), uploadMemoryThreshold+1)
+	if err != nil {
+		t.Fatalf("spoolUpload: %v", err)
+	}
+	name := upload.file.Name()
+	if err := upload.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if _, err := os.Stat(name); !os.IsNotExist(err) {
+		t.Fatalf("expected the spool file to be removed, stat returned %v", err)
+	}
+}
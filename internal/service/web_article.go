@@ -0,0 +1,191 @@
+package service
+
+import (
+	"bytes"
+	"encoding/xml"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// articleSkipElements are dropped with everything inside them: page furniture
+// around the article rather than part of it.
+var articleSkipElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"nav": true, "header": true, "footer": true, "aside": true, "form": true,
+	"button": true, "select": true, "iframe": true, "svg": true, "canvas": true,
+}
+
+// articleKeepElements are written to the article; other elements are unwrapped to
+// their text. Images are dropped, since they would have to be fetched as well.
+var articleKeepElements = map[string]bool{
+	"p": true, "div": true, "section": true, "span": true, "a": true, "br": true, "hr": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"ul": true, "ol": true, "li": true, "dl": true, "dt": true, "dd": true,
+	"blockquote": true, "pre": true, "code": true, "em": true, "strong": true,
+	"i": true, "b": true, "sub": true, "sup": true, "figure": true, "figcaption": true,
+	"table": true, "thead": true, "tbody": true, "tr": true, "th": true, "td": true,
+}
+
+// webArticle is the readable part of a web page.
+type webArticle struct {
+	Title string
+	Body  []byte // XHTML body content
+}
+
+// newHTMLDecoder reads HTML the way the EPUB chapter extractor does.
+func newHTMLDecoder(page []byte) *xml.Decoder {
+	dec := xml.NewDecoder(bytes.NewReader(page))
+	dec.Strict = false
+	dec.AutoClose = xml.HTMLAutoClose
+	dec.Entity = xml.HTMLEntity
+	return dec
+}
+
+// extractWebArticle keeps the page's first <article>, else its <main>, else its
+// <body>, without the page furniture, as well-formed XHTML. Relative links are
+// resolved against pageURL. Returns false when no text is left.
+func extractWebArticle(page []byte, pageURL string) (*webArticle, bool) {
+	base, _ := url.Parse(pageURL)
+
+	// The first pass finds the title and which root the page has.
+	roots := map[string]bool{}
+	var title strings.Builder
+	inTitle := false
+	dec := newHTMLDecoder(page)
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			roots[name] = true
+			inTitle = name == "title"
+		case xml.EndElement:
+			inTitle = false
+		case xml.CharData:
+			if inTitle {
+				title.Write(t)
+			}
+		}
+	}
+	root := ""
+	for _, name := range []string{"article", "main", "body"} {
+		if roots[name] {
+			root = name
+			break
+		}
+	}
+
+	var body bytes.Buffer
+	enc := xml.NewEncoder(&body)
+	var (
+		open    []string // per open element: its name if its start tag was written
+		depth   int      // open elements inside the root; -1 once the root is closed
+		skip    int
+		hasText bool
+	)
+	if root == "" {
+		depth = 1
+	}
+	dec = newHTMLDecoder(page)
+	for depth >= 0 {
+		tok, err := dec.Token()
+		if err != nil {
+			break
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := strings.ToLower(t.Name.Local)
+			if depth == 0 {
+				if name == root {
+					depth = 1
+				}
+				continue
+			}
+			depth++
+			if skip > 0 || articleSkipElements[name] {
+				skip++
+				open = append(open, "")
+				continue
+			}
+			if !articleKeepElements[name] {
+				open = append(open, "")
+				continue
+			}
+			start := xml.StartElement{Name: xml.Name{Local: name}}
+			if name == "a" {
+				if href := articleHref(base, t); href != "" {
+					start.Attr = []xml.Attr{{Name: xml.Name{Local: "href"}, Value: href}}
+				}
+			}
+			_ = enc.EncodeToken(start)
+			open = append(open, name)
+		case xml.EndElement:
+			if depth <= 0 {
+				continue
+			}
+			depth--
+			if depth == 0 {
+				depth = -1
+				continue
+			}
+			n := len(open) - 1
+			if skip > 0 {
+				skip--
+			} else if open[n] != "" {
+				_ = enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: open[n]}})
+			}
+			open = open[:n]
+		case xml.CharData:
+			if depth > 0 && skip == 0 {
+				if len(bytes.TrimSpace(t)) > 0 {
+					hasText = true
+				}
+				_ = enc.EncodeToken(t)
+			}
+		}
+	}
+	// Close what the page left open.
+	for i := len(open) - 1; i >= 0; i-- {
+		if open[i] != "" {
+			_ = enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: open[i]}})
+		}
+	}
+	if err := enc.Flush(); err != nil || !hasText {
+		return nil, false
+	}
+	return &webArticle{Title: strings.Join(strings.Fields(title.String()), " "), Body: body.Bytes()}, true
+}
+
+// articleHref returns the link's absolute http(s) address, or "" for other links.
+func articleHref(base *url.URL, el xml.StartElement) string {
+	ref, err := url.Parse(strings.TrimSpace(epubAttr(el, "href")))
+	if err != nil || base == nil {
+		return ""
+	}
+	u := base.ResolveReference(ref)
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return ""
+	}
+	return u.String()
+}
+
+// webArticleEPUB packages an article as a single-chapter EPUB, so it is extracted,
+// stored and exported like any other book.
+func webArticleEPUB(article *webArticle, title string) ([]byte, error) {
+	pkg := epubExport{
+		ID:       uuid.New().String(),
+		Title:    title,
+		Language: "en",
+		Modified: time.Now().UTC().Format(time.RFC3339),
+		Chapters: []epubChapter{{ID: "article", Title: title}},
+	}
+	return writeEPUB(pkg, []epubFile{
+		{"OEBPS/article.xhtml", "article", map[string]any{"Title": title, "Body": article.Body}},
+	})
+}
@@ -0,0 +1,995 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        v5.29.3
+// source: lector/v1/lector.proto
+
+// Internal gRPC API of the Lector server. Messages mirror the domain DTOs served by the
+// JSON API; every call must carry the user's access token as `authorization: Bearer <token>`
+// metadata, exactly like REST requests.
+
+package lectorv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	emptypb "google.golang.org/protobuf/types/known/emptypb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type DocumentMetadata struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	OriginalTitle  string                 `protobuf:"bytes,1,opt,name=original_title,json=originalTitle,proto3" json:"original_title,omitempty"`
+	OriginalAuthor string                 `protobuf:"bytes,2,opt,name=original_author,json=originalAuthor,proto3" json:"original_author,omitempty"`
+	Language       string                 `protobuf:"bytes,3,opt,name=language,proto3" json:"language,omitempty"`
+	PageCount      int32                  `protobuf:"varint,4,opt,name=page_count,json=pageCount,proto3" json:"page_count,omitempty"`
+	WordCount      int32                  `protobuf:"varint,5,opt,name=word_count,json=wordCount,proto3" json:"word_count,omitempty"`
+	FileSize       int64                  `protobuf:"varint,6,opt,name=file_size,json=fileSize,proto3" json:"file_size,omitempty"`
+	Format         string                 `protobuf:"bytes,7,opt,name=format,proto3" json:"format,omitempty"`
+	Source         string                 `protobuf:"bytes,8,opt,name=source,proto3" json:"source,omitempty"`
+	HasPassword    bool                   `protobuf:"varint,9,opt,name=has_password,json=hasPassword,proto3" json:"has_password,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *DocumentMetadata) Reset() {
+	*x = DocumentMetadata{}
+	mi := &file_lector_v1_lector_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DocumentMetadata) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DocumentMetadata) ProtoMessage() {}
+
+func (x *DocumentMetadata) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DocumentMetadata.ProtoReflect.Descriptor instead.
+func (*DocumentMetadata) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DocumentMetadata) GetOriginalTitle() string {
+	if x != nil {
+		return x.OriginalTitle
+	}
+	return ""
+}
+
+func (x *DocumentMetadata) GetOriginalAuthor() string {
+	if x != nil {
+		return x.OriginalAuthor
+	}
+	return ""
+}
+
+func (x *DocumentMetadata) GetLanguage() string {
+	if x != nil {
+		return x.Language
+	}
+	return ""
+}
+
+func (x *DocumentMetadata) GetPageCount() int32 {
+	if x != nil {
+		return x.PageCount
+	}
+	return 0
+}
+
+func (x *DocumentMetadata) GetWordCount() int32 {
+	if x != nil {
+		return x.WordCount
+	}
+	return 0
+}
+
+func (x *DocumentMetadata) GetFileSize() int64 {
+	if x != nil {
+		return x.FileSize
+	}
+	return 0
+}
+
+func (x *DocumentMetadata) GetFormat() string {
+	if x != nil {
+		return x.Format
+	}
+	return ""
+}
+
+func (x *DocumentMetadata) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *DocumentMetadata) GetHasPassword() bool {
+	if x != nil {
+		return x.HasPassword
+	}
+	return false
+}
+
+type Document struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	UserId      string                 `protobuf:"bytes,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Title       string                 `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Author      *string                `protobuf:"bytes,4,opt,name=author,proto3,oneof" json:"author,omitempty"`
+	Description *string                `protobuf:"bytes,5,opt,name=description,proto3,oneof" json:"description,omitempty"`
+	// JSON-encoded pages, as stored; empty in list responses.
+	ContentJson     []byte                 `protobuf:"bytes,6,opt,name=content_json,json=contentJson,proto3" json:"content_json,omitempty"`
+	Metadata        *DocumentMetadata      `protobuf:"bytes,7,opt,name=metadata,proto3" json:"metadata,omitempty"`
+	Tag             *string                `protobuf:"bytes,8,opt,name=tag,proto3,oneof" json:"tag,omitempty"`
+	IsFavorite      bool                   `protobuf:"varint,9,opt,name=is_favorite,json=isFavorite,proto3" json:"is_favorite,omitempty"`
+	ReadingPosition *ReadingPosition       `protobuf:"bytes,10,opt,name=reading_position,json=readingPosition,proto3" json:"reading_position,omitempty"`
+	CreatedAt       *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	UpdatedAt       *timestamppb.Timestamp `protobuf:"bytes,12,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Document) Reset() {
+	*x = Document{}
+	mi := &file_lector_v1_lector_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Document) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Document) ProtoMessage() {}
+
+func (x *Document) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Document.ProtoReflect.Descriptor instead.
+func (*Document) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Document) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Document) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Document) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Document) GetAuthor() string {
+	if x != nil && x.Author != nil {
+		return *x.Author
+	}
+	return ""
+}
+
+func (x *Document) GetDescription() string {
+	if x != nil && x.Description != nil {
+		return *x.Description
+	}
+	return ""
+}
+
+func (x *Document) GetContentJson() []byte {
+	if x != nil {
+		return x.ContentJson
+	}
+	return nil
+}
+
+func (x *Document) GetMetadata() *DocumentMetadata {
+	if x != nil {
+		return x.Metadata
+	}
+	return nil
+}
+
+func (x *Document) GetTag() string {
+	if x != nil && x.Tag != nil {
+		return *x.Tag
+	}
+	return ""
+}
+
+func (x *Document) GetIsFavorite() bool {
+	if x != nil {
+		return x.IsFavorite
+	}
+	return false
+}
+
+func (x *Document) GetReadingPosition() *ReadingPosition {
+	if x != nil {
+		return x.ReadingPosition
+	}
+	return nil
+}
+
+func (x *Document) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Document) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListDocumentsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Documents     []*Document            `protobuf:"bytes,1,rep,name=documents,proto3" json:"documents,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDocumentsResponse) Reset() {
+	*x = ListDocumentsResponse{}
+	mi := &file_lector_v1_lector_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDocumentsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDocumentsResponse) ProtoMessage() {}
+
+func (x *ListDocumentsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDocumentsResponse.ProtoReflect.Descriptor instead.
+func (*ListDocumentsResponse) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListDocumentsResponse) GetDocuments() []*Document {
+	if x != nil {
+		return x.Documents
+	}
+	return nil
+}
+
+type GetDocumentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetDocumentRequest) Reset() {
+	*x = GetDocumentRequest{}
+	mi := &file_lector_v1_lector_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetDocumentRequest) ProtoMessage() {}
+
+func (x *GetDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetDocumentRequest.ProtoReflect.Descriptor instead.
+func (*GetDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetDocumentRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type SearchDocumentsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Query         string                 `protobuf:"bytes,1,opt,name=query,proto3" json:"query,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SearchDocumentsRequest) Reset() {
+	*x = SearchDocumentsRequest{}
+	mi := &file_lector_v1_lector_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SearchDocumentsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SearchDocumentsRequest) ProtoMessage() {}
+
+func (x *SearchDocumentsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SearchDocumentsRequest.ProtoReflect.Descriptor instead.
+func (*SearchDocumentsRequest) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *SearchDocumentsRequest) GetQuery() string {
+	if x != nil {
+		return x.Query
+	}
+	return ""
+}
+
+type SetFavoriteRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Favorite      bool                   `protobuf:"varint,2,opt,name=favorite,proto3" json:"favorite,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetFavoriteRequest) Reset() {
+	*x = SetFavoriteRequest{}
+	mi := &file_lector_v1_lector_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetFavoriteRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetFavoriteRequest) ProtoMessage() {}
+
+func (x *SetFavoriteRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetFavoriteRequest.ProtoReflect.Descriptor instead.
+func (*SetFavoriteRequest) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *SetFavoriteRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *SetFavoriteRequest) GetFavorite() bool {
+	if x != nil {
+		return x.Favorite
+	}
+	return false
+}
+
+type DeleteDocumentRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteDocumentRequest) Reset() {
+	*x = DeleteDocumentRequest{}
+	mi := &file_lector_v1_lector_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteDocumentRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteDocumentRequest) ProtoMessage() {}
+
+func (x *DeleteDocumentRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteDocumentRequest.ProtoReflect.Descriptor instead.
+func (*DeleteDocumentRequest) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *DeleteDocumentRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type UserPreferences struct {
+	state             protoimpl.MessageState `protogen:"open.v1"`
+	FontSize          int32                  `protobuf:"varint,1,opt,name=font_size,json=fontSize,proto3" json:"font_size,omitempty"`
+	FontFamily        string                 `protobuf:"bytes,2,opt,name=font_family,json=fontFamily,proto3" json:"font_family,omitempty"`
+	Theme             string                 `protobuf:"bytes,3,opt,name=theme,proto3" json:"theme,omitempty"`
+	HighlightColor    string                 `protobuf:"bytes,4,opt,name=highlight_color,json=highlightColor,proto3" json:"highlight_color,omitempty"`
+	MarginSize        string                 `protobuf:"bytes,5,opt,name=margin_size,json=marginSize,proto3" json:"margin_size,omitempty"`
+	TextAlign         string                 `protobuf:"bytes,6,opt,name=text_align,json=textAlign,proto3" json:"text_align,omitempty"`
+	Hyphenation       bool                   `protobuf:"varint,7,opt,name=hyphenation,proto3" json:"hyphenation,omitempty"`
+	ReadingMode       string                 `protobuf:"bytes,8,opt,name=reading_mode,json=readingMode,proto3" json:"reading_mode,omitempty"`
+	PageTurnAnimation string                 `protobuf:"bytes,9,opt,name=page_turn_animation,json=pageTurnAnimation,proto3" json:"page_turn_animation,omitempty"`
+	Brightness        float64                `protobuf:"fixed64,10,opt,name=brightness,proto3" json:"brightness,omitempty"`
+	SubscriptionPlan  string                 `protobuf:"bytes,11,opt,name=subscription_plan,json=subscriptionPlan,proto3" json:"subscription_plan,omitempty"`
+	StorageLimitBytes int64                  `protobuf:"varint,12,opt,name=storage_limit_bytes,json=storageLimitBytes,proto3" json:"storage_limit_bytes,omitempty"`
+	AccountDisabled   bool                   `protobuf:"varint,13,opt,name=account_disabled,json=accountDisabled,proto3" json:"account_disabled,omitempty"`
+	Tags              []string               `protobuf:"bytes,14,rep,name=tags,proto3" json:"tags,omitempty"`
+	UpdatedAt         *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields     protoimpl.UnknownFields
+	sizeCache         protoimpl.SizeCache
+}
+
+func (x *UserPreferences) Reset() {
+	*x = UserPreferences{}
+	mi := &file_lector_v1_lector_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserPreferences) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserPreferences) ProtoMessage() {}
+
+func (x *UserPreferences) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserPreferences.ProtoReflect.Descriptor instead.
+func (*UserPreferences) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *UserPreferences) GetFontSize() int32 {
+	if x != nil {
+		return x.FontSize
+	}
+	return 0
+}
+
+func (x *UserPreferences) GetFontFamily() string {
+	if x != nil {
+		return x.FontFamily
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetTheme() string {
+	if x != nil {
+		return x.Theme
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetHighlightColor() string {
+	if x != nil {
+		return x.HighlightColor
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetMarginSize() string {
+	if x != nil {
+		return x.MarginSize
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetTextAlign() string {
+	if x != nil {
+		return x.TextAlign
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetHyphenation() bool {
+	if x != nil {
+		return x.Hyphenation
+	}
+	return false
+}
+
+func (x *UserPreferences) GetReadingMode() string {
+	if x != nil {
+		return x.ReadingMode
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetPageTurnAnimation() string {
+	if x != nil {
+		return x.PageTurnAnimation
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetBrightness() float64 {
+	if x != nil {
+		return x.Brightness
+	}
+	return 0
+}
+
+func (x *UserPreferences) GetSubscriptionPlan() string {
+	if x != nil {
+		return x.SubscriptionPlan
+	}
+	return ""
+}
+
+func (x *UserPreferences) GetStorageLimitBytes() int64 {
+	if x != nil {
+		return x.StorageLimitBytes
+	}
+	return 0
+}
+
+func (x *UserPreferences) GetAccountDisabled() bool {
+	if x != nil {
+		return x.AccountDisabled
+	}
+	return false
+}
+
+func (x *UserPreferences) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *UserPreferences) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ReadingPosition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocumentId    string                 `protobuf:"bytes,1,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`
+	Progress      float32                `protobuf:"fixed32,2,opt,name=progress,proto3" json:"progress,omitempty"`
+	PageNumber    int32                  `protobuf:"varint,3,opt,name=page_number,json=pageNumber,proto3" json:"page_number,omitempty"`
+	UpdatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReadingPosition) Reset() {
+	*x = ReadingPosition{}
+	mi := &file_lector_v1_lector_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReadingPosition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReadingPosition) ProtoMessage() {}
+
+func (x *ReadingPosition) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReadingPosition.ProtoReflect.Descriptor instead.
+func (*ReadingPosition) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReadingPosition) GetDocumentId() string {
+	if x != nil {
+		return x.DocumentId
+	}
+	return ""
+}
+
+func (x *ReadingPosition) GetProgress() float32 {
+	if x != nil {
+		return x.Progress
+	}
+	return 0
+}
+
+func (x *ReadingPosition) GetPageNumber() int32 {
+	if x != nil {
+		return x.PageNumber
+	}
+	return 0
+}
+
+func (x *ReadingPosition) GetUpdatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.UpdatedAt
+	}
+	return nil
+}
+
+type ListReadingPositionsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Positions     []*ReadingPosition     `protobuf:"bytes,1,rep,name=positions,proto3" json:"positions,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListReadingPositionsResponse) Reset() {
+	*x = ListReadingPositionsResponse{}
+	mi := &file_lector_v1_lector_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListReadingPositionsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListReadingPositionsResponse) ProtoMessage() {}
+
+func (x *ListReadingPositionsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListReadingPositionsResponse.ProtoReflect.Descriptor instead.
+func (*ListReadingPositionsResponse) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *ListReadingPositionsResponse) GetPositions() []*ReadingPosition {
+	if x != nil {
+		return x.Positions
+	}
+	return nil
+}
+
+type GetReadingPositionRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DocumentId    string                 `protobuf:"bytes,1,opt,name=document_id,json=documentId,proto3" json:"document_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetReadingPositionRequest) Reset() {
+	*x = GetReadingPositionRequest{}
+	mi := &file_lector_v1_lector_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetReadingPositionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetReadingPositionRequest) ProtoMessage() {}
+
+func (x *GetReadingPositionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_lector_v1_lector_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetReadingPositionRequest.ProtoReflect.Descriptor instead.
+func (*GetReadingPositionRequest) Descriptor() ([]byte, []int) {
+	return file_lector_v1_lector_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *GetReadingPositionRequest) GetDocumentId() string {
+	if x != nil {
+		return x.DocumentId
+	}
+	return ""
+}
+
+var File_lector_v1_lector_proto protoreflect.FileDescriptor
+
+const file_lector_v1_lector_proto_rawDesc = "" +
+	"\n" +
+	"\x16lector/v1/lector.proto\x12\tlector.v1\x1a\x1bgoogle/protobuf/empty.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xac\x02\n" +
+	"\x10DocumentMetadata\x12%\n" +
+	"\x0eoriginal_title\x18\x01 \x01(\tR\roriginalTitle\x12'\n" +
+	"\x0foriginal_author\x18\x02 \x01(\tR\x0eoriginalAuthor\x12\x1a\n" +
+	"\blanguage\x18\x03 \x01(\tR\blanguage\x12\x1d\n" +
+	"\n" +
+	"page_count\x18\x04 \x01(\x05R\tpageCount\x12\x1d\n" +
+	"\n" +
+	"word_count\x18\x05 \x01(\x05R\twordCount\x12\x1b\n" +
+	"\tfile_size\x18\x06 \x01(\x03R\bfileSize\x12\x16\n" +
+	"\x06format\x18\a \x01(\tR\x06format\x12\x16\n" +
+	"\x06source\x18\b \x01(\tR\x06source\x12!\n" +
+	"\fhas_password\x18\t \x01(\bR\vhasPassword\"\x81\x04\n" +
+	"\bDocument\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05title\x18\x03 \x01(\tR\x05title\x12\x1b\n" +
+	"\x06author\x18\x04 \x01(\tH\x00R\x06author\x88\x01\x01\x12%\n" +
+	"\vdescription\x18\x05 \x01(\tH\x01R\vdescription\x88\x01\x01\x12!\n" +
+	"\fcontent_json\x18\x06 \x01(\fR\vcontentJson\x127\n" +
+	"\bmetadata\x18\a \x01(\v2\x1b.lector.v1.DocumentMetadataR\bmetadata\x12\x15\n" +
+	"\x03tag\x18\b \x01(\tH\x02R\x03tag\x88\x01\x01\x12\x1f\n" +
+	"\vis_favorite\x18\t \x01(\bR\n" +
+	"isFavorite\x12E\n" +
+	"\x10reading_position\x18\n" +
+	" \x01(\v2\x1a.lector.v1.ReadingPositionR\x0freadingPosition\x129\n" +
+	"\n" +
+	"created_at\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x129\n" +
+	"\n" +
+	"updated_at\x18\f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAtB\t\n" +
+	"\a_authorB\x0e\n" +
+	"\f_descriptionB\x06\n" +
+	"\x04_tag\"J\n" +
+	"\x15ListDocumentsResponse\x121\n" +
+	"\tdocuments\x18\x01 \x03(\v2\x13.lector.v1.DocumentR\tdocuments\"$\n" +
+	"\x12GetDocumentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\".\n" +
+	"\x16SearchDocumentsRequest\x12\x14\n" +
+	"\x05query\x18\x01 \x01(\tR\x05query\"@\n" +
+	"\x12SetFavoriteRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x1a\n" +
+	"\bfavorite\x18\x02 \x01(\bR\bfavorite\"'\n" +
+	"\x15DeleteDocumentRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xba\x04\n" +
+	"\x0fUserPreferences\x12\x1b\n" +
+	"\tfont_size\x18\x01 \x01(\x05R\bfontSize\x12\x1f\n" +
+	"\vfont_family\x18\x02 \x01(\tR\n" +
+	"fontFamily\x12\x14\n" +
+	"\x05theme\x18\x03 \x01(\tR\x05theme\x12'\n" +
+	"\x0fhighlight_color\x18\x04 \x01(\tR\x0ehighlightColor\x12\x1f\n" +
+	"\vmargin_size\x18\x05 \x01(\tR\n" +
+	"marginSize\x12\x1d\n" +
+	"\n" +
+	"text_align\x18\x06 \x01(\tR\ttextAlign\x12 \n" +
+	"\vhyphenation\x18\a \x01(\bR\vhyphenation\x12!\n" +
+	"\freading_mode\x18\b \x01(\tR\vreadingMode\x12.\n" +
+	"\x13page_turn_animation\x18\t \x01(\tR\x11pageTurnAnimation\x12\x1e\n" +
+	"\n" +
+	"brightness\x18\n" +
+	" \x01(\x01R\n" +
+	"brightness\x12+\n" +
+	"\x11subscription_plan\x18\v \x01(\tR\x10subscriptionPlan\x12.\n" +
+	"\x13storage_limit_bytes\x18\f \x01(\x03R\x11storageLimitBytes\x12)\n" +
+	"\x10account_disabled\x18\r \x01(\bR\x0faccountDisabled\x12\x12\n" +
+	"\x04tags\x18\x0e \x03(\tR\x04tags\x129\n" +
+	"\n" +
+	"updated_at\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"\xaa\x01\n" +
+	"\x0fReadingPosition\x12\x1f\n" +
+	"\vdocument_id\x18\x01 \x01(\tR\n" +
+	"documentId\x12\x1a\n" +
+	"\bprogress\x18\x02 \x01(\x02R\bprogress\x12\x1f\n" +
+	"\vpage_number\x18\x03 \x01(\x05R\n" +
+	"pageNumber\x129\n" +
+	"\n" +
+	"updated_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tupdatedAt\"X\n" +
+	"\x1cListReadingPositionsResponse\x128\n" +
+	"\tpositions\x18\x01 \x03(\v2\x1a.lector.v1.ReadingPositionR\tpositions\"<\n" +
+	"\x19GetReadingPositionRequest\x12\x1f\n" +
+	"\vdocument_id\x18\x01 \x01(\tR\n" +
+	"documentId2\x89\x03\n" +
+	"\x0fDocumentService\x12I\n" +
+	"\rListDocuments\x12\x16.google.protobuf.Empty\x1a .lector.v1.ListDocumentsResponse\x12A\n" +
+	"\vGetDocument\x12\x1d.lector.v1.GetDocumentRequest\x1a\x13.lector.v1.Document\x12V\n" +
+	"\x0fSearchDocuments\x12!.lector.v1.SearchDocumentsRequest\x1a .lector.v1.ListDocumentsResponse\x12D\n" +
+	"\vSetFavorite\x12\x1d.lector.v1.SetFavoriteRequest\x1a\x16.google.protobuf.Empty\x12J\n" +
+	"\x0eDeleteDocument\x12 .lector.v1.DeleteDocumentRequest\x1a\x16.google.protobuf.Empty2\xa8\x03\n" +
+	"\x11PreferenceService\x12D\n" +
+	"\x0eGetPreferences\x12\x16.google.protobuf.Empty\x1a\x1a.lector.v1.UserPreferences\x12K\n" +
+	"\x11UpdatePreferences\x12\x1a.lector.v1.UserPreferences\x1a\x1a.lector.v1.UserPreferences\x12W\n" +
+	"\x14ListReadingPositions\x12\x16.google.protobuf.Empty\x1a'.lector.v1.ListReadingPositionsResponse\x12V\n" +
+	"\x12GetReadingPosition\x12$.lector.v1.GetReadingPositionRequest\x1a\x1a.lector.v1.ReadingPosition\x12O\n" +
+	"\x15UpdateReadingPosition\x12\x1a.lector.v1.ReadingPosition\x1a\x1a.lector.v1.ReadingPositionB,Z*pdf-text-reader/pkg/api/lector/v1;lectorv1b\x06proto3"
+
+var (
+	file_lector_v1_lector_proto_rawDescOnce sync.Once
+	file_lector_v1_lector_proto_rawDescData []byte
+)
+
+func file_lector_v1_lector_proto_rawDescGZIP() []byte {
+	file_lector_v1_lector_proto_rawDescOnce.Do(func() {
+		file_lector_v1_lector_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_lector_v1_lector_proto_rawDesc), len(file_lector_v1_lector_proto_rawDesc)))
+	})
+	return file_lector_v1_lector_proto_rawDescData
+}
+
+var file_lector_v1_lector_proto_msgTypes = make([]protoimpl.MessageInfo, 11)
+var file_lector_v1_lector_proto_goTypes = []any{
+	(*DocumentMetadata)(nil),             // 0: lector.v1.DocumentMetadata
+	(*Document)(nil),                     // 1: lector.v1.Document
+	(*ListDocumentsResponse)(nil),        // 2: lector.v1.ListDocumentsResponse
+	(*GetDocumentRequest)(nil),           // 3: lector.v1.GetDocumentRequest
+	(*SearchDocumentsRequest)(nil),       // 4: lector.v1.SearchDocumentsRequest
+	(*SetFavoriteRequest)(nil),           // 5: lector.v1.SetFavoriteRequest
+	(*DeleteDocumentRequest)(nil),        // 6: lector.v1.DeleteDocumentRequest
+	(*UserPreferences)(nil),              // 7: lector.v1.UserPreferences
+	(*ReadingPosition)(nil),              // 8: lector.v1.ReadingPosition
+	(*ListReadingPositionsResponse)(nil), // 9: lector.v1.ListReadingPositionsResponse
+	(*GetReadingPositionRequest)(nil),    // 10: lector.v1.GetReadingPositionRequest
+	(*timestamppb.Timestamp)(nil),        // 11: google.protobuf.Timestamp
+	(*emptypb.Empty)(nil),                // 12: google.protobuf.Empty
+}
+var file_lector_v1_lector_proto_depIdxs = []int32{
+	0,  // 0: lector.v1.Document.metadata:type_name -> lector.v1.DocumentMetadata
+	8,  // 1: lector.v1.Document.reading_position:type_name -> lector.v1.ReadingPosition
+	11, // 2: lector.v1.Document.created_at:type_name -> google.protobuf.Timestamp
+	11, // 3: lector.v1.Document.updated_at:type_name -> google.protobuf.Timestamp
+	1,  // 4: lector.v1.ListDocumentsResponse.documents:type_name -> lector.v1.Document
+	11, // 5: lector.v1.UserPreferences.updated_at:type_name -> google.protobuf.Timestamp
+	11, // 6: lector.v1.ReadingPosition.updated_at:type_name -> google.protobuf.Timestamp
+	8,  // 7: lector.v1.ListReadingPositionsResponse.positions:type_name -> lector.v1.ReadingPosition
+	12, // 8: lector.v1.DocumentService.ListDocuments:input_type -> google.protobuf.Empty
+	3,  // 9: lector.v1.DocumentService.GetDocument:input_type -> lector.v1.GetDocumentRequest
+	4,  // 10: lector.v1.DocumentService.SearchDocuments:input_type -> lector.v1.SearchDocumentsRequest
+	5,  // 11: lector.v1.DocumentService.SetFavorite:input_type -> lector.v1.SetFavoriteRequest
+	6,  // 12: lector.v1.DocumentService.DeleteDocument:input_type -> lector.v1.DeleteDocumentRequest
+	12, // 13: lector.v1.PreferenceService.GetPreferences:input_type -> google.protobuf.Empty
+	7,  // 14: lector.v1.PreferenceService.UpdatePreferences:input_type -> lector.v1.UserPreferences
+	12, // 15: lector.v1.PreferenceService.ListReadingPositions:input_type -> google.protobuf.Empty
+	10, // 16: lector.v1.PreferenceService.GetReadingPosition:input_type -> lector.v1.GetReadingPositionRequest
+	8,  // 17: lector.v1.PreferenceService.UpdateReadingPosition:input_type -> lector.v1.ReadingPosition
+	2,  // 18: lector.v1.DocumentService.ListDocuments:output_type -> lector.v1.ListDocumentsResponse
+	1,  // 19: lector.v1.DocumentService.GetDocument:output_type -> lector.v1.Document
+	2,  // 20: lector.v1.DocumentService.SearchDocuments:output_type -> lector.v1.ListDocumentsResponse
+	12, // 21: lector.v1.DocumentService.SetFavorite:output_type -> google.protobuf.Empty
+	12, // 22: lector.v1.DocumentService.DeleteDocument:output_type -> google.protobuf.Empty
+	7,  // 23: lector.v1.PreferenceService.GetPreferences:output_type -> lector.v1.UserPreferences
+	7,  // 24: lector.v1.PreferenceService.UpdatePreferences:output_type -> lector.v1.UserPreferences
+	9,  // 25: lector.v1.PreferenceService.ListReadingPositions:output_type -> lector.v1.ListReadingPositionsResponse
+	8,  // 26: lector.v1.PreferenceService.GetReadingPosition:output_type -> lector.v1.ReadingPosition
+	8,  // 27: lector.v1.PreferenceService.UpdateReadingPosition:output_type -> lector.v1.ReadingPosition
+	18, // [18:28] is the sub-list for method output_type
+	8,  // [8:18] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_lector_v1_lector_proto_init() }
+func file_lector_v1_lector_proto_init() {
+	if File_lector_v1_lector_proto != nil {
+		return
+	}
+	file_lector_v1_lector_proto_msgTypes[1].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_lector_v1_lector_proto_rawDesc), len(file_lector_v1_lector_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   11,
+			NumExtensions: 0,
+			NumServices:   2,
+		},
+		GoTypes:           file_lector_v1_lector_proto_goTypes,
+		DependencyIndexes: file_lector_v1_lector_proto_depIdxs,
+		MessageInfos:      file_lector_v1_lector_proto_msgTypes,
+	}.Build()
+	File_lector_v1_lector_proto = out.File
+	file_lector_v1_lector_proto_goTypes = nil
+	file_lector_v1_lector_proto_depIdxs = nil
+}
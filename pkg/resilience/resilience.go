@@ -0,0 +1,333 @@
+// Package resilience provides retry with exponential backoff and a circuit breaker
+// for calls to external services (Supabase, storage, ...).
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+// ErrCircuitOpen is returned without calling the wrapped function while the breaker is open.
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// State is the circuit breaker state.
+type State string
+
+const (
+	StateClosed   State = "closed"
+	StateOpen     State = "open"
+	StateHalfOpen State = "half_open"
+)
+
+// Policy configures retries and the circuit breaker of an Executor.
+type Policy struct {
+	MaxAttempts int           // total attempts including the first one
+	BaseDelay   time.Duration // delay before the first retry
+	MaxDelay    time.Duration // upper bound for a single backoff delay
+
+	FailureThreshold int           // consecutive transient failures that open the breaker
+	OpenTimeout      time.Duration // how long the breaker stays open before a trial call
+
+	// Retryable reports whether err is transient. Non-transient errors are returned
+	// immediately and do not count towards opening the breaker.
+	Retryable func(err error) bool
+}
+
+// DefaultPolicy returns the policy used for Supabase and storage calls.
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:      3,
+		BaseDelay:        100 * time.Millisecond,
+		MaxDelay:         2 * time.Second,
+		FailureThreshold: 5,
+		OpenTimeout:      30 * time.Second,
+		Retryable:        IsTransient,
+	}
+}
+
+// IsTransient reports whether err looks like a network-level failure worth retrying.
+func IsTransient(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	return errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+// Stats is a point-in-time snapshot of an Executor's metrics.
+type Stats struct {
+	Name         string `json:"name"`
+	State        State  `json:"state"`
+	Calls        int64  `json:"calls"`
+	Retries      int64  `json:"retries"`
+	Failures     int64  `json:"failures"`
+	Rejected     int64  `json:"rejected"`
+	BreakerOpens int64  `json:"breaker_opens"`
+}
+
+// Executor runs calls with retry/backoff and a circuit breaker, and records metrics.
+type Executor struct {
+	name   string
+	policy Policy
+
+	// OnStateChange is called (outside the lock) whenever the breaker changes state.
+	OnStateChange func(name string, from, to State)
+
+	mu                  sync.Mutex
+	state               State
+	consecutiveFailures int
+	openedAt            time.Time
+	trialInFlight       bool
+
+	calls        atomic.Int64
+	retries      atomic.Int64
+	failures     atomic.Int64
+	rejected     atomic.Int64
+	breakerOpens atomic.Int64
+
+	now   func() time.Time
+	sleep func(ctx context.Context, d time.Duration) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]*Executor)
+)
+
+// NewExecutor creates an Executor and registers it so its metrics show up in AllStats.
+// Creating a second executor with the same name replaces the first in the registry.
+func NewExecutor(name string, policy Policy) *Executor {
+	defaults := DefaultPolicy()
+	if policy.MaxAttempts <= 0 {
+		policy.MaxAttempts = defaults.MaxAttempts
+	}
+	if policy.BaseDelay <= 0 {
+		policy.BaseDelay = defaults.BaseDelay
+	}
+	if policy.MaxDelay <= 0 {
+		policy.MaxDelay = defaults.MaxDelay
+	}
+	if policy.FailureThreshold <= 0 {
+		policy.FailureThreshold = defaults.FailureThreshold
+	}
+	if policy.OpenTimeout <= 0 {
+		policy.OpenTimeout = defaults.OpenTimeout
+	}
+	if policy.Retryable == nil {
+		policy.Retryable = defaults.Retryable
+	}
+
+	e := &Executor{
+		name:   name,
+		policy: policy,
+		state:  StateClosed,
+		now:    time.Now,
+		sleep:  sleepContext,
+	}
+
+	registryMu.Lock()
+	registry[name] = e
+	registryMu.Unlock()
+
+	return e
+}
+
+// AllStats returns the metrics of every registered executor, sorted by name.
+func AllStats() []Stats {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	out := make([]Stats, 0, len(registry))
+	for _, e := range registry {
+		out = append(out, e.Stats())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Do runs fn, retrying transient failures with exponential backoff and jitter.
+// Only use it for idempotent calls.
+func (e *Executor) Do(ctx context.Context, fn func() error) error {
+	return e.run(ctx, e.policy.MaxAttempts, fn)
+}
+
+// Once runs fn a single time through the circuit breaker (for non-idempotent calls).
+func (e *Executor) Once(ctx context.Context, fn func() error) error {
+	return e.run(ctx, 1, fn)
+}
+
+// Transient reports whether the executor's policy considers err worth retrying.
+func (e *Executor) Transient(err error) bool {
+	return e.policy.Retryable(err)
+}
+
+// RetryAfter returns how long the breaker stays open before it lets a trial call
+// through, or zero when calls are currently allowed.
+func (e *Executor) RetryAfter() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.state != StateOpen {
+		return 0
+	}
+	return max(e.policy.OpenTimeout-e.now().Sub(e.openedAt), 0)
+}
+
+// Stats returns a snapshot of the executor's metrics.
+func (e *Executor) Stats() Stats {
+	e.mu.Lock()
+	state := e.state
+	e.mu.Unlock()
+
+	return Stats{
+		Name:         e.name,
+		State:        state,
+		Calls:        e.calls.Load(),
+		Retries:      e.retries.Load(),
+		Failures:     e.failures.Load(),
+		Rejected:     e.rejected.Load(),
+		BreakerOpens: e.breakerOpens.Load(),
+	}
+}
+
+func (e *Executor) run(ctx context.Context, attempts int, fn func() error) error {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	var err error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			e.retries.Add(1)
+			if sleepErr := e.sleep(ctx, e.backoff(attempt)); sleepErr != nil {
+				return err
+			}
+		}
+
+		if !e.allow() {
+			e.rejected.Add(1)
+			return ErrCircuitOpen
+		}
+
+		e.calls.Add(1)
+		err = fn()
+		if err == nil {
+			e.recordSuccess()
+			return nil
+		}
+		if !e.policy.Retryable(err) {
+			// Not a service failure (e.g. validation or permission error).
+			e.recordSuccess()
+			return err
+		}
+
+		e.failures.Add(1)
+		e.recordFailure()
+	}
+	return err
+}
+
+// backoff returns the delay before the given retry attempt (1-based) using full jitter.
+func (e *Executor) backoff(attempt int) time.Duration {
+	delay := e.policy.BaseDelay << (attempt - 1)
+	if delay <= 0 || delay > e.policy.MaxDelay {
+		delay = e.policy.MaxDelay
+	}
+	return time.Duration(rand.Int63n(int64(delay)) + 1)
+}
+
+func (e *Executor) allow() bool {
+	e.mu.Lock()
+	var from State
+	changed := false
+	defer func() {
+		e.mu.Unlock()
+		if changed {
+			e.notify(from, StateHalfOpen)
+		}
+	}()
+
+	switch e.state {
+	case StateOpen:
+		if e.now().Sub(e.openedAt) < e.policy.OpenTimeout {
+			return false
+		}
+		from, changed = e.state, true
+		e.state = StateHalfOpen
+		e.trialInFlight = true
+		return true
+	case StateHalfOpen:
+		// Only one trial call at a time while half-open.
+		if e.trialInFlight {
+			return false
+		}
+		e.trialInFlight = true
+		return true
+	default:
+		return true
+	}
+}
+
+func (e *Executor) recordSuccess() {
+	e.mu.Lock()
+	from := e.state
+	e.consecutiveFailures = 0
+	e.trialInFlight = false
+	e.state = StateClosed
+	e.mu.Unlock()
+
+	if from != StateClosed {
+		e.notify(from, StateClosed)
+	}
+}
+
+func (e *Executor) recordFailure() {
+	e.mu.Lock()
+	from := e.state
+	e.consecutiveFailures++
+	e.trialInFlight = false
+	opened := false
+	if from == StateHalfOpen || (from == StateClosed && e.consecutiveFailures >= e.policy.FailureThreshold) {
+		e.state = StateOpen
+		e.openedAt = e.now()
+		opened = true
+	}
+	e.mu.Unlock()
+
+	if opened {
+		e.breakerOpens.Add(1)
+		e.notify(from, StateOpen)
+	}
+}
+
+func (e *Executor) notify(from, to State) {
+	if e.OnStateChange != nil && from != to {
+		e.OnStateChange(e.name, from, to)
+	}
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
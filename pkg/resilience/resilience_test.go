@@ -0,0 +1,149 @@
+package resilience
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+	"time"
+)
+
+var errPermanent = errors.New("permanent")
+
+func newTestExecutor(t *testing.T, policy Policy) (*Executor, *time.Time) {
+	t.Helper()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	e := NewExecutor(t.Name(), policy)
+	e.now = func() time.Time { return now }
+	e.sleep = func(ctx context.Context, d time.Duration) error { return nil }
+	return e, &now
+}
+
+func TestExecutor_RetriesTransientErrors(t *testing.T) {
+	e, _ := newTestExecutor(t, Policy{MaxAttempts: 3})
+
+	calls := 0
+	err := e.Do(context.Background(), func() error {
+		calls++
+		if calls < 3 {
+			return io.ErrUnexpectedEOF
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("expected success after retries, got %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls)
+	}
+
+	stats := e.Stats()
+	if stats.Retries != 2 || stats.Failures != 2 || stats.State != StateClosed {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+}
+
+func TestExecutor_DoesNotRetryPermanentErrors(t *testing.T) {
+	e, _ := newTestExecutor(t, Policy{MaxAttempts: 3})
+
+	calls := 0
+	err := e.Do(context.Background(), func() error {
+		calls++
+		return errPermanent
+	})
+	if !errors.Is(err, errPermanent) {
+		t.Fatalf("expected permanent error, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestExecutor_OnceDoesNotRetry(t *testing.T) {
+	e, _ := newTestExecutor(t, Policy{MaxAttempts: 3})
+
+	calls := 0
+	_ = e.Once(context.Background(), func() error {
+		calls++
+		return io.ErrUnexpectedEOF
+	})
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestExecutor_BreakerOpensAndRecovers(t *testing.T) {
+	e, now := newTestExecutor(t, Policy{MaxAttempts: 1, FailureThreshold: 2, OpenTimeout: time.Minute})
+
+	var transitions []State
+	e.OnStateChange = func(name string, from, to State) { transitions = append(transitions, to) }
+
+	failing := func() error { return io.ErrUnexpectedEOF }
+	_ = e.Do(context.Background(), failing)
+	_ = e.Do(context.Background(), failing)
+
+	if e.Stats().State != StateOpen {
+		t.Fatalf("expected breaker to be open, got %s", e.Stats().State)
+	}
+
+	called := false
+	err := e.Do(context.Background(), func() error { called = true; return nil })
+	if !errors.Is(err, ErrCircuitOpen) || called {
+		t.Fatalf("expected call to be rejected while open, err=%v called=%v", err, called)
+	}
+	*now = now.Add(20 * time.Second)
+	if got := e.RetryAfter(); got != 40*time.Second {
+		t.Fatalf("expected retry after 40s, got %s", got)
+	}
+
+	// After the open timeout a trial call is allowed and closes the breaker on success.
+	*now = now.Add(2 * time.Minute)
+	if err := e.Do(context.Background(), func() error { return nil }); err != nil {
+		t.Fatalf("expected trial call to succeed, got %v", err)
+	}
+
+	if got := e.RetryAfter(); got != 0 {
+		t.Fatalf("expected no retry delay once closed, got %s", got)
+	}
+	stats := e.Stats()
+	if stats.State != StateClosed || stats.BreakerOpens != 1 || stats.Rejected != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	want := []State{StateOpen, StateHalfOpen, StateClosed}
+	if len(transitions) != len(want) {
+		t.Fatalf("expected transitions %v, got %v", want, transitions)
+	}
+	for i := range want {
+		if transitions[i] != want[i] {
+			t.Fatalf("expected transitions %v, got %v", want, transitions)
+		}
+	}
+}
+
+func TestExecutor_StopsWhenContextCancelled(t *testing.T) {
+	e := NewExecutor(t.Name(), Policy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := 0
+	err := e.Do(ctx, func() error {
+		calls++
+		cancel()
+		return io.ErrUnexpectedEOF
+	})
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Fatalf("expected last error to be returned, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestAllStats_IncludesRegisteredExecutors(t *testing.T) {
+	NewExecutor(t.Name(), Policy{})
+	for _, s := range AllStats() {
+		if s.Name == t.Name() {
+			return
+		}
+	}
+	t.Fatalf("expected %s to be registered", t.Name())
+}